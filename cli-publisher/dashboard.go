@@ -1,7 +1,10 @@
 package cli_publisher
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
 	"github.com/TykTechnologies/tyk-sync/clients/objects"
@@ -11,12 +14,94 @@ type DashboardPublisher struct {
 	Secret      string
 	Hostname    string
 	OrgOverride string
+	// Cloud forces Tyk Cloud behaviour, it is otherwise detected from the Hostname
+	Cloud bool
+	// PlanCheck is run on the planned changes before a sync is applied
+	PlanCheck objects.PlanCheck
+	// Hooks are run around every change, see objects.Hooks
+	Hooks *objects.Hooks
+	// PolicyIDMode sets which ID policies are matched by, see dashboard.PolicyIDsAuto
+	PolicyIDMode string
+	// ReplaceCategories clears the categories of APIs published without any, see
+	// dashboard.Client.SetReplaceCategories
+	ReplaceCategories bool
+	// ListOptions set the query parameters of the list calls, see dashboard.ListOptions
+	ListOptions dashboard.ListOptions
+	// Progress is told how syncs advance, see objects.Progress
+	Progress objects.Progress
+	// DeactivateRemoved deactivates the APIs a sync would delete instead
+	DeactivateRemoved bool
+
+	// dash is built on first use and kept, so the requests of the publisher share one
+	// Tyk Cloud request spacing and the org is looked up once
+	dash *dashboard.Client
+	mu   sync.Mutex
+}
+
+// client returns the dashboard client of the publisher, the fields are read when it is
+// first built
+func (p *DashboardPublisher) client() (*dashboard.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.dash != nil {
+		return p.dash, nil
+	}
+
+	c, err := dashboard.NewDashboardClient(p.Hostname, p.Secret, p.OrgOverride)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Cloud {
+		c.SetCloud(true)
+	}
+
+	c.SetPlanCheck(p.PlanCheck)
+	c.SetHooks(p.Hooks)
+	c.SetPolicyIDMode(p.PolicyIDMode)
+	c.SetReplaceCategories(p.ReplaceCategories)
+	c.SetListOptions(p.ListOptions)
+	c.SetProgress(p.Progress)
+	c.SetDeactivateRemoved(p.DeactivateRemoved)
+
+	if p.OrgOverride == "" {
+		p.OrgOverride = c.OrgID
+	}
+
+	// Cloud keys are scoped to a single org, objects carrying any other org ID will be rejected
+	if c.IsCloud() && p.OrgOverride == "" {
+		return nil, errors.New("could not detect the org ID of the Tyk Cloud user, please set it with --org")
+	}
+
+	p.dash = c
+	return c, nil
+}
+
+// preflight refuses to continue if any of the definitions use features the target can't support
+func (p *DashboardPublisher) preflight(c *dashboard.Client, apiDefs ...objects.DBApiDefinition) error {
+	if !c.IsCloud() {
+		return nil
+	}
+
+	problems := dashboard.CheckCloudRestrictions(apiDefs)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, len(problems))
+	for i, p := range problems {
+		msgs[i] = p.Error()
+	}
+
+	return fmt.Errorf("%v definition(s) can not be published to Tyk Cloud:\n  %v", len(problems), strings.Join(msgs, "\n  "))
 }
 
 func (p *DashboardPublisher) enforceOrgID(apiDef *objects.DBApiDefinition) *objects.DBApiDefinition {
 	if p.OrgOverride != "" {
 		fmt.Println("org override detected, setting.")
 		apiDef.OrgID = p.OrgOverride
+		apiDef.ApplySeed()
 	}
 
 	return apiDef
@@ -26,43 +111,46 @@ func (p *DashboardPublisher) enforceOrgIDForPolicy(pol *objects.Policy) *objects
 	if p.OrgOverride != "" {
 		fmt.Println("org override detected, setting.")
 		pol.OrgID = p.OrgOverride
+		pol.ApplySeed()
 	}
 
 	return pol
 }
 
 func (p *DashboardPublisher) Create(apiDef *objects.DBApiDefinition) (string, error) {
-	c, err := dashboard.NewDashboardClient(p.Hostname, p.Secret, p.OrgOverride)
+	c, err := p.client()
 	if err != nil {
 		return "", err
 	}
-	if p.OrgOverride == "" {
-		p.OrgOverride = c.OrgID
+
+	if err := p.preflight(c, *apiDef); err != nil {
+		return "", err
 	}
 
 	return c.CreateAPI(p.enforceOrgID(apiDef))
 }
 
 func (p *DashboardPublisher) Update(apiDef *objects.DBApiDefinition) error {
-	c, err := dashboard.NewDashboardClient(p.Hostname, p.Secret, p.OrgOverride)
+	c, err := p.client()
 	if err != nil {
 		return err
 	}
-	if p.OrgOverride == "" {
-		p.OrgOverride = c.OrgID
+
+	if err := p.preflight(c, *apiDef); err != nil {
+		return err
 	}
 
 	return c.UpdateAPI(p.enforceOrgID(apiDef))
 }
 
 func (p *DashboardPublisher) Sync(apiDefs []objects.DBApiDefinition) error {
-	c, err := dashboard.NewDashboardClient(p.Hostname, p.Secret, p.OrgOverride)
+	c, err := p.client()
 	if err != nil {
 		return err
 	}
 
-	if p.OrgOverride == "" {
-		p.OrgOverride = c.OrgID
+	if err := p.preflight(c, apiDefs...); err != nil {
+		return err
 	}
 
 	if p.OrgOverride != "" {
@@ -70,6 +158,7 @@ func (p *DashboardPublisher) Sync(apiDefs []objects.DBApiDefinition) error {
 		for i, a := range apiDefs {
 			newDef := a
 			newDef.OrgID = p.OrgOverride
+			newDef.ApplySeed()
 			fixedDefs[i] = newDef
 		}
 
@@ -89,41 +178,33 @@ func (p *DashboardPublisher) Name() string {
 }
 
 func (p *DashboardPublisher) CreatePolicy(pol *objects.Policy) (string, error) {
-	c, err := dashboard.NewDashboardClient(p.Hostname, p.Secret, p.OrgOverride)
+	c, err := p.client()
 	if err != nil {
 		return "", err
 	}
-	if p.OrgOverride == "" {
-		p.OrgOverride = c.OrgID
-	}
 	return c.CreatePolicy(p.enforceOrgIDForPolicy(pol))
 }
 
 func (p *DashboardPublisher) UpdatePolicy(pol *objects.Policy) error {
-	c, err := dashboard.NewDashboardClient(p.Hostname, p.Secret, p.OrgOverride)
+	c, err := p.client()
 	if err != nil {
 		return err
 	}
-	if p.OrgOverride == "" {
-		p.OrgOverride = c.OrgID
-	}
 	return c.UpdatePolicy(p.enforceOrgIDForPolicy(pol))
 }
 
 func (p *DashboardPublisher) SyncPolicies(pols []objects.Policy) error {
-	c, err := dashboard.NewDashboardClient(p.Hostname, p.Secret, p.OrgOverride)
+	c, err := p.client()
 	if err != nil {
 		return err
 	}
-	if p.OrgOverride == "" {
-		p.OrgOverride = c.OrgID
-	}
 
 	if p.OrgOverride != "" {
 		fixedPols := make([]objects.Policy, len(pols))
 		for i, pol := range pols {
 			newPol := pol
 			newPol.OrgID = p.OrgOverride
+			newPol.ApplySeed()
 			fixedPols[i] = newPol
 		}
 
@@ -132,3 +213,169 @@ func (p *DashboardPublisher) SyncPolicies(pols []objects.Policy) error {
 
 	return c.SyncPolicies(pols)
 }
+
+func (p *DashboardPublisher) CreateCertificate(cert []byte) (string, error) {
+	c, err := p.client()
+	if err != nil {
+		return "", err
+	}
+	return c.CreateCertificate(cert)
+}
+
+func (p *DashboardPublisher) CreateKey(key *objects.Key) error {
+	c, err := p.client()
+	if err != nil {
+		return err
+	}
+
+	if p.OrgOverride != "" && key.Session != nil {
+		key.Session["org_id"] = p.OrgOverride
+	}
+
+	return c.CreateKey(key)
+}
+
+func (p *DashboardPublisher) FetchOAuthClients(apiID string) ([]objects.OAuthClient, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+	return c.FetchOAuthClients(apiID)
+}
+
+func (p *DashboardPublisher) CreateOAuthClient(client *objects.OAuthClient) error {
+	c, err := p.client()
+	if err != nil {
+		return err
+	}
+	return c.CreateOAuthClient(client)
+}
+
+func (p *DashboardPublisher) FetchAPIs() ([]objects.DBApiDefinition, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.FetchAPIs()
+}
+
+func (p *DashboardPublisher) FetchPolicies() ([]objects.Policy, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.FetchPolicies()
+}
+
+func (p *DashboardPublisher) FetchAPIRaw(apiDef *objects.DBApiDefinition) (map[string]interface{}, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+	return c.FetchAPIRaw(apiDef.APIID)
+}
+
+func (p *DashboardPublisher) FetchAPIsRaw(defs []objects.DBApiDefinition) ([]map[string]interface{}, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+
+	apis, err := c.FetchAPIsRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]map[string]interface{}, len(defs))
+	for i, d := range defs {
+		out[i] = apis[d.APIID]
+	}
+	return out, nil
+}
+
+// FetchPoliciesRaw matches the policies by their explicit id if they have one, otherwise by
+// their database ID, like FetchPolicyRaw
+func (p *DashboardPublisher) FetchPoliciesRaw(pols []objects.Policy) ([]map[string]interface{}, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := c.FetchPoliciesRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	byID, byMID := map[string]map[string]interface{}{}, map[string]map[string]interface{}{}
+	for _, raw := range stored {
+		if id, _ := raw["id"].(string); id != "" {
+			byID[id] = raw
+		}
+		if mid, _ := raw["_id"].(string); mid != "" {
+			byMID[mid] = raw
+		}
+	}
+
+	out := make([]map[string]interface{}, len(pols))
+	for i, pol := range pols {
+		if raw, ok := byID[pol.ID]; ok && pol.ID != "" {
+			out[i] = raw
+		} else {
+			out[i] = byMID[pol.MID.Hex()]
+		}
+	}
+	return out, nil
+}
+
+func (p *DashboardPublisher) FetchPolicyRaw(pol *objects.Policy) (map[string]interface{}, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+
+	// Policies are stored under their database ID, which a repo may not know
+	id := pol.MID.Hex()
+	if pol.ID != "" {
+		pols, err := c.FetchPolicies()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, existing := range pols {
+			if existing.ID == pol.ID {
+				id = existing.MID.Hex()
+			}
+		}
+	}
+
+	return c.FetchPolicyRaw(id)
+}
+
+func (p *DashboardPublisher) TargetVersion() (string, error) {
+	c, err := p.client()
+	if err != nil {
+		return "", err
+	}
+
+	return c.FetchVersion()
+}
+
+func (p *DashboardPublisher) DeleteAPI(id string) error {
+	c, err := p.client()
+	if err != nil {
+		return err
+	}
+
+	return c.DeleteAPI(id)
+}
+
+func (p *DashboardPublisher) DeletePolicy(id string) error {
+	c, err := p.client()
+	if err != nil {
+		return err
+	}
+
+	return c.DeletePolicy(id)
+}
@@ -0,0 +1,145 @@
+package cli_publisher
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/files"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// FilesPublisher publishes to a Tyk CE gateway that loads its APIs and policies from files,
+// see files.Client. The gateway picks the changes up when it is hot reloaded or restarted.
+type FilesPublisher struct {
+	// Dir is the directory holding the apps/ and policies/ folders of the gateway
+	Dir string
+	// PlanCheck is run on the planned changes before a sync is applied
+	PlanCheck objects.PlanCheck
+	// Hooks are run around every change, see objects.Hooks
+	Hooks *objects.Hooks
+	// Progress is told how syncs advance, see objects.Progress
+	Progress objects.Progress
+	// DeactivateRemoved deactivates the APIs a sync would delete instead
+	DeactivateRemoved bool
+}
+
+func (p *FilesPublisher) client() (*files.Client, error) {
+	c, err := files.NewClient(p.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c.SetHooks(p.Hooks)
+	c.SetProgress(p.Progress)
+	return c, nil
+}
+
+func (p *FilesPublisher) Create(apiDef *objects.DBApiDefinition) (string, error) {
+	c, err := p.client()
+	if err != nil {
+		return "", err
+	}
+
+	return c.CreateAPI(apiDef)
+}
+
+func (p *FilesPublisher) Update(apiDef *objects.DBApiDefinition) error {
+	c, err := p.client()
+	if err != nil {
+		return err
+	}
+
+	return c.UpdateAPI(apiDef)
+}
+
+func (p *FilesPublisher) Name() string {
+	return fmt.Sprintf("Files Publisher (%v)", p.Dir)
+}
+
+// Reload is a no-op, the files are read by the gateway on its next reload
+func (p *FilesPublisher) Reload() error {
+	return nil
+}
+
+func (p *FilesPublisher) Sync(apiDefs []objects.DBApiDefinition) error {
+	c, err := p.client()
+	if err != nil {
+		return err
+	}
+
+	c.SetPlanCheck(p.PlanCheck)
+	c.SetDeactivateRemoved(p.DeactivateRemoved)
+	return c.Sync(apiDefs)
+}
+
+func (p *FilesPublisher) CreatePolicy(pol *objects.Policy) (string, error) {
+	c, err := p.client()
+	if err != nil {
+		return "", err
+	}
+
+	return c.CreatePolicy(pol)
+}
+
+func (p *FilesPublisher) UpdatePolicy(pol *objects.Policy) error {
+	c, err := p.client()
+	if err != nil {
+		return err
+	}
+
+	return c.UpdatePolicy(pol)
+}
+
+func (p *FilesPublisher) SyncPolicies(pols []objects.Policy) error {
+	c, err := p.client()
+	if err != nil {
+		return err
+	}
+
+	c.SetPlanCheck(p.PlanCheck)
+	return c.SyncPolicies(pols)
+}
+
+func (p *FilesPublisher) FetchAPIs() ([]objects.DBApiDefinition, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.FetchAPIs()
+}
+
+func (p *FilesPublisher) FetchAPIRaw(apiDef *objects.DBApiDefinition) (map[string]interface{}, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.FetchAPIRaw(apiDef.APIID)
+}
+
+func (p *FilesPublisher) FetchPolicyRaw(pol *objects.Policy) (map[string]interface{}, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.FetchPolicyRaw(files.PolicyID(pol))
+}
+
+func (p *FilesPublisher) DeleteAPI(id string) error {
+	c, err := p.client()
+	if err != nil {
+		return err
+	}
+
+	return c.DeleteAPI(id)
+}
+
+func (p *FilesPublisher) DeletePolicy(id string) error {
+	c, err := p.client()
+	if err != nil {
+		return err
+	}
+
+	return c.DeletePolicy(id)
+}
@@ -10,10 +10,29 @@ import (
 type GatewayPublisher struct {
 	Secret   string
 	Hostname string
+	// PlanCheck is run on the planned changes before a sync is applied
+	PlanCheck objects.PlanCheck
+	// Hooks are run around every change, see objects.Hooks
+	Hooks *objects.Hooks
+	// Progress is told how syncs advance, see objects.Progress
+	Progress objects.Progress
+	// DeactivateRemoved deactivates the APIs a sync would delete instead
+	DeactivateRemoved bool
 }
 
-func (p *GatewayPublisher) Create(apiDef *objects.DBApiDefinition) (string, error) {
+func (p *GatewayPublisher) client() (*gateway.Client, error) {
 	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	c.SetHooks(p.Hooks)
+	c.SetProgress(p.Progress)
+	return c, nil
+}
+
+func (p *GatewayPublisher) Create(apiDef *objects.DBApiDefinition) (string, error) {
+	c, err := p.client()
 	if err != nil {
 		return "", err
 	}
@@ -22,7 +41,7 @@ func (p *GatewayPublisher) Create(apiDef *objects.DBApiDefinition) (string, erro
 }
 
 func (p *GatewayPublisher) Update(apiDef *objects.DBApiDefinition) error {
-	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
+	c, err := p.client()
 	if err != nil {
 		return err
 	}
@@ -35,7 +54,7 @@ func (p *GatewayPublisher) Name() string {
 }
 
 func (p *GatewayPublisher) Reload() error {
-	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
+	c, err := p.client()
 	if err != nil {
 		return err
 	}
@@ -44,11 +63,13 @@ func (p *GatewayPublisher) Reload() error {
 }
 
 func (p *GatewayPublisher) Sync(apiDefs []objects.DBApiDefinition) error {
-	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
+	c, err := p.client()
 	if err != nil {
 		return err
 	}
 
+	c.SetPlanCheck(p.PlanCheck)
+	c.SetDeactivateRemoved(p.DeactivateRemoved)
 	return c.Sync(apiDefs)
 }
 
@@ -63,3 +84,87 @@ func (p *GatewayPublisher) UpdatePolicy(pol *objects.Policy) error {
 func (p *GatewayPublisher) SyncPolicies(pols []objects.Policy) error {
 	return errors.New("Policy handling not supported by Gateway publisher")
 }
+
+func (p *GatewayPublisher) CreateCertificate(cert []byte) (string, error) {
+	c, err := p.client()
+	if err != nil {
+		return "", err
+	}
+
+	return c.CreateCertificate(cert)
+}
+
+func (p *GatewayPublisher) CreateKey(key *objects.Key) error {
+	c, err := p.client()
+	if err != nil {
+		return err
+	}
+
+	return c.CreateKey(key)
+}
+
+func (p *GatewayPublisher) FetchAPIs() ([]objects.DBApiDefinition, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.FetchAPIs()
+}
+
+func (p *GatewayPublisher) FetchAPIRaw(apiDef *objects.DBApiDefinition) (map[string]interface{}, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.FetchAPIRaw(apiDef.APIID)
+}
+
+func (p *GatewayPublisher) FetchAPIsRaw(defs []objects.DBApiDefinition) ([]map[string]interface{}, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+
+	apis, err := c.FetchAPIsRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]map[string]interface{}, len(defs))
+	for i, d := range defs {
+		out[i] = apis[d.APIID]
+	}
+	return out, nil
+}
+
+func (p *GatewayPublisher) FetchPoliciesRaw(pols []objects.Policy) ([]map[string]interface{}, error) {
+	return nil, errors.New("Policy handling not supported by Gateway publisher")
+}
+
+func (p *GatewayPublisher) FetchPolicyRaw(pol *objects.Policy) (map[string]interface{}, error) {
+	return nil, errors.New("Policy handling not supported by Gateway publisher")
+}
+
+func (p *GatewayPublisher) TargetVersion() (string, error) {
+	c, err := p.client()
+	if err != nil {
+		return "", err
+	}
+
+	return c.FetchVersion()
+}
+
+func (p *GatewayPublisher) DeleteAPI(id string) error {
+	c, err := p.client()
+	if err != nil {
+		return err
+	}
+
+	return c.DeleteAPI(id)
+}
+
+func (p *GatewayPublisher) DeletePolicy(id string) error {
+	return errors.New("Policy handling not supported by Gateway publisher")
+}
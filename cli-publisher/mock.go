@@ -1,6 +1,7 @@
 package cli_publisher
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/TykTechnologies/tyk-sync/clients/objects"
@@ -49,3 +50,42 @@ func (mp MockPublisher) Name() string {
 func (mp MockPublisher) Reload() error {
 	return nil
 }
+
+func (mp MockPublisher) CreateCertificate(cert []byte) (string, error) {
+	fmt.Printf("Creating certificate (%v bytes)\n", len(cert))
+	return "", nil
+}
+
+func (mp MockPublisher) CreateKey(key *objects.Key) error {
+	fmt.Printf("Creating key: %v\n", key.KeyID)
+	return nil
+}
+
+func mockRaw(v interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string]interface{}{}
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
+
+func (mp MockPublisher) FetchAPIRaw(apiDef *objects.DBApiDefinition) (map[string]interface{}, error) {
+	return mockRaw(apiDef.APIDefinition)
+}
+
+func (mp MockPublisher) FetchPolicyRaw(pol *objects.Policy) (map[string]interface{}, error) {
+	return mockRaw(pol)
+}
+
+func (mp MockPublisher) DeleteAPI(id string) error {
+	fmt.Printf("Deleting API ID: %v\n", id)
+	return nil
+}
+
+func (mp MockPublisher) DeletePolicy(id string) error {
+	fmt.Printf("Deleting Policy ID: %v\n", id)
+	return nil
+}
@@ -1,9 +1,11 @@
 package dashboard
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
 	"github.com/levigross/grequests"
 	"github.com/ongoingio/urljoin"
 	uuid "github.com/satori/go.uuid"
@@ -25,42 +27,50 @@ func (c *Client) fixDBDef(def *objects.DBApiDefinition) {
 	if def.HookReferences == nil {
 		def.HookReferences = make([]interface{}, 0)
 	}
+
+	// Schemas sometimes reject null hook lists, send empty ones so the
+	// plugin driver and hooks survive a dump/publish round trip
+	mw := &def.CustomMiddleware
+	if mw.Pre == nil {
+		mw.Pre = make([]apidef.MiddlewareDefinition, 0)
+	}
+	if mw.Post == nil {
+		mw.Post = make([]apidef.MiddlewareDefinition, 0)
+	}
+	if mw.PostKeyAuth == nil {
+		mw.PostKeyAuth = make([]apidef.MiddlewareDefinition, 0)
+	}
+	if mw.Response == nil {
+		mw.Response = make([]apidef.MiddlewareDefinition, 0)
+	}
+	if mw.IdExtractor.ExtractorConfig == nil {
+		mw.IdExtractor.ExtractorConfig = map[string]interface{}{}
+	}
 }
 
 func (c *Client) SetInsecureTLS(val bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.InsecureSkipVerify = val
+	c.cloudClient = nil
 }
 
 func (c *Client) GetActiveID(def *objects.DBApiDefinition) string {
 	return def.Id.Hex()
 }
 
-func (c *Client) CreateAPI(def *objects.DBApiDefinition) (string, error) {
+func (c *Client) createAPI(def *objects.DBApiDefinition) (string, error) {
 	fullPath := urljoin.Join(c.url, endpointAPIs)
 
-	ro := &grequests.RequestOptions{
-		Params: map[string]string{"p": "-2"},
-		Headers: map[string]string{
-			"Authorization": c.secret,
-		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
-	}
-
-	resp, err := grequests.Get(fullPath, ro)
+	list, err := c.FetchAPIs()
 	if err != nil {
 		return "", err
 	}
-
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("API Returned error: %v for %v", resp.String(), fullPath)
-	}
-
-	apis := APISResponse{}
-	if err := resp.JSON(&apis); err != nil {
-		return "", err
-	}
+	apis := APISResponse{Apis: list}
 
 	retainedIDs := false
+	def.Id = objects.NormalID(def.Id)
 
 	for _, api := range apis.Apis {
 		if api.APIID == def.APIID {
@@ -68,7 +78,7 @@ func (c *Client) CreateAPI(def *objects.DBApiDefinition) (string, error) {
 			return "", UseUpdateError
 		}
 
-		if api.Id == def.Id {
+		if def.Id != "" && api.Id == def.Id {
 			fmt.Println("Warning: Object ID Exists")
 			return "", UseUpdateError
 		}
@@ -101,6 +111,7 @@ func (c *Client) CreateAPI(def *objects.DBApiDefinition) (string, error) {
 			"Authorization": c.secret,
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	})
 
 	if err != nil {
@@ -123,9 +134,11 @@ func (c *Client) CreateAPI(def *objects.DBApiDefinition) (string, error) {
 	// Create will always reset the API ID on dashboard, if we want to retain it, we must use UPDATE
 	if retainedIDs {
 		def.Id = bson.ObjectIdHex(status.Meta)
-		if err := c.UpdateAPI(def); err != nil {
+		if err := c.updateAPI(def); err != nil {
 			fmt.Printf("Problem trying to retain API ID: %v\n", err)
 		}
+	} else if err := c.assignCategories(status.Meta, def); err != nil {
+		return status.Meta, err
 	}
 
 	return status.Meta, nil
@@ -133,31 +146,7 @@ func (c *Client) CreateAPI(def *objects.DBApiDefinition) (string, error) {
 }
 
 func (c *Client) FetchAPIs() ([]objects.DBApiDefinition, error) {
-	fullPath := urljoin.Join(c.url, endpointAPIs)
-
-	ro := &grequests.RequestOptions{
-		Params: map[string]string{"p": "-2"},
-		Headers: map[string]string{
-			"Authorization": c.secret,
-		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
-	}
-
-	resp, err := grequests.Get(fullPath, ro)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("API Returned error: %v for %v", resp.String(), fullPath)
-	}
-
-	apis := APISResponse{}
-	if err := resp.JSON(&apis); err != nil {
-		return nil, err
-	}
-
-	return apis.Apis, nil
+	return c.FetchAPIsWith(ListOptions{})
 }
 
 func (c *Client) FetchAPI(apiID string) (objects.DBApiDefinition, error) {
@@ -165,11 +154,11 @@ func (c *Client) FetchAPI(apiID string) (objects.DBApiDefinition, error) {
 	fullPath := urljoin.Join(c.url, endpointAPIs, apiID)
 
 	ro := &grequests.RequestOptions{
-		Params: map[string]string{"p": "-2"},
 		Headers: map[string]string{
 			"Authorization": c.secret,
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	}
 
 	resp, err := grequests.Get(fullPath, ro)
@@ -188,33 +177,19 @@ func (c *Client) FetchAPI(apiID string) (objects.DBApiDefinition, error) {
 	return api, nil
 }
 
-func (c *Client) UpdateAPI(def *objects.DBApiDefinition) error {
-	fullPath := urljoin.Join(c.url, endpointAPIs)
-
-	ro := &grequests.RequestOptions{
-		Params: map[string]string{"p": "-2"},
-		Headers: map[string]string{
-			"Authorization": c.secret,
-		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
-	}
-
-	resp, err := grequests.Get(fullPath, ro)
+func (c *Client) updateAPI(def *objects.DBApiDefinition) error {
+	list, err := c.FetchAPIs()
 	if err != nil {
 		return err
 	}
+	apis := APISResponse{Apis: list}
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("API Returned error: %v for %v", resp.String(), fullPath)
-	}
-
-	apis := APISResponse{}
-	if err := resp.JSON(&apis); err != nil {
-		return err
-	}
-
+	// A zero object ID isn't a real one, the API is matched by its other IDs
+	def.Id = objects.NormalID(def.Id)
 	found := false
+	var current objects.DBApiDefinition
 	for _, api := range apis.Apis {
+		current = api
 		// For an update, prefer API IDs
 		if api.APIID == def.APIID {
 			// Lets make sure we target the internal ID of the matching API ID
@@ -224,7 +199,7 @@ func (c *Client) UpdateAPI(def *objects.DBApiDefinition) error {
 		}
 
 		// Dashboard uses it's own IDs
-		if api.Id == def.Id {
+		if def.Id != "" && api.Id == def.Id {
 			if def.APIID == "" {
 				def.APIID = api.APIID
 			}
@@ -262,6 +237,7 @@ func (c *Client) UpdateAPI(def *objects.DBApiDefinition) error {
 	if !found {
 		return UseCreateError
 	}
+	c.keepCategories(def, &current)
 
 	// Update
 	asDBDef := def
@@ -274,6 +250,7 @@ func (c *Client) UpdateAPI(def *objects.DBApiDefinition) error {
 			"Authorization": c.secret,
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	})
 
 	if err != nil {
@@ -293,41 +270,55 @@ func (c *Client) UpdateAPI(def *objects.DBApiDefinition) error {
 		return fmt.Errorf("API request completed, but with error: %v", status.Message)
 	}
 
-	return nil
+	return c.assignCategories(def.Id.Hex(), def)
 }
 
-func (c *Client) Sync(apiDefs []objects.DBApiDefinition) error {
-	deleteAPIs := []string{}
-	updateAPIs := []objects.DBApiDefinition{}
-	createAPIs := []objects.DBApiDefinition{}
+// SetPlanCheck sets a check that is run on the planned changes before Sync and SyncPolicies apply them
+func (c *Client) SetPlanCheck(check objects.PlanCheck) {
+	c.planCheck = check
+}
 
-	// Fetch the running API list
-	fullPath := urljoin.Join(c.url, endpointAPIs)
+// SetDeactivateRemoved makes Sync deactivate the APIs that are no longer in git instead of
+// deleting them, so they can be brought back with their IDs and keys
+func (c *Client) SetDeactivateRemoved(val bool) {
+	c.deactivateRemoved = val
+}
 
-	ro := &grequests.RequestOptions{
-		Params: map[string]string{"p": "-2"},
-		Headers: map[string]string{
-			"Authorization": c.secret,
-		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
+// deactivateAPI sets active to false on the API with the DB ID, keeping the rest of it as it is
+func (c *Client) deactivateAPI(id string) error {
+	raw, err := c.FetchAPIRaw(id)
+	if err != nil {
+		return err
 	}
 
-	resp, err := grequests.Get(fullPath, ro)
+	def, err := objects.APIFromRaw(raw)
 	if err != nil {
 		return err
 	}
+	def.Active = false
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("API Returned error: %v", resp.String())
-	}
+	return c.UpdateAPI(def)
+}
+
+func (c *Client) Sync(apiDefs []objects.DBApiDefinition) error {
+	deleteAPIs := []string{}
+	deleteItems := []objects.SyncItem{}
+	updateAPIs := []objects.DBApiDefinition{}
+	// Tags of the APIs as they are on the dashboard
+	updateTags := [][]string{}
+	createAPIs := []objects.DBApiDefinition{}
 
-	apis := APISResponse{}
-	if err := resp.JSON(&apis); err != nil {
+	// Fetch the running API list
+	list, err := c.FetchAPIs()
+	if err != nil {
 		return err
 	}
+	apis := APISResponse{Apis: list}
 
 	DashIDMap := map[string]int{}
 	GitIDMap := map[string]int{}
+	// The dashboard gives an API created without an API ID one of its own
+	dashDBIDs := map[string]int{}
 
 	// Build the dash ID map
 	for i, api := range apis.Apis {
@@ -337,6 +328,7 @@ func (c *Client) Sync(apiDefs []objects.DBApiDefinition) error {
 			continue
 		}
 		DashIDMap[api.APIID] = i
+		dashDBIDs[api.Id.Hex()] = i
 	}
 
 	// Build the Git ID Map
@@ -349,8 +341,12 @@ func (c *Client) Sync(apiDefs []objects.DBApiDefinition) error {
 		if def.APIID != "" {
 			GitIDMap[def.APIID] = i
 			continue
-		} else if def.Id.Hex() != "" {
-			// No API ID? Let's try the actual DB ID
+		} else if !objects.EmptyID(def.Id) {
+			// No API ID? Let's try the actual DB ID, the API it matches is keyed by its API ID
+			if dashIndex, ok := dashDBIDs[def.Id.Hex()]; ok {
+				GitIDMap[apis.Apis[dashIndex].APIID] = i
+				continue
+			}
 			GitIDMap[def.Id.Hex()] = i
 			continue
 		} else {
@@ -369,6 +365,7 @@ func (c *Client) Sync(apiDefs []objects.DBApiDefinition) error {
 			api.Id = apis.Apis[dashIndex].Id
 			api.APIID = apis.Apis[dashIndex].APIID
 			updateAPIs = append(updateAPIs, api)
+			updateTags = append(updateTags, apis.Apis[dashIndex].Tags)
 		}
 	}
 
@@ -376,8 +373,17 @@ func (c *Client) Sync(apiDefs []objects.DBApiDefinition) error {
 	for key, dashIndex := range DashIDMap {
 		_, ok := GitIDMap[key]
 		if !ok {
+			if c.deactivateRemoved && !apis.Apis[dashIndex].Active {
+				continue
+			}
 			// Make sure we always target the DB ID
 			deleteAPIs = append(deleteAPIs, apis.Apis[dashIndex].Id.Hex())
+			deleteItems = append(deleteItems, objects.SyncItem{
+				ID:    apis.Apis[dashIndex].APIID,
+				Name:  apis.Apis[dashIndex].Name,
+				Tags:  apis.Apis[dashIndex].Tags,
+				Index: len(deleteItems),
+			})
 		}
 	}
 
@@ -389,27 +395,65 @@ func (c *Client) Sync(apiDefs []objects.DBApiDefinition) error {
 		}
 	}
 
-	fmt.Printf("Deleting: %v\n", len(deleteAPIs))
+	if c.planCheck != nil {
+		plan := &objects.SyncPlan{Kind: "APIs", Existing: len(apis.Apis), Deactivate: c.deactivateRemoved}
+		plan.Delete = deleteItems
+		for i, api := range updateAPIs {
+			plan.Update = append(plan.Update, objects.SyncItem{ID: api.APIID, Name: api.Name, Tags: updateTags[i], Index: i})
+		}
+		for i, api := range createAPIs {
+			plan.Create = append(plan.Create, objects.SyncItem{ID: api.APIID, Name: api.Name, Index: i})
+		}
+
+		if err := c.planCheck(plan); err != nil {
+			return err
+		}
+
+		deleteAPIs = objects.KeepIDs(deleteAPIs, plan.Delete)
+		updateAPIs = objects.KeepAPIs(updateAPIs, plan.Update)
+		createAPIs = objects.KeepAPIs(createAPIs, plan.Create)
+	}
+
+	if c.deactivateRemoved {
+		fmt.Printf("Deactivating: %v\n", len(deleteAPIs))
+	} else {
+		fmt.Printf("Deleting: %v\n", len(deleteAPIs))
+	}
 	fmt.Printf("Updating: %v\n", len(updateAPIs))
 	fmt.Printf("Creating: %v\n", len(createAPIs))
+	progress := objects.TrackProgress(c.progress, "APIs", len(deleteAPIs)+len(updateAPIs)+len(createAPIs))
 
 	// Do the deletes
+	progress.Phase(objects.PhaseDelete, len(deleteAPIs))
 	for _, dbId := range deleteAPIs {
+		if c.deactivateRemoved {
+			fmt.Printf("SYNC Deactivating: %v\n", dbId)
+			if err := c.deactivateAPI(dbId); err != nil {
+				return err
+			}
+			progress.Done(dbId)
+			continue
+		}
+
 		fmt.Printf("SYNC Deleting: %v\n", dbId)
 		if err := c.DeleteAPI(dbId); err != nil {
 			return err
 		}
+		progress.Done(dbId)
 	}
 
 	// Do the updates
+	progress.Phase(objects.PhaseUpdate, len(updateAPIs))
 	for _, api := range updateAPIs {
 		fmt.Printf("SYNC Updating: %v\n", api.Id.Hex())
 		if err := c.UpdateAPI(&api); err != nil {
 			return err
 		}
+		progress.Done(api.APIID)
 	}
 
 	// Do the creates
+	progress.Phase(objects.PhaseCreate, len(createAPIs))
 	for _, api := range createAPIs {
 		fmt.Printf("SYNC Creating: %v\n", api.Name)
 		var err error
@@ -418,18 +462,20 @@ func (c *Client) Sync(apiDefs []objects.DBApiDefinition) error {
 			return err
 		}
 		fmt.Printf("--> ID: %v\n", id)
+		progress.Done(api.Name)
 	}
 
 	return nil
 }
 
-func (c *Client) DeleteAPI(id string) error {
+func (c *Client) deleteAPI(id string) error {
 	delPath := urljoin.Join(c.url, endpointAPIs, id)
 	delResp, err := grequests.Delete(delPath, &grequests.RequestOptions{
 		Headers: map[string]string{
 			"Authorization": c.secret,
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	})
 
 	if err != nil {
@@ -442,3 +488,58 @@ func (c *Client) DeleteAPI(id string) error {
 
 	return nil
 }
+
+// FetchAPIsRaw returns the API definitions as stored by the dashboard by API ID, in one list
+// call rather than one call per API, see FetchAPIRaw
+func (c *Client) FetchAPIsRaw() (map[string]map[string]interface{}, error) {
+	apis := map[string]map[string]interface{}{}
+	err := c.fetchList(endpointAPIs, "apis", c.listOptions, func(raw json.RawMessage) error {
+		item := struct {
+			APIDefinition map[string]interface{} `json:"api_definition"`
+		}{}
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return err
+		}
+		if id, _ := item.APIDefinition["api_id"].(string); id != "" {
+			apis[id] = item.APIDefinition
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return apis, nil
+}
+
+// FetchAPIRaw returns the API definition as stored by the dashboard, without decoding it into
+// the vendored apidef, so fields unknown to tyk-sync are retained
+func (c *Client) FetchAPIRaw(apiID string) (map[string]interface{}, error) {
+	fullPath := urljoin.Join(c.url, endpointAPIs, apiID)
+
+	ro := &grequests.RequestOptions{
+		Headers: map[string]string{
+			"Authorization": c.secret,
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	}
+
+	resp, err := grequests.Get(fullPath, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API %v Returned error: %v for %v", apiID, resp.String(), fullPath)
+	}
+
+	raw := struct {
+		APIDefinition map[string]interface{} `json:"api_definition"`
+	}{}
+	if err := resp.JSON(&raw); err != nil {
+		return nil, err
+	}
+
+	return raw.APIDefinition, nil
+}
@@ -0,0 +1,134 @@
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/levigross/grequests"
+	"github.com/ongoingio/urljoin"
+)
+
+const endpointCategories string = "/api/apis/categories"
+
+// ErrNoCategories is returned by dashboards without the API categories endpoints, they only
+// keep the categories as #hashtags in the API names
+var ErrNoCategories = errors.New("the dashboard has no API categories endpoints")
+
+// SetReplaceCategories makes the categories of the published definitions replace those of
+// the APIs on the dashboard even if they have none. By default an API published without
+// categories keeps the ones it has, e.g. assigned in the dashboard UI.
+func (c *Client) SetReplaceCategories(val bool) {
+	c.replaceCategories = val
+}
+
+func (c *Client) categoriesRequest(method, path string, body, out interface{}) error {
+	ro := &grequests.RequestOptions{
+		Headers: map[string]string{
+			"Authorization": c.secret,
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	}
+	if body != nil {
+		ro.JSON = body
+	}
+
+	fullPath := urljoin.Join(c.url, path)
+	resp, err := grequests.Req(method, fullPath, ro)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound && path == endpointCategories {
+		return ErrNoCategories
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("API Returned error: %v (code: %v) for %v %v", resp.String(), resp.StatusCode, method, fullPath)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return resp.JSON(out)
+}
+
+// FetchCategories returns the categories the APIs of the dashboard are filed under
+func (c *Client) FetchCategories() ([]string, error) {
+	out := objects.APICategories{}
+	if err := c.categoriesRequest(http.MethodGet, endpointCategories, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Categories, nil
+}
+
+// FetchAPICategories returns the categories of the API with the database ID id
+func (c *Client) FetchAPICategories(id string) ([]string, error) {
+	if !c.SupportsCategories() {
+		return nil, ErrNoCategories
+	}
+
+	out := objects.APICategories{}
+	if err := c.categoriesRequest(http.MethodGet, urljoin.Join(endpointAPIs, id, "categories"), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Categories, nil
+}
+
+// SetAPICategories replaces the categories of the API with the database ID id
+func (c *Client) SetAPICategories(id string, categories []string) error {
+	if !c.SupportsCategories() {
+		return ErrNoCategories
+	}
+	if categories == nil {
+		categories = []string{}
+	}
+
+	return c.categoriesRequest(http.MethodPut, urljoin.Join(endpointAPIs, id, "categories"), objects.APICategories{Categories: categories}, nil)
+}
+
+// SupportsCategories tells whether the dashboard has the API categories endpoints, it is
+// only asked once
+func (c *Client) SupportsCategories() bool {
+	c.categoriesOnce.Do(func() {
+		_, err := c.FetchCategories()
+		c.hasCategories = err == nil
+	})
+	return c.hasCategories
+}
+
+// keepCategories gives def, if its name has no categories, the categories the API currently
+// has on the dashboard, unless SetReplaceCategories is set
+func (c *Client) keepCategories(def *objects.DBApiDefinition, current *objects.DBApiDefinition) {
+	if c.replaceCategories {
+		return
+	}
+
+	name, categories := objects.SplitCategories(def.Name)
+	if len(categories) > 0 {
+		return
+	}
+
+	_, categories = objects.SplitCategories(current.Name)
+	if len(categories) == 0 && c.SupportsCategories() {
+		categories, _ = c.FetchAPICategories(current.Id.Hex())
+	}
+	if len(categories) > 0 {
+		def.Name = objects.JoinCategories(name, categories)
+	}
+}
+
+// assignCategories files the API with the database ID id under the categories in the name
+// of def, on dashboards with the categories endpoints
+func (c *Client) assignCategories(id string, def *objects.DBApiDefinition) error {
+	if !c.SupportsCategories() {
+		return nil
+	}
+
+	_, categories := objects.SplitCategories(def.Name)
+	if err := c.SetAPICategories(id, categories); err != nil {
+		return fmt.Errorf("couldn't set the categories of %v: %v", def.APIID, err)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/levigross/grequests"
 	"github.com/ongoingio/urljoin"
 	"io"
 	"io/ioutil"
@@ -33,8 +34,7 @@ func (c *Client) CreateCertificate(cert []byte) (string, error) {
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 	req.Header.Set("Authorization", c.secret)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient().Do(req)
 
 	rBody, _ := ioutil.ReadAll(resp.Body)
 	if resp.StatusCode != 200 {
@@ -52,3 +52,31 @@ func (c *Client) CreateCertificate(cert []byte) (string, error) {
 
 	return dbResp.Id, nil
 }
+
+// FetchCertificate reads the meta data of a certificate, e.g. its expiry, the certificate
+// itself is not returned
+func (c *Client) FetchCertificate(id string) (*objects.CertificateMeta, error) {
+	fullPath := urljoin.Join(c.url, endpointCerts, id)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		Headers: map[string]string{
+			"Authorization": c.secret,
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	meta := &objects.CertificateMeta{}
+	if err := resp.JSON(meta); err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
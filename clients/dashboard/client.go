@@ -1,21 +1,40 @@
 package dashboard
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/TykTechnologies/tyk-sync/clients/objects"
 	"github.com/levigross/grequests"
 	"github.com/ongoingio/urljoin"
 )
 
+// Client talks to the dashboard API. It is safe for concurrent use by multiple goroutines
+// once configured, the Set methods should be called before the client is shared.
 type Client struct {
 	url                string
 	secret             string
 	isCloud            bool
 	InsecureSkipVerify bool
 	OrgID              string
+	cloudClient        *http.Client
+	planCheck          objects.PlanCheck
+	hooks              *objects.Hooks
+	adminSecret        string
+	policyIDMode       string
+	listOptions        ListOptions
+	progress           objects.Progress
+	replaceCategories  bool
+	deactivateRemoved  bool
+	// categoriesOnce detects the categories endpoints, see SupportsCategories
+	categoriesOnce sync.Once
+	hasCategories  bool
+	// mu guards cloudClient, which is built on first use
+	mu sync.Mutex
 }
 
 const (
@@ -23,6 +42,7 @@ const (
 	endpointPolicies string = "/api/portal/policies"
 	endpointCerts    string = "/api/certs"
 	endpointUsers    string = "/api/users"
+	endpointHello    string = "/hello"
 )
 
 var (
@@ -38,34 +58,46 @@ func NewDashboardClient(url, secret, orgID string) (*Client, error) {
 		isCloud: strings.Contains(url, "tyk.io"),
 	}
 
-	if orgID == "" {
-		fullPath := urljoin.Join(url, endpointUsers)
-
-		ro := &grequests.RequestOptions{
-			Params: map[string]string{"p": "-2"},
-			Headers: map[string]string{
-				"Authorization": secret,
-			},
-		}
+	if client.isCloud {
+		client.url = normaliseCloudURL(url)
+	}
 
-		resp, err := grequests.Get(fullPath, ro)
+	if orgID == "" && secret != "" {
+		// The org of the first user is enough, only the first page is read
+		err := client.fetchList(endpointUsers, "users", ListOptions{PageSize: 1}, func(raw json.RawMessage) error {
+			user := objects.User{}
+			if err := json.Unmarshal(raw, &user); err != nil {
+				return err
+			}
+			client.OrgID = user.OrgID
+			return errStopList
+		})
 		if err != nil {
-			return client, err
+			return client, fmt.Errorf("Error getting users from dashboard: %v", err)
 		}
+	}
 
-		if resp.StatusCode != 200 {
-			return client, fmt.Errorf("Error getting users from dashboard: %v for %v", resp.String(), fullPath)
-		}
+	return client, nil
+}
 
-		users := objects.UsersResponse{}
-		if err := resp.JSON(&users); err != nil {
-			return client, err
-		}
+// FetchVersion returns the version the dashboard reports on its health check endpoint
+func (c *Client) FetchVersion() (string, error) {
+	resp, err := grequests.Get(urljoin.Join(c.url, endpointHello), &grequests.RequestOptions{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	})
+	if err != nil {
+		return "", err
+	}
 
-		if len(users.Users) > 0 {
-			client.OrgID = users.Users[0].OrgID
-		}
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("API Returned error: %v", resp.String())
 	}
 
-	return client, nil
+	hello := objects.HealthCheck{}
+	if err := resp.JSON(&hello); err != nil {
+		return "", err
+	}
+
+	return hello.Version, nil
 }
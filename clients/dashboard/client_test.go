@@ -0,0 +1,518 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func dashboardServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case endpointAPIs:
+			w.Write([]byte(`{"apis": [{"api_definition": {"api_id": "a1", "name": "A"}}], "pages": 1}`))
+		case endpointPolicies:
+			w.Write([]byte(`{"Data": [{"id": "p1", "name": "P"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// Run with -race, the client must be usable from several goroutines at once
+func TestClientConcurrentUse(t *testing.T) {
+	ts := dashboardServer()
+	defer ts.Close()
+
+	for _, cloud := range []bool{false, true} {
+		c, err := NewDashboardClient(ts.URL, "secret", "org")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetCloud(cloud)
+
+		wg := sync.WaitGroup{}
+		errs := make(chan error, 8)
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				apis, err := c.FetchAPIs()
+				if err == nil && (len(apis) != 1 || apis[0].APIID != "a1") {
+					t.Errorf("unexpected APIs: %v", apis)
+				}
+				errs <- err
+
+				_, err = c.FetchPolicies()
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Errorf("cloud %v: %v", cloud, err)
+			}
+		}
+	}
+}
+
+func TestClientDo(t *testing.T) {
+	var auth, method string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, method = r.Header.Get("Authorization"), r.Method
+		if r.URL.Path != "/api/org/analytics" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"Message": "not found"}`))
+			return
+		}
+		w.Write([]byte(`{"enabled": true}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewDashboardClient(ts.URL, "secret", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := map[string]bool{}
+	if err := c.Do(http.MethodPut, "/api/org/analytics", map[string]bool{"enabled": true}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "secret" || method != http.MethodPut || !out["enabled"] {
+		t.Errorf("unexpected request or response: %v %v %v", auth, method, out)
+	}
+
+	if err := c.Do(http.MethodGet, "/api/missing", nil, nil); err == nil {
+		t.Error("expected an error for a 404")
+	}
+}
+
+func TestExplicitPolicyIDs(t *testing.T) {
+	withID := []objects.Policy{{ID: "p1", MID: bson.NewObjectId()}}
+	withoutID := []objects.Policy{{MID: bson.NewObjectId()}}
+
+	cases := []struct {
+		mode     string
+		existing []objects.Policy
+		explicit bool
+	}{
+		{PolicyIDsAuto, nil, true},
+		{PolicyIDsAuto, withID, true},
+		{PolicyIDsAuto, withoutID, false},
+		{"", withoutID, false},
+		{PolicyIDsExplicit, withoutID, true},
+		{PolicyIDsDatabase, withID, false},
+	}
+
+	for _, tc := range cases {
+		c := &Client{}
+		c.SetPolicyIDMode(tc.mode)
+		if got := c.explicitPolicyIDs(tc.existing); got != tc.explicit {
+			t.Errorf("mode %q with %v: expected explicit %v, got %v", tc.mode, tc.existing, tc.explicit, got)
+		}
+	}
+}
+
+func TestSyncPoliciesByDatabaseID(t *testing.T) {
+	mid := bson.NewObjectId()
+	var updated, created int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == endpointPolicies:
+			w.Write([]byte(`{"Data": [{"_id": "` + mid.Hex() + `", "name": "P"}]}`))
+		case r.Method == http.MethodPut && r.URL.Path == endpointPolicies+"/"+mid.Hex():
+			updated++
+			w.Write([]byte(`{"Status": "OK"}`))
+		case r.Method == http.MethodPost:
+			created++
+			w.Write([]byte(`{"Status": "OK"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewDashboardClient(ts.URL, "secret", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The explicit ID differs from the one on the dashboard, which doesn't keep it
+	if err := c.SyncPolicies([]objects.Policy{{ID: "p1", MID: mid, Name: "P"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if updated != 1 || created != 0 {
+		t.Fatalf("expected the policy to be updated by its _id, got %v updates and %v creates", updated, created)
+	}
+}
+
+func TestResetUserKey(t *testing.T) {
+	key := "old"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "admin" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/users":
+			if r.URL.Query().Get("p") != "-2" || r.URL.Query().Get("region") != "eu" {
+				t.Errorf("users not fetched with the list options: %v", r.URL)
+			}
+			w.Write([]byte(`{"users": [{"id": "u1", "email_address": "ci@example.com", "access_key": "` + key + `"}]}`))
+		case "PUT /api/users/u1/actions/key/reset":
+			key = "new"
+			w.Write([]byte(`{"Status": "OK", "Message": "User session renewed"}`))
+		case "GET /api/users/u1":
+			w.Write([]byte(`{"id": "u1", "email_address": "ci@example.com", "access_key": "` + key + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewDashboardClient(ts.URL, "admin", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetListOptions(ListOptions{Params: map[string]string{"region": "eu"}})
+
+	users, err := c.FetchUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].ID != "u1" || users[0].AccessKey != "old" {
+		t.Fatalf("unexpected users: %v", users)
+	}
+
+	newKey, err := c.ResetUserKey("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newKey != "new" {
+		t.Errorf("expected the new key, got %q", newKey)
+	}
+
+	if _, err := c.ResetUserKey("u2"); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+}
+
+func TestListOptions(t *testing.T) {
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		switch r.URL.Query().Get("p") {
+		case "-2":
+			w.Write([]byte(`{"apis": [{"api_definition": {"api_id": "a1"}}, {"api_definition": {"api_id": "a2"}}, {"api_definition": {"api_id": "a3"}}], "pages": 1}`))
+		case "1":
+			w.Write([]byte(`{"apis": [{"api_definition": {"api_id": "a1"}}, {"api_definition": {"api_id": "a2"}}], "pages": 2}`))
+		case "2":
+			w.Write([]byte(`{"apis": [{"api_definition": {"api_id": "a3"}}], "pages": 2}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewDashboardClient(ts.URL, "secret", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetListOptions(ListOptions{Params: map[string]string{"region": "eu"}})
+
+	apis, err := c.FetchAPIs()
+	if err != nil || len(apis) != 3 {
+		t.Fatalf("expected 3 APIs at once, got %v %v", len(apis), err)
+	}
+	if len(queries) != 1 || queries[0] != "p=-2&region=eu" {
+		t.Errorf("unexpected queries %v", queries)
+	}
+
+	queries = nil
+	apis, err = c.FetchAPIsWith(ListOptions{PageSize: 2, Params: map[string]string{"region": "us"}})
+	if err != nil || len(apis) != 3 || apis[2].APIID != "a3" {
+		t.Fatalf("expected 3 APIs over 2 pages, got %v %v", len(apis), err)
+	}
+	if len(queries) != 2 || queries[0] != "p=1&region=us" || queries[1] != "p=2&region=us" {
+		t.Errorf("unexpected queries %v", queries)
+	}
+}
+
+func TestEachAPIStreams(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case endpointAPIs:
+			w.Write([]byte(`{"pages": 1, "other": {"x": [1, 2]}, "apis": [{"api_definition": {"api_id": "a1"}}, {"api_definition": {"api_id": "a2", "name": "` + strings.Repeat("x", 200) + `"}}]}`))
+		case endpointPolicies:
+			w.Write([]byte(`{"Data": null, "Pages": 0}`))
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewDashboardClient(ts.URL, "secret", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := []string{}
+	err = c.EachAPI(ListOptions{}, func(api *objects.DBApiDefinition) error {
+		seen = append(seen, api.APIID)
+		return nil
+	})
+	if err != nil || len(seen) != 2 || seen[1] != "a2" {
+		t.Errorf("expected both APIs, got %v %v", seen, err)
+	}
+
+	seen = []string{}
+	err = c.EachAPI(ListOptions{MaxObjectSize: 100}, func(api *objects.DBApiDefinition) error {
+		seen = append(seen, api.APIID)
+		return nil
+	})
+	if err == nil || len(seen) != 1 {
+		t.Errorf("expected the oversized API to fail the list after the first, got %v %v", seen, err)
+	}
+
+	pols, err := c.FetchPolicies()
+	if err != nil || len(pols) != 0 {
+		t.Errorf("expected no policies, got %v %v", pols, err)
+	}
+}
+
+func TestOAuthClients(t *testing.T) {
+	registered := []objects.OAuthClient{{ClientID: "c1", Secret: "s1", RedirectURI: "http://app/cb"}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/apis/oauth/a1/clients":
+			json.NewEncoder(w).Encode(map[string]interface{}{"apps": registered})
+		case "GET /api/apis/oauth/paged/clients":
+			// Pages of one client, as a dashboard configured with page_size 1 lists them
+			page, _ := strconv.Atoi(r.URL.Query().Get("p"))
+			if page < 1 || page > 2 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"apps": []objects.OAuthClient{{ClientID: fmt.Sprintf("p%v", page)}}, "pages": 2})
+		case "GET /api/apis/oauth/plain/clients":
+			json.NewEncoder(w).Encode([]objects.OAuthClient{{ClientID: "c9", APIID: "other"}})
+		case "POST /api/apis/oauth/a1/clients":
+			client := objects.OAuthClient{}
+			json.NewDecoder(r.Body).Decode(&client)
+			if client.ClientID == "" {
+				client.ClientID, client.Secret = "generated", "generated-secret"
+			}
+			registered = append(registered, client)
+			json.NewEncoder(w).Encode(client)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewDashboardClient(ts.URL, "admin", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clients, err := c.FetchOAuthClients("a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 1 || clients[0].ClientID != "c1" || clients[0].Secret != "s1" || clients[0].APIID != "a1" {
+		t.Fatalf("unexpected clients: %+v", clients)
+	}
+
+	if err := c.CreateOAuthClient(&objects.OAuthClient{ClientID: "c2", Secret: "s2", APIID: "a1"}); err != nil {
+		t.Fatal(err)
+	}
+	generated := &objects.OAuthClient{APIID: "a1", RedirectURI: "http://other/cb"}
+	if err := c.CreateOAuthClient(generated); err != nil {
+		t.Fatal(err)
+	}
+	if generated.ClientID != "generated" || generated.Secret != "generated-secret" {
+		t.Errorf("expected the generated credentials, got %+v", generated)
+	}
+	if len(registered) != 3 || registered[1].ClientID != "c2" || registered[1].Secret != "s2" {
+		t.Errorf("expected the client registered under its ID and secret, got %+v", registered)
+	}
+
+	if clients, err := c.FetchOAuthClients("plain"); err != nil || len(clients) != 1 || clients[0].APIID != "other" {
+		t.Errorf("expected the clients of a plain list, got %+v %v", clients, err)
+	}
+
+	c.SetListOptions(ListOptions{PageSize: 1})
+	if clients, err := c.FetchOAuthClients("paged"); err != nil || len(clients) != 2 || clients[1].ClientID != "p2" || clients[1].APIID != "paged" {
+		t.Errorf("expected the clients of both pages, got %+v %v", clients, err)
+	}
+
+	if _, err := c.FetchOAuthClients("a2"); err == nil {
+		t.Error("expected an error for an unknown API")
+	}
+	if err := c.CreateOAuthClient(&objects.OAuthClient{ClientID: "c3"}); err == nil {
+		t.Error("expected a client without an API refused")
+	}
+}
+
+func TestEachKey(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != endpointKeysDetailed {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.URL.Query().Get("p") {
+		case "1":
+			w.Write([]byte(`{"keys": [{"key_id": "k1", "data": {"apply_policies": ["p1"]}}, {"key_id": "k2", "data": {}}], "pages": 2}`))
+		case "2":
+			w.Write([]byte(`{"keys": [{"key_id": "k3", "data": {"apply_policy_id": "p2"}}], "pages": 2}`))
+		default:
+			t.Errorf("unexpected page %v", r.URL)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewDashboardClient(ts.URL, "admin", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetListOptions(ListOptions{PageSize: 2})
+
+	keys := []objects.Key{}
+	err = c.EachKey(ListOptions{}, func(k *objects.Key) error {
+		keys = append(keys, *k)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 || keys[0].KeyID != "k1" || keys[2].Session["apply_policy_id"] != "p2" {
+		t.Errorf("unexpected keys: %+v", keys)
+	}
+}
+
+func TestNewDashboardClientOrg(t *testing.T) {
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Path+"?"+r.URL.RawQuery)
+		switch r.URL.Path {
+		case endpointUsers:
+			w.Write([]byte(`{"users": [{"id": "u1", "org_id": "org1"}, {"id": "u2", "org_id": "org1"}], "pages": 3}`))
+		case endpointAPIs + "/a1":
+			w.Write([]byte(`{"api_definition": {"api_id": "a1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewDashboardClient(ts.URL, "secret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.OrgID != "org1" {
+		t.Errorf("expected the org of the first user, got %q", c.OrgID)
+	}
+
+	if api, err := c.FetchAPI("a1"); err != nil || api.APIID != "a1" {
+		t.Fatalf("expected the API, got %+v %v", api, err)
+	}
+
+	// Only the first page of users is read, and an API isn't a list
+	if expected := []string{"/api/users?p=1", "/api/apis/a1?"}; strings.Join(queries, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v, got %v", expected, queries)
+	}
+}
+
+func TestFetchRawLists(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case endpointAPIs:
+			w.Write([]byte(`{"apis": [{"api_definition": {"api_id": "a1", "unknown": 1}}, {"api_definition": {"api_id": "a2"}}], "pages": 1}`))
+		case endpointPolicies:
+			w.Write([]byte(`{"Data": [{"_id": "5e9d9544a1dcd60001d0ed21", "id": "p1", "unknown": true}], "Pages": 1}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewDashboardClient(ts.URL, "secret", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests = 0
+	apis, err := c.FetchAPIsRaw()
+	if err != nil || len(apis) != 2 || apis["a1"]["unknown"] != float64(1) {
+		t.Errorf("expected both APIs with their unknown fields, got %v %v", apis, err)
+	}
+	pols, err := c.FetchPoliciesRaw()
+	if err != nil || len(pols) != 1 || pols[0]["unknown"] != true {
+		t.Errorf("expected the policy with its unknown fields, got %v %v", pols, err)
+	}
+	if requests != 2 {
+		t.Errorf("expected one list call per kind, got %v requests", requests)
+	}
+}
+
+func TestAdminEndpoints(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("admin-auth") != "admin" || r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case endpointAdminLicence:
+			w.Write([]byte(`{"valid": true, "type": "production", "expires_at": "2027-01-02T00:00:00Z", "nodes_allowed": 4}`))
+		case endpointAdminNodes:
+			w.Write([]byte(`{"nodes": [{"node_id": "n1", "hostname": "gw-1", "version": "v2.9.4", "last_heartbeat": "2026-10-15T10:00:00Z", "apis_checksum": "abc"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewDashboardClient(ts.URL, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.FetchLicence(); err == nil {
+		t.Error("expected the admin secret to be required")
+	}
+
+	c.SetAdminSecret("admin")
+	licence, err := c.FetchLicence()
+	if err != nil || !licence.Valid || licence.NodesAllowed != 4 || licence.ExpiresAt.Year() != 2027 {
+		t.Errorf("unexpected licence %+v %v", licence, err)
+	}
+	nodes, err := c.FetchGatewayNodes()
+	if err != nil || len(nodes) != 1 || nodes[0].Hostname != "gw-1" || nodes[0].Checksum != "abc" {
+		t.Errorf("unexpected nodes %+v %v", nodes, err)
+	}
+
+	c.SetAdminSecret("wrong")
+	if _, err := c.FetchLicence(); err == nil || !strings.Contains(err.Error(), "admin_secret") {
+		t.Errorf("expected the refused secret to be reported, got %v", err)
+	}
+
+	// Dashboards without the endpoints say so rather than failing to decode a page
+	other := httptest.NewServer(http.NotFoundHandler())
+	defer other.Close()
+	c, _ = NewDashboardClient(other.URL, "", "")
+	c.SetAdminSecret("admin")
+	if _, err := c.FetchGatewayNodes(); err == nil || !strings.Contains(err.Error(), ErrNoAdminEndpoint.Error()) {
+		t.Errorf("expected the missing endpoint to be reported, got %v", err)
+	}
+}
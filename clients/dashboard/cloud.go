@@ -0,0 +1,162 @@
+package dashboard
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/clients/transport"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+const (
+	// cloudRequestInterval is the minimum gap between two requests to a Tyk Cloud dashboard
+	cloudRequestInterval = 100 * time.Millisecond
+	// cloudMaxRetries is how many times a rate limited request is retried before giving up
+	cloudMaxRetries = 5
+)
+
+// cloudTransport spaces out requests and retries those rejected with a 429,
+// Tyk Cloud applies rate limits to the dashboard API which a large sync will easily hit
+type cloudTransport struct {
+	base http.RoundTripper
+	mu   sync.Mutex
+	last time.Time
+}
+
+func (t *cloudTransport) wait() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if d := cloudRequestInterval - time.Since(t.last); d > 0 {
+		time.Sleep(d)
+	}
+	t.last = time.Now()
+}
+
+func retryAfter(resp *http.Response, attempt int) time.Duration {
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+
+	return time.Duration(1<<uint(attempt)) * time.Second
+}
+
+func (t *cloudTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		t.wait()
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt >= cloudMaxRetries {
+			return resp, err
+		}
+
+		if req.Body != nil && req.GetBody == nil {
+			// Can't replay the body, let the caller deal with the 429
+			return resp, nil
+		}
+
+		wait := retryAfter(resp, attempt)
+		resp.Body.Close()
+		fmt.Printf("--> Rate limited by Tyk Cloud, retrying in %v\n", wait)
+		time.Sleep(wait)
+
+		req = req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// SetCloud forces (or disables) Tyk Cloud behaviour, by default this is detected from the dashboard URL
+func (c *Client) SetCloud(val bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.isCloud = val
+	c.cloudClient = nil
+
+	if val {
+		c.url = normaliseCloudURL(c.url)
+	}
+}
+
+// IsCloud reports whether the client is targeting a Tyk Cloud dashboard
+func (c *Client) IsCloud() bool {
+	return c.isCloud
+}
+
+// httpClient returns the HTTP client for requests, all clients share their connections
+// but a Tyk Cloud client spaces out its own requests
+func (c *Client) httpClient() *http.Client {
+	if !c.isCloud {
+		return transport.Client(c.InsecureSkipVerify)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cloudClient == nil {
+		c.cloudClient = &http.Client{
+			Transport: &cloudTransport{
+				base: transport.RoundTripper(c.InsecureSkipVerify),
+			},
+			CheckRedirect: transport.CheckRedirect,
+		}
+	}
+
+	return c.cloudClient
+}
+
+// normaliseCloudURL makes sure cloud dashboard URLs are served over https and carry no trailing slash
+func normaliseCloudURL(url string) string {
+	if !strings.Contains(url, "://") {
+		url = "https://" + url
+	}
+
+	return strings.TrimRight(url, "/")
+}
+
+// CheckCloudRestrictions returns a list of problems for definitions that use features
+// which are not available on Tyk Cloud, these would otherwise fail half way through a sync.
+// Fields stripped from the payload are not checked.
+func CheckCloudRestrictions(defs []objects.DBApiDefinition) []error {
+	problems := []error{}
+	for _, def := range defs {
+		if def.APIDefinition == nil {
+			continue
+		}
+
+		name := def.Name
+		if name == "" {
+			name = def.APIID
+		}
+
+		if def.ListenPort != 0 && !def.Stripped("listen_port") {
+			problems = append(problems, fmt.Errorf("API %v: custom listen ports (listen_port) are not supported on Tyk Cloud", name))
+		}
+
+		if (def.Protocol == "tcp" || def.Protocol == "tls") && !def.Stripped("protocol") {
+			problems = append(problems, fmt.Errorf("API %v: TCP proxying (protocol: %v) is not supported on Tyk Cloud", name, def.Protocol))
+		}
+
+		if def.EnableProxyProtocol && !def.Stripped("enable_proxy_protocol") {
+			problems = append(problems, fmt.Errorf("API %v: enable_proxy_protocol is not supported on Tyk Cloud", name))
+		}
+
+		switch def.CustomMiddleware.Driver {
+		case apidef.PythonDriver, apidef.LuaDriver, apidef.GrpcDriver:
+			problems = append(problems, fmt.Errorf("API %v: the %v plugin driver is not supported on Tyk Cloud", name, def.CustomMiddleware.Driver))
+		}
+	}
+
+	return problems
+}
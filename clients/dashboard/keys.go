@@ -0,0 +1,65 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/levigross/grequests"
+	"github.com/ongoingio/urljoin"
+)
+
+const (
+	endpointKeys         string = "/api/keys"
+	endpointKeysDetailed string = "/api/keys/detailed"
+)
+
+// CreateKey creates (or overwrites) a key with the given ID. The dashboard can't store a
+// key under its hash, hashed keys can only be restored to a gateway.
+func (c *Client) CreateKey(key *objects.Key) error {
+	if key.KeyID == "" {
+		return errors.New("Key ID must be set")
+	}
+
+	if key.Hashed {
+		return errors.New("hashed keys can't be created through the dashboard, restore them to a gateway")
+	}
+
+	fullPath := urljoin.Join(c.url, endpointKeys, key.KeyID)
+
+	ro := &grequests.RequestOptions{
+		JSON: key.Session,
+		Headers: map[string]string{
+			"Authorization": c.secret,
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	}
+
+	resp, err := grequests.Post(fullPath, ro)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("API Returned error: %v (code: %v)", resp.String(), resp.StatusCode)
+	}
+
+	return nil
+}
+
+// EachKey streams the keys of the org, with their sessions, to each as they are read, like
+// EachAPI. The dashboard lists them with their hashes as IDs if it hashes keys.
+func (c *Client) EachKey(opts ListOptions, each func(key *objects.Key) error) error {
+	return c.fetchList(endpointKeysDetailed, "keys", c.listOptions.Merge(opts), func(raw json.RawMessage) error {
+		detailed := struct {
+			KeyID string                 `json:"key_id"`
+			Data  map[string]interface{} `json:"data"`
+		}{}
+		if err := json.Unmarshal(raw, &detailed); err != nil {
+			return err
+		}
+		return each(&objects.Key{KeyID: detailed.KeyID, Session: detailed.Data})
+	})
+}
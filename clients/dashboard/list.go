@@ -0,0 +1,245 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/levigross/grequests"
+	"github.com/ongoingio/urljoin"
+)
+
+// maxPages stops paged list calls that never end, e.g. behind a proxy ignoring the page
+const maxPages = 10000
+
+// errStopList is returned by the callback of a list to stop it without an error, once it
+// has what it needs
+var errStopList = errors.New("stop the list")
+
+// ListOptions set the query parameters of the list calls (APIs and policies). By default
+// lists are fetched in one response, with p=-2.
+type ListOptions struct {
+	// Params are sent with every list call, e.g. for a proxy in front of the dashboard
+	Params map[string]string `json:"params,omitempty"`
+	// PageSize fetches lists page by page (p=1, 2, ...) instead, for proxies that limit the
+	// size of responses. It is the page_size the dashboard is configured with, a shorter
+	// page is the last one.
+	PageSize int `json:"page_size,omitempty"`
+	// MaxObjectSize caps the memory a list takes, lists are decoded one object at a time and
+	// an object larger than this many bytes of JSON fails the list. Zero means no limit.
+	MaxObjectSize int `json:"max_object_size,omitempty"`
+}
+
+// Merge returns the options with those set in over replacing them, params are merged one
+// by one
+func (o ListOptions) Merge(over ListOptions) ListOptions {
+	merged := ListOptions{PageSize: o.PageSize, MaxObjectSize: o.MaxObjectSize}
+	if over.PageSize != 0 {
+		merged.PageSize = over.PageSize
+	}
+	if over.MaxObjectSize != 0 {
+		merged.MaxObjectSize = over.MaxObjectSize
+	}
+
+	if len(o.Params)+len(over.Params) > 0 {
+		merged.Params = map[string]string{}
+	}
+	for k, v := range o.Params {
+		merged.Params[k] = v
+	}
+	for k, v := range over.Params {
+		merged.Params[k] = v
+	}
+
+	return merged
+}
+
+// SetListOptions sets the options of every list call, FetchAPIsWith and FetchPoliciesWith
+// override them per call
+func (c *Client) SetListOptions(opts ListOptions) {
+	c.listOptions = opts
+}
+
+// params returns the query parameters of a page, page is 0 for lists fetched at once
+func (o ListOptions) params(page int) map[string]string {
+	params := map[string]string{"p": "-2"}
+	if page > 0 {
+		params["p"] = strconv.Itoa(page)
+	}
+	for k, v := range o.Params {
+		params[k] = v
+	}
+
+	return params
+}
+
+// decodeItems streams the objects of the list dec is in to each, up to the end of the list
+func decodeItems(dec *json.Decoder, maxSize int, each func(raw json.RawMessage) error) (int, error) {
+	n := 0
+	for dec.More() {
+		raw := json.RawMessage{}
+		if err := dec.Decode(&raw); err != nil {
+			return n, err
+		}
+		if maxSize > 0 && len(raw) > maxSize {
+			return n, fmt.Errorf("object %v of the list is %v bytes of JSON, over the limit of %v", n, len(raw), maxSize)
+		}
+		if err := each(raw); err != nil {
+			return n, err
+		}
+		n++
+	}
+	_, err := dec.Token()
+	return n, err
+}
+
+// decodeList streams the objects of the list under key of a response body to each, one at
+// a time, and returns how many there were and the pages the response reports. A body that
+// is a plain list is streamed as it is, as one page.
+func decodeList(r io.Reader, key string, maxSize int, each func(raw json.RawMessage) error) (int, int, error) {
+	dec := json.NewDecoder(r)
+	if t, err := dec.Token(); err != nil {
+		return 0, 0, err
+	} else if t == json.Delim('[') {
+		n, err := decodeItems(dec, maxSize, each)
+		return n, 1, err
+	} else if t != json.Delim('{') {
+		return 0, 0, fmt.Errorf("expected a JSON object, got %v", t)
+	}
+
+	n, pages := 0, 0
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return n, pages, err
+		}
+		name, _ := t.(string)
+
+		switch {
+		case strings.EqualFold(name, "pages"):
+			if err := dec.Decode(&pages); err != nil {
+				return n, pages, err
+			}
+		case strings.EqualFold(name, key):
+			if t, err := dec.Token(); err != nil {
+				return n, pages, err
+			} else if t == nil {
+				// null, an empty list
+				continue
+			} else if t != json.Delim('[') {
+				return n, pages, fmt.Errorf("expected a list of %v, got %v", key, t)
+			}
+
+			if n, err = decodeItems(dec, maxSize, each); err != nil {
+				return n, pages, err
+			}
+		default:
+			skip := json.RawMessage{}
+			if err := dec.Decode(&skip); err != nil {
+				return n, pages, err
+			}
+		}
+	}
+
+	return n, pages, nil
+}
+
+// fetchList gets a list endpoint page by page and streams the objects under key to each
+func (c *Client) fetchList(endpoint, key string, opts ListOptions, each func(raw json.RawMessage) error) error {
+	fullPath := urljoin.Join(c.url, endpoint)
+
+	page := 0
+	if opts.PageSize > 0 {
+		page = 1
+	}
+
+	for {
+		resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+			Params: opts.params(page),
+			Headers: map[string]string{
+				"Authorization": c.secret,
+			},
+			InsecureSkipVerify: c.InsecureSkipVerify,
+			HTTPClient:         c.httpClient(),
+		})
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode != 200 {
+			return fmt.Errorf("API Returned error: %v for %v", resp.String(), fullPath)
+		}
+
+		n, pages, err := decodeList(resp, key, opts.MaxObjectSize, each)
+		resp.Close()
+		if err == errStopList {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading %v: %v", fullPath, err)
+		}
+
+		if page == 0 || n < opts.PageSize || (pages > 0 && page >= pages) {
+			return nil
+		}
+		if page >= maxPages {
+			return fmt.Errorf("stopped listing %v after %v pages", fullPath, maxPages)
+		}
+		page++
+	}
+}
+
+// EachAPI streams the APIs to each as they are read, so that only one is held in memory at
+// a time, opts override the options of the client. An error of each stops the list.
+func (c *Client) EachAPI(opts ListOptions, each func(api *objects.DBApiDefinition) error) error {
+	return c.fetchList(endpointAPIs, "apis", c.listOptions.Merge(opts), func(raw json.RawMessage) error {
+		api := &objects.DBApiDefinition{}
+		if err := json.Unmarshal(raw, api); err != nil {
+			return err
+		}
+		return each(api)
+	})
+}
+
+// EachPolicy streams the policies to each as they are read, like EachAPI
+func (c *Client) EachPolicy(opts ListOptions, each func(pol *objects.Policy) error) error {
+	return c.fetchList(endpointPolicies, "Data", c.listOptions.Merge(opts), func(raw json.RawMessage) error {
+		pol := &objects.Policy{}
+		if err := json.Unmarshal(raw, pol); err != nil {
+			return err
+		}
+		return each(pol)
+	})
+}
+
+// FetchAPIsWith lists the APIs, opts override the options of the client
+func (c *Client) FetchAPIsWith(opts ListOptions) ([]objects.DBApiDefinition, error) {
+	apis := []objects.DBApiDefinition{}
+	err := c.EachAPI(opts, func(api *objects.DBApiDefinition) error {
+		apis = append(apis, *api)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return apis, nil
+}
+
+// FetchPoliciesWith lists the policies, opts override the options of the client
+func (c *Client) FetchPoliciesWith(opts ListOptions) ([]objects.Policy, error) {
+	pols := []objects.Policy{}
+	err := c.EachPolicy(opts, func(pol *objects.Policy) error {
+		pols = append(pols, *pol)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pols, nil
+}
@@ -0,0 +1,77 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/levigross/grequests"
+	"github.com/ongoingio/urljoin"
+)
+
+const endpointOAuth string = "/api/apis/oauth"
+
+func (c *Client) oauthClientsPath(apiID string) string {
+	return urljoin.Join(c.url, endpointOAuth, apiID, "clients")
+}
+
+// FetchOAuthClients returns the OAuth clients registered with the API with the API ID apiID,
+// listed with the list options of the client
+func (c *Client) FetchOAuthClients(apiID string) ([]objects.OAuthClient, error) {
+	// Dashboards list the clients as a plain array or as the apps of a page
+	clients := []objects.OAuthClient{}
+	err := c.fetchList(urljoin.Join(endpointOAuth, apiID, "clients"), "apps", c.listOptions, func(raw json.RawMessage) error {
+		client := objects.OAuthClient{}
+		if err := json.Unmarshal(raw, &client); err != nil {
+			return err
+		}
+		if client.APIID == "" {
+			client.APIID = apiID
+		}
+		clients = append(clients, client)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+// CreateOAuthClient registers the client with its API, under its client ID and secret if
+// they are set, the dashboard generates them otherwise and the client is updated with them
+func (c *Client) CreateOAuthClient(client *objects.OAuthClient) error {
+	if client.APIID == "" {
+		return errors.New("the API ID of the OAuth client must be set")
+	}
+
+	fullPath := c.oauthClientsPath(client.APIID)
+	resp, err := grequests.Post(fullPath, &grequests.RequestOptions{
+		JSON: client,
+		Headers: map[string]string{
+			"Authorization": c.secret,
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("API Returned error: %v (code: %v)", resp.String(), resp.StatusCode)
+	}
+
+	created := objects.OAuthClient{}
+	if err := resp.JSON(&created); err == nil {
+		if client.ClientID == "" {
+			client.ClientID = created.ClientID
+		}
+		if client.Secret == "" {
+			client.Secret = created.Secret
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package dashboard
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/TykTechnologies/tyk-sync/clients/objects"
@@ -10,51 +11,69 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
-type PoliciesData struct {
-	Data  []objects.Policy
-	Pages int
-}
+const (
+	// PolicyIDsAuto matches policies by their explicit ID, unless none of the policies on
+	// the dashboard has one
+	PolicyIDsAuto = "auto"
+	// PolicyIDsExplicit matches policies by their explicit ID (id), the dashboard must
+	// have allow_explicit_policy_id enabled
+	PolicyIDsExplicit = "explicit"
+	// PolicyIDsDatabase matches policies by their database ID (_id)
+	PolicyIDsDatabase = "database"
+)
 
-func (c *Client) FetchPolicies() ([]objects.Policy, error) {
-	fullPath := urljoin.Join(c.url, endpointPolicies)
+// SetPolicyIDMode sets which ID policies are matched by, one of the PolicyIDs modes
+func (c *Client) SetPolicyIDMode(mode string) {
+	c.policyIDMode = mode
+}
 
-	ro := &grequests.RequestOptions{
-		Params: map[string]string{"p": "-2"},
-		Headers: map[string]string{
-			"Authorization": c.secret,
-		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
+// explicitPolicyIDs tells whether policies are matched by their explicit ID. In auto mode
+// a dashboard without allow_explicit_policy_id is told by its policies: they don't keep
+// the id they were created with.
+func (c *Client) explicitPolicyIDs(existing []objects.Policy) bool {
+	switch c.policyIDMode {
+	case PolicyIDsExplicit:
+		return true
+	case PolicyIDsDatabase:
+		return false
 	}
 
-	resp, err := grequests.Get(fullPath, ro)
-	if err != nil {
-		return nil, err
+	if len(existing) == 0 {
+		return true
 	}
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	for _, pol := range existing {
+		if pol.ID != "" {
+			return true
+		}
 	}
 
-	policies := PoliciesData{}
-	if err := resp.JSON(&policies); err != nil {
-		return nil, err
-	}
+	return false
+}
+
+type PoliciesData struct {
+	Data  []objects.Policy
+	Pages int
+}
 
-	return policies.Data, nil
+func (c *Client) FetchPolicies() ([]objects.Policy, error) {
+	return c.FetchPoliciesWith(ListOptions{})
 }
 
-func (c *Client) CreatePolicy(pol *objects.Policy) (string, error) {
+func (c *Client) createPolicy(pol *objects.Policy) (string, error) {
 	existingPols, err := c.FetchPolicies()
 	if err != nil {
 		return "", err
 	}
 
+	pol.MID = objects.NormalID(pol.MID)
+	explicit := c.explicitPolicyIDs(existingPols)
 	for _, ePol := range existingPols {
-		if ePol.MID.Hex() == pol.MID.Hex() {
+		if pol.MID != "" && ePol.MID == pol.MID {
 			return "", UsePolUpdateError
 		}
 
-		if ePol.ID == pol.ID {
+		if explicit && ePol.ID == pol.ID {
 			return "", UsePolUpdateError
 		}
 	}
@@ -67,6 +86,7 @@ func (c *Client) CreatePolicy(pol *objects.Policy) (string, error) {
 			"Authorization": c.secret,
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	}
 
 	resp, err := grequests.Post(fullPath, ro)
@@ -90,7 +110,7 @@ func (c *Client) CreatePolicy(pol *objects.Policy) (string, error) {
 	return dbResp.Meta, nil
 }
 
-func (c *Client) DeletePolicy(id string) error {
+func (c *Client) deletePolicy(id string) error {
 	fullPath := urljoin.Join(c.url, endpointPolicies, id)
 
 	ro := &grequests.RequestOptions{
@@ -98,6 +118,7 @@ func (c *Client) DeletePolicy(id string) error {
 			"Authorization": c.secret,
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	}
 
 	resp, err := grequests.Delete(fullPath, ro)
@@ -120,6 +141,7 @@ func (c *Client) FetchPolicy(id string) (*objects.Policy, error) {
 			"Authorization": c.secret,
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	}
 
 	resp, err := grequests.Get(fullPath, ro)
@@ -139,26 +161,28 @@ func (c *Client) FetchPolicy(id string) (*objects.Policy, error) {
 	return &pol, nil
 }
 
-func (c *Client) UpdatePolicy(pol *objects.Policy) error {
+func (c *Client) updatePolicy(pol *objects.Policy) error {
 	existingPols, err := c.FetchPolicies()
 	if err != nil {
 		return err
 	}
 
-	if pol.MID.Hex() == "" && pol.ID == "" {
+	pol.MID = objects.NormalID(pol.MID)
+	if pol.MID == "" && pol.ID == "" {
 		return errors.New("--> Can't update policy without an ID or explicit (legacy) ID")
 	}
 
+	explicit := c.explicitPolicyIDs(existingPols)
 	found := false
 	for _, ePol := range existingPols {
-		if ePol.ID == pol.ID {
+		if explicit && ePol.ID == pol.ID {
 			fmt.Println("--> Found policy using explicit ID, substituting remote ID for update")
 			pol.MID = ePol.MID
 			found = true
 			break
 		}
 
-		if ePol.MID.Hex() == pol.MID.Hex() {
+		if pol.MID != "" && ePol.MID == pol.MID {
 			found = true
 			break
 		}
@@ -176,6 +200,7 @@ func (c *Client) UpdatePolicy(pol *objects.Policy) error {
 			"Authorization": c.secret,
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	}
 
 	resp, err := grequests.Put(fullPath, ro)
@@ -201,6 +226,7 @@ func (c *Client) UpdatePolicy(pol *objects.Policy) error {
 
 func (c *Client) SyncPolicies(pols []objects.Policy) error {
 	deletePols := []string{}
+	deleteNames := []string{}
 	updatePols := []objects.Policy{}
 	createPols := []objects.Policy{}
 
@@ -214,10 +240,15 @@ func (c *Client) SyncPolicies(pols []objects.Policy) error {
 	DashIDMap := map[string]int{}
 	GitIDMap := map[string]int{}
 
+	explicit := c.explicitPolicyIDs(ePols)
+	if !explicit {
+		fmt.Println("--> Matching policies by their database ID (_id)")
+	}
+
 	// Build the dash ID map
 	for i, pol := range ePols {
 		// Lets get a full list of existing IDs
-		if pol.ID != "" {
+		if explicit && pol.ID != "" {
 			DashIDMap[pol.ID] = i
 		} else {
 			DashIDMap[pol.MID.Hex()] = i
@@ -226,11 +257,14 @@ func (c *Client) SyncPolicies(pols []objects.Policy) error {
 
 	// Build the Git ID Map
 	for i, pol := range pols {
-		if pol.ID != "" {
+		if explicit && pol.ID != "" {
 			GitIDMap[pol.ID] = i
-		} else if pol.MID.Hex() != "" {
+		} else if !objects.EmptyID(pol.MID) {
 			GitIDMap[pol.MID.Hex()] = i
 		} else {
+			if !explicit {
+				fmt.Printf("--> [WARNING] Policy %v has no _id to match it by, it will be created again\n", pol.Name)
+			}
 			created := fmt.Sprintf("temp-pol-%v", uuid.NewV4().String())
 			GitIDMap[created] = i
 		}
@@ -252,6 +286,7 @@ func (c *Client) SyncPolicies(pols []objects.Policy) error {
 		_, ok := GitIDMap[key]
 		if !ok {
 			deletePols = append(deletePols, ePols[i].MID.Hex())
+			deleteNames = append(deleteNames, ePols[i].Name)
 		}
 	}
 
@@ -263,27 +298,54 @@ func (c *Client) SyncPolicies(pols []objects.Policy) error {
 		}
 	}
 
+	if c.planCheck != nil {
+		plan := &objects.SyncPlan{Kind: "policies", Existing: len(ePols)}
+		for i, id := range deletePols {
+			plan.Delete = append(plan.Delete, objects.SyncItem{ID: id, Name: deleteNames[i], Index: i})
+		}
+		for i, pol := range updatePols {
+			plan.Update = append(plan.Update, objects.SyncItem{ID: pol.ID, Name: pol.Name, Index: i})
+		}
+		for i, pol := range createPols {
+			plan.Create = append(plan.Create, objects.SyncItem{ID: pol.ID, Name: pol.Name, Index: i})
+		}
+
+		if err := c.planCheck(plan); err != nil {
+			return err
+		}
+
+		deletePols = objects.KeepIDs(deletePols, plan.Delete)
+		updatePols = objects.KeepPolicies(updatePols, plan.Update)
+		createPols = objects.KeepPolicies(createPols, plan.Create)
+	}
+
 	fmt.Printf("Deleting policies: %v\n", len(deletePols))
 	fmt.Printf("Updating policies: %v\n", len(updatePols))
 	fmt.Printf("Creating policies: %v\n", len(createPols))
+	progress := objects.TrackProgress(c.progress, "policies", len(deletePols)+len(updatePols)+len(createPols))
 
 	// Do the deletes
+	progress.Phase(objects.PhaseDelete, len(deletePols))
 	for _, dbId := range deletePols {
 		fmt.Printf("SYNC Deleting Policy: %v\n", dbId)
 		if err := c.DeletePolicy(dbId); err != nil {
 			return err
 		}
+		progress.Done(dbId)
 	}
 
 	// Do the updates
+	progress.Phase(objects.PhaseUpdate, len(updatePols))
 	for _, pol := range updatePols {
 		fmt.Printf("SYNC Updating Policy: %v\n", pol.Name)
 		if err := c.UpdatePolicy(&pol); err != nil {
 			return err
 		}
+		progress.Done(pol.Name)
 	}
 
 	// Do the creates
+	progress.Phase(objects.PhaseCreate, len(createPols))
 	for _, pol := range createPols {
 		fmt.Printf("SYNC Creating Policy: %v\n", pol.Name)
 		var err error
@@ -296,7 +358,56 @@ func (c *Client) SyncPolicies(pols []objects.Policy) error {
 			intID = pol.ID
 		}
 		fmt.Printf("--> ID: %v (%v)\n", id, intID)
+		progress.Done(pol.Name)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// FetchPoliciesRaw returns the policies as stored by the dashboard, in one list call, see
+// FetchPolicyRaw
+func (c *Client) FetchPoliciesRaw() ([]map[string]interface{}, error) {
+	pols := []map[string]interface{}{}
+	err := c.fetchList(endpointPolicies, "Data", c.listOptions, func(raw json.RawMessage) error {
+		pol := map[string]interface{}{}
+		if err := json.Unmarshal(raw, &pol); err != nil {
+			return err
+		}
+		pols = append(pols, pol)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pols, nil
+}
+
+// FetchPolicyRaw returns the policy as stored by the dashboard, see FetchAPIRaw
+func (c *Client) FetchPolicyRaw(id string) (map[string]interface{}, error) {
+	fullPath := urljoin.Join(c.url, endpointPolicies, id)
+
+	ro := &grequests.RequestOptions{
+		Headers: map[string]string{
+			"Authorization": c.secret,
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	}
+
+	resp, err := grequests.Get(fullPath, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	raw := map[string]interface{}{}
+	if err := resp.JSON(&raw); err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
@@ -0,0 +1,41 @@
+package dashboard
+
+import (
+	"fmt"
+
+	"github.com/levigross/grequests"
+	"github.com/ongoingio/urljoin"
+)
+
+// Do sends a request to any dashboard API endpoint, for those the client doesn't wrap yet.
+// The client's secret and transport are used, body (if not nil) is sent as JSON and out
+// (if not nil) is decoded from the JSON response. Responses other than 2xx are returned
+// as errors.
+func (c *Client) Do(method, path string, body, out interface{}) error {
+	ro := &grequests.RequestOptions{
+		Headers: map[string]string{
+			"Authorization": c.secret,
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	}
+	if body != nil {
+		ro.JSON = body
+	}
+
+	fullPath := urljoin.Join(c.url, path)
+	resp, err := grequests.Req(method, fullPath, ro)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("API Returned error: %v (code: %v) for %v %v", resp.String(), resp.StatusCode, method, fullPath)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return resp.JSON(out)
+}
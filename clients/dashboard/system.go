@@ -0,0 +1,78 @@
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/levigross/grequests"
+	"github.com/ongoingio/urljoin"
+)
+
+const (
+	endpointAdminLicence string = "/admin/license"
+	endpointAdminNodes   string = "/admin/system/nodes"
+)
+
+// ErrNoAdminEndpoint is returned when the dashboard doesn't serve an admin endpoint, e.g.
+// as its version has none
+var ErrNoAdminEndpoint = errors.New("the dashboard has no such admin endpoint")
+
+// SetAdminSecret sets the admin_secret of the dashboard, which the admin endpoints require
+func (c *Client) SetAdminSecret(secret string) {
+	c.adminSecret = secret
+}
+
+func (c *Client) adminGet(endpoint string, out interface{}) error {
+	if c.adminSecret == "" {
+		return errors.New("the dashboard admin secret is not set")
+	}
+
+	fullPath := urljoin.Join(c.url, endpoint)
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		Headers: map[string]string{
+			"admin-auth": c.adminSecret,
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	})
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return fmt.Errorf("%v: %v", endpoint, ErrNoAdminEndpoint)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%v: the admin secret was refused, it is the admin_secret of the dashboard config", endpoint)
+	default:
+		return fmt.Errorf("API Returned error: %v (code: %v)", resp.String(), resp.StatusCode)
+	}
+
+	if err := resp.JSON(out); err != nil {
+		return fmt.Errorf("%v: %v", endpoint, err)
+	}
+	return nil
+}
+
+// FetchLicence returns the licence state of the dashboard
+func (c *Client) FetchLicence() (*objects.Licence, error) {
+	licence := &objects.Licence{}
+	if err := c.adminGet(endpointAdminLicence, licence); err != nil {
+		return nil, err
+	}
+
+	return licence, nil
+}
+
+// FetchGatewayNodes returns the gateways that are registered with the dashboard
+func (c *Client) FetchGatewayNodes() ([]objects.GatewayNode, error) {
+	nodes := objects.GatewayNodesResponse{}
+	if err := c.adminGet(endpointAdminNodes, &nodes); err != nil {
+		return nil, err
+	}
+
+	return nodes.Nodes, nil
+}
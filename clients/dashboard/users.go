@@ -0,0 +1,59 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/ongoingio/urljoin"
+)
+
+// FetchUsers returns the users of the organisation of the client's secret, their API keys
+// included when the secret belongs to an admin. They are listed with the list options of
+// the client.
+func (c *Client) FetchUsers() ([]objects.User, error) {
+	users := []objects.User{}
+	err := c.fetchList(endpointUsers, "users", c.listOptions, func(raw json.RawMessage) error {
+		user := objects.User{}
+		if err := json.Unmarshal(raw, &user); err != nil {
+			return err
+		}
+		users = append(users, user)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// FetchUser returns a user by ID
+func (c *Client) FetchUser(id string) (*objects.User, error) {
+	user := &objects.User{}
+	if err := c.Do("GET", urljoin.Join(endpointUsers, id), nil, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ResetUserKey replaces the API key of a user with a new one and returns it, the old key
+// stops working at once. The client's secret must not be the key being reset, it is needed
+// to read the new one.
+func (c *Client) ResetUserKey(id string) (string, error) {
+	if err := c.Do("PUT", urljoin.Join(endpointUsers, id, "actions/key/reset"), nil, nil); err != nil {
+		return "", err
+	}
+
+	user, err := c.FetchUser(id)
+	if err != nil {
+		return "", fmt.Errorf("the key of user %v was reset, but reading the new key failed: %v", id, err)
+	}
+	if user.AccessKey == "" {
+		return "", errors.New("the dashboard returned no API key for user " + id)
+	}
+
+	return user.AccessKey, nil
+}
@@ -0,0 +1,380 @@
+package files
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// AppsDir is the folder of the API definitions, the app_path of the gateway
+	AppsDir = "apps"
+	// PoliciesFile is the file of the policies, the policy_record_name of the gateway
+	PoliciesFile = "policies/policies.json"
+)
+
+var (
+	UseUpdateError    error = errors.New("Object seems to exist (same API ID or Listen Path), use update()")
+	UseCreateError    error = errors.New("Object does not exist, use create()")
+	UsePolUpdateError error = errors.New("Policy seems to exist (same ID), use update()")
+)
+
+// Client manages the API definitions and policies of a Tyk CE gateway that loads them from
+// files: a file per API in apps/, named after its API ID as the gateway names the APIs it
+// creates, and the policies keyed by ID in policies/policies.json. APIs are updated in the
+// file they were read from, whatever its name. Files are replaced atomically, so a gateway
+// reloading meanwhile never reads half a definition.
+type Client struct {
+	dir string
+	// apiFiles are the files the APIs were read from by API ID
+	apiFiles          map[string]string
+	planCheck         objects.PlanCheck
+	hooks             *objects.Hooks
+	progress          objects.Progress
+	deactivateRemoved bool
+}
+
+// NewClient opens the gateway files in dir, which must exist, creating the apps/ and
+// policies/ folders if needed
+func NewClient(dir string) (*Client, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%v is not a directory", dir)
+	}
+
+	for _, sub := range []string{AppsDir, filepath.Dir(PoliciesFile)} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Client{dir: dir}, nil
+}
+
+// SetPlanCheck sets a check that is run on the planned changes before Sync applies them
+func (c *Client) SetPlanCheck(check objects.PlanCheck) {
+	c.planCheck = check
+}
+
+// SetDeactivateRemoved makes Sync deactivate the APIs that are no longer in git instead of
+// deleting them
+func (c *Client) SetDeactivateRemoved(val bool) {
+	c.deactivateRemoved = val
+}
+
+// APIFile is the file an API with id is created in, relative to the gateway directory
+func APIFile(id string) string {
+	return filepath.Join(AppsDir, id+".json")
+}
+
+// apiFile is the file the API with id was read from, or the one it would be created in
+func (c *Client) apiFile(id string) (string, error) {
+	if _, ok := c.apiFiles[id]; !ok {
+		if _, err := c.FetchAPIs(); err != nil {
+			return "", err
+		}
+	}
+	if name, ok := c.apiFiles[id]; ok {
+		return name, nil
+	}
+
+	if err := checkID(id); err != nil {
+		return "", err
+	}
+	return APIFile(id), nil
+}
+
+// newID generates an ID the way the gateway does for objects created without one
+func newID() string {
+	return strings.Replace(uuid.NewV4().String(), "-", "", -1)
+}
+
+func checkID(id string) error {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return fmt.Errorf("%q can't be used as a file name, give the object another ID", id)
+	}
+	return nil
+}
+
+// writeFile replaces the file at name, relative to the gateway directory
+func (c *Client) writeFile(name string, v interface{}) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(c.dir, name)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, raw, 0644); err != nil {
+		return fmt.Errorf("Error writing file: %v", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func (c *Client) readFile(name string, v interface{}) error {
+	raw, err := ioutil.ReadFile(filepath.Join(c.dir, name))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%v: %v", name, err)
+	}
+	return nil
+}
+
+// FetchAPIRaw returns the API definition as stored, without decoding it into the vendored
+// apidef, so fields unknown to tyk-sync are retained
+func (c *Client) FetchAPIRaw(apiID string) (map[string]interface{}, error) {
+	name, err := c.apiFile(apiID)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]interface{}{}
+	if err := c.readFile(name, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// FetchAPIs reads the API definitions of the apps/ folder
+func (c *Client) FetchAPIs() ([]objects.DBApiDefinition, error) {
+	names, err := filepath.Glob(filepath.Join(c.dir, AppsDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	apis := []objects.DBApiDefinition{}
+	files := map[string]string{}
+	for _, name := range names {
+		file := filepath.Join(AppsDir, filepath.Base(name))
+		raw := map[string]interface{}{}
+		if err := c.readFile(file, &raw); err != nil {
+			return nil, err
+		}
+		def, err := objects.APIFromRaw(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", name, err)
+		}
+		if other, ok := files[def.APIID]; ok {
+			return nil, fmt.Errorf("%v and %v define the same API ID %v", other, file, def.APIID)
+		}
+		files[def.APIID] = file
+		apis = append(apis, *def)
+	}
+	c.apiFiles = files
+
+	return apis, nil
+}
+
+func (c *Client) createAPI(def *objects.DBApiDefinition) (string, error) {
+	apis, err := c.FetchAPIs()
+	if err != nil {
+		return "", err
+	}
+
+	for _, api := range apis {
+		if def.APIID != "" && api.APIID == def.APIID {
+			return "", UseUpdateError
+		}
+
+		if api.Proxy.ListenPath == def.Proxy.ListenPath {
+			return "", UseUpdateError
+		}
+	}
+
+	if def.APIID == "" {
+		def.APIID = newID()
+	}
+	if err := checkID(def.APIID); err != nil {
+		return "", err
+	}
+	// The file may hold another API, read from a file not named after its ID
+	if _, err := os.Stat(filepath.Join(c.dir, APIFile(def.APIID))); err == nil {
+		return "", fmt.Errorf("%v already holds another API", APIFile(def.APIID))
+	}
+
+	// A zero object ID isn't a real one, it isn't written
+	def.Id = objects.NormalID(def.Id)
+	payload, err := def.DefinitionPayload()
+	if err != nil {
+		return "", err
+	}
+	if err := c.writeFile(APIFile(def.APIID), payload); err != nil {
+		return "", err
+	}
+	c.apiFiles[def.APIID] = APIFile(def.APIID)
+
+	return def.APIID, nil
+}
+
+func (c *Client) updateAPI(def *objects.DBApiDefinition) error {
+	if def.APIID == "" {
+		return errors.New("API ID must be set")
+	}
+
+	name, err := c.apiFile(def.APIID)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(c.dir, name)); os.IsNotExist(err) {
+		return UseCreateError
+	}
+
+	// A zero object ID isn't a real one, it isn't written
+	def.Id = objects.NormalID(def.Id)
+	payload, err := def.DefinitionPayload()
+	if err != nil {
+		return err
+	}
+	return c.writeFile(name, payload)
+}
+
+func (c *Client) deleteAPI(id string) error {
+	name, err := c.apiFile(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(c.dir, name)); err != nil {
+		return err
+	}
+	delete(c.apiFiles, id)
+	return nil
+}
+
+// deactivateAPI sets active to false on the API, keeping the rest of it as it is
+func (c *Client) deactivateAPI(id string) error {
+	raw, err := c.FetchAPIRaw(id)
+	if err != nil {
+		return err
+	}
+
+	def, err := objects.APIFromRaw(raw)
+	if err != nil {
+		return err
+	}
+	def.Active = false
+
+	return c.UpdateAPI(def)
+}
+
+// Sync makes the apps/ folder hold the APIs of apiDefs, matched by API ID
+func (c *Client) Sync(apiDefs []objects.DBApiDefinition) error {
+	apis, err := c.FetchAPIs()
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]int{}
+	for i, api := range apis {
+		existing[api.APIID] = i
+	}
+	wanted := map[string]bool{}
+	for _, def := range apiDefs {
+		if def.APIID != "" {
+			wanted[def.APIID] = true
+		}
+	}
+
+	deleteAPIs := []string{}
+	updateAPIs := []objects.DBApiDefinition{}
+	createAPIs := []objects.DBApiDefinition{}
+	for _, api := range apis {
+		if wanted[api.APIID] {
+			continue
+		}
+		if c.deactivateRemoved && !api.Active {
+			continue
+		}
+		deleteAPIs = append(deleteAPIs, api.APIID)
+	}
+	for _, def := range apiDefs {
+		if _, ok := existing[def.APIID]; ok && def.APIID != "" {
+			updateAPIs = append(updateAPIs, def)
+		} else {
+			createAPIs = append(createAPIs, def)
+		}
+	}
+
+	if c.planCheck != nil {
+		plan := &objects.SyncPlan{Kind: "APIs", Existing: len(apis), Deactivate: c.deactivateRemoved}
+		for i, id := range deleteAPIs {
+			plan.Delete = append(plan.Delete, objects.SyncItem{ID: id, Name: apis[existing[id]].Name, Tags: apis[existing[id]].Tags, Index: i})
+		}
+		for i, api := range updateAPIs {
+			plan.Update = append(plan.Update, objects.SyncItem{ID: api.APIID, Name: api.Name, Tags: apis[existing[api.APIID]].Tags, Index: i})
+		}
+		for i, api := range createAPIs {
+			plan.Create = append(plan.Create, objects.SyncItem{ID: api.APIID, Name: api.Name, Index: i})
+		}
+
+		if err := c.planCheck(plan); err != nil {
+			return err
+		}
+
+		deleteAPIs = objects.KeepIDs(deleteAPIs, plan.Delete)
+		updateAPIs = objects.KeepAPIs(updateAPIs, plan.Update)
+		createAPIs = objects.KeepAPIs(createAPIs, plan.Create)
+	}
+
+	if c.deactivateRemoved {
+		fmt.Printf("Deactivating: %v\n", len(deleteAPIs))
+	} else {
+		fmt.Printf("Deleting: %v\n", len(deleteAPIs))
+	}
+	fmt.Printf("Updating: %v\n", len(updateAPIs))
+	fmt.Printf("Creating: %v\n", len(createAPIs))
+	progress := objects.TrackProgress(c.progress, "APIs", len(deleteAPIs)+len(updateAPIs)+len(createAPIs))
+
+	progress.Phase(objects.PhaseDelete, len(deleteAPIs))
+	for _, id := range deleteAPIs {
+		if c.deactivateRemoved {
+			fmt.Printf("SYNC Deactivating: %v\n", id)
+			if err := c.deactivateAPI(id); err != nil {
+				return err
+			}
+			progress.Done(id)
+			continue
+		}
+
+		fmt.Printf("SYNC Deleting: %v\n", id)
+		if err := c.DeleteAPI(id); err != nil {
+			return err
+		}
+		progress.Done(id)
+	}
+
+	progress.Phase(objects.PhaseUpdate, len(updateAPIs))
+	for _, api := range updateAPIs {
+		fmt.Printf("SYNC Updating: %v\n", api.APIID)
+		if err := c.UpdateAPI(&api); err != nil {
+			return err
+		}
+		progress.Done(api.APIID)
+	}
+
+	progress.Phase(objects.PhaseCreate, len(createAPIs))
+	for _, api := range createAPIs {
+		fmt.Printf("SYNC Creating: %v\n", api.Name)
+		id, err := c.CreateAPI(&api)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("--> ID: %v\n", id)
+		progress.Done(api.Name)
+	}
+
+	return nil
+}
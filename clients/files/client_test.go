@@ -0,0 +1,209 @@
+package files
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func testClient(t *testing.T) (*Client, func()) {
+	dir, err := ioutil.TempDir("", "gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClient(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return c, func() { os.RemoveAll(dir) }
+}
+
+func api(id, listenPath string) objects.DBApiDefinition {
+	def := &apidef.APIDefinition{APIID: id, Name: id, Active: true}
+	def.Proxy.ListenPath = listenPath
+	return objects.DBApiDefinition{APIDefinition: def}
+}
+
+func TestClientSync(t *testing.T) {
+	c, done := testClient(t)
+	defer done()
+
+	if err := c.Sync([]objects.DBApiDefinition{api("a1", "/a1/"), api("a2", "/a2/"), api("", "/new/")}); err != nil {
+		t.Fatal(err)
+	}
+	apis, err := c.FetchAPIs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apis) != 3 {
+		t.Fatalf("expected 3 API files, got %v", apis)
+	}
+	if _, err := os.Stat(filepath.Join(c.dir, "apps", "a1.json")); err != nil {
+		t.Errorf("expected the API file named after its ID: %v", err)
+	}
+
+	// a2 is protected by the plan check, the API without ID was given one
+	var planned *objects.SyncPlan
+	c.SetPlanCheck(func(plan *objects.SyncPlan) error {
+		planned = plan
+		for i, item := range plan.Delete {
+			if item.ID == "a2" {
+				plan.Delete = append(plan.Delete[:i], plan.Delete[i+1:]...)
+				break
+			}
+		}
+		return nil
+	})
+	updated := api("a1", "/a1/")
+	updated.Name = "renamed"
+	if err := c.Sync([]objects.DBApiDefinition{updated}); err != nil {
+		t.Fatal(err)
+	}
+	if planned == nil || len(planned.Update) != 1 || len(planned.Create) != 0 || planned.Existing != 3 {
+		t.Errorf("unexpected plan %+v", planned)
+	}
+
+	apis, err = c.FetchAPIs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apis) != 2 || apis[0].Name != "renamed" || apis[1].APIID != "a2" {
+		t.Errorf("expected a1 updated, a2 kept and the other API deleted, got %v", apis)
+	}
+
+	c.SetPlanCheck(nil)
+	c.SetDeactivateRemoved(true)
+	if err := c.Sync(nil); err != nil {
+		t.Fatal(err)
+	}
+	apis, err = c.FetchAPIs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apis) != 2 || apis[0].Active || apis[1].Active {
+		t.Errorf("expected the removed APIs deactivated, got %v", apis)
+	}
+}
+
+func TestClientCreateAPI(t *testing.T) {
+	c, done := testClient(t)
+	defer done()
+
+	a := api("a1", "/a1/")
+	if _, err := c.CreateAPI(&a); err != nil {
+		t.Fatal(err)
+	}
+
+	other := api("a2", "/a1/")
+	if _, err := c.CreateAPI(&other); err != UseUpdateError {
+		t.Errorf("expected a listen path already used to be refused, got %v", err)
+	}
+	missing := api("a3", "/a3/")
+	if err := c.UpdateAPI(&missing); err != UseCreateError {
+		t.Errorf("expected updating a missing API to fail, got %v", err)
+	}
+	bad := api("../a4", "/a4/")
+	if _, err := c.CreateAPI(&bad); err == nil {
+		t.Error("expected an ID that isn't a file name to be refused")
+	}
+
+	zero := api("a5", "/a5/")
+	zero.Id = bson.ObjectIdHex("000000000000000000000000")
+	if _, err := c.CreateAPI(&zero); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := c.FetchAPIRaw("a5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := raw["id"]; ok && id != "" {
+		t.Errorf("expected the zero object ID not to be written, got %v", raw["id"])
+	}
+}
+
+func TestClientSyncNamedFiles(t *testing.T) {
+	c, done := testClient(t)
+	defer done()
+
+	// Files written by hand aren't necessarily named after the API ID
+	raw := []byte(`{"api_id": "a1", "name": "Users", "active": true, "proxy": {"listen_path": "/users/"}}`)
+	if err := ioutil.WriteFile(filepath.Join(c.dir, "apps", "users.json"), raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+	raw = []byte(`{"api_id": "a2", "name": "Orders", "active": true, "proxy": {"listen_path": "/orders/"}}`)
+	if err := ioutil.WriteFile(filepath.Join(c.dir, "apps", "orders.json"), raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	updated := api("a1", "/users/")
+	updated.Name = "renamed"
+	if err := c.Sync([]objects.DBApiDefinition{updated}); err != nil {
+		t.Fatal(err)
+	}
+
+	names, _ := filepath.Glob(filepath.Join(c.dir, "apps", "*.json"))
+	if len(names) != 1 || filepath.Base(names[0]) != "users.json" {
+		t.Fatalf("expected a1 updated in users.json and orders.json deleted, got %v", names)
+	}
+	def, err := c.FetchAPIRaw("a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def["name"] != "renamed" {
+		t.Errorf("expected the file of a1 updated, got %v", def)
+	}
+
+	// A new API must not overwrite a file holding another one
+	other := api("users", "/other/")
+	if _, err := c.CreateAPI(&other); err == nil {
+		t.Error("expected the file of another API not to be overwritten")
+	}
+}
+
+func TestClientSyncPolicies(t *testing.T) {
+	c, done := testClient(t)
+	defer done()
+
+	pols := []objects.Policy{{ID: "gold", Name: "Gold", Rate: 100}, {ID: "silver", Name: "Silver"}}
+	if err := c.SyncPolicies(pols); err != nil {
+		t.Fatal(err)
+	}
+
+	pols[0].Rate = 200
+	if err := c.SyncPolicies(pols[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := c.FetchPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].ID != "gold" || stored[0].Rate != 200 {
+		t.Errorf("expected gold updated and silver deleted, got %+v", stored)
+	}
+
+	raw, err := c.FetchPolicyRaw("gold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := raw["_id"]; ok || raw["id"] != "gold" {
+		t.Errorf("expected the policy keyed by ID without a database ID, got %v", raw)
+	}
+
+	// A zero database ID isn't a key, the policy is given an ID
+	id, err := c.CreatePolicy(&objects.Policy{MID: bson.ObjectIdHex("000000000000000000000000"), Name: "Bronze"})
+	if err != nil || id == "000000000000000000000000" || id == "" {
+		t.Errorf("expected the policy with a zero database ID to get an ID, got %q %v", id, err)
+	}
+
+	if _, err := c.CreatePolicy(&objects.Policy{ID: "gold"}); err != UsePolUpdateError {
+		t.Errorf("expected creating an existing policy to fail, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package files
+
+import (
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// SetHooks registers hooks that are run around every create, update and delete the
+// client makes, including those of Sync
+func (c *Client) SetHooks(h *objects.Hooks) {
+	c.hooks = h
+}
+
+// SetProgress registers a Progress told how Sync advances
+func (c *Client) SetProgress(p objects.Progress) {
+	c.progress = p
+}
+
+func (c *Client) CreateAPI(def *objects.DBApiDefinition) (string, error) {
+	e := &objects.HookEvent{Kind: "APIs", Action: objects.HookCreate, API: def}
+	err := c.hooks.Run(e, func() (err error) {
+		e.ID, err = c.createAPI(def)
+		return err
+	}, UseUpdateError)
+
+	return e.ID, err
+}
+
+func (c *Client) UpdateAPI(def *objects.DBApiDefinition) error {
+	e := &objects.HookEvent{Kind: "APIs", Action: objects.HookUpdate, API: def, ID: def.APIID}
+	return c.hooks.Run(e, func() error { return c.updateAPI(def) }, UseCreateError)
+}
+
+func (c *Client) DeleteAPI(id string) error {
+	e := &objects.HookEvent{Kind: "APIs", Action: objects.HookDelete, ID: id}
+	return c.hooks.Run(e, func() error { return c.deleteAPI(id) })
+}
+
+func (c *Client) CreatePolicy(pol *objects.Policy) (string, error) {
+	e := &objects.HookEvent{Kind: "policies", Action: objects.HookCreate, Policy: pol}
+	err := c.hooks.Run(e, func() (err error) {
+		e.ID, err = c.createPolicy(pol)
+		return err
+	}, UseUpdateError)
+
+	return e.ID, err
+}
+
+func (c *Client) UpdatePolicy(pol *objects.Policy) error {
+	e := &objects.HookEvent{Kind: "policies", Action: objects.HookUpdate, Policy: pol, ID: pol.ID}
+	return c.hooks.Run(e, func() error { return c.updatePolicy(pol) }, UseCreateError)
+}
+
+func (c *Client) DeletePolicy(id string) error {
+	e := &objects.HookEvent{Kind: "policies", Action: objects.HookDelete, ID: id}
+	return c.hooks.Run(e, func() error { return c.deletePolicy(id) })
+}
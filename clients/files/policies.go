@@ -0,0 +1,248 @@
+package files
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// PolicyID is the key of a policy in the policies file: its ID, or the database ID of
+// policies exported from a dashboard without one. It is empty if neither is set, the zero
+// database ID isn't a real one.
+func PolicyID(pol *objects.Policy) string {
+	if pol.ID != "" {
+		return pol.ID
+	}
+	return objects.NormalID(pol.MID).Hex()
+}
+
+// gatewayPolicy is pol as the gateway reads it, without the dashboard database ID
+func gatewayPolicy(pol *objects.Policy, id string) (map[string]interface{}, error) {
+	raw, err := json.Marshal(pol)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	delete(obj, "_id")
+	obj["id"] = id
+
+	return obj, nil
+}
+
+// readPolicies reads the raw policies file, a missing file holds no policies
+func (c *Client) readPolicies() (map[string]map[string]interface{}, error) {
+	raw := map[string]map[string]interface{}{}
+	if err := c.readFile(PoliciesFile, &raw); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// FetchPolicyRaw returns the policy as stored, see FetchAPIRaw
+func (c *Client) FetchPolicyRaw(id string) (map[string]interface{}, error) {
+	raw, err := c.readPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	pol, ok := raw[id]
+	if !ok {
+		return nil, fmt.Errorf("policy %v not found in %v", id, PoliciesFile)
+	}
+	return pol, nil
+}
+
+// FetchPolicies reads the policies file, sorted by ID
+func (c *Client) FetchPolicies() ([]objects.Policy, error) {
+	raw, err := c.readPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for id := range raw {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	pols := []objects.Policy{}
+	for _, id := range ids {
+		doc, err := json.Marshal(raw[id])
+		if err != nil {
+			return nil, err
+		}
+		pol := objects.Policy{}
+		if err := json.Unmarshal(doc, &pol); err != nil {
+			return nil, fmt.Errorf("%v: policy %v: %v", PoliciesFile, id, err)
+		}
+		pol.ID = id
+		pols = append(pols, pol)
+	}
+
+	return pols, nil
+}
+
+// putPolicy writes pol under id, or removes the policy with id if pol is nil
+func (c *Client) putPolicy(id string, pol *objects.Policy) error {
+	raw, err := c.readPolicies()
+	if err != nil {
+		return err
+	}
+
+	if pol == nil {
+		delete(raw, id)
+	} else {
+		obj, err := gatewayPolicy(pol, id)
+		if err != nil {
+			return err
+		}
+		raw[id] = obj
+	}
+
+	return c.writeFile(PoliciesFile, raw)
+}
+
+func (c *Client) createPolicy(pol *objects.Policy) (string, error) {
+	raw, err := c.readPolicies()
+	if err != nil {
+		return "", err
+	}
+
+	id := PolicyID(pol)
+	if id == "" {
+		id = newID()
+		pol.ID = id
+	}
+	if _, ok := raw[id]; ok {
+		return "", UsePolUpdateError
+	}
+
+	return id, c.putPolicy(id, pol)
+}
+
+func (c *Client) updatePolicy(pol *objects.Policy) error {
+	raw, err := c.readPolicies()
+	if err != nil {
+		return err
+	}
+
+	id := PolicyID(pol)
+	if _, ok := raw[id]; !ok || id == "" {
+		return UseCreateError
+	}
+
+	return c.putPolicy(id, pol)
+}
+
+func (c *Client) deletePolicy(id string) error {
+	raw, err := c.readPolicies()
+	if err != nil {
+		return err
+	}
+	if _, ok := raw[id]; !ok {
+		return fmt.Errorf("policy %v not found in %v", id, PoliciesFile)
+	}
+
+	return c.putPolicy(id, nil)
+}
+
+// SyncPolicies makes the policies file hold pols, matched by PolicyID
+func (c *Client) SyncPolicies(pols []objects.Policy) error {
+	ePols, err := c.FetchPolicies()
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]bool{}
+	for _, pol := range ePols {
+		existing[pol.ID] = true
+	}
+	wanted := map[string]bool{}
+	for i := range pols {
+		if id := PolicyID(&pols[i]); id != "" {
+			wanted[id] = true
+		}
+	}
+
+	deletePols := []string{}
+	deleteNames := []string{}
+	updatePols := []objects.Policy{}
+	createPols := []objects.Policy{}
+	for _, pol := range ePols {
+		if !wanted[pol.ID] {
+			deletePols = append(deletePols, pol.ID)
+			deleteNames = append(deleteNames, pol.Name)
+		}
+	}
+	for i := range pols {
+		if id := PolicyID(&pols[i]); id != "" && existing[id] {
+			updatePols = append(updatePols, pols[i])
+		} else {
+			createPols = append(createPols, pols[i])
+		}
+	}
+
+	if c.planCheck != nil {
+		plan := &objects.SyncPlan{Kind: "policies", Existing: len(ePols)}
+		for i, id := range deletePols {
+			plan.Delete = append(plan.Delete, objects.SyncItem{ID: id, Name: deleteNames[i], Index: i})
+		}
+		for i, pol := range updatePols {
+			plan.Update = append(plan.Update, objects.SyncItem{ID: PolicyID(&pol), Name: pol.Name, Index: i})
+		}
+		for i, pol := range createPols {
+			plan.Create = append(plan.Create, objects.SyncItem{ID: PolicyID(&pol), Name: pol.Name, Index: i})
+		}
+
+		if err := c.planCheck(plan); err != nil {
+			return err
+		}
+
+		deletePols = objects.KeepIDs(deletePols, plan.Delete)
+		updatePols = objects.KeepPolicies(updatePols, plan.Update)
+		createPols = objects.KeepPolicies(createPols, plan.Create)
+	}
+
+	fmt.Printf("Deleting policies: %v\n", len(deletePols))
+	fmt.Printf("Updating policies: %v\n", len(updatePols))
+	fmt.Printf("Creating policies: %v\n", len(createPols))
+	progress := objects.TrackProgress(c.progress, "policies", len(deletePols)+len(updatePols)+len(createPols))
+
+	progress.Phase(objects.PhaseDelete, len(deletePols))
+	for _, id := range deletePols {
+		fmt.Printf("SYNC Deleting Policy: %v\n", id)
+		if err := c.DeletePolicy(id); err != nil {
+			return err
+		}
+		progress.Done(id)
+	}
+
+	progress.Phase(objects.PhaseUpdate, len(updatePols))
+	for _, pol := range updatePols {
+		fmt.Printf("SYNC Updating Policy: %v\n", pol.Name)
+		if err := c.UpdatePolicy(&pol); err != nil {
+			return err
+		}
+		progress.Done(pol.Name)
+	}
+
+	progress.Phase(objects.PhaseCreate, len(createPols))
+	for _, pol := range createPols {
+		fmt.Printf("SYNC Creating Policy: %v\n", pol.Name)
+		id, err := c.CreatePolicy(&pol)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("--> ID: %v\n", id)
+		progress.Done(pol.Name)
+	}
+
+	return nil
+}
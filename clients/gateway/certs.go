@@ -2,10 +2,10 @@ package gateway
 
 import (
 	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/levigross/grequests"
 	"github.com/ongoingio/urljoin"
 	"io"
 	"io/ioutil"
@@ -34,11 +34,7 @@ func (c *Client) CreateCertificate(cert []byte) (string, error) {
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 	req.Header.Set("X-Tyk-Authorization", c.secret)
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify},
-	}
-	client := &http.Client{Transport: tr}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient().Do(req)
 
 	rBody, _ := ioutil.ReadAll(resp.Body)
 	if resp.StatusCode != 200 {
@@ -56,3 +52,57 @@ func (c *Client) CreateCertificate(cert []byte) (string, error) {
 
 	return dbResp.Id, nil
 }
+
+// FetchCertificateIDs lists the IDs of the certificates stored on the gateway for an org
+func (c *Client) FetchCertificateIDs(orgID string) ([]string, error) {
+	fullPath := urljoin.Join(c.url, endpointCerts)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		Params: map[string]string{"org_id": orgID},
+		Headers: map[string]string{
+			"x-tyk-authorization": c.secret,
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	list := objects.CertificateListResponse{}
+	if err := resp.JSON(&list); err != nil {
+		return nil, err
+	}
+
+	return list.CertIDs, nil
+}
+
+func (c *Client) FetchCertificate(id string) (*objects.CertificateMeta, error) {
+	fullPath := urljoin.Join(c.url, endpointCerts, id)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		Headers: map[string]string{
+			"x-tyk-authorization": c.secret,
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	meta := &objects.CertificateMeta{}
+	if err := resp.JSON(meta); err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
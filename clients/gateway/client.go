@@ -1,20 +1,29 @@
 package gateway
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/clients/transport"
 	"github.com/TykTechnologies/tyk/apidef"
 	"github.com/levigross/grequests"
 	"github.com/ongoingio/urljoin"
 	uuid "github.com/satori/go.uuid"
 )
 
+// Client talks to the gateway API. It is safe for concurrent use by multiple goroutines
+// once configured, the Set methods should be called before the client is shared.
 type Client struct {
 	url                string
 	secret             string
 	InsecureSkipVerify bool
+	planCheck          objects.PlanCheck
+	hooks              *objects.Hooks
+	progress           objects.Progress
+	deactivateRemoved  bool
 }
 
 const (
@@ -22,6 +31,7 @@ const (
 	endpointCerts    string = "/tyk/certs"
 	reloadAPIs       string = "/tyk/reload/group"
 	endpointPolicies string = "/tyk/policies"
+	endpointHello    string = "/hello"
 )
 
 var (
@@ -45,6 +55,11 @@ func NewGatewayClient(url, secret string) (*Client, error) {
 	}, nil
 }
 
+// httpClient returns the HTTP client for requests, all clients share their connections
+func (c *Client) httpClient() *http.Client {
+	return transport.Client(c.InsecureSkipVerify)
+}
+
 func (c *Client) SetInsecureTLS(val bool) {
 	c.InsecureSkipVerify = val
 }
@@ -62,6 +77,7 @@ func (c *Client) FetchAPIs() ([]objects.DBApiDefinition, error) {
 			"content-type":        "application/json",
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	}
 
 	resp, err := grequests.Get(fullPath, ro)
@@ -74,19 +90,29 @@ func (c *Client) FetchAPIs() ([]objects.DBApiDefinition, error) {
 	}
 
 	apis := APISList{}
-	if err := resp.JSON(&apis); err != nil {
+	body := resp.Bytes()
+	if err := json.Unmarshal(body, &apis); err != nil {
+		return nil, err
+	}
+
+	// Decoded again to keep the fields unknown to the vendored apidef
+	raws := []json.RawMessage{}
+	if err := json.Unmarshal(body, &raws); err != nil {
 		return nil, err
 	}
 
 	retList := make([]objects.DBApiDefinition, len(apis))
 	for i := range apis {
 		retList[i] = objects.DBApiDefinition{APIDefinition:&apis[i]}
+		if err := retList[i].KeepRaw(raws[i]); err != nil {
+			return nil, err
+		}
 	}
 
 	return retList, nil
 }
 
-func (c *Client) CreateAPI(def *objects.DBApiDefinition) (string, error) {
+func (c *Client) createAPI(def *objects.DBApiDefinition) (string, error) {
 	fullPath := urljoin.Join(c.url, endpointAPIs)
 
 	ro := &grequests.RequestOptions{
@@ -95,6 +121,7 @@ func (c *Client) CreateAPI(def *objects.DBApiDefinition) (string, error) {
 			"content-type":        "application/json",
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	}
 
 	resp, err := grequests.Get(fullPath, ro)
@@ -122,13 +149,21 @@ func (c *Client) CreateAPI(def *objects.DBApiDefinition) (string, error) {
 	}
 
 	// Create
+	// A zero object ID isn't a real one, it isn't written
+	def.Id = objects.NormalID(def.Id)
+	payload, err := def.DefinitionPayload()
+	if err != nil {
+		return "", err
+	}
+
 	createResp, err := grequests.Post(fullPath, &grequests.RequestOptions{
-		JSON: def.APIDefinition,
+		JSON: payload,
 		Headers: map[string]string{
 			"x-tyk-authorization": c.secret,
 			"content-type":        "application/json",
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	})
 
 	if err != nil {
@@ -163,6 +198,7 @@ func (c *Client) Reload() error {
 			"x-tyk-authorization": c.secret,
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	})
 
 	if err != nil {
@@ -179,13 +215,13 @@ func (c *Client) Reload() error {
 	}
 
 	if status.Status != "ok" {
-		fmt.Errorf("API request completed, but with error: %v", status.Message)
+		return fmt.Errorf("API request completed, but with error: %v", status.Message)
 	}
 
 	return nil
 }
 
-func (c *Client) UpdateAPI(def *objects.DBApiDefinition) error {
+func (c *Client) updateAPI(def *objects.DBApiDefinition) error {
 
 	apis, err := c.FetchAPIs()
 	if err != nil {
@@ -207,14 +243,22 @@ func (c *Client) UpdateAPI(def *objects.DBApiDefinition) error {
 		return errors.New("API ID must be set")
 	}
 
+	// A zero object ID isn't a real one, it isn't written
+	def.Id = objects.NormalID(def.Id)
+	payload, err := def.DefinitionPayload()
+	if err != nil {
+		return err
+	}
+
 	updatePath := urljoin.Join(c.url, endpointAPIs, def.APIID)
 	uResp, err := grequests.Put(updatePath, &grequests.RequestOptions{
-		JSON: def.APIDefinition,
+		JSON: payload,
 		Headers: map[string]string{
 			"x-tyk-authorization": c.secret,
 			"content-type":        "application/json",
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	})
 
 	if err != nil {
@@ -231,6 +275,33 @@ func (c *Client) UpdateAPI(def *objects.DBApiDefinition) error {
 	return nil
 }
 
+// SetPlanCheck sets a check that is run on the planned changes before Sync applies them
+func (c *Client) SetPlanCheck(check objects.PlanCheck) {
+	c.planCheck = check
+}
+
+// SetDeactivateRemoved makes Sync deactivate the APIs that are no longer in git instead of
+// deleting them
+func (c *Client) SetDeactivateRemoved(val bool) {
+	c.deactivateRemoved = val
+}
+
+// deactivateAPI sets active to false on the API, keeping the rest of it as it is
+func (c *Client) deactivateAPI(id string) error {
+	raw, err := c.FetchAPIRaw(id)
+	if err != nil {
+		return err
+	}
+
+	def, err := objects.APIFromRaw(raw)
+	if err != nil {
+		return err
+	}
+	def.Active = false
+
+	return c.UpdateAPI(def)
+}
+
 func (c *Client) Sync(apiDefs []objects.DBApiDefinition) error {
 	deleteAPIs := []string{}
 	updateAPIs := []objects.DBApiDefinition{}
@@ -272,9 +343,12 @@ func (c *Client) Sync(apiDefs []objects.DBApiDefinition) error {
 	}
 
 	// Deletes are when we find items in the dash that are not in git
-	for key, _ := range GWIDMap {
+	for key, i := range GWIDMap {
 		_, ok := GitIDMap[key]
 		if !ok {
+			if c.deactivateRemoved && !apis[i].Active {
+				continue
+			}
 			deleteAPIs = append(deleteAPIs, key)
 		}
 	}
@@ -287,28 +361,68 @@ func (c *Client) Sync(apiDefs []objects.DBApiDefinition) error {
 		}
 	}
 
-	fmt.Printf("Deleting: %v\n", len(deleteAPIs))
+	if c.planCheck != nil {
+		plan := &objects.SyncPlan{Kind: "APIs", Existing: len(apis), Deactivate: c.deactivateRemoved}
+		for i, id := range deleteAPIs {
+			plan.Delete = append(plan.Delete, objects.SyncItem{ID: id, Name: apis[GWIDMap[id]].Name, Tags: apis[GWIDMap[id]].Tags, Index: i})
+		}
+		for i, api := range updateAPIs {
+			plan.Update = append(plan.Update, objects.SyncItem{ID: api.APIID, Name: api.Name, Tags: apis[GWIDMap[api.APIID]].Tags, Index: i})
+		}
+		for i, api := range createAPIs {
+			plan.Create = append(plan.Create, objects.SyncItem{ID: api.APIID, Name: api.Name, Index: i})
+		}
+
+		if err := c.planCheck(plan); err != nil {
+			return err
+		}
+
+		deleteAPIs = objects.KeepIDs(deleteAPIs, plan.Delete)
+		updateAPIs = objects.KeepAPIs(updateAPIs, plan.Update)
+		createAPIs = objects.KeepAPIs(createAPIs, plan.Create)
+	}
+
+	if c.deactivateRemoved {
+		fmt.Printf("Deactivating: %v\n", len(deleteAPIs))
+	} else {
+		fmt.Printf("Deleting: %v\n", len(deleteAPIs))
+	}
 	fmt.Printf("Updating: %v\n", len(updateAPIs))
 	fmt.Printf("Creating: %v\n", len(createAPIs))
+	progress := objects.TrackProgress(c.progress, "APIs", len(deleteAPIs)+len(updateAPIs)+len(createAPIs))
 
 	// Do the deletes
+	progress.Phase(objects.PhaseDelete, len(deleteAPIs))
 	for _, dbId := range deleteAPIs {
+		if c.deactivateRemoved {
+			fmt.Printf("SYNC Deactivating: %v\n", dbId)
+			if err := c.deactivateAPI(dbId); err != nil {
+				return err
+			}
+			progress.Done(dbId)
+			continue
+		}
+
 		fmt.Printf("SYNC Deleting: %v\n", dbId)
-		if err := c.deleteAPI(dbId); err != nil {
+		if err := c.DeleteAPI(dbId); err != nil {
 			return err
 		}
+		progress.Done(dbId)
 	}
 
 	// Do the updates
+	progress.Phase(objects.PhaseUpdate, len(updateAPIs))
 	for _, api := range updateAPIs {
 		fmt.Printf("SYNC Updating: %v\n", api.APIID)
 		if err := c.UpdateAPI(&api); err != nil {
 			fmt.Println("ERR:",err)
 			return err
 		}
+		progress.Done(api.APIID)
 	}
 
 	// Do the creates
+	progress.Phase(objects.PhaseCreate, len(createAPIs))
 	for _, api := range createAPIs {
 		fmt.Printf("SYNC Creating: %v\n", api.Name)
 		var err error
@@ -317,15 +431,12 @@ func (c *Client) Sync(apiDefs []objects.DBApiDefinition) error {
 			return err
 		}
 		fmt.Printf("--> ID: %v\n", id)
+		progress.Done(api.Name)
 	}
 
 	return nil
 }
 
-func (c *Client) DeleteAPI(id string) error {
-	return c.deleteAPI(id)
-}
-
 func (c *Client) deleteAPI(id string) error {
 	delPath := urljoin.Join(c.url, endpointAPIs)
 	delPath += id
@@ -336,6 +447,7 @@ func (c *Client) deleteAPI(id string) error {
 			"content-type":        "application/json",
 		},
 		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
 	})
 
 	if err != nil {
@@ -351,3 +463,90 @@ func (c *Client) deleteAPI(id string) error {
 
 	return nil
 }
+
+// FetchAPIsRaw returns the API definitions as stored by the gateway by API ID, in one call
+// rather than one call per API, see FetchAPIRaw
+func (c *Client) FetchAPIsRaw() (map[string]map[string]interface{}, error) {
+	fullPath := urljoin.Join(c.url, endpointAPIs)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		Headers: map[string]string{
+			"x-tyk-authorization": c.secret,
+			"content-type":        "application/json",
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	raws := []map[string]interface{}{}
+	if err := resp.JSON(&raws); err != nil {
+		return nil, err
+	}
+
+	apis := map[string]map[string]interface{}{}
+	for _, raw := range raws {
+		if id, _ := raw["api_id"].(string); id != "" {
+			apis[id] = raw
+		}
+	}
+	return apis, nil
+}
+
+// FetchAPIRaw returns the API definition as stored by the gateway, without decoding it into
+// the vendored apidef, so fields unknown to tyk-sync are retained
+func (c *Client) FetchAPIRaw(apiID string) (map[string]interface{}, error) {
+	fullPath := urljoin.Join(c.url, endpointAPIs)
+	fullPath += apiID
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		Headers: map[string]string{
+			"x-tyk-authorization": c.secret,
+			"content-type":        "application/json",
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	raw := map[string]interface{}{}
+	if err := resp.JSON(&raw); err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+// FetchVersion returns the version the gateway reports on its health check endpoint
+func (c *Client) FetchVersion() (string, error) {
+	resp, err := grequests.Get(urljoin.Join(c.url, endpointHello), &grequests.RequestOptions{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	hello := objects.HealthCheck{}
+	if err := resp.JSON(&hello); err != nil {
+		return "", err
+	}
+
+	return hello.Version, nil
+}
@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Run with -race, the client must be usable from several goroutines at once
+func TestClientConcurrentUse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"api_id": "a1", "name": "A"}]`))
+	}))
+	defer ts.Close()
+
+	c, err := NewGatewayClient(ts.URL, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			apis, err := c.FetchAPIs()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(apis) != 1 || apis[0].APIID != "a1" {
+				t.Errorf("unexpected APIs: %v", apis)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestReload(t *testing.T) {
+	status := `{"status": "ok"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(status))
+	}))
+	defer ts.Close()
+
+	c, err := NewGatewayClient(ts.URL, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A reload the gateway reports as failed is an error, even with a 200
+	status = `{"status": "error", "message": "reload queue full"}`
+	if err := c.Reload(); err == nil {
+		t.Error("expected the failed reload to be returned")
+	}
+}
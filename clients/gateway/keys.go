@@ -0,0 +1,208 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/levigross/grequests"
+	"github.com/ongoingio/urljoin"
+)
+
+const endpointKeys string = "/tyk/keys"
+
+// FetchKeyIDs lists the keys stored on the gateway, with key hashing enabled the gateway
+// only lists (hashed) keys if enable_hashed_keys_listing is set
+func (c *Client) FetchKeyIDs() ([]string, error) {
+	fullPath := urljoin.Join(c.url, endpointKeys)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		Headers: map[string]string{
+			"x-tyk-authorization": c.secret,
+			"content-type":        "application/json",
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	keys := objects.KeyListResponse{}
+	if err := resp.JSON(&keys); err != nil {
+		return nil, err
+	}
+
+	return keys.Keys, nil
+}
+
+// FetchKeyIDsWithPrefix lists the keys whose ID starts with prefix, e.g. the test keys of
+// an environment created with IDs like test-. Hashed keys are listed by their hash, which a
+// prefix doesn't match.
+func (c *Client) FetchKeyIDsWithPrefix(prefix string) ([]string, error) {
+	all := objects.KeyListResponse{}
+	resp, err := c.keyRequest("GET", "", false, nil, map[string]string{"filter": prefix})
+	if err != nil {
+		return nil, err
+	}
+	if err := resp.JSON(&all); err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, k := range all.Keys {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys, nil
+}
+
+func (c *Client) FetchKey(keyID string, hashed bool) (*objects.Key, error) {
+	fullPath := urljoin.Join(c.url, endpointKeys, keyID)
+
+	ro := &grequests.RequestOptions{
+		Headers: map[string]string{
+			"x-tyk-authorization": c.secret,
+			"content-type":        "application/json",
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	}
+	if hashed {
+		ro.Params = map[string]string{"hashed": "true"}
+	}
+
+	resp, err := grequests.Get(fullPath, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	key := &objects.Key{KeyID: keyID, Hashed: hashed}
+	if err := resp.JSON(&key.Session); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// CreateKey creates (or overwrites) a key with the given ID, hashed keys are stored under their hash
+func (c *Client) CreateKey(key *objects.Key) error {
+	if key.KeyID == "" {
+		return errors.New("Key ID must be set")
+	}
+
+	fullPath := urljoin.Join(c.url, endpointKeys, key.KeyID)
+
+	ro := &grequests.RequestOptions{
+		JSON: key.Session,
+		Headers: map[string]string{
+			"x-tyk-authorization": c.secret,
+			"content-type":        "application/json",
+		},
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	}
+	if key.Hashed {
+		ro.Params = map[string]string{"hashed": "true"}
+	}
+
+	resp, err := grequests.Post(fullPath, ro)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("API Returned error: %v (code: %v)", resp.String(), resp.StatusCode)
+	}
+
+	return nil
+}
+
+// keyRequest sends a request to the keys endpoint, or to the key keyID if set. Responses other
+// than 200 are returned as errors.
+func (c *Client) keyRequest(method, keyID string, hashed bool, body interface{}, params map[string]string) (*grequests.Response, error) {
+	fullPath := urljoin.Join(c.url, endpointKeys)
+	if keyID != "" {
+		fullPath = urljoin.Join(fullPath, keyID)
+	}
+
+	ro := &grequests.RequestOptions{
+		Headers: map[string]string{
+			"x-tyk-authorization": c.secret,
+			"content-type":        "application/json",
+		},
+		Params:             params,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient:         c.httpClient(),
+	}
+	if body != nil {
+		ro.JSON = body
+	}
+	if hashed {
+		if ro.Params == nil {
+			ro.Params = map[string]string{}
+		}
+		ro.Params["hashed"] = "true"
+	}
+
+	resp, err := grequests.Req(method, fullPath, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v (code: %v)", resp.String(), resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// GenerateKey creates a key with the session under an ID the gateway generates, see
+// objects.NewKeySession for sessions applying policies or granting access to APIs
+func (c *Client) GenerateKey(session map[string]interface{}) (*objects.Key, error) {
+	resp, err := c.keyRequest("POST", "create", false, session, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := objects.KeyResponse{}
+	if err := resp.JSON(&reply); err != nil {
+		return nil, err
+	}
+	if reply.Key == "" {
+		return nil, fmt.Errorf("the gateway returned no key: %v", resp.String())
+	}
+
+	return &objects.Key{KeyID: reply.Key, Session: session}, nil
+}
+
+// UpdateKey replaces the session of an existing key, hashed keys are addressed by their hash
+func (c *Client) UpdateKey(key *objects.Key) error {
+	if key.KeyID == "" {
+		return errors.New("Key ID must be set")
+	}
+
+	_, err := c.keyRequest("PUT", key.KeyID, key.Hashed, key.Session, nil)
+	return err
+}
+
+// DeleteKey deletes a key, by its hash if hashed is set
+func (c *Client) DeleteKey(keyID string, hashed bool) error {
+	if keyID == "" {
+		return errors.New("Key ID must be set")
+	}
+
+	_, err := c.keyRequest("DELETE", keyID, hashed, nil, nil)
+	return err
+}
@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// keyStore is a gateway keys endpoint storing sessions in memory
+func keyStore() (*httptest.Server, map[string]map[string]interface{}) {
+	var mu sync.Mutex
+	keys := map[string]map[string]interface{}{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Header.Get("x-tyk-authorization") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, endpointKeys), "/")
+		session := map[string]interface{}{}
+		if r.Method == http.MethodPost || r.Method == http.MethodPut {
+			json.NewDecoder(r.Body).Decode(&session)
+		}
+
+		switch {
+		case r.Method == http.MethodGet && id == "":
+			list := objects.KeyListResponse{Keys: []string{}}
+			for k := range keys {
+				list.Keys = append(list.Keys, k)
+			}
+			json.NewEncoder(w).Encode(list)
+		case r.Method == http.MethodPost && id == "create":
+			id = "generated"
+			keys[id] = session
+			json.NewEncoder(w).Encode(objects.KeyResponse{Key: id, Status: "ok", Action: "added"})
+		case r.Method == http.MethodPost:
+			keys[id] = session
+			json.NewEncoder(w).Encode(objects.KeyResponse{Key: id, Status: "ok", Action: "added"})
+		case keys[id] == nil:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(keys[id])
+		case r.Method == http.MethodPut:
+			keys[id] = session
+			json.NewEncoder(w).Encode(objects.KeyResponse{Key: id, Status: "ok", Action: "modified"})
+		case r.Method == http.MethodDelete:
+			delete(keys, id)
+			json.NewEncoder(w).Encode(objects.KeyResponse{Key: id, Status: "ok", Action: "deleted"})
+		}
+	})), keys
+}
+
+func TestKeys(t *testing.T) {
+	ts, keys := keyStore()
+	defer ts.Close()
+
+	c, err := NewGatewayClient(ts.URL, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expires := time.Now().Add(time.Hour)
+	session := objects.NewKeySession("org", []string{"p1"}, []objects.KeyAccess{{APIID: "a1"}, {APIID: "a2", Versions: []string{"v1", "v2"}}}, expires)
+	generated, err := c.GenerateKey(session)
+	if err != nil || generated.KeyID != "generated" {
+		t.Fatalf("expected a generated key, got %+v %v", generated, err)
+	}
+	if err := c.CreateKey(&objects.Key{KeyID: "test-1", Session: session}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := keys["test-1"]
+	rights, _ := stored["access_rights"].(map[string]interface{})
+	a2, _ := rights["a2"].(map[string]interface{})
+	if stored["apply_policies"].([]interface{})[0] != "p1" || len(a2["versions"].([]interface{})) != 2 || stored["expires"].(float64) != float64(expires.Unix()) {
+		t.Errorf("unexpected session %v", stored)
+	}
+
+	if ids, err := c.FetchKeyIDsWithPrefix("test-"); err != nil || len(ids) != 1 || ids[0] != "test-1" {
+		t.Errorf("expected the test key listed, got %v %v", ids, err)
+	}
+
+	key, err := c.FetchKey("test-1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.Session["apply_policies"] = []string{"p2"}
+	if err := c.UpdateKey(key); err != nil {
+		t.Fatal(err)
+	}
+	if keys["test-1"]["apply_policies"].([]interface{})[0] != "p2" {
+		t.Errorf("expected the policies updated, got %v", keys["test-1"])
+	}
+	if err := c.UpdateKey(&objects.Key{KeyID: "missing", Session: session}); err == nil {
+		t.Error("expected the update of a missing key to fail")
+	}
+
+	if err := c.DeleteKey("test-1", false); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := c.FetchKeyIDsWithPrefix(""); len(ids) != 1 || ids[0] != "generated" {
+		t.Errorf("expected only the generated key left, got %v", ids)
+	}
+	if err := c.DeleteKey("", false); err == nil {
+		t.Error("expected a key ID required")
+	}
+}
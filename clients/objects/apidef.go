@@ -16,4 +16,17 @@ type DBApiDefinition struct {
 	SortBy               int           `bson:"sort_by" json:"sort_by"`
 	UserGroupOwners      []bson.ObjectId `bson:"user_group_owners" json:"user_group_owners"`
 	UserOwners           []bson.ObjectId `bson:"user_owners" json:"user_owners"`
+	// Passthrough is the raw definition read from the repo or the target, see KeepRaw
+	Passthrough map[string]interface{} `bson:"-" json:"-"`
+	// Strip are the fields (dotted JSON paths, e.g. proxy.preserve_host_header) removed from
+	// the definition sent to a target, see DefinitionPayload
+	Strip []string `bson:"-" json:"-"`
+	// File is the file of the repo the definition was read from, if any
+	File string `bson:"-" json:"-"`
+	// IDSeed is the id_seed the IDs of the definition are derived from, see ApplySeed
+	IDSeed string `bson:"-" json:"-"`
+	// seededAPIID is the API ID ApplySeed set last
+	seededAPIID string
+	// extra are the keys next to api_definition that none of the fields above decode
+	extra map[string]interface{}
 }
@@ -0,0 +1,36 @@
+package objects
+
+import "strings"
+
+// APICategories is the body of the dashboard categories endpoints
+type APICategories struct {
+	Categories []string `json:"categories"`
+}
+
+// SplitCategories splits the trailing #hashtags off an API name, the dashboard shows them
+// as the categories of the API
+func SplitCategories(name string) (string, []string) {
+	words := strings.Fields(name)
+	i := len(words)
+	for i > 0 && strings.HasPrefix(words[i-1], "#") && len(words[i-1]) > 1 {
+		i--
+	}
+
+	categories := []string{}
+	for _, w := range words[i:] {
+		categories = append(categories, strings.TrimPrefix(w, "#"))
+	}
+
+	return strings.Join(words[:i], " "), categories
+}
+
+// JoinCategories appends the categories to the name as #hashtags, spaces in a category are
+// replaced with dashes
+func JoinCategories(name string, categories []string) string {
+	parts := []string{name}
+	for _, c := range categories {
+		parts = append(parts, "#"+strings.Join(strings.Fields(c), "-"))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -5,3 +5,18 @@ type CertResponse struct {
 	Message string `json:"message"`
 	Status  string `json:"status"`
 }
+
+// CertificateMeta is the certificate information returned by the gateway, the
+// certificate content itself is not exposed by the API
+type CertificateMeta struct {
+	ID            string   `json:"id"`
+	Fingerprint   string   `json:"fingerprint"`
+	HasPrivateKey bool     `json:"has_private"`
+	NotBefore     string   `json:"not_before,omitempty"`
+	NotAfter      string   `json:"not_after,omitempty"`
+	DNSNames      []string `json:"dns_names,omitempty"`
+}
+
+type CertificateListResponse struct {
+	CertIDs []string `json:"certs"`
+}
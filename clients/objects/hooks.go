@@ -0,0 +1,82 @@
+package objects
+
+const (
+	HookCreate = "create"
+	HookUpdate = "update"
+	HookDelete = "delete"
+)
+
+// HookEvent describes a change a client is about to make or has made. API or Policy is
+// set depending on the object, deletes only carry the ID.
+type HookEvent struct {
+	Action string
+	// Kind is APIs or policies, like SyncPlan.Kind
+	Kind   string
+	API    *DBApiDefinition
+	Policy *Policy
+	ID     string
+	// Err is set for OnError
+	Err error
+}
+
+// Hooks let programs embedding the clients run their own code around every change, e.g.
+// validation, metrics or notifications. A Before hook returning an error stops the change,
+// the error is returned to the caller. Unset hooks are skipped.
+type Hooks struct {
+	OnBeforeCreate func(e *HookEvent) error
+	OnAfterCreate  func(e *HookEvent)
+	OnBeforeUpdate func(e *HookEvent) error
+	OnAfterUpdate  func(e *HookEvent)
+	OnBeforeDelete func(e *HookEvent) error
+	OnAfterDelete  func(e *HookEvent)
+	// OnError is called when a change fails, including when a Before hook stops it
+	OnError func(e *HookEvent)
+}
+
+// Run calls the hooks for the action around change, h may be nil. Errors in ignore are
+// returned without calling OnError, clients use them to signal create/update fallbacks.
+func (h *Hooks) Run(e *HookEvent, change func() error, ignore ...error) error {
+	if h == nil {
+		return change()
+	}
+
+	var before func(*HookEvent) error
+	var after func(*HookEvent)
+	switch e.Action {
+	case HookCreate:
+		before, after = h.OnBeforeCreate, h.OnAfterCreate
+	case HookUpdate:
+		before, after = h.OnBeforeUpdate, h.OnAfterUpdate
+	case HookDelete:
+		before, after = h.OnBeforeDelete, h.OnAfterDelete
+	}
+
+	err := error(nil)
+	if before != nil {
+		err = before(e)
+	}
+
+	if err == nil {
+		err = change()
+	}
+
+	if err != nil {
+		for _, i := range ignore {
+			if err == i {
+				return err
+			}
+		}
+
+		if h.OnError != nil {
+			e.Err = err
+			h.OnError(e)
+		}
+		return err
+	}
+
+	if after != nil {
+		after(e)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package objects
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+var errUseUpdate = errors.New("use update")
+
+func TestHooksRun(t *testing.T) {
+	calls := []string{}
+	h := &Hooks{
+		OnBeforeCreate: func(e *HookEvent) error {
+			calls = append(calls, "before")
+			if e.API.Name == "invalid" {
+				return errors.New("rejected")
+			}
+			return nil
+		},
+		OnAfterCreate: func(e *HookEvent) { calls = append(calls, "after "+e.ID) },
+		OnError:       func(e *HookEvent) { calls = append(calls, "error "+e.Err.Error()) },
+	}
+
+	create := func(name string, result error) error {
+		def := &DBApiDefinition{APIDefinition: &apidef.APIDefinition{Name: name}}
+		e := &HookEvent{Action: HookCreate, API: def}
+		return h.Run(e, func() error {
+			calls = append(calls, "create")
+			e.ID = "id1"
+			return result
+		}, errUseUpdate)
+	}
+
+	if err := create("ok", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := create("invalid", nil); err == nil {
+		t.Fatal("expected the before hook to stop the create")
+	}
+	if err := create("exists", errUseUpdate); err != errUseUpdate {
+		t.Fatalf("expected the ignored error, got %v", err)
+	}
+
+	expected := []string{"before", "create", "after id1", "before", "error rejected", "before", "create"}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, calls)
+		}
+	}
+
+	var none *Hooks
+	if err := none.Run(&HookEvent{Action: HookDelete}, func() error { return nil }); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,57 @@
+package objects
+
+import (
+	"crypto/sha256"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// zeroID is the object ID of 24 zeros, which files written by hand or by other tools use
+// for an ID that isn't set
+var zeroID = bson.ObjectId(make([]byte, 12))
+
+// EmptyID tells whether id is unset: empty or the zero object ID. Neither is a real ID,
+// objects with an empty ID are matched by their other IDs or created.
+func EmptyID(id bson.ObjectId) bool {
+	return id == "" || id == zeroID
+}
+
+// NormalID returns id, or "" if it is empty, see EmptyID
+func NormalID(id bson.ObjectId) bson.ObjectId {
+	if EmptyID(id) {
+		return ""
+	}
+	return id
+}
+
+// SeedID derives an object ID from a readable seed, e.g. the name of an API, and the org
+// of the object. The same seed always gives the same ID in an org, so repos don't have to
+// carry generated IDs, and orgs sharing a dashboard get different IDs for the same seed.
+func SeedID(orgID, seed string) bson.ObjectId {
+	sum := sha256.Sum256([]byte(orgID + "\x00" + seed))
+	return bson.ObjectId(sum[:12])
+}
+
+// ApplySeed derives the database ID of a definition with an IDSeed from the seed and its org,
+// and its API ID too unless one was set. The IDs follow the org, so it is applied again
+// whenever the org of the definition changes, e.g. to the org of the target.
+func (d *DBApiDefinition) ApplySeed() {
+	if d.IDSeed == "" || d.APIDefinition == nil {
+		return
+	}
+
+	id := SeedID(d.OrgID, d.IDSeed)
+	d.Id = id
+	if d.APIID == "" || d.APIID == d.seededAPIID {
+		d.APIID = id.Hex()
+		d.seededAPIID = d.APIID
+	}
+}
+
+// ApplySeed derives the _id of a policy with an IDSeed from the seed and its org, it is
+// applied again whenever the org of the policy changes
+func (p *Policy) ApplySeed() {
+	if p.IDSeed != "" {
+		p.MID = SeedID(p.OrgID, p.IDSeed)
+	}
+}
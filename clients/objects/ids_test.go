@@ -0,0 +1,68 @@
+package objects
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestEmptyID(t *testing.T) {
+	for id, empty := range map[bson.ObjectId]bool{
+		"": true,
+		bson.ObjectIdHex("000000000000000000000000"): true,
+		bson.ObjectIdHex("5e9d9544a1dcd60001d0ed20"): false,
+	} {
+		if EmptyID(id) != empty {
+			t.Errorf("EmptyID(%q) = %v, want %v", id.Hex(), !empty, empty)
+		}
+	}
+
+	if NormalID(zeroID) != "" {
+		t.Error("the zero ID should be normalised to an empty one")
+	}
+}
+
+func TestSeedID(t *testing.T) {
+	id := SeedID("org1", "payments-api")
+	if !id.Valid() || EmptyID(id) {
+		t.Fatalf("seeded ID %q is not a valid object ID", id.Hex())
+	}
+	if SeedID("org1", "payments-api") != id {
+		t.Error("the same seed should give the same ID")
+	}
+	if SeedID("org1", "orders-api") == id {
+		t.Error("different seeds should give different IDs")
+	}
+	if SeedID("org2", "payments-api") == id || SeedID("org1p", "ayments-api") == id {
+		t.Error("the same seed should give different IDs in different orgs")
+	}
+}
+
+func TestApplySeed(t *testing.T) {
+	def := DBApiDefinition{APIDefinition: &apidef.APIDefinition{OrgID: "org1"}, IDSeed: "payments-api"}
+	def.ApplySeed()
+	if def.Id != SeedID("org1", "payments-api") || def.APIID != def.Id.Hex() {
+		t.Fatalf("expected the IDs derived in org1, got %q and %q", def.Id.Hex(), def.APIID)
+	}
+
+	// The IDs follow the org, an API ID that wasn't seeded is kept
+	def.OrgID = "org2"
+	def.ApplySeed()
+	if def.Id != SeedID("org2", "payments-api") || def.APIID != def.Id.Hex() {
+		t.Errorf("expected the IDs derived in org2, got %q and %q", def.Id.Hex(), def.APIID)
+	}
+	def.APIID = "payments"
+	def.ApplySeed()
+	if def.APIID != "payments" {
+		t.Errorf("expected the API ID of the definition to be kept, got %q", def.APIID)
+	}
+
+	pol := Policy{OrgID: "org1", IDSeed: "gold"}
+	pol.ApplySeed()
+	pol.OrgID = "org2"
+	pol.ApplySeed()
+	if pol.MID != SeedID("org2", "gold") {
+		t.Errorf("expected the policy _id derived in org2, got %q", pol.MID.Hex())
+	}
+}
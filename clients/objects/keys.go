@@ -0,0 +1,72 @@
+package objects
+
+import "time"
+
+// Key is a gateway session with the key it is stored under, sessions are kept as
+// plain JSON so that fields of newer gateway versions survive a dump and restore
+type Key struct {
+	KeyID   string                 `json:"key_id"`
+	Hashed  bool                   `json:"hashed,omitempty"`
+	Session map[string]interface{} `json:"session"`
+}
+
+type KeyListResponse struct {
+	Keys []string `json:"keys"`
+}
+
+// KeyResponse is the reply of the gateway to key writes
+type KeyResponse struct {
+	Key     string `json:"key"`
+	Status  string `json:"status"`
+	Action  string `json:"action"`
+	KeyHash string `json:"key_hash,omitempty"`
+}
+
+// KeyAccess is the access of a key to an API, to its Default version if Versions is empty
+type KeyAccess struct {
+	APIID    string
+	Versions []string
+}
+
+// NewKeySession returns the session of a key of the org applying the policies and granted
+// the access, which never expires if expires is zero. Its own rate limit is 1000 requests a
+// second without quota, policies override them.
+func NewKeySession(orgID string, policies []string, access []KeyAccess, expires time.Time) map[string]interface{} {
+	if policies == nil {
+		policies = []string{}
+	}
+
+	return map[string]interface{}{
+		"org_id":             orgID,
+		"apply_policies":     policies,
+		"access_rights":      AccessRights(access),
+		"allowance":          1000,
+		"rate":               1000,
+		"per":                1,
+		"quota_max":          -1,
+		"quota_renewal_rate": 3600,
+		"expires":            KeyExpiry(expires),
+	}
+}
+
+// AccessRights returns the access_rights of a session granting the access
+func AccessRights(access []KeyAccess) map[string]interface{} {
+	rights := map[string]interface{}{}
+	for _, a := range access {
+		versions := a.Versions
+		if len(versions) == 0 {
+			versions = []string{"Default"}
+		}
+		rights[a.APIID] = map[string]interface{}{"api_id": a.APIID, "versions": versions}
+	}
+
+	return rights
+}
+
+// KeyExpiry returns the expires of a session expiring at t, 0 (never) if t is zero
+func KeyExpiry(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,13 @@
+package objects
+
+// OAuthClient is a client app registered with an API that uses the OAuth 2.0 server of
+// Tyk, its secret is a credential
+type OAuthClient struct {
+	ClientID    string      `json:"client_id"`
+	Secret      string      `json:"secret"`
+	RedirectURI string      `json:"redirect_uri"`
+	APIID       string      `json:"api_id,omitempty"`
+	PolicyID    string      `json:"policy_id,omitempty"`
+	Description string      `json:"description,omitempty"`
+	MetaData    interface{} `json:"meta_data,omitempty"`
+}
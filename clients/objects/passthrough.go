@@ -0,0 +1,411 @@
+package objects
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// KeepRaw stores the raw JSON a definition was read from (either a dump file wrapped in
+// api_definition or a plain definition), so that fields the vendored apidef does not know
+// can be passed through to the target
+func (d *DBApiDefinition) KeepRaw(raw []byte) error {
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+
+	if inner, ok := doc["api_definition"].(map[string]interface{}); ok {
+		doc = inner
+	}
+
+	d.Passthrough = doc
+	return nil
+}
+
+// APIFromRaw decodes a raw API definition read from a target, keeping the fields the
+// vendored apidef doesn't know, so that updating it doesn't lose them
+func APIFromRaw(raw map[string]interface{}) (*DBApiDefinition, error) {
+	doc, err := json.Marshal(map[string]interface{}{"api_definition": raw})
+	if err != nil {
+		return nil, err
+	}
+
+	def := &DBApiDefinition{}
+	if err := json.Unmarshal(doc, def); err != nil {
+		return nil, err
+	}
+	if def.APIDefinition == nil {
+		return nil, errors.New("empty API definition")
+	}
+
+	return def, nil
+}
+
+// SetRaw sets a field the vendored apidef doesn't know in the raw definition, it is only
+// sent to targets with passthrough
+func (d *DBApiDefinition) SetRaw(key string, value interface{}) {
+	if d.Passthrough == nil {
+		d.Passthrough = map[string]interface{}{}
+	}
+	d.Passthrough[key] = value
+}
+
+// DropRaw forgets the unknown fields, only the known ones will be encoded or sent
+func (d *DBApiDefinition) DropRaw() {
+	d.Passthrough = nil
+	d.extra = nil
+}
+
+// schema are the JSON keys a Go type decodes: the fields of a struct, or the element of
+// a map or slice. Keys a schema knows are owned by the typed value, only the others are
+// passed through from the raw one
+type schema struct {
+	fields map[string]*schema
+	elem   *schema
+}
+
+var (
+	schemaMu    sync.Mutex
+	schemaCache = map[reflect.Type]*schema{}
+
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaOf returns the schema of t, nil for values encoded as a whole (scalars, interfaces
+// and types with their own MarshalJSON)
+func schemaOf(t reflect.Type) *schema {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+
+	return buildSchema(t)
+}
+
+func buildSchema(t reflect.Type) *schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return nil
+	}
+	if s, ok := schemaCache[t]; ok {
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		s := &schema{fields: map[string]*schema{}}
+		// Cached before the fields so recursive types terminate
+		schemaCache[t] = s
+		addFields(s, t)
+		return s
+	case reflect.Map, reflect.Slice, reflect.Array:
+		s := &schema{}
+		schemaCache[t] = s
+		s.elem = buildSchema(t.Elem())
+		return s
+	}
+
+	return nil
+}
+
+// addFields adds the JSON keys of the fields of struct t to s, the fields of embedded
+// structs without a JSON name are promoted as encoding/json does
+func addFields(s *schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(s, ft)
+				continue
+			}
+		}
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		s.fields[name] = buildSchema(f.Type)
+	}
+}
+
+// merge overlays the typed value on the raw one: the keys the schema knows are taken from
+// the typed value only, so removing or clearing them sticks, the other keys of the raw value
+// are kept
+func merge(raw, typed interface{}, s *schema) interface{} {
+	if s == nil {
+		return typed
+	}
+
+	switch t := typed.(type) {
+	case map[string]interface{}:
+		r, ok := raw.(map[string]interface{})
+		if !ok {
+			return typed
+		}
+
+		for k, rv := range r {
+			tv, present := t[k]
+			if s.fields == nil {
+				// A map: entries missing from the typed value were removed
+				if present {
+					t[k] = merge(rv, tv, s.elem)
+				}
+				continue
+			}
+
+			fs, known := s.fields[k]
+			switch {
+			case !known:
+				t[k] = rv
+			case present:
+				t[k] = merge(rv, tv, fs)
+			}
+		}
+		return t
+	case []interface{}:
+		r, ok := raw.([]interface{})
+		if !ok || len(r) != len(t) {
+			return typed
+		}
+
+		for i := range t {
+			t[i] = merge(r[i], t[i], s.elem)
+		}
+		return t
+	}
+
+	return typed
+}
+
+// DefinitionPayload returns the API definition to send to a target, with the unknown fields
+// of the raw definition merged back in when passthrough is enabled and the Strip fields removed
+func (d *DBApiDefinition) DefinitionPayload() (interface{}, error) {
+	if d.Passthrough == nil && len(d.Strip) == 0 {
+		return d.APIDefinition, nil
+	}
+
+	raw, err := json.Marshal(d.APIDefinition)
+	if err != nil {
+		return nil, err
+	}
+
+	typed := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &typed); err != nil {
+		return nil, err
+	}
+
+	if d.Passthrough == nil {
+		stripFields(typed, d.Strip)
+		return typed, nil
+	}
+
+	// Round trip the stored copy, merge modifies it in place
+	stored, err := json.Marshal(d.Passthrough)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawDef interface{}
+	if err := json.Unmarshal(stored, &rawDef); err != nil {
+		return nil, err
+	}
+
+	payload := merge(rawDef, typed, schemaOf(reflect.TypeOf(d.APIDefinition)))
+	if doc, ok := payload.(map[string]interface{}); ok {
+		stripFields(doc, d.Strip)
+	}
+
+	return payload, nil
+}
+
+// stripFields removes the dotted paths from doc, paths that don't exist are ignored
+func stripFields(doc map[string]interface{}, paths []string) {
+	for _, p := range paths {
+		parts := strings.Split(p, ".")
+		parent := doc
+		for _, key := range parts[:len(parts)-1] {
+			next, ok := parent[key].(map[string]interface{})
+			if !ok {
+				parent = nil
+				break
+			}
+			parent = next
+		}
+
+		if parent != nil {
+			delete(parent, parts[len(parts)-1])
+		}
+	}
+}
+
+// Stripped tells whether field, or an object holding it, is removed from the payload
+func (d *DBApiDefinition) Stripped(field string) bool {
+	for _, p := range d.Strip {
+		if p == field || strings.HasPrefix(field, p+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dbAPIDefinition has the fields but not the methods of DBApiDefinition, so it can be used
+// to encode and decode the known fields
+type dbAPIDefinition DBApiDefinition
+
+// knownKeys are the JSON keys of the fields of v
+func knownKeys(v interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]interface{}{}
+	err = json.Unmarshal(raw, &known)
+	return known, err
+}
+
+// UnmarshalJSON decodes the known fields and keeps the raw definition, as well as any keys
+// next to api_definition, so fields the vendored apidef doesn't know survive a round trip
+func (d *DBApiDefinition) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*dbAPIDefinition)(d)); err != nil {
+		return err
+	}
+
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	inner, ok := doc["api_definition"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	d.Passthrough = inner
+
+	known := schemaOf(reflect.TypeOf(dbAPIDefinition{})).fields
+
+	d.extra = nil
+	for k, v := range doc {
+		if _, ok := known[k]; ok {
+			continue
+		}
+
+		if d.extra == nil {
+			d.extra = map[string]interface{}{}
+		}
+		d.extra[k] = v
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the known fields, with the unknown fields kept on decoding merged back in
+func (d DBApiDefinition) MarshalJSON() ([]byte, error) {
+	if d.Passthrough == nil && d.extra == nil && len(d.Strip) == 0 {
+		return json.Marshal(dbAPIDefinition(d))
+	}
+
+	doc, err := knownKeys(dbAPIDefinition(d))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range d.extra {
+		if _, ok := doc[k]; !ok {
+			doc[k] = v
+		}
+	}
+
+	if d.APIDefinition != nil {
+		def, err := d.DefinitionPayload()
+		if err != nil {
+			return nil, err
+		}
+		doc["api_definition"] = def
+	}
+
+	return json.Marshal(doc)
+}
+
+// VendoredVersion is the Tyk version the apidef package is vendored from
+const VendoredVersion = "2.9.4"
+
+func parseVersion(v string) ([3]int, error) {
+	parsed := [3]int{}
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+
+	parts := strings.Split(v, ".")
+	if len(parts) > 3 {
+		parts = parts[:3]
+	}
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid version %q", v)
+		}
+		parsed[i] = n
+	}
+
+	return parsed, nil
+}
+
+// NewerThanVendored reports if a target version (e.g. "v3.0.1") is newer than the apidef
+// tyk-sync was built with, and so may use fields that would be lost without passthrough
+func NewerThanVendored(version string) (bool, error) {
+	target, err := parseVersion(version)
+	if err != nil {
+		return false, err
+	}
+
+	vendored, _ := parseVersion(VendoredVersion)
+	for i := range target {
+		if target[i] != vendored[i] {
+			return target[i] > vendored[i], nil
+		}
+	}
+
+	return false, nil
+}
+
+// AtLeastVersion reports if a version (e.g. "v3.0.1") is min or newer
+func AtLeastVersion(version, min string) (bool, error) {
+	v, err := parseVersion(version)
+	if err != nil {
+		return false, err
+	}
+
+	m, err := parseVersion(min)
+	if err != nil {
+		return false, err
+	}
+
+	for i := range v {
+		if v[i] != m[i] {
+			return v[i] > m[i], nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,232 @@
+package objects
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDefinitionPayloadKeepsUnknownFields(t *testing.T) {
+	raw := []byte(`{"api_definition": {
+		"name": "old name",
+		"api_id": "a1",
+		"graphql": {"enabled": true},
+		"proxy": {"listen_path": "/a/", "preserve_host_header": true},
+		"tags": ["one"]
+	}}`)
+
+	def := DBApiDefinition{}
+	if err := json.Unmarshal(raw, &def); err != nil {
+		t.Fatal(err)
+	}
+	if err := def.KeepRaw(raw); err != nil {
+		t.Fatal(err)
+	}
+	def.Name = "new name"
+
+	payload, err := def.DefinitionPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := payload.(map[string]interface{})
+
+	if out["name"] != "new name" {
+		t.Errorf("typed field not preferred, got %v", out["name"])
+	}
+	if _, ok := out["graphql"]; !ok {
+		t.Error("unknown top level field dropped")
+	}
+	if out["proxy"].(map[string]interface{})["preserve_host_header"] != true {
+		t.Error("unknown nested field dropped")
+	}
+
+	// The stored raw definition must not be changed by building a payload
+	if def.Passthrough["name"] != "old name" {
+		t.Errorf("raw definition modified, got %v", def.Passthrough["name"])
+	}
+
+	def.Passthrough = nil
+	if payload, _ := def.DefinitionPayload(); payload != def.APIDefinition {
+		t.Error("expected the typed definition without passthrough")
+	}
+}
+
+func TestNewerThanVendored(t *testing.T) {
+	tests := map[string]bool{
+		"v2.9.4":     false,
+		"2.9.3":      false,
+		"v2.9.5":     true,
+		"v3.0.0-rc1": true,
+		"v2.10":      true,
+		"v1.9.9":     false,
+	}
+
+	for version, expected := range tests {
+		newer, err := NewerThanVendored(version)
+		if err != nil {
+			t.Fatalf("%v: %v", version, err)
+		}
+		if newer != expected {
+			t.Errorf("%v: expected %v, got %v", version, expected, newer)
+		}
+	}
+
+	if _, err := NewerThanVendored("latest"); err == nil {
+		t.Error("expected an error for an invalid version")
+	}
+}
+
+func TestAtLeastVersion(t *testing.T) {
+	tests := map[string]bool{
+		"v2.9.0": true,
+		"2.10.1": true,
+		"v2.8.9": false,
+	}
+
+	for version, expected := range tests {
+		atLeast, err := AtLeastVersion(version, "2.9")
+		if err != nil {
+			t.Fatalf("%v: %v", version, err)
+		}
+		if atLeast != expected {
+			t.Errorf("%v: expected %v, got %v", version, expected, atLeast)
+		}
+	}
+}
+
+func TestDBApiDefinitionRoundTrip(t *testing.T) {
+	raw := []byte(`{
+		"api_definition": {"name": "A", "graphql": {"graphql_playground": {"enabled": true}}},
+		"is_site": true,
+		"labels": ["x"]
+	}`)
+
+	def := DBApiDefinition{}
+	if err := json.Unmarshal(raw, &def); err != nil {
+		t.Fatal(err)
+	}
+	def.Name = "B"
+
+	out, err := json.Marshal(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	inner := doc["api_definition"].(map[string]interface{})
+	if inner["name"] != "B" {
+		t.Errorf("expected the changed name, got %v", inner["name"])
+	}
+	if _, ok := inner["graphql"]; !ok {
+		t.Error("unknown definition field dropped")
+	}
+	if doc["is_site"] != true {
+		t.Error("known field dropped")
+	}
+	if _, ok := doc["labels"]; !ok {
+		t.Error("unknown top level field dropped")
+	}
+
+	def.DropRaw()
+	out, _ = json.Marshal(def)
+	dropped := map[string]interface{}{}
+	if err := json.Unmarshal(out, &dropped); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dropped["labels"]; ok {
+		t.Error("unknown field kept after DropRaw")
+	}
+}
+
+func TestDefinitionPayloadStripsFields(t *testing.T) {
+	raw := []byte(`{"api_definition": {
+		"api_id": "a1",
+		"listen_port": 8443,
+		"graphql": {"enabled": true},
+		"proxy": {"listen_path": "/a/", "preserve_host_header": true}
+	}}`)
+
+	def := DBApiDefinition{}
+	if err := json.Unmarshal(raw, &def); err != nil {
+		t.Fatal(err)
+	}
+	def.Strip = []string{"listen_port", "graphql", "proxy.preserve_host_header", "no.such.field"}
+
+	payload, err := def.DefinitionPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := payload.(map[string]interface{})
+
+	for _, field := range []string{"listen_port", "graphql"} {
+		if _, ok := out[field]; ok {
+			t.Errorf("%v not stripped", field)
+		}
+	}
+	proxy := out["proxy"].(map[string]interface{})
+	if _, ok := proxy["preserve_host_header"]; ok || proxy["listen_path"] != "/a/" {
+		t.Errorf("unexpected proxy: %v", proxy)
+	}
+
+	def.DropRaw()
+	payload, err = def.DefinitionPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := payload.(map[string]interface{})["listen_port"]; ok {
+		t.Error("listen_port not stripped without passthrough")
+	}
+
+	if !def.Stripped("proxy.preserve_host_header") || def.Stripped("proxy.listen_path") {
+		t.Error("unexpected stripped fields")
+	}
+}
+
+func TestDefinitionPayloadKeepsRemovedFields(t *testing.T) {
+	raw := []byte(`{"api_definition": {
+		"id": "5e0fac4845bb46c77543be28",
+		"api_id": "a1",
+		"graphql": {"enabled": true},
+		"config_data": {"a": 1, "b": 2},
+		"version_data": {"not_versioned": true, "versions": {
+			"Default": {"name": "Default", "use_extended_paths": true},
+			"v2": {"name": "v2", "new_field": "x"}
+		}}
+	}}`)
+
+	def := DBApiDefinition{}
+	if err := json.Unmarshal(raw, &def); err != nil {
+		t.Fatal(err)
+	}
+	def.Id = ""
+	delete(def.ConfigData, "a")
+	delete(def.VersionData.Versions, "v2")
+
+	out, err := json.Marshal(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+	inner := doc["api_definition"].(map[string]interface{})
+
+	if _, ok := inner["id"]; ok {
+		t.Errorf("cleared id encoded: %v", inner["id"])
+	}
+	if config := inner["config_data"].(map[string]interface{}); len(config) != 1 || config["b"] == nil {
+		t.Errorf("removed config_data key encoded: %v", config)
+	}
+	versions := inner["version_data"].(map[string]interface{})["versions"].(map[string]interface{})
+	if _, ok := versions["v2"]; ok || versions["Default"] == nil {
+		t.Errorf("removed version encoded: %v", versions)
+	}
+	if _, ok := inner["graphql"]; !ok {
+		t.Error("unknown field dropped")
+	}
+}
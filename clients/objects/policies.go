@@ -77,4 +77,8 @@ type Policy struct {
 	} `bson:"partitions" json:"partitions"`
 	LastUpdated string                 `bson:"last_updated" json:"last_updated"`
 	MetaData    map[string]interface{} `bson:"meta_data" json:"meta_data"`
+	// File is the file of the repo the policy was read from, if any
+	File string `bson:"-" json:"-"`
+	// IDSeed is the id_seed the _id of the policy is derived from, see ApplySeed
+	IDSeed string `bson:"-" json:"-"`
 }
\ No newline at end of file
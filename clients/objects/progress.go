@@ -0,0 +1,84 @@
+package objects
+
+import (
+	"time"
+)
+
+// Phases of a sync, in the order they run
+const (
+	PhaseDelete = "delete"
+	PhaseUpdate = "update"
+	PhaseCreate = "create"
+)
+
+// ProgressEvent is how far a sync of one kind of object got: Done of its Total changes
+// were applied, Phase is the one running
+type ProgressEvent struct {
+	// Kind is APIs or policies, like SyncPlan.Kind
+	Kind  string
+	Phase string
+	Done  int
+	Total int
+	// Item is the ID or name of the object done last, empty when a phase starts
+	Item string
+	// Started is when the first change was applied
+	Started time.Time
+}
+
+// Elapsed is the time since the sync started applying changes
+func (e ProgressEvent) Elapsed() time.Duration {
+	return time.Since(e.Started)
+}
+
+// ETA estimates the time left from the pace so far, it is zero until a change is done
+func (e ProgressEvent) ETA() time.Duration {
+	if e.Done == 0 || e.Done >= e.Total {
+		return 0
+	}
+
+	return e.Elapsed() / time.Duration(e.Done) * time.Duration(e.Total-e.Done)
+}
+
+// Progress is told how a sync advances, e.g. to render a progress bar or to surface the
+// status in the UI of a program embedding the clients. Progress is called when each phase
+// starts and after every change, from the goroutine running the sync.
+type Progress interface {
+	Progress(e ProgressEvent)
+}
+
+// ProgressFunc lets a function be used as Progress
+type ProgressFunc func(e ProgressEvent)
+
+func (f ProgressFunc) Progress(e ProgressEvent) {
+	f(e)
+}
+
+// ProgressTracker reports the progress of one sync to a Progress, it does nothing if the
+// Progress is nil
+type ProgressTracker struct {
+	p Progress
+	e ProgressEvent
+}
+
+// TrackProgress starts reporting a sync of total changes of kind to p, p may be nil
+func TrackProgress(p Progress, kind string, total int) *ProgressTracker {
+	return &ProgressTracker{p: p, e: ProgressEvent{Kind: kind, Total: total, Started: time.Now()}}
+}
+
+// Phase reports that a phase starts, phases without changes are skipped
+func (t *ProgressTracker) Phase(phase string, changes int) {
+	t.e.Phase = phase
+	t.e.Item = ""
+	if t.p != nil && changes > 0 {
+		t.p.Progress(t.e)
+	}
+}
+
+// Done reports that the change of item was applied
+func (t *ProgressTracker) Done(item string) {
+	t.e.Done++
+	t.e.Item = item
+	if t.p != nil {
+		t.p.Progress(t.e)
+	}
+}
@@ -0,0 +1,44 @@
+package objects
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressTracker(t *testing.T) {
+	events := []ProgressEvent{}
+	tr := TrackProgress(ProgressFunc(func(e ProgressEvent) {
+		events = append(events, e)
+	}), "APIs", 3)
+
+	tr.Phase(PhaseDelete, 0)
+	tr.Phase(PhaseUpdate, 2)
+	tr.Done("a1")
+	tr.Done("a2")
+	tr.Phase(PhaseCreate, 1)
+	tr.Done("a3")
+
+	if len(events) != 5 {
+		t.Fatalf("expected the empty delete phase skipped, got %v events", len(events))
+	}
+	if e := events[2]; e.Phase != PhaseUpdate || e.Done != 2 || e.Item != "a2" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if e := events[4]; e.Phase != PhaseCreate || e.Done != 3 || e.Total != 3 || e.ETA() != 0 {
+		t.Errorf("unexpected last event %+v", e)
+	}
+
+	// Nothing is reported, or panics, without a Progress
+	TrackProgress(nil, "APIs", 1).Done("a1")
+}
+
+func TestProgressETA(t *testing.T) {
+	e := ProgressEvent{Done: 1, Total: 5, Started: time.Now().Add(-10 * time.Second)}
+	if eta := e.ETA(); eta < 39*time.Second || eta > 41*time.Second {
+		t.Errorf("expected about 40s left, got %v", eta)
+	}
+
+	if eta := (ProgressEvent{Total: 5, Started: time.Now()}).ETA(); eta != 0 {
+		t.Errorf("expected no estimate before a change is done, got %v", eta)
+	}
+}
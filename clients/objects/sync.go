@@ -0,0 +1,76 @@
+package objects
+
+// SyncItem is a single object a sync is going to create, update or delete
+type SyncItem struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	// Tags are the tags of the API on the target, for updates and deletes of APIs
+	Tags []string `json:"tags,omitempty"`
+	// Index points into the list of objects the item was planned from
+	Index int `json:"-"`
+}
+
+// SyncPlan lists the changes a sync will make for one kind of object (APIs or policies)
+type SyncPlan struct {
+	Kind     string     `json:"kind"`
+	Existing int        `json:"existing"`
+	Create   []SyncItem `json:"create"`
+	Update   []SyncItem `json:"update"`
+	Delete   []SyncItem `json:"delete"`
+	// Deactivate is set when the objects removed from git are deactivated instead of deleted
+	Deactivate bool `json:"deactivate,omitempty"`
+}
+
+// PlanCheck is called with the plan before a sync applies it, returning an error aborts the
+// sync. Items removed from the plan are skipped.
+type PlanCheck func(plan *SyncPlan) error
+
+// Kept returns the indexes of the items in a list
+func Kept(items []SyncItem) map[int]bool {
+	kept := make(map[int]bool, len(items))
+	for _, item := range items {
+		kept[item.Index] = true
+	}
+
+	return kept
+}
+
+// KeepIDs returns the IDs of a list whose items are still in items after a plan check
+func KeepIDs(ids []string, items []SyncItem) []string {
+	kept := Kept(items)
+	out := []string{}
+	for i, id := range ids {
+		if kept[i] {
+			out = append(out, id)
+		}
+	}
+
+	return out
+}
+
+// KeepAPIs returns the APIs of a list whose items are still in items after a plan check
+func KeepAPIs(apis []DBApiDefinition, items []SyncItem) []DBApiDefinition {
+	kept := Kept(items)
+	out := []DBApiDefinition{}
+	for i, api := range apis {
+		if kept[i] {
+			out = append(out, api)
+		}
+	}
+
+	return out
+}
+
+// KeepPolicies returns the policies of a list whose items are still in items after a plan
+// check
+func KeepPolicies(pols []Policy, items []SyncItem) []Policy {
+	kept := Kept(items)
+	out := []Policy{}
+	for i, pol := range pols {
+		if kept[i] {
+			out = append(out, pol)
+		}
+	}
+
+	return out
+}
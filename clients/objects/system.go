@@ -0,0 +1,25 @@
+package objects
+
+import "time"
+
+// Licence is the licence state reported by the dashboard admin API
+type Licence struct {
+	Valid        bool      `json:"valid"`
+	Type         string    `json:"type"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	NodesAllowed int       `json:"nodes_allowed"`
+}
+
+// GatewayNode is a gateway registered with the dashboard
+type GatewayNode struct {
+	NodeID        string    `json:"node_id"`
+	Hostname      string    `json:"hostname"`
+	Version       string    `json:"version"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	// Checksum identifies the set of API definitions the node has loaded
+	Checksum string `json:"apis_checksum,omitempty"`
+}
+
+type GatewayNodesResponse struct {
+	Nodes []GatewayNode `json:"nodes"`
+}
@@ -5,6 +5,14 @@ type UsersResponse struct {
 }
 
 type User struct {
-	OrgID     string `json:"org_id"`
-	AccessKey string `json:"access_key"`
+	ID           string `json:"id"`
+	EmailAddress string `json:"email_address"`
+	OrgID        string `json:"org_id"`
+	AccessKey    string `json:"access_key"`
+}
+
+// HealthCheck is the response of the /hello endpoint of the gateway and dashboard
+type HealthCheck struct {
+	Status  string `json:"status"`
+	Version string `json:"version"`
 }
@@ -0,0 +1,323 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the secrets of recorded interactions
+const Redacted = "REDACTED"
+
+// SecretHeaders are the headers whose values are never recorded
+var SecretHeaders = []string{"Authorization", "X-Tyk-Authorization", "Admin-Auth", "Cookie", "Set-Cookie"}
+
+// SecretFields are the JSON keys, at any depth of a body, whose values are never recorded
+var SecretFields = []string{"access_key", "secret", "shared_secret", "password", "key", "key_id", "key_hash", "jwt_source"}
+
+var privateKeyBlock = regexp.MustCompile(`(?s)-----BEGIN ([A-Z ]*)PRIVATE KEY-----.*?-----END ([A-Z ]*)PRIVATE KEY-----`)
+
+// Interaction is a recorded request and the response it got
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request of an interaction, with its secrets redacted
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response of an interaction, with its secrets redacted
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Cassette is the file interactions are recorded to and replayed from, as JSON
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// ReadCassette reads a cassette file
+func ReadCassette(path string) (*Cassette, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cassette{}
+	if err := json.Unmarshal(raw, c); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	return c, nil
+}
+
+// Save writes the cassette to path, replacing the file at once so that it always holds
+// a whole cassette, even if the program exits while recording
+func (c *Cassette) Save(path string) error {
+	raw, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func sanitizeHeaders(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+
+	out := http.Header{}
+	for k, v := range h {
+		out[k] = append([]string{}, v...)
+	}
+	for _, k := range SecretHeaders {
+		if out.Get(k) != "" {
+			out.Set(k, Redacted)
+		}
+	}
+
+	return out
+}
+
+func redactFields(node interface{}) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			secret := false
+			for _, f := range SecretFields {
+				if strings.EqualFold(k, f) {
+					secret = true
+					break
+				}
+			}
+
+			if s, ok := v.(string); secret && ok && s != "" {
+				n[k] = Redacted
+				continue
+			}
+			n[k] = redactFields(v)
+		}
+	case []interface{}:
+		for i, v := range n {
+			n[i] = redactFields(v)
+		}
+	}
+
+	return node
+}
+
+// sanitizeBody redacts the secret fields of JSON bodies, and private keys in any body
+func sanitizeBody(raw []byte) string {
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	// Keep numbers as they were, e.g. expiry timestamps
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err == nil && !dec.More() {
+		if out, err := json.Marshal(redactFields(doc)); err == nil {
+			return string(out)
+		}
+	}
+
+	return privateKeyBlock.ReplaceAllString(string(raw), "-----BEGIN ${1}PRIVATE KEY-----\n"+Redacted+"\n-----END ${2}PRIVATE KEY-----")
+}
+
+// readBody reads a body and returns a reader to put back in its place
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	raw, err := ioutil.ReadAll(*body)
+	(*body).Close()
+	*body = ioutil.NopCloser(bytes.NewReader(raw))
+	return raw, err
+}
+
+// Recorder records the interactions of the transports it wraps to a cassette, with secrets
+// redacted so the cassette can be attached to a bug report
+type Recorder struct {
+	path string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder returns a recorder saving its cassette to path after each interaction, or only
+// keeping it in memory if path is empty
+func NewRecorder(path string) *Recorder {
+	return &Recorder{path: path}
+}
+
+// recording is a transport recording to a Recorder
+type recording struct {
+	r    *Recorder
+	base http.RoundTripper
+}
+
+// Wrap returns a transport recording the interactions of base
+func (r *Recorder) Wrap(base http.RoundTripper) http.RoundTripper {
+	return &recording{r: r, base: base}
+}
+
+func (t *recording) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r := t.r
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: RecordedRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: sanitizeHeaders(req.Header),
+			Body:    sanitizeBody(reqBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    sanitizeHeaders(resp.Header),
+			Body:       sanitizeBody(respBody),
+		},
+	})
+
+	if r.path != "" {
+		if err := r.cassette.Save(r.path); err != nil {
+			return nil, fmt.Errorf("recording %v: %v", r.path, err)
+		}
+	}
+
+	return resp, nil
+}
+
+// Interactions returns what was recorded so far
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Interaction{}, r.cassette.Interactions...)
+}
+
+// Replayer is a transport answering requests from a cassette without sending them. A request
+// gets the response of the first interaction not replayed yet with the same method, path and
+// query, the host is ignored so cassettes replay against any URL.
+type Replayer struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayer replays the interactions of cassette
+func NewReplayer(cassette *Cassette) *Replayer {
+	return &Replayer{cassette: cassette, used: make([]bool, len(cassette.Interactions))}
+}
+
+// Wrap returns the replayer itself, base is never used
+func (r *Replayer) Wrap(base http.RoundTripper) http.RoundTripper {
+	return r
+}
+
+// target is the part of a URL interactions are matched on
+func target(raw string) string {
+	if i := strings.Index(raw, "://"); i >= 0 {
+		raw = raw[i+3:]
+		if j := strings.Index(raw, "/"); j >= 0 {
+			return raw[j:]
+		}
+		return "/"
+	}
+
+	return raw
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	want := target(req.URL.String())
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || in.Request.Method != req.Method || target(in.Request.URL) != want {
+			continue
+		}
+		r.used[i] = true
+
+		header := http.Header{}
+		for k, v := range in.Response.Headers {
+			header[k] = v
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no recorded interaction left for %v %v", req.Method, want)
+}
+
+// Remaining returns how many interactions were not replayed
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
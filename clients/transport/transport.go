@@ -0,0 +1,84 @@
+// Package transport holds the HTTP transports the dashboard and gateway clients share, so
+// that clients used in parallel (e.g. one per org) reuse connections
+package transport
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+var (
+	mu       sync.Mutex
+	secure   *http.Transport
+	insecure *http.Transport
+	vcr      VCR
+)
+
+// VCR records or replays the requests of clients, a Recorder or a Replayer
+type VCR interface {
+	Wrap(base http.RoundTripper) http.RoundTripper
+}
+
+// SetVCR makes the clients created from now on send their requests through v, nil stops
+// recording or replaying
+func SetVCR(v VCR) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	vcr = v
+}
+
+// RoundTripper returns the shared transport, wrapped by the VCR if one is set
+func RoundTripper(insecureSkipVerify bool) http.RoundTripper {
+	t := Transport(insecureSkipVerify)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if vcr == nil {
+		return t
+	}
+	return vcr.Wrap(t)
+}
+
+// Transport returns the shared transport, insecureSkipVerify selects the one that does not
+// verify TLS certificates
+func Transport(insecureSkipVerify bool) *http.Transport {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if insecureSkipVerify {
+		if insecure == nil {
+			insecure = newTransport(true)
+		}
+		return insecure
+	}
+
+	if secure == nil {
+		secure = newTransport(false)
+	}
+	return secure
+}
+
+func newTransport(insecureSkipVerify bool) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+	return t
+}
+
+// CheckRedirect follows up to 10 redirects like net/http does by default. Clients that are
+// shared must set it: grequests otherwise installs its own on first use, without locking.
+func CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	return nil
+}
+
+// Client returns an HTTP client using the shared transport
+func Client(insecureSkipVerify bool) *http.Client {
+	return &http.Client{Transport: RoundTripper(insecureSkipVerify), CheckRedirect: CheckRedirect}
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/spf13/cobra"
+)
+
+// activateCmd represents the activate command
+var activateCmd = &cobra.Command{
+	Use:   "activate",
+	Short: "Activate APIs of a gateway or dashboard by ID or tag",
+	Long: `Activate sets active on the APIs of the target with the given IDs (--id) or any of the
+	given tags (--tag), e.g. to bring back APIs deactivated by deactivate or sync --deactivate-removed.
+	The rest of their definitions is left as it is on the target.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processSetActive(cmd, args, true)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// deactivateCmd represents the deactivate command
+var deactivateCmd = &cobra.Command{
+	Use:   "deactivate",
+	Short: "Deactivate APIs of a gateway or dashboard by ID or tag, without deleting them",
+	Long: `Deactivate unsets active on the APIs of the target with the given IDs (--id) or any of the
+	given tags (--tag), after confirmation. The gateways stop serving them, but they keep their
+	IDs, keys and definitions, so activate brings them back. Use --yes to skip the confirmation.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processSetActive(cmd, args, false)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func processSetActive(cmd *cobra.Command, args []string, active bool) error {
+	action, doing, done := "Activate", "Activating", "activated"
+	if !active {
+		action, doing, done = "Deactivate", "Deactivating", "deactivated"
+	}
+
+	ids, _ := cmd.Flags().GetStringSlice("id")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	if len(ids) == 0 && len(tags) == 0 {
+		return errors.New("set --id or --tag to select the APIs")
+	}
+
+	yes, _ := cmd.Flags().GetBool("yes")
+	if !yes && !isInteractive() {
+		return fmt.Errorf("%v asks for confirmation, use --yes when not running in a terminal", cmd.Use)
+	}
+
+	publisher, err := getPublisher(cmd, args)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Using publisher: %v\n", publisher.Name())
+
+	lister, ok := publisher.(tyk_vcs.APILister)
+	if !ok && len(tags) > 0 {
+		return fmt.Errorf("%v can't list the APIs to select by tag", publisher.Name())
+	}
+
+	selected := []objects.DBApiDefinition{}
+	if ok {
+		fmt.Println("> Fetching APIs")
+		apis, err := lister.FetchAPIs()
+		if err != nil {
+			return err
+		}
+
+		found, missing := tyk_vcs.SelectAPIs(apis, ids, tags)
+		if len(missing) > 0 {
+			return fmt.Errorf("no API with the IDs %v was found", strings.Join(missing, ", "))
+		}
+
+		for _, api := range found {
+			if api.Active == active {
+				fmt.Printf("--> Skipping %v (%v), it is already %v\n", api.Name, api.APIID, done)
+				continue
+			}
+			selected = append(selected, api)
+		}
+	} else {
+		for _, id := range ids {
+			selected = append(selected, objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: id}})
+		}
+	}
+
+	if len(selected) == 0 {
+		fmt.Println("Nothing to do.")
+		return nil
+	}
+
+	fmt.Printf("--> Found %v APIs:\n", len(selected))
+	for _, api := range selected {
+		fmt.Printf("  - %v (%v) on %v\n", api.Name, api.APIID, api.Proxy.ListenPath)
+	}
+
+	if !yes && !confirm(fmt.Sprintf("%v these %v APIs?", action, len(selected))) {
+		return errors.New("aborted by user")
+	}
+
+	failed := 0
+	for _, api := range selected {
+		fmt.Printf("> %v: %v (%v)\n", doing, api.Name, api.APIID)
+		patch := tyk_vcs.ActivePatch(api.APIID, active)
+		if _, err := patch.Apply(publisher, false); err != nil {
+			fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("--> Status: OK, ID:%v\n", api.APIID)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v APIs could not be %v", failed, len(selected), done)
+	}
+
+	fmt.Println("Done.")
+	return nil
+}
+
+func init() {
+	for _, c := range []*cobra.Command{activateCmd, deactivateCmd} {
+		RootCmd.AddCommand(c)
+
+		c.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+		c.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+		c.Flags().StringP("secret", "s", "", "Your API secret")
+		c.Flags().StringSlice("id", []string{}, "IDs of the APIs (repeatable)")
+		c.Flags().StringSlice("tag", []string{}, "Select the APIs with this tag (repeatable)")
+		c.Flags().BoolP("yes", "y", false, "Apply without asking for confirmation")
+		c.Flags().Bool("test", false, "Use test publisher, output results to stdio")
+		c.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+	}
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// analyzeCmd represents the analyze command
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Report the size and complexity of the API definitions in a Github repo or file system",
+	Long: `Analyze reports, for every API definition of a Github repo or file system, its size,
+	number of versions, extended path entries, regular expression paths and middleware hooks,
+	the largest first, to spot the definitions that will hurt gateway performance before they
+	are published, and the deprecated fields they use. With the --max-* limits the
+	definitions exceeding them are reported and the command fails, e.g. in CI, as it does
+	when a policy breaks the guardrails of the spec. With
+	--check-idp the JWKS URLs and OpenID Connect issuers the definitions reference are
+	fetched, and the command fails if one can't be read or holds no usable signing key.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processAnalyze(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func processAnalyze(cmd *cobra.Command, args []string) error {
+	getter, err := NewGetter(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	if err := getter.FetchRepo(); err != nil {
+		return err
+	}
+
+	spec, err := getter.FetchTykSpec()
+	if err != nil {
+		return err
+	}
+
+	defs, err := getter.FetchAPIDef(spec)
+	if err != nil {
+		return err
+	}
+
+	limits := tyk_vcs.ComplexityLimits{}
+	limits.Size, _ = cmd.Flags().GetInt("max-size")
+	limits.Versions, _ = cmd.Flags().GetInt("max-versions")
+	limits.Paths, _ = cmd.Flags().GetInt("max-paths")
+	limits.RegexPaths, _ = cmd.Flags().GetInt("max-regex-paths")
+	limits.Middleware, _ = cmd.Flags().GetInt("max-middleware")
+
+	report, err := tyk_vcs.AnalyzeAPIs(defs, limits)
+	if err != nil {
+		return err
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	}
+
+	fmt.Printf("> Analyzed %v APIs\n", len(report))
+	exceeded := 0
+	for _, c := range report {
+		line := fmt.Sprintf("API %v (%v): %.1f KB, %v versions, %v paths (%v regex), %v middleware hooks",
+			c.Name, c.APIID, float64(c.Size)/1024, c.Versions, c.Paths, c.RegexPaths, c.Middleware)
+		if len(c.Exceeds) > 0 {
+			exceeded++
+			fmt.Printf("--> [WARNING] %v, exceeds %v\n", line, strings.Join(c.Exceeds, ", "))
+			continue
+		}
+		fmt.Printf("--> %v\n", line)
+	}
+
+	for _, w := range tyk_vcs.DeprecationWarnings(defs, "") {
+		fmt.Printf("--> [WARNING] %v\n", w)
+	}
+
+	if exceeded > 0 {
+		return fmt.Errorf("%v of %v APIs exceed the limits", exceeded, len(report))
+	}
+
+	pols, err := getter.FetchPolicies(spec)
+	if err != nil {
+		return err
+	}
+	if err := checkGuardrails(spec, defs, pols); err != nil {
+		return err
+	}
+
+	if checkIdP, _ := cmd.Flags().GetBool("check-idp"); checkIdP {
+		return checkIdentityProviders(cmd, defs)
+	}
+
+	return nil
+}
+
+// checkIdentityProviders fetches the key sets of the identity providers the definitions
+// use, so a broken JWT or OpenID Connect integration is caught before it is published
+func checkIdentityProviders(cmd *cobra.Command, defs []objects.DBApiDefinition) error {
+	timeout, _ := cmd.Flags().GetDuration("idp-timeout")
+	uses := tyk_vcs.CheckIdentityProviders(defs, &http.Client{Timeout: timeout})
+
+	fmt.Printf("> Checked %v identity providers\n", len(uses))
+	broken := 0
+	for _, u := range uses {
+		if u.Error != "" {
+			broken++
+			fmt.Printf("--> [WARNING] %v %v, used by %v: %v\n", u.Kind, u.URL, strings.Join(u.APIs, ", "), u.Error)
+			continue
+		}
+		fmt.Printf("--> %v %v: %v usable keys, used by %v\n", u.Kind, u.URL, u.Keys, strings.Join(u.APIs, ", "))
+	}
+
+	if broken > 0 {
+		return fmt.Errorf("%v of %v identity providers are unusable", broken, len(uses))
+	}
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(analyzeCmd)
+
+	analyzeCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
+	analyzeCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	analyzeCmd.Flags().String("tag", "", "Tag to check out instead of the branch (optional)")
+	analyzeCmd.Flags().String("commit", "", "Commit of the branch to check out instead of its tip (optional)")
+	analyzeCmd.Flags().String("subdir", "", "Directory of the repo holding the spec file, only its files are checked out (optional)")
+	analyzeCmd.Flags().Bool("submodules", false, "Also clone the submodules of the repo")
+	analyzeCmd.Flags().StringP("path", "p", "", "Source directory for definition files (optional)")
+	analyzeCmd.Flags().Int("max-size", 0, "Report definitions larger than this many bytes of JSON (optional)")
+	analyzeCmd.Flags().Int("max-versions", 0, "Report definitions with more versions (optional)")
+	analyzeCmd.Flags().Int("max-paths", 0, "Report definitions with more extended path entries, across versions (optional)")
+	analyzeCmd.Flags().Int("max-regex-paths", 0, "Report definitions with more paths matched as regular expressions (optional)")
+	analyzeCmd.Flags().Int("max-middleware", 0, "Report definitions with more custom middleware hooks and virtual endpoints (optional)")
+	analyzeCmd.Flags().Bool("json", false, "Also print the report as JSON")
+	analyzeCmd.Flags().Bool("check-idp", false, "Fetch the JWKS URLs and OpenID Connect discovery documents the definitions reference and check they hold usable keys")
+	analyzeCmd.Flags().Duration("idp-timeout", 10*time.Second, "Timeout of each request to an identity provider")
+}
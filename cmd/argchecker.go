@@ -9,13 +9,21 @@ import (
 func verifyArguments(cmd *cobra.Command) error {
 	gwString, _ := cmd.Flags().GetString("gateway")
 	dbString, _ := cmd.Flags().GetString("dashboard")
+	dirString, _ := cmd.Flags().GetString("gateway-dir")
 
-	if gwString == "" && dbString == "" {
+	set := 0
+	for _, t := range []string{gwString, dbString, dirString} {
+		if t != "" {
+			set++
+		}
+	}
+
+	if set == 0 {
 		return errors.New(fmt.Sprintf("%s requires either gateway or dashboard target to be set", cmd.Use))
 	}
 
-	if gwString != "" && dbString != "" {
-		return errors.New(fmt.Sprintf("%s requires either gateway or dashboard target to be set, not both", cmd.Use))
+	if set > 1 {
+		return errors.New(fmt.Sprintf("%s requires either gateway, dashboard or gateway directory target to be set, not several", cmd.Use))
 	}
 
 	brString, _ := cmd.Flags().GetString("branch")
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit [repo]",
+	Short: "List the APIs of a target without authentication, with open CORS or open admin paths",
+	Long: `Audit reads the APIs published to a dashboard or gateway and lists those that require
+	no authentication, allow cross origin requests from any origin, or serve admin paths
+	(--admin-paths) without restricting them to whitelisted IP addresses: a quick check of
+	the security posture after each sync. Given a Github repo or file system, every finding is
+	compared with the definition in the repo, and the command fails on those the repo doesn't
+	intend: drift of the target, or APIs that aren't in the repo. --fail-on-findings fails on
+	any finding.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processAudit(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func processAudit(cmd *cobra.Command, args []string) error {
+	target, err := auditTarget(cmd)
+	if err != nil {
+		return err
+	}
+
+	var repo []objects.DBApiDefinition
+	if filePath, _ := cmd.Flags().GetString("path"); filePath != "" || len(args) > 0 {
+		repo, _, _, err = doGetData(cmd, args)
+		if err != nil {
+			return err
+		}
+		if repo == nil {
+			repo = []objects.DBApiDefinition{}
+		}
+	}
+
+	fmt.Println("> Fetching APIs")
+	apis, err := target.FetchAPIs()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("--> Auditing %v APIs\n", len(apis))
+
+	adminPaths, _ := cmd.Flags().GetStringSlice("admin-paths")
+	findings, err := tyk_vcs.AuditSecurity(apis, repo, adminPaths)
+	if err != nil {
+		return err
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		out, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	}
+
+	unintended := 0
+	for _, f := range findings {
+		switch f.Status {
+		case tyk_vcs.FindingDrift:
+			unintended++
+			fmt.Printf("--> [WARNING] API %v (%v) %v, unlike its definition in the repo\n", f.Name, f.APIID, f.Detail)
+		case tyk_vcs.FindingUnmanaged:
+			unintended++
+			fmt.Printf("--> [WARNING] API %v (%v) %v, and isn't in the repo\n", f.Name, f.APIID, f.Detail)
+		case tyk_vcs.FindingIntended:
+			fmt.Printf("--> API %v (%v) %v, as in the repo\n", f.Name, f.APIID, f.Detail)
+		default:
+			fmt.Printf("--> [WARNING] API %v (%v) %v\n", f.Name, f.APIID, f.Detail)
+		}
+	}
+
+	if unintended > 0 {
+		return fmt.Errorf("%v of %v findings are not intended by the repo", unintended, len(findings))
+	}
+	if fail, _ := cmd.Flags().GetBool("fail-on-findings"); fail && len(findings) > 0 {
+		return fmt.Errorf("%v findings on %v APIs", len(findings), len(apis))
+	}
+
+	fmt.Println("Done.")
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	auditCmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+	auditCmd.Flags().StringP("secret", "s", "", "Your API secret")
+	auditCmd.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+	auditCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
+	auditCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	auditCmd.Flags().String("tag", "", "Tag to check out instead of the branch (optional)")
+	auditCmd.Flags().String("commit", "", "Commit of the branch to check out instead of its tip (optional)")
+	auditCmd.Flags().String("subdir", "", "Directory of the repo holding the spec file, only its files are checked out (optional)")
+	auditCmd.Flags().Bool("submodules", false, "Also clone the submodules of the repo")
+	auditCmd.Flags().StringP("path", "p", "", "Source directory for definition files to compare with (optional)")
+	auditCmd.Flags().String("profile", "", "Target profile of the spec file the repo was published with (optional)")
+	auditCmd.Flags().StringSlice("admin-paths", []string{"/admin"}, "Paths containing any of these are admin paths, to be restricted to whitelisted IPs")
+	auditCmd.Flags().Bool("fail-on-findings", false, "Fail on any finding, not only on those the repo doesn't intend")
+	auditCmd.Flags().Bool("json", false, "Also print the findings as JSON")
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
+	"github.com/TykTechnologies/tyk-sync/clients/gateway"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// auditCertsCmd represents the audit-certs command
+var auditCertsCmd = &cobra.Command{
+	Use:   "audit-certs",
+	Short: "List the certificates the managed APIs of a target use and when they expire",
+	Long: `Audit-certs reads the APIs tyk-sync published to a dashboard or gateway, lists the
+	server, client and upstream certificates they reference with their expiry dates and the
+	APIs using them. It fails if a certificate expires within --days or can't be found, so
+	pipelines can catch expiring certificates in time. Use --all to audit every API.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processAuditCerts(cmd)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// certTarget is the part of the dashboard and gateway clients audit-certs uses
+type certTarget interface {
+	FetchAPIs() ([]objects.DBApiDefinition, error)
+	FetchCertificate(id string) (*objects.CertificateMeta, error)
+}
+
+// auditTarget opens the dashboard or gateway of the audit commands
+func auditTarget(cmd *cobra.Command) (certTarget, error) {
+	secret, _ := cmd.Flags().GetString("secret")
+
+	if dbString, _ := cmd.Flags().GetString("dashboard"); dbString != "" {
+		if secret == "" {
+			secret = os.Getenv("TYKGIT_DB_SECRET")
+		}
+		if secret == "" {
+			return nil, errors.New("Please set TYKGIT_DB_SECRET, or set the --secret flag, to your dashboard user secret")
+		}
+
+		c, err := dashboard.NewDashboardClient(dbString, secret, "")
+		if err != nil {
+			return nil, err
+		}
+		if cloud, _ := cmd.Flags().GetBool("cloud"); cloud {
+			c.SetCloud(true)
+		}
+		return c, nil
+	}
+
+	if gwString, _ := cmd.Flags().GetString("gateway"); gwString != "" {
+		if secret == "" {
+			secret = os.Getenv("TYKGIT_GW_SECRET")
+		}
+		if secret == "" {
+			return nil, errors.New("Please set TYKGIT_GW_SECRET, or set the --secret flag, to your gateway secret")
+		}
+		return gateway.NewGatewayClient(gwString, secret)
+	}
+
+	return nil, fmt.Errorf("%v requires a dashboard or gateway URL to be set", cmd.Name())
+}
+
+func processAuditCerts(cmd *cobra.Command) error {
+	target, err := auditTarget(cmd)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("> Fetching APIs")
+	apis, err := target.FetchAPIs()
+	if err != nil {
+		return err
+	}
+
+	if all, _ := cmd.Flags().GetBool("all"); !all {
+		managed := []objects.DBApiDefinition{}
+		for _, a := range apis {
+			if tyk_vcs.IsManagedAPI(a) {
+				managed = append(managed, a)
+			}
+		}
+		apis = managed
+	}
+	fmt.Printf("--> Auditing the certificates of %v APIs\n", len(apis))
+
+	days, _ := cmd.Flags().GetInt("days")
+	now := time.Now()
+	uses := tyk_vcs.AuditCertificates(apis, target.FetchCertificate, now, time.Duration(days)*24*time.Hour)
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		out, err := json.MarshalIndent(uses, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	}
+
+	problems := 0
+	for _, u := range uses {
+		apis := strings.Join(u.APIs, ", ")
+		switch {
+		case u.Error != "":
+			problems++
+			fmt.Printf("--> [WARNING] Certificate %v could not be read: %v, used by %v\n", u.ID, u.Error, apis)
+		case u.Expiring:
+			problems++
+			fmt.Printf("--> [WARNING] Certificate %v expires on %v (%v days left), used by %v\n", u.ID, u.NotAfter.Format("2006-01-02"), int(u.NotAfter.Sub(now).Hours()/24), apis)
+		default:
+			fmt.Printf("--> Certificate %v expires on %v (%v days left), used by %v\n", u.ID, u.NotAfter.Format("2006-01-02"), int(u.NotAfter.Sub(now).Hours()/24), apis)
+		}
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("%v of %v certificates are missing or expire within %v days", problems, len(uses), days)
+	}
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(auditCertsCmd)
+
+	auditCertsCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	auditCertsCmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+	auditCertsCmd.Flags().StringP("secret", "s", "", "Your API secret")
+	auditCertsCmd.Flags().Int("days", 30, "Fail if a certificate expires within this many days")
+	auditCertsCmd.Flags().Bool("all", false, "Audit all APIs of the target, not only the ones tyk-sync published")
+	auditCertsCmd.Flags().Bool("json", false, "Also print the certificates as JSON")
+	auditCertsCmd.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"net/mail"
+	"time"
+
+	tyk_vcs "github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// commitDump commits the dump in dir with --commit, and pushes it with --push. A dump in
+// which objects failed isn't committed, so a backup never loses objects to a flaky target.
+func commitDump(cmd *cobra.Command, dir, url string, failed []string) error {
+	commit, _ := cmd.Flags().GetBool("commit")
+	remote, _ := cmd.Flags().GetString("push")
+	if !commit && remote == "" {
+		return nil
+	}
+
+	if len(failed) > 0 {
+		fmt.Println("--> [WARNING] Not committing the dump, some objects failed")
+		return nil
+	}
+
+	authorFlag, _ := cmd.Flags().GetString("commit-author")
+	author, err := mail.ParseAddress(authorFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --commit-author %q, expected Name <email>: %v", authorFlag, err)
+	}
+
+	message, _ := cmd.Flags().GetString("commit-message")
+	key, branch := getAuthAndBranch(cmd, nil)
+
+	fmt.Printf("> Committing the dump in %v\n", dir)
+	res, err := tyk_vcs.CommitBackup(dir, tyk_vcs.BackupOptions{
+		Message: message,
+		URL:     url,
+		Time:    time.Now().UTC(),
+		Author:  object.Signature{Name: author.Name, Email: author.Address},
+		Remote:  remote,
+		Branch:  branch,
+		Key:     key,
+	})
+	if err != nil {
+		return err
+	}
+
+	if res.Hash.IsZero() {
+		fmt.Println("--> Nothing changed since the last backup")
+	} else {
+		fmt.Printf("--> Committed %v files, commit: %v\n", len(res.Files), res.Hash)
+	}
+	if res.Pushed {
+		fmt.Printf("--> Pushed to %v %v\n", remote, branch)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// stageCanary tags defs with the segment tag set with --canary only, the canary is nil
+// without it
+func stageCanary(cmd *cobra.Command, defs []objects.DBApiDefinition) (*tyk_vcs.Canary, error) {
+	tag, _ := cmd.Flags().GetString("canary")
+	if tag == "" {
+		return nil, nil
+	}
+
+	canary := tyk_vcs.NewCanary(tag)
+	if err := canary.Stage(defs); err != nil {
+		return nil, err
+	}
+	fmt.Printf("> Publishing %v APIs to the gateways tagged %v first\n", len(defs), tag)
+
+	return canary, nil
+}
+
+// promoteCanary updates the staged defs with their own tags once the user confirms, they
+// are left on the canary gateways otherwise
+func promoteCanary(publisher tyk_vcs.Publisher, canary *tyk_vcs.Canary, defs []objects.DBApiDefinition) error {
+	if canary == nil || len(defs) == 0 {
+		return nil
+	}
+
+	if !isInteractive() || !confirm(fmt.Sprintf("Promote the %v APIs from the %v gateways to all their tags?", len(defs), canary.Tag)) {
+		fmt.Printf("> APIs left on the gateways tagged %v, run update without --canary to promote them\n", canary.Tag)
+		return nil
+	}
+
+	canary.Promote(defs)
+	for i, d := range defs {
+		fmt.Printf("Promoting API %v: %v\n", i, d.Name)
+		if err := publisher.Update(&d); err != nil {
+			fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+		} else {
+			fmt.Printf("--> Status: OK, ID:%v\n", d.APIID)
+		}
+	}
+
+	if isGateway {
+		return publisher.Reload()
+	}
+
+	return nil
+}
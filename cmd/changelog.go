@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// changelogCmd represents the changelog command
+var changelogCmd = &cobra.Command{
+	Use:   "changelog",
+	Short: "Summarise the API and policy changes between two revisions of a Github repo or file system",
+	Long: `Changelog compares the APIs and policies of two revisions (commits, tags or branches) of
+	a Github repo, or of the git repo a directory is part of, and describes what changed in
+	words: APIs and policies added or removed, endpoints added or removed, listen paths,
+	upstreams and authentication changed, rate limits, quotas and access rights changed.
+	The summary is printed as Markdown, e.g. for release notes or a change advisory board,
+	or as JSON with --format json. --to defaults to the commit checked out.
+
+	Changes that break API consumers are flagged: APIs or policies removed or deactivated,
+	listen paths or authentication changed, whitelisted endpoints or versions removed,
+	rate limits and quotas tightened, and access rights revoked. With --fail-on-breaking the
+	command fails on them unless the APIs or policies they affect are approved with
+	--approve, e.g. in the CI of a pull request.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processChangelog(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// revisionObjects reads the APIs and policies of the spec of a revision
+func revisionObjects(r tyk_vcs.RevisionReader, rev string) ([]objects.DBApiDefinition, []objects.Policy, error) {
+	getter, err := r.AtRevision(rev)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	spec, err := getter.FetchTykSpec()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %v", rev, err)
+	}
+
+	defs, err := getter.FetchAPIDef(spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %v", rev, err)
+	}
+
+	pols, err := getter.FetchPolicies(spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %v", rev, err)
+	}
+
+	return defs, pols, nil
+}
+
+func processChangelog(cmd *cobra.Command, args []string) error {
+	from, _ := cmd.Flags().GetString("from")
+	if from == "" {
+		return errors.New("changelog requires the revision to compare from, set --from")
+	}
+	to, _ := cmd.Flags().GetString("to")
+	if to == "" {
+		to = "HEAD"
+	}
+
+	format, _ := cmd.Flags().GetString("format")
+	if format != "markdown" && format != "json" {
+		return fmt.Errorf("unknown changelog format %q, must be markdown or json", format)
+	}
+
+	getter, err := NewGetter(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	if err := getter.FetchRepo(); err != nil {
+		return err
+	}
+
+	r, ok := getter.(tyk_vcs.RevisionReader)
+	if !ok {
+		return errors.New("the source of the definitions can't be read at other revisions")
+	}
+
+	fromDefs, fromPols, err := revisionObjects(r, from)
+	if err != nil {
+		return err
+	}
+	toDefs, toPols, err := revisionObjects(r, to)
+	if err != nil {
+		return err
+	}
+
+	entries := tyk_vcs.Changelog(fromDefs, toDefs, fromPols, toPols)
+
+	var out []byte
+	if format == "json" {
+		out, err = json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		out = append(out, '\n')
+	} else {
+		title, _ := cmd.Flags().GetString("title")
+		if title == "" {
+			title = fmt.Sprintf("Changes from %v to %v", from, to)
+		}
+		out = []byte(tyk_vcs.ChangelogMarkdown(title, entries))
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	if output == "" {
+		fmt.Print(string(out))
+	} else {
+		if err := ioutil.WriteFile(output, out, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("> Wrote %v changes to %v\n", len(entries), output)
+	}
+
+	if fail, _ := cmd.Flags().GetBool("fail-on-breaking"); !fail {
+		return nil
+	}
+
+	approved, _ := cmd.Flags().GetStringSlice("approve")
+	breaking := tyk_vcs.BreakingChanges(entries, approved)
+	for _, e := range breaking {
+		fmt.Printf("--> [WARNING] Breaking change to %v %v (%v): %v\n", e.Kind, e.Name, e.ID, strings.Join(e.Breaking, "; "))
+	}
+	if len(breaking) > 0 {
+		return fmt.Errorf("%v APIs or policies have breaking changes, approve them with --approve <id>", len(breaking))
+	}
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(changelogCmd)
+
+	changelogCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
+	changelogCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	changelogCmd.Flags().String("subdir", "", "Directory of the repo holding the spec file, only its files are compared (optional)")
+	changelogCmd.Flags().StringP("path", "p", "", "Source directory for definition files, part of a git repo (optional)")
+	changelogCmd.Flags().String("from", "", "Revision to compare from, a commit, tag or branch")
+	changelogCmd.Flags().String("to", "", "Revision to compare to, defaults to the commit checked out")
+	changelogCmd.Flags().String("format", "markdown", "Format of the changelog: markdown or json")
+	changelogCmd.Flags().String("title", "", "Title of the Markdown changelog, defaults to Changes from <from> to <to>")
+	changelogCmd.Flags().StringP("output", "o", "", "File to write the changelog to, printed if not set")
+	changelogCmd.Flags().Bool("fail-on-breaking", false, "Fail if a change breaks API consumers and its API or policy is not approved")
+	changelogCmd.Flags().StringSlice("approve", []string{}, "IDs of the APIs and policies whose breaking changes are approved")
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// isInteractive is true when stdin is a terminal, so a user can answer prompts
+func isInteractive() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// confirm asks a yes/no question on the terminal, anything but yes is a no
+func confirm(question string) bool {
+	fmt.Printf("%v [y/N]: ", question)
+	answer, _ := stdin.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
+
+func itemLabel(item objects.SyncItem) string {
+	if item.ID == "" {
+		return item.Name
+	}
+
+	return fmt.Sprintf("%v (%v)", item.Name, item.ID)
+}
+
+func printPlan(plan *objects.SyncPlan) {
+	if plan.Existing > 0 {
+		fmt.Printf("> Planned changes to %v (%v existing):\n", plan.Kind, plan.Existing)
+	} else {
+		fmt.Printf("> Planned changes to %v:\n", plan.Kind)
+	}
+
+	for _, item := range plan.Create {
+		fmt.Printf("  + create %v\n", itemLabel(item))
+	}
+	for _, item := range plan.Update {
+		fmt.Printf("  ~ update %v\n", itemLabel(item))
+	}
+	for _, item := range plan.Delete {
+		if plan.Deactivate {
+			fmt.Printf("  - deactivate %v\n", itemLabel(item))
+			continue
+		}
+		fmt.Printf("  - delete %v\n", itemLabel(item))
+	}
+}
+
+func selectItems(action string, items []objects.SyncItem) []objects.SyncItem {
+	selected := []objects.SyncItem{}
+	for _, item := range items {
+		if confirm(fmt.Sprintf("%v %v?", action, itemLabel(item))) {
+			selected = append(selected, item)
+		}
+	}
+
+	return selected
+}
+
+// confirmPlan prints the plan and asks to apply all of it, to pick the items to apply
+// one by one, or to abort
+func confirmPlan(plan *objects.SyncPlan) error {
+	printPlan(plan)
+	if len(plan.Create)+len(plan.Update)+len(plan.Delete) == 0 {
+		return nil
+	}
+
+	fmt.Print("Apply these changes? [y/N/s(elect)]: ")
+	answer, _ := stdin.ReadString('\n')
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	case "s", "select":
+		plan.Create = selectItems("Create", plan.Create)
+		plan.Update = selectItems("Update", plan.Update)
+		if plan.Deactivate {
+			plan.Delete = selectItems("Deactivate", plan.Delete)
+		} else {
+			plan.Delete = selectItems("Delete", plan.Delete)
+		}
+		return nil
+	default:
+		return errors.New("aborted by user")
+	}
+}
+
+// syncProtect are the APIs the spec of the current sync protects
+var syncProtect *tyk_vcs.ProtectInfo
+
+// syncWindow is the deployment window gate of the current sync, publish or update
+var syncWindow *tyk_vcs.WindowGate
+
+// windowGate checks the deployment windows of the spec and prints whether one is open
+func windowGate(cmd *cobra.Command, spec *tyk_vcs.TykSourceSpec) (*tyk_vcs.WindowGate, error) {
+	override, _ := cmd.Flags().GetBool("override-window")
+	gate, err := tyk_vcs.NewWindowGate(spec.Windows, time.Now(), override)
+	if err != nil {
+		return nil, err
+	}
+
+	if gate != nil {
+		fmt.Printf("> %v\n", gate.Status())
+	}
+
+	return gate, nil
+}
+
+// planCheck builds the check run on sync plans: the objects outside of the commit range
+// (--from-commit) and the protected APIs of the spec are dropped from the plan, and all of
+// it outside of the deployment windows, followed by the interactive confirmation (--interactive) and the delete guard
+func planCheck(cmd *cobra.Command) (objects.PlanCheck, error) {
+	interactive, _ := cmd.Flags().GetBool("interactive")
+	if interactive && !isInteractive() {
+		return nil, errors.New("--interactive requires a terminal")
+	}
+
+	var guard *tyk_vcs.DeleteGuard
+	if cmd.Flags().Lookup("force-delete") != nil {
+		force, _ := cmd.Flags().GetBool("force-delete")
+		maxDeletes, _ := cmd.Flags().GetInt("max-deletes")
+		maxPercent, _ := cmd.Flags().GetFloat64("max-delete-percent")
+
+		guard = &tyk_vcs.DeleteGuard{
+			MaxDeletes:       maxDeletes,
+			MaxDeletePercent: maxPercent,
+			// Every delete was already confirmed
+			Force: force || interactive,
+		}
+
+		if isInteractive() {
+			guard.Confirm = confirm
+		}
+	}
+
+	protect := syncProtect
+	scope := syncScope
+	window := syncWindow
+	if !interactive && guard == nil && protect == nil && scope == nil && window == nil {
+		return nil, nil
+	}
+
+	return func(plan *objects.SyncPlan) error {
+		if err := scope.Check(plan); err != nil {
+			return err
+		}
+
+		if err := protect.Check(plan); err != nil {
+			return err
+		}
+
+		if err := window.Check(plan); err != nil {
+			return err
+		}
+
+		if interactive {
+			if err := confirmPlan(plan); err != nil {
+				return err
+			}
+		}
+
+		if guard != nil {
+			return guard.Check(plan)
+		}
+
+		return nil
+	}, nil
+}
+
+// publishPlans lists the objects publish and update will write as plans
+func publishPlans(cmd *cobra.Command, defs []objects.DBApiDefinition, pols []objects.Policy) (*objects.SyncPlan, *objects.SyncPlan) {
+	apiPlan := &objects.SyncPlan{Kind: "APIs"}
+	polPlan := &objects.SyncPlan{Kind: "policies"}
+	for i, d := range defs {
+		item := objects.SyncItem{ID: d.APIID, Name: d.Name, Index: i}
+		if cmd.Use == "update" {
+			apiPlan.Update = append(apiPlan.Update, item)
+		} else {
+			apiPlan.Create = append(apiPlan.Create, item)
+		}
+	}
+	for i, p := range pols {
+		item := objects.SyncItem{ID: p.ID, Name: p.Name, Index: i}
+		if cmd.Use == "update" {
+			polPlan.Update = append(polPlan.Update, item)
+		} else {
+			polPlan.Create = append(polPlan.Create, item)
+		}
+	}
+
+	return apiPlan, polPlan
+}
+
+// confirmPublish lets the user review and pick the objects publish and update will write,
+// when running with --interactive
+func confirmPublish(cmd *cobra.Command, defs []objects.DBApiDefinition, pols []objects.Policy) ([]objects.DBApiDefinition, []objects.Policy, error) {
+	if interactive, _ := cmd.Flags().GetBool("interactive"); !interactive {
+		return defs, pols, nil
+	}
+
+	if !isInteractive() {
+		return nil, nil, errors.New("--interactive requires a terminal")
+	}
+
+	apiPlan, polPlan := publishPlans(cmd, defs, pols)
+	if err := confirmPlan(apiPlan); err != nil {
+		return nil, nil, err
+	}
+
+	keptDefs := objects.Kept(append(apiPlan.Create, apiPlan.Update...))
+	selectedDefs := []objects.DBApiDefinition{}
+	for i, d := range defs {
+		if keptDefs[i] {
+			selectedDefs = append(selectedDefs, d)
+		}
+	}
+
+	if isGateway || len(pols) == 0 {
+		return selectedDefs, pols, nil
+	}
+
+	if err := confirmPlan(polPlan); err != nil {
+		return nil, nil, err
+	}
+
+	keptPols := objects.Kept(append(polPlan.Create, polPlan.Update...))
+	selectedPols := []objects.Policy{}
+	for i, p := range pols {
+		if keptPols[i] {
+			selectedPols = append(selectedPols, p)
+		}
+	}
+
+	return selectedDefs, selectedPols, nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// runContractTests runs the contract tests of the spec against the gateway set with
+// --contract-tests, the results are added to report, which may be nil. Failed cases fail the
+// command, the objects stay published.
+func runContractTests(cmd *cobra.Command, getter tyk_vcs.Getter, spec *tyk_vcs.TykSourceSpec, defs []objects.DBApiDefinition, report *tyk_vcs.SyncReport) error {
+	gwURL, _ := cmd.Flags().GetString("contract-tests")
+	if gwURL == "" || len(spec.ContractTests) == 0 {
+		return nil
+	}
+	timeout, _ := cmd.Flags().GetDuration("contract-tests-timeout")
+
+	tests, err := getter.FetchContractTests(spec)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("> Running contract tests on %v\n", gwURL)
+	runner := &tyk_vcs.ContractRunner{GatewayURL: gwURL, Timeout: timeout}
+	results := runner.Run(tests, defs)
+
+	for _, r := range results {
+		switch {
+		case r.Passed:
+			fmt.Printf("--> PASS: %v (%v) %v %v, code: %v\n", r.Name, r.APIID, r.Method, r.Path, r.StatusCode)
+		case r.Error != "":
+			fmt.Printf("--> FAIL: %v (%v) %v %v, Error:%v\n", r.Name, r.APIID, r.Method, r.Path, r.Error)
+		default:
+			fmt.Printf("--> FAIL: %v (%v) %v %v, code: %v\n", r.Name, r.APIID, r.Method, r.Path, r.StatusCode)
+			for _, f := range r.Failures {
+				fmt.Printf("    - %v\n", f)
+			}
+		}
+	}
+
+	if report != nil {
+		report.Contracts = append(report.Contracts, results...)
+	}
+
+	return tyk_vcs.ContractFailures(results)
+}
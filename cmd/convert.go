@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-diff"
+	"github.com/TykTechnologies/tyk-sync/tyk-importer"
+	"github.com/TykTechnologies/tyk-sync/tyk-oas"
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/spf13/cobra"
+)
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert [files]",
+	Short: "Convert API definition files between the classic and the Tyk OAS format",
+	Long: `Convert rewrites classic API definition files as Tyk OAS definitions (--to oas), or
+	Tyk OAS definitions as classic ones (--to classic), and lists the fields the other format
+	doesn't carry. The converted files are written to --output, named after the originals, or
+	printed if not set. Repos can migrate one API at a time: sync, publish and update convert
+	the Tyk OAS files of apidef repos into classic definitions, which the targets predating
+	Tyk OAS accept. With --fail-on-loss the command fails if a field is lost, e.g. in CI.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processConvert(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// readClassicDefinition decodes a classic definition, as dumped or bare
+func readClassicDefinition(raw []byte) (*objects.DBApiDefinition, error) {
+	def := &objects.DBApiDefinition{}
+	if err := json.Unmarshal(raw, def); err == nil && def.APIDefinition != nil {
+		return def, nil
+	}
+
+	bare := &apidef.APIDefinition{}
+	if err := json.Unmarshal(raw, bare); err != nil {
+		return nil, err
+	}
+	return &objects.DBApiDefinition{APIDefinition: bare}, nil
+}
+
+// convertFile converts the definition file to the format to, returning the converted
+// definition and the fields it lost
+func convertFile(file, to string) (interface{}, []tyk_diff.Change, error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		if raw, err = tyk_importer.YAMLToJSON(raw); err != nil {
+			return nil, nil, fmt.Errorf("%v: %v", file, err)
+		}
+	}
+
+	isOAS := tyk_oas.IsTykOAS(raw)
+	if (to == "oas") == isOAS {
+		return nil, nil, fmt.Errorf("%v is already a %v definition", file, to)
+	}
+
+	if to == "classic" {
+		return tyk_oas.ToClassic(raw)
+	}
+
+	def, err := readClassicDefinition(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %v", file, err)
+	}
+	return tyk_oas.ToOAS(def)
+}
+
+func processConvert(cmd *cobra.Command, args []string) error {
+	to, _ := cmd.Flags().GetString("to")
+	if to != "oas" && to != "classic" {
+		return errors.New("--to must be oas or classic")
+	}
+	if len(args) == 0 {
+		return errors.New("list the definition files to convert")
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	// Without --output the definition is printed, the report goes to stderr
+	var log io.Writer = os.Stdout
+	if output == "" {
+		if len(args) > 1 {
+			return errors.New("set --output to convert more than one file")
+		}
+		log = os.Stderr
+	} else if err := os.MkdirAll(output, 0755); err != nil {
+		return err
+	}
+
+	lost := 0
+	for _, file := range args {
+		fmt.Fprintf(log, "> Converting %v to %v\n", file, to)
+		def, changes, err := convertFile(file, to)
+		if err != nil {
+			return err
+		}
+		for _, c := range changes {
+			fmt.Fprintf(log, "--> [WARNING] Lost: %v\n", c)
+		}
+		lost += len(changes)
+
+		raw, err := json.MarshalIndent(def, "", "  ")
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			fmt.Println(string(raw))
+			continue
+		}
+
+		name := filepath.Base(file)
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + ".json"
+		if err := ioutil.WriteFile(filepath.Join(output, name), raw, 0644); err != nil {
+			return fmt.Errorf("Error writing file: %v", err)
+		}
+		fmt.Fprintf(log, "--> Wrote %v\n", filepath.Join(output, name))
+	}
+
+	if fail, _ := cmd.Flags().GetBool("fail-on-loss"); fail && lost > 0 {
+		return fmt.Errorf("%v fields were lost converting to %v", lost, to)
+	}
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(convertCmd)
+
+	convertCmd.Flags().String("to", "", "Format to convert to: oas or classic")
+	convertCmd.Flags().StringP("output", "o", "", "Directory to write the converted files to, printed if not set (optional)")
+	convertCmd.Flags().Bool("fail-on-loss", false, "Fail if a field can't be converted")
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-template"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// createAPICmd represents the create-api command
+var createAPICmd = &cobra.Command{
+	Use:   "create-api",
+	Short: "Generate a new API definition file from a template",
+	Long: `Create-api will render a ready-to-publish API definition from a built-in template
+	or from a template file of your own, write it to the target directory and add it to the
+	spec file (.tyk.json) in that directory, creating the spec file if it does not exist.
+
+	Built-in templates: ` + strings.Join(tyk_template.Names(), ", "),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processCreateAPI(cmd)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func processCreateAPI(cmd *cobra.Command) error {
+	tplName, _ := cmd.Flags().GetString("template")
+	dir, _ := cmd.Flags().GetString("path")
+
+	tpl, err := tyk_template.Load(tplName)
+	if err != nil {
+		return err
+	}
+
+	opts := tyk_template.Options{}
+	opts.Name, _ = cmd.Flags().GetString("name")
+	opts.Upstream, _ = cmd.Flags().GetString("upstream")
+	opts.ListenPath, _ = cmd.Flags().GetString("listen-path")
+	opts.APIID, _ = cmd.Flags().GetString("api-id")
+	opts.OrgID, _ = cmd.Flags().GetString("org")
+
+	def, err := tyk_template.Render(tpl, opts)
+	if err != nil {
+		return err
+	}
+
+	fname := fmt.Sprintf("api-%v.json", tyk_vcs.SafeFileName(def.APIID))
+	p := filepath.Join(dir, fname)
+	if _, err := os.Stat(p); err == nil {
+		return fmt.Errorf("file %v already exists", p)
+	}
+
+	j, err := json.MarshalIndent(def, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("> Writing API definition %v to: %v\n", def.Name, p)
+	if err := ioutil.WriteFile(p, j, 0644); err != nil {
+		return err
+	}
+
+	specPath := filepath.Join(dir, ".tyk.json")
+	spec := tyk_vcs.TykSourceSpec{Type: tyk_vcs.TYPE_APIDEF}
+	if rawSpec, err := ioutil.ReadFile(specPath); err == nil {
+		if err := json.Unmarshal(rawSpec, &spec); err != nil {
+			return fmt.Errorf("could not read existing spec file %v: %v", specPath, err)
+		}
+		if spec.Type != tyk_vcs.TYPE_APIDEF {
+			return fmt.Errorf("spec file %v is of type '%v', generated APIs require '%v'", specPath, spec.Type, tyk_vcs.TYPE_APIDEF)
+		}
+	}
+
+	if spec.ListsAPIFile(fname) {
+		fmt.Printf("> The spec file %v already lists the API\n", specPath)
+	} else {
+		spec.Files = append(spec.Files, tyk_vcs.APIInfo{File: fname})
+
+		fmt.Printf("> Adding API to spec file: %v\n", specPath)
+		j, err = json.MarshalIndent(spec, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(specPath, j, 0644); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("--> API ID: %v, listen path: %v\n", def.APIID, def.Proxy.ListenPath)
+	fmt.Println("Done.")
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(createAPICmd)
+
+	createAPICmd.Flags().StringP("template", "T", "rest-keyless", "Built-in template name or path to a template file")
+	createAPICmd.Flags().StringP("name", "n", "", "Name of the new API")
+	createAPICmd.Flags().StringP("upstream", "u", "", "Upstream target URL for the new API")
+	createAPICmd.Flags().StringP("listen-path", "l", "", "Listen path (defaults to /<slugified name>/)")
+	createAPICmd.Flags().String("api-id", "", "API ID to use (generated if not set)")
+	createAPICmd.Flags().StringP("org", "o", "", "Org ID to set on the definition (optional)")
+	createAPICmd.Flags().StringP("path", "p", ".", "Directory to write the definition and spec file to")
+}
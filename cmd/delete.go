@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/spf13/cobra"
+)
+
+// deleteCmd represents the delete command
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete APIs from a dashboard by listen path or slug",
+	Long: `Delete finds the APIs on a dashboard that have the given listen path (--listen-path)
+	or slug (--slug) and deletes them after confirmation, to decommission an API quickly
+	without looking up its ID. Use --yes to skip the confirmation, e.g. in scripts.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processDelete(cmd)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// sameListenPath compares listen paths the way the gateway routes them, the trailing
+// slash doesn't matter
+func sameListenPath(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+// matchAPIs returns the APIs with any of the listen paths or slugs
+func matchAPIs(apis []objects.DBApiDefinition, listenPaths, slugs []string) []objects.DBApiDefinition {
+	found := []objects.DBApiDefinition{}
+	for _, api := range apis {
+		match := false
+		for _, lp := range listenPaths {
+			if sameListenPath(api.Proxy.ListenPath, lp) {
+				match = true
+			}
+		}
+		for _, slug := range slugs {
+			if api.Slug == slug {
+				match = true
+			}
+		}
+
+		if match {
+			found = append(found, api)
+		}
+	}
+
+	return found
+}
+
+func processDelete(cmd *cobra.Command) error {
+	dbString, _ := cmd.Flags().GetString("dashboard")
+	if dbString == "" {
+		return errors.New("delete requires a dashboard URL to be set")
+	}
+
+	listenPaths, _ := cmd.Flags().GetStringSlice("listen-path")
+	slugs, _ := cmd.Flags().GetStringSlice("slug")
+	if len(listenPaths) == 0 && len(slugs) == 0 {
+		return errors.New("set --listen-path or --slug to select the APIs to delete")
+	}
+
+	yes, _ := cmd.Flags().GetBool("yes")
+	if !yes && !isInteractive() {
+		return errors.New("delete asks for confirmation, use --yes when not running in a terminal")
+	}
+
+	secret, _ := cmd.Flags().GetString("secret")
+	if secret == "" {
+		secret = os.Getenv("TYKGIT_DB_SECRET")
+	}
+	if secret == "" {
+		return errors.New("Please set TYKGIT_DB_SECRET, or set the --secret flag, to your dashboard user secret")
+	}
+
+	c, err := dashboard.NewDashboardClient(dbString, secret, "")
+	if err != nil {
+		return err
+	}
+
+	if cloud, _ := cmd.Flags().GetBool("cloud"); cloud {
+		c.SetCloud(true)
+	}
+
+	fmt.Println("> Fetching APIs")
+	apis, err := c.FetchAPIs()
+	if err != nil {
+		return err
+	}
+
+	found := matchAPIs(apis, listenPaths, slugs)
+	if len(found) == 0 {
+		return errors.New("no API with the given listen path or slug was found")
+	}
+
+	fmt.Printf("--> Found %v APIs:\n", len(found))
+	for _, api := range found {
+		fmt.Printf("  - %v (%v) on %v\n", api.Name, api.APIID, api.Proxy.ListenPath)
+	}
+
+	if !yes && !confirm(fmt.Sprintf("Delete these %v APIs?", len(found))) {
+		return errors.New("aborted by user")
+	}
+
+	failed := 0
+	for _, api := range found {
+		fmt.Printf("> Deleting: %v (%v)\n", api.Name, api.APIID)
+		if err := c.DeleteAPI(api.Id.Hex()); err != nil {
+			fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("--> Status: OK, ID:%v\n", api.APIID)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v APIs could not be deleted", failed, len(found))
+	}
+
+	fmt.Println("Done.")
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(deleteCmd)
+
+	deleteCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	deleteCmd.Flags().StringP("secret", "s", "", "Your API secret")
+	deleteCmd.Flags().StringSlice("listen-path", []string{}, "Listen paths of the APIs to delete")
+	deleteCmd.Flags().StringSlice("slug", []string{}, "Slugs of the APIs to delete")
+	deleteCmd.Flags().BoolP("yes", "y", false, "Delete without asking for confirmation")
+	deleteCmd.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+}
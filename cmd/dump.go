@@ -6,10 +6,8 @@ import (
 
 	"gopkg.in/mgo.v2/bson"
 
-	"encoding/json"
-	"io/ioutil"
 	"os"
-	"path"
+	"path/filepath"
 
 	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
 	"github.com/TykTechnologies/tyk-sync/clients/objects"
@@ -20,15 +18,55 @@ import (
 // dumpCmd represents the dump command
 var dumpCmd = &cobra.Command{
 	Use:   "dump",
-	Short: "Dump will extract policies and APIs from a target (dashboard)",
+	Short: "Dump will extract policies and APIs from a target (dashboard or gateway)",
 	Long: `Dump will extract policies and APIs from a target (dashboard) and
 	place them in a directory of your choosing. It will also generate a spec file
-	that can be used for sync.`,
+	that can be used for sync.
+
+	With --gateway, APIs, certificate meta data and (with --keys) keys are extracted
+	from an open source gateway instead, e.g. as a scheduled backup.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		if format != dumpFormatJSON && format != dumpFormatOperator && format != dumpFormatTerraform {
+			fmt.Printf("Unknown format %v, must be %v, %v or %v\n", format, dumpFormatJSON, dumpFormatOperator, dumpFormatTerraform)
+			os.Exit(1)
+		}
+
+		if since, _ := cmd.Flags().GetString("since"); since != "" && format != dumpFormatJSON {
+			fmt.Printf("--since is supported for the %v format only\n", dumpFormatJSON)
+			os.Exit(1)
+		}
+
+		gwString, _ := cmd.Flags().GetString("gateway")
+		encryptTo, _ := cmd.Flags().GetStringSlice("encrypt-to")
+		profile, _ := cmd.Flags().GetString("profile")
+		oauthClients, _ := cmd.Flags().GetBool("oauth-clients")
+		if gwString == "" && !oauthClients && (len(encryptTo) > 0 || profile != "") {
+			fmt.Println("--encrypt-to and --profile are supported for gateway dumps and --oauth-clients only, dashboard dumps hold no other credentials")
+			os.Exit(1)
+		}
+		if oauthClients && (gwString != "" || format != dumpFormatJSON) {
+			fmt.Printf("--oauth-clients is supported for %v dashboard dumps only\n", dumpFormatJSON)
+			os.Exit(1)
+		}
+
+		if gwString != "" {
+			if format != dumpFormatJSON {
+				fmt.Printf("The %v format is supported for dashboard dumps only\n", format)
+				os.Exit(1)
+			}
+
+			if err := dumpGateway(cmd, gwString); err != nil {
+				fmt.Println("Error: ", err)
+				os.Exit(1)
+			}
+			return
+		}
+
 		dbString, _ := cmd.Flags().GetString("dashboard")
 
 		if dbString == "" {
-			fmt.Println("Dump requires a dashboard URL to be set")
+			fmt.Println("Dump requires a dashboard or gateway URL to be set")
 			return
 		}
 
@@ -56,7 +94,11 @@ var dumpCmd = &cobra.Command{
 			fmt.Println(err)
 		}
 
-		fmt.Println("> Fetching policies")
+		if cloud, _ := cmd.Flags().GetBool("cloud"); cloud {
+			c.SetCloud(true)
+		}
+		c.SetListOptions(profileListOptions(cmd, &tyk_vcs.TargetProfile{}))
+
 		wantedPolicies , _ := cmd.Flags().GetStringSlice("policies")
 		wantedAPIs , _ := cmd.Flags().GetStringSlice("apis")
 
@@ -88,20 +130,27 @@ var dumpCmd = &cobra.Command{
 		}
 
 
+		// Without a selection, the APIs of a json dump are written as they are read rather
+		// than held in memory, orgs can have thousands of them
+		streamAPIs := format == dumpFormatJSON && len(wantedAPIs) == 0 && len(wantedPolicies) == 0
+
 		if len(wantedAPIs) == 0 && len(wantedPolicies) == 0 {
-			fmt.Println("> Fetching policies ")
+			fmt.Println("> Fetching policies")
 
 			policies, errPoliciesFetch = c.FetchPolicies()
 			if errPoliciesFetch != nil {
 				fmt.Println(errPoliciesFetch)
 				return
 			}
-			fmt.Println("> Fetching APIs")
 
-			apis, errApisFetch = c.FetchAPIs()
-			if err != nil {
-				fmt.Println(errApisFetch)
-				return
+			if !streamAPIs {
+				fmt.Println("> Fetching APIs")
+
+				apis, errApisFetch = c.FetchAPIs()
+				if errApisFetch != nil {
+					fmt.Println(errApisFetch)
+					return
+				}
 			}
 		}
 
@@ -113,14 +162,16 @@ var dumpCmd = &cobra.Command{
 		}else{
 			fmt.Println("--> Cleaning policy objects")
 		}
+		workers, _ := cmd.Flags().GetInt("workers")
+		failed := []string{}
+
 		// A bug exists which causes decoding of the access rights to break,
 		// so we should fetch individually
-		cleanPolicyObjects := make([]*objects.Policy, len(policies))
-		for i, p := range policies {
-			cp, err := c.FetchPolicy(p.MID.Hex())
+		fetchedPolicies := make([]*objects.Policy, len(policies))
+		failures := tyk_vcs.FetchParallel(len(policies), workers, func(i int) error {
+			cp, err := c.FetchPolicy(policies[i].MID.Hex())
 			if err != nil {
-				fmt.Println(err)
-				return
+				return err
 			}
 
 			// Make sure we retain IDs
@@ -128,7 +179,18 @@ var dumpCmd = &cobra.Command{
 				cp.ID = cp.MID.Hex()
 			}
 
-			cleanPolicyObjects[i] = cp
+			fetchedPolicies[i] = cp
+			return nil
+		}, newProgress("Fetching policies"))
+		for _, f := range failures {
+			failed = append(failed, fmt.Sprintf("policy %v (%v): %v", policies[f.Index].Name, policies[f.Index].MID.Hex(), f.Err))
+		}
+
+		cleanPolicyObjects := []*objects.Policy{}
+		for _, p := range fetchedPolicies {
+			if p != nil {
+				cleanPolicyObjects = append(cleanPolicyObjects, p)
+			}
 		}
 		fmt.Printf("--> Fetched %v Policies\n", len(cleanPolicyObjects))
 
@@ -136,36 +198,125 @@ var dumpCmd = &cobra.Command{
 			fmt.Printf("--> Identified %v APIs\n", len(apis))
 			fmt.Println("--> Fetching and cleaning APIs objects")
 
-			for i, api := range apis {
-				fullAPI, err := c.FetchAPI(api.APIID)
+			fetchedAPIs := make([]*objects.DBApiDefinition, len(apis))
+			failures := tyk_vcs.FetchParallel(len(apis), workers, func(i int) error {
+				fullAPI, err := c.FetchAPI(apis[i].APIID)
 				if err != nil {
-					fmt.Println(err)
-					return
+					return err
+				}
+				fetchedAPIs[i] = &fullAPI
+				return nil
+			}, newProgress("Fetching APIs"))
+			for _, f := range failures {
+				failed = append(failed, fmt.Sprintf("API %v: %v", apis[f.Index].APIID, f.Err))
+			}
+
+			apis = []objects.DBApiDefinition{}
+			for _, api := range fetchedAPIs {
+				if api != nil {
+					apis = append(apis, *api)
 				}
-				apis[i] = fullAPI
 			}
 		}
 
-		fmt.Printf("--> Fetched %v APIs\n", len(apis))
+		if !streamAPIs {
+			fmt.Printf("--> Fetched %v APIs\n", len(apis))
+
+			if err := redactAPIs(newRedactor(cmd), apis); err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
 
 		dir, _ := cmd.Flags().GetString("target")
+		if format == dumpFormatOperator {
+			if err := dumpOperator(cmd, dir, apis, cleanPolicyObjects); err != nil {
+				fmt.Println(err)
+				return
+			}
+			if err := commitDump(cmd, dir, dbString, failed); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			exitOnFailures(failed)
+			fmt.Println("Done.")
+			return
+		}
+
+		if format == dumpFormatTerraform {
+			if err := dumpTerraform(cmd, dir, apis, cleanPolicyObjects); err != nil {
+				fmt.Println(err)
+				return
+			}
+			if err := commitDump(cmd, dir, dbString, failed); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			exitOnFailures(failed)
+			fmt.Println("Done.")
+			return
+		}
+
+		namer, err := newFileNamer(cmd, dir)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		out, err := newDumpWriter(cmd, dir)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		// The APIs OAuth clients are registered with
+		oauthAPIs := []string{}
+
 		apiFiles := make([]string, len(apis))
 		for i, api := range apis {
-
-			j, jerr := json.MarshalIndent(api, "", "  ")
-			if jerr != nil {
-				fmt.Printf("JSON Encoding error: %v\n", jerr.Error())
+			fname := namer.APIFile(api.APIID, api.Name)
+			if err := out.WriteJSON(fname, api); err != nil {
+				fmt.Println(err)
 				return
 			}
+			apiFiles[i] = fname
+			if api.UseOauth2 {
+				oauthAPIs = append(oauthAPIs, api.APIID)
+			}
+		}
 
-			fname := fmt.Sprintf("api-%v.json", api.APIID)
-			p := path.Join(dir, fname)
-			err := ioutil.WriteFile(p, j, 0644)
+		if streamAPIs {
+			fmt.Println("> Fetching APIs")
+			redactor := newRedactor(cmd)
+			redacted := []string{}
+			err := c.EachAPI(dashboard.ListOptions{}, func(api *objects.DBApiDefinition) error {
+				if redactor != nil {
+					found, err := redactor.RedactAPI(api)
+					if err != nil {
+						return err
+					}
+					redacted = append(redacted, found...)
+				}
+
+				fname := namer.APIFile(api.APIID, api.Name)
+				if err := out.WriteJSON(fname, api); err != nil {
+					return err
+				}
+				apiFiles = append(apiFiles, fname)
+				if api.UseOauth2 {
+					oauthAPIs = append(oauthAPIs, api.APIID)
+				}
+				return nil
+			})
 			if err != nil {
-				fmt.Printf("Error writing file: %v\n", err)
+				fmt.Println(err)
 				return
 			}
-			apiFiles[i] = fname
+
+			fmt.Printf("--> Fetched %v APIs\n", len(apiFiles))
+			if redactor != nil {
+				printRedacted(redacted)
+			}
 		}
 
 
@@ -213,17 +364,9 @@ var dumpCmd = &cobra.Command{
 				pol.ID = pol.MID.Hex()
 			}
 
-			j, jerr := json.MarshalIndent(pol, "", "  ")
-			if jerr != nil {
-				fmt.Printf("JSON Encoding error: %v\n", jerr.Error())
-				return
-			}
-
-			fname := fmt.Sprintf("policy-%v.json", pol.ID)
-			p := path.Join(dir, fname)
-			err := ioutil.WriteFile(p, j, 0644)
-			if err != nil {
-				fmt.Printf("Error writing file: %v\n", err)
+			fname := namer.PolicyFile(pol.ID, pol.Name)
+			if err := out.WriteJSON(fname, pol); err != nil {
+				fmt.Println(err)
 				return
 			}
 
@@ -251,30 +394,108 @@ var dumpCmd = &cobra.Command{
 			gitSpec.Policies[i] = asInfo
 		}
 
+		if oauthClients {
+			recipients, profiles, err := dumpRecipients(cmd, dir)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			out.Recipients = recipients
+			gitSpec.Profiles = profiles
+
+			fmt.Println("> Fetching OAuth clients")
+			gitSpec.OAuthClients, err = dumpOAuthClients(c, out, oauthAPIs, &failed)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			fmt.Printf("--> Fetched %v OAuth clients of %v APIs\n", len(gitSpec.OAuthClients), len(oauthAPIs))
+			if len(recipients) > 0 {
+				fmt.Printf("--> OAuth clients are encrypted to %v recipients\n", len(recipients))
+			} else if len(gitSpec.OAuthClients) > 0 {
+				fmt.Println("--> [WARNING] OAuth client secrets are credentials, make sure the target directory is not pushed to a shared repository.")
+			}
+		}
+
 		fname := ".tyk.json"
-		p := path.Join(dir, fname)
+		p := filepath.Join(dir, fname)
 		fmt.Printf("> Creating spec file in: %v\n", p)
-		j, jerr := json.MarshalIndent(gitSpec, "", "  ")
-		if jerr != nil {
-			fmt.Printf("JSON Encoding error: %v\n", jerr.Error())
+		if err := out.WriteJSON(fname, gitSpec); err != nil {
+			fmt.Println(err)
 			return
 		}
-		if err := ioutil.WriteFile(p, j, 0644); err != nil {
+		if err := namer.Index().Write(dir); err != nil {
 			fmt.Printf("Error writing file: %v\n", err)
 			return
 		}
+		if err := finishDump(cmd, out, failed); err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := commitDump(cmd, dir, dbString, failed); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		exitOnFailures(failed)
 		fmt.Println("Done.")
 	},
 }
 
+// dumpOAuthClients writes the OAuth clients registered with the APIs, as sensitive files as
+// they hold the client secrets. APIs whose clients can't be listed are added to failed.
+func dumpOAuthClients(c *dashboard.Client, out *tyk_vcs.DumpWriter, apiIDs []string, failed *[]string) ([]tyk_vcs.OAuthClientInfo, error) {
+	infos := []tyk_vcs.OAuthClientInfo{}
+	for _, apiID := range apiIDs {
+		clients, err := c.FetchOAuthClients(apiID)
+		if err != nil {
+			*failed = append(*failed, fmt.Sprintf("OAuth clients of API %v: %v", apiID, err))
+			continue
+		}
+
+		for i := range clients {
+			name := fmt.Sprintf("oauth-%v-%v.json", tyk_vcs.SafeFileName(apiID), tyk_vcs.SafeFileName(clients[i].ClientID))
+			fname, err := out.WriteSensitiveJSON(name, clients[i])
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, tyk_vcs.OAuthClientInfo{File: fname, ClientID: clients[i].ClientID, APIID: apiID})
+		}
+	}
+
+	return infos, nil
+}
+
 func init() {
 	RootCmd.AddCommand(dumpCmd)
 
 	dumpCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
-	dumpCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
-	dumpCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	dumpCmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+	dumpCmd.Flags().StringP("key", "k", "", "Key file location for auth when pushing the dump (optional)")
+	dumpCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch the dump is pushed to (defaults to refs/heads/master)")
 	dumpCmd.Flags().StringP("secret", "s", "", "Your API secret")
 	dumpCmd.Flags().StringP("target", "t", "", "Target directory for files")
+	dumpCmd.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+	dumpCmd.Flags().StringToString("list-param", map[string]string{}, "Query parameter to send with the dashboard list calls, e.g. --list-param region=eu, overrides the list_params of the profile (repeatable)")
+	dumpCmd.Flags().Int("page-size", 0, "Fetch the dashboard lists page by page, the page_size the dashboard is configured with, overrides the page_size of the profile (optional)")
+	dumpCmd.Flags().Int("max-object-size", 0, "Fail if an API or policy of the dashboard lists is larger than this many bytes of JSON, lists are read one object at a time (optional)")
 	dumpCmd.Flags().StringSlice("policies",[]string{},"Specific Policies ids to dump")
 	dumpCmd.Flags().StringSlice("apis",[]string{},"Specific Apis ids to dump")
+	dumpCmd.Flags().Bool("redact", false, "Replace secrets (signing secrets, JWT sources, upstream auth headers, certificate pins) with ${TYK_SECRET_...} placeholders")
+	dumpCmd.Flags().StringSlice("redact-path", []string{}, "Additional JSON pointers of API definition fields to redact, * matches any key (implies --redact for those fields)")
+	dumpCmd.Flags().Bool("keys", false, "Also dump keys (gateway only)")
+	dumpCmd.Flags().Bool("oauth-clients", false, "Also dump the OAuth clients registered with the OAuth APIs, their secrets included (dashboard only)")
+	dumpCmd.Flags().Bool("hashed", false, "The gateway uses hashed keys, fetch keys by their hash (gateway only)")
+	dumpCmd.Flags().String("format", dumpFormatJSON, "Format of the dumped files: json, operator for Tyk Operator ApiDefinition and SecurityPolicy resources in YAML, or terraform for plain definitions and a tyk.tf.json exposing them to Terraform")
+	dumpCmd.Flags().String("namespace", "", "Kubernetes namespace to set on the resources of an operator dump (optional)")
+	dumpCmd.Flags().String("file-names", tyk_vcs.FileNamesByID, "Name the files of new APIs and policies after their id or name, files of objects listed in "+tyk_vcs.IndexFile+" keep their name")
+	dumpCmd.Flags().StringP("org", "o", "", "Org ID to dump certificates for, defaults to the orgs of the dumped APIs (gateway only)")
+	dumpCmd.Flags().Int("workers", 8, "Number of objects to fetch at once")
+	dumpCmd.Flags().StringSlice("encrypt-to", []string{}, "ASCII armored OpenPGP public key files to encrypt the files of keys and OAuth clients to")
+	dumpCmd.Flags().String("profile", "", "Profile of the spec file in the target directory whose encrypt_to keys the files of keys and OAuth clients are encrypted to")
+	dumpCmd.Flags().StringSlice("ignore", []string{}, "Rules of fields whose changes don't make an object changed for --since, e.g. active or tags[] to ignore the order of the tags")
+	dumpCmd.Flags().Bool("commit", false, "Commit the dump to the git repo the target directory is part of, one is created if there is none")
+	dumpCmd.Flags().String("push", "", "Name or URL of a remote to push the dump commit to (implies --commit)")
+	dumpCmd.Flags().String("commit-message", tyk_vcs.DefaultBackupMessage, "Template of the dump commit message, {{.URL}}, {{.Time}} and {{.Files}} are replaced by the dumped target, when and the number of files changed")
+	dumpCmd.Flags().String("commit-author", "tyk-sync <tyk-sync@localhost>", "Author of the dump commit")
+	dumpCmd.Flags().String("since", "", "State file of the previous dump, only files of changed objects are rewritten and the state is updated (optional)")
 }
@@ -0,0 +1,361 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/TykTechnologies/tyk-sync/clients/gateway"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-diff"
+	tyk_vcs "github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/openpgp"
+)
+
+// newRedactor returns the redactor configured by --redact and --redact-path, or nil
+func newRedactor(cmd *cobra.Command) *tyk_vcs.Redactor {
+	enabled, _ := cmd.Flags().GetBool("redact")
+	extra, _ := cmd.Flags().GetStringSlice("redact-path")
+	if !enabled && len(extra) == 0 {
+		return nil
+	}
+
+	r := &tyk_vcs.Redactor{Paths: extra}
+	if enabled {
+		r.Paths = append(append([]string{}, tyk_vcs.DefaultRedactions...), extra...)
+	}
+
+	return r
+}
+
+// redactAPIs replaces secrets in place and reports the placeholders that need values on publish
+func redactAPIs(r *tyk_vcs.Redactor, apis []objects.DBApiDefinition) error {
+	if r == nil {
+		return nil
+	}
+
+	names := []string{}
+	for i := range apis {
+		found, err := r.RedactAPI(&apis[i])
+		if err != nil {
+			return err
+		}
+		names = append(names, found...)
+	}
+
+	printRedacted(names)
+	return nil
+}
+
+func printRedacted(names []string) {
+	fmt.Printf("--> Redacted %v secrets\n", len(names))
+	for _, name := range names {
+		fmt.Printf("--> %v must be set when publishing\n", name)
+	}
+}
+
+func writeJSONFile(dir, fname string, obj interface{}) error {
+	j, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON Encoding error: %v", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, fname), j, 0644); err != nil {
+		return fmt.Errorf("Error writing file: %v", err)
+	}
+
+	return nil
+}
+
+// newDumpWriter writes the files of a dump into dir, with --since only those of the objects
+// that changed since the previous dump
+func newDumpWriter(cmd *cobra.Command, dir string) (*tyk_vcs.DumpWriter, error) {
+	since, _ := cmd.Flags().GetString("since")
+	if since == "" {
+		return tyk_vcs.NewDumpWriter(dir, nil), nil
+	}
+
+	ignore, _ := cmd.Flags().GetStringSlice("ignore")
+	for _, rule := range ignore {
+		if _, _, err := tyk_diff.ParseRule(rule); err != nil {
+			return nil, err
+		}
+	}
+
+	prev, err := tyk_vcs.ReadDumpState(since)
+	if err != nil {
+		return nil, err
+	}
+
+	w := tyk_vcs.NewDumpWriter(dir, prev)
+	w.Ignore = ignore
+	return w, nil
+}
+
+// finishDump removes the files of the objects deleted since the previous dump and writes
+// the state for the next one, when running with --since. No file is removed when objects
+// failed to be fetched, see DumpWriter.Finish.
+func finishDump(cmd *cobra.Command, w *tyk_vcs.DumpWriter, failed []string) error {
+	since, _ := cmd.Flags().GetString("since")
+	if since == "" {
+		return nil
+	}
+
+	removed, err := w.Finish(since, len(failed))
+	for _, fname := range removed {
+		fmt.Printf("--> Removed %v, its object no longer exists\n", fname)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		fmt.Printf("--> %v files written, %v unchanged since the previous dump, not removing any files as some objects failed\n", w.Written, w.Unchanged)
+		return nil
+	}
+	fmt.Printf("--> %v files written, %v unchanged since the previous dump\n", w.Written, w.Unchanged)
+
+	return nil
+}
+
+// dumpRecipients reads who the files of keys are encrypted to: the keys of --encrypt-to and
+// the encrypt_to keys of the --profile selected from the spec file of the previous dump in
+// dir. The profiles of that spec file are returned so the new one keeps them.
+func dumpRecipients(cmd *cobra.Command, dir string) (openpgp.EntityList, map[string]tyk_vcs.TargetProfile, error) {
+	paths, _ := cmd.Flags().GetStringSlice("encrypt-to")
+	profileName, _ := cmd.Flags().GetString("profile")
+
+	var profiles map[string]tyk_vcs.TargetProfile
+	if _, err := os.Stat(filepath.Join(dir, ".tyk.json")); err == nil {
+		getter, err := tyk_vcs.NewFSGetter(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		spec, err := getter.FetchTykSpec()
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading the spec file of the previous dump: %v", err)
+		}
+		profiles = spec.Profiles
+
+		profile, err := spec.Profile(profileName)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range profile.EncryptTo {
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(dir, p)
+			}
+			paths = append(paths, p)
+		}
+	} else if profileName != "" {
+		return nil, nil, fmt.Errorf("profile %v: there is no spec file in %v to read it from", profileName, dir)
+	}
+
+	if len(paths) == 0 {
+		return nil, profiles, nil
+	}
+
+	recipients, err := tyk_vcs.ReadRecipients(paths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return recipients, profiles, nil
+}
+
+// newFileNamer names the files of a dump, keeping the names of the previous dump in dir
+func newFileNamer(cmd *cobra.Command, dir string) (*tyk_vcs.FileNamer, error) {
+	mode, _ := cmd.Flags().GetString("file-names")
+
+	prev, err := tyk_vcs.ReadIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return tyk_vcs.NewFileNamer(prev, mode)
+}
+
+// dumpGateway writes the APIs, certificates and (optionally) keys of a CE gateway
+// into the same layout as a dashboard dump
+func dumpGateway(cmd *cobra.Command, gwString string) error {
+	flagVal, _ := cmd.Flags().GetString("secret")
+
+	secret := os.Getenv("TYKGIT_GW_SECRET")
+	if flagVal != "" {
+		secret = flagVal
+	}
+
+	if secret == "" {
+		return errors.New("Please set TYKGIT_GW_SECRET, or set the --secret flag, to your gateway secret")
+	}
+
+	fmt.Printf("Extracting APIs from %v\n", gwString)
+
+	c, err := gateway.NewGatewayClient(gwString, secret)
+	if err != nil {
+		return err
+	}
+
+	wantedAPIs, _ := cmd.Flags().GetStringSlice("apis")
+	wantedKeys, _ := cmd.Flags().GetBool("keys")
+	hashed, _ := cmd.Flags().GetBool("hashed")
+	orgID, _ := cmd.Flags().GetString("org")
+	dir, _ := cmd.Flags().GetString("target")
+
+	fmt.Println("> Fetching APIs")
+	all, err := c.FetchAPIs()
+	if err != nil {
+		return err
+	}
+
+	apis := all
+	if len(wantedAPIs) > 0 {
+		apis = []objects.DBApiDefinition{}
+		for _, api := range all {
+			for _, id := range wantedAPIs {
+				if api.APIID == id {
+					apis = append(apis, api)
+				}
+			}
+		}
+	}
+	fmt.Printf("--> Fetched %v APIs\n", len(apis))
+
+	if err := redactAPIs(newRedactor(cmd), apis); err != nil {
+		return err
+	}
+
+	recipients, profiles, err := dumpRecipients(cmd, dir)
+	if err != nil {
+		return err
+	}
+
+	gitSpec := tyk_vcs.TykSourceSpec{
+		Type:     tyk_vcs.TYPE_APIDEF,
+		Files:    make([]tyk_vcs.APIInfo, len(apis)),
+		Profiles: profiles,
+	}
+
+	namer, err := newFileNamer(cmd, dir)
+	if err != nil {
+		return err
+	}
+
+	out, err := newDumpWriter(cmd, dir)
+	if err != nil {
+		return err
+	}
+	out.Recipients = recipients
+
+	orgs := map[string]bool{}
+	for i, api := range apis {
+		fname := namer.APIFile(api.APIID, api.Name)
+		if err := out.WriteJSON(fname, api); err != nil {
+			return err
+		}
+		gitSpec.Files[i] = tyk_vcs.APIInfo{File: fname}
+		orgs[api.OrgID] = true
+	}
+
+	if orgID != "" {
+		orgs = map[string]bool{orgID: true}
+	}
+
+	workers, _ := cmd.Flags().GetInt("workers")
+	failed := []string{}
+
+	fmt.Println("> Fetching certificates")
+	for org := range orgs {
+		ids, err := c.FetchCertificateIDs(org)
+		if err != nil {
+			return err
+		}
+
+		metas := make([]*objects.CertificateMeta, len(ids))
+		failures := tyk_vcs.FetchParallel(len(ids), workers, func(i int) (err error) {
+			metas[i], err = c.FetchCertificate(ids[i])
+			return err
+		}, newProgress("Fetching certificates"))
+		for _, f := range failures {
+			failed = append(failed, fmt.Sprintf("certificate %v: %v", ids[f.Index], f.Err))
+		}
+
+		for i, id := range ids {
+			if metas[i] == nil {
+				continue
+			}
+
+			fname := fmt.Sprintf("cert-%v.json", tyk_vcs.SafeFileName(id))
+			if err := out.WriteJSON(fname, metas[i]); err != nil {
+				return err
+			}
+			gitSpec.Certificates = append(gitSpec.Certificates, tyk_vcs.CertificateInfo{File: fname, ID: id})
+		}
+	}
+	fmt.Printf("--> Fetched %v certificates\n", len(gitSpec.Certificates))
+	if len(gitSpec.Certificates) > 0 {
+		fmt.Println("--> [WARNING] The gateway API does not return certificate contents, only their meta data was written. " +
+			"Replace the files with the PEM files to be able to restore them.")
+	}
+
+	if wantedKeys {
+		fmt.Println("> Fetching keys")
+		ids, err := c.FetchKeyIDs()
+		if err != nil {
+			return err
+		}
+
+		keys := make([]*objects.Key, len(ids))
+		failures := tyk_vcs.FetchParallel(len(ids), workers, func(i int) (err error) {
+			keys[i], err = c.FetchKey(ids[i], hashed)
+			return err
+		}, newProgress("Fetching keys"))
+		for _, f := range failures {
+			failed = append(failed, fmt.Sprintf("key %v: %v", ids[f.Index], f.Err))
+		}
+
+		for i, id := range ids {
+			if keys[i] == nil {
+				continue
+			}
+
+			fname, err := out.WriteSensitiveJSON(fmt.Sprintf("key-%v.json", tyk_vcs.SafeFileName(id)), keys[i])
+			if err != nil {
+				return err
+			}
+			gitSpec.Keys = append(gitSpec.Keys, tyk_vcs.KeyInfo{File: fname, KeyID: id})
+		}
+		fmt.Printf("--> Fetched %v keys\n", len(gitSpec.Keys))
+		if len(recipients) > 0 {
+			fmt.Printf("--> Keys are encrypted to %v recipients\n", len(recipients))
+		} else if !hashed && len(gitSpec.Keys) > 0 {
+			fmt.Println("--> [WARNING] Keys are credentials, make sure the target directory is not pushed to a shared repository.")
+		}
+	}
+
+	p := filepath.Join(dir, ".tyk.json")
+	fmt.Printf("> Creating spec file in: %v\n", p)
+	if err := out.WriteJSON(".tyk.json", gitSpec); err != nil {
+		return err
+	}
+	if err := namer.Index().Write(dir); err != nil {
+		return err
+	}
+	if err := finishDump(cmd, out, failed); err != nil {
+		return err
+	}
+	if err := commitDump(cmd, dir, gwString, failed); err != nil {
+		return err
+	}
+
+	if err := printFailures(failed); err != nil {
+		return err
+	}
+
+	fmt.Println("Done.")
+	return nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-importer"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+const (
+	dumpFormatJSON     = "json"
+	dumpFormatOperator = "operator"
+)
+
+// dumpOperator writes the APIs and policies as Tyk Operator resources, one YAML file per
+// resource, and a spec file so the directory can also be synced with tyk-sync
+func dumpOperator(cmd *cobra.Command, dir string, apis []objects.DBApiDefinition, pols []*objects.Policy) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+
+	plain := make([]objects.Policy, len(pols))
+	for i, p := range pols {
+		plain[i] = *p
+	}
+
+	resources, warnings, err := tyk_importer.CreateOperatorResources(apis, plain, namespace)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		fmt.Printf("--> [WARNING] %v\n", w)
+	}
+
+	gitSpec := tyk_vcs.TykSourceSpec{Type: tyk_vcs.TYPE_OPERATOR}
+	for _, r := range resources {
+		out, err := tyk_importer.MarshalOperatorResource(r)
+		if err != nil {
+			return err
+		}
+
+		fname := fmt.Sprintf("%v-%v.yaml", strings.ToLower(r.Kind), r.Metadata.Name)
+		if err := ioutil.WriteFile(filepath.Join(dir, fname), out, 0644); err != nil {
+			return fmt.Errorf("Error writing file: %v", err)
+		}
+		gitSpec.Files = append(gitSpec.Files, tyk_vcs.APIInfo{File: fname})
+	}
+	fmt.Printf("--> Wrote %v resources\n", len(resources))
+
+	fmt.Printf("> Creating spec file in: %v\n", filepath.Join(dir, ".tyk.json"))
+	return writeJSONFile(dir, ".tyk.json", gitSpec)
+}
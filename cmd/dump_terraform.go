@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+const (
+	dumpFormatTerraform = "terraform"
+	terraformFile       = "tyk.tf.json"
+)
+
+// terraformDecode is a Terraform expression reading a JSON file of the module
+func terraformDecode(fname string) string {
+	return fmt.Sprintf(`${jsondecode(file("${path.module}/%v"))}`, fname)
+}
+
+// plainJSON encodes v without the keys in drop
+func plainJSON(v interface{}, drop ...string) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	for _, k := range drop {
+		delete(obj, k)
+	}
+
+	return json.MarshalIndent(obj, "", "  ")
+}
+
+// dumpTerraform writes the APIs and policies as they are sent to the Tyk APIs, without the
+// dashboard database IDs, and a tyk.tf.json that exposes them to Terraform as the locals
+// tyk_apis and tyk_policies, keyed by API and policy ID. The spec file is written too, so
+// tyk-sync and Terraform can share the directory.
+func dumpTerraform(cmd *cobra.Command, dir string, apis []objects.DBApiDefinition, pols []*objects.Policy) error {
+	namer, err := newFileNamer(cmd, dir)
+	if err != nil {
+		return err
+	}
+
+	gitSpec := tyk_vcs.TykSourceSpec{Type: tyk_vcs.TYPE_APIDEF}
+	tfAPIs := map[string]string{}
+	tfPols := map[string]string{}
+
+	for i := range apis {
+		payload, err := apis[i].DefinitionPayload()
+		if err != nil {
+			return err
+		}
+
+		j, err := plainJSON(payload, "id")
+		if err != nil {
+			return err
+		}
+
+		fname := namer.APIFile(apis[i].APIID, apis[i].Name)
+		if err := ioutil.WriteFile(filepath.Join(dir, fname), j, 0644); err != nil {
+			return fmt.Errorf("Error writing file: %v", err)
+		}
+		gitSpec.Files = append(gitSpec.Files, tyk_vcs.APIInfo{File: fname})
+		tfAPIs[apis[i].APIID] = terraformDecode(fname)
+	}
+
+	for _, pol := range pols {
+		j, err := plainJSON(pol, "_id")
+		if err != nil {
+			return err
+		}
+
+		fname := namer.PolicyFile(pol.ID, pol.Name)
+		if err := ioutil.WriteFile(filepath.Join(dir, fname), j, 0644); err != nil {
+			return fmt.Errorf("Error writing file: %v", err)
+		}
+		gitSpec.Policies = append(gitSpec.Policies, tyk_vcs.PolicyInfo{File: fname})
+		tfPols[pol.ID] = terraformDecode(fname)
+	}
+
+	tf := map[string]interface{}{
+		"locals": map[string]interface{}{
+			"tyk_apis":     tfAPIs,
+			"tyk_policies": tfPols,
+		},
+	}
+	fmt.Printf("> Creating Terraform file in: %v\n", filepath.Join(dir, terraformFile))
+	if err := writeJSONFile(dir, terraformFile, tf); err != nil {
+		return err
+	}
+
+	fmt.Printf("> Creating spec file in: %v\n", filepath.Join(dir, ".tyk.json"))
+	if err := writeJSONFile(dir, ".tyk.json", gitSpec); err != nil {
+		return err
+	}
+
+	return namer.Index().Write(dir)
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// gcCmd represents the gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Find the objects tyk-sync published to a dashboard that are no longer in git",
+	Long: `GC lists the APIs and policies of a dashboard that tyk-sync published from the repo
+	(they carry the tyk_sync_repo marker set to the repo of the spec file) but that are no
+	longer in the Github repo or file system. Objects created by hand or published from other
+	repos are never listed. With --delete the orphans are removed after confirmation,
+	use --yes to skip it, e.g. in scripts.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processGC(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func processGC(cmd *cobra.Command, args []string) error {
+	dbString, _ := cmd.Flags().GetString("dashboard")
+	if dbString == "" {
+		return errors.New("gc requires a dashboard URL to be set")
+	}
+
+	remove, _ := cmd.Flags().GetBool("delete")
+	yes, _ := cmd.Flags().GetBool("yes")
+	if remove && !yes && !isInteractive() {
+		return errors.New("gc --delete asks for confirmation, use --yes when not running in a terminal")
+	}
+
+	secret, _ := cmd.Flags().GetString("secret")
+	if secret == "" {
+		secret = os.Getenv("TYKGIT_DB_SECRET")
+	}
+	if secret == "" {
+		return errors.New("Please set TYKGIT_DB_SECRET, or set the --secret flag, to your dashboard user secret")
+	}
+
+	getter, err := NewGetter(cmd, args)
+	if err != nil {
+		return err
+	}
+	if err := getter.FetchRepo(); err != nil {
+		return err
+	}
+
+	spec, err := getter.FetchTykSpec()
+	if err != nil {
+		return err
+	}
+	if len(spec.Tenants) > 0 {
+		return errors.New("gc does not support specs with tenants, run it for the spec of each tenant")
+	}
+	if spec.Repo == "" {
+		return errors.New("gc finds the objects published from the repo set in the spec file, set its repo")
+	}
+
+	gitDefs, err := getter.FetchAPIDef(spec)
+	if err != nil {
+		return err
+	}
+	gitPols, err := getter.FetchPolicies(spec)
+	if err != nil {
+		return err
+	}
+
+	orgOverride, _ := cmd.Flags().GetString("org")
+	c, err := dashboard.NewDashboardClient(dbString, secret, orgOverride)
+	if err != nil {
+		return err
+	}
+
+	if cloud, _ := cmd.Flags().GetBool("cloud"); cloud {
+		c.SetCloud(true)
+	}
+
+	fmt.Println("> Fetching APIs and policies")
+	apis, err := c.FetchAPIs()
+	if err != nil {
+		return err
+	}
+	pols, err := c.FetchPolicies()
+	if err != nil {
+		return err
+	}
+
+	orphans := tyk_vcs.FindOrphans(spec.Repo, apis, pols, gitDefs, gitPols)
+	if orphans.Size() == 0 {
+		fmt.Println("--> No orphaned objects found")
+		return nil
+	}
+
+	fmt.Printf("--> Found %v orphaned objects:\n", orphans.Size())
+	for _, api := range orphans.APIs {
+		fmt.Printf("  - API %v (%v) on %v\n", api.Name, api.APIID, api.Proxy.ListenPath)
+	}
+	for _, pol := range orphans.Policies {
+		fmt.Printf("  - policy %v (%v)\n", pol.Name, pol.MID.Hex())
+	}
+
+	if !remove {
+		return nil
+	}
+
+	if !yes && !confirm(fmt.Sprintf("Delete these %v objects?", orphans.Size())) {
+		return errors.New("aborted by user")
+	}
+
+	failed := 0
+	for _, api := range orphans.APIs {
+		fmt.Printf("> Deleting API: %v (%v)\n", api.Name, api.APIID)
+		if err := c.DeleteAPI(api.Id.Hex()); err != nil {
+			fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("--> Status: OK, ID:%v\n", api.APIID)
+	}
+	for _, pol := range orphans.Policies {
+		fmt.Printf("> Deleting policy: %v (%v)\n", pol.Name, pol.MID.Hex())
+		if err := c.DeletePolicy(pol.MID.Hex()); err != nil {
+			fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("--> Status: OK, ID:%v\n", pol.MID.Hex())
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v objects could not be deleted", failed, orphans.Size())
+	}
+
+	fmt.Println("Done.")
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(gcCmd)
+
+	gcCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	gcCmd.Flags().StringP("secret", "s", "", "Your API secret")
+	gcCmd.Flags().StringP("org", "o", "", "org ID override")
+	gcCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
+	gcCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	gcCmd.Flags().String("tag", "", "Tag to check out instead of the branch (optional)")
+	gcCmd.Flags().String("commit", "", "Commit of the branch to check out instead of its tip (optional)")
+	gcCmd.Flags().String("subdir", "", "Directory of the repo holding the spec file, only its files are checked out (optional)")
+	gcCmd.Flags().Bool("submodules", false, "Also clone the submodules of the repo")
+	gcCmd.Flags().StringP("path", "p", "", "Source directory for definition files (optional)")
+	gcCmd.Flags().Bool("delete", false, "Delete the orphaned objects, after confirmation")
+	gcCmd.Flags().BoolP("yes", "y", false, "Delete without asking for confirmation")
+	gcCmd.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+}
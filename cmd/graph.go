@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// graphCmd represents the graph command
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Export the dependencies between the APIs, policies, certificates, webhooks and plugins of a Github repo or file system",
+	Long: `Graph resolves the references between the objects of a Github repo or file system, the
+	APIs policies grant access to, and the certificates, webhooks, plugin bundles, plugins
+	and OIDC client policies of the APIs, and exports them as a Graphviz (dot) or Mermaid
+	graph. With --focus only the objects connected to the given IDs are kept, to see what a
+	change to them will affect. Objects referenced but not in the repo are drawn dashed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processGraph(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func processGraph(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	if _, err := (&tyk_vcs.DependencyGraph{}).Render(format); err != nil {
+		return err
+	}
+
+	getter, err := NewGetter(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	if err := getter.FetchRepo(); err != nil {
+		return err
+	}
+
+	spec, err := getter.FetchTykSpec()
+	if err != nil {
+		return err
+	}
+
+	defs, err := getter.FetchAPIDef(spec)
+	if err != nil {
+		return err
+	}
+
+	pols, err := getter.FetchPolicies(spec)
+	if err != nil {
+		return err
+	}
+
+	g := tyk_vcs.BuildDependencyGraph(defs, pols)
+	if focus, _ := cmd.Flags().GetStringSlice("focus"); len(focus) > 0 {
+		g = g.Around(focus)
+		if len(g.Nodes) == 0 {
+			return fmt.Errorf("no object of the repo has the IDs %v", focus)
+		}
+	}
+
+	out, err := g.Render(format)
+	if err != nil {
+		return err
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	if output == "" {
+		fmt.Print(out)
+		return nil
+	}
+
+	if err := ioutil.WriteFile(output, []byte(out), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("> Wrote a graph of %v objects and %v references to %v\n", len(g.Nodes), len(g.Edges), output)
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(graphCmd)
+
+	graphCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
+	graphCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	graphCmd.Flags().String("tag", "", "Tag to check out instead of the branch (optional)")
+	graphCmd.Flags().String("commit", "", "Commit of the branch to check out instead of its tip (optional)")
+	graphCmd.Flags().String("subdir", "", "Directory of the repo holding the spec file, only its files are checked out (optional)")
+	graphCmd.Flags().Bool("submodules", false, "Also clone the submodules of the repo")
+	graphCmd.Flags().StringP("path", "p", "", "Source directory for definition files (optional)")
+	graphCmd.Flags().String("format", tyk_vcs.GraphDOT, "Format of the graph: dot for Graphviz, or mermaid")
+	graphCmd.Flags().StringSlice("focus", []string{}, "Only keep the objects connected to these IDs (API, policy, certificate IDs...)")
+	graphCmd.Flags().StringP("output", "o", "", "File to write the graph to, printed if not set")
+}
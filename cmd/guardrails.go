@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+)
+
+// checkGuardrails fails the run if a policy breaks the guardrails of the spec, before
+// anything is published
+func checkGuardrails(spec *tyk_vcs.TykSourceSpec, defs []objects.DBApiDefinition, pols []objects.Policy) error {
+	if spec.Guardrails == nil {
+		return nil
+	}
+
+	violations := tyk_vcs.CheckGuardrails(spec.Guardrails, pols, defs)
+	fmt.Printf("> Checked %v policies against the guardrails\n", len(pols))
+	for _, v := range violations {
+		fmt.Printf("--> [WARNING] %v\n", v)
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%v guardrail violations, fix the policies or the guardrails of the spec", len(violations))
+	}
+
+	return nil
+}
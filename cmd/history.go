@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// syncHistory records the changes of the running sync, publish or update if --history is set
+var syncHistory *tyk_vcs.History
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history <history file>",
+	Short: "List the changes recorded in a publish history",
+	Long: `History lists the changes sync, publish and update recorded in the file given to their
+	--history flag: when each object was created, updated or deleted on which target, from which
+	commit, the hash of what was sent and whether it worked. Each run also records its outcome.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processHistory(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// targetURL is the dashboard or gateway the command publishes to
+func targetURL(cmd *cobra.Command) string {
+	target, _ := cmd.Flags().GetString("dashboard")
+	if target == "" {
+		target, _ = cmd.Flags().GetString("gateway")
+	}
+	return target
+}
+
+// openHistory sets syncHistory if --history is set, with the commit the getter checked out
+func openHistory(cmd *cobra.Command, getter tyk_vcs.Getter) error {
+	syncHistory = nil
+	path, _ := cmd.Flags().GetString("history")
+	if path == "" {
+		return nil
+	}
+
+	if err := getter.FetchRepo(); err != nil {
+		return err
+	}
+
+	commit := ""
+	if cr, ok := getter.(tyk_vcs.CommitReporter); ok {
+		commit, _ = cr.CheckedOutCommit()
+	}
+
+	syncHistory = &tyk_vcs.History{Path: path, Target: targetURL(cmd), Commit: commit}
+	return nil
+}
+
+// resolveFromCommit turns --from-commit last into the commit of the last successful sync
+// of the target in the history, empty if there is none
+func resolveFromCommit(cmd *cobra.Command, from string) (string, error) {
+	if from != "last" {
+		return from, nil
+	}
+
+	path, _ := cmd.Flags().GetString("history")
+	if path == "" {
+		return "", errors.New("--from-commit last reads the last synced commit from --history")
+	}
+
+	commit, err := tyk_vcs.LastSyncedCommit(path, targetURL(cmd))
+	if err != nil {
+		return "", err
+	}
+	if commit == "" {
+		fmt.Printf("--> No successful sync of %v in %v, processing all objects\n", targetURL(cmd), path)
+	}
+
+	return commit, nil
+}
+
+// resolveRollback sets --commit to the commit the target was synced to before its last
+// successful sync in the history, for sync --rollback
+func resolveRollback(cmd *cobra.Command) error {
+	if on, _ := cmd.Flags().GetBool("rollback"); !on {
+		return nil
+	}
+
+	path, _ := cmd.Flags().GetString("history")
+	if path == "" {
+		return errors.New("--rollback reads the commit to roll back to from --history")
+	}
+	if commit, _ := cmd.Flags().GetString("commit"); commit != "" {
+		return errors.New("set either --rollback or --commit, not both")
+	}
+	if from, _ := cmd.Flags().GetString("from-commit"); from != "" {
+		return errors.New("set either --rollback or --from-commit, not both")
+	}
+
+	commit, err := tyk_vcs.PreviousSyncedCommit(path, targetURL(cmd))
+	if err != nil {
+		return err
+	}
+	if commit == "" {
+		return fmt.Errorf("%v has no earlier successful sync of %v to roll back to", path, targetURL(cmd))
+	}
+
+	fmt.Printf("> Rolling back %v to %v\n", targetURL(cmd), commit)
+	return cmd.Flags().Set("commit", commit)
+}
+
+// recordRun records the outcome of the command in the history, if there is one
+func recordRun(cmd *cobra.Command, err error) {
+	if syncHistory == nil {
+		return
+	}
+	if hErr := syncHistory.RecordRun(cmd.Use, err); hErr != nil {
+		fmt.Printf("--> [WARNING] Could not record the %v in the history: %v\n", cmd.Use, hErr)
+	}
+}
+
+func processHistory(cmd *cobra.Command, args []string) error {
+	filter := tyk_vcs.HistoryFilter{}
+	filter.Target, _ = cmd.Flags().GetString("target")
+	filter.Kind, _ = cmd.Flags().GetString("kind")
+	filter.ID, _ = cmd.Flags().GetString("id")
+	filter.Commit, _ = cmd.Flags().GetString("commit")
+	filter.Failed, _ = cmd.Flags().GetBool("failed")
+	if since, _ := cmd.Flags().GetDuration("since"); since > 0 {
+		filter.Since = time.Now().Add(-since)
+	}
+
+	entries, err := tyk_vcs.ReadHistory(args[0], filter)
+	if err != nil {
+		return err
+	}
+
+	if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, e := range entries {
+		commit := e.Commit
+		if len(commit) > 8 {
+			commit = commit[:8]
+		}
+		line := fmt.Sprintf("%v %v %v", e.Time.Local().Format(time.RFC3339), e.Target, commit)
+		if e.Kind == "" {
+			line += " " + e.Action
+		} else {
+			line += fmt.Sprintf(" %v %v %v", e.Action, e.Kind, e.ID)
+			if e.Name != "" {
+				line += fmt.Sprintf(" (%v)", e.Name)
+			}
+		}
+		line += ": " + e.Result
+		if e.Error != "" {
+			line += ", " + e.Error
+		}
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().String("target", "", "Only list the changes of this dashboard or gateway URL")
+	historyCmd.Flags().String("kind", "", "Only list the changes of APIs or policies")
+	historyCmd.Flags().String("id", "", "Only list the changes of the object with this ID")
+	historyCmd.Flags().String("commit", "", "Only list the changes published from this commit")
+	historyCmd.Flags().Duration("since", 0, "Only list the changes of this last period, e.g. 24h")
+	historyCmd.Flags().Bool("failed", false, "Only list the failed changes and runs")
+	historyCmd.Flags().Int("limit", 0, "Only list this many of the latest changes")
+	historyCmd.Flags().Bool("json", false, "Print the entries as JSON, one per line")
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// syncScope limits the current sync to the objects changed in the commit range given with
+// --from-commit, nil syncs everything
+var syncScope *tyk_vcs.ChangeScope
+
+// listChanges lists the files changed between --from-commit and --to-commit, it returns nil
+// if no range is given. It must run before the spec is read, as the getter checks out
+// --to-commit.
+func listChanges(cmd *cobra.Command, getter tyk_vcs.Getter) (*tyk_vcs.FileChanges, error) {
+	from, _ := cmd.Flags().GetString("from-commit")
+	to, _ := cmd.Flags().GetString("to-commit")
+	from, err := resolveFromCommit(cmd, from)
+	if err != nil {
+		return nil, err
+	}
+	if from == "" {
+		if to != "" {
+			return nil, errors.New("--to-commit requires --from-commit")
+		}
+		return nil, nil
+	}
+
+	lister, ok := getter.(tyk_vcs.ChangeLister)
+	if !ok {
+		return nil, fmt.Errorf("commit ranges are not supported by %T", getter)
+	}
+
+	if err := getter.FetchRepo(); err != nil {
+		return nil, err
+	}
+
+	changes, err := lister.Changes(from, to)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("> %v files changed, %v deleted since %v\n", len(changes.Changed), len(changes.Deleted), from)
+
+	return changes, nil
+}
+
+// scopeChanges works out the APIs and policies the changes touch, changes may be nil
+func scopeChanges(getter tyk_vcs.Getter, spec *tyk_vcs.TykSourceSpec, changes *tyk_vcs.FileChanges) (*tyk_vcs.ChangeScope, error) {
+	if changes == nil {
+		return nil, nil
+	}
+
+	scope, err := tyk_vcs.NewChangeScope(getter, spec, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	if scope.Full {
+		fmt.Printf("--> Processing all objects: %v\n", scope.Reason)
+		return scope, nil
+	}
+	fmt.Printf("--> Limited to %v changed APIs and policies\n", scope.Size())
+
+	return scope, nil
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/spf13/cobra"
+)
+
+// infoCmd represents the info command
+var infoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show the licence, gateway nodes and versions of a dashboard",
+	Long: `Info reads the licence and the registered gateway nodes from the admin API of a
+	dashboard. It fails if the licence is invalid or about to expire (--min-days), if no
+	gateway is registered or if more gateways are registered than the licence allows,
+	so pipelines can check that a target is healthy before publishing to it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processInfo(cmd)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// SystemInfo is the state of a dashboard as reported by info
+type SystemInfo struct {
+	Version  string                `json:"version"`
+	Licence  *objects.Licence      `json:"licence"`
+	Gateways []objects.GatewayNode `json:"gateways"`
+}
+
+// problems lists the reasons the target should not be published to
+func (i *SystemInfo) problems(minDays int, now time.Time) []string {
+	found := []string{}
+	if !i.Licence.Valid {
+		found = append(found, "the licence is not valid")
+	}
+
+	if left := i.Licence.ExpiresAt.Sub(now); left < time.Duration(minDays)*24*time.Hour {
+		found = append(found, fmt.Sprintf("the licence expires on %v", i.Licence.ExpiresAt.Format("2006-01-02")))
+	}
+
+	if len(i.Gateways) == 0 {
+		found = append(found, "no gateway is registered")
+	}
+
+	if i.Licence.NodesAllowed > 0 && len(i.Gateways) > i.Licence.NodesAllowed {
+		found = append(found, fmt.Sprintf("%v gateways are registered, the licence allows %v", len(i.Gateways), i.Licence.NodesAllowed))
+	}
+
+	return found
+}
+
+func printInfo(info *SystemInfo, now time.Time) {
+	fmt.Printf("> Dashboard version: %v\n", info.Version)
+
+	fmt.Println("> Licence")
+	state := "valid"
+	if !info.Licence.Valid {
+		state = "invalid"
+	}
+	fmt.Printf("--> Type: %v (%v)\n", info.Licence.Type, state)
+	fmt.Printf("--> Expires: %v (%v days left)\n", info.Licence.ExpiresAt.Format("2006-01-02"), int(info.Licence.ExpiresAt.Sub(now).Hours()/24))
+
+	fmt.Println("> Gateways")
+	if info.Licence.NodesAllowed > 0 {
+		fmt.Printf("--> %v of %v allowed nodes registered\n", len(info.Gateways), info.Licence.NodesAllowed)
+	} else {
+		fmt.Printf("--> %v nodes registered\n", len(info.Gateways))
+	}
+
+	versions := map[string]bool{}
+	for _, n := range info.Gateways {
+		versions[n.Version] = true
+		fmt.Printf("--> %v (%v): %v, last seen %v\n", n.Hostname, n.NodeID, n.Version, n.LastHeartbeat.Format(time.RFC3339))
+	}
+
+	if len(versions) > 1 {
+		fmt.Println("--> [WARNING] The gateways run different versions")
+	}
+}
+
+func processInfo(cmd *cobra.Command) error {
+	dbString, _ := cmd.Flags().GetString("dashboard")
+	if dbString == "" {
+		return errors.New("info requires a dashboard URL to be set")
+	}
+
+	adminSecret, _ := cmd.Flags().GetString("admin-secret")
+	if adminSecret == "" {
+		adminSecret = os.Getenv("TYKGIT_DB_ADMIN_SECRET")
+	}
+	if adminSecret == "" {
+		return errors.New("Please set TYKGIT_DB_ADMIN_SECRET, or set the --admin-secret flag, to the admin_secret of your dashboard")
+	}
+
+	c, err := dashboard.NewDashboardClient(dbString, "", "")
+	if err != nil {
+		return err
+	}
+	c.SetAdminSecret(adminSecret)
+
+	if cloud, _ := cmd.Flags().GetBool("cloud"); cloud {
+		c.SetCloud(true)
+	}
+
+	info := &SystemInfo{}
+	if info.Version, err = c.FetchVersion(); err != nil {
+		fmt.Printf("--> [WARNING] Could not detect the dashboard version: %v\n", err)
+	}
+
+	if info.Licence, err = c.FetchLicence(); err != nil {
+		return err
+	}
+
+	if info.Gateways, err = c.FetchGatewayNodes(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		out, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	} else {
+		printInfo(info, now)
+	}
+
+	minDays, _ := cmd.Flags().GetInt("min-days")
+	problems := info.problems(minDays, now)
+	for _, p := range problems {
+		fmt.Printf("--> [WARNING] %v\n", p)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("the dashboard is not ready to be published to")
+	}
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(infoCmd)
+
+	infoCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	infoCmd.Flags().String("admin-secret", "", "The admin_secret of the dashboard")
+	infoCmd.Flags().Int("min-days", 0, "Fail if the licence expires within this many days")
+	infoCmd.Flags().Bool("json", false, "Print the information as JSON")
+	infoCmd.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+}
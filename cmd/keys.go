@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/gateway"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/spf13/cobra"
+)
+
+// keysCmd represents the keys command
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Create, update, delete and list the keys of a gateway",
+	Long: `Keys manages the keys of a Tyk CE gateway, so the test keys of an environment can be
+	provisioned by script alongside its APIs: keys applying policies or granted access to
+	APIs are created, updated and deleted, and listed by the prefix of their ID.`,
+}
+
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the keys of the gateway, those whose ID starts with --prefix if set",
+	Args:  cobra.NoArgs,
+	Run:   runKeys(processKeysList),
+}
+
+var keysCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a key applying --policy and granted --access, under --id or a generated ID",
+	Args:  cobra.NoArgs,
+	Run:   runKeys(processKeysCreate),
+}
+
+var keysUpdateCmd = &cobra.Command{
+	Use:   "update <key id>",
+	Short: "Replace the policies, access or expiry of a key, those not set are kept",
+	Args:  cobra.ExactArgs(1),
+	Run:   runKeys(processKeysUpdate),
+}
+
+var keysDeleteCmd = &cobra.Command{
+	Use:   "delete <key id>...",
+	Short: "Delete keys",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   runKeys(processKeysDelete),
+}
+
+func runKeys(process func(cmd *cobra.Command, c *gateway.Client, args []string) error) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		err := func() error {
+			c, err := keysClient(cmd)
+			if err != nil {
+				return err
+			}
+			return process(cmd, c, args)
+		}()
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func keysClient(cmd *cobra.Command) (*gateway.Client, error) {
+	gwString, _ := cmd.Flags().GetString("gateway")
+	if gwString == "" {
+		return nil, errors.New("keys requires a gateway URL to be set")
+	}
+
+	secret, _ := cmd.Flags().GetString("secret")
+	if secret == "" {
+		secret = os.Getenv("TYKGIT_GW_SECRET")
+	}
+	if secret == "" {
+		return nil, errors.New("Please set TYKGIT_GW_SECRET, or set the --secret flag, to your gateway secret")
+	}
+
+	return gateway.NewGatewayClient(gwString, secret)
+}
+
+// keyAccess parses --access, API IDs optionally followed by the versions granted, api:v1,v2
+func keyAccess(cmd *cobra.Command) ([]objects.KeyAccess, error) {
+	flags, _ := cmd.Flags().GetStringArray("access")
+	access := []objects.KeyAccess{}
+	for _, f := range flags {
+		parts := strings.SplitN(f, ":", 2)
+		if parts[0] == "" {
+			return nil, fmt.Errorf("invalid --access %q, must be an API ID, optionally followed by :versions", f)
+		}
+
+		a := objects.KeyAccess{APIID: parts[0]}
+		if len(parts) == 2 {
+			for _, v := range strings.Split(parts[1], ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					a.Versions = append(a.Versions, v)
+				}
+			}
+		}
+		access = append(access, a)
+	}
+
+	return access, nil
+}
+
+func keyExpiry(cmd *cobra.Command) time.Time {
+	if expires, _ := cmd.Flags().GetDuration("expires"); expires > 0 {
+		return time.Now().Add(expires)
+	}
+	return time.Time{}
+}
+
+func processKeysList(cmd *cobra.Command, c *gateway.Client, args []string) error {
+	prefix, _ := cmd.Flags().GetString("prefix")
+	keys, err := c.FetchKeyIDsWithPrefix(prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		fmt.Println(k)
+	}
+	return nil
+}
+
+func processKeysCreate(cmd *cobra.Command, c *gateway.Client, args []string) error {
+	orgID, _ := cmd.Flags().GetString("org")
+	policies, _ := cmd.Flags().GetStringSlice("policy")
+	access, err := keyAccess(cmd)
+	if err != nil {
+		return err
+	}
+	if len(policies) == 0 && len(access) == 0 {
+		return errors.New("a key needs a --policy or an --access to be of use")
+	}
+
+	session := objects.NewKeySession(orgID, policies, access, keyExpiry(cmd))
+
+	id, _ := cmd.Flags().GetString("id")
+	if id == "" {
+		key, err := c.GenerateKey(session)
+		if err != nil {
+			return err
+		}
+		fmt.Println(key.KeyID)
+		return nil
+	}
+
+	hashed, _ := cmd.Flags().GetBool("hashed")
+	if err := c.CreateKey(&objects.Key{KeyID: id, Hashed: hashed, Session: session}); err != nil {
+		return err
+	}
+	fmt.Println(id)
+	return nil
+}
+
+func processKeysUpdate(cmd *cobra.Command, c *gateway.Client, args []string) error {
+	hashed, _ := cmd.Flags().GetBool("hashed")
+	key, err := c.FetchKey(args[0], hashed)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Flags().Changed("policy") {
+		policies, _ := cmd.Flags().GetStringSlice("policy")
+		key.Session["apply_policies"] = policies
+	}
+	if cmd.Flags().Changed("access") {
+		access, err := keyAccess(cmd)
+		if err != nil {
+			return err
+		}
+		key.Session["access_rights"] = objects.AccessRights(access)
+	}
+	if cmd.Flags().Changed("expires") {
+		key.Session["expires"] = objects.KeyExpiry(keyExpiry(cmd))
+	}
+
+	if err := c.UpdateKey(key); err != nil {
+		return err
+	}
+	fmt.Printf("Updated %v\n", key.KeyID)
+	return nil
+}
+
+func processKeysDelete(cmd *cobra.Command, c *gateway.Client, args []string) error {
+	hashed, _ := cmd.Flags().GetBool("hashed")
+	for _, id := range args {
+		if err := c.DeleteKey(id, hashed); err != nil {
+			return fmt.Errorf("%v: %v", id, err)
+		}
+		fmt.Printf("Deleted %v\n", id)
+	}
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(keysCmd)
+	keysCmd.AddCommand(keysListCmd, keysCreateCmd, keysUpdateCmd, keysDeleteCmd)
+
+	keysCmd.PersistentFlags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+	keysCmd.PersistentFlags().StringP("secret", "s", "", "Your gateway secret, falls back to TYKGIT_GW_SECRET")
+	keysCmd.PersistentFlags().Bool("hashed", false, "Key IDs are the hashes of the keys, for gateways with hash_keys")
+
+	keysListCmd.Flags().String("prefix", "", "Only list the keys whose ID starts with this prefix")
+
+	for _, c := range []*cobra.Command{keysCreateCmd, keysUpdateCmd} {
+		c.Flags().StringSlice("policy", []string{}, "ID of a policy the key applies, may be repeated")
+		c.Flags().StringArray("access", []string{}, "API the key may access, optionally with the versions granted (api-id:v1,v2), may be repeated")
+		c.Flags().Duration("expires", 0, "Expire the key after this long, e.g. 72h (never if not set)")
+	}
+	keysCreateCmd.Flags().String("id", "", "ID to create the key under, generated by the gateway if not set")
+	keysCreateCmd.Flags().StringP("org", "o", "", "Org ID of the key")
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// planLimits are the plan limits of the target, from the target profile and the --max-apis
+// and --max-policies flags
+var planLimits tyk_vcs.PlanLimits
+
+// profileLimits returns the plan limits of a profile, overridden by the flags
+func profileLimits(cmd *cobra.Command, profile *tyk_vcs.TargetProfile) tyk_vcs.PlanLimits {
+	limits := tyk_vcs.PlanLimits{}
+	if profile.Limits != nil {
+		limits = *profile.Limits
+	}
+
+	if n, _ := cmd.Flags().GetInt("max-apis"); n > 0 {
+		limits.MaxAPIs = n
+	}
+	if n, _ := cmd.Flags().GetInt("max-policies"); n > 0 {
+		limits.MaxPolicies = n
+	}
+
+	return limits
+}
+
+// checkPlanLimits compares the objects the target will hold after the sync, publish or update
+// with the limits of its plan, so a run that can't fit fails before changing anything. The
+// sizes are estimates when the run doesn't delete every object removed from the repo, which
+// publish and update never do.
+func checkPlanLimits(cmd *cobra.Command, publisher tyk_vcs.Publisher, gateway bool, defs []objects.DBApiDefinition, pols []objects.Policy) error {
+	if planLimits.MaxAPIs <= 0 && planLimits.MaxPolicies <= 0 {
+		return nil
+	}
+
+	deactivate, _ := cmd.Flags().GetBool("deactivate-removed")
+	wantedAPIs, _ := cmd.Flags().GetStringSlice("apis")
+	wantedPolicies, _ := cmd.Flags().GetStringSlice("policies")
+	partial := cmd.Use != "sync" || (syncScope != nil && !syncScope.Full) || syncProtect != nil || len(wantedAPIs) > 0 || len(wantedPolicies) > 0
+
+	sizes := []tyk_vcs.PlannedSize{}
+	if planLimits.MaxAPIs > 0 {
+		lister, ok := publisher.(tyk_vcs.APILister)
+		if !ok {
+			fmt.Printf("--> [WARNING] %v can't list its APIs, max_apis isn't checked\n", publisher.Name())
+		} else {
+			existing, err := lister.FetchAPIs()
+			if err != nil {
+				return err
+			}
+
+			have := make([]string, len(existing))
+			for i, d := range existing {
+				have[i] = d.APIID
+			}
+			want := make([]string, len(defs))
+			for i, d := range defs {
+				want[i] = d.APIID
+			}
+			sizes = append(sizes, tyk_vcs.PlanSize("APIs", have, want, deactivate || partial, planLimits.MaxAPIs))
+		}
+	}
+
+	if planLimits.MaxPolicies > 0 && !gateway {
+		lister, ok := publisher.(tyk_vcs.PolicyLister)
+		if !ok {
+			fmt.Printf("--> [WARNING] %v can't list its policies, max_policies isn't checked\n", publisher.Name())
+		} else {
+			existing, err := lister.FetchPolicies()
+			if err != nil {
+				return err
+			}
+
+			// Policies are matched by their database ID, an explicit ID finds the _id of the
+			// policy holding it on the target
+			have := make([]string, len(existing))
+			byID := map[string]string{}
+			for i, p := range existing {
+				have[i] = objects.NormalID(p.MID).Hex()
+				if p.ID != "" {
+					byID[p.ID] = have[i]
+				}
+			}
+			want := make([]string, len(pols))
+			for i, p := range pols {
+				if mid := objects.NormalID(p.MID); mid != "" {
+					want[i] = mid.Hex()
+				} else if p.ID != "" {
+					want[i] = byID[p.ID]
+				}
+			}
+			sizes = append(sizes, tyk_vcs.PlanSize("policies", have, want, partial, planLimits.MaxPolicies))
+		}
+	}
+
+	for _, s := range sizes {
+		after := fmt.Sprint(s.After)
+		if s.Estimate {
+			after = "up to " + after
+		}
+		fmt.Printf("> The target holds %v %v, %v after the sync (plan limit: %v)\n", s.Existing, s.Kind, after, s.Max)
+	}
+
+	warnings, err := tyk_vcs.CheckPlanLimits(sizes)
+	for _, w := range warnings {
+		fmt.Printf("--> [WARNING] %v\n", w)
+	}
+
+	return err
+}
+
+// checkTargetLimits checks the plan limits of the primary target and of the secondary targets
+// of --push-to before any of them is synced
+func checkTargetLimits(cmd *cobra.Command, primary *tyk_vcs.PublisherTarget, secondary []tyk_vcs.Target, defs []objects.DBApiDefinition, pols []objects.Policy) error {
+	if err := checkPlanLimits(cmd, primary.Publisher, primary.Gateway, defs, pols); err != nil {
+		return err
+	}
+
+	for _, t := range secondary {
+		pt, ok := t.(*tyk_vcs.PublisherTarget)
+		if !ok {
+			continue
+		}
+		if err := checkPlanLimits(cmd, pt.Publisher, pt.Gateway, defs, pols); err != nil {
+			return fmt.Errorf("--push-to %v: %v", t.Name(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// checkLive checks the published APIs are served by the gateway set with --check-live, the
+// results are added to report, which may be nil. APIs that are not live only cause warnings.
+func checkLive(cmd *cobra.Command, defs []objects.DBApiDefinition, report *tyk_vcs.SyncReport) error {
+	gwURL, _ := cmd.Flags().GetString("check-live")
+	if gwURL == "" {
+		return nil
+	}
+	timeout, _ := cmd.Flags().GetDuration("check-live-timeout")
+
+	fmt.Printf("> Checking APIs are live on %v\n", gwURL)
+	checker := &tyk_vcs.LivenessChecker{GatewayURL: gwURL, Timeout: timeout}
+	checks, err := checker.Check(defs)
+	if err != nil {
+		return fmt.Errorf("liveness check failed: %v", err)
+	}
+
+	for _, c := range checks {
+		switch {
+		case c.Live:
+			fmt.Printf("--> Live: %v (%v) on %v, code: %v\n", c.Name, c.APIID, c.ListenPath, c.StatusCode)
+		case c.Error != "":
+			fmt.Printf("--> [WARNING] Not live: %v (%v) on %v, Error:%v\n", c.Name, c.APIID, c.ListenPath, c.Error)
+		default:
+			fmt.Printf("--> [WARNING] Not live: %v (%v) on %v, code: %v\n", c.Name, c.APIID, c.ListenPath, c.StatusCode)
+		}
+	}
+
+	if report != nil {
+		report.Live = append(report.Live, checks...)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// syncReport collects the plans of the running sync, getPublisher hooks it into the plan check
+var syncReport *tyk_vcs.SyncReport
+
+func newNotifier(cmd *cobra.Command) (*tyk_vcs.Notifier, error) {
+	urls, _ := cmd.Flags().GetStringSlice("notify-url")
+	slack, _ := cmd.Flags().GetStringSlice("notify-slack")
+	on, _ := cmd.Flags().GetString("notify-on")
+
+	switch on {
+	case tyk_vcs.NotifyAlways, tyk_vcs.NotifySuccess, tyk_vcs.NotifyFailure:
+	default:
+		return nil, fmt.Errorf("unknown --notify-on value %q, must be one of always, success, failure", on)
+	}
+
+	status, err := newCommitStatus(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := newPRComment(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tyk_vcs.Notifier{URLs: urls, SlackURLs: slack, On: on, Status: status, Comment: comment}, nil
+}
+
+// newCommitStatus returns nil unless --commit-status is set
+func newCommitStatus(cmd *cobra.Command) (*tyk_vcs.CommitStatus, error) {
+	provider, _ := cmd.Flags().GetString("commit-status")
+	if provider == "" {
+		return nil, nil
+	}
+
+	context, _ := cmd.Flags().GetString("commit-status-context")
+	return tyk_vcs.CommitStatusFromEnv(provider, context, os.Getenv)
+}
+
+// newPRComment returns nil unless --pr-comment is set. Each command and target has its own
+// comment, updated by the next run.
+func newPRComment(cmd *cobra.Command) (*tyk_vcs.PRComment, error) {
+	provider, _ := cmd.Flags().GetString("pr-comment")
+	if provider == "" {
+		return nil, nil
+	}
+
+	return tyk_vcs.PRCommentFromEnv(provider, fmt.Sprintf("%v %v", cmd.Name(), targetURL(cmd)), os.Getenv)
+}
+
+func notifySync(n *tyk_vcs.Notifier, report *tyk_vcs.SyncReport, err error) {
+	report.Finish(err)
+	for _, nErr := range n.Send(report) {
+		fmt.Printf("--> [WARNING] Notification failed: %v\n", nErr)
+	}
+}
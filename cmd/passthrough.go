@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// usePassthrough decides if fields unknown to the vendored apidef are sent to the target.
+// In auto mode they are when the target is newer than the vendored apidef.
+func usePassthrough(cmd *cobra.Command, publisher tyk_vcs.Publisher) (bool, error) {
+	mode, _ := cmd.Flags().GetString("passthrough")
+	switch mode {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	case "auto":
+	default:
+		return false, fmt.Errorf("unknown passthrough mode %q, must be one of auto, on, off", mode)
+	}
+
+	vr, ok := publisher.(tyk_vcs.VersionReporter)
+	if !ok {
+		return false, nil
+	}
+
+	version, err := vr.TargetVersion()
+	if err == nil && version != "" {
+		var newer bool
+		if newer, err = objects.NewerThanVendored(version); err == nil {
+			if newer {
+				fmt.Printf("--> Target version %v is newer than %v, passing unknown fields through\n", version, objects.VendoredVersion)
+			}
+			return newer, nil
+		}
+	}
+
+	fmt.Println("--> [WARNING] Could not detect the target version, fields unknown to tyk-sync are dropped (use --passthrough=on to keep them)")
+	return false, nil
+}
+
+// negotiatePassthrough drops the unknown fields kept by the getter if passthrough is not used
+func negotiatePassthrough(cmd *cobra.Command, publisher tyk_vcs.Publisher, defs []objects.DBApiDefinition) error {
+	passthrough, err := usePassthrough(cmd, publisher)
+	if err != nil {
+		return err
+	}
+
+	if !passthrough {
+		for i := range defs {
+			defs[i].DropRaw()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// patchCmd represents the patch command
+var patchCmd = &cobra.Command{
+	Use:   "patch",
+	Short: "Apply JSON merge patches to live APIs and policies of a gateway or dashboard",
+	Long: `Patch applies RFC 7396 JSON merge patches to APIs or policies of the target by their ID, e.g.
+	to deactivate an API or raise a rate limit in an emergency without its full definition at hand.
+	The patches are read from patch files (--file), which can be committed to git to record the change:
+
+	  {"api_id": "a1", "reason": "INC-42 upstream down", "patch": {"active": false}}
+
+	or given inline with --api or --policy and --patch. Only the fields of the patch change, a null
+	removes a field, the ID and org of the object can't be patched. Use --dry-run to print the
+	patched objects instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processPatch(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// readPatches reads the patches of the patch files, or the inline patch of the flags
+func readPatches(cmd *cobra.Command) ([]tyk_vcs.ObjectPatch, error) {
+	files, _ := cmd.Flags().GetStringSlice("file")
+	apiID, _ := cmd.Flags().GetString("api")
+	policyID, _ := cmd.Flags().GetString("policy")
+	inline, _ := cmd.Flags().GetString("patch")
+	reason, _ := cmd.Flags().GetString("reason")
+
+	if len(files) > 0 {
+		if apiID != "" || policyID != "" || inline != "" {
+			return nil, errors.New("--file can't be used with --api, --policy or --patch")
+		}
+
+		patches := []tyk_vcs.ObjectPatch{}
+		for _, f := range files {
+			raw, err := ioutil.ReadFile(f)
+			if err != nil {
+				return nil, err
+			}
+			filePatches, err := tyk_vcs.ReadObjectPatches(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", f, err)
+			}
+			patches = append(patches, filePatches...)
+		}
+		return patches, nil
+	}
+
+	if inline == "" {
+		return nil, errors.New("set --file, or --api or --policy and --patch, to select the patches to apply")
+	}
+	raw, err := json.Marshal(tyk_vcs.ObjectPatch{APIID: apiID, PolicyID: policyID, Reason: reason, Patch: json.RawMessage(inline)})
+	if err != nil {
+		return nil, fmt.Errorf("--patch is not valid JSON: %v", err)
+	}
+	return tyk_vcs.ReadObjectPatches(raw)
+}
+
+func processPatch(cmd *cobra.Command, args []string) error {
+	patches, err := readPatches(cmd)
+	if err != nil {
+		return err
+	}
+
+	publisher, err := getPublisher(cmd, args)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Using publisher: %v\n", publisher.Name())
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	failed := 0
+	for _, p := range patches {
+		fmt.Printf("> Patching %v: %v\n", p.Target(), strings.Join(p.Fields(), ", "))
+		if p.Reason != "" {
+			fmt.Printf("--> Reason: %v\n", p.Reason)
+		}
+
+		patched, err := p.Apply(publisher, dryRun)
+		if err != nil {
+			fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+			failed++
+			continue
+		}
+
+		if dryRun {
+			asJSON, _ := json.MarshalIndent(patched, "", "  ")
+			fmt.Println(string(asJSON))
+			continue
+		}
+		fmt.Println("--> Status: OK")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v patches could not be applied", failed, len(patches))
+	}
+
+	fmt.Println("Done.")
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(patchCmd)
+
+	patchCmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+	patchCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	patchCmd.Flags().StringP("secret", "s", "", "Your API secret")
+	patchCmd.Flags().StringP("org", "o", "", "org ID override")
+	patchCmd.Flags().StringSliceP("file", "f", []string{}, "Patch files to apply (repeatable)")
+	patchCmd.Flags().String("api", "", "ID of the API to patch with --patch")
+	patchCmd.Flags().String("policy", "", "ID of the policy to patch with --patch")
+	patchCmd.Flags().String("patch", "", "JSON merge patch to apply to the --api or --policy, e.g. '{\"active\": false}'")
+	patchCmd.Flags().String("reason", "", "Why the --patch is applied, printed with it")
+	patchCmd.Flags().Bool("dry-run", false, "Print the patched objects instead of updating them")
+	patchCmd.Flags().Bool("test", false, "Use test publisher, output results to stdio")
+	patchCmd.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// previewCmd represents the preview command
+var previewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Render the definitions of a Github repo or file system for a local CE gateway",
+	Long: `Preview renders the APIs and policies of a Github repo or file system into the target
+	directory (--target) as a local Tyk CE gateway loads them: an apps/ folder with a file per
+	active API, policies/policies.json and a tyk.conf reading both, and with --docker-compose a
+	docker-compose.yml running the gateway and a redis, so definitions can be tried before any
+	dashboard is involved. The definitions are transformed as publish does, e.g. with --profile.
+	The definition files of a previous preview in the directory are replaced.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processPreview(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func processPreview(cmd *cobra.Command, args []string) error {
+	dir, _ := cmd.Flags().GetString("target")
+	if dir == "" {
+		return errors.New("set the target directory with --target")
+	}
+
+	getter, err := NewGetter(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	defs, pols, _, err := doGetDataFrom(cmd, getter)
+	if err != nil {
+		return err
+	}
+	printCoprocessWarnings(cmd, defs)
+
+	opts := tyk_vcs.PreviewOptions{}
+	opts.Secret, _ = cmd.Flags().GetString("secret")
+	opts.Port, _ = cmd.Flags().GetInt("port")
+	opts.Redis, _ = cmd.Flags().GetString("redis")
+	opts.Image, _ = cmd.Flags().GetString("image")
+	opts.Compose, _ = cmd.Flags().GetBool("docker-compose")
+
+	fmt.Printf("> Rendering preview in: %v\n", dir)
+	written, err := tyk_vcs.WritePreview(dir, defs, pols, opts)
+	if err != nil {
+		return err
+	}
+	for _, f := range written {
+		fmt.Printf("--> Wrote: %v\n", f)
+	}
+
+	if opts.Compose {
+		fmt.Printf("Run `docker-compose up` in %v, the gateway listens on http://localhost:%v\n", dir, opts.Port)
+	} else {
+		fmt.Printf("Mount %v, %v and %v into the gateway at /opt/tyk-gateway\n",
+			filepath.Join(dir, "tyk.conf"), filepath.Join(dir, "apps"), filepath.Join(dir, "policies"))
+	}
+
+	fmt.Println("Done.")
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(previewCmd)
+
+	previewCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
+	previewCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	previewCmd.Flags().String("tag", "", "Tag to check out instead of the branch (optional)")
+	previewCmd.Flags().String("commit", "", "Commit of the branch to check out instead of its tip (optional)")
+	previewCmd.Flags().String("subdir", "", "Directory of the repo holding the spec file, only its files are checked out (optional)")
+	previewCmd.Flags().Bool("submodules", false, "Also clone the submodules of the repo")
+	previewCmd.Flags().StringP("path", "p", "", "Source directory for definition files (optional)")
+	previewCmd.Flags().StringP("target", "t", "", "Target directory for the gateway files")
+	previewCmd.Flags().StringP("org", "o", "", "org ID override")
+	previewCmd.Flags().String("profile", "", "Target profile from the spec file to apply to the definitions (optional)")
+	previewCmd.Flags().StringSlice("apis", []string{}, "Specific Apis ids to render")
+	previewCmd.Flags().StringSlice("policies", []string{}, "Specific Policies ids to render")
+	previewCmd.Flags().StringP("secret", "s", "tyk-sync-preview", "Secret of the API of the local gateway")
+	previewCmd.Flags().Int("port", 8080, "Port the local gateway listens on")
+	previewCmd.Flags().String("redis", "redis:6379", "host:port of the redis of the local gateway, the one of the docker compose file by default")
+	previewCmd.Flags().Bool("docker-compose", false, "Also write a docker-compose.yml running the gateway and a redis")
+	previewCmd.Flags().String("image", "tykio/tyk-gateway:v2.9.4", "Gateway image of the docker compose file")
+	previewCmd.Flags().StringSlice("coprocess-drivers", []string{}, "Plugin drivers enabled on the local gateway, used to warn about unsupported plugins (optional)")
+}
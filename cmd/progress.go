@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const progressWidth = 30
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// useColor tells whether to color the output as set with --color, by default when stdout is
+// a terminal
+func useColor(cmd *cobra.Command) (bool, error) {
+	mode, _ := cmd.Flags().GetString("color")
+	switch mode {
+	case colorAlways:
+		return true, nil
+	case colorNever:
+		return false, nil
+	case colorAuto, "":
+		return terminal.IsTerminal(int(os.Stdout.Fd())), nil
+	default:
+		return false, fmt.Errorf("unknown color mode %v, must be %v, %v or %v", mode, colorAuto, colorAlways, colorNever)
+	}
+}
+
+// newProgress returns a progress bar for FetchParallel when stdout is a terminal, there is
+// no bar in logs
+func newProgress(label string) func(done, total int) {
+	if !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		return nil
+	}
+
+	return func(done, total int) {
+		filled := progressWidth * done / total
+		fmt.Printf("\r--> %v [%v%v] %v/%v", label, strings.Repeat("=", filled), strings.Repeat(" ", progressWidth-filled), done, total)
+		if done == total {
+			fmt.Println()
+		}
+	}
+}
+
+// newSyncProgress returns a progress bar for syncs when stdout is a terminal. The clients
+// print a line per change, so the bar is printed on a line of its own after each one.
+func newSyncProgress() objects.Progress {
+	if !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		return nil
+	}
+
+	return objects.ProgressFunc(func(e objects.ProgressEvent) {
+		if e.Item == "" || e.Total == 0 {
+			return
+		}
+
+		filled := progressWidth * e.Done / e.Total
+		status := fmt.Sprintf("about %v left", e.ETA().Round(time.Second))
+		if e.Done == e.Total {
+			status = fmt.Sprintf("done in %v", e.Elapsed().Round(time.Second))
+		}
+		fmt.Printf("--> Syncing %v (%v) [%v%v] %v/%v, %v\n", e.Kind, e.Phase, strings.Repeat("=", filled), strings.Repeat(" ", progressWidth-filled), e.Done, e.Total, status)
+	})
+}
+
+// trackSyncProgress is newSyncProgress, counting the changes applied in the report of the
+// running sync if there is one
+func trackSyncProgress() objects.Progress {
+	if syncReport == nil {
+		return newSyncProgress()
+	}
+	return syncReport.Track(newSyncProgress())
+}
+
+// printFailures lists the objects a dump could not fetch, it returns an error if there
+// are any
+func printFailures(failed []string) error {
+	if len(failed) == 0 {
+		return nil
+	}
+
+	fmt.Printf("--> [WARNING] %v objects could not be fetched and were not written:\n", len(failed))
+	for _, f := range failed {
+		fmt.Printf("  - %v\n", f)
+	}
+
+	return fmt.Errorf("%v objects could not be fetched", len(failed))
+}
+
+// exitOnFailures lists the objects that could not be fetched and exits with an error, after
+// a dump wrote the ones that could
+func exitOnFailures(failed []string) {
+	if err := printFailures(failed); err != nil {
+		fmt.Println("Error: ", err)
+		os.Exit(1)
+	}
+}
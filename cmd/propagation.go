@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// startPropagation records the state of the gateways for --wait-for-propagation before the
+// changes are made, it returns nil if the flag isn't set
+func startPropagation(cmd *cobra.Command) (*tyk_vcs.PropagationWaiter, error) {
+	if wait, _ := cmd.Flags().GetBool("wait-for-propagation"); !wait {
+		return nil, nil
+	}
+	if mock, _ := cmd.Flags().GetBool("test"); mock {
+		return nil, nil
+	}
+
+	dbString, _ := cmd.Flags().GetString("dashboard")
+	if dbString == "" {
+		return nil, errors.New("--wait-for-propagation reads the gateway nodes from the dashboard, a dashboard target is required")
+	}
+
+	adminSecret, _ := cmd.Flags().GetString("admin-secret")
+	if adminSecret == "" {
+		adminSecret = os.Getenv("TYKGIT_DB_ADMIN_SECRET")
+	}
+	if adminSecret == "" {
+		return nil, errors.New("Please set TYKGIT_DB_ADMIN_SECRET, or set the --admin-secret flag, to the admin_secret of your dashboard")
+	}
+
+	c, err := dashboard.NewDashboardClient(dbString, "", "")
+	if err != nil {
+		return nil, err
+	}
+	c.SetAdminSecret(adminSecret)
+
+	if cloud, _ := cmd.Flags().GetBool("cloud"); cloud {
+		c.SetCloud(true)
+	}
+
+	timeout, _ := cmd.Flags().GetDuration("propagation-timeout")
+	w := &tyk_vcs.PropagationWaiter{Fetch: c.FetchGatewayNodes, Timeout: timeout}
+	if err := w.Start(); err != nil {
+		return nil, fmt.Errorf("can't wait for propagation: %v", err)
+	}
+
+	return w, nil
+}
+
+// waitForPropagation waits for all gateways to load the changes, w may be nil. There is
+// nothing to wait for if no change was sent to the target.
+func waitForPropagation(w *tyk_vcs.PropagationWaiter, sent bool) error {
+	if w == nil {
+		return nil
+	}
+	if !sent {
+		fmt.Println("> No changes were sent, not waiting for the gateways")
+		return nil
+	}
+
+	fmt.Println("> Waiting for the gateways to load the changes")
+	if err := w.Wait(true); err != nil {
+		return err
+	}
+	fmt.Println("--> All gateways loaded the changes")
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/spf13/cobra"
 	"os"
+	"time"
 )
 
 // publishCmd represents the publish command
@@ -32,12 +33,56 @@ func init() {
 
 	// Here you will define your flags and configuration settings.
 	publishCmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+	publishCmd.Flags().String("gateway-dir", "", "Directory of a gateway loading its APIs and policies from files, its apps/ and policies/ folders are written")
 	publishCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
 	publishCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
 	publishCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	publishCmd.Flags().String("tag", "", "Tag to check out instead of the branch (optional)")
+	publishCmd.Flags().String("commit", "", "Commit of the branch to check out instead of its tip (optional)")
+	publishCmd.Flags().String("subdir", "", "Directory of the repo holding the spec file, only its files are checked out (optional)")
+	publishCmd.Flags().Bool("submodules", false, "Also clone the submodules of the repo")
 	publishCmd.Flags().StringP("secret", "s", "", "Your API secret")
+	publishCmd.Flags().StringP("org", "o", "", "org ID override")
 	publishCmd.Flags().StringP("path", "p", "", "Source directory for definition files (optional)")
 	publishCmd.Flags().Bool("test", false, "Use test publisher, output results to stdio")
+	publishCmd.Flags().BoolP("interactive", "i", false, "Print the planned changes and ask for confirmation, or pick the objects to apply, before applying them")
+	publishCmd.Flags().Bool("override-window", false, "Apply the changes even if no deployment window of the spec file is open")
+	publishCmd.Flags().String("canary", "", "Publish the APIs with this gateway segment tag only, then promote them to their own tags on confirmation (optional)")
+	publishCmd.Flags().Bool("stagger", false, "Roll the APIs out to the MDCB data plane groups of the spec (data_planes) one group at a time")
+	publishCmd.Flags().Duration("stagger-timeout", 2*time.Minute, "How long to wait for the APIs of a group to go live on its gateway_url")
+	publishCmd.Flags().Duration("stagger-pause", 0, "Pause between the data plane groups (optional)")
+	publishCmd.Flags().String("smoke-test", "", "Smoke test command to run against a copy of each API published under --smoke-test-prefix first, nothing is published if it fails. Needs --wait-for-propagation or --check-live (optional)")
+	publishCmd.Flags().String("smoke-test-prefix", "/smoke-test", "Listen path prefix of the copies tested with --smoke-test")
+	publishCmd.Flags().Duration("smoke-test-timeout", 5*time.Minute, "How long the --smoke-test command may run for each API")
+	publishCmd.Flags().String("policy-ids", "auto", "Match policies on the dashboard by their explicit id (explicit, needs allow_explicit_policy_id) or their _id (database), auto uses _id if the dashboard policies have no explicit id")
+	publishCmd.Flags().Bool("replace-categories", false, "Clear the categories of dashboard APIs whose definitions have none, by default they keep theirs")
+	publishCmd.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+	publishCmd.Flags().StringToString("list-param", map[string]string{}, "Query parameter to send with the dashboard list calls, e.g. --list-param region=eu, overrides the list_params of the profile (repeatable)")
+	publishCmd.Flags().Int("max-apis", 0, "Most APIs the plan of the target allows, checked before publishing, overrides the limits of the profile (optional)")
+	publishCmd.Flags().Int("max-policies", 0, "Most policies the plan of the target allows, checked before publishing, overrides the limits of the profile (optional)")
+	publishCmd.Flags().Int("page-size", 0, "Fetch the dashboard lists page by page, the page_size the dashboard is configured with, overrides the page_size of the profile (optional)")
+	publishCmd.Flags().Int("max-object-size", 0, "Fail if an API or policy of the dashboard lists is larger than this many bytes of JSON, lists are read one object at a time (optional)")
+	publishCmd.Flags().String("passthrough", "auto", "Send fields unknown to tyk-sync's API definition format to the target: auto (if the target is newer), on or off")
+	publishCmd.Flags().String("check-live", "", "Gateway URL to check the published APIs are loaded and route on, results are reported as warnings (optional)")
+	publishCmd.Flags().Duration("check-live-timeout", 30*time.Second, "How long to wait for each API to go live")
+	publishCmd.Flags().String("contract-tests", "", "Gateway URL to run the contract tests of the spec against, failed tests fail the command (optional)")
+	publishCmd.Flags().Duration("contract-tests-timeout", 10*time.Second, "Timeout of each contract test request")
+	publishCmd.Flags().StringSlice("notify-url", []string{}, "URL to POST the publish report (JSON) to when the publish finishes (repeatable)")
+	publishCmd.Flags().StringSlice("notify-slack", []string{}, "Slack incoming webhook URL to post a summary of the publish to (repeatable)")
+	publishCmd.Flags().String("notify-on", "always", "When to send notifications: always, success or failure")
+	publishCmd.Flags().String("commit-status", "", "Post the result as a commit status to github or gitlab, using the CI environment (optional)")
+	publishCmd.Flags().String("commit-status-context", "tyk-sync/publish", "Name of the commit status")
+	publishCmd.Flags().String("pr-comment", "", "Post the summary of the publish as a comment on the pull request to github or gitlab, using the CI environment (optional)")
+	publishCmd.Flags().String("check-upstreams", "", "Before publishing, check the upstreams of the APIs are reachable from here: tcp to resolve and connect to them, head to also send them a HEAD request (optional)")
+	publishCmd.Flags().Duration("check-upstreams-timeout", 5*time.Second, "Timeout of each upstream check")
+	publishCmd.Flags().Bool("wait-for-propagation", false, "Wait until all gateways of the dashboard loaded the changes, needs the dashboard admin secret")
+	publishCmd.Flags().Duration("propagation-timeout", 2*time.Minute, "How long to wait for the gateways to load the changes")
+	publishCmd.Flags().String("admin-secret", "", "The admin_secret of the dashboard, for --wait-for-propagation")
+	publishCmd.Flags().String("from-commit", "", "Only publish the objects whose files changed since this commit, or last for the commit of the last successful sync in --history (optional)")
+	publishCmd.Flags().String("history", "", "File to record every change in, with the commit it was published from, see the history command (optional)")
+	publishCmd.Flags().String("to-commit", "", "Last commit of the range for --from-commit, defaults to the checked out commit")
+	publishCmd.Flags().String("profile", "", "Target profile from the spec file to apply to the published objects (optional)")
+	publishCmd.Flags().StringSlice("coprocess-drivers", []string{}, "Plugin drivers enabled on the target gateways, otto if they enable the JSVM, used to warn about unsupported plugins (optional)")
 	publishCmd.Flags().StringSlice("policies",[]string{},"Specific Policies ids to publish")
 	publishCmd.Flags().StringSlice("apis",[]string{},"Specific Apis ids to publish")
 }
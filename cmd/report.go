@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report on how the objects of a dashboard are used",
+}
+
+var reportPoliciesCmd = &cobra.Command{
+	Use:   "policies",
+	Short: "Report the keys applying and the APIs granted by each policy of a dashboard",
+	Long: `Report policies cross-references every policy of a dashboard with the keys of the org,
+	counting the keys applying it, and with the APIs of the dashboard, listing those its
+	access rights grant. Policies no key applies are flagged as unused, and access rights to
+	APIs that don't exist as missing. With --fail-on-issues the command fails if any policy
+	is flagged, e.g. in a scheduled clean up job.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processReportPolicies(cmd)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func processReportPolicies(cmd *cobra.Command) error {
+	dbString, _ := cmd.Flags().GetString("dashboard")
+	if dbString == "" {
+		return errors.New("report policies requires a dashboard URL to be set")
+	}
+
+	secret, _ := cmd.Flags().GetString("secret")
+	if secret == "" {
+		secret = os.Getenv("TYKGIT_DB_SECRET")
+	}
+	if secret == "" {
+		return errors.New("Please set TYKGIT_DB_SECRET, or set the --secret flag, to your dashboard user secret")
+	}
+
+	c, err := dashboard.NewDashboardClient(dbString, secret, "")
+	if err != nil {
+		return err
+	}
+	if cloud, _ := cmd.Flags().GetBool("cloud"); cloud {
+		c.SetCloud(true)
+	}
+	c.SetListOptions(profileListOptions(cmd, &tyk_vcs.TargetProfile{}))
+
+	fmt.Println("> Fetching policies")
+	listed, err := c.FetchPolicies()
+	if err != nil {
+		return err
+	}
+
+	// The access rights of listed policies may not decode, they are fetched one by one
+	workers, _ := cmd.Flags().GetInt("workers")
+	fetched := make([]*objects.Policy, len(listed))
+	failures := tyk_vcs.FetchParallel(len(listed), workers, func(i int) (err error) {
+		fetched[i], err = c.FetchPolicy(listed[i].MID.Hex())
+		return err
+	}, newProgress("Fetching policies"))
+	if len(failures) > 0 {
+		f := failures[0]
+		return fmt.Errorf("policy %v (%v): %v", listed[f.Index].Name, listed[f.Index].MID.Hex(), f.Err)
+	}
+	pols := make([]objects.Policy, len(fetched))
+	for i, p := range fetched {
+		pols[i] = *p
+	}
+
+	fmt.Println("> Fetching APIs")
+	apis, err := c.FetchAPIs()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("> Fetching keys")
+	keys := tyk_vcs.PolicyKeyCounter{}
+	total := 0
+	err = c.EachKey(dashboard.ListOptions{}, func(k *objects.Key) error {
+		keys.Add(k)
+		total++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("--> Fetched %v policies, %v APIs and %v keys\n", len(pols), len(apis), total)
+
+	report := tyk_vcs.ReportPolicyUsage(pols, apis, keys)
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	}
+
+	flagged := 0
+	for _, u := range report {
+		line := fmt.Sprintf("Policy %v (%v): %v keys, grants %v", u.Name, u.ID, u.Keys, strings.Join(u.APIs, ", "))
+		if len(u.APIs) == 0 {
+			line = fmt.Sprintf("Policy %v (%v): %v keys, grants no API", u.Name, u.ID, u.Keys)
+		}
+
+		issues := []string{}
+		if u.Unused {
+			issues = append(issues, "unused")
+		}
+		if len(u.MissingAPIs) > 0 {
+			issues = append(issues, "grants access to missing APIs "+strings.Join(u.MissingAPIs, ", "))
+		}
+		if len(issues) > 0 {
+			flagged++
+			fmt.Printf("--> [WARNING] %v; %v\n", line, strings.Join(issues, ", "))
+			continue
+		}
+		fmt.Printf("--> %v\n", line)
+	}
+
+	if fail, _ := cmd.Flags().GetBool("fail-on-issues"); fail && flagged > 0 {
+		return fmt.Errorf("%v of %v policies are unused or grant access to missing APIs", flagged, len(report))
+	}
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportPoliciesCmd)
+
+	reportCmd.PersistentFlags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	reportCmd.PersistentFlags().StringP("secret", "s", "", "Your API secret")
+	reportCmd.PersistentFlags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+	reportCmd.PersistentFlags().Bool("json", false, "Also print the report as JSON")
+
+	reportPoliciesCmd.Flags().StringToString("list-param", map[string]string{}, "Query parameter to send with the dashboard list calls, e.g. --list-param region=eu (repeatable)")
+	reportPoliciesCmd.Flags().Int("page-size", 0, "Fetch the dashboard lists page by page, the page_size the dashboard is configured with (optional)")
+	reportPoliciesCmd.Flags().Int("max-object-size", 0, "Fail if an object of the dashboard lists is larger than this many bytes of JSON, lists are read one object at a time (optional)")
+	reportPoliciesCmd.Flags().Int("workers", 8, "Number of policies to fetch at once")
+	reportPoliciesCmd.Flags().Bool("fail-on-issues", false, "Fail if a policy is unused or grants access to missing APIs")
+}
@@ -0,0 +1,353 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
+	"github.com/TykTechnologies/tyk-sync/clients/files"
+	"github.com/TykTechnologies/tyk-sync/clients/gateway"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var restoreTypes = []string{"apis", "policies", "certs", "keys", "oauth-clients"}
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore objects from a dump or backup to a gateway or dashboard",
+	Long: `Restore republishes the objects of a dump or backup directory (or repo) to
+	a gateway or dashboard. Existing objects are updated, missing ones are created, and
+	nothing is deleted. Use --types and --ids to only restore some of the objects, e.g.
+	after an accidental deletion.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		verificationError := verifyArguments(cmd)
+		if verificationError != nil {
+			fmt.Println(verificationError)
+			os.Exit(1)
+		}
+
+		err := processRestore(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+type restoreFilter struct {
+	types map[string]bool
+	ids   map[string]bool
+}
+
+func newRestoreFilter(cmd *cobra.Command) (*restoreFilter, error) {
+	types, _ := cmd.Flags().GetStringSlice("types")
+	ids, _ := cmd.Flags().GetStringSlice("ids")
+
+	f := &restoreFilter{types: map[string]bool{}, ids: map[string]bool{}}
+	for _, t := range types {
+		known := false
+		for _, rt := range restoreTypes {
+			known = known || t == rt
+		}
+
+		if !known {
+			return nil, fmt.Errorf("unknown type %q, must be one of %v", t, restoreTypes)
+		}
+		f.types[t] = true
+	}
+
+	for _, id := range ids {
+		f.ids[id] = true
+	}
+
+	return f, nil
+}
+
+func (f *restoreFilter) wantType(t string) bool {
+	return len(f.types) == 0 || f.types[t]
+}
+
+func (f *restoreFilter) wantID(ids ...string) bool {
+	if len(f.ids) == 0 {
+		return true
+	}
+
+	for _, id := range ids {
+		if id != "" && f.ids[id] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isCreateError tells whether an update failed because the object doesn't exist on the
+// target, it is then created
+func isCreateError(err error) bool {
+	return err == dashboard.UseCreateError || err == gateway.UseCreateError || err == files.UseCreateError
+}
+
+// mapPolicy records the ID keys must use to apply a restored policy on the target, which
+// differs from the one in the dump if the target doesn't keep explicit policy IDs
+func mapPolicy(publisher tyk_vcs.Publisher, ids *tyk_vcs.IDMap, p *objects.Policy, sourceID, sourceMID string) {
+	f, ok := publisher.(tyk_vcs.Fetcher)
+	if !ok {
+		return
+	}
+
+	raw, err := f.FetchPolicyRaw(p)
+	if err != nil {
+		fmt.Printf("--> [WARNING] Policy %v not found on the target, keys applying it are not remapped: %v\n", p.Name, err)
+		return
+	}
+
+	ref := tyk_vcs.PolicyRef(raw)
+	ids.AddPolicy(sourceID, ref)
+	ids.AddPolicy(sourceMID, ref)
+}
+
+func printRestoreStatus(failed *int, id string, err error) {
+	if err != nil {
+		*failed++
+		fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+		return
+	}
+
+	fmt.Printf("--> Status: OK, ID:%v\n", id)
+}
+
+func processRestore(cmd *cobra.Command, args []string) error {
+	filter, err := newRestoreFilter(cmd)
+	if err != nil {
+		return err
+	}
+
+	if paths, _ := cmd.Flags().GetStringSlice("decrypt-with"); len(paths) > 0 {
+		keys, err := tyk_vcs.ReadPrivateKeys(paths, os.Getenv("TYKGIT_PGP_PASSPHRASE"))
+		if err != nil {
+			return err
+		}
+		tyk_vcs.SetDecryptionKeys(keys)
+	}
+
+	getter, err := NewGetter(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	if err := getter.FetchRepo(); err != nil {
+		return err
+	}
+
+	spec, err := getter.FetchTykSpec()
+	if err != nil {
+		return err
+	}
+
+	publisher, err := getPublisher(cmd, args)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Using publisher: %v\n", publisher.Name())
+
+	failed := 0
+
+	if filter.wantType("certs") && len(spec.Certificates) > 0 {
+		certs, err := getter.FetchCertificates(spec)
+		if err != nil {
+			return err
+		}
+
+		cp, ok := publisher.(tyk_vcs.CertificatePublisher)
+		for _, cert := range certs {
+			if !filter.wantID(cert.ID) {
+				continue
+			}
+
+			fmt.Printf("Restoring certificate: %v\n", cert.ID)
+			switch {
+			case !ok:
+				fmt.Println("--> [WARNING] Certificates are not supported by this publisher, skipping")
+			case len(cert.PEM) == 0:
+				fmt.Println("--> [WARNING] No certificate content in the backup (meta data only), skipping")
+			default:
+				id, err := cp.CreateCertificate(cert.PEM)
+				printRestoreStatus(&failed, id, err)
+			}
+		}
+	}
+
+	if filter.wantType("apis") {
+		defs, err := getter.FetchAPIDef(spec)
+		if err != nil {
+			return err
+		}
+
+		if err := negotiatePassthrough(cmd, publisher, defs); err != nil {
+			return err
+		}
+
+		for _, d := range defs {
+			if !filter.wantID(d.APIID, d.Id.Hex()) {
+				continue
+			}
+
+			fmt.Printf("Restoring API: %v\n", d.Name)
+			err := publisher.Update(&d)
+			if isCreateError(err) {
+				_, err = publisher.Create(&d)
+			}
+			printRestoreStatus(&failed, d.APIID, err)
+		}
+	}
+
+	// The IDs keys and OAuth clients must use for the policies and APIs on the target
+	ids := tyk_vcs.NewIDMap()
+	apiIDs, _ := cmd.Flags().GetStringToString("api-id-map")
+	for source, target := range apiIDs {
+		ids.AddAPI(source, target)
+	}
+	restoreKeys := filter.wantType("keys") && len(spec.Keys) > 0
+	restoreClients := filter.wantType("oauth-clients") && len(spec.OAuthClients) > 0 && !isGateway
+	mapPolicies := restoreKeys || restoreClients
+	if (filter.wantType("policies") || mapPolicies) && !isGateway {
+		pols, err := getter.FetchPolicies(spec)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range pols {
+			sourceID, sourceMID := p.ID, p.MID.Hex()
+			if !filter.wantType("policies") || !filter.wantID(p.ID, p.MID.Hex()) {
+				if mapPolicies {
+					mapPolicy(publisher, ids, &p, sourceID, sourceMID)
+				}
+				continue
+			}
+
+			fmt.Printf("Restoring Policy: %v\n", p.Name)
+			err := publisher.UpdatePolicy(&p)
+			if isCreateError(err) {
+				var id string
+				id, err = publisher.CreatePolicy(&p)
+				if err == nil && bson.IsObjectIdHex(id) {
+					p.MID = bson.ObjectIdHex(id)
+				}
+			}
+			printRestoreStatus(&failed, p.ID, err)
+
+			if err == nil && mapPolicies {
+				mapPolicy(publisher, ids, &p, sourceID, sourceMID)
+			}
+		}
+	}
+
+	if restoreKeys {
+		keys, err := getter.FetchKeys(spec)
+		if err != nil {
+			return err
+		}
+
+		kp, ok := publisher.(tyk_vcs.KeyPublisher)
+		if !ok {
+			fmt.Println("--> [WARNING] Keys are not supported by this publisher, skipping")
+			keys = []objects.Key{}
+		}
+
+		for _, k := range keys {
+			if !filter.wantID(k.KeyID) {
+				continue
+			}
+
+			fmt.Printf("Restoring key: %v\n", k.KeyID)
+			if n := ids.RemapKey(&k); n > 0 {
+				fmt.Printf("--> Remapped %v policy and API references to the IDs of the target\n", n)
+			}
+			printRestoreStatus(&failed, k.KeyID, kp.CreateKey(&k))
+		}
+	}
+
+	if restoreClients {
+		clients, err := getter.FetchOAuthClients(spec)
+		if err != nil {
+			return err
+		}
+
+		op, ok := publisher.(tyk_vcs.OAuthClientPublisher)
+		if !ok {
+			fmt.Println("--> [WARNING] OAuth clients are not supported by this publisher, skipping")
+			clients = []objects.OAuthClient{}
+		}
+
+		// The clients already registered on the target, by API
+		registered := map[string]map[string]bool{}
+		for _, c := range clients {
+			if !filter.wantID(c.ClientID, c.APIID) {
+				continue
+			}
+
+			fmt.Printf("Restoring OAuth client: %v\n", c.ClientID)
+			if n := ids.RemapOAuthClient(&c); n > 0 {
+				fmt.Printf("--> Remapped %v policy and API references to the IDs of the target\n", n)
+			}
+
+			if registered[c.APIID] == nil {
+				existing, err := op.FetchOAuthClients(c.APIID)
+				if err != nil {
+					printRestoreStatus(&failed, c.ClientID, err)
+					continue
+				}
+				registered[c.APIID] = map[string]bool{}
+				for _, e := range existing {
+					registered[c.APIID][e.ClientID] = true
+				}
+			}
+			if registered[c.APIID][c.ClientID] {
+				fmt.Println("--> Already registered on the target, skipping")
+				continue
+			}
+
+			printRestoreStatus(&failed, c.ClientID, op.CreateOAuthClient(&c))
+		}
+	}
+
+	if isGateway {
+		if err := publisher.Reload(); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v objects could not be restored", failed)
+	}
+
+	fmt.Println("Done")
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+	restoreCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	restoreCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
+	restoreCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	restoreCmd.Flags().String("tag", "", "Tag to check out instead of the branch (optional)")
+	restoreCmd.Flags().String("commit", "", "Commit of the branch to check out instead of its tip (optional)")
+	restoreCmd.Flags().String("subdir", "", "Directory of the repo holding the spec file, only its files are checked out (optional)")
+	restoreCmd.Flags().Bool("submodules", false, "Also clone the submodules of the repo")
+	restoreCmd.Flags().StringP("secret", "s", "", "Your API secret")
+	restoreCmd.Flags().StringP("org", "o", "", "org ID override")
+	restoreCmd.Flags().StringP("path", "p", "", "Source directory for definition files (optional)")
+	restoreCmd.Flags().Bool("test", false, "Use test publisher, output results to stdio")
+	restoreCmd.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+	restoreCmd.Flags().String("passthrough", "auto", "Send fields unknown to tyk-sync's API definition format to the target: auto (if the target is newer), on or off")
+	restoreCmd.Flags().StringSlice("types", []string{}, "Object types to restore: apis, policies, certs, keys, oauth-clients (defaults to all)")
+	restoreCmd.Flags().StringSlice("ids", []string{}, "Only restore the objects with these IDs (API IDs, policy IDs, certificate, key or OAuth client IDs)")
+	restoreCmd.Flags().StringSlice("decrypt-with", []string{}, "ASCII armored OpenPGP private key files to decrypt the .asc files of the dump with, protected keys are unlocked with TYKGIT_PGP_PASSPHRASE (repeatable)")
+	restoreCmd.Flags().StringToString("api-id-map", map[string]string{}, "API ID of the dump and the ID of the API on the target, for OAuth clients and keys of APIs the target restored under another ID, e.g. --api-id-map old=new (repeatable)")
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
+	"github.com/TykTechnologies/tyk-sync/clients/files"
+	"github.com/TykTechnologies/tyk-sync/clients/gateway"
+	"github.com/spf13/cobra"
+)
+
+func TestIsCreateError(t *testing.T) {
+	for _, err := range []error{dashboard.UseCreateError, gateway.UseCreateError, files.UseCreateError} {
+		if !isCreateError(err) {
+			t.Errorf("expected %v to be a create error", err)
+		}
+	}
+
+	for _, err := range []error{nil, dashboard.UseUpdateError, errors.New("Object does not exist, use create()")} {
+		if isCreateError(err) {
+			t.Errorf("expected %v not to be a create error", err)
+		}
+	}
+}
+
+func TestRestoreFilter(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().StringSlice("types", []string{"apis", "keys"}, "")
+	cmd.Flags().StringSlice("ids", []string{"a1", "gold"}, "")
+
+	f, err := newRestoreFilter(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.wantType("apis") || f.wantType("policies") {
+		t.Errorf("expected only the given types, got %v", f.types)
+	}
+	if !f.wantID("", "a1") || f.wantID("a2") || f.wantID("") {
+		t.Errorf("expected only the given IDs, got %v", f.ids)
+	}
+
+	cmd.Flags().Set("types", "apps")
+	if _, err := newRestoreFilter(cmd); err == nil {
+		t.Error("expected an unknown type to be refused")
+	}
+
+	all, err := newRestoreFilter(&cobra.Command{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !all.wantType("certs") || !all.wantID("anything") {
+		t.Error("expected no flags to restore everything")
+	}
+}
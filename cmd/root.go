@@ -1,18 +1,54 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"errors"
+	"fmt"
 
-func init() {
+	"github.com/TykTechnologies/tyk-sync/clients/transport"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
 
+func init() {
+	RootCmd.PersistentFlags().String("record", "", "Record the requests to the dashboard or gateway, secrets redacted, to this cassette file (optional)")
+	RootCmd.PersistentFlags().String("replay", "", "Answer the requests to the dashboard or gateway from this cassette file instead of sending them (optional)")
 }
 
+// Version is the release of the binary, see tyk_vcs.Version
+var Version = tyk_vcs.Version().Version
+
 var RootCmd = &cobra.Command{
-	Use:   "tyk-sync",
-	Short: "Tyk Git is a tool to integrate Tyk Gateway with Git",
+	Use:     "tyk-sync",
+	Version: Version,
+	Short:   "Tyk Git is a tool to integrate Tyk Gateway with Git",
 	Long: `A tool to use Tyk API Definitions or OAS (Swagger) files stored
 		in Git (or potentially other VCS) with the Tyk API Management
 		Platform (https://tyk.io)`,
+	PersistentPreRunE: setupVCR,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Do Stuff Here
 	},
 }
+
+// setupVCR records or replays the requests of the clients of any command
+func setupVCR(cmd *cobra.Command, args []string) error {
+	record, _ := cmd.Flags().GetString("record")
+	replay, _ := cmd.Flags().GetString("replay")
+
+	switch {
+	case record != "" && replay != "":
+		return errors.New("--record and --replay can't be used together")
+	case record != "":
+		fmt.Printf("> Recording requests to %v\n", record)
+		transport.SetVCR(transport.NewRecorder(record))
+	case replay != "":
+		cassette, err := transport.ReadCassette(replay)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("> Replaying %v recorded requests from %v\n", len(cassette.Interactions), replay)
+		transport.SetVCR(transport.NewReplayer(cassette))
+	}
+
+	return nil
+}
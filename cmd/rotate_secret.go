@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// secretEnv is the variable the dashboard commands read the secret from
+const secretEnv = "TYKGIT_DB_SECRET"
+
+// rotateSecretCmd represents the rotate-secret command
+var rotateSecretCmd = &cobra.Command{
+	Use:   "rotate-secret",
+	Short: "Replace the dashboard API key used by CI with a new one",
+	Long: `Rotate-secret resets the API key of the dashboard user CI publishes with, using the
+	API key of a dashboard admin, so a leaked key stops working. The user is the one whose key
+	is the current secret, or the one given with --user. The new key is written to the
+	--secret-file CI reads it from, replacing or adding the TYKGIT_DB_SECRET line of a .env file,
+	or printed when no file is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processRotateSecret(cmd)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// findUser returns the user with the email address, or without one the user whose key is
+// secret
+func findUser(users []objects.User, email, secret string) (*objects.User, error) {
+	for i, u := range users {
+		if email != "" && u.EmailAddress == email {
+			return &users[i], nil
+		}
+		if email == "" && u.AccessKey == secret {
+			return &users[i], nil
+		}
+	}
+
+	if email != "" {
+		return nil, fmt.Errorf("there is no user %v", email)
+	}
+
+	return nil, errors.New("no user has the current secret as API key, set --user")
+}
+
+func processRotateSecret(cmd *cobra.Command) error {
+	dbString, _ := cmd.Flags().GetString("dashboard")
+	if dbString == "" {
+		return errors.New("rotate-secret requires a dashboard URL to be set")
+	}
+
+	adminKey, _ := cmd.Flags().GetString("admin-key")
+	if adminKey == "" {
+		adminKey = os.Getenv("TYKGIT_DB_ADMIN_KEY")
+	}
+	if adminKey == "" {
+		return errors.New("Please set TYKGIT_DB_ADMIN_KEY, or set the --admin-key flag, to the API key of a dashboard admin")
+	}
+
+	secret, _ := cmd.Flags().GetString("secret")
+	if secret == "" {
+		secret = os.Getenv(secretEnv)
+	}
+	email, _ := cmd.Flags().GetString("user")
+	if secret == "" && email == "" {
+		return fmt.Errorf("set the --user to rotate the key of, or set %v, or the --secret flag, to its current key", secretEnv)
+	}
+	if secret == adminKey {
+		return errors.New("the admin key is the key being rotated, it can't read the new key once it is reset")
+	}
+
+	c, err := dashboard.NewDashboardClient(dbString, adminKey, "")
+	if err != nil {
+		return err
+	}
+	if cloud, _ := cmd.Flags().GetBool("cloud"); cloud {
+		c.SetCloud(true)
+	}
+
+	users, err := c.FetchUsers()
+	if err != nil {
+		return err
+	}
+	user, err := findUser(users, email, secret)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("> Rotating the API key of %v (%v)\n", user.EmailAddress, user.ID)
+	newKey, err := c.ResetUserKey(user.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Println("--> The previous key no longer works")
+
+	secretFile, _ := cmd.Flags().GetString("secret-file")
+	if secretFile == "" {
+		fmt.Printf("--> New key: %v\n", newKey)
+		return nil
+	}
+
+	if err := tyk_vcs.UpdateSecretFile(secretFile, secretEnv, newKey); err != nil {
+		// The old key is gone, the new one must not be lost with the file
+		fmt.Printf("--> New key: %v\n", newKey)
+		return fmt.Errorf("writing the new key to %v failed: %v", secretFile, err)
+	}
+	fmt.Printf("--> New key written to %v\n", secretFile)
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(rotateSecretCmd)
+
+	rotateSecretCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	rotateSecretCmd.Flags().String("admin-key", "", "The API key of a dashboard admin, which resets the key")
+	rotateSecretCmd.Flags().StringP("secret", "s", "", "The API key to rotate")
+	rotateSecretCmd.Flags().String("user", "", "Email address of the user whose key to rotate (defaults to the owner of the secret)")
+	rotateSecretCmd.Flags().String("secret-file", "", "File CI reads the secret from, a .env file gets its "+secretEnv+" line replaced or added (optional)")
+	rotateSecretCmd.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// releaseKeyEnv names the file of the key the releases are signed with, if --key isn't set
+const releaseKeyEnv = "TYKGIT_RELEASE_KEY"
+
+// selfUpdateCmd represents the self-update command
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Replace this binary with the latest release",
+	Long: `Self-update downloads the latest release of the channel for this platform and replaces
+		the running binary with it, for hosts without a package manager. The release is only
+		installed if its checksums are signed by the release key and its archive matches them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processSelfUpdate(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func processSelfUpdate(cmd *cobra.Command, args []string) error {
+	u := &tyk_vcs.Updater{}
+	u.Channel, _ = cmd.Flags().GetString("channel")
+	u.ReleasesURL, _ = cmd.Flags().GetString("releases-url")
+	check, _ := cmd.Flags().GetBool("check")
+	force, _ := cmd.Flags().GetBool("force")
+
+	latest, err := u.Latest()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("> Running %v, the latest %v release is %v\n", tyk_vcs.Version(), u.Channel, latest.Version())
+
+	newer := tyk_vcs.NewerVersion(latest.Version(), Version)
+	if check {
+		if newer {
+			fmt.Printf("--> %v is available, run self-update to install it\n", latest.Version())
+		} else {
+			fmt.Println("--> Up to date")
+		}
+		return nil
+	}
+
+	switch {
+	case force:
+	case Version == tyk_vcs.DevVersion:
+		return errors.New("this binary wasn't built from a release, set --force to replace it anyway")
+	case !newer:
+		fmt.Println("--> Up to date")
+		return nil
+	}
+
+	keyFiles, _ := cmd.Flags().GetStringSlice("key")
+	if len(keyFiles) == 0 && os.Getenv(releaseKeyEnv) != "" {
+		keyFiles = strings.Split(os.Getenv(releaseKeyEnv), ",")
+	}
+	if len(keyFiles) == 0 {
+		return fmt.Errorf("Please set %v, or set the --key flag, to the public key the releases are signed with", releaseKeyEnv)
+	}
+	if u.Keys, err = tyk_vcs.ReadRecipients(keyFiles); err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+
+	fmt.Printf("--> Downloading %v\n", u.ArchiveName(latest))
+	bin, err := u.Download(latest)
+	if err != nil {
+		return err
+	}
+	fmt.Println("--> Signature and checksum verified")
+
+	if err := tyk_vcs.ReplaceExecutable(exe, bin); err != nil {
+		return fmt.Errorf("couldn't replace %v: %v", exe, err)
+	}
+	fmt.Printf("Updated %v to %v\n", exe, latest.Version())
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(selfUpdateCmd)
+
+	selfUpdateCmd.Flags().String("channel", tyk_vcs.ChannelStable, "Release channel to update from: stable, or prerelease to also get release candidates")
+	selfUpdateCmd.Flags().Bool("check", false, "Only tell whether a newer release is available")
+	selfUpdateCmd.Flags().Bool("force", false, "Install the latest release even if it isn't newer than this binary")
+	selfUpdateCmd.Flags().StringSlice("key", []string{}, "ASCII armored OpenPGP public key file the releases are signed with, falls back to "+releaseKeyEnv)
+	selfUpdateCmd.Flags().String("releases-url", tyk_vcs.DefaultReleasesURL, "GitHub API URL listing the releases, e.g. of a mirror")
+}
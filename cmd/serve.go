@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// cloneCache is shared by the getters of a long running sync, see serveSync
+var cloneCache *tyk_vcs.CloneCache
+
+// newElector returns nil unless --leader-election is set
+func newElector(cmd *cobra.Command) (*tyk_vcs.LeaderElector, error) {
+	backend, _ := cmd.Flags().GetString("leader-election")
+	name, _ := cmd.Flags().GetString("leader-lease")
+	holder, _ := cmd.Flags().GetString("leader-id")
+	ttl, _ := cmd.Flags().GetDuration("leader-ttl")
+
+	var lease tyk_vcs.LeaderLease
+	switch backend {
+	case "":
+		return nil, nil
+	case "kubernetes":
+		namespace, _ := cmd.Flags().GetString("leader-namespace")
+		l, err := tyk_vcs.NewKubernetesLease(namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		lease = l
+	case "redis":
+		redisURL, _ := cmd.Flags().GetString("redis-url")
+		if redisURL == "" {
+			redisURL = os.Getenv("TYKGIT_REDIS_URL")
+		}
+		if redisURL == "" {
+			return nil, errors.New("Please set TYKGIT_REDIS_URL, or set the --redis-url flag, for --leader-election redis")
+		}
+		l, err := tyk_vcs.NewRedisLease(redisURL, name)
+		if err != nil {
+			return nil, err
+		}
+		lease = l
+	default:
+		return nil, fmt.Errorf("unknown --leader-election backend %q, must be kubernetes or redis", backend)
+	}
+
+	return &tyk_vcs.LeaderElector{
+		Lease:  lease,
+		Holder: holder,
+		TTL:    ttl,
+		OnChange: func(leader bool) {
+			if leader {
+				fmt.Println("> Elected leader, applying changes")
+			} else {
+				fmt.Println("> Standing by, another instance leads, a running sync stops applying changes")
+			}
+		},
+		OnError: func(err error) {
+			fmt.Printf("--> [WARNING] Leader election: %v\n", err)
+		},
+	}, nil
+}
+
+// serveSync runs sync as a daemon: it syncs once, then on every push webhook to the synced
+// branch, until SIGTERM or SIGINT, which let the running sync finish. With leader election
+// only the elected replica syncs.
+func serveSync(cmd *cobra.Command, args []string, addr string) error {
+	if interactive, _ := cmd.Flags().GetBool("interactive"); interactive {
+		return errors.New("--interactive can not be used with --serve")
+	}
+	timeout, _ := cmd.Flags().GetDuration("shutdown-timeout")
+	elector, err := newElector(cmd)
+	if err != nil {
+		return err
+	}
+
+	secret, _ := cmd.Flags().GetString("webhook-secret")
+	if secret == "" {
+		secret = os.Getenv("TYKGIT_WEBHOOK_SECRET")
+	}
+	if secret == "" {
+		fmt.Println("--> [WARNING] No --webhook-secret is set: ANYONE who can reach /webhook can trigger a sync, only expose it to your git server")
+	}
+
+	cloneCache = tyk_vcs.NewCloneCache()
+	_, branch := getAuthAndBranch(cmd, args)
+	daemon := &tyk_vcs.Daemon{
+		Branch:  branch,
+		Elector: elector,
+		Secret:  secret,
+		Sync: func(ctx context.Context) error {
+			syncCtx = ctx
+			defer func() { syncCtx = context.Background() }()
+
+			err := processSync(cmd, args)
+			if err != nil {
+				fmt.Println("Error: ", err)
+			}
+			return err
+		},
+	}
+	if len(args) > 0 {
+		daemon.Repo = args[0]
+	}
+
+	srv := &http.Server{Addr: addr, Handler: daemon.Handler()}
+	served := make(chan error, 1)
+	go func() { served <- srv.ListenAndServe() }()
+	fmt.Printf("> Serving /webhook, /healthz and /readyz on %v\n", addr)
+
+	daemon.Start()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	select {
+	case err := <-served:
+		return err
+	case s := <-signals:
+		fmt.Printf("> Received %v, shutting down once the running sync finished\n", s)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := daemon.Shutdown(ctx); err != nil {
+		return fmt.Errorf("the running sync did not finish in %v: %v", timeout, err)
+	}
+
+	return srv.Shutdown(ctx)
+}
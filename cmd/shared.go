@@ -1,44 +1,141 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/TykTechnologies/tyk-sync/cli-publisher"
+	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
 	"github.com/TykTechnologies/tyk-sync/clients/objects"
 	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/TykTechnologies/tyk/apidef"
 	"github.com/spf13/cobra"
 )
 
 var isGateway bool
 
-func doGitFetchCycle(getter tyk_vcs.Getter) ([]objects.DBApiDefinition, []objects.Policy, error) {
+// syncCtx is canceled when the running sync must stop, e.g. when serve loses the leader lease
+var syncCtx = context.Background()
+
+// errSyncStopped refuses the changes of a sync once syncCtx is canceled
+var errSyncStopped = errors.New("the sync was stopped, the change was not applied")
+
+// syncHooks are the hooks of the publishers: the changes are recorded in the history, and
+// refused once syncCtx is canceled
+func syncHooks() *objects.Hooks {
+	ctx := syncCtx
+	hooks := syncHistory.Hooks()
+	if hooks == nil {
+		hooks = &objects.Hooks{}
+	}
+
+	stop := func(*objects.HookEvent) error {
+		if ctx.Err() != nil {
+			return errSyncStopped
+		}
+		return nil
+	}
+	hooks.OnBeforeCreate, hooks.OnBeforeUpdate, hooks.OnBeforeDelete = stop, stop, stop
+	return hooks
+}
+
+// listOptions are the options of the dashboard list calls, from the target profile and the
+// --list-param and --page-size flags
+var listOptions dashboard.ListOptions
+
+// profileListOptions returns the list options of a profile, overridden by the flags
+func profileListOptions(cmd *cobra.Command, profile *tyk_vcs.TargetProfile) dashboard.ListOptions {
+	opts := dashboard.ListOptions{Params: profile.ListParams, PageSize: profile.PageSize}
+
+	over := dashboard.ListOptions{}
+	over.Params, _ = cmd.Flags().GetStringToString("list-param")
+	over.PageSize, _ = cmd.Flags().GetInt("page-size")
+	over.MaxObjectSize, _ = cmd.Flags().GetInt("max-object-size")
+
+	return opts.Merge(over)
+}
+
+// doGitFetchCycle loads the definitions and policies of the spec and runs the definitions
+// through the transformation pipeline: the profile defaults, the display fields, sunsets and
+// patches of the spec, the org, the managed marker, the secrets of the environment and last the
+// transformers registered with tyk_vcs.RegisterTransformer
+func doGitFetchCycle(getter tyk_vcs.Getter, profileName, orgID string) ([]objects.DBApiDefinition, []objects.Policy, *tyk_vcs.TykSourceSpec, error) {
 	err := getter.FetchRepo()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	ts, err := getter.FetchTykSpec()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	profile, err := ts.Profile(profileName)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
 	ads, err := getter.FetchAPIDef(ts)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	strip, err := ts.StripFields(profile)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
 	pols, err := getter.FetchPolicies(ts)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	sunsets, err := ts.SunsetStatus(time.Now())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, s := range sunsets {
+		fmt.Printf("--> %v\n", s)
+	}
+
+	pipeline := tyk_vcs.Pipeline{
+		tyk_vcs.ProfileTransformer(profile, strip),
+		ts.FileTransformer(profileName),
+		tyk_vcs.OrgTransformer(orgID),
+		tyk_vcs.ManagedTransformer(ts.Repo),
+		tyk_vcs.SecretTransformer(os.LookupEnv),
+	}
+	pipeline = append(pipeline, tyk_vcs.RegisteredTransformers()...)
+	if err := pipeline.Run(ads); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// The definitions are patched by the pipeline
+	if err := ts.ApplyPatches(profileName, nil, pols); err != nil {
+		return nil, nil, nil, err
 	}
+	tyk_vcs.MarkManaged(ts.Repo, nil, pols)
 
-	return ads, pols, nil
+	return ads, pols, ts, nil
+}
+
+// targetsGateway tells whether getPublisher returns a gateway publisher, which takes no
+// policies, for the checks made before it is called
+func targetsGateway(cmd *cobra.Command) bool {
+	mock, _ := cmd.Flags().GetBool("test")
+	dbString, _ := cmd.Flags().GetString("dashboard")
+	gwString, _ := cmd.Flags().GetString("gateway")
+	return !mock && dbString == "" && gwString != ""
 }
 
 func getPublisher(cmd *cobra.Command, args []string) (tyk_vcs.Publisher, error) {
+	isGateway = targetsGateway(cmd)
+
 	mock, _ := cmd.Flags().GetBool("test")
 	if mock {
 		return cli_publisher.MockPublisher{}, nil
@@ -62,12 +159,39 @@ func getPublisher(cmd *cobra.Command, args []string) (tyk_vcs.Publisher, error)
 			secret = flagVal
 		}
 
+		check, err := planCheck(cmd)
+		if err != nil {
+			return nil, err
+		}
+		if syncReport != nil {
+			check = syncReport.Record(check)
+		}
+
 		orgOverride, _ := cmd.Flags().GetString("org")
+		cloud, _ := cmd.Flags().GetBool("cloud")
+
+		policyIDs, _ := cmd.Flags().GetString("policy-ids")
+		switch policyIDs {
+		case "", dashboard.PolicyIDsAuto, dashboard.PolicyIDsExplicit, dashboard.PolicyIDsDatabase:
+		default:
+			return nil, fmt.Errorf("--policy-ids must be %v, %v or %v", dashboard.PolicyIDsAuto, dashboard.PolicyIDsExplicit, dashboard.PolicyIDsDatabase)
+		}
+
+		replaceCategories, _ := cmd.Flags().GetBool("replace-categories")
+		deactivateRemoved, _ := cmd.Flags().GetBool("deactivate-removed")
 
 		newDashPublisher := &cli_publisher.DashboardPublisher{
-			Secret:      secret,
-			Hostname:    dbString,
-			OrgOverride: orgOverride,
+			Secret:            secret,
+			Hostname:          dbString,
+			OrgOverride:       orgOverride,
+			Cloud:             cloud,
+			PlanCheck:         check,
+			PolicyIDMode:      policyIDs,
+			ReplaceCategories: replaceCategories,
+			ListOptions:       listOptions,
+			Progress:          trackSyncProgress(),
+			Hooks:             syncHooks(),
+			DeactivateRemoved: deactivateRemoved,
 		}
 
 		return newDashPublisher, nil
@@ -89,15 +213,47 @@ func getPublisher(cmd *cobra.Command, args []string) (tyk_vcs.Publisher, error)
 			secret = flagVal
 		}
 
+		check, err := planCheck(cmd)
+		if err != nil {
+			return nil, err
+		}
+		if syncReport != nil {
+			check = syncReport.Record(check)
+		}
+
+		deactivateRemoved, _ := cmd.Flags().GetBool("deactivate-removed")
 		newGWPublisher := &cli_publisher.GatewayPublisher{
-			Secret:   secret,
-			Hostname: gwString,
+			Secret:            secret,
+			Hostname:          gwString,
+			PlanCheck:         check,
+			Progress:          trackSyncProgress(),
+			Hooks:             syncHooks(),
+			DeactivateRemoved: deactivateRemoved,
 		}
 
-		isGateway = true
 		return newGWPublisher, nil
 	}
 
+	gwDir, _ := cmd.Flags().GetString("gateway-dir")
+	if gwDir != "" {
+		check, err := planCheck(cmd)
+		if err != nil {
+			return nil, err
+		}
+		if syncReport != nil {
+			check = syncReport.Record(check)
+		}
+
+		deactivateRemoved, _ := cmd.Flags().GetBool("deactivate-removed")
+		return &cli_publisher.FilesPublisher{
+			Dir:               gwDir,
+			PlanCheck:         check,
+			Progress:          trackSyncProgress(),
+			Hooks:             syncHooks(),
+			DeactivateRemoved: deactivateRemoved,
+		}, nil
+	}
+
 	return nil, errors.New("Publisher target not defined!")
 }
 
@@ -116,36 +272,92 @@ func getAuthAndBranch(cmd *cobra.Command, args []string) ([]byte, string) {
 	return auth, branch
 }
 
+// gitOptions reads the flags selecting what to check out of a git repo, commands without
+// them get the defaults
+func gitOptions(cmd *cobra.Command) tyk_vcs.GitOptions {
+	tag, _ := cmd.Flags().GetString("tag")
+	commit, _ := cmd.Flags().GetString("commit")
+	subDir, _ := cmd.Flags().GetString("subdir")
+	submodules, _ := cmd.Flags().GetBool("submodules")
+	from, _ := cmd.Flags().GetString("from-commit")
+	if from == "" {
+		// changelog compares two revisions
+		from, _ = cmd.Flags().GetString("from")
+	}
+
+	return tyk_vcs.GitOptions{
+		Tag:        tag,
+		Commit:     commit,
+		SubDir:     subDir,
+		Submodules: submodules,
+		// The commit a range starts with is not part of a shallow clone
+		FullHistory: from != "",
+		Cache:       cloneCache,
+	}
+}
+
 func NewGetter(cmd *cobra.Command, args []string) (tyk_vcs.Getter, error) {
+	opts := gitOptions(cmd)
 	filePath, _ :=  cmd.Flags().GetString("path")
 	if filePath != "" {
+		if opts.Tag != "" || opts.Commit != "" || opts.SubDir != "" || opts.Submodules {
+			return nil, errors.New("--tag, --commit, --subdir and --submodules apply to git repos, not to --path")
+		}
 		return tyk_vcs.NewFSGetter(filePath)
 	}
 
 	if len(args) == 0 {
 		return nil, errors.New("must specify repo address to pull from as first argument")
 	}
+
+	// S3 buckets, archives and the sources registered with tyk_vcs.RegisterSource
+	src, ok, err := tyk_vcs.NewSource(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if opts.Tag != "" || opts.Commit != "" || opts.SubDir != "" || opts.Submodules {
+			return nil, errors.New("--tag, --commit, --subdir and --submodules apply to git repos, not to " + args[0])
+		}
+		return tyk_vcs.NewSourceGetter(src), nil
+	}
 	auth, branch := getAuthAndBranch(cmd, args)
-	return tyk_vcs.NewGGetter(args[0], branch, auth)
+	if opts.Tag != "" && cmd.Flags().Changed("branch") {
+		return nil, errors.New("set either --branch or --tag, not both")
+	}
+	return tyk_vcs.NewGGetterWithOptions(args[0], branch, auth, opts)
 }
 
-func doGetData(cmd *cobra.Command, args []string) ([]objects.DBApiDefinition, []objects.Policy, error) {
+func doGetData(cmd *cobra.Command, args []string) ([]objects.DBApiDefinition, []objects.Policy, *tyk_vcs.TykSourceSpec, error) {
 
 	getter, err := NewGetter(cmd, args)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	defs, pols, err := doGitFetchCycle(getter)
+	return doGetDataFrom(cmd, getter)
+}
+
+func doGetDataFrom(cmd *cobra.Command, getter tyk_vcs.Getter) ([]objects.DBApiDefinition, []objects.Policy, *tyk_vcs.TykSourceSpec, error) {
+	profileName, _ := cmd.Flags().GetString("profile")
+	orgID, _ := cmd.Flags().GetString("org")
+	defs, pols, spec, err := doGitFetchCycle(getter, profileName, orgID)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
+	profile, err := spec.Profile(profileName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	listOptions = profileListOptions(cmd, profile)
+	planLimits = profileLimits(cmd, profile)
+
 	wantedPolicies , _ := cmd.Flags().GetStringSlice("policies")
 	wantedAPIs , _ := cmd.Flags().GetStringSlice("apis")
 
 	if len(wantedAPIs) == 0 && len(wantedPolicies) == 0 {
-		return defs, pols, nil
+		return defs, pols, spec, nil
 	}
 	filteredAPIS := []objects.DBApiDefinition{}
 	filteredPolicies := []objects.Policy{}
@@ -180,47 +392,243 @@ func doGetData(cmd *cobra.Command, args []string) ([]objects.DBApiDefinition, []
 		filteredPolicies = filteredPolicies[:newL]
 	}
 
-	return filteredAPIS, filteredPolicies, nil
+	return filteredAPIS, filteredPolicies, spec, nil
+}
+
+func printCoprocessWarnings(cmd *cobra.Command, defs []objects.DBApiDefinition) {
+	drivers, _ := cmd.Flags().GetStringSlice("coprocess-drivers")
+	for _, w := range tyk_vcs.CoprocessWarnings(defs, drivers) {
+		fmt.Printf("--> [WARNING] %v\n", w)
+	}
+}
+
+// printDeprecationWarnings warns about the deprecated fields the definitions use, those the
+// target version deprecated when the publisher can tell its version
+func printDeprecationWarnings(publisher tyk_vcs.Publisher, defs []objects.DBApiDefinition) {
+	version := ""
+	if vr, ok := publisher.(tyk_vcs.VersionReporter); ok {
+		version, _ = vr.TargetVersion()
+	}
+
+	for _, w := range tyk_vcs.DeprecationWarnings(defs, version) {
+		fmt.Printf("--> [WARNING] %v\n", w)
+	}
+}
+
+// checkTargetVersion refuses to publish to a target whose version doesn't meet the requires
+// of the spec, before anything is changed
+func checkTargetVersion(publisher tyk_vcs.Publisher, requires *tyk_vcs.RequiresInfo, gateway bool) error {
+	if requires == nil || (requires.Dashboard == "" && requires.Gateway == "") {
+		return nil
+	}
+
+	vr, ok := publisher.(tyk_vcs.VersionReporter)
+	if !ok {
+		fmt.Printf("--> [WARNING] %v can't tell its version, the requires of the spec aren't checked\n", publisher.Name())
+		return nil
+	}
+
+	version, err := vr.TargetVersion()
+	if err != nil {
+		fmt.Printf("--> [WARNING] Could not detect the target version: %v\n", err)
+	}
+	return requires.CheckTarget(version, gateway)
 }
 
-func processSync(cmd *cobra.Command, args []string) error {
-	defs, pols, err := doGetData(cmd, args)
+func processSync(cmd *cobra.Command, args []string) (err error) {
+	notifier, err := newNotifier(cmd)
 	if err != nil {
 		return err
 	}
 
-	publisher, err := getPublisher(cmd, args)
+	syncReport = tyk_vcs.NewSyncReport(targetURL(cmd))
+	defer func() { notifySync(notifier, syncReport, err) }()
+
+	if err := resolveRollback(cmd); err != nil {
+		return err
+	}
+
+	getter, err := NewGetter(cmd, args)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Using publisher: %v\n", publisher.Name())
 
-	if len(pols) > 0 && !isGateway {
-		fmt.Println("Processing Policies...")
-		if err := publisher.SyncPolicies(pols); err != nil {
-			return err
-		}
+	if err := getter.FetchRepo(); err != nil {
+		return err
 	}
 
-	fmt.Println("Processing APIs...")
-	if err := publisher.Sync(defs); err != nil {
+	changes, err := listChanges(cmd, getter)
+	if err != nil {
 		return err
 	}
 
-	if isGateway {
-		if err := publisher.Reload(); err != nil {
+	if err := openHistory(cmd, getter); err != nil {
+		return err
+	}
+	defer func() { recordRun(cmd, err) }()
+
+	spec, err := getter.FetchTykSpec()
+	if err != nil {
+		return err
+	}
+
+	if syncScope, err = scopeChanges(getter, spec, changes); err != nil {
+		return err
+	}
+
+	if syncWindow, err = windowGate(cmd, spec); err != nil {
+		return err
+	}
+
+	waiter, err := startPropagation(cmd)
+	if err != nil {
+		return err
+	}
+
+	secondary, err := openTargets(cmd)
+	if err != nil {
+		return err
+	}
+
+	if len(spec.Tenants) > 0 {
+		if len(secondary) > 0 {
+			return errors.New("--push-to doesn't apply to the tenants of a spec, each tenant would replace the objects of the others")
+		}
+		if err := syncTenants(cmd, getter, spec.Tenants); err != nil {
 			return err
 		}
+		if err := waitForPropagation(waiter, syncReport.Sent()); err != nil {
+			return err
+		}
+		return syncWindow.Err()
 	}
+	syncProtect = spec.Protect
 
-	return nil
+	defs, pols, _, err := doGetDataFrom(cmd, getter)
+	if err != nil {
+		return err
+	}
+	printCoprocessWarnings(cmd, defs)
+	if err := checkGuardrails(spec, defs, pols); err != nil {
+		return err
+	}
+	if err := checkUpstreams(cmd, defs); err != nil {
+		return err
+	}
+
+	publisher, err := getPublisher(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	if err := configureTargets(cmd, secondary); err != nil {
+		return err
+	}
+
+	target := &tyk_vcs.PublisherTarget{Publisher: publisher, Gateway: isGateway}
+	gatePlans(target)
+	if err := checkTargetLimits(cmd, target, secondary, defs, pols); err != nil {
+		return err
+	}
+	if err := applySync(cmd, target, spec.Requires, defs, pols); err != nil {
+		return err
+	}
+
+	if err := pushTargets(cmd, spec.Requires, secondary, defs, pols); err != nil {
+		return err
+	}
+
+	if err := waitForPropagation(waiter, syncReport.Sent()); err != nil {
+		return err
+	}
+
+	if err := checkLive(cmd, defs, syncReport); err != nil {
+		return err
+	}
+
+	if err := runContractTests(cmd, getter, spec, defs, syncReport); err != nil {
+		return err
+	}
+
+	return syncWindow.Err()
+}
+
+// applySync syncs the objects of one target, the primary one or a secondary of --push-to,
+// after the checks of the spec and the flags. The definitions are copied as the passthrough
+// negotiated with one target must not change what the others are sent.
+func applySync(cmd *cobra.Command, target *tyk_vcs.PublisherTarget, requires *tyk_vcs.RequiresInfo, defs []objects.DBApiDefinition, pols []objects.Policy) error {
+	publisher := target.Publisher
+	fmt.Printf("Using publisher: %v\n", publisher.Name())
+
+	if err := checkTargetVersion(publisher, requires, target.Gateway); err != nil {
+		return err
+	}
+
+	defs = append([]objects.DBApiDefinition{}, defs...)
+	if err := negotiatePassthrough(cmd, publisher, defs); err != nil {
+		return err
+	}
+	printDeprecationWarnings(publisher, defs)
+
+	return target.Push(defs, pols)
 }
 
-func processPublish(cmd *cobra.Command, args []string) error {
-	defs, pols, err := doGetData(cmd, args)
+func processPublish(cmd *cobra.Command, args []string) (err error) {
+	notifier, err := newNotifier(cmd)
+	if err != nil {
+		return err
+	}
+
+	syncReport = tyk_vcs.NewSyncReport(targetURL(cmd))
+	syncReport.Action = cmd.Use
+	defer func() { notifySync(notifier, syncReport, err) }()
+
+	getter, err := NewGetter(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	changes, err := listChanges(cmd, getter)
+	if err != nil {
+		return err
+	}
+
+	if err := openHistory(cmd, getter); err != nil {
+		return err
+	}
+	defer func() { recordRun(cmd, err) }()
+
+	defs, pols, spec, err := doGetDataFrom(cmd, getter)
+	if err != nil {
+		return err
+	}
+
+	scope, err := scopeChanges(getter, spec, changes)
 	if err != nil {
 		return err
 	}
+	// The guardrails tell the production APIs by the tags of all of them
+	allDefs := defs
+	defs, pols = scope.Select(defs, pols)
+	printCoprocessWarnings(cmd, defs)
+	if err := checkGuardrails(spec, allDefs, pols); err != nil {
+		return err
+	}
+	if err := checkUpstreams(cmd, defs); err != nil {
+		return err
+	}
+
+	if syncWindow, err = windowGate(cmd, spec); err != nil {
+		return err
+	}
+	if syncWindow.Closed() {
+		apiPlan, polPlan := publishPlans(cmd, defs, pols)
+		syncWindow.Check(apiPlan)
+		if !targetsGateway(cmd) {
+			syncWindow.Check(polPlan)
+		}
+		return syncWindow.Err()
+	}
 
 	publisher, err := getPublisher(cmd, args)
 	if err != nil {
@@ -228,14 +636,77 @@ func processPublish(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Printf("Using publisher: %v\n", publisher.Name())
 
-	for i, d := range defs {
+	if err := checkTargetVersion(publisher, spec.Requires, isGateway); err != nil {
+		return err
+	}
+	if err := negotiatePassthrough(cmd, publisher, defs); err != nil {
+		return err
+	}
+	printDeprecationWarnings(publisher, defs)
+
+	defs, pols, err = confirmPublish(cmd, defs, pols)
+	if err != nil {
+		return err
+	}
+
+	if err := checkPlanLimits(cmd, publisher, isGateway, defs, pols); err != nil {
+		return err
+	}
+
+	if err := smokeTest(cmd, publisher, defs); err != nil {
+		return err
+	}
+
+	canary, err := stageCanary(cmd, defs)
+	if err != nil {
+		return err
+	}
+	// The definitions share their API definition, so promoting staged promotes the ones
+	// grouped into products too
+	staged := append([]objects.DBApiDefinition{}, defs...)
+
+	stagger, err := newStagger(cmd, spec, defs)
+	if err != nil {
+		return err
+	}
+
+	waiter, err := startPropagation(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Products are published first, each as a unit, the remaining objects one by one
+	units, defs, pols, err := tyk_vcs.GroupProducts(spec.Products, defs, pols)
+	if err != nil {
+		return err
+	}
+	if stagger != nil && len(units) > 0 {
+		return errors.New("products are published as a unit, they can't be rolled out with --stagger")
+	}
+	// sent counts what the target took, the gateways are only waited for if it is any
+	sent, unitsErr := publishUnits(cmd, publisher, units)
+
+	// failed holds the APIs the target didn't take, the others are checked once published
+	failed := map[*apidef.APIDefinition]bool{}
+	apiDefs := defs
+	if stagger != nil {
+		if err := publishStaged(cmd, publisher, stagger, defs); err != nil {
+			return err
+		}
+		sent += len(defs)
+		apiDefs = nil
+	}
+
+	for i, d := range apiDefs {
 		if cmd.Use == "publish" {
 			fmt.Printf("Creating API %v: %v\n", i, d.Name)
 			id, err := publisher.Create(&d)
 			if err != nil {
 				fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+				failed[d.APIDefinition] = true
 			} else {
 				fmt.Printf("--> Status: OK, ID:%v\n", id)
+				sent++
 			}
 		}
 
@@ -244,8 +715,10 @@ func processPublish(cmd *cobra.Command, args []string) error {
 			err := publisher.Update(&d)
 			if err != nil {
 				fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+				failed[d.APIDefinition] = true
 			} else {
 				fmt.Printf("--> Status: OK, ID:%v\n", d.APIID)
+				sent++
 			}
 		}
 	}
@@ -259,6 +732,7 @@ func processPublish(cmd *cobra.Command, args []string) error {
 					fmt.Printf("--> Status: FAIL, Error:%v\n", err)
 				} else {
 					fmt.Printf("--> Status: OK, ID:%v\n", id)
+					sent++
 				}
 			}
 
@@ -269,6 +743,7 @@ func processPublish(cmd *cobra.Command, args []string) error {
 					fmt.Printf("--> Status: FAIL, Error:%v\n", err)
 				} else {
 					fmt.Printf("--> Status: OK, ID:%v\n", d.Name)
+					sent++
 				}
 			}
 		}
@@ -280,6 +755,67 @@ func processPublish(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// The other objects are published even if a product failed, but nothing is promoted
+	if unitsErr != nil {
+		return unitsErr
+	}
+
+	if err := waitForPropagation(waiter, sent > 0); err != nil {
+		return err
+	}
+
+	// The APIs grouped into products are checked and tested too, they were all published if we got here
+	published := make([]objects.DBApiDefinition, 0, len(staged))
+	for _, d := range staged {
+		if !failed[d.APIDefinition] {
+			published = append(published, d)
+		}
+	}
+
+	if err := checkLive(cmd, published, syncReport); err != nil {
+		return err
+	}
+
+	if err := runContractTests(cmd, getter, spec, published, syncReport); err != nil {
+		return err
+	}
+
+	if err := promoteCanary(publisher, canary, staged); err != nil {
+		return err
+	}
+
 	fmt.Println("Done")
 	return nil
 }
+
+// publishUnits publishes each product as a unit, it returns the number of objects published
+// and an error naming the products that failed, the others are published regardless
+func publishUnits(cmd *cobra.Command, publisher tyk_vcs.Publisher, units []tyk_vcs.Unit) (int, error) {
+	sent := 0
+	action := tyk_vcs.CREATE
+	if cmd.Use == "update" {
+		action = tyk_vcs.UPDATE
+	}
+
+	failed := []string{}
+	for _, u := range units {
+		if isGateway && len(u.Policies) > 0 {
+			fmt.Printf("--> [WARNING] Product %v: policies are not supported by the gateway, publishing its APIs only\n", u.Name)
+			u.Policies = nil
+		}
+
+		fmt.Printf("Publishing product %v (%v APIs, %v policies)\n", u.Name, len(u.APIs), len(u.Policies))
+		if err := tyk_vcs.PublishUnit(publisher, u, action); err != nil {
+			fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+			failed = append(failed, u.Name)
+			continue
+		}
+		fmt.Println("--> Status: OK")
+		sent += len(u.APIs) + len(u.Policies)
+	}
+
+	if len(failed) > 0 {
+		return sent, fmt.Errorf("%v of the %v products failed to publish: %v", len(failed), len(units), strings.Join(failed, ", "))
+	}
+	return sent, nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/cli-publisher"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/spf13/cobra"
+)
+
+// failingPublisher fails to create the APIs named in fail
+type failingPublisher struct {
+	cli_publisher.MockPublisher
+	fail map[string]bool
+}
+
+func (p failingPublisher) Create(def *objects.DBApiDefinition) (string, error) {
+	if p.fail[def.Name] {
+		return "", errors.New("dashboard down")
+	}
+	return p.MockPublisher.Create(def)
+}
+
+func TestPublishUnitsReturnsFailures(t *testing.T) {
+	api := func(name string) []objects.DBApiDefinition {
+		return []objects.DBApiDefinition{{APIDefinition: &apidef.APIDefinition{APIID: name, Name: name}}}
+	}
+	units := []tyk_vcs.Unit{
+		{Name: "payments", APIs: api("pay"), Policies: []objects.Policy{{ID: "gold", Name: "Gold"}}},
+		{Name: "search", APIs: api("search")},
+	}
+	publisher := failingPublisher{fail: map[string]bool{"search": true}}
+	cmd := &cobra.Command{Use: "publish"}
+
+	sent, err := publishUnits(cmd, publisher, units)
+	if err == nil || !strings.Contains(err.Error(), "1 of the 2 products") || !strings.Contains(err.Error(), "search") {
+		t.Errorf("expected the failed product to be returned, got %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("expected the objects of the other product counted, got %v", sent)
+	}
+
+	if sent, err := publishUnits(cmd, failingPublisher{}, units); err != nil || sent != 3 {
+		t.Errorf("expected every product published, got %v %v", sent, err)
+	}
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// smokeTest publishes a copy of each of defs under a temporary listen path, waits for the
+// gateways to load them, runs the command set with --smoke-test against each copy and
+// removes them again. Nothing should be published to the live listen paths if it fails.
+func smokeTest(cmd *cobra.Command, publisher tyk_vcs.Publisher, defs []objects.DBApiDefinition) error {
+	command, _ := cmd.Flags().GetString("smoke-test")
+	if command == "" || len(defs) == 0 {
+		return nil
+	}
+
+	deleter, ok := publisher.(tyk_vcs.Deleter)
+	if !ok {
+		return fmt.Errorf("%v can't remove the temporary APIs of --smoke-test", publisher.Name())
+	}
+
+	waiter, err := startPropagation(cmd)
+	if err != nil {
+		return err
+	}
+	gwURL, _ := cmd.Flags().GetString("check-live")
+	if waiter == nil && gwURL == "" {
+		return errors.New("--smoke-test needs --wait-for-propagation or --check-live to know when the gateways loaded the copies")
+	}
+
+	prefix, _ := cmd.Flags().GetString("smoke-test-prefix")
+	timeout, _ := cmd.Flags().GetDuration("smoke-test-timeout")
+	test := &tyk_vcs.SmokeTest{Command: command, Timeout: timeout}
+
+	failed := []string{}
+	copies := []objects.DBApiDefinition{}
+	ids := []string{}
+	defer func() {
+		for _, id := range ids {
+			if err := deleter.DeleteAPI(id); err != nil {
+				fmt.Printf("--> [WARNING] Could not remove the temporary API %v: %v\n", id, err)
+			}
+		}
+		if isGateway {
+			if err := publisher.Reload(); err != nil {
+				fmt.Printf("--> [WARNING] Reload failed: %v\n", err)
+			}
+		}
+	}()
+
+	for _, d := range defs {
+		c := tyk_vcs.SmokeTestCopy(d, prefix)
+		fmt.Printf("Publishing API %v on %v for the smoke test\n", d.Name, c.Proxy.ListenPath)
+
+		id, err := publisher.Create(&c)
+		if err != nil {
+			fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+			failed = append(failed, d.Name)
+			continue
+		}
+		ids = append(ids, id)
+		copies = append(copies, c)
+	}
+
+	if isGateway {
+		if err := publisher.Reload(); err != nil {
+			return err
+		}
+	}
+	if err := waitForPropagation(waiter, len(copies) > 0); err != nil {
+		return err
+	}
+
+	loaded := copies
+	if gwURL != "" {
+		loaded = nil
+		checker := &tyk_vcs.LivenessChecker{GatewayURL: gwURL, Timeout: timeout}
+		checks, err := checker.Check(copies)
+		if err != nil {
+			return fmt.Errorf("liveness check failed: %v", err)
+		}
+		live := map[string]bool{}
+		for _, c := range checks {
+			live[c.APIID] = c.Live
+		}
+
+		for _, c := range copies {
+			// Inactive copies are not checked, the smoke test tells
+			if checked, ok := live[c.APIID]; ok && !checked {
+				fmt.Printf("--> Status: FAIL, Error:%v did not go live on %v\n", c.Proxy.ListenPath, gwURL)
+				failed = append(failed, c.Name)
+				continue
+			}
+			loaded = append(loaded, c)
+		}
+	}
+
+	for _, c := range loaded {
+		fmt.Printf("Smoke testing API %v on %v\n", c.Name, c.Proxy.ListenPath)
+		if err := test.Run(c); err != nil {
+			fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+			failed = append(failed, c.Name)
+			continue
+		}
+		fmt.Println("--> Status: OK")
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("smoke tests failed for %v, nothing was published", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// newStagger returns the data plane groups of the spec to roll out to one after the other
+// with --stagger, it is nil without the flag
+func newStagger(cmd *cobra.Command, spec *tyk_vcs.TykSourceSpec, defs []objects.DBApiDefinition) (*tyk_vcs.Stagger, error) {
+	if on, _ := cmd.Flags().GetBool("stagger"); !on {
+		return nil, nil
+	}
+	if isGateway {
+		return nil, errors.New("--stagger rolls out to the data planes of an MDCB control plane, a dashboard target is required")
+	}
+	if canary, _ := cmd.Flags().GetString("canary"); canary != "" {
+		return nil, errors.New("set either --stagger or --canary, not both")
+	}
+
+	s, err := tyk_vcs.NewStagger(spec.DataPlanes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range defs {
+		if d.APIDefinition != nil && d.APIID == "" {
+			return nil, fmt.Errorf("API %v has no API ID, it can't be rolled out group by group", d.Name)
+		}
+	}
+
+	for _, name := range s.Ungrouped(defs) {
+		fmt.Printf("--> [WARNING] API %v has none of the data plane tags, it is published with the first group and the segmented data planes don't load it\n", name)
+	}
+
+	return s, nil
+}
+
+// publishStaged creates or updates defs group by group, checking the APIs of a group are
+// live on its gateway_url before the next group gets them. APIs keep the tags they already
+// have on the target until the last group. Definitions are created the
+// first time they are published by publish, and updated afterwards.
+func publishStaged(cmd *cobra.Command, publisher tyk_vcs.Publisher, s *tyk_vcs.Stagger, defs []objects.DBApiDefinition) error {
+	timeout, _ := cmd.Flags().GetDuration("stagger-timeout")
+	pause, _ := cmd.Flags().GetDuration("stagger-pause")
+	published := map[string]bool{}
+
+	// The groups already serving an API keep it until the roll out completes
+	lister, ok := publisher.(tyk_vcs.APILister)
+	if !ok {
+		return fmt.Errorf("%v can't list the deployed APIs to stagger the publish by", publisher.Name())
+	}
+	apis, err := lister.FetchAPIs()
+	if err != nil {
+		return err
+	}
+	deployed := map[string][]string{}
+	for _, a := range apis {
+		deployed[a.APIID] = a.Tags
+	}
+
+	for n, g := range s.Groups {
+		staged := s.Stage(defs, deployed, n)
+		fmt.Printf("> Rolling out %v APIs to the data plane group %v (%v of %v)\n", len(staged), g.Tag, n+1, len(s.Groups))
+
+		failed := 0
+		for i, d := range staged {
+			if cmd.Use == "publish" && !published[d.APIID] {
+				fmt.Printf("Creating API %v: %v\n", i, d.Name)
+				id, err := publisher.Create(&d)
+				if err != nil {
+					failed++
+					fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+					continue
+				}
+				fmt.Printf("--> Status: OK, ID:%v\n", id)
+				published[d.APIID] = true
+				continue
+			}
+
+			fmt.Printf("Updating API %v: %v\n", i, d.Name)
+			if err := publisher.Update(&d); err != nil {
+				failed++
+				fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+				continue
+			}
+			fmt.Printf("--> Status: OK, ID:%v\n", d.APIID)
+			published[d.APIID] = true
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%v APIs failed on the data plane group %v, the next groups were not published", failed, g.Tag)
+		}
+
+		if g.GatewayURL != "" && len(staged) > 0 {
+			fmt.Printf("> Checking APIs are live on %v (%v)\n", g.Tag, g.GatewayURL)
+			checker := &tyk_vcs.LivenessChecker{GatewayURL: g.GatewayURL, Timeout: timeout}
+			checks, err := checker.Check(staged)
+			if err != nil {
+				return fmt.Errorf("liveness check of %v failed: %v", g.Tag, err)
+			}
+
+			notLive := 0
+			for _, c := range checks {
+				if !c.Live {
+					notLive++
+					fmt.Printf("--> Not live: %v (%v) on %v, code: %v %v\n", c.Name, c.APIID, c.ListenPath, c.StatusCode, c.Error)
+				}
+			}
+			if notLive > 0 {
+				return fmt.Errorf("%v APIs are not live on the data plane group %v, the next groups were not published", notLive, g.Tag)
+			}
+			fmt.Printf("--> All APIs are live on %v\n", g.Tag)
+		}
+
+		if pause > 0 && n < len(s.Groups)-1 {
+			fmt.Printf("> Waiting %v before the next group\n", pause)
+			time.Sleep(pause)
+		}
+	}
+
+	return nil
+}
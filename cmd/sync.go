@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
 	"github.com/spf13/cobra"
 	"os"
+	"time"
 )
 
 // syncCmd represents the sync command
@@ -21,7 +23,12 @@ var syncCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		err := processSync(cmd, args)
+		var err error
+		if addr, _ := cmd.Flags().GetString("serve"); addr != "" {
+			err = serveSync(cmd, args, addr)
+		} else {
+			err = processSync(cmd, args)
+		}
 		if err != nil {
 			fmt.Println("Error: ", err)
 			os.Exit(1)
@@ -33,13 +40,64 @@ func init() {
 	RootCmd.AddCommand(syncCmd)
 
 	syncCmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+	syncCmd.Flags().String("gateway-dir", "", "Directory of a gateway loading its APIs and policies from files, its apps/ and policies/ folders are written")
 	syncCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
 	syncCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
 	syncCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	syncCmd.Flags().String("tag", "", "Tag to check out instead of the branch (optional)")
+	syncCmd.Flags().String("commit", "", "Commit of the branch to check out instead of its tip (optional)")
+	syncCmd.Flags().String("subdir", "", "Directory of the repo holding the spec file, only its files are checked out (optional)")
+	syncCmd.Flags().Bool("submodules", false, "Also clone the submodules of the repo")
 	syncCmd.Flags().StringP("secret", "s", "", "Your API secret")
 	syncCmd.Flags().StringP("org", "o", "", "org ID override")
 	syncCmd.Flags().StringP("path", "p", "", "Source directory for definition files (optional)")
 	syncCmd.Flags().Bool("test", false, "Use test publisher, output results to stdio")
+	syncCmd.Flags().BoolP("interactive", "i", false, "Print the planned changes and ask for confirmation, or pick the objects to apply, before applying them")
+	syncCmd.Flags().Bool("override-window", false, "Apply the changes even if no deployment window of the spec file is open")
+	syncCmd.Flags().String("policy-ids", "auto", "Match policies on the dashboard by their explicit id (explicit, needs allow_explicit_policy_id) or their _id (database), auto uses _id if the dashboard policies have no explicit id")
+	syncCmd.Flags().Bool("replace-categories", false, "Clear the categories of dashboard APIs whose definitions have none, by default they keep theirs")
+	syncCmd.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+	syncCmd.Flags().StringToString("list-param", map[string]string{}, "Query parameter to send with the dashboard list calls, e.g. --list-param region=eu, overrides the list_params of the profile (repeatable)")
+	syncCmd.Flags().Int("max-apis", 0, "Most APIs the plan of the target allows, checked before the sync, overrides the limits of the profile (optional)")
+	syncCmd.Flags().Int("max-policies", 0, "Most policies the plan of the target allows, checked before the sync, overrides the limits of the profile (optional)")
+	syncCmd.Flags().Int("page-size", 0, "Fetch the dashboard lists page by page, the page_size the dashboard is configured with, overrides the page_size of the profile (optional)")
+	syncCmd.Flags().Int("max-object-size", 0, "Fail if an API or policy of the dashboard lists is larger than this many bytes of JSON, lists are read one object at a time (optional)")
+	syncCmd.Flags().String("passthrough", "auto", "Send fields unknown to tyk-sync's API definition format to the target: auto (if the target is newer), on or off")
+	syncCmd.Flags().String("check-live", "", "Gateway URL to check the published APIs are loaded and route on, results are reported as warnings (optional)")
+	syncCmd.Flags().Duration("check-live-timeout", 30*time.Second, "How long to wait for each API to go live")
+	syncCmd.Flags().String("contract-tests", "", "Gateway URL to run the contract tests of the spec against, failed tests fail the command (optional)")
+	syncCmd.Flags().Duration("contract-tests-timeout", 10*time.Second, "Timeout of each contract test request")
+	syncCmd.Flags().String("check-upstreams", "", "Before publishing, check the upstreams of the APIs are reachable from here: tcp to resolve and connect to them, head to also send them a HEAD request (optional)")
+	syncCmd.Flags().Duration("check-upstreams-timeout", 5*time.Second, "Timeout of each upstream check")
+	syncCmd.Flags().Bool("wait-for-propagation", false, "Wait until all gateways of the dashboard loaded the changes, needs the dashboard admin secret")
+	syncCmd.Flags().Duration("propagation-timeout", 2*time.Minute, "How long to wait for the gateways to load the changes")
+	syncCmd.Flags().String("admin-secret", "", "The admin_secret of the dashboard, for --wait-for-propagation")
+	syncCmd.Flags().String("from-commit", "", "Only sync the objects whose files changed since this commit, or last for the commit of the last successful sync in --history (optional)")
+	syncCmd.Flags().String("history", "", "File to record every change in, with the commit it was published from, see the history command (optional)")
+	syncCmd.Flags().Bool("rollback", false, "Sync the commit the target was synced to before its last successful sync in --history")
+	syncCmd.Flags().String("to-commit", "", "Last commit of the range for --from-commit, defaults to the checked out commit")
+	syncCmd.Flags().String("profile", "", "Target profile from the spec file to apply to the published objects (optional)")
+	syncCmd.Flags().StringSlice("coprocess-drivers", []string{}, "Plugin drivers enabled on the target gateways, otto if they enable the JSVM, used to warn about unsupported plugins (optional)")
 	syncCmd.Flags().StringSlice("policies",[]string{},"Specific Policies ids to sync")
 	syncCmd.Flags().StringSlice("apis",[]string{},"Specific Apis ids to sync")
+	syncCmd.Flags().Bool("force-delete", false, "Apply the sync even if it deletes more objects than the delete thresholds allow")
+	syncCmd.Flags().Int("max-deletes", 10, "Number of objects a sync may delete without --force-delete or confirmation (0 to disable)")
+	syncCmd.Flags().Bool("deactivate-removed", false, "Deactivate the APIs that are no longer in git instead of deleting them, they keep their IDs and keys")
+	syncCmd.Flags().StringSlice("push-to", []string{}, "Also push the synced objects to this secondary target, dashboard+https://:secret@host, gateway+http://:secret@host or a target registered with tyk_vcs.RegisterTarget (repeatable)")
+	syncCmd.Flags().StringSlice("notify-url", []string{}, "URL to POST the sync report (JSON) to when the sync finishes (repeatable)")
+	syncCmd.Flags().StringSlice("notify-slack", []string{}, "Slack incoming webhook URL to post a summary of the sync to (repeatable)")
+	syncCmd.Flags().String("notify-on", "always", "When to send notifications: always, success or failure")
+	syncCmd.Flags().Float64("max-delete-percent", 50, "Share of the existing objects (in percent) a sync may delete without --force-delete or confirmation (0 to disable)")
+	syncCmd.Flags().String("commit-status", "", "Post the result as a commit status to github or gitlab, using the CI environment (optional)")
+	syncCmd.Flags().String("commit-status-context", "tyk-sync/sync", "Name of the commit status")
+	syncCmd.Flags().String("pr-comment", "", "Post the summary of the sync as a comment on the pull request to github or gitlab, using the CI environment (optional)")
+	syncCmd.Flags().String("serve", "", "Run as a daemon listening on this address (e.g. :8080): sync, then sync again on every push webhook to the branch (optional)")
+	syncCmd.Flags().String("webhook-secret", "", "Secret the webhooks of --serve must be signed with (GitHub, Gitea and Bitbucket Server), or carry as their token (GitLab) or basic auth password (Azure DevOps), or set TYKGIT_WEBHOOK_SECRET")
+	syncCmd.Flags().Duration("shutdown-timeout", 5*time.Minute, "How long a daemon waits for the running sync to finish on SIGTERM")
+	syncCmd.Flags().String("leader-election", "", "Elect the replica of a daemon that syncs with a kubernetes lease or a redis key, the others stand by (optional)")
+	syncCmd.Flags().String("leader-lease", "tyk-sync", "Name of the Kubernetes lease or Redis key replicas are elected with")
+	syncCmd.Flags().String("leader-namespace", "", "Namespace of the Kubernetes lease, defaults to the namespace of the pod")
+	syncCmd.Flags().String("leader-id", "", "Identity of the replica in the leader election, defaults to the hostname")
+	syncCmd.Flags().Duration("leader-ttl", tyk_vcs.DefaultLeaseTTL, "How long the leader holds the lease without renewing it")
+	syncCmd.Flags().String("redis-url", "", "Redis to elect the leader with, redis://[:password@]host[:port][/db] or rediss:// (or set TYKGIT_REDIS_URL)")
 }
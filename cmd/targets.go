@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/TykTechnologies/tyk-sync/cli-publisher"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// targetSecret is the password of the user info of a target URL, or the env variable, the
+// URL without the user info is what the target is reached at
+func targetSecret(u *url.URL, env string) (string, string, error) {
+	secret := os.Getenv(env)
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			secret = pass
+		}
+	}
+	if secret == "" {
+		return "", "", fmt.Errorf("set the secret of %v as the password of its URL or with %v", u.Host, env)
+	}
+
+	copied := *u
+	copied.User = nil
+	return copied.String(), secret, nil
+}
+
+func init() {
+	// dashboard+https://:secret@dashboard:3000 and gateway+http://:secret@gateway:8080
+	tyk_vcs.RegisterTarget("dashboard", func(u *url.URL) (tyk_vcs.Target, error) {
+		host, secret, err := targetSecret(u, "TYKGIT_DB_SECRET")
+		if err != nil {
+			return nil, err
+		}
+		return &tyk_vcs.PublisherTarget{Publisher: &cli_publisher.DashboardPublisher{Secret: secret, Hostname: host}}, nil
+	})
+	tyk_vcs.RegisterTarget("gateway", func(u *url.URL) (tyk_vcs.Target, error) {
+		host, secret, err := targetSecret(u, "TYKGIT_GW_SECRET")
+		if err != nil {
+			return nil, err
+		}
+		return &tyk_vcs.PublisherTarget{Publisher: &cli_publisher.GatewayPublisher{Secret: secret, Hostname: host}, Gateway: true}, nil
+	})
+	// files:///etc/tyk, the directory of a gateway loading its apps/ and policies/ from files
+	tyk_vcs.RegisterTarget("files", func(u *url.URL) (tyk_vcs.Target, error) {
+		dir := u.Host + u.Path
+		if u.Opaque != "" {
+			dir = u.Opaque
+		}
+		if dir == "" {
+			return nil, errors.New("set the directory of the gateway, e.g. files:///etc/tyk")
+		}
+		return &tyk_vcs.PublisherTarget{Publisher: &cli_publisher.FilesPublisher{Dir: dir}}, nil
+	})
+}
+
+// openTargets opens the secondary targets of --push-to, before anything is published so
+// that a mistyped URL fails the run early
+func openTargets(cmd *cobra.Command) ([]tyk_vcs.Target, error) {
+	urls, _ := cmd.Flags().GetStringSlice("push-to")
+	opened := []tyk_vcs.Target{}
+	for _, u := range urls {
+		t, err := tyk_vcs.NewTarget(u)
+		if err != nil {
+			return nil, fmt.Errorf("--push-to %v: %v", u, err)
+		}
+		opened = append(opened, t)
+	}
+
+	return opened, nil
+}
+
+// configureTargets sets up the publishers of the secondary targets like the primary one,
+// before they are first used: their plans are checked by their own planCheck, so the delete
+// guard, protect, scope, deployment windows and --interactive apply to them too
+func configureTargets(cmd *cobra.Command, opened []tyk_vcs.Target) error {
+	deactivateRemoved, _ := cmd.Flags().GetBool("deactivate-removed")
+	for _, t := range opened {
+		pt, ok := t.(*tyk_vcs.PublisherTarget)
+		if !ok {
+			continue
+		}
+
+		check, err := planCheck(cmd)
+		if err != nil {
+			return err
+		}
+
+		switch p := pt.Publisher.(type) {
+		case *cli_publisher.DashboardPublisher:
+			p.PlanCheck = check
+			p.DeactivateRemoved = deactivateRemoved
+		case *cli_publisher.GatewayPublisher:
+			p.PlanCheck = check
+			p.DeactivateRemoved = deactivateRemoved
+		case *cli_publisher.FilesPublisher:
+			p.PlanCheck = check
+			p.DeactivateRemoved = deactivateRemoved
+		}
+		gatePlans(pt)
+	}
+
+	return nil
+}
+
+// gatePlans has the policies and the APIs of a target planned and checked before either is
+// synced, see tyk_vcs.PlanGate. It must be called before the publisher is first used.
+func gatePlans(t *tyk_vcs.PublisherTarget) {
+	if t.Gateway {
+		return
+	}
+
+	switch p := t.Publisher.(type) {
+	case *cli_publisher.DashboardPublisher:
+		if p.PlanCheck != nil {
+			t.Gate = tyk_vcs.NewPlanGate(p.PlanCheck)
+			p.PlanCheck = t.Gate.Check
+		}
+	case *cli_publisher.FilesPublisher:
+		if p.PlanCheck != nil {
+			t.Gate = tyk_vcs.NewPlanGate(p.PlanCheck)
+			p.PlanCheck = t.Gate.Check
+		}
+	}
+}
+
+// pushTargets pushes the synced objects to the secondary targets, a failing target doesn't
+// stop the others but fails the run. Dashboards, gateways and gateway directories are synced
+// with the same checks as the primary target, see applySync; other registered targets are
+// handed the objects as they are.
+func pushTargets(cmd *cobra.Command, requires *tyk_vcs.RequiresInfo, opened []tyk_vcs.Target, defs []objects.DBApiDefinition, pols []objects.Policy) error {
+	failed := 0
+	for _, t := range opened {
+		fmt.Printf("> Pushing to %v\n", t.Name())
+
+		var err error
+		if pt, ok := t.(*tyk_vcs.PublisherTarget); ok {
+			err = applySync(cmd, pt, requires, defs, pols)
+		} else {
+			err = t.Push(defs, pols)
+		}
+		if err != nil {
+			failed++
+			fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+			continue
+		}
+		fmt.Println("--> Status: OK")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v --push-to targets failed", failed, len(opened))
+	}
+	return nil
+}
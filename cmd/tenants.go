@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/cli-publisher"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// tenantPublisher publishes to the org of a tenant, with the secret of one of its users
+func tenantPublisher(cmd *cobra.Command, t tyk_vcs.TenantInfo, report *tyk_vcs.SyncReport) (tyk_vcs.Publisher, error) {
+	if mock, _ := cmd.Flags().GetBool("test"); mock {
+		return cli_publisher.MockPublisher{}, nil
+	}
+
+	dbString, _ := cmd.Flags().GetString("dashboard")
+	if dbString == "" {
+		return nil, errors.New("tenants are dashboard orgs, a dashboard target is required")
+	}
+
+	secret := ""
+	if t.SecretEnv != "" {
+		secret = os.Getenv(t.SecretEnv)
+		if secret == "" {
+			return nil, fmt.Errorf("please set %v to the secret of a dashboard user of org %v", t.SecretEnv, t.OrgID)
+		}
+	} else if secret, _ = cmd.Flags().GetString("secret"); secret == "" {
+		secret = os.Getenv("TYKGIT_DB_SECRET")
+	}
+
+	check, err := planCheck(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	cloud, _ := cmd.Flags().GetBool("cloud")
+	replaceCategories, _ := cmd.Flags().GetBool("replace-categories")
+	return &cli_publisher.DashboardPublisher{
+		Secret:            secret,
+		Hostname:          dbString,
+		OrgOverride:       t.OrgID,
+		Cloud:             cloud,
+		PlanCheck:         report.Record(check),
+		ListOptions:       listOptions,
+		Progress:          report.Track(newSyncProgress()),
+		Hooks:             syncHooks(),
+		ReplaceCategories: replaceCategories,
+	}, nil
+}
+
+func syncTenant(cmd *cobra.Command, getter tyk_vcs.Getter, t tyk_vcs.TenantInfo, report *tyk_vcs.SyncReport) error {
+	sub, err := tyk_vcs.NewSubGetter(getter, t.Path)
+	if err != nil {
+		return err
+	}
+
+	defs, pols, spec, err := doGetDataFrom(cmd, sub)
+	if err != nil {
+		return err
+	}
+	syncProtect = spec.Protect
+	printCoprocessWarnings(cmd, defs)
+	if err := checkGuardrails(spec, defs, pols); err != nil {
+		return err
+	}
+	if err := checkUpstreams(cmd, defs); err != nil {
+		return err
+	}
+
+	publisher, err := tenantPublisher(cmd, t, report)
+	if err != nil {
+		return err
+	}
+
+	target := &tyk_vcs.PublisherTarget{Publisher: publisher, Gateway: isGateway}
+	gatePlans(target)
+	if err := checkTargetLimits(cmd, target, nil, defs, pols); err != nil {
+		return err
+	}
+	if err := applySync(cmd, target, spec.Requires, defs, pols); err != nil {
+		return err
+	}
+
+	if err := checkLive(cmd, defs, report); err != nil {
+		return err
+	}
+
+	return runContractTests(cmd, sub, spec, defs, report)
+}
+
+// syncTenants syncs every tenant directory to its org, a failing tenant doesn't stop the others
+func syncTenants(cmd *cobra.Command, getter tyk_vcs.Getter, tenants []tyk_vcs.TenantInfo) error {
+	failed := []string{}
+	for _, t := range tenants {
+		fmt.Printf("> Tenant %v (org %v)\n", t.Path, t.OrgID)
+
+		report := tyk_vcs.NewSyncReport(fmt.Sprintf("%v (org %v)", syncReport.Target, t.OrgID))
+		err := syncTenant(cmd, getter, t, report)
+		report.Finish(err)
+		syncReport.Tenants = append(syncReport.Tenants, report)
+
+		if err != nil {
+			failed = append(failed, t.Path)
+			fmt.Printf("--> Status: FAIL, Error:%v\n", err)
+			continue
+		}
+		fmt.Println("--> Status: OK")
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%v of %v tenants failed: %v", len(failed), len(tenants), strings.Join(failed, ", "))
+	}
+
+	return nil
+}
@@ -17,6 +17,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 	"github.com/spf13/cobra"
 )
 
@@ -44,12 +45,56 @@ var updateCmd = &cobra.Command{
 func init() {
 	RootCmd.AddCommand(updateCmd)
 	updateCmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+	updateCmd.Flags().String("gateway-dir", "", "Directory of a gateway loading its APIs and policies from files, its apps/ and policies/ folders are written")
 	updateCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
 	updateCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
 	updateCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	updateCmd.Flags().String("tag", "", "Tag to check out instead of the branch (optional)")
+	updateCmd.Flags().String("commit", "", "Commit of the branch to check out instead of its tip (optional)")
+	updateCmd.Flags().String("subdir", "", "Directory of the repo holding the spec file, only its files are checked out (optional)")
+	updateCmd.Flags().Bool("submodules", false, "Also clone the submodules of the repo")
 	updateCmd.Flags().StringP("secret", "s", "", "Your API secret")
+	updateCmd.Flags().StringP("org", "o", "", "org ID override")
 	updateCmd.Flags().StringP("path", "p", "", "Source directory for definition files (optional)")
 	updateCmd.Flags().Bool("test", false, "Use test publisher, output results to stdio")
+	updateCmd.Flags().BoolP("interactive", "i", false, "Print the planned changes and ask for confirmation, or pick the objects to apply, before applying them")
+	updateCmd.Flags().Bool("override-window", false, "Apply the changes even if no deployment window of the spec file is open")
+	updateCmd.Flags().String("canary", "", "Publish the APIs with this gateway segment tag only, then promote them to their own tags on confirmation (optional)")
+	updateCmd.Flags().Bool("stagger", false, "Roll the APIs out to the MDCB data plane groups of the spec (data_planes) one group at a time")
+	updateCmd.Flags().Duration("stagger-timeout", 2*time.Minute, "How long to wait for the APIs of a group to go live on its gateway_url")
+	updateCmd.Flags().Duration("stagger-pause", 0, "Pause between the data plane groups (optional)")
+	updateCmd.Flags().String("smoke-test", "", "Smoke test command to run against a copy of each API published under --smoke-test-prefix first, nothing is published if it fails. Needs --wait-for-propagation or --check-live (optional)")
+	updateCmd.Flags().String("smoke-test-prefix", "/smoke-test", "Listen path prefix of the copies tested with --smoke-test")
+	updateCmd.Flags().Duration("smoke-test-timeout", 5*time.Minute, "How long the --smoke-test command may run for each API")
+	updateCmd.Flags().String("policy-ids", "auto", "Match policies on the dashboard by their explicit id (explicit, needs allow_explicit_policy_id) or their _id (database), auto uses _id if the dashboard policies have no explicit id")
+	updateCmd.Flags().Bool("replace-categories", false, "Clear the categories of dashboard APIs whose definitions have none, by default they keep theirs")
+	updateCmd.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+	updateCmd.Flags().StringToString("list-param", map[string]string{}, "Query parameter to send with the dashboard list calls, e.g. --list-param region=eu, overrides the list_params of the profile (repeatable)")
+	updateCmd.Flags().Int("max-apis", 0, "Most APIs the plan of the target allows, checked before publishing, overrides the limits of the profile (optional)")
+	updateCmd.Flags().Int("max-policies", 0, "Most policies the plan of the target allows, checked before publishing, overrides the limits of the profile (optional)")
+	updateCmd.Flags().Int("page-size", 0, "Fetch the dashboard lists page by page, the page_size the dashboard is configured with, overrides the page_size of the profile (optional)")
+	updateCmd.Flags().Int("max-object-size", 0, "Fail if an API or policy of the dashboard lists is larger than this many bytes of JSON, lists are read one object at a time (optional)")
+	updateCmd.Flags().String("passthrough", "auto", "Send fields unknown to tyk-sync's API definition format to the target: auto (if the target is newer), on or off")
+	updateCmd.Flags().String("check-live", "", "Gateway URL to check the published APIs are loaded and route on, results are reported as warnings (optional)")
+	updateCmd.Flags().Duration("check-live-timeout", 30*time.Second, "How long to wait for each API to go live")
+	updateCmd.Flags().String("contract-tests", "", "Gateway URL to run the contract tests of the spec against, failed tests fail the command (optional)")
+	updateCmd.Flags().Duration("contract-tests-timeout", 10*time.Second, "Timeout of each contract test request")
+	updateCmd.Flags().StringSlice("notify-url", []string{}, "URL to POST the update report (JSON) to when the update finishes (repeatable)")
+	updateCmd.Flags().StringSlice("notify-slack", []string{}, "Slack incoming webhook URL to post a summary of the update to (repeatable)")
+	updateCmd.Flags().String("notify-on", "always", "When to send notifications: always, success or failure")
+	updateCmd.Flags().String("commit-status", "", "Post the result as a commit status to github or gitlab, using the CI environment (optional)")
+	updateCmd.Flags().String("commit-status-context", "tyk-sync/update", "Name of the commit status")
+	updateCmd.Flags().String("pr-comment", "", "Post the summary of the update as a comment on the pull request to github or gitlab, using the CI environment (optional)")
+	updateCmd.Flags().String("check-upstreams", "", "Before publishing, check the upstreams of the APIs are reachable from here: tcp to resolve and connect to them, head to also send them a HEAD request (optional)")
+	updateCmd.Flags().Duration("check-upstreams-timeout", 5*time.Second, "Timeout of each upstream check")
+	updateCmd.Flags().Bool("wait-for-propagation", false, "Wait until all gateways of the dashboard loaded the changes, needs the dashboard admin secret")
+	updateCmd.Flags().Duration("propagation-timeout", 2*time.Minute, "How long to wait for the gateways to load the changes")
+	updateCmd.Flags().String("admin-secret", "", "The admin_secret of the dashboard, for --wait-for-propagation")
+	updateCmd.Flags().String("from-commit", "", "Only update the objects whose files changed since this commit, or last for the commit of the last successful sync in --history (optional)")
+	updateCmd.Flags().String("history", "", "File to record every change in, with the commit it was published from, see the history command (optional)")
+	updateCmd.Flags().String("to-commit", "", "Last commit of the range for --from-commit, defaults to the checked out commit")
+	updateCmd.Flags().String("profile", "", "Target profile from the spec file to apply to the published objects (optional)")
+	updateCmd.Flags().StringSlice("coprocess-drivers", []string{}, "Plugin drivers enabled on the target gateways, otto if they enable the JSVM, used to warn about unsupported plugins (optional)")
 	updateCmd.Flags().StringSlice("policies",[]string{},"Specific Policies ids to update")
 	updateCmd.Flags().StringSlice("apis",[]string{},"Specific Apis ids to update")
 }
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// checkUpstreams checks the upstreams of the APIs are reachable from here before they are
+// published, with the check set with --check-upstreams. Unreachable upstreams fail the run.
+func checkUpstreams(cmd *cobra.Command, defs []objects.DBApiDefinition) error {
+	mode, _ := cmd.Flags().GetString("check-upstreams")
+	if mode == "" {
+		return nil
+	}
+	timeout, _ := cmd.Flags().GetDuration("check-upstreams-timeout")
+
+	fmt.Println("> Checking the upstreams of the APIs are reachable")
+	checker := &tyk_vcs.UpstreamChecker{Mode: mode, Timeout: timeout, Workers: 8}
+	checks, err := checker.Check(defs)
+	if err != nil {
+		return err
+	}
+
+	unreachable := 0
+	for _, c := range checks {
+		switch {
+		case !c.Reachable:
+			unreachable++
+			fmt.Printf("--> [WARNING] Unreachable: %v (%v) upstream %v, Error:%v\n", c.Name, c.APIID, c.URL, c.Error)
+		case c.StatusCode != 0:
+			fmt.Printf("--> Reachable: %v (%v) upstream %v, code: %v\n", c.Name, c.APIID, c.URL, c.StatusCode)
+		default:
+			fmt.Printf("--> Reachable: %v (%v) upstream %v\n", c.Name, c.APIID, c.URL)
+		}
+	}
+
+	if unreachable > 0 {
+		return fmt.Errorf("%v of %v upstreams are unreachable, set %v in the config_data of APIs whose upstreams only the gateways reach", unreachable, len(checks), tyk_vcs.UpstreamCheckSkip)
+	}
+
+	return nil
+}
@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-diff"
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Fields the target assigns itself
+	apiVerifyIgnores    = []string{"/id", "/org_id"}
+	policyVerifyIgnores = []string{"/_id", "/org_id", "/date_created", "/last_updated"}
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:     "verify",
+	Aliases: []string{"diff"},
+	Short:   "Verify that a gateway or dashboard stores the API definitions and policies as published",
+	Long: `Verify fetches every API and policy of a Git repo or file system back from the
+	gateway or dashboard it was published to, normalises both sides and reports the fields
+	the target changed or dropped. Run it after a publish to catch schema drift between the
+	API definition format tyk-sync was built with and the version of the target.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		verificationError := verifyArguments(cmd)
+		if verificationError != nil {
+			fmt.Println(verificationError)
+			os.Exit(1)
+		}
+
+		err := processVerify(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+const (
+	diffFormatText  = "text"
+	diffFormatJSON  = "json"
+	diffFormatPatch = "json-patch"
+)
+
+// verifyObject compares what was published with what the target returned, it returns the
+// differences and the number of fields that were changed or dropped. The differences are
+// printed unless quiet, in color if colored.
+func verifyObject(kind, name string, expected interface{}, actual map[string]interface{}, ignore []string, quiet, colored bool) ([]tyk_diff.Change, int, error) {
+	exp, err := tyk_diff.Normalize(expected, ignore)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	act, err := tyk_diff.Normalize(actual, ignore)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	changes := tyk_diff.Compare(exp, act)
+	drift := 0
+	for _, c := range changes {
+		if c.Kind != tyk_diff.Added {
+			drift++
+		}
+	}
+
+	if quiet {
+		return changes, drift, nil
+	}
+
+	switch {
+	case drift > 0:
+		fmt.Printf("--> [WARNING] %v %v: %v fields changed or dropped by the target\n", kind, name, drift)
+	case len(changes) > 0:
+		fmt.Printf("--> %v %v: OK, %v fields unknown to tyk-sync\n", kind, name, len(changes))
+	default:
+		fmt.Printf("--> %v %v: OK\n", kind, name)
+	}
+
+	for _, c := range changes {
+		// Added fields are only in the stored object
+		doc := exp
+		if c.Kind == tyk_diff.Added {
+			doc = act
+		}
+		fmt.Print(c.Pretty(tyk_diff.DottedPath(c.Path, doc), "    ", colored))
+	}
+
+	return changes, drift, nil
+}
+
+// objectDiff is the machine readable differences of an object in format
+func objectDiff(format, kind, name, id string, changes []tyk_diff.Change, err error) tyk_diff.ObjectDiff {
+	d := tyk_diff.ObjectDiff{Kind: kind, Name: name, ID: id}
+	switch {
+	case err != nil:
+		d.Error = err.Error()
+	case format == diffFormatPatch:
+		d.Patch = tyk_diff.Patch(changes)
+	default:
+		d.Changes = changes
+	}
+
+	return d
+}
+
+// writeDiffs writes the differences as JSON to path, - is stdout
+func writeDiffs(path string, diffs []tyk_diff.ObjectDiff) error {
+	out, err := json.MarshalIndent(diffs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if path == "-" {
+		fmt.Println(string(out))
+		return nil
+	}
+
+	return ioutil.WriteFile(path, append(out, '\n'), 0644)
+}
+
+func processVerify(cmd *cobra.Command, args []string) (err error) {
+	var diffs []tyk_diff.ObjectDiff
+	status, err := newCommitStatus(cmd)
+	if err != nil {
+		return err
+	}
+	comment, err := newPRComment(cmd)
+	if err != nil {
+		return err
+	}
+	if comment != nil {
+		defer func() {
+			body := tyk_vcs.DiffMarkdown(targetURL(cmd), diffs)
+			if err != nil && len(diffs) == 0 {
+				body = fmt.Sprintf("### Verifying %v failed\n\n```\n%v\n```\n", targetURL(cmd), err)
+			}
+
+			if cErr := comment.Post(body); cErr != nil {
+				fmt.Printf("--> [WARNING] Pull request comment failed: %v\n", cErr)
+			}
+		}()
+	}
+	if status != nil {
+		defer func() {
+			description := "All objects are stored as published"
+			if err != nil {
+				description = err.Error()
+			}
+
+			if sErr := status.Post(err == nil, description); sErr != nil {
+				fmt.Printf("--> [WARNING] Commit status failed: %v\n", sErr)
+			}
+		}()
+	}
+
+	format, _ := cmd.Flags().GetString("format")
+	if format != diffFormatText && format != diffFormatJSON && format != diffFormatPatch {
+		return fmt.Errorf("unknown format %v, must be %v, %v or %v", format, diffFormatText, diffFormatJSON, diffFormatPatch)
+	}
+	quiet := format != diffFormatText
+	colored, err := useColor(cmd)
+	if err != nil {
+		return err
+	}
+	diffs = []tyk_diff.ObjectDiff{}
+
+	defs, pols, spec, err := doGetData(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	publisher, err := getPublisher(cmd, args)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Using publisher: %v\n", publisher.Name())
+
+	fetcher, ok := publisher.(tyk_vcs.Fetcher)
+	if !ok {
+		return errors.New("the publisher can not fetch objects back")
+	}
+
+	extra, _ := cmd.Flags().GetStringSlice("ignore")
+	extra = append(extra, spec.Ignore...)
+
+	// Targets that can list the objects are read with one call per kind
+	var apiRaws, polRaws []map[string]interface{}
+	batch, batched := publisher.(tyk_vcs.BatchFetcher)
+	if batched {
+		if apiRaws, err = batch.FetchAPIsRaw(defs); err != nil {
+			return err
+		}
+		if !isGateway {
+			if polRaws, err = batch.FetchPoliciesRaw(pols); err != nil {
+				return err
+			}
+		}
+	}
+
+	drift := 0
+	failed := 0
+	for i, d := range defs {
+		actual, err := fetchVerified(batched, apiRaws, i, func() (map[string]interface{}, error) { return fetcher.FetchAPIRaw(&d) })
+		if err != nil {
+			failed++
+			fmt.Printf("--> [WARNING] API %v could not be fetched: %v\n", d.Name, err)
+			diffs = append(diffs, objectDiff(format, "API", d.Name, d.APIID, nil, err))
+			continue
+		}
+
+		// Fields unknown to tyk-sync are only expected back if they were sent
+		expected, err := d.DefinitionPayload()
+		if err != nil {
+			return err
+		}
+
+		changes, n, err := verifyObject("API", d.Name, expected, actual, append(extra, apiVerifyIgnores...), quiet, colored)
+		if err != nil {
+			return err
+		}
+		drift += n
+		if len(changes) > 0 {
+			diffs = append(diffs, objectDiff(format, "API", d.Name, d.APIID, changes, nil))
+		}
+	}
+
+	if !isGateway {
+		for i, p := range pols {
+			actual, err := fetchVerified(batched, polRaws, i, func() (map[string]interface{}, error) { return fetcher.FetchPolicyRaw(&p) })
+			if err != nil {
+				failed++
+				fmt.Printf("--> [WARNING] Policy %v could not be fetched: %v\n", p.Name, err)
+				diffs = append(diffs, objectDiff(format, "Policy", p.Name, p.ID, nil, err))
+				continue
+			}
+
+			changes, n, err := verifyObject("Policy", p.Name, p, actual, append(extra, policyVerifyIgnores...), quiet, colored)
+			if err != nil {
+				return err
+			}
+			drift += n
+			if len(changes) > 0 {
+				diffs = append(diffs, objectDiff(format, "Policy", p.Name, p.ID, changes, nil))
+			}
+		}
+	}
+
+	if quiet {
+		output, _ := cmd.Flags().GetString("output")
+		if err := writeDiffs(output, diffs); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 || drift > 0 {
+		return fmt.Errorf("%v objects missing, %v fields changed or dropped by the target", failed, drift)
+	}
+
+	fmt.Println("Done")
+	return nil
+}
+
+// fetchVerified returns object i of the batch read from the target, or fetches it on its own
+// when the target can't be read in batches
+func fetchVerified(batched bool, raws []map[string]interface{}, i int, fetch func() (map[string]interface{}, error)) (map[string]interface{}, error) {
+	if !batched {
+		return fetch()
+	}
+	if raws[i] == nil {
+		return nil, errors.New("not found on the target")
+	}
+	return raws[i], nil
+}
+
+func init() {
+	RootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+	verifyCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	verifyCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
+	verifyCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	verifyCmd.Flags().String("tag", "", "Tag to check out instead of the branch (optional)")
+	verifyCmd.Flags().String("commit", "", "Commit of the branch to check out instead of its tip (optional)")
+	verifyCmd.Flags().String("subdir", "", "Directory of the repo holding the spec file, only its files are checked out (optional)")
+	verifyCmd.Flags().Bool("submodules", false, "Also clone the submodules of the repo")
+	verifyCmd.Flags().StringP("secret", "s", "", "Your API secret")
+	verifyCmd.Flags().StringP("org", "o", "", "org ID override")
+	verifyCmd.Flags().StringP("path", "p", "", "Source directory for definition files (optional)")
+	verifyCmd.Flags().Bool("test", false, "Use test publisher, output results to stdio")
+	verifyCmd.Flags().Bool("cloud", false, "Target is a Tyk Cloud dashboard (detected from the URL if not set)")
+	verifyCmd.Flags().String("profile", "", "Target profile from the spec file to apply to the published objects (optional)")
+	verifyCmd.Flags().String("format", diffFormatText, "Format of the differences: text, json for the changed fields of every object or json-patch for the RFC 6902 patch turning the stored object into the published one")
+	verifyCmd.Flags().String("color", colorAuto, "Color the text differences: auto when the output is a terminal, always or never")
+	verifyCmd.Flags().String("output", "-", "File to write the json or json-patch differences to, - is stdout")
+	verifyCmd.Flags().StringSlice("ignore", []string{}, "Rules of fields to leave out of the comparison, added to the ignore rules of the spec file: JSON pointers or paths such as version_data.versions.*.expires, or tags[] to ignore the order of the tags")
+	verifyCmd.Flags().StringSlice("policies", []string{}, "Specific Policies ids to verify")
+	verifyCmd.Flags().StringSlice("apis", []string{}, "Specific Apis ids to verify")
+	verifyCmd.Flags().String("commit-status", "", "Post the result as a commit status to github or gitlab, using the CI environment (optional)")
+	verifyCmd.Flags().String("commit-status-context", "tyk-sync/verify", "Name of the commit status")
+	verifyCmd.Flags().String("pr-comment", "", "Post the differences as a comment on the pull request to github or gitlab, using the CI environment (optional)")
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-vcs"
+	"github.com/spf13/cobra"
+)
+
+// versionCmd represents the version command
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Show the version, commit and build date of this binary",
+	Long: `Version prints the release this binary was built from, with its commit, the date of that
+		commit, the Go version and the platform, to paste in bug reports. Builds without
+		a release version report dev.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := processVersion(cmd)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func processVersion(cmd *cobra.Command) error {
+	info := tyk_vcs.Version()
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		out, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	fmt.Printf("tyk-sync %v\n", info.Version)
+	if info.Commit != "" {
+		fmt.Printf("Commit:   %v\n", info.Commit)
+	}
+	if info.Date != "" {
+		fmt.Printf("Date:     %v\n", info.Date)
+	}
+	fmt.Printf("Go:       %v\n", info.GoVersion)
+	fmt.Printf("Platform: %v\n", info.Platform)
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(versionCmd)
+
+	versionCmd.Flags().Bool("json", false, "Print the build information as JSON")
+}
@@ -0,0 +1,591 @@
+// Package testserver is an in-memory stand-in for the Tyk Dashboard API, enough of it for
+// tyk-sync to sync, dump and verify APIs, policies and certificates against, so end-to-end
+// tests run without a dashboard, MongoDB or Docker.
+//
+// It behaves like a dashboard where tyk-sync depends on it: created APIs get a new API ID,
+// which only an update sets back, and a slug from their name if they have none, policies
+// lose their explicit ID unless SetExplicitPolicyIDs turned it on, listen paths and slugs must be
+// unique, and every request needs the secret.
+package testserver
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	uuid "github.com/satori/go.uuid"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// DefaultSecret is the API key of the user of a server created without one
+	DefaultSecret = "testserver-secret"
+	// DefaultOrgID is the org of that user
+	DefaultOrgID = "5e9d9544a1dcd60001d0ed20"
+	// Version is the dashboard version the server reports
+	Version = "v2.9.4"
+)
+
+// Server is a dashboard API served over HTTP on a local port, it is safe for concurrent use
+type Server struct {
+	// URL is the base URL of the dashboard API, e.g. http://127.0.0.1:41235
+	URL string
+	// Secret is the API key requests must send in the Authorization header
+	Secret string
+	// OrgID is the org of the user of the secret, and of the objects it creates
+	OrgID string
+
+	srv *httptest.Server
+	mu  sync.Mutex
+	// explicitPolicyIDs and withCategories are set by SetExplicitPolicyIDs and SetCategories
+	explicitPolicyIDs bool
+	withCategories    bool
+	// apis and policies are stored as the JSON they were sent as, keyed by database ID, so
+	// fields tyk-sync doesn't know survive like on a real dashboard
+	apis     map[string]map[string]interface{}
+	policies map[string]map[string]interface{}
+	certs    map[string]*objects.CertificateMeta
+	certPEM  map[string][]byte
+}
+
+// New starts a server with the default secret and org, Close stops it
+func New() *Server {
+	return NewWithSecret(DefaultSecret, DefaultOrgID)
+}
+
+// NewWithSecret starts a server accepting secret, for the user of orgID
+func NewWithSecret(secret, orgID string) *Server {
+	s := &Server{
+		Secret:   secret,
+		OrgID:    orgID,
+		apis:     map[string]map[string]interface{}{},
+		policies: map[string]map[string]interface{}{},
+		certs:    map[string]*objects.CertificateMeta{},
+		certPEM:  map[string][]byte{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", s.hello)
+	mux.HandleFunc("/api/users", s.authorised(s.users))
+	mux.HandleFunc("/api/apis", s.authorised(s.apiList))
+	mux.HandleFunc("/api/apis/", s.authorised(s.api))
+	mux.HandleFunc("/api/portal/policies", s.authorised(s.policyList))
+	mux.HandleFunc("/api/portal/policies/", s.authorised(s.policy))
+	mux.HandleFunc("/api/certs", s.authorised(s.certList))
+	mux.HandleFunc("/api/certs/", s.authorised(s.cert))
+
+	s.srv = httptest.NewServer(mux)
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// status is the body of the dashboard's responses to writes
+type status struct {
+	Status  string
+	Message string
+	Meta    interface{}
+}
+
+func reply(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+func replyError(w http.ResponseWriter, code int, message string) {
+	reply(w, code, status{Status: "Error", Message: message})
+}
+
+func (s *Server) authorised(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != s.Secret {
+			replyError(w, http.StatusUnauthorized, "Not authorised")
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		h(w, r)
+	}
+}
+
+// idFromPath returns the ID following prefix in the request path, "" if there is none
+func idFromPath(r *http.Request, prefix string) string {
+	return strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+}
+
+func (s *Server) hello(w http.ResponseWriter, r *http.Request) {
+	reply(w, http.StatusOK, objects.HealthCheck{Status: "ok", Version: Version})
+}
+
+func (s *Server) users(w http.ResponseWriter, r *http.Request) {
+	reply(w, http.StatusOK, objects.UsersResponse{Users: []objects.User{{
+		ID:           "5e9d9544a1dcd60001d0ed21",
+		EmailAddress: "testserver@example.com",
+		OrgID:        s.OrgID,
+		AccessKey:    s.Secret,
+	}}})
+}
+
+// decode reads the JSON body of a request into a map
+func decode(r *http.Request) (map[string]interface{}, error) {
+	raw, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("Malformed request body: %v", err)
+	}
+
+	return doc, nil
+}
+
+// definition is the api_definition of a stored API
+func definition(doc map[string]interface{}) map[string]interface{} {
+	def, _ := doc["api_definition"].(map[string]interface{})
+	if def == nil {
+		def = map[string]interface{}{}
+		doc["api_definition"] = def
+	}
+	return def
+}
+
+func str(doc map[string]interface{}, key string) string {
+	v, _ := doc[key].(string)
+	return v
+}
+
+// slugify derives the slug the dashboard gives APIs created without one from their name
+func slugify(name string) string {
+	out := &strings.Builder{}
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			out.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && out.Len() > 0 {
+			out.WriteRune('-')
+			dash = true
+		}
+	}
+
+	return strings.TrimSuffix(out.String(), "-")
+}
+
+func listenPath(def map[string]interface{}) string {
+	proxy, _ := def["proxy"].(map[string]interface{})
+	return str(proxy, "listen_path")
+}
+
+// conflict returns why def can't be stored next to the other APIs, the dashboard requires
+// unique slugs and listen paths per domain
+func (s *Server) conflict(id string, def map[string]interface{}) string {
+	for otherID, other := range s.apis {
+		if otherID == id {
+			continue
+		}
+
+		o := definition(other)
+		if str(def, "slug") != "" && str(o, "slug") == str(def, "slug") {
+			return fmt.Sprintf("Validation failed: the slug %v is used by API %v", str(def, "slug"), str(o, "api_id"))
+		}
+		if str(o, "domain") == str(def, "domain") && listenPath(o) == listenPath(def) {
+			return fmt.Sprintf("Validation failed: the listen path %v is used by API %v", listenPath(def), str(o, "api_id"))
+		}
+	}
+
+	return ""
+}
+
+func (s *Server) sortedAPIs() []map[string]interface{} {
+	ids := make([]string, 0, len(s.apis))
+	for id := range s.apis {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	out := make([]map[string]interface{}, len(ids))
+	for i, id := range ids {
+		out[i] = s.apis[id]
+	}
+	return out
+}
+
+func (s *Server) apiList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		reply(w, http.StatusOK, map[string]interface{}{"apis": s.sortedAPIs(), "pages": 1})
+	case http.MethodPost:
+		doc, err := decode(r)
+		if err != nil {
+			replyError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		id := bson.NewObjectId().Hex()
+		def := definition(doc)
+		if str(def, "slug") == "" {
+			def["slug"] = slugify(str(def, "name"))
+		}
+		if msg := s.conflict(id, def); msg != "" {
+			replyError(w, http.StatusBadRequest, msg)
+			return
+		}
+
+		// The dashboard ignores the IDs a created API is sent with
+		def["id"] = id
+		def["api_id"] = strings.Replace(uuid.NewV4().String(), "-", "", -1)
+		if str(def, "org_id") == "" {
+			def["org_id"] = s.OrgID
+		}
+		s.apis[id] = doc
+
+		reply(w, http.StatusOK, status{Status: "OK", Message: "API created", Meta: id})
+	default:
+		replyError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (s *Server) api(w http.ResponseWriter, r *http.Request) {
+	id := idFromPath(r, "/api/apis/")
+	if s.withCategories && (id == "categories" || strings.HasSuffix(id, "/categories")) {
+		s.categories(w, r, strings.TrimSuffix(strings.TrimSuffix(id, "categories"), "/"))
+		return
+	}
+
+	existing, ok := s.apis[id]
+	if !ok {
+		replyError(w, http.StatusNotFound, "API not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		reply(w, http.StatusOK, existing)
+	case http.MethodPut:
+		doc, err := decode(r)
+		if err != nil {
+			replyError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		def := definition(doc)
+		if str(def, "slug") == "" {
+			def["slug"] = str(definition(existing), "slug")
+		}
+		if msg := s.conflict(id, def); msg != "" {
+			replyError(w, http.StatusBadRequest, msg)
+			return
+		}
+
+		def["id"] = id
+		if str(def, "api_id") == "" {
+			def["api_id"] = str(definition(existing), "api_id")
+		}
+		if str(def, "org_id") == "" {
+			def["org_id"] = s.OrgID
+		}
+		s.apis[id] = doc
+
+		reply(w, http.StatusOK, status{Status: "OK", Message: "Api updated"})
+	case http.MethodDelete:
+		delete(s.apis, id)
+		reply(w, http.StatusOK, status{Status: "OK", Message: "API deleted"})
+	default:
+		replyError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// categories lists the categories of all APIs, or reads and sets those of the API id
+func (s *Server) categories(w http.ResponseWriter, r *http.Request, id string) {
+	if id == "" {
+		if r.Method != http.MethodGet {
+			replyError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		seen := map[string]bool{}
+		list := []string{}
+		for _, doc := range s.sortedAPIs() {
+			_, categories := objects.SplitCategories(str(definition(doc), "name"))
+			for _, c := range categories {
+				if !seen[c] {
+					seen[c] = true
+					list = append(list, c)
+				}
+			}
+		}
+		sort.Strings(list)
+
+		reply(w, http.StatusOK, objects.APICategories{Categories: list})
+		return
+	}
+
+	doc, ok := s.apis[id]
+	if !ok {
+		replyError(w, http.StatusNotFound, "API not found")
+		return
+	}
+	def := definition(doc)
+	name, categories := objects.SplitCategories(str(def, "name"))
+
+	switch r.Method {
+	case http.MethodGet:
+		reply(w, http.StatusOK, objects.APICategories{Categories: categories})
+	case http.MethodPut:
+		in := objects.APICategories{}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			replyError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		def["name"] = objects.JoinCategories(name, in.Categories)
+
+		reply(w, http.StatusOK, status{Status: "OK", Message: "Categories updated"})
+	default:
+		replyError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (s *Server) sortedPolicies() []map[string]interface{} {
+	ids := make([]string, 0, len(s.policies))
+	for id := range s.policies {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	out := make([]map[string]interface{}, len(ids))
+	for i, id := range ids {
+		out[i] = s.policies[id]
+	}
+	return out
+}
+
+func (s *Server) policyList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		reply(w, http.StatusOK, map[string]interface{}{"Data": s.sortedPolicies(), "Pages": 1})
+	case http.MethodPost:
+		doc, err := decode(r)
+		if err != nil {
+			replyError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		id := bson.NewObjectId().Hex()
+		doc["_id"] = id
+		if !s.explicitPolicyIDs {
+			// Without allow_explicit_policy_id the dashboard drops the id
+			doc["id"] = ""
+		}
+		if str(doc, "org_id") == "" {
+			doc["org_id"] = s.OrgID
+		}
+		doc["last_updated"] = fmt.Sprintf("%v", time.Now().Unix())
+		s.policies[id] = doc
+
+		reply(w, http.StatusOK, status{Status: "OK", Message: "Policy created", Meta: id})
+	default:
+		replyError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (s *Server) policy(w http.ResponseWriter, r *http.Request) {
+	id := idFromPath(r, "/api/portal/policies/")
+	existing, ok := s.policies[id]
+	if !ok {
+		replyError(w, http.StatusNotFound, "Policy not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		reply(w, http.StatusOK, existing)
+	case http.MethodPut:
+		doc, err := decode(r)
+		if err != nil {
+			replyError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		doc["_id"] = id
+		if str(doc, "org_id") == "" {
+			doc["org_id"] = s.OrgID
+		}
+		doc["last_updated"] = fmt.Sprintf("%v", time.Now().Unix())
+		s.policies[id] = doc
+
+		reply(w, http.StatusOK, status{Status: "OK", Message: "Data updated"})
+	case http.MethodDelete:
+		delete(s.policies, id)
+		reply(w, http.StatusOK, status{Status: "OK", Message: "Data deleted"})
+	default:
+		replyError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// certMeta reads the meta data the dashboard reports for a PEM file, the ID is the org
+// followed by the SHA-256 fingerprint of the certificate
+func (s *Server) certMeta(raw []byte) (*objects.CertificateMeta, error) {
+	meta := &objects.CertificateMeta{}
+	var cert *x509.Certificate
+	for block, rest := pem.Decode(raw); block != nil; block, rest = pem.Decode(rest) {
+		switch {
+		case block.Type == "CERTIFICATE" && cert == nil:
+			c, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			cert = c
+			sum := sha256.Sum256(block.Bytes)
+			meta.Fingerprint = hex.EncodeToString(sum[:])
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			meta.HasPrivateKey = true
+		}
+	}
+
+	if cert == nil {
+		return nil, fmt.Errorf("Certificate with private key or public certificate expected")
+	}
+
+	meta.ID = s.OrgID + meta.Fingerprint
+	meta.NotBefore = cert.NotBefore.UTC().Format(time.RFC3339)
+	meta.NotAfter = cert.NotAfter.UTC().Format(time.RFC3339)
+	meta.DNSNames = cert.DNSNames
+	return meta, nil
+}
+
+func (s *Server) certList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ids := make([]string, 0, len(s.certs))
+		for id := range s.certs {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		reply(w, http.StatusOK, map[string]interface{}{"certs": ids, "pages": 1})
+	case http.MethodPost:
+		f, _, err := r.FormFile("cert")
+		if err != nil {
+			replyError(w, http.StatusBadRequest, "Certificate expected in the cert form field")
+			return
+		}
+		raw, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			replyError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		meta, err := s.certMeta(raw)
+		if err != nil {
+			replyError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, exists := s.certs[meta.ID]; exists {
+			reply(w, http.StatusForbidden, objects.CertResponse{Status: "error", Message: "Certificate with " + meta.ID + " id already exists"})
+			return
+		}
+
+		s.certs[meta.ID] = meta
+		s.certPEM[meta.ID] = raw
+		reply(w, http.StatusOK, objects.CertResponse{Id: meta.ID, Status: "ok", Message: "Certificate added"})
+	default:
+		replyError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (s *Server) cert(w http.ResponseWriter, r *http.Request) {
+	id := idFromPath(r, "/api/certs/")
+	meta, ok := s.certs[id]
+	if !ok {
+		replyError(w, http.StatusNotFound, "Certificate not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		reply(w, http.StatusOK, meta)
+	case http.MethodDelete:
+		delete(s.certs, id)
+		delete(s.certPEM, id)
+		reply(w, http.StatusOK, status{Status: "OK", Message: "removed"})
+	default:
+		replyError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// SetExplicitPolicyIDs makes created policies keep their id, as a dashboard with
+// allow_explicit_policy_id does
+func (s *Server) SetExplicitPolicyIDs(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.explicitPolicyIDs = on
+}
+
+// SetCategories serves the API categories endpoints of newer dashboards, which read and
+// write the #hashtags at the end of the API names
+func (s *Server) SetCategories(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.withCategories = on
+}
+
+// APIs returns the stored APIs, ordered by database ID
+func (s *Server) APIs() []objects.DBApiDefinition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, _ := json.Marshal(s.sortedAPIs())
+	apis := []objects.DBApiDefinition{}
+	json.Unmarshal(raw, &apis)
+	return apis
+}
+
+// Policies returns the stored policies, ordered by database ID
+func (s *Server) Policies() []objects.Policy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, _ := json.Marshal(s.sortedPolicies())
+	pols := []objects.Policy{}
+	json.Unmarshal(raw, &pols)
+	return pols
+}
+
+// Certificates returns the meta data of the stored certificates, ordered by ID
+func (s *Server) Certificates() []objects.CertificateMeta {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.certs))
+	for id := range s.certs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	out := make([]objects.CertificateMeta, len(ids))
+	for i, id := range ids {
+		out[i] = *s.certs[id]
+	}
+	return out
+}
@@ -0,0 +1,294 @@
+package testserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func testAPI(apiID, listenPath string) objects.DBApiDefinition {
+	def := &apidef.APIDefinition{APIID: apiID, Name: apiID, Active: true}
+	def.Proxy.ListenPath = listenPath
+	def.VersionData.NotVersioned = true
+	def.VersionData.Versions = map[string]apidef.VersionInfo{"Default": {Name: "Default"}}
+	return objects.DBApiDefinition{APIDefinition: def}
+}
+
+func testCert(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "testserver"},
+		DNSNames:     []string{"testserver.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestSyncAgainstServer(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	c, err := dashboard.NewDashboardClient(s.URL, DefaultSecret, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.OrgID != DefaultOrgID {
+		t.Fatalf("expected the org of the user, got %q", c.OrgID)
+	}
+
+	done := 0
+	c.SetProgress(objects.ProgressFunc(func(e objects.ProgressEvent) {
+		if e.Item != "" {
+			done = e.Done
+		}
+	}))
+	if err := c.Sync([]objects.DBApiDefinition{testAPI("a1", "/a/"), testAPI("a2", "/b/")}); err != nil {
+		t.Fatal(err)
+	}
+	if done != 2 {
+		t.Errorf("expected progress up to 2 changes, got %v", done)
+	}
+	ids := map[string]bool{}
+	for _, api := range s.APIs() {
+		ids[api.APIID] = true
+	}
+	if len(ids) != 2 || !ids["a1"] || !ids["a2"] {
+		t.Fatalf("expected the API IDs to be retained, got %v", ids)
+	}
+
+	if err := c.Sync([]objects.DBApiDefinition{testAPI("a1", "/c/")}); err != nil {
+		t.Fatal(err)
+	}
+	apis := s.APIs()
+	if len(apis) != 1 || apis[0].APIID != "a1" || apis[0].Proxy.ListenPath != "/c/" {
+		t.Fatalf("expected a2 deleted and a1 updated, got %v APIs", len(apis))
+	}
+
+	pols := []objects.Policy{{ID: "p1", Name: "P1"}, {ID: "p2", Name: "P2"}}
+	if err := c.SyncPolicies(pols); err != nil {
+		t.Fatal(err)
+	}
+	stored := s.Policies()
+	if len(stored) != 2 {
+		t.Fatalf("expected 2 policies, got %v", stored)
+	}
+	for _, p := range stored {
+		if p.ID != "" || p.MID.Hex() == "" {
+			t.Errorf("expected the explicit ID dropped and a database ID, got %q %q", p.ID, p.MID.Hex())
+		}
+	}
+
+	id, err := c.CreateCertificate(testCert(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := c.FetchCertificate(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.ID != id || len(meta.DNSNames) != 1 || meta.HasPrivateKey {
+		t.Errorf("unexpected meta data %+v", meta)
+	}
+	if _, err := c.CreateCertificate(testCert(t)); err != nil {
+		t.Errorf("a different certificate must be accepted: %v", err)
+	}
+	if len(s.Certificates()) != 2 {
+		t.Errorf("expected 2 certificates, got %v", s.Certificates())
+	}
+}
+
+func TestSyncDeactivateRemoved(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	c, err := dashboard.NewDashboardClient(s.URL, DefaultSecret, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Sync([]objects.DBApiDefinition{testAPI("a1", "/a/"), testAPI("a2", "/b/")}); err != nil {
+		t.Fatal(err)
+	}
+
+	c.SetDeactivateRemoved(true)
+	var planned *objects.SyncPlan
+	c.SetPlanCheck(func(plan *objects.SyncPlan) error {
+		planned = plan
+		return nil
+	})
+	if err := c.Sync([]objects.DBApiDefinition{testAPI("a1", "/a/")}); err != nil {
+		t.Fatal(err)
+	}
+	if !planned.Deactivate || len(planned.Delete) != 1 || planned.Delete[0].ID != "a2" {
+		t.Errorf("expected a2 planned for deactivation, got %+v", planned)
+	}
+
+	apis := s.APIs()
+	if len(apis) != 2 {
+		t.Fatalf("expected a2 to be kept, got %v APIs", len(apis))
+	}
+	for _, api := range apis {
+		if api.Active != (api.APIID == "a1") || api.Proxy.ListenPath == "" {
+			t.Errorf("expected only a2 deactivated, with the rest of it kept, got %v active %v", api.APIID, api.Active)
+		}
+	}
+
+	// Deactivated APIs are left alone
+	if err := c.Sync([]objects.DBApiDefinition{testAPI("a1", "/a/")}); err != nil {
+		t.Fatal(err)
+	}
+	if len(planned.Delete) != 0 {
+		t.Errorf("expected nothing to deactivate, got %+v", planned.Delete)
+	}
+}
+
+func TestSyncByDatabaseID(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	c, err := dashboard.NewDashboardClient(s.URL, DefaultSecret, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Sync([]objects.DBApiDefinition{testAPI("a1", "/a/")}); err != nil {
+		t.Fatal(err)
+	}
+	dbID := s.APIs()[0].Id
+
+	// A definition without an API ID matches the API with its database ID
+	var planned *objects.SyncPlan
+	c.SetPlanCheck(func(plan *objects.SyncPlan) error {
+		planned = plan
+		return nil
+	})
+	def := testAPI("", "/b/")
+	def.Id = dbID
+	if err := c.Sync([]objects.DBApiDefinition{def}); err != nil {
+		t.Fatal(err)
+	}
+	if len(planned.Update) != 1 || len(planned.Create) != 0 || len(planned.Delete) != 0 {
+		t.Errorf("expected the API to be updated, got %+v", planned)
+	}
+	if apis := s.APIs(); len(apis) != 1 || apis[0].APIID != "a1" || apis[0].Proxy.ListenPath != "/b/" {
+		t.Errorf("expected a1 updated in place, got %v APIs", len(apis))
+	}
+}
+
+func TestServerQuirks(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	if _, err := dashboard.NewDashboardClient(s.URL, "wrong", ""); err == nil {
+		t.Error("expected an error for a wrong secret")
+	}
+
+	c, err := dashboard.NewDashboardClient(s.URL, DefaultSecret, DefaultOrgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := c.FetchVersion()
+	if err != nil || version != Version {
+		t.Errorf("expected version %v, got %q %v", Version, version, err)
+	}
+
+	// Created APIs don't keep their API ID, the client updates them to retain it
+	created := map[string]interface{}{}
+	if err := c.Do("POST", "/api/apis", testAPI("a1", "/a/"), &created); err != nil {
+		t.Fatal(err)
+	}
+	if apis := s.APIs(); len(apis) != 1 || apis[0].APIID == "a1" || apis[0].APIID == "" {
+		t.Errorf("expected a new API ID, got %v APIs", len(apis))
+	}
+
+	if err := c.Do("POST", "/api/apis", testAPI("a2", "/a/"), nil); err == nil {
+		t.Error("expected a duplicate listen path to be rejected")
+	}
+
+	s.SetExplicitPolicyIDs(true)
+	if err := c.SyncPolicies([]objects.Policy{{ID: "p1", Name: "P1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if pols := s.Policies(); len(pols) != 1 || pols[0].ID != "p1" {
+		t.Errorf("expected the explicit ID kept, got %v", pols)
+	}
+}
+
+func TestAPICategories(t *testing.T) {
+	old := New()
+	defer old.Close()
+	c, err := dashboard.NewDashboardClient(old.URL, DefaultSecret, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.FetchCategories(); err != dashboard.ErrNoCategories {
+		t.Errorf("expected no categories endpoints, got %v", err)
+	}
+
+	s := New()
+	defer s.Close()
+	s.SetCategories(true)
+	if c, err = dashboard.NewDashboardClient(s.URL, DefaultSecret, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	named := func(name string) []objects.DBApiDefinition {
+		def := testAPI("a1", "/a/")
+		def.Name = name
+		return []objects.DBApiDefinition{def}
+	}
+	stored := func() (string, []string) {
+		apis := s.APIs()
+		if len(apis) != 1 {
+			t.Fatalf("expected 1 API, got %v", len(apis))
+		}
+		categories, err := c.FetchAPICategories(apis[0].Id.Hex())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return apis[0].Name, categories
+	}
+
+	if err := c.Sync(named("A1 #finance #public")); err != nil {
+		t.Fatal(err)
+	}
+	if all, err := c.FetchCategories(); err != nil || len(all) != 2 || all[0] != "finance" {
+		t.Errorf("expected the categories of the created API, got %v %v", all, err)
+	}
+
+	// An API published without categories keeps those it has
+	if err := c.Sync(named("A1 renamed")); err != nil {
+		t.Fatal(err)
+	}
+	if name, categories := stored(); name != "A1 renamed #finance #public" || len(categories) != 2 {
+		t.Errorf("expected the categories kept, got %q %v", name, categories)
+	}
+
+	c.SetReplaceCategories(true)
+	if err := c.Sync(named("A1")); err != nil {
+		t.Fatal(err)
+	}
+	if name, categories := stored(); name != "A1" || len(categories) != 0 {
+		t.Errorf("expected the categories cleared, got %q %v", name, categories)
+	}
+}
@@ -0,0 +1,397 @@
+package tyk_diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-patch"
+)
+
+const (
+	// Changed means both sides have the field, with different values
+	Changed = "changed"
+	// Dropped means the expected field is missing from the actual object
+	Dropped = "dropped"
+	// Added means the actual object has a field that was not expected
+	Added = "added"
+)
+
+// Change is a single difference between two objects
+type Change struct {
+	Path     string      `json:"path"`
+	Kind     string      `json:"kind"`
+	Expected interface{} `json:"expected,omitempty"`
+	Actual   interface{} `json:"actual,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Dropped:
+		return fmt.Sprintf("%v dropped (expected: %v)", c.Path, format(c.Expected))
+	case Added:
+		return fmt.Sprintf("%v added (value: %v)", c.Path, format(c.Actual))
+	default:
+		return fmt.Sprintf("%v changed (expected: %v, got: %v)", c.Path, format(c.Expected), format(c.Actual))
+	}
+}
+
+func format(v interface{}) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	if len(raw) > 80 {
+		return string(raw[:77]) + "..."
+	}
+
+	return string(raw)
+}
+
+// isEmpty is true for values a server may leave out or fill in without changing the
+// meaning of an object
+func isEmpty(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return t == ""
+	case bool:
+		return !t
+	case float64:
+		return t == 0
+	case []interface{}:
+		return len(t) == 0
+	case map[string]interface{}:
+		// An object with only empty fields is what decoding "nothing" into a struct gives
+		for _, v := range t {
+			if !isEmpty(v) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// Unordered are the arrays of API definitions and policies whose order has no meaning, and
+// may change when the Dashboard stores an object, Normalize sorts them. The lists of
+// extended paths are not, their order decides which entry matches a request.
+var Unordered = []string{
+	"tags[]",
+	"tag_headers[]",
+	"allowed_ips[]",
+	"blacklisted_ips[]",
+	"certificates[]",
+	"client_certificates[]",
+	"hmac_allowed_algorithms[]",
+	"jwt_default_policies[]",
+	"CORS.allowed_origins[]",
+	"CORS.allowed_methods[]",
+	"CORS.allowed_headers[]",
+	"CORS.exposed_headers[]",
+	"version_data.versions.*.global_headers_remove[]",
+	"version_data.versions.*.extended_paths.cache[]",
+	"access_rights.*.versions[]",
+	"access_rights.*.allowed_urls[*].methods[]",
+}
+
+// Normalize converts v into plain JSON values, sorts the Unordered arrays and applies the
+// ignore rules: the fields they match are removed, or for rules ending with [] the order of
+// the array is ignored. See ParseRule for their syntax.
+func Normalize(v interface{}, ignore []string) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range append(append([]string{}, Unordered...), ignore...) {
+		tokens, unordered, err := ParseRule(rule)
+		if err != nil {
+			return nil, err
+		}
+
+		if unordered {
+			doc = sortArrays(doc, tokens)
+			continue
+		}
+		doc = remove(doc, tokens)
+	}
+
+	return doc, nil
+}
+
+// ParseRule reads an ignore rule, either a JSON pointer (* matches any key or index) or a
+// JSONPath style path: keys separated by dots, optionally starting with $., where [n] or
+// [*] index arrays and ["key"] quotes keys holding dots, e.g.
+// version_data.versions.*.paths.ignored[*].path. A path ending with [] ignores the order of
+// the array rather than the array itself, e.g. tags[]. It returns the tokens of the path.
+func ParseRule(rule string) ([]string, bool, error) {
+	if strings.HasPrefix(rule, "/") {
+		tokens, err := tyk_patch.ParsePointer(rule)
+		return tokens, false, err
+	}
+
+	path := strings.TrimPrefix(strings.TrimPrefix(rule, "$"), ".")
+	unordered := strings.HasSuffix(path, "[]")
+	path = strings.TrimSuffix(path, "[]")
+	if path == "" {
+		return nil, false, fmt.Errorf("invalid ignore rule %q, it matches the whole object", rule)
+	}
+
+	tokens := []string{}
+	key := &strings.Builder{}
+	flush := func() {
+		if key.Len() > 0 {
+			tokens = append(tokens, key.String())
+			key.Reset()
+		}
+	}
+
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '.':
+			flush()
+		case '[':
+			flush()
+			end := strings.Index(path[i:], "]")
+			if end < 0 {
+				return nil, false, fmt.Errorf("invalid ignore rule %q, missing ]", rule)
+			}
+
+			token := path[i+1 : i+end]
+			if strings.HasPrefix(token, `"`) {
+				// The quoted key may itself hold a ]
+				end = strings.Index(path[i+1:], `"]`) + 2
+				if end < 2 {
+					return nil, false, fmt.Errorf("invalid ignore rule %q, missing \"]", rule)
+				}
+				unquoted, err := strconv.Unquote(path[i+1 : i+end])
+				if err != nil {
+					return nil, false, fmt.Errorf("invalid ignore rule %q: %v", rule, err)
+				}
+				token = unquoted
+			} else if token == "" {
+				return nil, false, fmt.Errorf("invalid ignore rule %q, [] must end the rule", rule)
+			}
+
+			tokens = append(tokens, token)
+			i += end
+		default:
+			key.WriteByte(path[i])
+		}
+	}
+	flush()
+
+	return tokens, unordered, nil
+}
+
+// sortArrays sorts the arrays at the path by the JSON of their elements, so their order
+// doesn't count in a comparison
+func sortArrays(node interface{}, tokens []string) interface{} {
+	if len(tokens) == 0 {
+		a, ok := node.([]interface{})
+		if !ok {
+			return node
+		}
+
+		keys := make([]string, len(a))
+		for i, v := range a {
+			raw, _ := json.Marshal(v)
+			keys[i] = string(raw)
+		}
+		sort.Sort(byKey{a, keys})
+		return a
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			if tokens[0] == "*" || tokens[0] == k {
+				n[k] = sortArrays(v, tokens[1:])
+			}
+		}
+	case []interface{}:
+		for i, v := range n {
+			if tokens[0] == "*" || tokens[0] == fmt.Sprintf("%v", i) {
+				n[i] = sortArrays(v, tokens[1:])
+			}
+		}
+	}
+
+	return node
+}
+
+// byKey sorts values by their keys
+type byKey struct {
+	values []interface{}
+	keys   []string
+}
+
+func (b byKey) Len() int           { return len(b.values) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.values[i], b.values[j] = b.values[j], b.values[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+func remove(node interface{}, tokens []string) interface{} {
+	if len(tokens) == 0 {
+		return node
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			if tokens[0] != "*" && tokens[0] != k {
+				continue
+			}
+
+			if len(tokens) == 1 {
+				delete(n, k)
+				continue
+			}
+			n[k] = remove(v, tokens[1:])
+		}
+	case []interface{}:
+		for i, v := range n {
+			if tokens[0] != "*" && tokens[0] != fmt.Sprintf("%v", i) {
+				continue
+			}
+			n[i] = remove(v, tokens[1:])
+		}
+	}
+
+	return node
+}
+
+// scalars tells whether an array only holds strings, numbers, booleans or nulls
+func scalars(a []interface{}) bool {
+	for _, v := range a {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+
+	return true
+}
+
+// Compare lists the differences between two normalized objects, missing and empty
+// values are treated as equal
+func Compare(expected, actual interface{}) []Change {
+	changes := []Change{}
+	compare("", expected, actual, &changes)
+	return changes
+}
+
+func compare(path string, expected, actual interface{}, changes *[]Change) {
+	if isEmpty(expected) && isEmpty(actual) {
+		return
+	}
+
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := map[string]bool{}
+		for k := range e {
+			keys[k] = true
+		}
+		for k := range a {
+			keys[k] = true
+		}
+
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			ev, eok := e[k]
+			av, aok := a[k]
+			p := path + "/" + tyk_patch.EscapeToken(k)
+
+			switch {
+			case eok && !aok:
+				if !isEmpty(ev) {
+					*changes = append(*changes, Change{Path: p, Kind: Dropped, Expected: ev})
+				}
+			case !eok && aok:
+				if !isEmpty(av) {
+					*changes = append(*changes, Change{Path: p, Kind: Added, Actual: av})
+				}
+			default:
+				compare(p, ev, av, changes)
+			}
+		}
+		return
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		// Arrays of values are compared as a whole, an index into them means nothing
+		// once sorted
+		if !ok || len(a) != len(e) || scalars(e) || scalars(a) {
+			break
+		}
+
+		for i := range e {
+			compare(fmt.Sprintf("%v/%v", path, i), e[i], a[i], changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		if path == "" {
+			path = "/"
+		}
+		*changes = append(*changes, Change{Path: path, Kind: Changed, Expected: expected, Actual: actual})
+	}
+}
+
+// Patch is the RFC 6902 JSON Patch that turns the actual object into the expected one,
+// fields the actual object added are removed
+func Patch(changes []Change) []tyk_patch.Operation {
+	ops := make([]tyk_patch.Operation, 0, len(changes))
+	for _, c := range changes {
+		path := c.Path
+		if path == "/" {
+			path = ""
+		}
+
+		switch c.Kind {
+		case Dropped:
+			ops = append(ops, tyk_patch.Operation{Op: "add", Path: path, Value: c.Expected})
+		case Added:
+			ops = append(ops, tyk_patch.Operation{Op: "remove", Path: path})
+		default:
+			ops = append(ops, tyk_patch.Operation{Op: "replace", Path: path, Value: c.Expected})
+		}
+	}
+
+	return ops
+}
+
+// ObjectDiff is the differences of one object, as machine readable output. Changes or
+// Patch is set depending on the format, Error when the object could not be compared.
+type ObjectDiff struct {
+	Kind    string                `json:"kind"`
+	Name    string                `json:"name"`
+	ID      string                `json:"id,omitempty"`
+	Changes []Change              `json:"changes,omitempty"`
+	Patch   []tyk_patch.Operation `json:"patch,omitempty"`
+	Error   string                `json:"error,omitempty"`
+}
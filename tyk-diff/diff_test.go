@@ -0,0 +1,166 @@
+package tyk_diff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-patch"
+)
+
+func TestCompare(t *testing.T) {
+	expected, err := Normalize(map[string]interface{}{
+		"id":           "local",
+		"name":         "API",
+		"tags":         []string{},
+		"custom_field": "x",
+		"proxy":        map[string]interface{}{"listen_path": "/a/", "target_url": "http://a"},
+		"versions":     []interface{}{map[string]interface{}{"name": "v1"}},
+	}, []string{"/id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := Normalize(map[string]interface{}{
+		"id":       "remote",
+		"name":     "API",
+		"tags":     nil,
+		"active":   false,
+		"new_flag": true,
+		"proxy":    map[string]interface{}{"listen_path": "/b/", "target_url": "http://a"},
+		"versions": []interface{}{map[string]interface{}{"name": "v1"}},
+	}, []string{"/id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := Compare(expected, actual)
+	want := []Change{
+		{Path: "/custom_field", Kind: Dropped, Expected: "x"},
+		{Path: "/new_flag", Kind: Added, Actual: true},
+		{Path: "/proxy/listen_path", Kind: Changed, Expected: "/a/", Actual: "/b/"},
+	}
+
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("Expected: %+v, got: %+v", want, changes)
+	}
+}
+
+func TestNormalizeWildcard(t *testing.T) {
+	doc, err := Normalize(map[string]interface{}{
+		"versions": map[string]interface{}{
+			"v1": map[string]interface{}{"expires": "x", "name": "v1"},
+			"v2": map[string]interface{}{"expires": "y", "name": "v2"},
+		},
+	}, []string{"/versions/*/expires"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"versions": map[string]interface{}{
+			"v1": map[string]interface{}{"name": "v1"},
+			"v2": map[string]interface{}{"name": "v2"},
+		},
+	}
+
+	if !reflect.DeepEqual(doc, want) {
+		t.Fatalf("Expected: %+v, got: %+v", want, doc)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	actual := map[string]interface{}{
+		"name":     "API",
+		"new_flag": true,
+		"proxy":    map[string]interface{}{"listen_path": "/b/"},
+	}
+	expected := map[string]interface{}{
+		"name":         "API",
+		"custom_field": "x",
+		"proxy":        map[string]interface{}{"listen_path": "/a/"},
+	}
+
+	exp, _ := Normalize(expected, nil)
+	act, _ := Normalize(actual, nil)
+	ops := Patch(Compare(exp, act))
+	if len(ops) != 3 {
+		t.Fatalf("expected 3 operations, got %+v", ops)
+	}
+
+	patched, err := tyk_patch.Apply(act, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := Compare(exp, patched); len(changes) != 0 {
+		t.Fatalf("the patched object still differs: %+v", changes)
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	cases := []struct {
+		rule      string
+		tokens    []string
+		unordered bool
+	}{
+		{"/proxy/listen_path", []string{"proxy", "listen_path"}, false},
+		{"active", []string{"active"}, false},
+		{"$.version_data.versions.*.paths.ignored[*].path", []string{"version_data", "versions", "*", "paths", "ignored", "*", "path"}, false},
+		{`["a.b"].c`, []string{"a.b", "c"}, false},
+		{"tags[]", []string{"tags"}, true},
+	}
+
+	for _, c := range cases {
+		tokens, unordered, err := ParseRule(c.rule)
+		if err != nil {
+			t.Fatalf("%v: %v", c.rule, err)
+		}
+		if !reflect.DeepEqual(tokens, c.tokens) || unordered != c.unordered {
+			t.Fatalf("%v: got %v %v, expected %v %v", c.rule, tokens, unordered, c.tokens, c.unordered)
+		}
+	}
+
+	for _, rule := range []string{"", "$", "tags[", "a[].b"} {
+		if _, _, err := ParseRule(rule); err == nil {
+			t.Fatalf("expected an error for %q", rule)
+		}
+	}
+}
+
+func TestNormalize_IgnoreRules(t *testing.T) {
+	rules := []string{"active", "tags[]"}
+	expected, err := Normalize(map[string]interface{}{"active": true, "tags": []string{"a", "b"}}, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := Normalize(map[string]interface{}{"active": false, "tags": []string{"b", "a"}}, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := Compare(expected, actual); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestNormalize_Unordered(t *testing.T) {
+	expected, _ := Normalize(map[string]interface{}{
+		"tags":          []string{"b", "a"},
+		"access_rights": map[string]interface{}{"api": map[string]interface{}{"allowed_urls": []interface{}{map[string]interface{}{"url": "/x", "methods": []string{"POST", "GET"}}}}},
+	}, nil)
+	actual, _ := Normalize(map[string]interface{}{
+		"tags":          []string{"a", "b"},
+		"access_rights": map[string]interface{}{"api": map[string]interface{}{"allowed_urls": []interface{}{map[string]interface{}{"url": "/x", "methods": []string{"GET", "POST"}}}}},
+	}, nil)
+
+	if changes := Compare(expected, actual); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+
+	// Arrays of values change as a whole, not at the indexes of the sorted array
+	actual, _ = Normalize(map[string]interface{}{"tags": []string{"c", "a"}}, nil)
+	expected, _ = Normalize(map[string]interface{}{"tags": []string{"a", "b"}}, nil)
+	changes := Compare(expected, actual)
+	if len(changes) != 1 || changes[0].Path != "/tags" {
+		t.Fatalf("expected the tags to change as a whole, got %+v", changes)
+	}
+}
@@ -0,0 +1,98 @@
+package tyk_diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-patch"
+)
+
+const (
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorReset  = "\x1b[0m"
+)
+
+// DottedPath turns the JSON pointer of a change into the path people read, e.g.
+// version_data.versions.Default.extended_paths.white_list[3].path. doc is the object the
+// pointer is into, it tells array indexes from keys.
+func DottedPath(pointer string, doc interface{}) string {
+	tokens, err := tyk_patch.ParsePointer(pointer)
+	if err != nil || len(tokens) == 0 || pointer == "/" {
+		return pointer
+	}
+
+	out := &strings.Builder{}
+	node := doc
+	for _, t := range tokens {
+		switch n := node.(type) {
+		case []interface{}:
+			fmt.Fprintf(out, "[%v]", t)
+			if i, err := strconv.Atoi(t); err == nil && i >= 0 && i < len(n) {
+				node = n[i]
+			} else {
+				node = nil
+			}
+			continue
+		case map[string]interface{}:
+			node = n[t]
+		default:
+			node = nil
+		}
+
+		if strings.ContainsAny(t, ".[]") || t == "" {
+			fmt.Fprintf(out, "[%q]", t)
+			continue
+		}
+		if out.Len() > 0 {
+			out.WriteString(".")
+		}
+		out.WriteString(t)
+	}
+
+	return out.String()
+}
+
+// pretty formats a value as indented JSON, its lines after the first are indented by indent
+func pretty(v interface{}, indent string) string {
+	raw, err := json.MarshalIndent(v, indent, "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return string(raw)
+}
+
+func paint(s, color string, colored bool) string {
+	if !colored {
+		return s
+	}
+
+	return color + s + colorReset
+}
+
+// Pretty renders a change for people: the dotted path of the field and the values it has on
+// both sides, published (expected) in green and stored (actual) in red when colored. Every
+// line starts with indent.
+func (c Change) Pretty(field, indent string, colored bool) string {
+	out := &strings.Builder{}
+	valueIndent := indent + "    "
+
+	switch c.Kind {
+	case Dropped:
+		fmt.Fprintf(out, "%v%v %v, dropped by the target\n", indent, paint("-", colorRed, colored), field)
+		fmt.Fprintf(out, "%vpublished: %v\n", valueIndent, paint(pretty(c.Expected, valueIndent+"           "), colorGreen, colored))
+	case Added:
+		fmt.Fprintf(out, "%v%v %v, unknown to tyk-sync\n", indent, paint("+", colorYellow, colored), field)
+		fmt.Fprintf(out, "%vstored:    %v\n", valueIndent, paint(pretty(c.Actual, valueIndent+"           "), colorYellow, colored))
+	default:
+		fmt.Fprintf(out, "%v%v %v\n", indent, paint("~", colorYellow, colored), field)
+		fmt.Fprintf(out, "%vpublished: %v\n", valueIndent, paint(pretty(c.Expected, valueIndent+"           "), colorGreen, colored))
+		fmt.Fprintf(out, "%vstored:    %v\n", valueIndent, paint(pretty(c.Actual, valueIndent+"           "), colorRed, colored))
+	}
+
+	return out.String()
+}
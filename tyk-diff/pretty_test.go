@@ -0,0 +1,50 @@
+package tyk_diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDottedPath(t *testing.T) {
+	doc, err := Normalize(map[string]interface{}{
+		"version_data": map[string]interface{}{
+			"versions": map[string]interface{}{
+				"Default": map[string]interface{}{
+					"extended_paths": map[string]interface{}{
+						"white_list": []interface{}{"a", "b", "c", map[string]interface{}{"path": "/d"}},
+					},
+				},
+			},
+		},
+		"a.b": map[string]interface{}{"c": 1},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"/version_data/versions/Default/extended_paths/white_list/3/path": "version_data.versions.Default.extended_paths.white_list[3].path",
+		"/a.b/c": `["a.b"].c`,
+		"/":      "/",
+	}
+	for pointer, want := range cases {
+		if got := DottedPath(pointer, doc); got != want {
+			t.Fatalf("%v: expected %v, got %v", pointer, want, got)
+		}
+	}
+}
+
+func TestChange_Pretty(t *testing.T) {
+	c := Change{Path: "/proxy/listen_path", Kind: Changed, Expected: "/a/", Actual: "/b/"}
+	want := "  ~ proxy.listen_path\n" +
+		"      published: \"/a/\"\n" +
+		"      stored:    \"/b/\"\n"
+	if got := c.Pretty("proxy.listen_path", "  ", false); got != want {
+		t.Fatalf("expected:\n%v\ngot:\n%v", want, got)
+	}
+
+	colored := c.Pretty("proxy.listen_path", "  ", true)
+	if !strings.Contains(colored, colorGreen+`"/a/"`+colorReset) || !strings.Contains(colored, colorRed+`"/b/"`+colorReset) {
+		t.Fatalf("expected colored values, got %q", colored)
+	}
+}
@@ -0,0 +1,134 @@
+package tyk_importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// asyncAPIServer is a server of AsyncAPI 2 (url) or 3 (host and pathname)
+type asyncAPIServer struct {
+	URL      string `json:"url"`
+	Host     string `json:"host"`
+	Pathname string `json:"pathname"`
+	Protocol string `json:"protocol"`
+}
+
+type asyncAPIChannel struct {
+	// Address is the path of an AsyncAPI 3 channel, AsyncAPI 2 channels are keyed by it
+	Address string `json:"address"`
+}
+
+// AsyncAPIDocument is the subset of an AsyncAPI 2 or 3 document, in JSON or YAML, needed to
+// build a definition
+type AsyncAPIDocument struct {
+	AsyncAPI string `json:"asyncapi"`
+	Info     struct {
+		Title string `json:"title"`
+	} `json:"info"`
+	Servers  map[string]asyncAPIServer  `json:"servers"`
+	Channels map[string]asyncAPIChannel `json:"channels"`
+}
+
+// ParseAsyncAPI reads an AsyncAPI document, YAML or JSON
+func ParseAsyncAPI(raw []byte) (*AsyncAPIDocument, error) {
+	conv, err := YAMLToJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &AsyncAPIDocument{}
+	if err := json.Unmarshal(conv, doc); err != nil {
+		return nil, err
+	}
+
+	if doc.AsyncAPI == "" {
+		return nil, errors.New("not an AsyncAPI document, the asyncapi version is not set")
+	}
+	if len(doc.Channels) == 0 {
+		return nil, errors.New("no channels defined in the AsyncAPI document")
+	}
+
+	return doc, nil
+}
+
+// websocketTarget is the URL of the first websocket server, by name, as the HTTP URL the
+// gateway proxies the upgrade requests to
+func (d *AsyncAPIDocument) websocketTarget() (*url.URL, error) {
+	names := make([]string, 0, len(d.Servers))
+	for n := range d.Servers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		s := d.Servers[n]
+		raw := s.URL
+		if raw == "" {
+			raw = s.Host + s.Pathname
+		}
+		if !strings.Contains(raw, "://") {
+			raw = s.Protocol + "://" + raw
+		}
+
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url of server %v: %v", n, err)
+		}
+
+		switch strings.ToLower(u.Scheme) {
+		case "ws":
+			u.Scheme = "http"
+		case "wss":
+			u.Scheme = "https"
+		default:
+			continue
+		}
+		return u, nil
+	}
+
+	return nil, errors.New("no websocket (ws or wss) server defined in the AsyncAPI document")
+}
+
+// CreateDefinitionFromAsyncAPI builds a definition proxying the websocket server of an
+// AsyncAPI document, with a white list entry for the upgrade request (GET) of every channel.
+// Websockets are proxied once the gateway runs with http_server_options.enable_websockets.
+func CreateDefinitionFromAsyncAPI(doc *AsyncAPIDocument, orgId string, versionName string) (*objects.DBApiDefinition, error) {
+	name := doc.Info.Title
+	if name == "" {
+		name = "AsyncAPI"
+	}
+
+	target, err := doc.websocketTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	ad := newDefinition(name, orgId)
+	ad.Proxy.TargetURL = strings.TrimSuffix(target.String(), "/")
+
+	e := endpoints{}
+	for key, ch := range doc.Channels {
+		address := key
+		if strings.HasPrefix(doc.AsyncAPI, "3") {
+			if ch.Address == "" {
+				// Unknown or dynamic addresses can't be white listed
+				continue
+			}
+			address = ch.Address
+		}
+		e.add(tykPath(address), "GET", apidef.EndpointMethodMeta{})
+	}
+
+	if err := e.setVersion(ad, versionName); err != nil {
+		return nil, err
+	}
+
+	return ad, nil
+}
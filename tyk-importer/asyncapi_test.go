@@ -0,0 +1,83 @@
+package tyk_importer
+
+import (
+	"testing"
+)
+
+const chatAsyncAPI = `asyncapi: 2.6.0
+info:
+  title: Chat Service
+  version: 1.0.0
+servers:
+  mqtt:
+    url: mqtt://broker.example.com
+    protocol: mqtt
+  production:
+    url: chat.example.com:8443/ws
+    protocol: wss
+channels:
+  rooms/{roomId}:
+    subscribe:
+      message:
+        payload:
+          type: string
+  /presence:
+    publish:
+      message:
+        payload:
+          type: object
+`
+
+func TestCreateDefinitionFromAsyncAPI(t *testing.T) {
+	doc, err := ParseAsyncAPI([]byte(chatAsyncAPI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ad, err := CreateDefinitionFromAsyncAPI(doc, "org1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ad.Name != "Chat Service" || ad.Proxy.ListenPath != "/chat-service/" || ad.Proxy.TargetURL != "https://chat.example.com:8443/ws" {
+		t.Fatalf("Unexpected definition: %v %+v", ad.Name, ad.Proxy)
+	}
+
+	wl := ad.VersionData.Versions["Default"].ExtendedPaths.WhiteList
+	if len(wl) != 2 || wl[0].Path != "/presence" || wl[1].Path != "/rooms/{roomId}" {
+		t.Fatalf("Expected the channels to be white listed, got: %+v", wl)
+	}
+	if _, ok := wl[1].MethodActions["GET"]; !ok {
+		t.Fatalf("Expected the upgrade requests to be allowed, got: %+v", wl[1].MethodActions)
+	}
+}
+
+func TestCreateDefinitionFromAsyncAPI3(t *testing.T) {
+	doc, err := ParseAsyncAPI([]byte(`{
+		"asyncapi": "3.0.0",
+		"info": {"title": "Prices"},
+		"servers": {"local": {"host": "localhost:8080", "pathname": "/stream", "protocol": "ws"}},
+		"channels": {"prices": {"address": "/prices/{symbol}"}, "replies": {"address": null}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ad, err := CreateDefinitionFromAsyncAPI(doc, "org1", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ad.Proxy.TargetURL != "http://localhost:8080/stream" {
+		t.Fatalf("Unexpected target: %v", ad.Proxy.TargetURL)
+	}
+	wl := ad.VersionData.Versions["v1"].ExtendedPaths.WhiteList
+	if len(wl) != 1 || wl[0].Path != "/prices/{symbol}" {
+		t.Fatalf("Expected the channel with an address only, got: %+v", wl)
+	}
+
+	doc.Servers = map[string]asyncAPIServer{"kafka": {URL: "kafka://broker:9092", Protocol: "kafka"}}
+	if _, err := CreateDefinitionFromAsyncAPI(doc, "org1", ""); err == nil {
+		t.Fatal("Expected an error without a websocket server")
+	}
+}
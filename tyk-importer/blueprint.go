@@ -0,0 +1,206 @@
+package tyk_importer
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+var (
+	bpHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	bpResource = regexp.MustCompile(`^(.*?)\[(/[^\]]*)\]$`)
+	bpAction   = regexp.MustCompile(`^(.*?)\[([A-Z]+)(?:\s+(/[^\]]*))?\]$`)
+	bpResponse = regexp.MustCompile(`^\s*[+*-]\s+Response\s+(\d{3})`)
+	bpSection  = regexp.MustCompile(`^\s*[+*-]\s+(\w+)`)
+	bpMetadata = regexp.MustCompile(`^([A-Z]+):\s*(.*)$`)
+)
+
+// BlueprintAction is a single method on a resource, with its first response example
+type BlueprintAction struct {
+	Method  string
+	Path    string
+	Code    int
+	Headers map[string]string
+	Body    string
+}
+
+// BlueprintDocument is the subset of an API Blueprint (markdown) document needed to build a definition
+type BlueprintDocument struct {
+	Name    string
+	Host    string
+	Actions []BlueprintAction
+}
+
+func dedent(lines []string) string {
+	min := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		indent := len(l) - len(strings.TrimLeft(l, " \t"))
+		if min < 0 || indent < min {
+			min = indent
+		}
+	}
+
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		if len(l) >= min && min > 0 {
+			l = l[min:]
+		}
+		out[i] = l
+	}
+
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// ParseBlueprint reads the resources, actions and response examples from an API Blueprint document
+func ParseBlueprint(raw []byte) (*BlueprintDocument, error) {
+	doc := &BlueprintDocument{}
+
+	var resourcePath string
+	var current *BlueprintAction
+	var body []string
+	section := ""
+	responses := 0
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		current.Body = dedent(body)
+		doc.Actions = append(doc.Actions, *current)
+		current = nil
+		body = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if m := bpHeading.FindStringSubmatch(line); m != nil {
+			title := m[2]
+			section = ""
+
+			if m := bpAction.FindStringSubmatch(title); m != nil {
+				finish()
+				path := m[3]
+				if path == "" {
+					path = resourcePath
+				}
+				current = &BlueprintAction{Method: m[2], Path: path, Headers: map[string]string{}}
+				responses = 0
+				continue
+			}
+
+			if m := bpResource.FindStringSubmatch(title); m != nil {
+				finish()
+				resourcePath = m[2]
+				continue
+			}
+
+			if doc.Name == "" && !strings.HasPrefix(title, "Group ") && len(m[1]) == 1 {
+				doc.Name = title
+			}
+			continue
+		}
+
+		if doc.Name == "" && current == nil {
+			if m := bpMetadata.FindStringSubmatch(line); m != nil {
+				if m[1] == "HOST" {
+					doc.Host = strings.TrimSpace(m[2])
+				}
+				continue
+			}
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if m := bpResponse.FindStringSubmatch(line); m != nil {
+			responses++
+			if responses == 1 {
+				current.Code, _ = strconv.Atoi(m[1])
+				section = "response"
+			} else {
+				section = "ignored"
+			}
+			continue
+		}
+
+		if m := bpSection.FindStringSubmatch(line); m != nil && strings.TrimSpace(line) != "" {
+			switch {
+			case m[1] == "Body" && (section == "response" || section == "headers"):
+				section = "body"
+			case m[1] == "Headers" && (section == "response" || section == "body"):
+				section = "headers"
+			case section == "response" || section == "body" || section == "headers":
+				// Schema, Attributes and friends of the response are not used
+				section = "ignored"
+			default:
+				if m[1] == "Request" || m[1] == "Parameters" || m[1] == "Attributes" {
+					section = "ignored"
+				}
+			}
+			continue
+		}
+
+		indented := strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")
+		switch section {
+		case "response", "body":
+			if indented || strings.TrimSpace(line) == "" {
+				body = append(body, line)
+			}
+		case "headers":
+			if kv := strings.SplitN(strings.TrimSpace(line), ":", 2); len(kv) == 2 {
+				current.Headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+			}
+		}
+	}
+	finish()
+
+	return doc, scanner.Err()
+}
+
+// CreateDefinitionFromBlueprint builds a skeleton definition white listing every action in the blueprint
+func CreateDefinitionFromBlueprint(b *BlueprintDocument, orgId string, versionName string) (*objects.DBApiDefinition, error) {
+	ad := newDefinition(b.Name, orgId)
+
+	target := b.Host
+	if target == "" {
+		target = "http://unset.com"
+	}
+	ad.Proxy.TargetURL = target
+
+	e := endpoints{}
+	for _, a := range b.Actions {
+		path := a.Path
+		if path == "" {
+			continue
+		}
+
+		// Drop URI template query parameters: /notes{?limit}
+		if i := strings.Index(path, "{?"); i >= 0 {
+			path = path[:i]
+		}
+
+		e.add(tykPath(path), a.Method, apidef.EndpointMethodMeta{
+			Code:    a.Code,
+			Data:    a.Body,
+			Headers: a.Headers,
+		})
+	}
+
+	if err := e.setVersion(ad, versionName); err != nil {
+		return nil, err
+	}
+
+	return ad, nil
+}
@@ -0,0 +1,76 @@
+package tyk_importer
+
+import (
+	"testing"
+)
+
+const notesBlueprint = `FORMAT: 1A
+HOST: https://notes.example.com/api
+
+# Notes API
+
+# Group Notes
+
+## Notes Collection [/notes{?limit}]
+
+### List Notes [GET]
+
++ Response 200 (application/json)
+
+    + Headers
+
+            X-Total: 2
+
+    + Body
+
+            [{"id": 1}]
+
++ Response 404
+
+### Create a Note [POST]
+
++ Request (application/json)
+
+        {"title": "Buy milk"}
+
++ Response 201
+
+## Note [/notes/{id}]
+
+### Remove a Note [DELETE]
+
++ Response 204
+`
+
+func TestCreateDefinitionFromBlueprint(t *testing.T) {
+	doc, err := ParseBlueprint([]byte(notesBlueprint))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ad, err := CreateDefinitionFromBlueprint(doc, "org1", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ad.Name != "Notes API" || ad.Proxy.ListenPath != "/notes-api/" || ad.Proxy.TargetURL != "https://notes.example.com/api" {
+		t.Fatalf("Unexpected definition: %v %+v", ad.Name, ad.Proxy)
+	}
+
+	wl := ad.VersionData.Versions["v1"].ExtendedPaths.WhiteList
+	if len(wl) != 2 || wl[0].Path != "/notes" || wl[1].Path != "/notes/{id}" {
+		t.Fatalf("Expected the resources to be white listed, got: %+v", wl)
+	}
+
+	list := wl[0].MethodActions["GET"]
+	if list.Code != 200 || list.Data != `[{"id": 1}]` || list.Headers["X-Total"] != "2" {
+		t.Errorf("Expected the first response example, got: %+v", list)
+	}
+	if wl[0].MethodActions["POST"].Code != 201 || wl[1].MethodActions["DELETE"].Code != 204 {
+		t.Errorf("Unexpected actions: %+v", wl)
+	}
+
+	if _, err := CreateDefinitionFromBlueprint(&BlueprintDocument{Name: "Empty"}, "org1", ""); err == nil {
+		t.Error("Expected a blueprint without actions to fail")
+	}
+}
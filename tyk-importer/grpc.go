@@ -0,0 +1,122 @@
+package tyk_importer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+	uuid "github.com/satori/go.uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// DefaultGRPCTarget is the upstream of imported gRPC services, descriptors don't name the
+// hosts serving them so it is meant to be overridden with override_target. h2c is HTTP/2
+// without TLS, use https:// for upstreams serving TLS.
+const DefaultGRPCTarget = "h2c://localhost:50051"
+
+// GRPCMethod is a method of a gRPC service, clients call it with a POST to Path, e.g.
+// /helloworld.Greeter/SayHello
+type GRPCMethod struct {
+	Name            string
+	Path            string
+	ClientStreaming bool
+	ServerStreaming bool
+}
+
+// GRPCService is a gRPC service of a descriptor set
+type GRPCService struct {
+	// FullName is the service name qualified with its package, e.g. helloworld.Greeter
+	FullName string
+	Methods  []GRPCMethod
+}
+
+// ParseDescriptorSet lists the services of a compiled protobuf descriptor set, as written by
+// protoc --descriptor_set_out (-o)
+func ParseDescriptorSet(raw []byte) ([]GRPCService, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(raw, set); err != nil {
+		return nil, fmt.Errorf("not a protobuf descriptor set: %v", err)
+	}
+
+	services := []GRPCService{}
+	for _, file := range set.File {
+		for _, svc := range file.Service {
+			name := svc.GetName()
+			if pkg := file.GetPackage(); pkg != "" {
+				name = pkg + "." + name
+			}
+
+			s := GRPCService{FullName: name}
+			for _, m := range svc.Method {
+				s.Methods = append(s.Methods, GRPCMethod{
+					Name:            m.GetName(),
+					Path:            "/" + name + "/" + m.GetName(),
+					ClientStreaming: m.GetClientStreaming(),
+					ServerStreaming: m.GetServerStreaming(),
+				})
+			}
+			services = append(services, s)
+		}
+	}
+
+	if len(services) == 0 {
+		return nil, errors.New("no services defined in the descriptor set")
+	}
+
+	return services, nil
+}
+
+// GRPCServiceID derives the API ID of a gRPC service from its full name
+func GRPCServiceID(fullName string) string {
+	return strings.Replace(uuid.NewV5(uuid.NamespaceOID, "grpc:"+fullName).String(), "-", "", -1)
+}
+
+// CreateDefinitionFromGRPC builds a definition proxying a gRPC service over HTTP/2. The
+// listen path is the path of the service, which is not stripped as upstreams route on it,
+// and only its methods are white listed. The gateway must run with enable_http2 and
+// proxy_enable_http2 to proxy gRPC.
+//
+// The gateway matches white list paths as unanchored regular expressions after the listen
+// path, the method paths are anchored so that allowing SayHello doesn't allow SayHelloAgain.
+func CreateDefinitionFromGRPC(svc GRPCService, orgId string, versionName string) (*objects.DBApiDefinition, error) {
+	if len(svc.Methods) == 0 {
+		return nil, fmt.Errorf("no methods defined in %v", svc.FullName)
+	}
+
+	ad := newDefinition(svc.FullName, orgId)
+	// Every import of the service gets the same ID, so syncs update the API it created
+	ad.APIID = GRPCServiceID(svc.FullName)
+	ad.Slug = slugify(svc.FullName)
+	ad.Proxy.ListenPath = "/" + svc.FullName + "/"
+	ad.Proxy.StripListenPath = false
+	ad.Proxy.TargetURL = DefaultGRPCTarget
+
+	e := endpoints{}
+	for _, m := range svc.Methods {
+		e.add("^/"+regexp.QuoteMeta(m.Name)+"$", "POST", apidef.EndpointMethodMeta{})
+	}
+
+	if err := e.setVersion(ad, versionName); err != nil {
+		return nil, err
+	}
+
+	return ad, nil
+}
+
+// CreateDefinitionsFromGRPC builds a definition for every service of a descriptor set
+func CreateDefinitionsFromGRPC(services []GRPCService, orgId string, versionName string) ([]objects.DBApiDefinition, error) {
+	defs := []objects.DBApiDefinition{}
+	for _, svc := range services {
+		ad, err := CreateDefinitionFromGRPC(svc, orgId, versionName)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, *ad)
+	}
+
+	return defs, nil
+}
@@ -0,0 +1,70 @@
+package tyk_importer
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func greeterDescriptorSet(t *testing.T) []byte {
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		{Name: proto.String("google/protobuf/empty.proto"), Package: proto.String("google.protobuf")},
+		{
+			Name:    proto.String("helloworld.proto"),
+			Package: proto.String("helloworld"),
+			Service: []*descriptorpb.ServiceDescriptorProto{{
+				Name: proto.String("Greeter"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{Name: proto.String("SayHello"), InputType: proto.String(".helloworld.HelloRequest"), OutputType: proto.String(".helloworld.HelloReply")},
+					{Name: proto.String("SayHelloStream"), ServerStreaming: proto.Bool(true)},
+				},
+			}},
+		},
+	}}
+
+	raw, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestCreateDefinitionFromGRPC(t *testing.T) {
+	services, err := ParseDescriptorSet(greeterDescriptorSet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(services) != 1 || services[0].FullName != "helloworld.Greeter" || len(services[0].Methods) != 2 {
+		t.Fatalf("Expected the Greeter service with two methods, got: %+v", services)
+	}
+	if m := services[0].Methods[1]; m.Path != "/helloworld.Greeter/SayHelloStream" || !m.ServerStreaming {
+		t.Fatalf("Unexpected method: %+v", m)
+	}
+
+	defs, err := CreateDefinitionsFromGRPC(services, "org1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ad := defs[0]
+	if ad.Proxy.ListenPath != "/helloworld.Greeter/" || ad.Proxy.StripListenPath || ad.Proxy.TargetURL != DefaultGRPCTarget {
+		t.Fatalf("Unexpected proxy settings: %+v", ad.Proxy)
+	}
+	if ad.APIID != GRPCServiceID("helloworld.Greeter") || ad.OrgID != "org1" {
+		t.Fatalf("Expected the ID derived from the service, got %v", ad.APIID)
+	}
+
+	wl := ad.VersionData.Versions["Default"].ExtendedPaths.WhiteList
+	if len(wl) != 2 || wl[0].Path != "^/SayHello$" || wl[1].Path != "^/SayHelloStream$" {
+		t.Fatalf("Expected the methods to be white listed, got: %+v", wl)
+	}
+	if _, ok := wl[0].MethodActions["POST"]; !ok {
+		t.Fatalf("Expected the methods to allow POST, got: %+v", wl[0].MethodActions)
+	}
+
+	if _, err := ParseDescriptorSet([]byte("syntax = \"proto3\";")); err == nil {
+		t.Fatal("Expected an error for a .proto source file")
+	}
+}
@@ -0,0 +1,133 @@
+package tyk_importer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	slugCleaner = regexp.MustCompile("[^a-z0-9]+")
+	// :id (postman) and {{id}} (postman variables) both become Tyk's {id}
+	colonParam  = regexp.MustCompile(`(^|/):([A-Za-z0-9_]+)`)
+	doubleBrace = regexp.MustCompile(`\{\{([^}]+)\}\}`)
+)
+
+func slugify(name string) string {
+	return strings.Trim(slugCleaner.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// tykPath converts a path template from an API description into a Tyk endpoint path
+func tykPath(p string) string {
+	p = doubleBrace.ReplaceAllString(p, "{$1}")
+	p = colonParam.ReplaceAllString(p, "$1{$2}")
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+
+	return p
+}
+
+// newDefinition returns a keyless skeleton definition, empty lists are set because
+// the dashboard schema sometimes rejects null values
+func newDefinition(name, orgID string) *objects.DBApiDefinition {
+	def := &apidef.APIDefinition{
+		Name:               name,
+		Active:             true,
+		UseKeylessAccess:   true,
+		APIID:              strings.Replace(uuid.NewV4().String(), "-", "", -1),
+		OrgID:              orgID,
+		ConfigData:         map[string]interface{}{},
+		ResponseProcessors: make([]apidef.ResponseProcessor, 0),
+		AllowedIPs:         make([]string, 0),
+		Tags:               make([]string, 0),
+		CustomMiddleware: apidef.MiddlewareSection{
+			Pre:  make([]apidef.MiddlewareDefinition, 0),
+			Post: make([]apidef.MiddlewareDefinition, 0),
+		},
+	}
+
+	slug := slugify(name)
+	if slug == "" {
+		slug = def.APIID
+	}
+
+	def.Slug = slug
+	def.Proxy.ListenPath = fmt.Sprintf("/%v/", slug)
+	def.Proxy.StripListenPath = true
+	def.VersionDefinition.Key = "version"
+	def.VersionDefinition.Location = "header"
+	def.VersionData.Versions = make(map[string]apidef.VersionInfo)
+
+	return &objects.DBApiDefinition{APIDefinition: def}
+}
+
+// endpoints collects example based white list entries keyed by path
+type endpoints map[string]map[string]apidef.EndpointMethodMeta
+
+func (e endpoints) add(path, method string, meta apidef.EndpointMethodMeta) {
+	if e[path] == nil {
+		e[path] = map[string]apidef.EndpointMethodMeta{}
+	}
+
+	method = strings.ToUpper(method)
+	if _, exists := e[path][method]; exists {
+		// Keep the first example for each method
+		return
+	}
+
+	if meta.Action == "" {
+		meta.Action = apidef.NoAction
+	}
+
+	if meta.Code == 0 {
+		meta.Code = 200
+	}
+
+	if meta.Headers == nil {
+		meta.Headers = map[string]string{}
+	}
+
+	e[path][method] = meta
+}
+
+// setVersion adds a white listed version built from the collected endpoints to the definition
+func (e endpoints) setVersion(def *objects.DBApiDefinition, versionName string) error {
+	if len(e) == 0 {
+		return fmt.Errorf("no paths defined in %v", def.Name)
+	}
+
+	paths := make([]string, 0, len(e))
+	for p := range e {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	version := apidef.VersionInfo{Name: versionName}
+	version.Paths.Ignored = make([]string, 0)
+	version.Paths.WhiteList = make([]string, 0)
+	version.Paths.BlackList = make([]string, 0)
+	version.UseExtendedPaths = true
+	version.ExtendedPaths.WhiteList = make([]apidef.EndPointMeta, 0, len(paths))
+
+	for _, p := range paths {
+		version.ExtendedPaths.WhiteList = append(version.ExtendedPaths.WhiteList, apidef.EndPointMeta{
+			Path:          p,
+			MethodActions: e[p],
+		})
+	}
+
+	if versionName == "" {
+		version.Name = "Default"
+		def.VersionData.NotVersioned = true
+	}
+
+	def.VersionData.DefaultVersion = version.Name
+	def.VersionData.Versions[version.Name] = version
+	return nil
+}
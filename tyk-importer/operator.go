@@ -0,0 +1,423 @@
+package tyk_importer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	OperatorAPIVersion   = "tyk.tyk.io/v1alpha1"
+	OperatorKindAPI      = "ApiDefinition"
+	OperatorKindPolicy   = "SecurityPolicy"
+	operatorDefaultSpace = "default"
+)
+
+type OperatorMetadata struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// OperatorResource is a Tyk Operator custom resource, Spec holds the API definition or
+// policy in the format of the Tyk APIs
+type OperatorResource struct {
+	APIVersion string                 `json:"apiVersion"`
+	Kind       string                 `json:"kind"`
+	Metadata   OperatorMetadata       `json:"metadata"`
+	Spec       map[string]interface{} `json:"spec"`
+}
+
+// OperatorAccessRight is an entry of access_rights_array, pointing to an ApiDefinition
+// resource instead of an API ID
+type OperatorAccessRight struct {
+	objects.AccessDefinition
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+func (m OperatorMetadata) key() string {
+	ns := m.Namespace
+	if ns == "" {
+		ns = operatorDefaultSpace
+	}
+
+	return ns + "/" + m.Name
+}
+
+// OperatorID derives the ID of a resource that doesn't set one from its namespace and
+// name, so a resource always gets the same ID
+func OperatorID(namespace, name string) string {
+	if namespace == "" {
+		namespace = operatorDefaultSpace
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(namespace + "/" + name))
+}
+
+// jsonValue turns the maps decoded by yaml into ones encoding/json can encode
+func jsonValue(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported key %v, keys must be strings", k)
+			}
+
+			conv, err := jsonValue(val)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = conv
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			conv, err := jsonValue(val)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = conv
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// YAMLToJSON converts a YAML document, e.g. an API definition or policy written in YAML, to
+// JSON
+func YAMLToJSON(raw []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	conv, err := jsonValue(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(conv)
+}
+
+// ParseOperatorResources reads the resources of a YAML file, which may hold several
+// documents. Documents of other kinds, e.g. Kubernetes services, are skipped.
+func ParseOperatorResources(raw []byte) ([]OperatorResource, error) {
+	resources := []OperatorResource{}
+
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	for {
+		var doc interface{}
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			continue
+		}
+
+		conv, err := jsonValue(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		asJSON, err := json.Marshal(conv)
+		if err != nil {
+			return nil, err
+		}
+
+		r := OperatorResource{}
+		if err := json.Unmarshal(asJSON, &r); err != nil {
+			return nil, err
+		}
+
+		if r.Kind != OperatorKindAPI && r.Kind != OperatorKindPolicy {
+			continue
+		}
+		if r.Metadata.Name == "" {
+			return nil, fmt.Errorf("%v has no metadata.name", r.Kind)
+		}
+
+		resources = append(resources, r)
+	}
+
+	return resources, nil
+}
+
+func decodeSpec(spec map[string]interface{}, v interface{}) error {
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, v)
+}
+
+// CreateDefinitionsFromOperator converts ApiDefinition and SecurityPolicy resources. The
+// access rights of policies are resolved against the API resources given.
+func CreateDefinitionsFromOperator(resources []OperatorResource) ([]objects.DBApiDefinition, []objects.Policy, error) {
+	defs := []objects.DBApiDefinition{}
+	apiIDs := map[string]string{}
+	apiNames := map[string]string{}
+
+	for _, r := range resources {
+		if r.Kind != OperatorKindAPI {
+			continue
+		}
+
+		def := &apidef.APIDefinition{}
+		if err := decodeSpec(r.Spec, def); err != nil {
+			return nil, nil, fmt.Errorf("ApiDefinition %v: %v", r.Metadata.Name, err)
+		}
+
+		if def.APIID == "" {
+			def.APIID = OperatorID(r.Metadata.Namespace, r.Metadata.Name)
+		}
+		if def.Name == "" {
+			def.Name = r.Metadata.Name
+		}
+
+		apiIDs[r.Metadata.key()] = def.APIID
+		apiNames[r.Metadata.key()] = def.Name
+
+		ad := objects.DBApiDefinition{APIDefinition: def}
+		// Keep the fields of newer Tyk versions, like the other definitions read from git
+		if err := ad.KeepRaw(mustJSON(r.Spec)); err != nil {
+			return nil, nil, err
+		}
+		defs = append(defs, ad)
+	}
+
+	pols := []objects.Policy{}
+	for _, r := range resources {
+		if r.Kind != OperatorKindPolicy {
+			continue
+		}
+
+		var rights struct {
+			AccessRightsArray []OperatorAccessRight `json:"access_rights_array"`
+		}
+		if err := decodeSpec(r.Spec, &rights); err != nil {
+			return nil, nil, fmt.Errorf("SecurityPolicy %v: %v", r.Metadata.Name, err)
+		}
+
+		spec := map[string]interface{}{}
+		for k, v := range r.Spec {
+			if k != "access_rights_array" && k != "_id" {
+				spec[k] = v
+			}
+		}
+
+		pol := objects.Policy{}
+		if err := decodeSpec(spec, &pol); err != nil {
+			return nil, nil, fmt.Errorf("SecurityPolicy %v: %v", r.Metadata.Name, err)
+		}
+
+		if pol.ID == "" {
+			pol.ID = OperatorID(r.Metadata.Namespace, r.Metadata.Name)
+		}
+		if pol.Name == "" {
+			pol.Name = r.Metadata.Name
+		}
+
+		if pol.AccessRights == nil {
+			pol.AccessRights = map[string]objects.AccessDefinition{}
+		}
+		for _, ar := range rights.AccessRightsArray {
+			ns := ar.Namespace
+			if ns == "" {
+				ns = r.Metadata.Namespace
+			}
+			key := OperatorMetadata{Name: ar.Name, Namespace: ns}.key()
+
+			id, ok := apiIDs[key]
+			if !ok {
+				return nil, nil, fmt.Errorf("SecurityPolicy %v: ApiDefinition %v not found", r.Metadata.Name, key)
+			}
+
+			access := ar.AccessDefinition
+			access.APIID = id
+			access.APIName = apiNames[key]
+			if len(access.Versions) == 0 {
+				access.Versions = []string{"Default"}
+			}
+			pol.AccessRights[id] = access
+		}
+
+		pols = append(pols, pol)
+	}
+
+	if len(defs) == 0 && len(pols) == 0 {
+		return nil, nil, errors.New("no ApiDefinition or SecurityPolicy resources found")
+	}
+
+	return defs, pols, nil
+}
+
+func mustJSON(v interface{}) []byte {
+	raw, _ := json.Marshal(v)
+	return raw
+}
+
+// OperatorName turns a name into a valid Kubernetes resource name: lower case letters,
+// digits and '-', at most 63 characters
+func OperatorName(name string) string {
+	out := []rune{}
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			out = append(out, r)
+			dash = false
+			continue
+		}
+		if !dash && len(out) > 0 {
+			out = append(out, '-')
+			dash = true
+		}
+	}
+
+	if len(out) > 63 {
+		out = out[:63]
+	}
+
+	return strings.Trim(string(out), "-")
+}
+
+// toSpec turns an API definition or policy into the map used as the spec of a resource
+func toSpec(v interface{}, drop ...string) (map[string]interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, err
+	}
+
+	for _, k := range drop {
+		delete(spec, k)
+	}
+
+	return spec, nil
+}
+
+// CreateOperatorResources converts APIs and policies into ApiDefinition and SecurityPolicy
+// resources in namespace. Access rights of policies that point to the given APIs are written
+// to access_rights_array, the returned warnings list those that don't.
+func CreateOperatorResources(defs []objects.DBApiDefinition, pols []objects.Policy, namespace string) ([]OperatorResource, []string, error) {
+	resources := []OperatorResource{}
+	warnings := []string{}
+	used := map[string]bool{}
+	apiNames := map[string]string{}
+
+	name := func(kind, base, id string) string {
+		n := OperatorName(base)
+		if n == "" {
+			n = OperatorName(id)
+		}
+		if n == "" {
+			n = strings.ToLower(kind)
+		}
+
+		unique := n
+		for i := 2; used[kind+"/"+unique]; i++ {
+			unique = fmt.Sprintf("%v-%v", n, i)
+		}
+		used[kind+"/"+unique] = true
+
+		return unique
+	}
+
+	for i := range defs {
+		payload, err := defs[i].DefinitionPayload()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// The database ID is specific to the dashboard the API was dumped from
+		spec, err := toSpec(payload, "id")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		meta := OperatorMetadata{Name: name(OperatorKindAPI, defs[i].Name, defs[i].APIID), Namespace: namespace}
+		apiNames[defs[i].APIID] = meta.Name
+		resources = append(resources, OperatorResource{
+			APIVersion: OperatorAPIVersion,
+			Kind:       OperatorKindAPI,
+			Metadata:   meta,
+			Spec:       spec,
+		})
+	}
+
+	for _, pol := range pols {
+		spec, err := toSpec(pol, "_id", "access_rights")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		unresolved := map[string]objects.AccessDefinition{}
+		rights := []interface{}{}
+		for id, access := range pol.AccessRights {
+			apiName, ok := apiNames[id]
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("policy %v: API %v is not part of the export, its access rights are kept in access_rights", pol.Name, id))
+				unresolved[id] = access
+				continue
+			}
+
+			entry, err := toSpec(access, "api_id", "api_name")
+			if err != nil {
+				return nil, nil, err
+			}
+			entry["name"] = apiName
+			entry["namespace"] = namespace
+			rights = append(rights, entry)
+		}
+
+		if len(rights) > 0 {
+			spec["access_rights_array"] = rights
+		}
+		if len(unresolved) > 0 {
+			asMap, err := toSpec(unresolved)
+			if err != nil {
+				return nil, nil, err
+			}
+			spec["access_rights"] = asMap
+		}
+
+		resources = append(resources, OperatorResource{
+			APIVersion: OperatorAPIVersion,
+			Kind:       OperatorKindPolicy,
+			Metadata:   OperatorMetadata{Name: name(OperatorKindPolicy, pol.Name, pol.ID), Namespace: namespace},
+			Spec:       spec,
+		})
+	}
+
+	return resources, warnings, nil
+}
+
+// MarshalOperatorResource encodes a resource as a YAML document
+func MarshalOperatorResource(r OperatorResource) ([]byte, error) {
+	doc, err := toSpec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(doc)
+}
@@ -0,0 +1,155 @@
+package tyk_importer
+
+import (
+	"testing"
+)
+
+const operatorYAML = `apiVersion: v1
+kind: Service
+metadata:
+  name: httpbin
+---
+apiVersion: tyk.tyk.io/v1alpha1
+kind: ApiDefinition
+metadata:
+  name: httpbin
+  namespace: apps
+spec:
+  name: httpbin
+  use_keyless: false
+  use_standard_auth: true
+  active: true
+  graphql:
+    enabled: false
+  proxy:
+    target_url: http://httpbin.apps.svc:8000
+    listen_path: /httpbin
+    strip_listen_path: true
+---
+apiVersion: tyk.tyk.io/v1alpha1
+kind: SecurityPolicy
+metadata:
+  name: httpbin-policy
+  namespace: apps
+spec:
+  name: Httpbin policy
+  active: true
+  rate: 10
+  per: 60
+  access_rights_array:
+    - name: httpbin
+      versions: [Default]
+`
+
+func TestCreateDefinitionsFromOperator(t *testing.T) {
+	resources, err := ParseOperatorResources([]byte(operatorYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 2 {
+		t.Fatalf("expected the Service to be skipped, got %v resources", len(resources))
+	}
+
+	defs, pols, err := CreateDefinitionsFromOperator(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(defs) != 1 || len(pols) != 1 {
+		t.Fatalf("expected 1 API and 1 policy, got %v and %v", len(defs), len(pols))
+	}
+
+	def := defs[0]
+	if def.APIID != OperatorID("apps", "httpbin") {
+		t.Fatalf("unexpected API ID %v", def.APIID)
+	}
+	if def.Proxy.ListenPath != "/httpbin" || !def.UseStandardAuth {
+		t.Fatalf("definition not converted: %+v", def.Proxy)
+	}
+	if _, ok := def.Passthrough["graphql"]; !ok {
+		t.Fatal("fields of newer versions should be kept")
+	}
+
+	pol := pols[0]
+	if pol.ID != OperatorID("apps", "httpbin-policy") || pol.Rate != 10 {
+		t.Fatalf("policy not converted: %+v", pol)
+	}
+	access, ok := pol.AccessRights[def.APIID]
+	if !ok || access.APIName != "httpbin" || access.Versions[0] != "Default" {
+		t.Fatalf("access rights not resolved: %+v", pol.AccessRights)
+	}
+
+	resources[1].Spec["access_rights_array"] = []interface{}{map[string]interface{}{"name": "missing"}}
+	if _, _, err := CreateDefinitionsFromOperator(resources); err == nil {
+		t.Fatal("expected an error for a policy pointing to an unknown API")
+	}
+}
+
+func TestOperatorName(t *testing.T) {
+	cases := map[string]string{
+		"Users API":       "users-api",
+		"  --Payments v2": "payments-v2",
+		"ünïcode/Path_x":  "n-code-path-x",
+		"!!!":             "",
+	}
+
+	for in, want := range cases {
+		if got := OperatorName(in); got != want {
+			t.Errorf("OperatorName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestOperatorRoundTrip(t *testing.T) {
+	resources, err := ParseOperatorResources([]byte(operatorYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, pols, err := CreateDefinitionsFromOperator(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A policy with access to an API that is not exported
+	pols[0].AccessRights["other"] = pols[0].AccessRights[defs[0].APIID]
+
+	exported, warnings, err := CreateOperatorResources(defs, pols, "apps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected a warning for the API that is not exported: %v", warnings)
+	}
+
+	yamlDocs := ""
+	for _, r := range exported {
+		if _, ok := r.Spec["id"]; ok && r.Kind == OperatorKindAPI {
+			t.Fatal("the database ID of APIs should not be exported")
+		}
+
+		out, err := MarshalOperatorResource(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		yamlDocs += "---\n" + string(out)
+	}
+
+	parsed, err := ParseOperatorResources([]byte(yamlDocs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs2, pols2, err := CreateDefinitionsFromOperator(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if defs2[0].APIID != defs[0].APIID || defs2[0].Proxy.TargetURL != defs[0].Proxy.TargetURL {
+		t.Fatalf("API changed in the round trip: %+v", defs2[0].Proxy)
+	}
+	if pols2[0].ID != pols[0].ID || len(pols2[0].AccessRights) != 2 {
+		t.Fatalf("policy changed in the round trip: %+v", pols2[0])
+	}
+	if pols2[0].AccessRights[defs[0].APIID].APIName != "httpbin" {
+		t.Fatalf("access rights not resolved: %+v", pols2[0].AccessRights)
+	}
+}
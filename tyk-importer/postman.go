@@ -0,0 +1,212 @@
+package tyk_importer
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+type PostmanKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// PostmanURL is either a plain string or an object in the collection format
+type PostmanURL struct {
+	Raw  string   `json:"raw"`
+	Host []string `json:"host"`
+	Path []string `json:"path"`
+}
+
+func (u *PostmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		u.Raw = raw
+		return nil
+	}
+
+	var obj struct {
+		Raw  string            `json:"raw"`
+		Host json.RawMessage   `json:"host"`
+		Path []json.RawMessage `json:"path"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+
+	u.Raw = obj.Raw
+	for _, p := range obj.Path {
+		var s string
+		if err := json.Unmarshal(p, &s); err == nil {
+			u.Path = append(u.Path, s)
+			continue
+		}
+
+		var seg struct {
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(p, &seg); err == nil {
+			u.Path = append(u.Path, seg.Value)
+		}
+	}
+
+	return nil
+}
+
+type PostmanRequest struct {
+	Method string            `json:"method"`
+	URL    PostmanURL        `json:"url"`
+	Header []PostmanKeyValue `json:"header"`
+}
+
+// UnmarshalJSON supports requests that are only a URL string
+func (r *PostmanRequest) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		r.Method = "GET"
+		r.URL.Raw = raw
+		return nil
+	}
+
+	type plain PostmanRequest
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+type PostmanResponse struct {
+	Name   string            `json:"name"`
+	Code   int               `json:"code"`
+	Header []PostmanKeyValue `json:"header"`
+	Body   string            `json:"body"`
+}
+
+// PostmanItem is a request or a folder of further items
+type PostmanItem struct {
+	Name     string            `json:"name"`
+	Item     []PostmanItem     `json:"item"`
+	Request  *PostmanRequest   `json:"request"`
+	Response []PostmanResponse `json:"response"`
+}
+
+// PostmanCollection is the subset of a Postman v2.0/v2.1 collection needed to build a definition
+type PostmanCollection struct {
+	Info struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Schema      string `json:"schema"`
+	} `json:"info"`
+	Item     []PostmanItem     `json:"item"`
+	Variable []PostmanKeyValue `json:"variable"`
+}
+
+func (c *PostmanCollection) expand(s string) string {
+	for _, v := range c.Variable {
+		s = strings.Replace(s, "{{"+v.Key+"}}", v.Value, -1)
+	}
+
+	return s
+}
+
+// leadingVariable is the {{baseUrl}} most collections start their request URLs with
+var leadingVariable = regexp.MustCompile(`^\{\{[^}]+\}\}`)
+
+// splitURL splits a raw request URL, without its query, into the upstream and the path of
+// the request. The upstream is the URL of a leading variable, path included, or the scheme
+// and host.
+func (c *PostmanCollection) splitURL(raw string) (string, string) {
+	if i := strings.IndexAny(raw, "?#"); i >= 0 {
+		raw = raw[:i]
+	}
+
+	if v := leadingVariable.FindString(raw); v != "" {
+		if parsed, err := url.Parse(c.expand(v)); err == nil && parsed.Host != "" {
+			return parsed.Scheme + "://" + parsed.Host + strings.TrimSuffix(parsed.Path, "/"), raw[len(v):]
+		}
+	}
+
+	raw = c.expand(raw)
+	if parsed, err := url.Parse(raw); err == nil && parsed.Host != "" {
+		return parsed.Scheme + "://" + parsed.Host, parsed.Path
+	}
+
+	// No scheme, strip anything before the first slash as the host
+	if i := strings.Index(raw, "/"); i >= 0 {
+		return "", raw[i:]
+	}
+
+	return "", ""
+}
+
+// requestPath returns the path of a request URL without upstream and query
+func (c *PostmanCollection) requestPath(u PostmanURL) string {
+	if len(u.Path) > 0 {
+		return "/" + strings.Join(u.Path, "/")
+	}
+
+	if _, p := c.splitURL(u.Raw); p != "" {
+		return p
+	}
+	return "/"
+}
+
+func (c *PostmanCollection) upstream(u PostmanURL) string {
+	target, _ := c.splitURL(u.Raw)
+	return target
+}
+
+func (c *PostmanCollection) collect(items []PostmanItem, e endpoints, target *string) {
+	for _, item := range items {
+		if len(item.Item) > 0 {
+			c.collect(item.Item, e, target)
+		}
+
+		if item.Request == nil {
+			continue
+		}
+
+		if *target == "" {
+			*target = c.upstream(item.Request.URL)
+		}
+
+		meta := apidef.EndpointMethodMeta{}
+		if len(item.Response) > 0 {
+			example := item.Response[0]
+			meta.Code = example.Code
+			meta.Data = example.Body
+			meta.Headers = map[string]string{}
+			for _, h := range example.Header {
+				meta.Headers[h.Key] = h.Value
+			}
+		}
+
+		method := item.Request.Method
+		if method == "" {
+			method = "GET"
+		}
+
+		e.add(tykPath(c.requestPath(item.Request.URL)), method, meta)
+	}
+}
+
+// CreateDefinitionFromPostman builds a skeleton definition white listing every request in the collection
+func CreateDefinitionFromPostman(c *PostmanCollection, orgId string, versionName string) (*objects.DBApiDefinition, error) {
+	ad := newDefinition(c.Info.Name, orgId)
+
+	e := endpoints{}
+	target := ""
+	c.collect(c.Item, e, &target)
+
+	if target == "" {
+		target = "http://unset.com"
+	}
+	ad.Proxy.TargetURL = target
+
+	if err := e.setVersion(ad, versionName); err != nil {
+		return nil, err
+	}
+
+	return ad, nil
+}
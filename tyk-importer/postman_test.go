@@ -0,0 +1,65 @@
+package tyk_importer
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const petsPostman = `{
+	"info": {"name": "Pets", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+	"variable": [{"key": "baseUrl", "value": "https://api.example.com/v1/"}],
+	"item": [
+		{"name": "Pets", "item": [
+			{"name": "List pets", "request": {"method": "GET", "url": {"raw": "{{baseUrl}}/pets?limit=10", "host": ["{{baseUrl}}"], "path": ["pets"]}},
+			 "response": [{"name": "OK", "code": 200, "header": [{"key": "Content-Type", "value": "application/json"}], "body": "[]"}]},
+			{"name": "Get pet", "request": {"method": "GET", "url": {"raw": "{{baseUrl}}/pets/:petId", "path": ["pets", ":petId"]}}}
+		]},
+		{"name": "Add pet", "request": {"method": "POST", "url": "{{baseUrl}}/pets"}},
+		{"name": "Health", "request": "{{baseUrl}}/health"}
+	]
+}`
+
+func TestCreateDefinitionFromPostman(t *testing.T) {
+	c := &PostmanCollection{}
+	if err := json.Unmarshal([]byte(petsPostman), c); err != nil {
+		t.Fatal(err)
+	}
+
+	ad, err := CreateDefinitionFromPostman(c, "org1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The path of baseUrl is part of the upstream, not of the endpoints
+	if ad.Name != "Pets" || ad.Proxy.ListenPath != "/pets/" || ad.Proxy.TargetURL != "https://api.example.com/v1" {
+		t.Fatalf("Unexpected definition: %v %+v", ad.Name, ad.Proxy)
+	}
+
+	wl := ad.VersionData.Versions["Default"].ExtendedPaths.WhiteList
+	if len(wl) != 3 || wl[0].Path != "/health" || wl[1].Path != "/pets" || wl[2].Path != "/pets/{petId}" {
+		t.Fatalf("Expected the requests to be white listed, got: %+v", wl)
+	}
+
+	list := wl[1].MethodActions["GET"]
+	if list.Code != 200 || list.Data != "[]" || list.Headers["Content-Type"] != "application/json" {
+		t.Errorf("Expected the example response, got: %+v", list)
+	}
+	if _, ok := wl[1].MethodActions["POST"]; !ok {
+		t.Errorf("Expected the methods of a path to be merged, got: %+v", wl[1].MethodActions)
+	}
+}
+
+func TestPostmanSplitURL(t *testing.T) {
+	c := &PostmanCollection{Variable: []PostmanKeyValue{{Key: "host", Value: "localhost:8080"}}}
+
+	for raw, expected := range map[string][2]string{
+		"http://api.example.com/v2/users?page=1": {"http://api.example.com", "/v2/users"},
+		"{{host}}/users/{{id}}":                  {"", "/users/{{id}}"},
+		"api.example.com/users":                  {"", "/users"},
+	} {
+		upstream, p := c.splitURL(raw)
+		if upstream != expected[0] || p != expected[1] {
+			t.Errorf("%v: expected %v, got %v %v", raw, expected, upstream, p)
+		}
+	}
+}
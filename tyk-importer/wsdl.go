@@ -0,0 +1,250 @@
+package tyk_importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+const (
+	soap11Namespace = "http://schemas.xmlsoap.org/wsdl/soap/"
+	soap12Namespace = "http://schemas.xmlsoap.org/wsdl/soap12/"
+)
+
+type wsdlAddress struct {
+	XMLName  xml.Name
+	Location string `xml:"location,attr"`
+}
+
+type wsdlSOAPOperation struct {
+	XMLName    xml.Name
+	SOAPAction string `xml:"soapAction,attr"`
+}
+
+type wsdlBindingOperation struct {
+	Name       string              `xml:"name,attr"`
+	Operations []wsdlSOAPOperation `xml:"operation"`
+}
+
+type wsdlBinding struct {
+	Name       string                 `xml:"name,attr"`
+	Type       string                 `xml:"type,attr"`
+	Operations []wsdlBindingOperation `xml:"operation"`
+}
+
+type wsdlPort struct {
+	Name      string        `xml:"name,attr"`
+	Binding   string        `xml:"binding,attr"`
+	Addresses []wsdlAddress `xml:"address"`
+}
+
+type wsdlService struct {
+	Name  string     `xml:"name,attr"`
+	Ports []wsdlPort `xml:"port"`
+}
+
+// WSDLDocument is the subset of a WSDL 1.1 document needed to build a definition
+type WSDLDocument struct {
+	Name            string        `xml:"name,attr"`
+	TargetNamespace string        `xml:"targetNamespace,attr"`
+	Bindings        []wsdlBinding `xml:"binding"`
+	Services        []wsdlService `xml:"service"`
+}
+
+// WSDLOperation is a SOAP operation exposed by a service port
+type WSDLOperation struct {
+	Name       string
+	SOAPAction string
+	SOAP12     bool
+	Location   string
+}
+
+// ParseWSDL reads a WSDL 1.1 document
+func ParseWSDL(raw []byte) (*WSDLDocument, error) {
+	doc := &WSDLDocument{}
+	if err := xml.Unmarshal(raw, doc); err != nil {
+		return nil, err
+	}
+
+	if len(doc.Services) == 0 {
+		return nil, fmt.Errorf("no services defined in WSDL")
+	}
+
+	return doc, nil
+}
+
+// localName strips the namespace prefix from a qualified name (tns:MyBinding)
+func localName(qname string) string {
+	if i := strings.LastIndex(qname, ":"); i >= 0 {
+		return qname[i+1:]
+	}
+
+	return qname
+}
+
+func (w *WSDLDocument) binding(name string) *wsdlBinding {
+	for i := range w.Bindings {
+		if w.Bindings[i].Name == localName(name) {
+			return &w.Bindings[i]
+		}
+	}
+
+	return nil
+}
+
+// Operations lists the SOAP operations of every service port, an operation offered over
+// both SOAP 1.1 and 1.2 is only listed once, preferring 1.1
+func (w *WSDLDocument) Operations() []WSDLOperation {
+	ops := []WSDLOperation{}
+	seen := map[string]int{}
+
+	for _, svc := range w.Services {
+		for _, port := range svc.Ports {
+			location := ""
+			soap12 := false
+			for _, a := range port.Addresses {
+				if a.XMLName.Space == soap11Namespace || a.XMLName.Space == soap12Namespace {
+					location = a.Location
+					soap12 = a.XMLName.Space == soap12Namespace
+				}
+			}
+
+			// Not a SOAP port (e.g. HTTP bindings)
+			if location == "" {
+				continue
+			}
+
+			b := w.binding(port.Binding)
+			if b == nil {
+				continue
+			}
+
+			for _, bop := range b.Operations {
+				op := WSDLOperation{Name: bop.Name, SOAP12: soap12, Location: location}
+				for _, so := range bop.Operations {
+					if so.XMLName.Space == soap11Namespace || so.XMLName.Space == soap12Namespace {
+						op.SOAPAction = so.SOAPAction
+					}
+				}
+
+				if i, ok := seen[op.Name]; ok {
+					if ops[i].SOAP12 && !op.SOAP12 {
+						ops[i] = op
+					}
+					continue
+				}
+
+				seen[op.Name] = len(ops)
+				ops = append(ops, op)
+			}
+		}
+	}
+
+	return ops
+}
+
+// soapActionTrigger routes a request on the root path to the location of the operation
+// matching its SOAPAction header (1.1) or action content type parameter (1.2)
+func soapActionTrigger(op WSDLOperation) apidef.RoutingTrigger {
+	action := regexp.QuoteMeta(op.SOAPAction)
+	trigger := apidef.RoutingTrigger{
+		On:        apidef.Any,
+		RewriteTo: op.Location,
+	}
+
+	if op.SOAP12 {
+		trigger.Options.HeaderMatches = map[string]apidef.StringRegexMap{
+			"Content-Type": {MatchPattern: `action="?` + action + `"?`},
+		}
+	} else {
+		trigger.Options.HeaderMatches = map[string]apidef.StringRegexMap{
+			"SOAPAction": {MatchPattern: `^"?` + action + `"?$`},
+		}
+	}
+
+	return trigger
+}
+
+// CreateDefinitionFromWSDL builds a definition for the first SOAP service in the document. Clients
+// can either post envelopes to the listen path root and are routed on their SOAP action, or
+// post to /{operation}, which sets the SOAP action and rewrites to the service address
+func CreateDefinitionFromWSDL(w *WSDLDocument, orgId string, versionName string) (*objects.DBApiDefinition, error) {
+	name := w.Services[0].Name
+	if name == "" {
+		name = w.Name
+	}
+
+	ad := newDefinition(name, orgId)
+
+	ops := w.Operations()
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("no SOAP operations defined in %v", name)
+	}
+
+	target, err := url.Parse(ops[0].Location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service address %q: %v", ops[0].Location, err)
+	}
+	ad.Proxy.TargetURL = target.Scheme + "://" + target.Host
+
+	e := endpoints{}
+	rootRewrite := apidef.URLRewriteMeta{
+		Path:         "/",
+		Method:       "POST",
+		MatchPattern: "(.*)",
+		RewriteTo:    ops[0].Location,
+		Triggers:     []apidef.RoutingTrigger{},
+	}
+	rewrites := []apidef.URLRewriteMeta{}
+	headers := []apidef.HeaderInjectionMeta{}
+
+	e.add("/", "POST", apidef.EndpointMethodMeta{})
+	for _, op := range ops {
+		path := "/" + op.Name
+		e.add(path, "POST", apidef.EndpointMethodMeta{})
+
+		rewrites = append(rewrites, apidef.URLRewriteMeta{
+			Path:         path,
+			Method:       "POST",
+			MatchPattern: "(.*)",
+			RewriteTo:    op.Location,
+			Triggers:     []apidef.RoutingTrigger{},
+		})
+
+		if op.SOAPAction == "" {
+			continue
+		}
+
+		rootRewrite.Triggers = append(rootRewrite.Triggers, soapActionTrigger(op))
+
+		inject := apidef.HeaderInjectionMeta{
+			Path:          path,
+			Method:        "POST",
+			DeleteHeaders: []string{},
+			AddHeaders:    map[string]string{"SOAPAction": `"` + op.SOAPAction + `"`},
+		}
+		if op.SOAP12 {
+			inject.AddHeaders = map[string]string{
+				"Content-Type": fmt.Sprintf(`application/soap+xml; charset=utf-8; action="%v"`, op.SOAPAction),
+			}
+		}
+		headers = append(headers, inject)
+	}
+
+	if err := e.setVersion(ad, versionName); err != nil {
+		return nil, err
+	}
+
+	version := ad.VersionData.Versions[ad.VersionData.DefaultVersion]
+	// Tyk uses the first matching rewrite, the root path matches everything so it goes last
+	version.ExtendedPaths.URLRewrite = append(rewrites, rootRewrite)
+	version.ExtendedPaths.TransformHeader = headers
+	ad.VersionData.Versions[ad.VersionData.DefaultVersion] = version
+
+	return ad, nil
+}
@@ -0,0 +1,54 @@
+package tyk_importer
+
+import (
+	"testing"
+)
+
+const stockQuoteWSDL = `<?xml version="1.0"?>
+<definitions name="StockQuote" targetNamespace="http://example.com/stockquote.wsdl"
+  xmlns:tns="http://example.com/stockquote.wsdl" xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/" xmlns="http://schemas.xmlsoap.org/wsdl/">
+  <binding name="StockQuoteSoapBinding" type="tns:StockQuotePortType">
+    <soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <operation name="GetLastTradePrice"><soap:operation soapAction="http://example.com/GetLastTradePrice"/></operation>
+  </binding>
+  <binding name="StockQuoteSoap12Binding" type="tns:StockQuotePortType">
+    <soap12:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <operation name="GetLastTradePrice"><soap12:operation soapAction="http://example.com/GetLastTradePrice"/></operation>
+  </binding>
+  <service name="StockQuoteService">
+    <port name="StockQuotePort12" binding="tns:StockQuoteSoap12Binding"><soap12:address location="http://example.com/stockquote12"/></port>
+    <port name="StockQuotePort" binding="tns:StockQuoteSoapBinding"><soap:address location="http://example.com/stockquote"/></port>
+  </service>
+</definitions>`
+
+func TestCreateDefinitionFromWSDL(t *testing.T) {
+	w, err := ParseWSDL([]byte(stockQuoteWSDL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops := w.Operations()
+	if len(ops) != 1 || ops[0].SOAP12 || ops[0].Location != "http://example.com/stockquote" {
+		t.Fatalf("Expected the SOAP 1.1 operation only, got: %+v", ops)
+	}
+
+	ad, err := CreateDefinitionFromWSDL(w, "org1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ad.Proxy.TargetURL != "http://example.com" || ad.Proxy.ListenPath != "/stockquoteservice/" {
+		t.Fatalf("Unexpected proxy settings: %+v", ad.Proxy)
+	}
+
+	version := ad.VersionData.Versions["Default"]
+	rewrites := version.ExtendedPaths.URLRewrite
+	if len(rewrites) != 2 || rewrites[1].Path != "/" || len(rewrites[1].Triggers) != 1 {
+		t.Fatalf("Expected an operation rewrite and a SOAP action routed root rewrite, got: %+v", rewrites)
+	}
+
+	if h := version.ExtendedPaths.TransformHeader; len(h) != 1 || h[0].AddHeaders["SOAPAction"] != `"http://example.com/GetLastTradePrice"` {
+		t.Fatalf("Expected the SOAPAction header to be injected, got: %+v", h)
+	}
+}
@@ -0,0 +1,268 @@
+package tyk_oas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-diff"
+	"github.com/TykTechnologies/tyk/apidef"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var errMissing = errors.New("the definition is empty")
+
+// ToClassic converts the Tyk OAS definition raw, JSON, into a classic definition for targets
+// that predate Tyk OAS. The OpenAPI description itself, such as schemas and parameters, has
+// no place in classic definitions. It returns the fields of the gateway extension the
+// classic definition doesn't carry, see Lost.
+func ToClassic(raw []byte) (*objects.DBApiDefinition, []tyk_diff.Change, error) {
+	doc := &Document{}
+	if err := json.Unmarshal(raw, doc); err != nil {
+		return nil, nil, err
+	}
+	if doc.Gateway == nil {
+		return nil, nil, fmt.Errorf("not a Tyk OAS definition, it has no %v extension", Extension)
+	}
+
+	def, err := toClassic(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	back, err := toOAS(def)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Compare the extension as written, with the fields tyk-sync doesn't decode
+	orig := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &orig); err != nil {
+		return nil, nil, err
+	}
+	ext, _ := orig[Extension].(map[string]interface{})
+	renameOperations(doc, ext)
+	lost, err := Lost(map[string]interface{}{Extension: ext}, back, []string{"openapi", "info", "paths", "components", "security"})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return def, lost, nil
+}
+
+// renameOperations keys the operations of the raw extension ext by the operationId the
+// gateway gives them, the one a classic definition converts back to, see OperationID
+func renameOperations(doc *Document, ext map[string]interface{}) {
+	mw, _ := ext["middleware"].(map[string]interface{})
+	ops, _ := mw["operations"].(map[string]interface{})
+	if ops == nil {
+		return
+	}
+
+	renamed := map[string]interface{}{}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			o, _ := op.(map[string]interface{})
+			id, _ := o["operationId"].(string)
+			if v, ok := ops[id]; ok && id != "" {
+				renamed[OperationID(path, method)] = v
+				delete(ops, id)
+			}
+		}
+	}
+	// Operations of no path stay lost
+	for id, v := range ops {
+		renamed[id] = v
+	}
+	mw["operations"] = renamed
+}
+
+// sourceConfig is the auth config reading a token from the sources of opts
+func sourceConfig(opts *SchemeOptions) apidef.AuthConfig {
+	cfg := apidef.AuthConfig{AuthHeaderName: "Authorization"}
+	if opts.Header != nil && opts.Header.Name != "" {
+		cfg.AuthHeaderName = opts.Header.Name
+	}
+	if opts.Query != nil && opts.Query.Enabled {
+		cfg.UseParam = true
+		cfg.ParamName = opts.Query.Name
+	}
+	if opts.Cookie != nil && opts.Cookie.Enabled {
+		cfg.UseCookie = true
+		cfg.CookieName = opts.Cookie.Name
+	}
+	return cfg
+}
+
+func toClassic(doc *Document) (*objects.DBApiDefinition, error) {
+	gw := doc.Gateway
+	if gw == nil {
+		return nil, errMissing
+	}
+
+	// Empty lists rather than null values, the dashboard schema rejects some of them
+	a := &apidef.APIDefinition{
+		APIID:              gw.Info.ID,
+		OrgID:              gw.Info.OrgID,
+		Name:               gw.Info.Name,
+		Active:             gw.Info.State.Active,
+		Internal:           gw.Info.State.Internal,
+		ConfigData:         map[string]interface{}{},
+		ResponseProcessors: make([]apidef.ResponseProcessor, 0),
+		AllowedIPs:         make([]string, 0),
+		Tags:               make([]string, 0),
+		AuthConfigs:        map[string]apidef.AuthConfig{},
+		CustomMiddleware: apidef.MiddlewareSection{
+			Pre:  make([]apidef.MiddlewareDefinition, 0),
+			Post: make([]apidef.MiddlewareDefinition, 0),
+		},
+	}
+	if a.Name == "" {
+		a.Name = doc.Info.Title
+	}
+	if gw.Info.DBID != "" {
+		if !bson.IsObjectIdHex(gw.Info.DBID) {
+			return nil, fmt.Errorf("dbId %q is not an object ID", gw.Info.DBID)
+		}
+		a.Id = objects.NormalID(bson.ObjectIdHex(gw.Info.DBID))
+	}
+
+	a.Proxy.TargetURL = gw.Upstream.URL
+	a.Proxy.ListenPath = gw.Server.ListenPath.Value
+	a.Proxy.StripListenPath = gw.Server.ListenPath.Strip
+	if d := gw.Server.CustomDomain; d != nil && d.Enabled {
+		a.Domain = d.Name
+	}
+	if t := gw.Server.GatewayTags; t != nil && t.Enabled {
+		a.Tags = append(a.Tags, t.Tags...)
+	}
+
+	auth := gw.Server.Authentication
+	a.UseKeylessAccess = auth == nil || !auth.Enabled
+	if !a.UseKeylessAccess {
+		a.StripAuthData = auth.StripAuthorizationData
+		enabled := func(name string) *SchemeOptions {
+			if opts := auth.SecuritySchemes[name]; opts != nil && opts.Enabled {
+				return opts
+			}
+			return nil
+		}
+
+		if opts := enabled(SchemeToken); opts != nil {
+			// The header of the OpenAPI security scheme unless the extension names one
+			if opts.Header == nil && doc.Components != nil {
+				if s, ok := doc.Components.SecuritySchemes[SchemeToken]; ok && s.In == "header" {
+					opts.Header = &AuthSource{Enabled: true, Name: s.Name}
+				}
+			}
+			a.UseStandardAuth = true
+			a.Auth = sourceConfig(opts)
+			a.AuthConfigs[SchemeToken] = a.Auth
+		}
+		if opts := enabled(SchemeBasic); opts != nil {
+			a.UseBasicAuth = true
+			a.BasicAuth.DisableCaching = opts.DisableCaching
+			a.BasicAuth.CacheTTL = opts.CacheTTL
+		}
+		if opts := enabled(SchemeJWT); opts != nil {
+			a.EnableJWT = true
+			a.JWTSource = opts.Source
+			a.JWTSigningMethod = opts.SigningMethod
+			a.JWTIdentityBaseField = opts.IdentityBaseField
+			a.JWTPolicyFieldName = opts.PolicyFieldName
+			a.JWTDefaultPolicies = opts.DefaultPolicies
+			a.JWTSkipKid = opts.SkipKid
+			a.JWTIssuedAtValidationSkew = opts.IssuedAtValidationSkew
+			a.JWTNotBeforeValidationSkew = opts.NotBeforeValidationSkew
+			a.JWTExpiresAtValidationSkew = opts.ExpiresAtValidationSkew
+			a.AuthConfigs["jwt"] = sourceConfig(opts)
+		}
+	}
+
+	versionName := doc.Info.Version
+	if versionName == "" || versionName == "1.0.0" {
+		versionName = "Default"
+	}
+	version := apidef.VersionInfo{Name: versionName, UseExtendedPaths: true}
+	version.Paths.Ignored = make([]string, 0)
+	version.Paths.WhiteList = make([]string, 0)
+	version.Paths.BlackList = make([]string, 0)
+	a.VersionData.NotVersioned = true
+	a.VersionData.DefaultVersion = versionName
+	a.VersionData.Versions = map[string]apidef.VersionInfo{versionName: version}
+	a.VersionDefinition.Key = "version"
+	a.VersionDefinition.Location = "header"
+
+	if mw := gw.Middleware; mw != nil {
+		if c := mw.Global; c != nil && c.CORS != nil {
+			a.CORS.Enable = c.CORS.Enabled
+			a.CORS.MaxAge = c.CORS.MaxAge
+			a.CORS.AllowCredentials = c.CORS.AllowCredentials
+			a.CORS.ExposedHeaders = c.CORS.ExposedHeaders
+			a.CORS.AllowedHeaders = c.CORS.AllowedHeaders
+			a.CORS.OptionsPassthrough = c.CORS.OptionsPassthrough
+			a.CORS.Debug = c.CORS.Debug
+			a.CORS.AllowedOrigins = c.CORS.AllowedOrigins
+			a.CORS.AllowedMethods = c.CORS.AllowedMethods
+		}
+
+		white := map[string]map[string]apidef.EndpointMethodMeta{}
+		black := map[string]map[string]apidef.EndpointMethodMeta{}
+		ignored := map[string]map[string]apidef.EndpointMethodMeta{}
+		add := func(list map[string]map[string]apidef.EndpointMethodMeta, path, method string) {
+			if list[path] == nil {
+				list[path] = map[string]apidef.EndpointMethodMeta{}
+			}
+			list[path][method] = apidef.EndpointMethodMeta{Action: apidef.NoAction, Code: 200, Headers: map[string]string{}}
+		}
+
+		for path, item := range doc.Paths {
+			for method, op := range item {
+				o, _ := op.(map[string]interface{})
+				id, _ := o["operationId"].(string)
+				if id == "" {
+					id = OperationID(path, method)
+				}
+				m := mw.Operations[id]
+				if m == nil {
+					continue
+				}
+				method = strings.ToUpper(method)
+				if m.Allow != nil && m.Allow.Enabled {
+					add(white, path, method)
+				}
+				if m.Block != nil && m.Block.Enabled {
+					add(black, path, method)
+				}
+				if m.IgnoreAuthentication != nil && m.IgnoreAuthentication.Enabled {
+					add(ignored, path, method)
+				}
+			}
+		}
+
+		version.ExtendedPaths.WhiteList = endpointList(white)
+		version.ExtendedPaths.BlackList = endpointList(black)
+		version.ExtendedPaths.Ignored = endpointList(ignored)
+		a.VersionData.Versions[versionName] = version
+	}
+
+	return &objects.DBApiDefinition{APIDefinition: a}, nil
+}
+
+// endpointList returns the entries of an extended paths list, sorted by path
+func endpointList(entries map[string]map[string]apidef.EndpointMethodMeta) []apidef.EndPointMeta {
+	paths := make([]string, 0, len(entries))
+	for p := range entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	list := make([]apidef.EndPointMeta, 0, len(paths))
+	for _, p := range paths {
+		list = append(list, apidef.EndPointMeta{Path: p, MethodActions: entries[p]})
+	}
+	return list
+}
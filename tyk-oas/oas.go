@@ -0,0 +1,456 @@
+package tyk_oas
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-diff"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// Extension is the key of the gateway configuration of a Tyk OAS definition
+const Extension = "x-tyk-api-gateway"
+
+// The security schemes of Tyk OAS definitions tyk-sync converts
+const (
+	SchemeToken = "authToken"
+	SchemeBasic = "basicAuth"
+	SchemeJWT   = "jwtAuth"
+)
+
+// Document is a Tyk OAS definition: an OpenAPI 3 document whose x-tyk-api-gateway extension
+// configures the gateway. Only the parts tyk-sync converts are decoded.
+type Document struct {
+	OpenAPI    string                            `json:"openapi"`
+	Info       DocumentInfo                      `json:"info"`
+	Paths      map[string]map[string]interface{} `json:"paths"`
+	Components *Components                       `json:"components,omitempty"`
+	Security   []map[string][]string             `json:"security,omitempty"`
+	Gateway    *Gateway                          `json:"x-tyk-api-gateway"`
+}
+
+type DocumentInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// Gateway is the x-tyk-api-gateway extension
+type Gateway struct {
+	Info       Info        `json:"info"`
+	Upstream   Upstream    `json:"upstream"`
+	Server     Server      `json:"server"`
+	Middleware *Middleware `json:"middleware,omitempty"`
+}
+
+type Info struct {
+	ID    string `json:"id,omitempty"`
+	DBID  string `json:"dbId,omitempty"`
+	OrgID string `json:"orgId,omitempty"`
+	Name  string `json:"name"`
+	State State  `json:"state"`
+}
+
+type State struct {
+	Active   bool `json:"active"`
+	Internal bool `json:"internal,omitempty"`
+}
+
+type Upstream struct {
+	URL string `json:"url"`
+}
+
+type Server struct {
+	ListenPath     ListenPath      `json:"listenPath"`
+	CustomDomain   *CustomDomain   `json:"customDomain,omitempty"`
+	Authentication *Authentication `json:"authentication,omitempty"`
+	GatewayTags    *GatewayTags    `json:"gatewayTags,omitempty"`
+}
+
+type ListenPath struct {
+	Value string `json:"value"`
+	Strip bool   `json:"strip,omitempty"`
+}
+
+type CustomDomain struct {
+	Enabled bool   `json:"enabled"`
+	Name    string `json:"name"`
+}
+
+type GatewayTags struct {
+	Enabled bool     `json:"enabled"`
+	Tags    []string `json:"tags"`
+}
+
+type Authentication struct {
+	Enabled                bool                      `json:"enabled"`
+	StripAuthorizationData bool                      `json:"stripAuthorizationData,omitempty"`
+	SecuritySchemes        map[string]*SchemeOptions `json:"securitySchemes,omitempty"`
+}
+
+// SchemeOptions configure a security scheme, the fields apply to the schemes they are set
+// for: the sources to the token and JWT schemes, the JWT fields to jwtAuth and the basic
+// auth fields to basicAuth
+type SchemeOptions struct {
+	Enabled bool        `json:"enabled"`
+	Header  *AuthSource `json:"header,omitempty"`
+	Query   *AuthSource `json:"query,omitempty"`
+	Cookie  *AuthSource `json:"cookie,omitempty"`
+
+	Source                  string   `json:"source,omitempty"`
+	SigningMethod           string   `json:"signingMethod,omitempty"`
+	IdentityBaseField       string   `json:"identityBaseField,omitempty"`
+	PolicyFieldName         string   `json:"policyFieldName,omitempty"`
+	DefaultPolicies         []string `json:"defaultPolicies,omitempty"`
+	SkipKid                 bool     `json:"skipKid,omitempty"`
+	IssuedAtValidationSkew  uint64   `json:"issuedAtValidationSkew,omitempty"`
+	NotBeforeValidationSkew uint64   `json:"notBeforeValidationSkew,omitempty"`
+	ExpiresAtValidationSkew uint64   `json:"expiresAtValidationSkew,omitempty"`
+
+	DisableCaching bool `json:"disableCaching,omitempty"`
+	CacheTTL       int  `json:"cacheTTL,omitempty"`
+}
+
+type AuthSource struct {
+	Enabled bool   `json:"enabled"`
+	Name    string `json:"name,omitempty"`
+}
+
+type Middleware struct {
+	Global     *Global               `json:"global,omitempty"`
+	Operations map[string]*Operation `json:"operations,omitempty"`
+}
+
+type Global struct {
+	CORS *CORS `json:"cors,omitempty"`
+}
+
+type CORS struct {
+	Enabled            bool     `json:"enabled"`
+	MaxAge             int      `json:"maxAge,omitempty"`
+	AllowCredentials   bool     `json:"allowCredentials,omitempty"`
+	ExposedHeaders     []string `json:"exposedHeaders,omitempty"`
+	AllowedHeaders     []string `json:"allowedHeaders,omitempty"`
+	OptionsPassthrough bool     `json:"optionsPassthrough,omitempty"`
+	Debug              bool     `json:"debug,omitempty"`
+	AllowedOrigins     []string `json:"allowedOrigins,omitempty"`
+	AllowedMethods     []string `json:"allowedMethods,omitempty"`
+}
+
+// Operation is the middleware of an operation of the OpenAPI paths, by operationId
+type Operation struct {
+	Allow                *Toggle `json:"allow,omitempty"`
+	Block                *Toggle `json:"block,omitempty"`
+	IgnoreAuthentication *Toggle `json:"ignoreAuthentication,omitempty"`
+}
+
+type Toggle struct {
+	Enabled bool `json:"enabled"`
+}
+
+// IsTykOAS tells if raw, JSON, is a Tyk OAS definition rather than a classic one
+func IsTykOAS(raw []byte) bool {
+	doc := struct {
+		OpenAPI string          `json:"openapi"`
+		Gateway json.RawMessage `json:"x-tyk-api-gateway"`
+	}{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return false
+	}
+	return doc.OpenAPI != "" && len(doc.Gateway) > 0
+}
+
+// OperationID is the operationId the gateway gives the operation of method on path
+func OperationID(path, method string) string {
+	return strings.TrimPrefix(path, "/") + strings.ToUpper(method)
+}
+
+// defaultVersion returns the version of def whose endpoints are converted, the only version
+// of a definition that isn't versioned
+func defaultVersion(def *apidef.APIDefinition) (string, apidef.VersionInfo, bool) {
+	if v, ok := def.VersionData.Versions[def.VersionData.DefaultVersion]; ok {
+		return def.VersionData.DefaultVersion, v, true
+	}
+	if v, ok := def.VersionData.Versions["Default"]; ok {
+		return "Default", v, true
+	}
+	for name, v := range def.VersionData.Versions {
+		if len(def.VersionData.Versions) == 1 {
+			return name, v, true
+		}
+	}
+	return "", apidef.VersionInfo{}, false
+}
+
+// authSources are the places a token is read from by cfg
+func authSources(cfg apidef.AuthConfig, opts *SchemeOptions) {
+	name := cfg.AuthHeaderName
+	if name == "" {
+		name = "Authorization"
+	}
+	opts.Header = &AuthSource{Enabled: true, Name: name}
+	if cfg.UseParam {
+		opts.Query = &AuthSource{Enabled: true, Name: cfg.ParamName}
+	}
+	if cfg.UseCookie {
+		opts.Cookie = &AuthSource{Enabled: true, Name: cfg.CookieName}
+	}
+}
+
+// usesToken tells if def authenticates requests with keys, the default of definitions that
+// aren't keyless
+func usesToken(def *apidef.APIDefinition) bool {
+	return def.UseStandardAuth || (!def.UseKeylessAccess && !def.UseBasicAuth && !def.EnableJWT && !def.UseOauth2 && !def.UseOpenID &&
+		!def.EnableCoProcessAuth && !def.UseGoPluginAuth && !def.EnableSignatureChecking && !def.UseMutualTLSAuth)
+}
+
+// withAuthDefaults returns def with the header the gateway reads credentials from by
+// default set where it isn't
+func withAuthDefaults(def apidef.APIDefinition) apidef.APIDefinition {
+	if def.Auth.AuthHeaderName == "" {
+		def.Auth.AuthHeaderName = "Authorization"
+	}
+
+	configs := map[string]apidef.AuthConfig{}
+	for key, cfg := range def.AuthConfigs {
+		if cfg.AuthHeaderName == "" {
+			cfg.AuthHeaderName = "Authorization"
+		}
+		configs[key] = cfg
+	}
+	def.AuthConfigs = configs
+
+	return def
+}
+
+// authConfig returns the auth config of the classic definition for key, falling back to
+// the deprecated auth section for the token
+func authConfig(def *apidef.APIDefinition, key string) apidef.AuthConfig {
+	if cfg, ok := def.AuthConfigs[key]; ok {
+		return cfg
+	}
+	if key == SchemeToken {
+		return def.Auth
+	}
+	return apidef.AuthConfig{}
+}
+
+// ToOAS converts the classic definition def into a Tyk OAS definition. The endpoints of its
+// default version become the paths of the document, with the allow, block and ignore
+// authentication middleware of its white, black and ignored lists. It returns the fields
+// of def the Tyk OAS definition doesn't carry, see Lost.
+func ToOAS(def *objects.DBApiDefinition) (*Document, []tyk_diff.Change, error) {
+	doc, err := toOAS(def)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	back, err := toClassic(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ignore := []string{
+		// The dashboard derives it from the name
+		"slug",
+		"version_data.versions.*.extended_paths.white_list[]",
+		"version_data.versions.*.extended_paths.black_list[]",
+		"version_data.versions.*.extended_paths.ignored[]",
+	}
+	if def.VersionData.NotVersioned {
+		// Only versioned definitions read them
+		ignore = append(ignore, "definition", "version_data.default_version")
+	}
+	a := def.APIDefinition
+	if _, ok := a.AuthConfigs[SchemeToken]; ok || !usesToken(a) {
+		// The deprecated section is only read for keys without the token config
+		ignore = append(ignore, "auth")
+	}
+	for key, used := range map[string]bool{
+		SchemeToken: usesToken(a),
+		"jwt":       a.EnableJWT,
+		"basic":     a.UseBasicAuth,
+		"hmac":      a.EnableSignatureChecking,
+		"coprocess": a.EnableCoProcessAuth,
+		"oauth":     a.UseOauth2,
+		"oidc":      a.UseOpenID,
+	} {
+		if !used {
+			ignore = append(ignore, "auth_configs."+key)
+		}
+	}
+
+	lost, err := Lost(withAuthDefaults(*a), back.APIDefinition, ignore)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return doc, lost, nil
+}
+
+func toOAS(def *objects.DBApiDefinition) (*Document, error) {
+	a := def.APIDefinition
+	if a == nil {
+		return nil, errMissing
+	}
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    DocumentInfo{Title: a.Name, Version: "1.0.0"},
+		Paths:   map[string]map[string]interface{}{},
+	}
+	gw := &Gateway{
+		Info: Info{
+			ID:    a.APIID,
+			OrgID: a.OrgID,
+			Name:  a.Name,
+			State: State{Active: a.Active, Internal: a.Internal},
+		},
+		Upstream: Upstream{URL: a.Proxy.TargetURL},
+		Server: Server{
+			ListenPath: ListenPath{Value: a.Proxy.ListenPath, Strip: a.Proxy.StripListenPath},
+		},
+	}
+	if a.Id != "" {
+		gw.Info.DBID = a.Id.Hex()
+	}
+	if a.Domain != "" {
+		gw.Server.CustomDomain = &CustomDomain{Enabled: true, Name: a.Domain}
+	}
+	if len(a.Tags) > 0 {
+		gw.Server.GatewayTags = &GatewayTags{Enabled: true, Tags: a.Tags}
+	}
+	doc.Gateway = gw
+
+	auth := &Authentication{Enabled: !a.UseKeylessAccess, StripAuthorizationData: a.StripAuthData, SecuritySchemes: map[string]*SchemeOptions{}}
+	schemes := map[string]SecurityScheme{}
+	if usesToken(a) {
+		opts := &SchemeOptions{Enabled: true}
+		authSources(authConfig(a, SchemeToken), opts)
+		auth.SecuritySchemes[SchemeToken] = opts
+		schemes[SchemeToken] = SecurityScheme{Type: "apiKey", In: "header", Name: opts.Header.Name}
+	}
+	if a.UseBasicAuth {
+		auth.SecuritySchemes[SchemeBasic] = &SchemeOptions{Enabled: true, DisableCaching: a.BasicAuth.DisableCaching, CacheTTL: a.BasicAuth.CacheTTL}
+		schemes[SchemeBasic] = SecurityScheme{Type: "http", Scheme: "basic"}
+	}
+	if a.EnableJWT {
+		opts := &SchemeOptions{
+			Enabled:                 true,
+			Source:                  a.JWTSource,
+			SigningMethod:           a.JWTSigningMethod,
+			IdentityBaseField:       a.JWTIdentityBaseField,
+			PolicyFieldName:         a.JWTPolicyFieldName,
+			DefaultPolicies:         a.JWTDefaultPolicies,
+			SkipKid:                 a.JWTSkipKid,
+			IssuedAtValidationSkew:  a.JWTIssuedAtValidationSkew,
+			NotBeforeValidationSkew: a.JWTNotBeforeValidationSkew,
+			ExpiresAtValidationSkew: a.JWTExpiresAtValidationSkew,
+		}
+		authSources(authConfig(a, "jwt"), opts)
+		auth.SecuritySchemes[SchemeJWT] = opts
+		schemes[SchemeJWT] = SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}
+	}
+	if !a.UseKeylessAccess {
+		gw.Server.Authentication = auth
+		doc.Components = &Components{SecuritySchemes: schemes}
+		names := []string{}
+		for name := range schemes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			doc.Security = append(doc.Security, map[string][]string{name: {}})
+		}
+	} else {
+		gw.Server.Authentication = &Authentication{Enabled: false}
+	}
+
+	mw := &Middleware{Operations: map[string]*Operation{}}
+	if a.CORS.Enable || len(a.CORS.AllowedOrigins) > 0 {
+		mw.Global = &Global{CORS: &CORS{
+			Enabled:            a.CORS.Enable,
+			MaxAge:             a.CORS.MaxAge,
+			AllowCredentials:   a.CORS.AllowCredentials,
+			ExposedHeaders:     a.CORS.ExposedHeaders,
+			AllowedHeaders:     a.CORS.AllowedHeaders,
+			OptionsPassthrough: a.CORS.OptionsPassthrough,
+			Debug:              a.CORS.Debug,
+			AllowedOrigins:     a.CORS.AllowedOrigins,
+			AllowedMethods:     a.CORS.AllowedMethods,
+		}}
+	}
+
+	if name, v, ok := defaultVersion(a); ok {
+		if name != "Default" && name != "" {
+			doc.Info.Version = name
+		}
+
+		add := func(entries []apidef.EndPointMeta, set func(*Operation)) {
+			for _, e := range entries {
+				for method, action := range e.MethodActions {
+					// Mocked replies aren't converted
+					if action.Action != apidef.NoAction {
+						continue
+					}
+					if doc.Paths[e.Path] == nil {
+						doc.Paths[e.Path] = map[string]interface{}{}
+					}
+					id := OperationID(e.Path, method)
+					doc.Paths[e.Path][strings.ToLower(method)] = map[string]interface{}{
+						"operationId": id,
+						"responses":   map[string]interface{}{"200": map[string]interface{}{"description": "OK"}},
+					}
+					if mw.Operations[id] == nil {
+						mw.Operations[id] = &Operation{}
+					}
+					set(mw.Operations[id])
+				}
+			}
+		}
+		add(v.ExtendedPaths.WhiteList, func(o *Operation) { o.Allow = &Toggle{Enabled: true} })
+		add(v.ExtendedPaths.BlackList, func(o *Operation) { o.Block = &Toggle{Enabled: true} })
+		add(v.ExtendedPaths.Ignored, func(o *Operation) { o.IgnoreAuthentication = &Toggle{Enabled: true} })
+	}
+
+	if mw.Global != nil || len(mw.Operations) > 0 {
+		gw.Middleware = mw
+	}
+
+	return doc, nil
+}
+
+// Lost lists the fields of expected the converted object doesn't have or has with another
+// value, fields the conversion added aren't lost. The ignore rules are those of
+// tyk_diff.Normalize.
+func Lost(expected, converted interface{}, ignore []string) ([]tyk_diff.Change, error) {
+	exp, err := tyk_diff.Normalize(expected, ignore)
+	if err != nil {
+		return nil, err
+	}
+	act, err := tyk_diff.Normalize(converted, ignore)
+	if err != nil {
+		return nil, err
+	}
+
+	lost := []tyk_diff.Change{}
+	for _, c := range tyk_diff.Compare(exp, act) {
+		if c.Kind != tyk_diff.Added {
+			lost = append(lost, c)
+		}
+	}
+	return lost, nil
+}
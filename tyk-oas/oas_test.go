@@ -0,0 +1,143 @@
+package tyk_oas
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+const classicUsers = `{"api_definition": {
+	"id": "5e0fac4845bb46c77543be28", "name": "Users", "slug": "users", "api_id": "users", "org_id": "org",
+	"use_standard_auth": true,
+	"auth_configs": {"authToken": {"auth_header_name": "X-Key"}, "basic": {"auth_header_name": "Authorization"}},
+	"definition": {"location": "header", "key": "x-api-version"},
+	"version_data": {"not_versioned": true, "versions": {"Default": {"name": "Default", "use_extended_paths": true, "extended_paths": {
+		"white_list": [{"path": "/users/{id}", "method_actions": {"GET": {"action": "no_action", "code": 200, "headers": {}}}}],
+		"ignored": [{"path": "/health", "method_actions": {"GET": {"action": "no_action", "code": 200, "headers": {}}}}],
+		"transform": [{"path": "/users", "method": "POST"}]
+	}}}},
+	"proxy": {"listen_path": "/users/", "target_url": "http://users", "strip_listen_path": true, "preserve_host_header": true},
+	"active": true, "tags": ["edge"],
+	"CORS": {"enable": true, "allowed_origins": ["https://app.example.com"]}
+}}`
+
+func lostPaths(t *testing.T, lost interface{}) []string {
+	raw, err := json.Marshal(lost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := []struct {
+		Path string `json:"path"`
+	}{}
+	if err := json.Unmarshal(raw, &changes); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{}
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	return paths
+}
+
+func TestToOAS(t *testing.T) {
+	def := &objects.DBApiDefinition{}
+	if err := json.Unmarshal([]byte(classicUsers), def); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, lost, err := ToOAS(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"/proxy/preserve_host_header", "/version_data/versions/Default/extended_paths/transform"}; !reflect.DeepEqual(lostPaths(t, lost), expected) {
+		t.Errorf("expected to lose %v, lost %v", expected, lost)
+	}
+
+	gw := doc.Gateway
+	if gw.Info.ID != "users" || gw.Info.DBID != "5e0fac4845bb46c77543be28" || !gw.Info.State.Active {
+		t.Errorf("unexpected info: %+v", gw.Info)
+	}
+	if gw.Upstream.URL != "http://users" || gw.Server.ListenPath != (ListenPath{Value: "/users/", Strip: true}) {
+		t.Errorf("unexpected upstream %+v or listen path %+v", gw.Upstream, gw.Server.ListenPath)
+	}
+	token := gw.Server.Authentication.SecuritySchemes[SchemeToken]
+	if token == nil || token.Header.Name != "X-Key" || doc.Components.SecuritySchemes[SchemeToken].Name != "X-Key" {
+		t.Errorf("unexpected token auth: %+v", token)
+	}
+	ops := gw.Middleware.Operations
+	if ops["users/{id}GET"].Allow == nil || ops["healthGET"].IgnoreAuthentication == nil {
+		t.Errorf("unexpected operations: %+v", ops)
+	}
+	if _, ok := doc.Paths["/users/{id}"]["get"]; !ok {
+		t.Errorf("unexpected paths: %v", doc.Paths)
+	}
+
+	// Converting it back only loses what the Tyk OAS definition lacks
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsTykOAS(raw) || IsTykOAS([]byte(classicUsers)) {
+		t.Fatal("the formats should be told apart")
+	}
+	back, lost, err := ToClassic(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lost) != 0 {
+		t.Errorf("nothing should be lost converting back, lost %v", lost)
+	}
+	if back.Auth.AuthHeaderName != "X-Key" || !back.UseStandardAuth || back.CORS.AllowedOrigins[0] != "https://app.example.com" {
+		t.Errorf("unexpected classic definition: %+v", back.APIDefinition)
+	}
+	if l := back.VersionData.Versions["Default"].ExtendedPaths.WhiteList; len(l) != 1 || l[0].Path != "/users/{id}" {
+		t.Errorf("unexpected white list: %+v", l)
+	}
+}
+
+func TestToClassic(t *testing.T) {
+	raw := []byte(`{
+		"openapi": "3.0.3",
+		"info": {"title": "Orders", "version": "2.0"},
+		"paths": {"/orders": {"post": {"operationId": "createOrder"}, "parameters": []}},
+		"x-tyk-api-gateway": {
+			"info": {"name": "Orders", "state": {"active": true}},
+			"upstream": {"url": "http://orders"},
+			"server": {
+				"listenPath": {"value": "/orders/"},
+				"authentication": {"enabled": true, "securitySchemes": {"jwtAuth": {"enabled": true, "source": "https://idp/jwks", "signingMethod": "rsa"}}}
+			},
+			"middleware": {
+				"global": {"transformRequest": {"enabled": true}},
+				"operations": {"createOrder": {"block": {"enabled": true}}}
+			}
+		}
+	}`)
+
+	def, lost, err := ToClassic(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"/x-tyk-api-gateway/middleware/global"}; !reflect.DeepEqual(lostPaths(t, lost), expected) {
+		t.Errorf("expected to lose %v, lost %v", expected, lost)
+	}
+	if !def.EnableJWT || def.JWTSource != "https://idp/jwks" || def.UseKeylessAccess || def.UseStandardAuth {
+		t.Errorf("unexpected auth: %+v", def.APIDefinition)
+	}
+	v, ok := def.VersionData.Versions["2.0"]
+	if !ok || !def.VersionData.NotVersioned {
+		t.Fatalf("expected a single 2.0 version, got %+v", def.VersionData)
+	}
+	if l := v.ExtendedPaths.BlackList; len(l) != 1 || l[0].Path != "/orders" || l[0].MethodActions["POST"].Action != "no_action" {
+		t.Errorf("unexpected black list: %+v", l)
+	}
+
+	if _, _, err := ToClassic([]byte(`{"openapi": "3.0.3"}`)); err == nil {
+		t.Error("an OpenAPI document without the extension can't be converted")
+	}
+}
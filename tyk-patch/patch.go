@@ -0,0 +1,297 @@
+package tyk_patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Operation is a single RFC 6902 JSON Patch operation
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// ParsePointer splits an RFC 6901 JSON pointer into its unescaped tokens
+func ParsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q, must start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+
+	return tokens, nil
+}
+
+// EscapeToken escapes a key for use in a JSON pointer
+func EscapeToken(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	max := length - 1
+	if allowEnd {
+		max = length
+	}
+
+	if i > max {
+		return 0, fmt.Errorf("array index %v out of range", i)
+	}
+
+	return i, nil
+}
+
+func get(doc interface{}, tokens []string) (interface{}, error) {
+	cur := doc
+	for _, t := range tokens {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, ok := node[t]
+			if !ok {
+				return nil, fmt.Errorf("path element %q not found", t)
+			}
+			cur = v
+		case []interface{}:
+			i, err := arrayIndex(t, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			cur = node[i]
+		default:
+			return nil, fmt.Errorf("path element %q does not exist on a scalar value", t)
+		}
+	}
+
+	return cur, nil
+}
+
+// mutate applies fn to the container holding the last token, replacing the container
+// in its parent so that slices can grow and shrink
+func mutate(doc interface{}, tokens []string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 0 {
+		return fn(nil, "")
+	}
+
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("path element %q not found", tokens[0])
+		}
+		newChild, err := mutate(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[tokens[0]] = newChild
+		return node, nil
+	case []interface{}:
+		i, err := arrayIndex(tokens[0], len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		newChild, err := mutate(node[i], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = newChild
+		return node, nil
+	default:
+		return nil, fmt.Errorf("path element %q does not exist on a scalar value", tokens[0])
+	}
+}
+
+func add(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	return mutate(doc, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[key] = value
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		default:
+			return nil, fmt.Errorf("can not add %q to a scalar value", key)
+		}
+	})
+}
+
+func remove(doc interface{}, tokens []string) (interface{}, error) {
+	return mutate(doc, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := node[key]; !ok {
+				return nil, fmt.Errorf("path element %q not found", key)
+			}
+			delete(node, key)
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:i], node[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("can not remove %q", key)
+		}
+	})
+}
+
+// normalise converts a value into plain JSON types so it can be compared and inserted
+func normalise(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out interface{}
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
+
+// Apply applies the operations in order to a decoded JSON document and returns the patched document
+func Apply(doc interface{}, ops []Operation) (interface{}, error) {
+	for _, op := range ops {
+		tokens, err := ParsePointer(op.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := normalise(op.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.Op {
+		case "add":
+			if len(tokens) == 0 {
+				doc = value
+			} else {
+				doc, err = add(doc, tokens, value)
+			}
+		case "remove":
+			doc, err = remove(doc, tokens)
+		case "replace":
+			if len(tokens) == 0 {
+				doc = value
+			} else if _, err = get(doc, tokens); err == nil {
+				if doc, err = remove(doc, tokens); err == nil {
+					doc, err = add(doc, tokens, value)
+				}
+			}
+		case "move", "copy":
+			var fromTokens []string
+			if fromTokens, err = ParsePointer(op.From); err != nil {
+				break
+			}
+			var moved interface{}
+			if moved, err = get(doc, fromTokens); err != nil {
+				break
+			}
+			if moved, err = normalise(moved); err != nil {
+				break
+			}
+			if op.Op == "move" {
+				if doc, err = remove(doc, fromTokens); err != nil {
+					break
+				}
+			}
+			if len(tokens) == 0 {
+				doc = moved
+			} else {
+				doc, err = add(doc, tokens, moved)
+			}
+		case "test":
+			var current interface{}
+			if current, err = get(doc, tokens); err == nil && !reflect.DeepEqual(current, value) {
+				err = fmt.Errorf("test failed, value at %v is %v", op.Path, current)
+			}
+		default:
+			err = fmt.Errorf("unknown patch operation %q", op.Op)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("patch %v %v: %v", op.Op, op.Path, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// ApplyTo patches a Go value by round-tripping it through JSON, out receives the result
+func ApplyTo(in interface{}, out interface{}, ops []Operation) error {
+	doc, err := normalise(in)
+	if err != nil {
+		return err
+	}
+
+	patched, err := Apply(doc, ops)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(patched)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, out)
+}
+
+// MergePatch applies an RFC 7396 JSON merge patch to a decoded JSON document: the members
+// of an object patch replace those of the document, recursively, a null member removes
+// the one of the document, and a patch that isn't an object replaces the document
+func MergePatch(doc, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	docObj, ok := doc.(map[string]interface{})
+	if !ok {
+		docObj = map[string]interface{}{}
+	}
+
+	out := make(map[string]interface{}, len(docObj))
+	for k, v := range docObj {
+		out[k] = v
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		out[k] = MergePatch(out[k], v)
+	}
+
+	return out
+}
@@ -0,0 +1,86 @@
+package tyk_patch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	cases := []struct {
+		name     string
+		doc      string
+		ops      string
+		expected string
+		fails    bool
+	}{
+		{"replace", `{"proxy":{"target_url":"http://dev"}}`, `[{"op":"replace","path":"/proxy/target_url","value":"http://prod"}]`, `{"proxy":{"target_url":"http://prod"}}`, false},
+		{"add to map", `{"a":{}}`, `[{"op":"add","path":"/a/b~1c","value":1}]`, `{"a":{"b/c":1}}`, false},
+		{"append", `{"tags":["a"]}`, `[{"op":"add","path":"/tags/-","value":"b"}]`, `{"tags":["a","b"]}`, false},
+		{"insert", `{"tags":["a","c"]}`, `[{"op":"add","path":"/tags/1","value":"b"}]`, `{"tags":["a","b","c"]}`, false},
+		{"remove", `{"tags":["a","b"],"x":1}`, `[{"op":"remove","path":"/tags/0"},{"op":"remove","path":"/x"}]`, `{"tags":["b"]}`, false},
+		{"move", `{"a":1}`, `[{"op":"move","from":"/a","path":"/b"}]`, `{"b":1}`, false},
+		{"copy", `{"a":[1]}`, `[{"op":"copy","from":"/a","path":"/b"}]`, `{"a":[1],"b":[1]}`, false},
+		{"test passes", `{"a":false}`, `[{"op":"test","path":"/a","value":false}]`, `{"a":false}`, false},
+		{"test fails", `{"a":true}`, `[{"op":"test","path":"/a","value":false}]`, ``, true},
+		{"replace missing", `{}`, `[{"op":"replace","path":"/a","value":1}]`, ``, true},
+		{"add to null", `{"tags":null}`, `[{"op":"add","path":"/tags/-","value":"a"}]`, ``, true},
+	}
+
+	for _, c := range cases {
+		var doc, expected interface{}
+		var ops []Operation
+		json.Unmarshal([]byte(c.doc), &doc)
+		json.Unmarshal([]byte(c.expected), &expected)
+		if err := json.Unmarshal([]byte(c.ops), &ops); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Apply(doc, ops)
+		if c.fails {
+			if err == nil {
+				t.Fatalf("%v: expected an error, got %v", c.name, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%v: %v", c.name, err)
+		}
+
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("%v: expected %v, got %v", c.name, expected, got)
+		}
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// The examples of RFC 7396
+	cases := [][3]string{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, c := range cases {
+		var doc, patch, expected interface{}
+		json.Unmarshal([]byte(c[0]), &doc)
+		json.Unmarshal([]byte(c[1]), &patch)
+		json.Unmarshal([]byte(c[2]), &expected)
+
+		if got := MergePatch(doc, patch); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%v + %v: expected %v, got %v", c[0], c[1], expected, got)
+		}
+	}
+}
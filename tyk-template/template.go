@@ -0,0 +1,170 @@
+package tyk_template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Options are the values made available to a template when it is rendered
+type Options struct {
+	Name       string
+	Slug       string
+	APIID      string
+	OrgID      string
+	ListenPath string
+	Upstream   string
+}
+
+const baseTemplate = `{
+  "name": {{ json .Name }},
+  "slug": {{ json .Slug }},
+  "api_id": {{ json .APIID }},
+  "org_id": {{ json .OrgID }},
+  "active": true,
+  %s,
+  "definition": {
+    "location": "header",
+    "key": "x-api-version"
+  },
+  "version_data": {
+    "not_versioned": true,
+    "default_version": "Default",
+    "versions": {
+      "Default": {
+        "name": "Default",
+        "use_extended_paths": true,
+        "paths": {
+          "ignored": [],
+          "white_list": [],
+          "black_list": []
+        }
+      }
+    }
+  },
+  "proxy": {
+    "listen_path": {{ json .ListenPath }},
+    "target_url": {{ json .Upstream }},
+    "strip_listen_path": true
+  },
+  "custom_middleware": {
+    "pre": [],
+    "post": []
+  },
+  "response_processors": [],
+  "allowed_ips": [],
+  "tags": [],
+  "config_data": {}
+}`
+
+var builtIn = map[string]string{
+	"rest-keyless": fmt.Sprintf(baseTemplate, `"use_keyless": true`),
+	"rest-token": fmt.Sprintf(baseTemplate, `"use_keyless": false,
+  "use_standard_auth": true,
+  "auth": {
+    "auth_header_name": "Authorization"
+  }`),
+	"rest-jwt": fmt.Sprintf(baseTemplate, `"use_keyless": false,
+  "enable_jwt": true,
+  "jwt_signing_method": "rsa",
+  "jwt_source": "",
+  "jwt_identity_base_field": "sub",
+  "jwt_policy_field_name": "pol",
+  "auth": {
+    "auth_header_name": "Authorization"
+  }`),
+}
+
+var slugCleaner = regexp.MustCompile("[^a-z0-9]+")
+
+// Names returns the names of the built-in templates
+func Names() []string {
+	names := make([]string, 0, len(builtIn))
+	for n := range builtIn {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load returns the template body for a built-in template name, or reads it
+// from disk if name does not match a built-in template
+func Load(name string) (string, error) {
+	if t, ok := builtIn[name]; ok {
+		return t, nil
+	}
+
+	raw, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("template %v is not a built-in template (%v) and could not be read: %v",
+			name, strings.Join(Names(), ", "), err)
+	}
+
+	return string(raw), nil
+}
+
+// Slugify turns an API name into a value suitable for a slug or listen path
+func Slugify(name string) string {
+	return strings.Trim(slugCleaner.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+func (o *Options) setDefaults() {
+	if o.Slug == "" {
+		o.Slug = Slugify(o.Name)
+	}
+
+	if o.APIID == "" {
+		o.APIID = strings.Replace(uuid.NewV4().String(), "-", "", -1)
+	}
+
+	if o.ListenPath == "" && o.Slug != "" {
+		o.ListenPath = fmt.Sprintf("/%v/", o.Slug)
+	}
+}
+
+// Render executes the template with the given options and returns the resulting definition
+func Render(tpl string, opts Options) (*objects.DBApiDefinition, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("an API name is required")
+	}
+
+	if opts.Upstream == "" {
+		return nil, fmt.Errorf("an upstream target URL is required")
+	}
+
+	opts.setDefaults()
+	if opts.ListenPath == "" {
+		return nil, fmt.Errorf("the name %q has no letters or digits to make a listen path of, a listen path is required", opts.Name)
+	}
+
+	t, err := template.New("api").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, opts); err != nil {
+		return nil, err
+	}
+
+	def := apidef.APIDefinition{}
+	if err := json.Unmarshal(buf.Bytes(), &def); err != nil {
+		return nil, fmt.Errorf("rendered template is not a valid API definition: %v", err)
+	}
+
+	return &objects.DBApiDefinition{APIDefinition: &def}, nil
+}
@@ -0,0 +1,78 @@
+package tyk_template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Payments API":     "payments-api",
+		"  Users / v2 ":    "users-v2",
+		"Ümlaut & Co.":     "mlaut-co",
+		"!!!":              "",
+		"already-a-slug-1": "already-a-slug-1",
+	}
+	for name, expected := range cases {
+		if got := Slugify(name); got != expected {
+			t.Errorf("Slugify(%q) = %q, want %q", name, got, expected)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, name := range Names() {
+		tpl, err := Load(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		def, err := Render(tpl, Options{Name: `Payments "v2"`, Upstream: "http://payments.internal", OrgID: "org"})
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if def.Name != `Payments "v2"` || def.Slug != "payments-v2" || def.Proxy.ListenPath != "/payments-v2/" ||
+			def.Proxy.TargetURL != "http://payments.internal" || def.OrgID != "org" || len(def.APIID) != 32 {
+			t.Errorf("%v: unexpected definition %+v", name, def.APIDefinition)
+		}
+	}
+
+	tpl, _ := Load("rest-jwt")
+	def, err := Render(tpl, Options{Name: "JWT", Upstream: "http://jwt", APIID: "jwt1", ListenPath: "/auth/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !def.EnableJWT || def.UseKeylessAccess || def.APIID != "jwt1" || def.Proxy.ListenPath != "/auth/" {
+		t.Errorf("unexpected JWT definition %+v", def.APIDefinition)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tpl, _ := Load("rest-keyless")
+
+	if _, err := Render(tpl, Options{Upstream: "http://up"}); err == nil {
+		t.Error("expected a name to be required")
+	}
+	if _, err := Render(tpl, Options{Name: "A"}); err == nil {
+		t.Error("expected an upstream to be required")
+	}
+
+	// A name without letters or digits has no slug to make a listen path of
+	if _, err := Render(tpl, Options{Name: "!!!", Upstream: "http://up"}); err == nil || !strings.Contains(err.Error(), "listen path") {
+		t.Errorf("expected a listen path to be required, got %v", err)
+	}
+	def, err := Render(tpl, Options{Name: "!!!", Upstream: "http://up", ListenPath: "/bang/"})
+	if err != nil || def.Proxy.ListenPath != "/bang/" {
+		t.Errorf("expected the listen path given, got %v", err)
+	}
+
+	if _, err := Render(`{"name": {{ .Nope }}}`, Options{Name: "A", Upstream: "http://up"}); err == nil {
+		t.Error("expected an unknown field to fail the template")
+	}
+	if _, err := Render(`not json`, Options{Name: "A", Upstream: "http://up"}); err == nil {
+		t.Error("expected a template that isn't a definition to fail")
+	}
+	if _, err := Load("does-not-exist.json"); err == nil || !strings.Contains(err.Error(), "rest-keyless") {
+		t.Errorf("expected the built-in templates listed, got %v", err)
+	}
+}
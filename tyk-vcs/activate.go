@@ -0,0 +1,50 @@
+package tyk_vcs
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// ActivePatch is the patch that activates or deactivates an API, leaving the rest of its
+// definition as it is on the target
+func ActivePatch(apiID string, active bool) ObjectPatch {
+	return ObjectPatch{APIID: apiID, Patch: []byte(fmt.Sprintf(`{"active": %v}`, active))}
+}
+
+// SelectAPIs returns the APIs with any of the IDs or tags, and the IDs no API has
+func SelectAPIs(apis []objects.DBApiDefinition, ids, tags []string) ([]objects.DBApiDefinition, []string) {
+	wantID := map[string]bool{}
+	for _, id := range ids {
+		wantID[id] = true
+	}
+	wantTag := map[string]bool{}
+	for _, t := range tags {
+		wantTag[t] = true
+	}
+
+	found := []objects.DBApiDefinition{}
+	seen := map[string]bool{}
+	for _, api := range apis {
+		match := wantID[api.APIID]
+		for _, t := range api.Tags {
+			if wantTag[t] {
+				match = true
+			}
+		}
+
+		if match {
+			found = append(found, api)
+			seen[api.APIID] = true
+		}
+	}
+
+	missing := []string{}
+	for _, id := range ids {
+		if !seen[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	return found, missing
+}
@@ -0,0 +1,41 @@
+package tyk_vcs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestSelectAPIs(t *testing.T) {
+	apis := []objects.DBApiDefinition{
+		{APIDefinition: &apidef.APIDefinition{APIID: "a1", Tags: []string{"payments", "edge"}}},
+		{APIDefinition: &apidef.APIDefinition{APIID: "a2", Tags: []string{"edge"}}},
+		{APIDefinition: &apidef.APIDefinition{APIID: "a3"}},
+	}
+
+	found, missing := SelectAPIs(apis, []string{"a3", "a9"}, []string{"payments"})
+	ids := []string{}
+	for _, api := range found {
+		ids = append(ids, api.APIID)
+	}
+	if !reflect.DeepEqual(ids, []string{"a1", "a3"}) {
+		t.Errorf("expected the APIs with the ID or tag, got %v", ids)
+	}
+	if !reflect.DeepEqual(missing, []string{"a9"}) {
+		t.Errorf("expected the unknown ID to be reported, got %v", missing)
+	}
+}
+
+func TestActivePatch(t *testing.T) {
+	p := &livePublisher{apis: map[string]map[string]interface{}{"a1": {"api_id": "a1", "active": true}}}
+
+	patch := ActivePatch("a1", false)
+	if _, err := patch.Apply(p, false); err != nil {
+		t.Fatal(err)
+	}
+	if p.updated.Active {
+		t.Error("expected the API to be deactivated")
+	}
+}
@@ -0,0 +1,46 @@
+package tyk_vcs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// ParseAgeIdentities reads the AGE-SECRET-KEY-1... identities of an age key file, as
+// age-keygen writes it
+func ParseAgeIdentities(raw string) ([]age.Identity, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	ids, err := age.ParseIdentities(strings.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age identity: %v", err)
+	}
+	return ids, nil
+}
+
+// DecryptAge decrypts an age file, ASCII armored or not, with the first of the identities
+// it is encrypted to
+func DecryptAge(raw []byte, ids []age.Identity) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(raw)
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte(armor.Header)) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimSpace(raw)))
+	}
+
+	r, err := age.Decrypt(src, ids...)
+	var noMatch *age.NoIdentityMatchError
+	if errors.As(err, &noMatch) {
+		return nil, errors.New("no age identity matches the recipients of the file")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,150 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// regexChars are the characters that make the gateway match a path as a regular expression
+// rather than a plain prefix, {id} style parameters are regular expressions too
+const regexChars = `*+?()[]{}|^$\`
+
+// APIComplexity is the size and complexity of an API definition, what the gateway pays for on
+// every request or reload
+type APIComplexity struct {
+	Name  string `json:"name"`
+	APIID string `json:"api_id"`
+	// Size is the size of the definition, in bytes of JSON
+	Size     int `json:"size"`
+	Versions int `json:"versions"`
+	// Paths are the entries of the extended paths of all versions
+	Paths int `json:"paths"`
+	// RegexPaths are the paths matched as regular expressions, URL rewrite patterns included
+	RegexPaths int `json:"regex_paths"`
+	// Middleware are the custom middleware hooks and virtual endpoints
+	Middleware int `json:"middleware"`
+	// Exceeds lists the limits the definition exceeds
+	Exceeds []string `json:"exceeds,omitempty"`
+}
+
+// ComplexityLimits are the sizes above which analyze reports a definition, zero disables a
+// limit
+type ComplexityLimits struct {
+	Size       int
+	Versions   int
+	Paths      int
+	RegexPaths int
+	Middleware int
+}
+
+func isRegexPath(p string) bool {
+	return strings.ContainsAny(p, regexChars)
+}
+
+// countPaths counts the entries of extended paths, given as decoded JSON, and how many of
+// them are regular expressions
+func countPaths(extended map[string]interface{}) (paths, regex int) {
+	for list, entries := range extended {
+		items, ok := entries.([]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, item := range items {
+			paths++
+			switch e := item.(type) {
+			case string:
+				// The cache list only holds paths
+				if isRegexPath(e) {
+					regex++
+				}
+			case map[string]interface{}:
+				p, _ := e["path"].(string)
+				if isRegexPath(p) {
+					regex++
+				} else if list == "url_rewrites" {
+					// Rewrites always match their pattern as a regular expression
+					regex++
+				}
+			}
+		}
+	}
+
+	return paths, regex
+}
+
+// AnalyzeAPI measures the size and complexity of a definition against limits
+func AnalyzeAPI(def objects.DBApiDefinition, limits ComplexityLimits) (*APIComplexity, error) {
+	if def.APIDefinition == nil {
+		return nil, fmt.Errorf("API %v has no definition", def.APIID)
+	}
+
+	raw, err := json.Marshal(def.APIDefinition)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &APIComplexity{
+		Name:     def.Name,
+		APIID:    def.APIID,
+		Size:     len(raw),
+		Versions: len(def.VersionData.Versions),
+	}
+
+	for _, v := range def.VersionData.Versions {
+		ext, err := json.Marshal(v.ExtendedPaths)
+		if err != nil {
+			return nil, err
+		}
+		extended := map[string]interface{}{}
+		if err := json.Unmarshal(ext, &extended); err != nil {
+			return nil, err
+		}
+
+		paths, regex := countPaths(extended)
+		c.Paths += paths
+		c.RegexPaths += regex
+		c.Middleware += len(v.ExtendedPaths.Virtual)
+	}
+
+	mw := def.CustomMiddleware
+	c.Middleware += len(mw.Pre) + len(mw.Post) + len(mw.PostKeyAuth) + len(mw.Response)
+	if mw.AuthCheck.Name != "" {
+		c.Middleware++
+	}
+
+	check := func(name string, value, limit int) {
+		if limit > 0 && value > limit {
+			c.Exceeds = append(c.Exceeds, fmt.Sprintf("%v %v > %v", name, value, limit))
+		}
+	}
+	check("size", c.Size, limits.Size)
+	check("versions", c.Versions, limits.Versions)
+	check("paths", c.Paths, limits.Paths)
+	check("regex paths", c.RegexPaths, limits.RegexPaths)
+	check("middleware", c.Middleware, limits.Middleware)
+
+	return c, nil
+}
+
+// AnalyzeAPIs measures every definition, the largest first
+func AnalyzeAPIs(defs []objects.DBApiDefinition, limits ComplexityLimits) ([]*APIComplexity, error) {
+	report := []*APIComplexity{}
+	for _, d := range defs {
+		c, err := AnalyzeAPI(d, limits)
+		if err != nil {
+			return nil, err
+		}
+		report = append(report, c)
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].Size > report[j].Size
+	})
+
+	return report, nil
+}
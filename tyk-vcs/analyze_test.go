@@ -0,0 +1,37 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestAnalyzeAPI(t *testing.T) {
+	def := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: "a", Name: "A"}}
+	def.VersionData.Versions = map[string]apidef.VersionInfo{
+		"Default": {
+			ExtendedPaths: apidef.ExtendedPathsSet{
+				WhiteList:  []apidef.EndPointMeta{{Path: "/plain"}, {Path: "/users/{id}"}},
+				Cached:     []string{"/cached.*"},
+				URLRewrite: []apidef.URLRewriteMeta{{Path: "/old", MatchPattern: "/old/(.*)"}},
+				Virtual:    []apidef.VirtualMeta{{Path: "/virtual"}},
+			},
+		},
+		"v2": {},
+	}
+	def.CustomMiddleware.Pre = []apidef.MiddlewareDefinition{{Name: "pre"}}
+	def.CustomMiddleware.AuthCheck = apidef.MiddlewareDefinition{Name: "auth"}
+
+	c, err := AnalyzeAPI(def, ComplexityLimits{RegexPaths: 2, Versions: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Versions != 2 || c.Paths != 5 || c.RegexPaths != 3 || c.Middleware != 3 || c.Size == 0 {
+		t.Fatalf("unexpected report %+v", c)
+	}
+	if len(c.Exceeds) != 1 || c.Exceeds[0] != "regex paths 3 > 2" {
+		t.Fatalf("expected the regex paths limit to be exceeded, got %v", c.Exceeds)
+	}
+}
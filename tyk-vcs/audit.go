@@ -0,0 +1,174 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+const (
+	// FindingKeyless is an API that requires no authentication
+	FindingKeyless = "keyless"
+	// FindingOpenCORS is an API allowing cross origin requests from any origin
+	FindingOpenCORS = "open_cors"
+	// FindingOpenAdmin is an API with admin paths that any IP address may call
+	FindingOpenAdmin = "open_admin"
+
+	// The findings compared with the repo: the definition in the repo has it too, the repo
+	// has the API without it, or the API isn't in the repo
+	FindingIntended  = "intended"
+	FindingDrift     = "drift"
+	FindingUnmanaged = "unmanaged"
+)
+
+// SecurityFinding is a weakness of the security of a published API
+type SecurityFinding struct {
+	APIID  string `json:"api_id"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+	// Status is how the finding compares with the repo, empty if it wasn't compared
+	Status string `json:"status,omitempty"`
+}
+
+// apiPaths returns the listen path of def and the paths of the extended paths of its versions,
+// but those of black_list, which the gateway blocks
+func apiPaths(def *objects.DBApiDefinition) ([]string, error) {
+	paths := []string{def.Proxy.ListenPath}
+	for _, v := range def.VersionData.Versions {
+		ext, err := json.Marshal(v.ExtendedPaths)
+		if err != nil {
+			return nil, err
+		}
+		extended := map[string]interface{}{}
+		if err := json.Unmarshal(ext, &extended); err != nil {
+			return nil, err
+		}
+
+		for kind, entries := range extended {
+			if kind == "black_list" {
+				continue
+			}
+			items, _ := entries.([]interface{})
+			for _, item := range items {
+				switch e := item.(type) {
+				case string:
+					paths = append(paths, e)
+				case map[string]interface{}:
+					if p, _ := e["path"].(string); p != "" {
+						paths = append(paths, p)
+					}
+				}
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// SecurityFindings returns the findings of def. Paths containing one of adminPaths, e.g.
+// /admin, are admin paths, which must be restricted to the IP addresses of a whitelist.
+func SecurityFindings(def objects.DBApiDefinition, adminPaths []string) ([]SecurityFinding, error) {
+	findings := []SecurityFinding{}
+	if def.APIDefinition == nil {
+		return findings, nil
+	}
+	add := func(kind, detail string) {
+		findings = append(findings, SecurityFinding{APIID: def.APIID, Name: def.Name, Kind: kind, Detail: detail})
+	}
+
+	if def.UseKeylessAccess {
+		add(FindingKeyless, "requires no authentication")
+	}
+
+	if def.CORS.Enable {
+		open := len(def.CORS.AllowedOrigins) == 0
+		for _, o := range def.CORS.AllowedOrigins {
+			if o == "*" {
+				open = true
+			}
+		}
+		if open {
+			add(FindingOpenCORS, "allows cross origin requests from any origin")
+		}
+	}
+
+	if !def.EnableIpWhiteListing || len(def.AllowedIPs) == 0 {
+		paths, err := apiPaths(&def)
+		if err != nil {
+			return nil, err
+		}
+
+		admin := []string{}
+		seen := map[string]bool{}
+		for _, p := range paths {
+			for _, a := range adminPaths {
+				if a != "" && !seen[p] && strings.Contains(strings.ToLower(p), strings.ToLower(a)) {
+					seen[p] = true
+					admin = append(admin, p)
+				}
+			}
+		}
+		if len(admin) > 0 {
+			sort.Strings(admin)
+			add(FindingOpenAdmin, fmt.Sprintf("serves the admin paths %v to any IP address", strings.Join(admin, ", ")))
+		}
+	}
+
+	return findings, nil
+}
+
+// AuditSecurity returns the findings of the published apis, sorted by API name. With the
+// definitions of the repo, repo not nil, every finding is compared with the API of the same
+// API ID in the repo.
+func AuditSecurity(apis, repo []objects.DBApiDefinition, adminPaths []string) ([]SecurityFinding, error) {
+	var intended map[string]map[string]bool
+	if repo != nil {
+		intended = map[string]map[string]bool{}
+		for _, d := range repo {
+			if d.APIDefinition == nil || d.APIID == "" {
+				continue
+			}
+			kinds := map[string]bool{}
+			findings, err := SecurityFindings(d, adminPaths)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range findings {
+				kinds[f.Kind] = true
+			}
+			intended[d.APIID] = kinds
+		}
+	}
+
+	sorted := append([]objects.DBApiDefinition{}, apis...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	all := []SecurityFinding{}
+	for _, api := range sorted {
+		findings, err := SecurityFindings(api, adminPaths)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range findings {
+			if intended != nil {
+				kinds, ok := intended[api.APIID]
+				switch {
+				case !ok:
+					f.Status = FindingUnmanaged
+				case kinds[f.Kind]:
+					f.Status = FindingIntended
+				default:
+					f.Status = FindingDrift
+				}
+			}
+			all = append(all, f)
+		}
+	}
+
+	return all, nil
+}
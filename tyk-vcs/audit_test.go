@@ -0,0 +1,75 @@
+package tyk_vcs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestAuditSecurity(t *testing.T) {
+	open := liveDef("open", "/open/", "", true)
+	open.UseKeylessAccess = true
+	open.CORS.Enable = true
+	open.CORS.AllowedOrigins = []string{"https://app.example.com", "*"}
+
+	admin := liveDef("admin", "/shop/", "", true)
+	admin.VersionData.Versions = map[string]apidef.VersionInfo{"Default": {
+		ExtendedPaths: apidef.ExtendedPathsSet{
+			WhiteList: []apidef.EndPointMeta{{Path: "/Admin/users"}, {Path: "/products"}},
+			// Blocked, so not served
+			BlackList: []apidef.EndPointMeta{{Path: "/admin/internal"}},
+		},
+	}}
+
+	blocked := liveDef("blocked", "/blocked/", "", true)
+	blocked.VersionData.Versions = map[string]apidef.VersionInfo{"Default": {
+		ExtendedPaths: apidef.ExtendedPathsSet{BlackList: []apidef.EndPointMeta{{Path: "/admin"}}},
+	}}
+
+	restricted := liveDef("restricted", "/admin/", "", true)
+	restricted.EnableIpWhiteListing = true
+	restricted.AllowedIPs = []string{"10.0.0.0/8"}
+	restricted.CORS.Enable = true
+	restricted.CORS.AllowedOrigins = []string{"https://app.example.com"}
+
+	legacy := liveDef("legacy", "/legacy/", "", true)
+	legacy.UseKeylessAccess = true
+
+	apis := []objects.DBApiDefinition{restricted, open, admin, legacy, blocked}
+	findings, err := AuditSecurity(apis, nil, []string{"/admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, f := range findings {
+		got = append(got, f.APIID+" "+f.Kind+" "+f.Status)
+	}
+	expected := []string{"admin open_admin ", "legacy keyless ", "open keyless ", "open open_cors "}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected findings %v, expected %v", got, expected)
+	}
+	if d := findings[0].Detail; d != "serves the admin paths /Admin/users to any IP address" {
+		t.Errorf("unexpected detail: %v", d)
+	}
+
+	// The repo intends the open API to be keyless, but not its CORS, and has no legacy API
+	repoOpen := liveDef("open", "/open/", "", true)
+	repoOpen.UseKeylessAccess = true
+	repo := []objects.DBApiDefinition{repoOpen, liveDef("admin", "/shop/", "", true)}
+
+	findings, err = AuditSecurity(apis, repo, []string{"/admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = []string{}
+	for _, f := range findings {
+		got = append(got, f.APIID+" "+f.Kind+" "+f.Status)
+	}
+	expected = []string{"admin open_admin drift", "legacy keyless unmanaged", "open keyless intended", "open open_cors drift"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected findings %v, expected %v", got, expected)
+	}
+}
@@ -0,0 +1,199 @@
+package tyk_vcs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+// DefaultBackupMessage is the message template of backup commits
+const DefaultBackupMessage = "Backup of {{.URL}} at {{.Time}}"
+
+// backupRemoteName is the name the remote of a backup is pushed through when it is given
+// by its URL rather than the name of a remote of the repo
+const backupRemoteName = "tyk-sync-backup"
+
+// BackupMessage is what the message template of a backup commit is rendered with
+type BackupMessage struct {
+	// URL is the dashboard or gateway the dump was taken from
+	URL string
+	// Time is when the dump was taken, in RFC 3339
+	Time string
+	// Files is the number of files added, changed or removed by the commit
+	Files int
+}
+
+// BackupOptions tell how a dump is committed
+type BackupOptions struct {
+	// Message is the template of the commit message, see BackupMessage for its fields
+	Message string
+	URL     string
+	Time    time.Time
+	Author  object.Signature
+	// Remote is the name or URL of the remote the commit is pushed to, it isn't pushed if empty
+	Remote string
+	// Branch is the branch of the remote the commit is pushed to, e.g. refs/heads/master
+	Branch string
+	// Key is the SSH key to push with, optional
+	Key []byte
+}
+
+// BackupResult is the commit a backup made, the hash is zero if nothing changed
+type BackupResult struct {
+	Hash   plumbing.Hash
+	Files  []string
+	Pushed bool
+}
+
+// RenderBackupMessage renders the message template of a backup commit
+func RenderBackupMessage(tmpl string, msg BackupMessage) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultBackupMessage
+	}
+
+	t, err := template.New("message").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("commit message template: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := t.Execute(out, msg); err != nil {
+		return "", fmt.Errorf("commit message template: %v", err)
+	}
+
+	return out.String(), nil
+}
+
+// CommitBackup commits the changes to the files under dir in the repo dir is part of, a
+// repo is created in dir if it isn't part of one, and pushes the branch when a remote is set
+func CommitBackup(dir string, opts BackupOptions) (*BackupResult, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := git.PlainOpenWithOptions(abs, &git.PlainOpenOptions{DetectDotGit: true})
+	if err == git.ErrRepositoryNotExists {
+		r, err = git.PlainInit(abs, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := filepath.Rel(w.Filesystem.Root(), abs)
+	if err != nil {
+		return nil, err
+	}
+	prefix = filepath.ToSlash(prefix)
+
+	status, err := w.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &BackupResult{}
+	for path, s := range status {
+		if prefix != "." && path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		if s.Worktree == git.Unmodified && s.Staging == git.Unmodified {
+			continue
+		}
+
+		if s.Worktree == git.Deleted {
+			_, err = w.Remove(path)
+		} else {
+			_, err = w.Add(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		}
+		res.Files = append(res.Files, path)
+	}
+	sort.Strings(res.Files)
+
+	if len(res.Files) > 0 {
+		msg, err := RenderBackupMessage(opts.Message, BackupMessage{
+			URL:   opts.URL,
+			Time:  opts.Time.Format(time.RFC3339),
+			Files: len(res.Files),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		author := opts.Author
+		author.When = opts.Time
+		if res.Hash, err = w.Commit(msg, &git.CommitOptions{Author: &author}); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Remote == "" {
+		return res, nil
+	}
+
+	if err := pushBackup(r, opts); err != nil {
+		return res, fmt.Errorf("push to %v: %v", opts.Remote, err)
+	}
+	res.Pushed = true
+
+	return res, nil
+}
+
+func pushBackup(r *git.Repository, opts BackupOptions) error {
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	if !head.Name().IsBranch() {
+		return errors.New("the backup repo has no branch checked out")
+	}
+
+	branch := opts.Branch
+	if branch == "" {
+		branch = head.Name().String()
+	}
+
+	remote, err := r.Remote(opts.Remote)
+	if err == git.ErrRemoteNotFound {
+		remote = git.NewRemote(r.Storer, &config.RemoteConfig{Name: backupRemoteName, URLs: []string{opts.Remote}})
+	} else if err != nil {
+		return err
+	}
+
+	var auth transport.AuthMethod
+	if len(opts.Key) != 0 {
+		if auth, err = ssh.NewPublicKeys("git", opts.Key, ""); err != nil {
+			return err
+		}
+	}
+
+	err = remote.Push(&git.PushOptions{
+		RemoteName: remote.Config().Name,
+		RefSpecs:   []config.RefSpec{config.RefSpec(head.Name().String() + ":" + branch)},
+		Auth:       auth,
+	})
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+
+	return err
+}
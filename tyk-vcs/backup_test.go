@@ -0,0 +1,89 @@
+package tyk_vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestRenderBackupMessage(t *testing.T) {
+	msg, err := RenderBackupMessage("", BackupMessage{URL: "http://dash:3000", Time: "2020-01-02T03:04:05Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "Backup of http://dash:3000 at 2020-01-02T03:04:05Z" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+
+	if _, err := RenderBackupMessage("{{.Nope}}", BackupMessage{}); err == nil {
+		t.Fatal("expected an error for an unknown field")
+	}
+}
+
+func TestCommitBackup(t *testing.T) {
+	root, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	dir := filepath.Join(root, "dump")
+	remote := filepath.Join(root, "remote.git")
+	if _, err := git.PlainInit(remote, true); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := BackupOptions{
+		Message: "{{.Files}} files from {{.URL}}",
+		URL:     "http://dash:3000",
+		Time:    time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Author:  object.Signature{Name: "tyk-sync", Email: "tyk-sync@localhost"},
+		Remote:  remote,
+		Branch:  "refs/heads/backup",
+	}
+
+	os.MkdirAll(dir, 0755)
+	ioutil.WriteFile(filepath.Join(dir, "a.json"), []byte("{}"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b.json"), []byte("{}"), 0644)
+
+	res, err := CommitBackup(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Hash.IsZero() || len(res.Files) != 2 || !res.Pushed {
+		t.Fatalf("expected a pushed commit of 2 files, got %+v", res)
+	}
+
+	r, _ := git.PlainOpen(remote)
+	ref, err := r.Reference(plumbing.ReferenceName("refs/heads/backup"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := r.CommitObject(ref.Hash())
+	if c.Message != "2 files from http://dash:3000" {
+		t.Fatalf("unexpected message %q", c.Message)
+	}
+
+	res, err = CommitBackup(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Hash.IsZero() {
+		t.Fatalf("expected no commit without changes, got %v", res.Hash)
+	}
+
+	os.Remove(filepath.Join(dir, "b.json"))
+	res, err = CommitBackup(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 1 || res.Files[0] != "b.json" {
+		t.Fatalf("expected the removal of b.json to be committed, got %v", res.Files)
+	}
+}
@@ -0,0 +1,160 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// perSecond is the number of requests a limit of rate per per seconds allows each second,
+// negative without a limit
+func perSecond(rate, per float64) float64 {
+	if rate <= 0 {
+		return -1
+	}
+	if per <= 0 {
+		return rate
+	}
+	return rate / per
+}
+
+// tightened is true when the limit to allows less than the limit from, negative limits are
+// no limit
+func tightened(from, to float64) bool {
+	if to < 0 {
+		return false
+	}
+	return from < 0 || to < from
+}
+
+func quotaPerSecond(max, renewal int64) float64 {
+	if max < 0 {
+		return -1
+	}
+	if renewal <= 0 {
+		return float64(max)
+	}
+	return float64(max) / float64(renewal)
+}
+
+// breakingAPIChanges are the changes from from to to that break requests consumers made to
+// the API: a deactivated API, another listen path or authentication, a tighter global rate
+// limit, and versions or whitelisted endpoints removed or endpoints blacklisted
+func breakingAPIChanges(from, to objects.DBApiDefinition) []string {
+	breaking := []string{}
+	if from.Active && !to.Active {
+		breaking = append(breaking, "deactivated")
+	}
+	if from.Proxy.ListenPath != to.Proxy.ListenPath {
+		breaking = append(breaking, fmt.Sprintf("listen path changed from %v to %v", from.Proxy.ListenPath, to.Proxy.ListenPath))
+	}
+	// Keyless access doesn't reject the credentials consumers already send
+	if a, b := authMethods(from), authMethods(to); a != b && b != "keyless" {
+		breaking = append(breaking, fmt.Sprintf("authentication changed from %v to %v", a, b))
+	}
+
+	fromRate, toRate := float64(-1), float64(-1)
+	if !from.DisableRateLimit {
+		fromRate = perSecond(from.GlobalRateLimit.Rate, from.GlobalRateLimit.Per)
+	}
+	if !to.DisableRateLimit {
+		toRate = perSecond(to.GlobalRateLimit.Rate, to.GlobalRateLimit.Per)
+	}
+	if tightened(fromRate, toRate) {
+		breaking = append(breaking, fmt.Sprintf("global rate limit tightened to %v", describeRate(to.GlobalRateLimit.Rate, to.GlobalRateLimit.Per)))
+	}
+
+	removed := []string{}
+	toVersions := versionNames(to)
+	for _, v := range sortedKeys(versionNames(from)) {
+		if !toVersions[v] {
+			removed = append(removed, v)
+			breaking = append(breaking, "removed version "+v)
+		}
+	}
+
+	// The endpoints of the versions removed went with them
+	ofRemoved := func(endpoint string) bool {
+		for _, v := range removed {
+			if strings.HasSuffix(endpoint, " ("+v+")") {
+				return true
+			}
+		}
+		return false
+	}
+	fromWhite, toWhite := endpoints(from, "white_list"), endpoints(to, "white_list")
+	for _, e := range sortedKeys(fromWhite) {
+		if !toWhite[e] && !ofRemoved(e) {
+			breaking = append(breaking, "removed whitelisted endpoint "+e)
+		}
+	}
+	fromBlack, toBlack := endpoints(from, "black_list"), endpoints(to, "black_list")
+	for _, e := range sortedKeys(toBlack) {
+		if !fromBlack[e] {
+			breaking = append(breaking, "blacklisted endpoint "+e)
+		}
+	}
+
+	return breaking
+}
+
+// breakingPolicyChanges are the changes from from to to that break the keys applying the
+// policy: a deactivated policy, a tighter rate limit or quota, and APIs or versions of APIs
+// no longer granted
+func breakingPolicyChanges(from, to objects.Policy) []string {
+	breaking := []string{}
+	if from.Active && !from.IsInactive && (!to.Active || to.IsInactive) {
+		breaking = append(breaking, "deactivated")
+	}
+	if tightened(perSecond(from.Rate, from.Per), perSecond(to.Rate, to.Per)) {
+		breaking = append(breaking, fmt.Sprintf("rate limit tightened to %v", describeRate(to.Rate, to.Per)))
+	}
+	if tightened(quotaPerSecond(from.QuotaMax, from.QuotaRenewalRate), quotaPerSecond(to.QuotaMax, to.QuotaRenewalRate)) {
+		breaking = append(breaking, fmt.Sprintf("quota tightened to %v", describeQuota(to.QuotaMax, to.QuotaRenewalRate)))
+	}
+
+	ids := []string{}
+	for id := range from.AccessRights {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		access, ok := to.AccessRights[id]
+		if !ok {
+			breaking = append(breaking, "no longer grants access to "+id)
+			continue
+		}
+
+		versions := map[string]bool{}
+		for _, v := range access.Versions {
+			versions[v] = true
+		}
+		for _, v := range from.AccessRights[id].Versions {
+			if !versions[v] {
+				breaking = append(breaking, fmt.Sprintf("no longer grants access to version %v of %v", v, id))
+			}
+		}
+	}
+
+	return breaking
+}
+
+// BreakingChanges returns the entries of a changelog that break API consumers, leaving out
+// those of the objects whose IDs are in approved
+func BreakingChanges(entries []ChangelogEntry, approved []string) []ChangelogEntry {
+	skip := map[string]bool{}
+	for _, id := range approved {
+		skip[id] = true
+	}
+
+	breaking := []ChangelogEntry{}
+	for _, e := range entries {
+		if len(e.Breaking) > 0 && !skip[e.ID] {
+			breaking = append(breaking, e)
+		}
+	}
+
+	return breaking
+}
@@ -0,0 +1,87 @@
+package tyk_vcs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestBreakingAPIChanges(t *testing.T) {
+	from := changelogAPI("a1", "Payments", "/charges", "/refunds")
+	from.UseKeylessAccess = true
+
+	// Opening an API up breaks no consumer
+	to := changelogAPI("a1", "Payments", "/charges", "/refunds", "/payouts")
+	to.UseKeylessAccess = true
+	to.Proxy.TargetURL = "http://other-upstream"
+	if breaking := breakingAPIChanges(from, to); len(breaking) != 0 {
+		t.Errorf("expected no breaking change, got %v", breaking)
+	}
+	if breaking := breakingAPIChanges(to, from); !reflect.DeepEqual(breaking, []string{"removed whitelisted endpoint GET /payouts (Default)"}) {
+		t.Errorf("expected the removed endpoint to break, got %v", breaking)
+	}
+
+	to = changelogAPI("a1", "Payments", "/charges")
+	to.Proxy.ListenPath = "/payments/"
+	to.GlobalRateLimit = apidef.GlobalRateLimit{Rate: 10, Per: 1}
+	expected := []string{
+		"listen path changed from /a1/ to /payments/",
+		"authentication changed from keyless to auth token",
+		"global rate limit tightened to 10 per 1s",
+		"removed whitelisted endpoint GET /refunds (Default)",
+	}
+	if breaking := breakingAPIChanges(from, to); !reflect.DeepEqual(breaking, expected) {
+		t.Errorf("expected the breaking changes %v, got %v", expected, breaking)
+	}
+
+	// The endpoints of a removed version needn't be listed one by one
+	to = changelogAPI("a1", "Payments")
+	to.UseKeylessAccess = true
+	to.VersionData.Versions = map[string]apidef.VersionInfo{"v2": {Name: "v2"}}
+	if breaking := breakingAPIChanges(from, to); !reflect.DeepEqual(breaking, []string{"removed version Default"}) {
+		t.Errorf("expected the removed version to break, got %v", breaking)
+	}
+}
+
+func TestBreakingPolicyChanges(t *testing.T) {
+	from := objects.Policy{ID: "gold", Active: true, Rate: 10, Per: 1, QuotaMax: 1000, QuotaRenewalRate: 3600,
+		AccessRights: map[string]objects.AccessDefinition{"a1": {Versions: []string{"Default", "v2"}}, "a2": {}}}
+
+	to := from
+	to.Rate, to.QuotaMax = 100, -1
+	to.AccessRights = map[string]objects.AccessDefinition{"a1": {Versions: []string{"Default", "v2"}}, "a2": {}, "a3": {}}
+	if breaking := breakingPolicyChanges(from, to); len(breaking) != 0 {
+		t.Errorf("expected no breaking change, got %v", breaking)
+	}
+
+	to = from
+	to.Per = 10
+	to.QuotaRenewalRate = 7200
+	to.AccessRights = map[string]objects.AccessDefinition{"a1": {Versions: []string{"v2"}}}
+	expected := []string{
+		"rate limit tightened to 10 per 10s",
+		"quota tightened to 1000 per 7200s",
+		"no longer grants access to version Default of a1",
+		"no longer grants access to a2",
+	}
+	if breaking := breakingPolicyChanges(from, to); !reflect.DeepEqual(breaking, expected) {
+		t.Errorf("expected the breaking changes %v, got %v", expected, breaking)
+	}
+}
+
+func TestBreakingChanges(t *testing.T) {
+	entries := []ChangelogEntry{
+		{ID: "a1", Breaking: []string{"removed"}},
+		{ID: "a2"},
+		{ID: "gold", Breaking: []string{"deactivated"}},
+	}
+
+	if breaking := BreakingChanges(entries, nil); len(breaking) != 2 {
+		t.Errorf("expected 2 breaking entries, got %v", breaking)
+	}
+	if breaking := BreakingChanges(entries, []string{"a1"}); len(breaking) != 1 || breaking[0].ID != "gold" {
+		t.Errorf("expected the approved change left out, got %v", breaking)
+	}
+}
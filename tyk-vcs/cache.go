@@ -0,0 +1,69 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"sync"
+
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+type cachedClone struct {
+	r        *git.Repository
+	worktree billy.Filesystem
+}
+
+// CloneCache keeps the clones of git getters in memory, keyed by remote and branch, so a
+// long running process only fetches the new commits of a repo on each run instead of
+// cloning it again. A clone must not be used by two getters at the same time.
+type CloneCache struct {
+	mu     sync.Mutex
+	clones map[string]*cachedClone
+}
+
+func NewCloneCache() *CloneCache {
+	return &CloneCache{clones: map[string]*cachedClone{}}
+}
+
+func cacheKey(repo string, ref plumbing.ReferenceName, depth int) string {
+	return fmt.Sprintf("%v %v %v", repo, ref, depth)
+}
+
+// get returns the clone stored under key, c may be nil
+func (c *CloneCache) get(key string) *cachedClone {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.clones[key]
+}
+
+func (c *CloneCache) put(key string, clone *cachedClone) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clones[key] = clone
+}
+
+func (c *CloneCache) drop(key string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.clones, key)
+}
+
+// Len is the number of cached clones
+func (c *CloneCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.clones)
+}
@@ -0,0 +1,90 @@
+package tyk_vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/src-d/go-git.v4"
+)
+
+func TestCloneCache(t *testing.T) {
+	root, err := ioutil.TempDir("", "tyk-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := os.Mkdir(filepath.Join(root, "apis"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitFiles(t, root, w, map[string]string{
+		".tyk.json": `{"type": "apidef", "files": [{"file": "a.json"}]}`,
+		"a.json":    `{"api_id": "a", "name": "A"}`,
+	})
+	commitFiles(t, root, w, map[string]string{
+		"a.json": `{"api_id": "a", "name": "A v2"}`,
+	})
+
+	cache := NewCloneCache()
+	fetchName := func(full bool) string {
+		g, err := NewGGetterWithOptions(root, "refs/heads/master", nil, GitOptions{SubDir: "apis", FullHistory: full, Cache: cache})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.FetchRepo(); err != nil {
+			t.Skipf("cloning from the file system needs git: %v", err)
+		}
+
+		shallow, err := g.r.Storer.Shallow()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if full == (len(shallow) > 0) {
+			t.Fatalf("unexpected shallow commits for full history %v: %v", full, shallow)
+		}
+
+		spec, err := g.FetchTykSpec()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defs, err := g.FetchAPIDef(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return defs[0].Name
+	}
+
+	for _, full := range []bool{false, true} {
+		if name := fetchName(full); name != "A v2" {
+			t.Fatalf("expected the tip of the branch, got %v", name)
+		}
+		if name := fetchName(full); name != "A v2" {
+			t.Fatalf("expected the cached clone to be reused, got %v", name)
+		}
+	}
+
+	commitFiles(t, root, w, map[string]string{
+		"a.json": `{"api_id": "a", "name": "A v3"}`,
+	})
+
+	for _, full := range []bool{false, true} {
+		if name := fetchName(full); name != "A v3" {
+			t.Fatalf("expected the cached clone to get the new commit, got %v", name)
+		}
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("expected a shallow and a full clone, got %v", cache.Len())
+	}
+}
@@ -0,0 +1,51 @@
+package tyk_vcs
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// Canary stages definitions on the gateways of one segment: Stage swaps the tags of the
+// definitions for the canary tag, so only the gateways with that tag load them, Promote
+// gives them back their own tags
+type Canary struct {
+	Tag  string
+	tags map[string][]string
+}
+
+func NewCanary(tag string) *Canary {
+	return &Canary{Tag: tag, tags: map[string][]string{}}
+}
+
+// Stage tags defs with the canary tag only, the definitions need an API ID to be promoted
+func (c *Canary) Stage(defs []objects.DBApiDefinition) error {
+	for _, d := range defs {
+		if d.APIDefinition == nil {
+			continue
+		}
+		if d.APIID == "" {
+			return fmt.Errorf("API %v has no API ID, it can't be promoted from the canary", d.Name)
+		}
+
+		if _, staged := c.tags[d.APIID]; !staged {
+			c.tags[d.APIID] = d.Tags
+		}
+		d.Tags = []string{c.Tag}
+	}
+
+	return nil
+}
+
+// Promote gives the staged definitions of defs back the tags they had before Stage
+func (c *Canary) Promote(defs []objects.DBApiDefinition) {
+	for _, d := range defs {
+		if d.APIDefinition == nil {
+			continue
+		}
+
+		if tags, staged := c.tags[d.APIID]; staged {
+			d.Tags = tags
+		}
+	}
+}
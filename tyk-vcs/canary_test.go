@@ -0,0 +1,36 @@
+package tyk_vcs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestCanary(t *testing.T) {
+	defs := []objects.DBApiDefinition{
+		{APIDefinition: &apidef.APIDefinition{APIID: "a", Tags: []string{"eu", "us"}}},
+		{APIDefinition: &apidef.APIDefinition{APIID: "b"}},
+	}
+
+	c := NewCanary("canary")
+	if err := c.Stage(defs); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range defs {
+		if !reflect.DeepEqual(d.Tags, []string{"canary"}) {
+			t.Fatalf("expected the canary tag only, got %v", d.Tags)
+		}
+	}
+
+	c.Promote(defs)
+	if !reflect.DeepEqual(defs[0].Tags, []string{"eu", "us"}) || len(defs[1].Tags) != 0 {
+		t.Fatalf("expected the original tags, got %v and %v", defs[0].Tags, defs[1].Tags)
+	}
+
+	noID := []objects.DBApiDefinition{{APIDefinition: &apidef.APIDefinition{Name: "A"}}}
+	if err := c.Stage(noID); err == nil {
+		t.Fatal("expected an error for a definition without API ID")
+	}
+}
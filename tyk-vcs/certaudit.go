@@ -0,0 +1,87 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// CertUse is a certificate and the APIs that reference it
+type CertUse struct {
+	ID       string    `json:"id"`
+	APIs     []string  `json:"apis"`
+	NotAfter time.Time `json:"not_after,omitempty"`
+	// Error is set when the meta data of the certificate could not be read
+	Error string `json:"error,omitempty"`
+	// Expiring is set when the certificate expires within the audited period, or already has
+	Expiring bool `json:"expiring"`
+}
+
+// CertReferences maps the IDs of the certificates defs use, as server, client or upstream
+// certificates, to the APIs using them
+func CertReferences(defs []objects.DBApiDefinition) map[string][]string {
+	refs := map[string][]string{}
+	add := func(id, api string) {
+		if id == "" {
+			return
+		}
+		for _, a := range refs[id] {
+			if a == api {
+				return
+			}
+		}
+		refs[id] = append(refs[id], api)
+	}
+
+	for _, d := range defs {
+		if d.APIDefinition == nil {
+			continue
+		}
+
+		api := fmt.Sprintf("%v (%v)", d.Name, d.APIID)
+		for _, id := range d.Certificates {
+			add(id, api)
+		}
+		for _, id := range d.ClientCertificates {
+			add(id, api)
+		}
+		for _, id := range d.UpstreamCertificates {
+			add(id, api)
+		}
+	}
+
+	return refs
+}
+
+// AuditCertificates reads the meta data of the certificates defs use with fetch, and flags
+// the ones expiring before now+within. The result is sorted by expiry, unreadable
+// certificates first.
+func AuditCertificates(defs []objects.DBApiDefinition, fetch func(id string) (*objects.CertificateMeta, error), now time.Time, within time.Duration) []CertUse {
+	uses := []CertUse{}
+	for id, apis := range CertReferences(defs) {
+		use := CertUse{ID: id, APIs: apis}
+
+		meta, err := fetch(id)
+		if err == nil {
+			use.NotAfter, err = time.Parse(time.RFC3339, meta.NotAfter)
+		}
+		if err != nil {
+			use.Error = err.Error()
+		} else {
+			use.Expiring = use.NotAfter.Before(now.Add(within))
+		}
+
+		uses = append(uses, use)
+	}
+
+	sort.Slice(uses, func(i, j int) bool {
+		if uses[i].NotAfter.Equal(uses[j].NotAfter) {
+			return uses[i].ID < uses[j].ID
+		}
+		return uses[i].NotAfter.Before(uses[j].NotAfter)
+	})
+
+	return uses
+}
@@ -0,0 +1,41 @@
+package tyk_vcs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestAuditCertificates(t *testing.T) {
+	defs := []objects.DBApiDefinition{
+		{APIDefinition: &apidef.APIDefinition{APIID: "a", Name: "A", Certificates: []string{"soon"}, ClientCertificates: []string{"later"}}},
+		{APIDefinition: &apidef.APIDefinition{APIID: "b", Name: "B", UpstreamCertificates: map[string]string{"*": "soon"}, ClientCertificates: []string{"missing"}}},
+	}
+
+	expiry := map[string]string{"soon": "2026-10-20T00:00:00Z", "later": "2027-10-20T00:00:00Z"}
+	fetch := func(id string) (*objects.CertificateMeta, error) {
+		if expiry[id] == "" {
+			return nil, errors.New("not found")
+		}
+		return &objects.CertificateMeta{ID: id, NotAfter: expiry[id]}, nil
+	}
+
+	now, _ := time.Parse(time.RFC3339, "2026-10-14T00:00:00Z")
+	uses := AuditCertificates(defs, fetch, now, 30*24*time.Hour)
+	if len(uses) != 3 {
+		t.Fatalf("expected 3 certificates, got %+v", uses)
+	}
+
+	if uses[0].ID != "missing" || uses[0].Error == "" {
+		t.Fatalf("expected the unreadable certificate first, got %+v", uses[0])
+	}
+	if uses[1].ID != "soon" || !uses[1].Expiring || len(uses[1].APIs) != 2 {
+		t.Fatalf("expected the expiring certificate used by both APIs, got %+v", uses[1])
+	}
+	if uses[2].ID != "later" || uses[2].Expiring {
+		t.Fatalf("expected the certificate valid for a year last, got %+v", uses[2])
+	}
+}
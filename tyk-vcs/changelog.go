@@ -0,0 +1,442 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-diff"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// ChangelogEntry is an API or policy added, removed or changed between two revisions, Notes
+// describe the changes in words
+type ChangelogEntry struct {
+	Kind   string   `json:"kind"`
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Action string   `json:"action"`
+	Notes  []string `json:"notes,omitempty"`
+	// Breaking are the changes that break the consumers of the API or the keys of the policy
+	Breaking []string `json:"breaking,omitempty"`
+}
+
+// apiChangelogFields and policyChangelogFields are the fields the notes describe, changes to
+// the other fields are listed by name
+var (
+	apiChangelogFields = map[string]bool{
+		"proxy.listen_path": true, "proxy.target_url": true, "active": true, "global_rate_limit": true,
+		"disable_rate_limit": true, "disable_quota": true, "use_keyless": true, "use_oauth2": true,
+		"use_basic_auth": true, "enable_jwt": true, "use_openid": true, "enable_signature_checking": true,
+		"use_mutual_tls_auth": true, "use_go_plugin_auth": true, "enable_coprocess_auth": true,
+	}
+	policyChangelogFields = map[string]bool{
+		"rate": true, "per": true, "quota_max": true, "quota_renewal_rate": true,
+		"access_rights": true, "active": true, "is_inactive": true,
+	}
+	// changelogIgnores are fields that change without changing what an object does
+	changelogIgnores = []string{"/id", "/_id", "/org_id", "/date_created", "/last_updated", "/name"}
+)
+
+// authMethods names the ways an API authenticates requests
+func authMethods(def objects.DBApiDefinition) string {
+	if def.UseKeylessAccess {
+		return "keyless"
+	}
+
+	methods := []string{}
+	for _, m := range []struct {
+		on   bool
+		name string
+	}{
+		{def.UseOauth2, "OAuth 2.0"},
+		{def.UseBasicAuth, "basic auth"},
+		{def.EnableJWT, "JWT"},
+		{def.UseOpenID, "OpenID Connect"},
+		{def.EnableSignatureChecking, "HMAC signatures"},
+		{def.UseMutualTLSAuth, "mutual TLS"},
+		{def.UseGoPluginAuth, "Go plugin auth"},
+		{def.EnableCoProcessAuth, "plugin auth"},
+	} {
+		if m.on {
+			methods = append(methods, m.name)
+		}
+	}
+	if len(methods) == 0 {
+		return "auth token"
+	}
+
+	return strings.Join(methods, ", ")
+}
+
+// endpoints lists the extended paths of each version of def, as METHOD path (version), only
+// those of the given lists of extended paths, e.g. white_list, if any are given
+func endpoints(def objects.DBApiDefinition, lists ...string) map[string]bool {
+	found := map[string]bool{}
+	only := map[string]bool{}
+	for _, l := range lists {
+		only[l] = true
+	}
+	doc, err := tyk_diff.Normalize(def.VersionData, nil)
+	if err != nil {
+		return found
+	}
+
+	versions, _ := doc.(map[string]interface{})["versions"].(map[string]interface{})
+	for version, v := range versions {
+		paths, _ := v.(map[string]interface{})["extended_paths"].(map[string]interface{})
+		for name, list := range paths {
+			if len(only) > 0 && !only[name] {
+				continue
+			}
+			entries, _ := list.([]interface{})
+			for _, entry := range entries {
+				e, _ := entry.(map[string]interface{})
+				path, _ := e["path"].(string)
+				if path == "" {
+					continue
+				}
+
+				methods := []string{}
+				if m, _ := e["method"].(string); m != "" {
+					methods = append(methods, m)
+				}
+				if actions, ok := e["method_actions"].(map[string]interface{}); ok {
+					for m := range actions {
+						methods = append(methods, m)
+					}
+				}
+				if len(methods) == 0 {
+					methods = append(methods, "*")
+				}
+
+				for _, m := range methods {
+					found[fmt.Sprintf("%v %v (%v)", strings.ToUpper(m), path, version)] = true
+				}
+			}
+		}
+	}
+
+	return found
+}
+
+func versionNames(def objects.DBApiDefinition) map[string]bool {
+	names := map[string]bool{}
+	for name := range def.VersionData.Versions {
+		names[name] = true
+	}
+	return names
+}
+
+// setChanges describes what was added to and removed from a set
+func setChanges(from, to map[string]bool, added, removed string) []string {
+	notes := []string{}
+	for _, s := range sortedKeys(to) {
+		if !from[s] {
+			notes = append(notes, added+" "+s)
+		}
+	}
+	for _, s := range sortedKeys(from) {
+		if !to[s] {
+			notes = append(notes, removed+" "+s)
+		}
+	}
+	return notes
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := []string{}
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func describeRate(rate, per float64) string {
+	if rate <= 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%v per %vs", rate, per)
+}
+
+func describeQuota(max, renewal int64) string {
+	if max < 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%v per %vs", max, renewal)
+}
+
+// changedFields names the top level fields that differ between from and to, the fields of
+// the proxy section by their name in it, e.g. proxy.strip_listen_path
+func changedFields(from, to interface{}) map[string]bool {
+	fields := map[string]bool{}
+	a, err := tyk_diff.Normalize(from, changelogIgnores)
+	if err != nil {
+		return fields
+	}
+	b, err := tyk_diff.Normalize(to, changelogIgnores)
+	if err != nil {
+		return fields
+	}
+
+	for _, c := range tyk_diff.Compare(a, b) {
+		parts := strings.SplitN(strings.TrimPrefix(c.Path, "/"), "/", 3)
+		field := parts[0]
+		if field == "proxy" && len(parts) > 1 {
+			field += "." + parts[1]
+		}
+		fields[field] = true
+	}
+
+	return fields
+}
+
+// otherChanges lists the changed fields the notes don't describe
+func otherChanges(fields, described map[string]bool) []string {
+	other := map[string]bool{}
+	for f := range fields {
+		if !described[f] {
+			other[f] = true
+		}
+	}
+	if len(other) == 0 {
+		return nil
+	}
+
+	return []string{"also changed: " + strings.Join(sortedKeys(other), ", ")}
+}
+
+func apiNotes(from, to objects.DBApiDefinition) []string {
+	notes := []string{}
+	if from.Active != to.Active {
+		if to.Active {
+			notes = append(notes, "activated")
+		} else {
+			notes = append(notes, "deactivated")
+		}
+	}
+	if from.Proxy.ListenPath != to.Proxy.ListenPath {
+		notes = append(notes, fmt.Sprintf("listen path changed from %v to %v", from.Proxy.ListenPath, to.Proxy.ListenPath))
+	}
+	if from.Proxy.TargetURL != to.Proxy.TargetURL {
+		notes = append(notes, fmt.Sprintf("upstream changed from %v to %v", from.Proxy.TargetURL, to.Proxy.TargetURL))
+	}
+	if a, b := authMethods(from), authMethods(to); a != b {
+		notes = append(notes, fmt.Sprintf("authentication changed from %v to %v", a, b))
+	}
+
+	fromRate := describeRate(from.GlobalRateLimit.Rate, from.GlobalRateLimit.Per)
+	toRate := describeRate(to.GlobalRateLimit.Rate, to.GlobalRateLimit.Per)
+	if fromRate != toRate {
+		notes = append(notes, fmt.Sprintf("global rate limit changed from %v to %v", fromRate, toRate))
+	}
+	if from.DisableRateLimit != to.DisableRateLimit {
+		notes = append(notes, fmt.Sprintf("rate limiting disabled: %v", to.DisableRateLimit))
+	}
+	if from.DisableQuota != to.DisableQuota {
+		notes = append(notes, fmt.Sprintf("quotas disabled: %v", to.DisableQuota))
+	}
+
+	versions := setChanges(versionNames(from), versionNames(to), "added version", "removed version")
+	versions = append(versions, setChanges(endpoints(from), endpoints(to), "added endpoint", "removed endpoint")...)
+	notes = append(notes, versions...)
+
+	fields := changedFields(from.APIDefinition, to.APIDefinition)
+	if len(versions) > 0 {
+		// The versions can change in more ways than their endpoints, which are the gist
+		delete(fields, "version_data")
+	}
+
+	return append(notes, otherChanges(fields, apiChangelogFields)...)
+}
+
+func policyNotes(from, to objects.Policy, apiNames map[string]string) []string {
+	notes := []string{}
+	fromActive, toActive := from.Active && !from.IsInactive, to.Active && !to.IsInactive
+	if fromActive != toActive {
+		if toActive {
+			notes = append(notes, "activated")
+		} else {
+			notes = append(notes, "deactivated")
+		}
+	}
+	if a, b := describeRate(from.Rate, from.Per), describeRate(to.Rate, to.Per); a != b {
+		notes = append(notes, fmt.Sprintf("rate limit changed from %v to %v", a, b))
+	}
+	if a, b := describeQuota(from.QuotaMax, from.QuotaRenewalRate), describeQuota(to.QuotaMax, to.QuotaRenewalRate); a != b {
+		notes = append(notes, fmt.Sprintf("quota changed from %v to %v", a, b))
+	}
+
+	apis := func(p objects.Policy) map[string]bool {
+		set := map[string]bool{}
+		for id, access := range p.AccessRights {
+			name := apiNames[id]
+			if name == "" {
+				name = access.APIName
+			}
+			if name == "" {
+				name = id
+			}
+			set[fmt.Sprintf("%v (%v)", name, id)] = true
+		}
+		return set
+	}
+	notes = append(notes, setChanges(apis(from), apis(to), "grants access to", "no longer grants access to")...)
+
+	ids := []string{}
+	for id := range to.AccessRights {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		a, ok := from.AccessRights[id]
+		if !ok {
+			continue
+		}
+		if len(changedFields(a, to.AccessRights[id])) > 0 {
+			notes = append(notes, fmt.Sprintf("access to %v (versions, allowed URLs or limits) changed", id))
+		}
+	}
+
+	return append(notes, otherChanges(changedFields(from, to), policyChangelogFields)...)
+}
+
+func policyKey(p objects.Policy) string {
+	if p.ID != "" {
+		return p.ID
+	}
+	return p.MID.Hex()
+}
+
+// Changelog compares the APIs, by API ID, and the policies, by ID, of two revisions and
+// describes what was added, removed or changed. Objects that didn't change are left out.
+func Changelog(fromDefs, toDefs []objects.DBApiDefinition, fromPols, toPols []objects.Policy) []ChangelogEntry {
+	entries := []ChangelogEntry{}
+
+	apiNames := map[string]string{}
+	oldAPIs := map[string]objects.DBApiDefinition{}
+	for _, d := range fromDefs {
+		if d.APIDefinition != nil {
+			oldAPIs[d.APIID] = d
+			apiNames[d.APIID] = d.Name
+		}
+	}
+	newAPIs := map[string]bool{}
+	for _, d := range toDefs {
+		if d.APIDefinition == nil {
+			continue
+		}
+		newAPIs[d.APIID] = true
+		apiNames[d.APIID] = d.Name
+
+		old, ok := oldAPIs[d.APIID]
+		if !ok {
+			notes := []string{fmt.Sprintf("listens on %v, %v", d.Proxy.ListenPath, authMethods(d))}
+			entries = append(entries, ChangelogEntry{Kind: "API", ID: d.APIID, Name: d.Name, Action: ChangeAdded, Notes: notes})
+			continue
+		}
+
+		notes := apiNotes(old, d)
+		if old.Name != d.Name {
+			notes = append([]string{fmt.Sprintf("renamed from %v", old.Name)}, notes...)
+		}
+		if len(notes) > 0 {
+			entries = append(entries, ChangelogEntry{Kind: "API", ID: d.APIID, Name: d.Name, Action: ChangeChanged, Notes: notes, Breaking: breakingAPIChanges(old, d)})
+		}
+	}
+	for id, d := range oldAPIs {
+		if !newAPIs[id] {
+			entries = append(entries, ChangelogEntry{Kind: "API", ID: id, Name: d.Name, Action: ChangeRemoved, Breaking: []string{"removed"}})
+		}
+	}
+
+	oldPols := map[string]objects.Policy{}
+	for _, p := range fromPols {
+		oldPols[policyKey(p)] = p
+	}
+	newPols := map[string]bool{}
+	for _, p := range toPols {
+		key := policyKey(p)
+		newPols[key] = true
+
+		old, ok := oldPols[key]
+		if !ok {
+			notes := []string{fmt.Sprintf("rate limit %v, quota %v", describeRate(p.Rate, p.Per), describeQuota(p.QuotaMax, p.QuotaRenewalRate))}
+			entries = append(entries, ChangelogEntry{Kind: "policy", ID: key, Name: p.Name, Action: ChangeAdded, Notes: notes})
+			continue
+		}
+
+		notes := policyNotes(old, p, apiNames)
+		if old.Name != p.Name {
+			notes = append([]string{fmt.Sprintf("renamed from %v", old.Name)}, notes...)
+		}
+		if len(notes) > 0 {
+			entries = append(entries, ChangelogEntry{Kind: "policy", ID: key, Name: p.Name, Action: ChangeChanged, Notes: notes, Breaking: breakingPolicyChanges(old, p)})
+		}
+	}
+	for key, p := range oldPols {
+		if !newPols[key] {
+			entries = append(entries, ChangelogEntry{Kind: "policy", ID: key, Name: p.Name, Action: ChangeRemoved, Breaking: []string{"removed"}})
+		}
+	}
+
+	order := map[string]int{ChangeAdded: 0, ChangeChanged: 1, ChangeRemoved: 2}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Kind != b.Kind {
+			return a.Kind == "API"
+		}
+		if a.Action != b.Action {
+			return order[a.Action] < order[b.Action]
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+
+	return entries
+}
+
+// ChangelogMarkdown renders the entries as Markdown release notes, titled with title
+func ChangelogMarkdown(title string, entries []ChangelogEntry) string {
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "# %v\n", title)
+	if len(entries) == 0 {
+		out.WriteString("\nNo API or policy changed.\n")
+		return out.String()
+	}
+
+	section := ""
+	for _, e := range entries {
+		heading := "APIs " + e.Action
+		if e.Kind == "policy" {
+			heading = "Policies " + e.Action
+		}
+		if heading != section {
+			section = heading
+			fmt.Fprintf(out, "\n## %v\n\n", heading)
+		}
+
+		if len(e.Breaking) > 0 {
+			fmt.Fprintf(out, "- **%v** (`%v`), **breaking**\n", e.Name, e.ID)
+		} else {
+			fmt.Fprintf(out, "- **%v** (`%v`)\n", e.Name, e.ID)
+		}
+		for _, n := range e.Notes {
+			fmt.Fprintf(out, "  - %v\n", n)
+		}
+		if len(e.Breaking) > 0 && e.Action != ChangeRemoved {
+			fmt.Fprintf(out, "  - breaking: %v\n", strings.Join(e.Breaking, "; "))
+		}
+	}
+
+	return out.String()
+}
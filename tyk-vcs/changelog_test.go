@@ -0,0 +1,100 @@
+package tyk_vcs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func changelogAPI(id, name string, paths ...string) objects.DBApiDefinition {
+	def := &apidef.APIDefinition{APIID: id, Name: name, Active: true}
+	def.Proxy.ListenPath = "/" + id + "/"
+	def.Proxy.TargetURL = "http://upstream"
+	meta := []apidef.EndPointMeta{}
+	for _, p := range paths {
+		meta = append(meta, apidef.EndPointMeta{Path: p, MethodActions: map[string]apidef.EndpointMethodMeta{"GET": {Action: apidef.NoAction}}})
+	}
+	def.VersionData.Versions = map[string]apidef.VersionInfo{
+		"Default": {Name: "Default", UseExtendedPaths: true, ExtendedPaths: apidef.ExtendedPathsSet{WhiteList: meta}},
+	}
+	return objects.DBApiDefinition{APIDefinition: def}
+}
+
+func TestChangelog(t *testing.T) {
+	payments := changelogAPI("a1", "Payments", "/charges")
+	orders := changelogAPI("a2", "Orders")
+	legacy := changelogAPI("a3", "Legacy")
+
+	newPayments := changelogAPI("a1", "Payments", "/charges", "/refunds")
+	newPayments.UseKeylessAccess = false
+	newPayments.EnableJWT = true
+	newPayments.GlobalRateLimit = apidef.GlobalRateLimit{Rate: 100, Per: 60}
+	newPayments.Proxy.StripListenPath = true
+	users := changelogAPI("a4", "Users")
+	users.UseKeylessAccess = true
+
+	gold := objects.Policy{ID: "gold", Name: "Gold", Active: true, Rate: 10, Per: 1, QuotaMax: -1,
+		AccessRights: map[string]objects.AccessDefinition{"a1": {APIID: "a1", Versions: []string{"Default"}}}}
+	newGold := gold
+	newGold.Rate = 20
+	newGold.QuotaMax, newGold.QuotaRenewalRate = 1000, 3600
+	newGold.AccessRights = map[string]objects.AccessDefinition{
+		"a1": {APIID: "a1", Versions: []string{"Default", "v2"}},
+		"a4": {APIID: "a4"},
+	}
+	bronze := objects.Policy{ID: "bronze", Name: "Bronze", Active: true}
+
+	entries := Changelog(
+		[]objects.DBApiDefinition{payments, orders, legacy},
+		[]objects.DBApiDefinition{newPayments, orders, users},
+		[]objects.Policy{gold, bronze},
+		[]objects.Policy{newGold, bronze},
+	)
+
+	got := []string{}
+	for _, e := range entries {
+		got = append(got, e.Kind+" "+e.Action+" "+e.Name)
+	}
+	expected := []string{"API added Users", "API changed Payments", "API removed Legacy", "policy changed Gold"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected the entries %v, got %v", expected, got)
+	}
+
+	if notes := entries[0].Notes; len(notes) != 1 || notes[0] != "listens on /a4/, keyless" {
+		t.Errorf("expected the added API described, got %v", notes)
+	}
+
+	expectedNotes := []string{
+		"authentication changed from auth token to JWT",
+		"global rate limit changed from none to 100 per 60s",
+		"added endpoint GET /refunds (Default)",
+		"also changed: proxy.strip_listen_path",
+	}
+	if notes := entries[1].Notes; !reflect.DeepEqual(notes, expectedNotes) {
+		t.Errorf("expected the API notes %v, got %v", expectedNotes, notes)
+	}
+
+	expectedNotes = []string{
+		"rate limit changed from 10 per 1s to 20 per 1s",
+		"quota changed from unlimited to 1000 per 3600s",
+		"grants access to Users (a4)",
+		"access to a1 (versions, allowed URLs or limits) changed",
+	}
+	if notes := entries[3].Notes; !reflect.DeepEqual(notes, expectedNotes) {
+		t.Errorf("expected the policy notes %v, got %v", expectedNotes, notes)
+	}
+
+	md := ChangelogMarkdown("Changes from v1.2 to v1.3", entries)
+	for _, s := range []string{"# Changes from v1.2 to v1.3\n", "## APIs added\n", "## Policies changed\n", "- **Legacy** (`a3`), **breaking**\n", "  - added endpoint GET /refunds (Default)\n"} {
+		if !strings.Contains(md, s) {
+			t.Errorf("expected %q in the changelog, got %v", s, md)
+		}
+	}
+
+	if entries := Changelog([]objects.DBApiDefinition{orders}, []objects.DBApiDefinition{orders}, nil, nil); len(entries) != 0 {
+		t.Errorf("expected no change between identical revisions, got %v", entries)
+	}
+}
@@ -0,0 +1,553 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/utils/merkletrie"
+)
+
+// ChangeLister is implemented by the getters that read from a git repository, it lists the
+// files changed between two commits
+type ChangeLister interface {
+	Changes(from, to string) (*FileChanges, error)
+}
+
+// FileChanges are the files added, modified or deleted between two commits, relative to
+// the directory of the spec file
+type FileChanges struct {
+	Changed map[string]bool
+	Deleted map[string]bool
+
+	from   *object.Tree
+	prefix string
+}
+
+// Changes lists the files changed between two revisions of the repo, to defaults to the
+// checked out commit. If to is set it is checked out, so the objects read afterwards are
+// those of that commit.
+func (gg *GitGetter) Changes(from, to string) (*FileChanges, error) {
+	if gg.r == nil {
+		return nil, errors.New("no repository in memory, fetch repo first")
+	}
+
+	if to == "" {
+		to = gg.opts.Commit
+	} else {
+		toHash, err := gg.r.ResolveRevision(plumbing.Revision(to))
+		if err != nil {
+			return nil, fmt.Errorf("commit %v: %v", to, err)
+		}
+
+		if err := gg.checkout(*toHash); err != nil {
+			return nil, err
+		}
+	}
+
+	prefix := gg.subDir()
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return diffCommits(gg.r, from, to, prefix)
+}
+
+// Changes lists the files changed between two commits of the repo the directory is part of,
+// to must be the checked out commit as the objects are read from the directory
+func (gg *FSGetter) Changes(from, to string) (*FileChanges, error) {
+	r, err := git.PlainOpenWithOptions(gg.fs.Root(), &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if to != "" {
+		head, err := r.Head()
+		if err != nil {
+			return nil, err
+		}
+		toHash, err := r.ResolveRevision(plumbing.Revision(to))
+		if err != nil {
+			return nil, fmt.Errorf("commit %v: %v", to, err)
+		}
+		if *toHash != head.Hash() {
+			return nil, fmt.Errorf("commit %v is not checked out in %v", to, gg.fs.Root())
+		}
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := repoPrefix(w.Filesystem.Root(), gg.fs.Root())
+	if err != nil {
+		return nil, err
+	}
+
+	return diffCommits(r, from, to, prefix)
+}
+
+// RevisionReader is implemented by the getters that read from a git repository, it reads
+// the spec and its objects as they were in a revision
+type RevisionReader interface {
+	AtRevision(rev string) (Getter, error)
+}
+
+// AtRevision returns a getter of the files of the repo, or of the subdirectory checked out,
+// in the revision rev. The checked out commit is left as it is.
+func (gg *GitGetter) AtRevision(rev string) (Getter, error) {
+	if gg.r == nil {
+		return nil, errors.New("no repository in memory, fetch repo first")
+	}
+
+	prefix := gg.subDir()
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return revisionGetter(gg.r, rev, prefix)
+}
+
+// AtRevision returns a getter of the files of the directory in the revision rev of the repo
+// the directory is part of, the directory itself is left as it is
+func (gg *FSGetter) AtRevision(rev string) (Getter, error) {
+	r, err := git.PlainOpenWithOptions(gg.fs.Root(), &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := repoPrefix(w.Filesystem.Root(), gg.fs.Root())
+	if err != nil {
+		return nil, err
+	}
+
+	return revisionGetter(r, rev, prefix)
+}
+
+// revisionGetter copies the files under prefix of the tree of rev into memory
+func revisionGetter(r *git.Repository, rev, prefix string) (Getter, error) {
+	tree, err := commitTree(r, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := memfs.New()
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if !strings.HasPrefix(f.Name, prefix) || !f.Mode.IsFile() {
+			return nil
+		}
+
+		contents, err := f.Contents()
+		if err != nil {
+			return err
+		}
+
+		out, err := fs.Create(strings.TrimPrefix(f.Name, prefix))
+		if err != nil {
+			return err
+		}
+		_, err = out.Write([]byte(contents))
+		out.Close()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading commit %v: %v", rev, err)
+	}
+
+	return &FSGetter{fs: fs}, nil
+}
+
+// repoPrefix is the path of dir inside the worktree at root, as used in git trees
+func repoPrefix(root, dir string) (string, error) {
+	for _, p := range []*string{&root, &dir} {
+		abs, err := filepath.Abs(*p)
+		if err != nil {
+			return "", err
+		}
+		if real, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = real
+		}
+		*p = abs
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return "", nil
+	}
+
+	return filepath.ToSlash(rel) + "/", nil
+}
+
+func commitTree(r *git.Repository, rev string) (*object.Tree, error) {
+	h, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("commit %v: %v", rev, err)
+	}
+
+	c, err := r.CommitObject(*h)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Tree()
+}
+
+func diffCommits(r *git.Repository, from, to, prefix string) (*FileChanges, error) {
+	if to == "" {
+		to = "HEAD"
+	}
+
+	fromTree, err := commitTree(r, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toTree, err := commitTree(r, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &FileChanges{
+		Changed: map[string]bool{},
+		Deleted: map[string]bool{},
+		from:    fromTree,
+		prefix:  prefix,
+	}
+
+	for _, c := range diff {
+		action, err := c.Action()
+		if err != nil {
+			return nil, err
+		}
+
+		switch action {
+		case merkletrie.Delete:
+			if strings.HasPrefix(c.From.Name, prefix) {
+				changes.Deleted[strings.TrimPrefix(c.From.Name, prefix)] = true
+			}
+		default:
+			if strings.HasPrefix(c.To.Name, prefix) {
+				changes.Changed[strings.TrimPrefix(c.To.Name, prefix)] = true
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// touched tells whether a file of the spec was added, modified or deleted
+func (c *FileChanges) touched(name string) bool {
+	name = specPath(name)
+	return c.Changed[name] || c.Deleted[name]
+}
+
+// oldFS is a file system holding the given files as they were in the from commit
+func (c *FileChanges) oldFS(names ...string) (billy.Filesystem, error) {
+	fs := memfs.New()
+	for _, name := range names {
+		f, err := c.from.File(c.prefix + specPath(name))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", name, err)
+		}
+
+		contents, err := f.Contents()
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := fs.Create(specPath(name))
+		if err != nil {
+			return nil, err
+		}
+		_, err = out.Write([]byte(contents))
+		out.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fs, nil
+}
+
+// ChangeScope limits a sync to the APIs and policies of the files changed in a commit range,
+// by their IDs. Full is set when the changes can't be narrowed down, Reason tells why.
+type ChangeScope struct {
+	Full   bool
+	Reason string
+
+	APIs            map[string]bool
+	Policies        map[string]bool
+	DeletedAPIs     map[string]bool
+	DeletedPolicies map[string]bool
+}
+
+func fullScope(reason string) *ChangeScope {
+	return &ChangeScope{Full: true, Reason: reason}
+}
+
+// specWithoutObjects is the spec with the lists of API and policy files left out, the
+// spec file may change in those without requiring a full sync
+func specWithoutObjects(spec *TykSourceSpec) string {
+	rest := *spec
+	rest.Files = nil
+	rest.Policies = nil
+
+	raw, _ := json.Marshal(rest)
+	return string(raw)
+}
+
+func hasAPIInfo(infos []APIInfo, info APIInfo) bool {
+	for _, i := range infos {
+		if reflect.DeepEqual(i, info) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPolicyInfo(infos []PolicyInfo, info PolicyInfo) bool {
+	for _, i := range infos {
+		if reflect.DeepEqual(i, info) {
+			return true
+		}
+	}
+	return false
+}
+
+func policyIDs(pols []objects.Policy, ids map[string]bool) {
+	for _, p := range pols {
+		if p.ID != "" {
+			ids[p.ID] = true
+		}
+		if p.MID.Hex() != "" {
+			ids[p.MID.Hex()] = true
+		}
+	}
+}
+
+// NewChangeScope works out which APIs and policies of spec the changes touch. Entries of the
+// spec whose file changed, or that were added to the spec, are read with g to get their
+// IDs, entries removed from the spec are read from the from commit.
+func NewChangeScope(g Getter, spec *TykSourceSpec, changes *FileChanges) (*ChangeScope, error) {
+	if len(spec.Tenants) > 0 {
+		return fullScope("the spec fans out to tenants"), nil
+	}
+	if spec.Type == TYPE_OPERATOR {
+		return fullScope("resources of operator specs may depend on other files"), nil
+	}
+
+	for _, files := range []map[string]bool{changes.Changed, changes.Deleted} {
+		for name := range files {
+			if path.Base(name) == DefaultsFile {
+				return fullScope("the defaults of a directory changed"), nil
+			}
+		}
+	}
+
+	for _, inc := range spec.includes {
+		if inc != SpecFile && changes.touched(inc) {
+			return fullScope("a spec file the spec includes changed"), nil
+		}
+	}
+
+	old := spec
+	if changes.touched(".tyk.json") {
+		if changes.Deleted[".tyk.json"] {
+			return fullScope("the spec file was deleted"), nil
+		}
+
+		fs, err := changes.oldFS(".tyk.json")
+		if err != nil {
+			return fullScope("the spec file was added"), nil
+		}
+		if old, err = fetchSpec(fs); err != nil {
+			// The spec files it included are not at hand
+			return fullScope("the previous spec file can't be read on its own"), nil
+		}
+		if len(old.patterns) > 0 {
+			// Only the spec file is at hand, the files its patterns matched are not
+			return fullScope("the spec file changed and lists files by pattern"), nil
+		}
+
+		if specWithoutObjects(old) != specWithoutObjects(spec) {
+			return fullScope("the spec file changed"), nil
+		}
+	}
+
+	for name := range changes.Deleted {
+		if matchesAny(name, spec.patterns) {
+			return fullScope("a file listed by pattern was deleted"), nil
+		}
+	}
+
+	scope := &ChangeScope{
+		APIs:            map[string]bool{},
+		Policies:        map[string]bool{},
+		DeletedAPIs:     map[string]bool{},
+		DeletedPolicies: map[string]bool{},
+	}
+
+	current := *spec
+	current.Files = []APIInfo{}
+	current.Policies = []PolicyInfo{}
+	for _, info := range spec.Files {
+		if changes.touched(info.File) || !hasAPIInfo(old.Files, info) {
+			current.Files = append(current.Files, info)
+		}
+	}
+	for _, info := range spec.Policies {
+		if changes.touched(info.File) || !hasPolicyInfo(old.Policies, info) {
+			current.Policies = append(current.Policies, info)
+		}
+	}
+
+	if len(current.Files) > 0 {
+		defs, err := g.FetchAPIDef(&current)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range defs {
+			scope.APIs[d.APIID] = true
+		}
+	}
+	if len(current.Policies) > 0 {
+		pols, err := g.FetchPolicies(&current)
+		if err != nil {
+			return nil, err
+		}
+		policyIDs(pols, scope.Policies)
+	}
+
+	removed := *old
+	removed.Files = []APIInfo{}
+	removed.Policies = []PolicyInfo{}
+	names := []string{}
+	for _, info := range old.Files {
+		if !hasAPIInfo(spec.Files, info) {
+			removed.Files = append(removed.Files, info)
+			names = append(names, info.File)
+		}
+	}
+	for _, info := range old.Policies {
+		if !hasPolicyInfo(spec.Policies, info) {
+			removed.Policies = append(removed.Policies, info)
+			names = append(names, info.File)
+		}
+	}
+
+	if len(names) > 0 {
+		fs, err := changes.oldFS(names...)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(removed.Files) > 0 {
+			defs, err := fetchAPIDefinitions(fs, &removed)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range defs {
+				if !scope.APIs[d.APIID] {
+					scope.DeletedAPIs[d.APIID] = true
+				}
+			}
+		}
+		if len(removed.Policies) > 0 {
+			pols, err := fetchPolicies(fs, &removed)
+			if err != nil {
+				return nil, err
+			}
+			deleted := map[string]bool{}
+			policyIDs(pols, deleted)
+			for id := range deleted {
+				if !scope.Policies[id] {
+					scope.DeletedPolicies[id] = true
+				}
+			}
+		}
+	}
+
+	return scope, nil
+}
+
+func (s *ChangeScope) keep(items []objects.SyncItem, ids map[string]bool) []objects.SyncItem {
+	kept := []objects.SyncItem{}
+	for _, item := range items {
+		if ids[item.ID] {
+			kept = append(kept, item)
+		}
+	}
+
+	return kept
+}
+
+// Check can be used as the plan check of a client, it drops the objects that weren't changed
+// in the commit range from the plan. s may be nil.
+func (s *ChangeScope) Check(plan *objects.SyncPlan) error {
+	if s == nil || s.Full {
+		return nil
+	}
+
+	changed, deleted := s.APIs, s.DeletedAPIs
+	if plan.Kind == "policies" {
+		changed, deleted = s.Policies, s.DeletedPolicies
+	}
+
+	plan.Create = s.keep(plan.Create, changed)
+	plan.Update = s.keep(plan.Update, changed)
+	plan.Delete = s.keep(plan.Delete, deleted)
+
+	return nil
+}
+
+// Select keeps the APIs and policies in the scope, for publish and update. s may be nil.
+func (s *ChangeScope) Select(defs []objects.DBApiDefinition, pols []objects.Policy) ([]objects.DBApiDefinition, []objects.Policy) {
+	if s == nil || s.Full {
+		return defs, pols
+	}
+
+	selectedDefs := []objects.DBApiDefinition{}
+	for _, d := range defs {
+		if s.APIs[d.APIID] {
+			selectedDefs = append(selectedDefs, d)
+		}
+	}
+
+	selectedPols := []objects.Policy{}
+	for _, p := range pols {
+		if s.Policies[p.ID] || (p.MID.Hex() != "" && s.Policies[p.MID.Hex()]) {
+			selectedPols = append(selectedPols, p)
+		}
+	}
+
+	return selectedDefs, selectedPols
+}
+
+// Size is the number of APIs and policies in the scope
+func (s *ChangeScope) Size() int {
+	return len(s.APIs) + len(s.Policies) + len(s.DeletedAPIs) + len(s.DeletedPolicies)
+}
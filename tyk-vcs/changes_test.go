@@ -0,0 +1,142 @@
+package tyk_vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func commitFiles(t *testing.T, root string, w *git.Worktree, files map[string]string) string {
+	for name, contents := range files {
+		p := filepath.Join(root, "apis", name)
+		if contents == "" {
+			if _, err := w.Remove(filepath.ToSlash(filepath.Join("apis", name))); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		if err := ioutil.WriteFile(p, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Add(filepath.ToSlash(filepath.Join("apis", name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h, err := w.Commit("change", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h.String()
+}
+
+func TestChangeScope(t *testing.T) {
+	root, err := ioutil.TempDir("", "tyk-changes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := os.Mkdir(filepath.Join(root, "apis"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := commitFiles(t, root, w, map[string]string{
+		".tyk.json": `{"type": "apidef", "files": [{"file": "a.json"}, {"file": "b.json"}], "policies": [{"file": "p.json"}]}`,
+		"a.json":    `{"api_id": "a", "name": "A"}`,
+		"b.json":    `{"api_id": "b", "name": "B"}`,
+		"p.json":    `{"id": "p", "name": "P"}`,
+	})
+	to := commitFiles(t, root, w, map[string]string{
+		".tyk.json": `{"type": "apidef", "files": [{"file": "a.json"}, {"file": "c.json"}], "policies": [{"file": "p.json"}]}`,
+		"a.json":    `{"api_id": "a", "name": "A v2"}`,
+		"b.json":    "",
+		"c.json":    `{"api_id": "c", "name": "C"}`,
+	})
+
+	g, _ := NewFSGetter(filepath.Join(root, "apis"))
+	changes, err := g.Changes(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !changes.Changed["a.json"] || !changes.Changed["c.json"] || !changes.Deleted["b.json"] || changes.Changed["p.json"] {
+		t.Fatalf("unexpected changes: %v, deleted: %v", changes.Changed, changes.Deleted)
+	}
+
+	spec, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scope, err := NewChangeScope(g, spec, changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scope.Full {
+		t.Fatalf("expected a limited scope, got a full one: %v", scope.Reason)
+	}
+	if len(scope.APIs) != 2 || !scope.APIs["a"] || !scope.APIs["c"] || !scope.DeletedAPIs["b"] || len(scope.Policies) != 0 {
+		t.Fatalf("unexpected scope: %+v", scope)
+	}
+
+	plan := &objects.SyncPlan{
+		Kind:   "APIs",
+		Update: []objects.SyncItem{{ID: "a"}, {ID: "x"}},
+		Create: []objects.SyncItem{{ID: "c"}},
+		Delete: []objects.SyncItem{{ID: "b"}, {ID: "y"}},
+	}
+	if err := scope.Check(plan); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Update) != 1 || len(plan.Create) != 1 || len(plan.Delete) != 1 || plan.Delete[0].ID != "b" {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+
+	polPlan := &objects.SyncPlan{Kind: "policies", Update: []objects.SyncItem{{ID: "p"}}}
+	scope.Check(polPlan)
+	if len(polPlan.Update) != 0 {
+		t.Fatalf("unchanged policy kept: %+v", polPlan)
+	}
+
+	if _, err := g.Changes(from, from); err == nil {
+		t.Fatal("expected an error for a commit that isn't checked out")
+	}
+}
+
+func TestChangeScope_SpecChanged(t *testing.T) {
+	changes := &FileChanges{Changed: map[string]bool{}, Deleted: map[string]bool{".tyk.json": true}}
+
+	scope, err := NewChangeScope(nil, &TykSourceSpec{Type: TYPE_APIDEF}, changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !scope.Full {
+		t.Fatal("expected a full scope when the spec file is deleted")
+	}
+
+	plan := &objects.SyncPlan{Kind: "APIs", Update: []objects.SyncItem{{ID: "a"}}}
+	scope.Check(plan)
+	if len(plan.Update) != 1 {
+		t.Fatal("a full scope must not change the plan")
+	}
+}
@@ -0,0 +1,240 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/levigross/grequests"
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+// ContractTestInfo points to a file of contract tests, the API ID overrides the one of the file
+type ContractTestInfo struct {
+	File  string `json:"file,omitempty"`
+	APIID string `json:"api_id,omitempty"`
+}
+
+// ContractTests are the request and response cases of an API, run against a gateway after
+// publishing to catch misconfigured middleware, e.g. an API left open by a wrong auth setting
+type ContractTests struct {
+	APIID string         `json:"api_id"`
+	Cases []ContractCase `json:"cases"`
+}
+
+// ContractCase is a request to an API and the response expected. Header values and the body
+// may hold ${TYK_SECRET_...} placeholders, e.g. for the keys used, which are set from the
+// environment.
+type ContractCase struct {
+	Name string `json:"name"`
+	// Method defaults to GET
+	Method string `json:"method,omitempty"`
+	// Path is relative to the listen path of the API, e.g. /orders/1
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Expect  ContractExpect    `json:"expect"`
+}
+
+// ContractExpect is the response a case expects, only the fields set are compared
+type ContractExpect struct {
+	Status int `json:"status,omitempty"`
+	// Headers are compared to the values of the response, an empty value means the header must
+	// not be set
+	Headers      map[string]string `json:"headers,omitempty"`
+	BodyContains string            `json:"body_contains,omitempty"`
+}
+
+// ContractResult is the outcome of a case
+type ContractResult struct {
+	APIID  string `json:"api_id"`
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Passed bool   `json:"passed"`
+	// StatusCode is the status the gateway answered with
+	StatusCode int `json:"status_code,omitempty"`
+	// Failures are the expectations the response didn't meet
+	Failures []string `json:"failures,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+func fetchContractTests(fs billy.Filesystem, spec *TykSourceSpec) ([]ContractTests, error) {
+	tests := make([]ContractTests, len(spec.ContractTests))
+	for i, info := range spec.ContractTests {
+		raw, err := readTextFile(fs, info.File)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(raw, &tests[i]); err != nil {
+			return nil, fmt.Errorf("%v: %v", info.File, err)
+		}
+		if info.APIID != "" {
+			tests[i].APIID = info.APIID
+		}
+		if tests[i].APIID == "" {
+			return nil, fmt.Errorf("%v: the contract tests have no api_id", info.File)
+		}
+	}
+
+	fmt.Printf("Fetched %v contract test files\n", len(tests))
+	return tests, nil
+}
+
+// ContractRunner sends the requests of contract tests to a gateway
+type ContractRunner struct {
+	GatewayURL string
+	// Lookup returns the values of the placeholders, os.LookupEnv if not set
+	Lookup func(string) (string, bool)
+	// Timeout is the timeout of each request, 10s if not set
+	Timeout time.Duration
+}
+
+func (r *ContractRunner) resolve(s string) (string, error) {
+	missing := []string{}
+	resolved := placeholderMatch.ReplaceAllStringFunc(s, func(m string) string {
+		name := placeholderMatch.FindStringSubmatch(m)[1]
+		val, ok := r.Lookup(name)
+		if !ok {
+			missing = append(missing, name)
+			return m
+		}
+		return val
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("secrets referenced by the test are not set: %v", strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
+
+// runCase sends the request of a case to the API and compares the response
+func (r *ContractRunner) runCase(def objects.DBApiDefinition, c ContractCase) ContractResult {
+	res := ContractResult{APIID: def.APIID, Name: c.Name, Method: c.Method, Path: c.Path}
+	if res.Method == "" {
+		res.Method = http.MethodGet
+	}
+
+	headers := map[string]string{}
+	for k, v := range c.Headers {
+		resolved, err := r.resolve(v)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		headers[k] = resolved
+	}
+	body, err := r.resolve(c.Body)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	ro := &grequests.RequestOptions{
+		Headers: headers,
+		// APIs on a custom domain are only routed for that host
+		Host: def.Domain,
+		// Redirects are responses to check, not to follow
+		HTTPClient: &http.Client{
+			Timeout: r.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	if body != "" {
+		ro.RequestBody = strings.NewReader(body)
+	}
+
+	path := "/" + strings.TrimPrefix(def.Proxy.ListenPath, "/")
+	if p := strings.TrimPrefix(c.Path, "/"); p != "" {
+		path = strings.TrimSuffix(path, "/") + "/" + p
+	}
+	url := strings.TrimSuffix(r.GatewayURL, "/") + path
+
+	resp, err := grequests.DoRegularRequest(res.Method, url, ro)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer resp.Close()
+	res.StatusCode = resp.StatusCode
+
+	if c.Expect.Status != 0 && resp.StatusCode != c.Expect.Status {
+		res.Failures = append(res.Failures, fmt.Sprintf("expected status %v, got %v", c.Expect.Status, resp.StatusCode))
+	}
+
+	names := []string{}
+	for name := range c.Expect.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		expected, got := c.Expect.Headers[name], resp.Header.Get(name)
+		switch {
+		case expected == "" && got != "":
+			res.Failures = append(res.Failures, fmt.Sprintf("expected no %v header, got %q", name, got))
+		case expected != "" && got != expected:
+			res.Failures = append(res.Failures, fmt.Sprintf("expected header %v: %q, got %q", name, expected, got))
+		}
+	}
+
+	if c.Expect.BodyContains != "" && !strings.Contains(resp.String(), c.Expect.BodyContains) {
+		res.Failures = append(res.Failures, fmt.Sprintf("expected the body to contain %q", c.Expect.BodyContains))
+	}
+
+	res.Passed = len(res.Failures) == 0
+	return res
+}
+
+// Run runs the cases of the tests of the published APIs, tests of APIs that weren't published
+// or are inactive are skipped
+func (r *ContractRunner) Run(tests []ContractTests, defs []objects.DBApiDefinition) []ContractResult {
+	if r.Timeout == 0 {
+		r.Timeout = 10 * time.Second
+	}
+	if r.Lookup == nil {
+		r.Lookup = os.LookupEnv
+	}
+
+	published := map[string]objects.DBApiDefinition{}
+	for _, def := range defs {
+		if def.APIDefinition != nil && def.Active {
+			published[def.APIID] = def
+		}
+	}
+
+	results := []ContractResult{}
+	for _, t := range tests {
+		def, ok := published[t.APIID]
+		if !ok {
+			continue
+		}
+		for _, c := range t.Cases {
+			results = append(results, r.runCase(def, c))
+		}
+	}
+
+	return results
+}
+
+// ContractFailures returns an error describing the failed contract tests, if any
+func ContractFailures(results []ContractResult) error {
+	failed := 0
+	for _, res := range results {
+		if !res.Passed {
+			failed++
+		}
+	}
+
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%v of %v contract tests failed", failed, len(results))
+}
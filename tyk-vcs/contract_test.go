@@ -0,0 +1,113 @@
+package tyk_vcs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+func TestFetchContractTests(t *testing.T) {
+	g := memGetter(t, map[string]string{
+		".tyk.json":                 `{"include": ["teams/a/.tyk.json"], "contract_tests": [{"file": "tests/users.json", "api_id": "users-prod"}]}`,
+		"tests/users.json":          `{"api_id": "users", "cases": [{"name": "needs a key", "path": "/", "expect": {"status": 401}}]}`,
+		"teams/a/.tyk.json":         `{"contract_tests": [{"file": "tests/orders.json"}]}`,
+		"teams/a/tests/orders.json": `{"api_id": "orders", "cases": []}`,
+		"teams/b/tests/broken.json": `{"cases": []}`,
+	})
+
+	spec, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests, err := g.FetchContractTests(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tests) != 2 || tests[0].APIID != "orders" || tests[1].APIID != "users-prod" || len(tests[1].Cases) != 1 {
+		t.Errorf("unexpected contract tests %+v", tests)
+	}
+
+	spec.ContractTests = []ContractTestInfo{{File: "teams/b/tests/broken.json"}}
+	if _, err := g.FetchContractTests(spec); err == nil {
+		t.Error("expected tests without an API ID to fail")
+	}
+}
+
+func TestContractRunner(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/1":
+			if r.Header.Get("Authorization") != "key-1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("X-Cache", "HIT")
+			w.Write([]byte(`{"id": 1}`))
+		case "/users/old":
+			http.Redirect(w, r, "/users/1", http.StatusMovedPermanently)
+		case "/orders/":
+			if r.Host != "orders.example.com" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("X-Upstream", "orders-1")
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	env := map[string]string{"TYK_SECRET_USERS_KEY": "key-1"}
+	runner := &ContractRunner{GatewayURL: ts.URL, Lookup: func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}}
+
+	results := runner.Run([]ContractTests{
+		{APIID: "users", Cases: []ContractCase{
+			{Name: "needs a key", Path: "/1", Expect: ContractExpect{Status: 401}},
+			{Name: "with a key", Path: "/1", Headers: map[string]string{"Authorization": "${TYK_SECRET_USERS_KEY}"},
+				Expect: ContractExpect{Status: 200, Headers: map[string]string{"X-Cache": "HIT"}, BodyContains: `"id": 1`}},
+			{Name: "redirects", Path: "old", Expect: ContractExpect{Status: 301}},
+			{Name: "unknown secret", Path: "/1", Headers: map[string]string{"Authorization": "${TYK_SECRET_OTHER}"}},
+		}},
+		{APIID: "orders", Cases: []ContractCase{
+			{Name: "hides the upstream", Method: http.MethodPost, Body: `{}`,
+				Expect: ContractExpect{Status: 201, Headers: map[string]string{"X-Upstream": ""}}},
+		}},
+		{APIID: "inactive", Cases: []ContractCase{{Name: "skipped"}}},
+		{APIID: "unknown", Cases: []ContractCase{{Name: "skipped"}}},
+	}, []objects.DBApiDefinition{
+		liveDef("users", "/users/", "", true),
+		liveDef("orders", "/orders/", "orders.example.com", true),
+		liveDef("inactive", "/inactive/", "", false),
+	})
+
+	if len(results) != 5 {
+		t.Fatalf("expected the cases of the published APIs, got %+v", results)
+	}
+	for _, i := range []int{0, 1, 2} {
+		if !results[i].Passed {
+			t.Errorf("%v: expected to pass, got %+v", results[i].Name, results[i])
+		}
+	}
+	if results[1].Method != http.MethodGet {
+		t.Errorf("expected GET by default, got %v", results[1].Method)
+	}
+	if results[3].Passed || results[3].Error == "" {
+		t.Errorf("expected a missing secret to fail the case, got %+v", results[3])
+	}
+	if results[4].Passed || len(results[4].Failures) != 1 || results[4].StatusCode != 201 {
+		t.Errorf("expected the upstream header to fail the case, got %+v", results[4])
+	}
+
+	if err := ContractFailures(results); err == nil || err.Error() != "2 of 5 contract tests failed" {
+		t.Errorf("unexpected failures %v", err)
+	}
+	if err := ContractFailures(results[:3]); err != nil {
+		t.Errorf("expected no failures, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package tyk_vcs
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func usesCustomMiddleware(mw apidef.MiddlewareSection) bool {
+	return len(mw.Pre) > 0 ||
+		len(mw.Post) > 0 ||
+		len(mw.PostKeyAuth) > 0 ||
+		len(mw.Response) > 0 ||
+		mw.AuthCheck.Name != ""
+}
+
+// CoprocessWarnings checks the custom middleware (plugin) settings of the definitions. enabledDrivers lists
+// the plugin drivers the target gateways have been configured for, when it is empty that check is skipped.
+func CoprocessWarnings(defs []objects.DBApiDefinition, enabledDrivers []string) []string {
+	enabled := map[apidef.MiddlewareDriver]bool{}
+	for _, d := range enabledDrivers {
+		enabled[apidef.MiddlewareDriver(d)] = true
+	}
+
+	warnings := []string{}
+	for _, def := range defs {
+		if def.APIDefinition == nil {
+			continue
+		}
+
+		mw := def.CustomMiddleware
+		uses := usesCustomMiddleware(mw) || def.EnableCoProcessAuth
+
+		// Without a driver the gateway runs the hooks in its JSVM, only custom auth needs one
+		if def.EnableCoProcessAuth && mw.Driver == "" {
+			warnings = append(warnings, fmt.Sprintf("API %v enables coprocess auth but no driver is set", def.APIID))
+			continue
+		}
+		if uses && mw.Driver == "" {
+			mw.Driver = apidef.OttoDriver
+		}
+
+		if def.EnableCoProcessAuth && mw.AuthCheck.Name == "" {
+			warnings = append(warnings, fmt.Sprintf("API %v enables coprocess auth but has no auth_check middleware", def.APIID))
+		}
+
+		if mw.Driver == apidef.PythonDriver && def.CustomMiddlewareBundle == "" && uses {
+			warnings = append(warnings, fmt.Sprintf("API %v uses the python driver without a custom_middleware_bundle", def.APIID))
+		}
+
+		if uses && len(enabled) > 0 && !enabled[mw.Driver] {
+			warnings = append(warnings, fmt.Sprintf("API %v uses the %v plugin driver, which is not enabled on the target", def.APIID, mw.Driver))
+		}
+	}
+
+	return warnings
+}
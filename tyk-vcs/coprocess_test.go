@@ -0,0 +1,116 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func grpcDefinition() objects.DBApiDefinition {
+	def := &apidef.APIDefinition{
+		APIID:               "grpc-api",
+		Name:                "gRPC plugin API",
+		EnableCoProcessAuth: true,
+	}
+	def.CustomMiddlewareBundle = "bundle-v1.zip"
+	def.CustomMiddleware = apidef.MiddlewareSection{
+		Driver:    apidef.GrpcDriver,
+		Pre:       []apidef.MiddlewareDefinition{{Name: "MyPreHook"}},
+		Post:      []apidef.MiddlewareDefinition{{Name: "MyPostHook", RequireSession: true}},
+		Response:  []apidef.MiddlewareDefinition{{Name: "MyResponseHook"}},
+		AuthCheck: apidef.MiddlewareDefinition{Name: "MyAuthCheck"},
+		IdExtractor: apidef.MiddlewareIdExtractor{
+			ExtractFrom:     apidef.HeaderSource,
+			ExtractWith:     apidef.ValueExtractor,
+			ExtractorConfig: map[string]interface{}{"header_name": "Authorization"},
+		},
+	}
+
+	return objects.DBApiDefinition{APIDefinition: def}
+}
+
+func writeSpecFS(t *testing.T, raw []byte) billy.Filesystem {
+	fs := memfs.New()
+	f, err := fs.Create("api-grpc-api.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(raw)
+	f.Close()
+
+	return fs
+}
+
+func TestCoprocessRoundTrip(t *testing.T) {
+	orig := grpcDefinition()
+	spec := &TykSourceSpec{Type: TYPE_APIDEF, Files: []APIInfo{{File: "api-grpc-api.json"}}}
+
+	// Dump format (wrapped in api_definition) and a raw gateway definition
+	dumped, err := json.MarshalIndent(orig, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asGateway, err := json.Marshal(orig.APIDefinition)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, raw := range map[string][]byte{"dump": dumped, "gateway": asGateway} {
+		defs, err := fetchAPIDefinitions(writeSpecFS(t, raw), spec)
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+
+		got := defs[0]
+		if !reflect.DeepEqual(got.CustomMiddleware, orig.CustomMiddleware) {
+			t.Fatalf("%v: custom middleware did not survive, expected: %+v, got %+v", name, orig.CustomMiddleware, got.CustomMiddleware)
+		}
+
+		if got.CustomMiddlewareBundle != orig.CustomMiddlewareBundle {
+			t.Fatalf("%v: bundle was not retained, expected: %v, got %v", name, orig.CustomMiddlewareBundle, got.CustomMiddlewareBundle)
+		}
+
+		if !got.EnableCoProcessAuth {
+			t.Fatalf("%v: enable_coprocess_auth was lost", name)
+		}
+	}
+}
+
+func TestCoprocessWarnings(t *testing.T) {
+	def := grpcDefinition()
+
+	if w := CoprocessWarnings([]objects.DBApiDefinition{def}, nil); len(w) != 0 {
+		t.Fatalf("Expected no warnings without driver list, got: %v", w)
+	}
+
+	if w := CoprocessWarnings([]objects.DBApiDefinition{def}, []string{"grpc"}); len(w) != 0 {
+		t.Fatalf("Expected no warnings when the driver is enabled, got: %v", w)
+	}
+
+	if w := CoprocessWarnings([]objects.DBApiDefinition{def}, []string{"python"}); len(w) != 1 {
+		t.Fatalf("Expected a warning for a missing driver, got: %v", w)
+	}
+
+	def.CustomMiddleware.Driver = ""
+	if w := CoprocessWarnings([]objects.DBApiDefinition{def}, nil); len(w) != 1 {
+		t.Fatalf("Expected a warning for coprocess auth without a driver, got: %v", w)
+	}
+
+	// Hooks without a driver run in the JSVM of the gateway
+	def.EnableCoProcessAuth = false
+	def.CustomMiddleware.AuthCheck = apidef.MiddlewareDefinition{}
+	if w := CoprocessWarnings([]objects.DBApiDefinition{def}, nil); len(w) != 0 {
+		t.Fatalf("Expected no warnings for JSVM hooks, got: %v", w)
+	}
+	if w := CoprocessWarnings([]objects.DBApiDefinition{def}, []string{"otto"}); len(w) != 0 {
+		t.Fatalf("Expected no warnings when the JSVM is enabled, got: %v", w)
+	}
+	if w := CoprocessWarnings([]objects.DBApiDefinition{def}, []string{"grpc"}); len(w) != 1 {
+		t.Fatalf("Expected a warning when the JSVM isn't enabled, got: %v", w)
+	}
+}
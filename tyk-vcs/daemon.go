@@ -0,0 +1,293 @@
+package tyk_vcs
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxWebhookBody caps the size of the webhook payloads read
+const maxWebhookBody = 10 << 20
+
+// DaemonStatus is what the health endpoints of a Daemon report
+type DaemonStatus struct {
+	Ready   bool `json:"ready"`
+	Syncing bool `json:"syncing"`
+	// Leader is set if the instance applies changes, always without leader election
+	Leader bool `json:"leader"`
+	// Stopping is set once Shutdown was called, no sync is started after it
+	Stopping bool `json:"stopping"`
+	// LastSync is when the last sync finished, LastError is its error
+	LastSync  time.Time `json:"last_sync,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Daemon runs syncs on demand, e.g. on push webhooks, and serves the endpoints an
+// orchestrator like Kubernetes probes. Syncs never overlap: a sync triggered while one runs
+// is started once it finished, however many triggers came in meanwhile.
+type Daemon struct {
+	// Sync runs one sync. ctx is canceled when the instance loses the lease of its Elector,
+	// the sync must stop changing the target then as another replica may be syncing.
+	Sync func(ctx context.Context) error
+	// Repo and Branch (a full ref name) are what pushes must be for to trigger a sync, with an
+	// empty Repo any push does
+	Repo   string
+	Branch string
+	// Elector, if set, elects the instance syncing among the replicas of the daemon, the
+	// others stand by and pass the pushes they get on to the leader
+	Elector *LeaderElector
+	// Secret, if set, is what webhooks must be signed with, see VerifyWebhook
+	Secret string
+
+	mu       sync.Mutex
+	running  bool
+	pending  bool
+	stopping bool
+	synced   bool
+	idle     chan struct{}
+	lastSync time.Time
+	lastErr  error
+	// cancelSync cancels the context of the running sync
+	cancelSync context.CancelFunc
+
+	stopElector context.CancelFunc
+	electorDone chan struct{}
+}
+
+// leads tells whether the instance applies changes
+func (d *Daemon) leads() bool {
+	return d.Elector == nil || d.Elector.IsLeader()
+}
+
+// Start runs the first sync, or with an Elector starts racing for the lease: the instance
+// syncs once it is elected
+func (d *Daemon) Start() {
+	if d.Elector == nil {
+		d.Trigger()
+		return
+	}
+
+	// Losing the lease stops the running sync
+	onChange := d.Elector.OnChange
+	d.Elector.OnChange = func(leader bool) {
+		if onChange != nil {
+			onChange(leader)
+		}
+		if !leader {
+			d.stopSync()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.mu.Lock()
+	d.stopElector = cancel
+	d.electorDone = make(chan struct{})
+	d.mu.Unlock()
+
+	go func() {
+		defer close(d.electorDone)
+		d.Elector.Run(ctx, func() { d.Trigger() })
+	}()
+}
+
+// Trigger starts a sync, or queues one if a sync is running, standbys do nothing. It returns
+// false once the daemon is stopping.
+func (d *Daemon) Trigger() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopping {
+		return false
+	}
+	if !d.leads() {
+		return true
+	}
+
+	if d.running {
+		d.pending = true
+		return true
+	}
+
+	d.running = true
+	d.idle = make(chan struct{})
+	go d.run()
+	return true
+}
+
+// stopSync cancels the context of the running sync, if there is one
+func (d *Daemon) stopSync() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.cancelSync != nil {
+		d.cancelSync()
+	}
+}
+
+func (d *Daemon) run() {
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		d.mu.Lock()
+		d.cancelSync = cancel
+		d.mu.Unlock()
+
+		err := d.Sync(ctx)
+		cancel()
+
+		d.mu.Lock()
+		d.cancelSync = nil
+		d.lastSync = time.Now()
+		d.lastErr = err
+		if err == nil {
+			d.synced = true
+		}
+
+		if d.pending && !d.stopping && d.leads() {
+			d.pending = false
+			d.mu.Unlock()
+			continue
+		}
+
+		d.pending = false
+		d.running = false
+		close(d.idle)
+		d.mu.Unlock()
+		return
+	}
+}
+
+// Shutdown stops starting syncs and waits for the running one to finish, or for ctx to be
+// done, then releases the lease of the Elector
+func (d *Daemon) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	d.stopping = true
+	d.pending = false
+	idle := d.idle
+	running := d.running
+	stopElector, electorDone := d.stopElector, d.electorDone
+	d.mu.Unlock()
+
+	if running {
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if stopElector == nil {
+		return nil
+	}
+
+	stopElector()
+	select {
+	case <-electorDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Status returns the state of the daemon. It is ready once a sync succeeded, or while it
+// stands by, until Shutdown.
+func (d *Daemon) Status() DaemonStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	leader := d.leads()
+	s := DaemonStatus{
+		Ready:    (d.synced || !leader) && !d.stopping,
+		Syncing:  d.running,
+		Leader:   leader,
+		Stopping: d.stopping,
+		LastSync: d.lastSync,
+	}
+	if d.lastErr != nil {
+		s.LastError = d.lastErr.Error()
+	}
+
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Handler serves /healthz, which answers as long as the process does (a failed sync doesn't
+// make the daemon unhealthy), /readyz, which fails until the leader synced and while stopping,
+// and /webhook, which triggers a sync on pushes to the Repo and Branch, on the leader. With a
+// Secret, webhooks that aren't signed with it are rejected.
+func (d *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, d.Status())
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		s := d.Status()
+		code := http.StatusOK
+		if !s.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, s)
+	})
+
+	mux.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"message": "webhooks must be POSTed"})
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+
+		if err := VerifyWebhook(r.Header, body, d.Secret); err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": err.Error()})
+			return
+		}
+
+		events, err := ParsePushEvents(r.Header, body)
+		if err == ErrNotPush {
+			writeJSON(w, http.StatusAccepted, map[string]string{"message": "ignored, not a push"})
+			return
+		} else if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+
+		for _, e := range events {
+			if e.Deleted || (d.Repo != "" && !e.Matches(d.Repo, d.Branch)) {
+				continue
+			}
+
+			if !d.leads() {
+				if err := d.Elector.Lease.RequestSync(); err != nil {
+					writeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "standby, could not pass the push on to the leader: " + err.Error()})
+					return
+				}
+				writeJSON(w, http.StatusAccepted, map[string]string{"message": "standby, sync requested from the leader"})
+				return
+			}
+
+			if !d.Trigger() {
+				writeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "shutting down"})
+				return
+			}
+			writeJSON(w, http.StatusAccepted, map[string]string{"message": "sync triggered"})
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, map[string]string{"message": "ignored, not a push to the synced branch"})
+	})
+
+	return mux
+}
@@ -0,0 +1,116 @@
+package tyk_vcs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testPush = `{"ref": "refs/heads/master", "before": "a1", "after": "b2", "repository": {"clone_url": "https://github.com/acme/apis.git"}}`
+
+func TestDaemon(t *testing.T) {
+	release := make(chan struct{})
+	var running, max, runs int32
+	fail := int32(1)
+
+	d := &Daemon{
+		Repo:   "git@github.com:acme/apis.git",
+		Branch: "refs/heads/master",
+		Sync: func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			if n > atomic.LoadInt32(&max) {
+				atomic.StoreInt32(&max, n)
+			}
+			<-release
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&runs, 1)
+			if atomic.LoadInt32(&fail) == 1 {
+				return errors.New("dashboard down")
+			}
+			return nil
+		},
+	}
+
+	ts := httptest.NewServer(d.Handler())
+	defer ts.Close()
+
+	get := func(path string) int {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	push := func(event, body string) int {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/webhook", strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if get("/healthz") != http.StatusOK || get("/readyz") != http.StatusServiceUnavailable {
+		t.Error("expected healthy and not ready before the first sync")
+	}
+
+	// Three pushes while the first sync runs queue a single one
+	for i := 0; i < 3; i++ {
+		if code := push("push", testPush); code != http.StatusAccepted {
+			t.Fatalf("expected the push accepted, got %v", code)
+		}
+	}
+	push("push", strings.Replace(testPush, "acme/apis", "acme/other", 1))
+	push("ping", `{}`)
+
+	release <- struct{}{}
+	atomic.StoreInt32(&fail, 0)
+	release <- struct{}{}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for d.Status().Syncing && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if r, m := atomic.LoadInt32(&runs), atomic.LoadInt32(&max); r != 2 || m != 1 {
+		t.Errorf("expected 2 syncs one at a time, got %v runs and %v at once", r, m)
+	}
+	if code := get("/readyz"); code != http.StatusOK {
+		t.Errorf("expected ready after a sync succeeded, got %v", code)
+	}
+
+	// Shutdown waits for the running sync and then refuses new ones
+	d.Trigger()
+	done := make(chan error)
+	go func() { done <- d.Shutdown(context.Background()) }()
+	select {
+	case <-done:
+		t.Fatal("shutdown returned while a sync was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release <- struct{}{}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if r := atomic.LoadInt32(&runs); r != 3 || d.Trigger() {
+		t.Errorf("expected the running sync finished and no new one, got %v runs", r)
+	}
+	if get("/readyz") != http.StatusServiceUnavailable || push("push", testPush) != http.StatusServiceUnavailable {
+		t.Error("expected not ready and pushes refused while stopping")
+	}
+
+	// With a secret, unsigned pushes are rejected before anything is parsed
+	d.Secret = "s3cret"
+	if code := push("push", testPush); code != http.StatusUnauthorized {
+		t.Errorf("expected an unsigned push rejected, got %v", code)
+	}
+}
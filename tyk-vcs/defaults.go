@@ -0,0 +1,137 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+// DefaultsFile holds the fields every API definition in its directory, and in the
+// directories below it, gets unless the definition sets them, e.g. the org ID, tags or auth
+// mode of a team's APIs
+const DefaultsFile = "_defaults.json"
+
+// copyValue deep copies decoded JSON, so merged defaults are not shared between definitions
+func copyValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[k] = copyValue(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = copyValue(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// mergeDefaults merges defaults into doc, doc wins: objects are merged key by key, any other
+// value set in doc, arrays included, replaces the default
+func mergeDefaults(doc, defaults map[string]interface{}) {
+	for k, def := range defaults {
+		cur, ok := doc[k]
+		if !ok {
+			doc[k] = copyValue(def)
+			continue
+		}
+
+		curObj, okCur := cur.(map[string]interface{})
+		defObj, okDef := def.(map[string]interface{})
+		if okCur && okDef {
+			mergeDefaults(curObj, defObj)
+		}
+	}
+}
+
+// definitionDoc is the API definition of a decoded file, which is either a definition or a
+// dump file wrapping it in api_definition
+func definitionDoc(doc map[string]interface{}) map[string]interface{} {
+	if inner, ok := doc["api_definition"].(map[string]interface{}); ok {
+		return inner
+	}
+	return doc
+}
+
+// dirDefaults reads the defaults files of the directories of a repo, once per directory
+type dirDefaults struct {
+	fs    billy.Filesystem
+	files map[string]map[string]interface{}
+}
+
+func newDirDefaults(fs billy.Filesystem) *dirDefaults {
+	return &dirDefaults{fs: fs, files: map[string]map[string]interface{}{}}
+}
+
+// read returns the defaults file of dir, nil if it has none
+func (d *dirDefaults) read(dir string) (map[string]interface{}, error) {
+	if defaults, ok := d.files[dir]; ok {
+		return defaults, nil
+	}
+
+	name := path.Join(dir, DefaultsFile)
+	raw, err := readTextFile(d.fs, name)
+	if os.IsNotExist(err) {
+		d.files[dir] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("%v: %v", name, err)
+	}
+
+	d.files[dir] = definitionDoc(doc)
+	return d.files[dir], nil
+}
+
+// apply merges the defaults of the directories of the definition file name into its JSON,
+// those of deeper directories over those of the directories above them
+func (d *dirDefaults) apply(name string, raw []byte) ([]byte, error) {
+	dirs := []string{""}
+	dir := ""
+	for _, part := range strings.Split(path.Dir(specPath(name)), "/") {
+		if part == "." {
+			continue
+		}
+		dir = path.Join(dir, part)
+		dirs = append(dirs, dir)
+	}
+
+	var doc map[string]interface{}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		defaults, err := d.read(dirs[i])
+		if err != nil {
+			return nil, err
+		}
+		if defaults == nil {
+			continue
+		}
+
+		if doc == nil {
+			doc = map[string]interface{}{}
+			if err := json.Unmarshal(raw, &doc); err != nil {
+				return nil, fmt.Errorf("%v: %v", name, err)
+			}
+		}
+		mergeDefaults(definitionDoc(doc), defaults)
+	}
+
+	if doc == nil {
+		return raw, nil
+	}
+
+	return json.Marshal(doc)
+}
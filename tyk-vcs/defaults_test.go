@@ -0,0 +1,61 @@
+package tyk_vcs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDirDefaults(t *testing.T) {
+	g := memGetter(t, map[string]string{
+		".tyk.json": `{"type": "apidef", "files": [{"file": "top.json"}, {"file": "teams/**/*.json"}]}`,
+		"_defaults.json": `{"org_id": "org", "tags": ["internal"], "use_keyless": false, "use_standard_auth": true,
+			"proxy": {"strip_listen_path": true, "target_url": "http://default"}}`,
+		"teams/a/_defaults.json": `{"api_definition": {"tags": ["team-a"], "proxy": {"preserve_host_header": true}}}`,
+		"top.json":               `{"api_id": "top", "name": "Top", "proxy": {"listen_path": "/top/"}}`,
+		"teams/a/api.json": `{"api_definition": {"api_id": "a", "name": "A", "use_keyless": true,
+			"proxy": {"listen_path": "/a/", "target_url": "http://a"}}}`,
+		"teams/b/api.json": `{"api_id": "b", "name": "B", "org_id": "other", "tags": []}`,
+	})
+
+	spec, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Files) != 3 {
+		t.Fatalf("the defaults files should not be listed as definitions: %v", spec.Files)
+	}
+
+	defs, err := g.FetchAPIDef(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, a, b := defs[0], defs[1], defs[2]
+	if top.OrgID != "org" || !reflect.DeepEqual(top.Tags, []string{"internal"}) || !top.UseStandardAuth ||
+		top.Proxy.TargetURL != "http://default" || top.Proxy.ListenPath != "/top/" || !top.Proxy.StripListenPath {
+		t.Errorf("the root defaults were not applied: %+v", top.APIDefinition)
+	}
+
+	if a.OrgID != "org" || !reflect.DeepEqual(a.Tags, []string{"team-a"}) || !a.UseKeylessAccess ||
+		a.Proxy.TargetURL != "http://a" || !a.Proxy.PreserveHostHeader || !a.Proxy.StripListenPath {
+		t.Errorf("the team defaults were not applied over the root ones: %+v", a.APIDefinition)
+	}
+
+	if b.OrgID != "other" || len(b.Tags) != 0 || b.Proxy.PreserveHostHeader {
+		t.Errorf("the values of the definition should win: %+v", b.APIDefinition)
+	}
+}
+
+func TestMergeDefaultsCopies(t *testing.T) {
+	defaults := map[string]interface{}{"proxy": map[string]interface{}{"strip_listen_path": true}}
+
+	first := map[string]interface{}{}
+	mergeDefaults(first, defaults)
+	first["proxy"].(map[string]interface{})["listen_path"] = "/first/"
+
+	second := map[string]interface{}{}
+	mergeDefaults(second, defaults)
+	if _, ok := second["proxy"].(map[string]interface{})["listen_path"]; ok {
+		t.Error("the defaults are shared between definitions")
+	}
+}
@@ -0,0 +1,94 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// deprecation is a field or pattern of API definitions the gateway deprecated in a version,
+// check returns a warning, with the replacement, for each use in a definition
+type deprecation struct {
+	since string
+	check func(def *objects.DBApiDefinition) []string
+}
+
+func hasExtendedPaths(e apidef.ExtendedPathsSet) bool {
+	return len(e.Ignored) > 0 || len(e.WhiteList) > 0 || len(e.BlackList) > 0 || len(e.Cached) > 0 ||
+		len(e.Transform) > 0 || len(e.TransformResponse) > 0 || len(e.TransformHeader) > 0 ||
+		len(e.TransformResponseHeader) > 0 || len(e.HardTimeouts) > 0 || len(e.CircuitBreaker) > 0 ||
+		len(e.URLRewrite) > 0 || len(e.Virtual) > 0 || len(e.SizeLimit) > 0 || len(e.MethodTransforms) > 0 ||
+		len(e.TrackEndpoints) > 0 || len(e.DoNotTrackEndpoints) > 0 || len(e.ValidateJSON) > 0 ||
+		len(e.Internal) > 0
+}
+
+// sortedVersions lists the names of the versions of a definition, for stable warnings
+func sortedVersions(def *objects.DBApiDefinition) []string {
+	names := []string{}
+	for name := range def.VersionData.Versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+var deprecations = []deprecation{
+	{
+		since: "2.0",
+		check: func(def *objects.DBApiDefinition) []string {
+			found := []string{}
+			for _, name := range sortedVersions(def) {
+				v := def.VersionData.Versions[name]
+				if v.UseExtendedPaths {
+					continue
+				}
+
+				if len(v.Paths.Ignored) > 0 || len(v.Paths.WhiteList) > 0 || len(v.Paths.BlackList) > 0 {
+					found = append(found, fmt.Sprintf("version %v uses the legacy paths lists, move them to extended_paths and set use_extended_paths", name))
+				}
+				if hasExtendedPaths(v.ExtendedPaths) {
+					found = append(found, fmt.Sprintf("version %v has extended_paths, which are ignored as use_extended_paths is false", name))
+				}
+			}
+			return found
+		},
+	},
+	{
+		since: "2.9",
+		check: func(def *objects.DBApiDefinition) []string {
+			a := def.Auth
+			if len(def.AuthConfigs) > 0 || (a.AuthHeaderName == "" && !a.UseParam && a.ParamName == "" && !a.UseCookie && a.CookieName == "" && !a.UseCertificate) {
+				return nil
+			}
+			return []string{"uses the auth section, replace it with auth_configs.authToken"}
+		},
+	},
+}
+
+// DeprecationWarnings lists the deprecated fields and patterns the definitions use. With the
+// version of the target only what that version deprecated is reported, everything if the
+// version is empty or can't be read.
+func DeprecationWarnings(defs []objects.DBApiDefinition, targetVersion string) []string {
+	warnings := []string{}
+	for _, d := range deprecations {
+		if targetVersion != "" {
+			if deprecated, err := objects.AtLeastVersion(targetVersion, d.since); err == nil && !deprecated {
+				continue
+			}
+		}
+
+		for i := range defs {
+			if defs[i].APIDefinition == nil {
+				continue
+			}
+			for _, w := range d.check(&defs[i]) {
+				warnings = append(warnings, fmt.Sprintf("API %v %v (deprecated since %v)", defs[i].APIID, w, d.since))
+			}
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,39 @@
+package tyk_vcs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestDeprecationWarnings(t *testing.T) {
+	legacy := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: "legacy"}}
+	legacy.VersionData.Versions = map[string]apidef.VersionInfo{"Default": {}}
+	v := legacy.VersionData.Versions["Default"]
+	v.Paths.WhiteList = []string{"/a"}
+	v.ExtendedPaths.Ignored = []apidef.EndPointMeta{{Path: "/b"}}
+	legacy.VersionData.Versions["Default"] = v
+	legacy.Auth.AuthHeaderName = "Authorization"
+
+	current := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: "current"}}
+	current.VersionData.Versions = map[string]apidef.VersionInfo{"Default": {UseExtendedPaths: true}}
+	current.Auth.AuthHeaderName = "Authorization"
+	current.AuthConfigs = map[string]apidef.AuthConfig{"authToken": {AuthHeaderName: "Authorization"}}
+
+	warnings := DeprecationWarnings([]objects.DBApiDefinition{legacy, current}, "")
+	if len(warnings) != 3 {
+		t.Fatalf("expected 3 warnings, got %v", warnings)
+	}
+	for _, w := range warnings {
+		if !strings.HasPrefix(w, "API legacy ") {
+			t.Fatalf("unexpected warning %v", w)
+		}
+	}
+
+	// The auth section was deprecated by 2.9
+	if warnings := DeprecationWarnings([]objects.DBApiDefinition{legacy}, "v2.8.0"); len(warnings) != 2 {
+		t.Fatalf("expected only the paths warnings for 2.8, got %v", warnings)
+	}
+}
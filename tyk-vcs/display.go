@@ -0,0 +1,37 @@
+package tyk_vcs
+
+import (
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// DisplayInfo are the dashboard presentation fields of an API: the name shown in the UI and
+// the API categories, which the dashboard keeps as #hashtags at the end of the API name
+type DisplayInfo struct {
+	Name       string   `json:"name,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+}
+
+// Apply sets the display name and adds the categories to the categories already in the name
+// of def
+func (di *DisplayInfo) Apply(def *objects.DBApiDefinition) {
+	if di == nil || def.APIDefinition == nil {
+		return
+	}
+
+	name, categories := objects.SplitCategories(def.Name)
+	if di.Name != "" {
+		name = di.Name
+	}
+
+	def.Name = objects.JoinCategories(name, mergeStrings(categories, di.Categories))
+}
+
+// ApplyDisplay applies the display fields of the file entries to defs, each gets those of
+// the entry of the file it was read from
+func (ts *TykSourceSpec) ApplyDisplay(defs []objects.DBApiDefinition) {
+	for i := range defs {
+		if info, ok := ts.apiInfo(&defs[i]); ok {
+			info.Display.Apply(&defs[i])
+		}
+	}
+}
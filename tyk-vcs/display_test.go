@@ -0,0 +1,31 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestApplyDisplay(t *testing.T) {
+	spec := &TykSourceSpec{Files: []APIInfo{
+		{File: "a.json", Display: &DisplayInfo{Name: "Payments", Categories: []string{"finance", "public api"}}},
+		{File: "b.json", Display: &DisplayInfo{Categories: []string{"internal"}}},
+		{File: "c.json"},
+	}}
+	// Not in the order of the spec
+	defs := []objects.DBApiDefinition{
+		{APIDefinition: &apidef.APIDefinition{Name: "Users #core"}, File: "c.json"},
+		{APIDefinition: &apidef.APIDefinition{Name: "payments-v2 #finance"}, File: "a.json"},
+		{APIDefinition: &apidef.APIDefinition{Name: "Billing #v1"}, File: "b.json"},
+		{APIDefinition: &apidef.APIDefinition{Name: "Unlisted"}},
+	}
+
+	spec.ApplyDisplay(defs)
+
+	for i, expected := range []string{"Users #core", "Payments #finance #public-api", "Billing #v1 #internal", "Unlisted"} {
+		if defs[i].Name != expected {
+			t.Errorf("expected %q, got %q", expected, defs[i].Name)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package tyk_vcs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-diff"
+	"golang.org/x/crypto/openpgp"
+)
+
+// volatileFields change on the target without the object changing, e.g. the update time of
+// policies, they are left out of the hashes of a dump state
+var volatileFields = []string{"last_updated"}
+
+// DumpState has the hashes of the files of a dump, the next dump with the state only
+// rewrites the files of the objects that changed
+type DumpState struct {
+	Files map[string]string `json:"files"`
+}
+
+// ReadDumpState reads the state written by a previous dump, an empty state is returned if
+// the file doesn't exist
+func ReadDumpState(path string) (*DumpState, error) {
+	raw, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &DumpState{Files: map[string]string{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &DumpState{}
+	if err := json.Unmarshal(raw, state); err != nil {
+		return nil, fmt.Errorf("invalid dump state %v: %v", path, err)
+	}
+	if state.Files == nil {
+		state.Files = map[string]string{}
+	}
+	for fname := range state.Files {
+		if err := checkDumpFile(fname); err != nil {
+			return nil, fmt.Errorf("invalid dump state %v: %v", path, err)
+		}
+	}
+
+	return state, nil
+}
+
+// Write writes the state to path
+func (s *DumpState) Write(path string) error {
+	raw, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, raw, 0644)
+}
+
+// stateHash hashes the JSON of a dumped object without its volatile fields and the fields
+// matched by the ignore rules, which apply to the definition of API files
+func stateHash(raw []byte, ignore []string) string {
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &doc); err == nil {
+		for _, f := range volatileFields {
+			delete(doc, f)
+		}
+
+		if def, ok := doc["api_definition"]; ok {
+			if normalized, err := tyk_diff.Normalize(def, ignore); err == nil {
+				doc["api_definition"] = normalized
+			}
+		} else if normalized, err := tyk_diff.Normalize(doc, ignore); err == nil {
+			if m, ok := normalized.(map[string]interface{}); ok {
+				doc = m
+			}
+		}
+
+		if stable, err := json.Marshal(doc); err == nil {
+			raw = stable
+		}
+	}
+
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// DumpWriter writes the files of a dump into Dir. With the state of a previous dump, files
+// whose object didn't change are left as they are.
+type DumpWriter struct {
+	Dir string
+	// Recipients are who the files of sensitive objects are encrypted to, they are written
+	// in the clear if there are none
+	Recipients openpgp.EntityList
+	// Ignore are the rules, see tyk_diff.ParseRule, of the fields whose changes don't make
+	// an object changed
+	Ignore    []string
+	prev      *DumpState
+	next      *DumpState
+	Written   int
+	Unchanged int
+}
+
+// NewDumpWriter writes into dir, prev may be nil to write every file
+func NewDumpWriter(dir string, prev *DumpState) *DumpWriter {
+	return &DumpWriter{Dir: dir, prev: prev, next: &DumpState{Files: map[string]string{}}}
+}
+
+// WriteJSON writes obj as indented JSON to the file fname of the dump, unless it is
+// unchanged since the previous dump and the file is still there
+func (w *DumpWriter) WriteJSON(fname string, obj interface{}) error {
+	return w.writeJSON(fname, obj, false)
+}
+
+// WriteSensitiveJSON writes the file of a sensitive object, e.g. a key, encrypted to the
+// recipients when there are any, and returns the name of the file written: fname with
+// EncryptedSuffix when encrypted. As encrypting the same object twice gives different
+// files, the state has the hash of the unencrypted object and of the recipients.
+func (w *DumpWriter) WriteSensitiveJSON(fname string, obj interface{}) (string, error) {
+	if len(w.Recipients) == 0 {
+		return fname, w.writeJSON(fname, obj, false)
+	}
+
+	fname += EncryptedSuffix
+	return fname, w.writeJSON(fname, obj, true)
+}
+
+func (w *DumpWriter) writeJSON(fname string, obj interface{}, encrypt bool) error {
+	raw, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON Encoding error: %v", err)
+	}
+
+	p := filepath.Join(w.Dir, fname)
+	hash := stateHash(raw, w.Ignore)
+	if encrypt {
+		hash = recipientsHash(hash, w.Recipients)
+	}
+	w.next.Files[fname] = hash
+
+	if w.prev != nil && w.prev.Files[fname] == hash {
+		if _, err := os.Stat(p); err == nil {
+			w.Unchanged++
+			return nil
+		}
+	}
+
+	if encrypt {
+		if raw, err = EncryptArmored(raw, w.Recipients); err != nil {
+			return fmt.Errorf("%v: %v", fname, err)
+		}
+	}
+
+	if err := ioutil.WriteFile(p, raw, 0644); err != nil {
+		return fmt.Errorf("Error writing file: %v", err)
+	}
+	w.Written++
+
+	return nil
+}
+
+// Stale lists the files of the previous dump that this one didn't write, their objects no
+// longer exist on the target
+func (w *DumpWriter) Stale() []string {
+	stale := []string{}
+	if w.prev == nil {
+		return stale
+	}
+
+	for fname := range w.prev.Files {
+		if _, ok := w.next.Files[fname]; !ok {
+			stale = append(stale, fname)
+		}
+	}
+	sort.Strings(stale)
+
+	return stale
+}
+
+// Finish removes the files of the objects deleted since the previous dump and writes the
+// state for the next one to path, it returns the files removed. The files of objects that
+// failed to be fetched can't be told from those of deleted objects, so when any failed
+// nothing is removed and the state keeps the previous hashes of the files not written: the
+// next dump removes them if their objects are gone by then.
+func (w *DumpWriter) Finish(path string, failed int) ([]string, error) {
+	removed := []string{}
+	for _, fname := range w.Stale() {
+		if failed > 0 {
+			w.next.Files[fname] = w.prev.Files[fname]
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(w.Dir, fname)); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed = append(removed, fname)
+	}
+
+	return removed, w.next.Write(path)
+}
+
+// State is the state of this dump, for the next one
+func (w *DumpWriter) State() *DumpState {
+	return w.next
+}
@@ -0,0 +1,151 @@
+package tyk_vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDumpWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tyk-dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	statePath := filepath.Join(dir, "state.json")
+
+	first := NewDumpWriter(dir, nil)
+	for fname, obj := range map[string]interface{}{
+		"a.json": map[string]interface{}{"name": "A"},
+		"b.json": map[string]interface{}{"name": "B"},
+		"p.json": map[string]interface{}{"name": "P", "last_updated": "1"},
+	} {
+		if err := first.WriteJSON(fname, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if first.Written != 3 {
+		t.Fatalf("expected all files written, got %v", first.Written)
+	}
+	if err := first.State().Write(statePath); err != nil {
+		t.Fatal(err)
+	}
+
+	prev, err := ReadDumpState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stamp := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "p.json"), stamp, stamp)
+
+	second := NewDumpWriter(dir, prev)
+	second.WriteJSON("a.json", map[string]interface{}{"name": "A v2"})
+	second.WriteJSON("p.json", map[string]interface{}{"name": "P", "last_updated": "2"})
+	if second.Written != 1 || second.Unchanged != 1 {
+		t.Fatalf("expected one file written and one unchanged, got %v and %v", second.Written, second.Unchanged)
+	}
+
+	info, _ := os.Stat(filepath.Join(dir, "p.json"))
+	if !info.ModTime().Equal(stamp) {
+		t.Fatal("a file that only changed in volatile fields must not be rewritten")
+	}
+
+	if stale := second.Stale(); len(stale) != 1 || stale[0] != "b.json" {
+		t.Fatalf("expected b.json to be stale, got %v", stale)
+	}
+
+	if s, err := ReadDumpState(filepath.Join(dir, "missing.json")); err != nil || len(s.Files) != 0 {
+		t.Fatal("expected an empty state for a missing file")
+	}
+}
+
+func TestDumpWriter_Finish(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tyk-dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	statePath := filepath.Join(dir, "state.json")
+
+	first := NewDumpWriter(dir, nil)
+	first.WriteJSON("a.json", map[string]interface{}{"name": "A"})
+	first.WriteJSON("b.json", map[string]interface{}{"name": "B"})
+	if _, err := first.Finish(statePath, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// b.json failed to be fetched, it is kept along with its hash
+	prev, _ := ReadDumpState(statePath)
+	failing := NewDumpWriter(dir, prev)
+	failing.WriteJSON("a.json", map[string]interface{}{"name": "A v2"})
+	removed, err := failing.Finish(statePath, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.json")); err != nil || len(removed) != 0 {
+		t.Fatalf("expected no file to be removed after a failure, removed %v", removed)
+	}
+
+	// b.json was deleted since
+	prev, _ = ReadDumpState(statePath)
+	next := NewDumpWriter(dir, prev)
+	next.WriteJSON("a.json", map[string]interface{}{"name": "A v2"})
+	if next.Unchanged != 1 {
+		t.Fatal("expected the hash of the file written after a failure to be kept")
+	}
+	removed, err = next.Finish(statePath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.json")); !os.IsNotExist(err) || len(removed) != 1 || removed[0] != "b.json" {
+		t.Fatalf("expected b.json to be removed, removed %v", removed)
+	}
+}
+
+func TestDumpWriter_Ignore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dumpstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	api := func(active bool, tags ...string) map[string]interface{} {
+		return map[string]interface{}{"api_definition": map[string]interface{}{"active": active, "tags": tags}}
+	}
+
+	first := NewDumpWriter(dir, nil)
+	first.WriteJSON("api.json", api(true, "a", "b"))
+
+	second := NewDumpWriter(dir, first.State())
+	second.Ignore = []string{"active", "tags[]"}
+	second.WriteJSON("api.json", api(false, "b", "a"))
+	if second.Unchanged != 0 {
+		t.Fatal("the first dump hashed all fields, the file must be rewritten")
+	}
+
+	third := NewDumpWriter(dir, second.State())
+	third.Ignore = second.Ignore
+	third.WriteJSON("api.json", api(true, "a", "b"))
+	if third.Unchanged != 1 {
+		t.Fatal("changes to ignored fields must not make an object changed")
+	}
+}
+
+func TestReadDumpState_RefusesPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tyk-dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	statePath := filepath.Join(dir, "state.json")
+
+	if err := ioutil.WriteFile(statePath, []byte(`{"files": {"../outside.json": "x"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadDumpState(statePath); err == nil {
+		t.Fatal("expected a file outside of the dump directory to be refused")
+	}
+}
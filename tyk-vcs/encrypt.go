@@ -0,0 +1,181 @@
+package tyk_vcs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+	// keys that state no hash preferences get RIPEMD-160, the OpenPGP default
+	_ "golang.org/x/crypto/ripemd160"
+)
+
+// EncryptedSuffix is appended to the name of the files written encrypted
+const EncryptedSuffix = ".asc"
+
+// ReadRecipients reads the ASCII armored OpenPGP public keys files are encrypted to, a file
+// may hold several keys
+func ReadRecipients(paths []string) (openpgp.EntityList, error) {
+	recipients := openpgp.EntityList{}
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+
+		keys, err := openpgp.ReadArmoredKeyRing(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", p, err)
+		}
+		recipients = append(recipients, keys...)
+	}
+
+	return recipients, nil
+}
+
+// EncryptArmored encrypts raw to every recipient, the output is an ASCII armored OpenPGP
+// message that any of their private keys decrypts, e.g. with gpg --decrypt
+func EncryptArmored(raw []byte, to openpgp.EntityList) ([]byte, error) {
+	if len(to) == 0 {
+		return nil, errors.New("no recipients to encrypt to")
+	}
+
+	out := &bytes.Buffer{}
+	a, err := armor.Encode(out, "PGP MESSAGE", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := openpgp.Encrypt(a, to, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := a.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// ReadPrivateKeys reads the ASCII armored OpenPGP private keys encrypted files are decrypted
+// with, keys protected by a passphrase are unlocked with passphrase
+func ReadPrivateKeys(paths []string, passphrase string) (openpgp.EntityList, error) {
+	keys := openpgp.EntityList{}
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+
+		ring, err := openpgp.ReadArmoredKeyRing(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", p, err)
+		}
+
+		for _, e := range ring {
+			if e.PrivateKey == nil {
+				return nil, fmt.Errorf("%v: %v is a public key, the private key is needed to decrypt", p, e.PrimaryKey.KeyIdString())
+			}
+
+			locked := []*packet.PrivateKey{e.PrivateKey}
+			for _, sub := range e.Subkeys {
+				if sub.PrivateKey != nil {
+					locked = append(locked, sub.PrivateKey)
+				}
+			}
+			for _, k := range locked {
+				if !k.Encrypted {
+					continue
+				}
+				if passphrase == "" {
+					return nil, fmt.Errorf("%v: the key is protected by a passphrase", p)
+				}
+				if err := k.Decrypt([]byte(passphrase)); err != nil {
+					return nil, fmt.Errorf("%v: %v", p, err)
+				}
+			}
+		}
+		keys = append(keys, ring...)
+	}
+
+	return keys, nil
+}
+
+// DecryptArmored decrypts an ASCII armored OpenPGP message, as EncryptArmored writes it,
+// with the private key of whichever recipient keys holds
+func DecryptArmored(raw []byte, keys openpgp.EntityList) ([]byte, error) {
+	block, err := armor.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	md, err := openpgp.ReadMessage(block.Body, keys, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(md.UnverifiedBody)
+}
+
+var (
+	decryptionKeysMu sync.Mutex
+	decryptionKeys   openpgp.EntityList
+)
+
+// SetDecryptionKeys sets the private keys the files a dump encrypted, see EncryptedSuffix,
+// are decrypted with when they are read back, e.g. to restore them
+func SetDecryptionKeys(keys openpgp.EntityList) {
+	decryptionKeysMu.Lock()
+	defer decryptionKeysMu.Unlock()
+	decryptionKeys = keys
+}
+
+// openEncrypted decrypts the files a dump encrypted, other files are returned as they are
+func openEncrypted(name string, raw []byte) ([]byte, error) {
+	if !strings.HasSuffix(name, EncryptedSuffix) {
+		return raw, nil
+	}
+
+	decryptionKeysMu.Lock()
+	keys := decryptionKeys
+	decryptionKeysMu.Unlock()
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%v is encrypted, no private key to decrypt it with was given", name)
+	}
+
+	plain, err := DecryptArmored(raw, keys)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", name, err)
+	}
+	return plain, nil
+}
+
+// recipientsHash adds who a file is encrypted to to the hash of its content, so that the
+// file is encrypted again when the recipients change
+func recipientsHash(hash string, to openpgp.EntityList) string {
+	prints := []string{}
+	for _, e := range to {
+		prints = append(prints, hex.EncodeToString(e.PrimaryKey.Fingerprint[:]))
+	}
+	sort.Strings(prints)
+
+	sum := sha256.Sum256([]byte(hash + "\n" + strings.Join(prints, "\n")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,189 @@
+package tyk_vcs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func writeTestRecipient(t *testing.T, dir string) *openpgp.Entity {
+	e, err := openpgp.NewEntity("backup", "", "backup@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Create(filepath.Join(dir, "backup.asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	a, err := armor.Encode(f, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Serialize(a); err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+
+	return e
+}
+
+func decryptTestFile(t *testing.T, p string, keys openpgp.EntityList) []byte {
+	raw, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := armor.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := openpgp.ReadMessage(block.Body, keys, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return plain
+}
+
+func TestDumpWriter_WriteSensitiveJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "encrypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	private := writeTestRecipient(t, dir)
+	recipients, err := ReadRecipients([]string{filepath.Join(dir, "backup.asc")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipients) != 1 {
+		t.Fatalf("expected one recipient, got %v", len(recipients))
+	}
+
+	w := NewDumpWriter(dir, nil)
+	w.Recipients = recipients
+	fname, err := w.WriteSensitiveJSON("key-a.json", map[string]string{"key_id": "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fname != "key-a.json.asc" {
+		t.Fatalf("unexpected file name %v", fname)
+	}
+
+	plain := decryptTestFile(t, filepath.Join(dir, fname), openpgp.EntityList{private})
+	if !bytes.Contains(plain, []byte(`"key_id": "a"`)) {
+		t.Fatalf("unexpected content %s", plain)
+	}
+
+	// The hash is of the unencrypted object, so the next dump leaves the file alone
+	next := NewDumpWriter(dir, w.State())
+	next.Recipients = recipients
+	if _, err := next.WriteSensitiveJSON("key-a.json", map[string]string{"key_id": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if next.Unchanged != 1 {
+		t.Fatalf("expected the encrypted file to be unchanged, wrote %v", next.Written)
+	}
+
+	// Encrypting to other recipients rewrites the file
+	other, err := openpgp.NewEntity("ops", "", "ops@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	third := NewDumpWriter(dir, next.State())
+	third.Recipients = append(recipients, other)
+	if _, err := third.WriteSensitiveJSON("key-a.json", map[string]string{"key_id": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if third.Written != 1 {
+		t.Fatal("expected the file to be encrypted again for the new recipients")
+	}
+	decryptTestFile(t, filepath.Join(dir, fname), openpgp.EntityList{other})
+
+	clear := NewDumpWriter(dir, nil)
+	if fname, _ := clear.WriteSensitiveJSON("key-b.json", map[string]string{}); fname != "key-b.json" {
+		t.Fatalf("without recipients the file must be written in the clear, got %v", fname)
+	}
+}
+
+func TestFetchEncryptedKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "encrypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	private := writeTestRecipient(t, dir)
+	f, err := os.Create(filepath.Join(dir, "private.asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := armor.Encode(f, openpgp.PrivateKeyType, nil)
+	if err := private.SerializePrivate(a, nil); err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+	f.Close()
+
+	enc, err := EncryptArmored([]byte(`{"key_id": "ignored", "session": {"org_id": "org"}}`), openpgp.EntityList{private})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := EncryptArmored([]byte(`{"client_id": "c1", "secret": "s1"}`), openpgp.EntityList{private})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := memGetter(t, map[string]string{
+		".tyk.json": `{"type": "apidef", "keys": [{"file": "key-a.json.asc", "key_id": "a"}],
+			"oauth_clients": [{"file": "oauth-a1-c1.json.asc", "api_id": "a1"}]}`,
+		"key-a.json.asc":       string(enc),
+		"oauth-a1-c1.json.asc": string(client),
+	})
+	spec, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.FetchKeys(spec); err == nil {
+		t.Fatal("expected encrypted keys to need a private key")
+	}
+
+	if _, err := ReadPrivateKeys([]string{filepath.Join(dir, "backup.asc")}, ""); err == nil {
+		t.Fatal("expected a public key to be refused")
+	}
+	keys, err := ReadPrivateKeys([]string{filepath.Join(dir, "private.asc")}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetDecryptionKeys(keys)
+	defer SetDecryptionKeys(nil)
+
+	fetched, err := g.FetchKeys(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetched[0].KeyID != "a" || fetched[0].Session["org_id"] != "org" {
+		t.Errorf("expected the decrypted key, got %+v", fetched[0])
+	}
+
+	clients, err := g.FetchOAuthClients(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clients[0].Secret != "s1" || clients[0].APIID != "a1" {
+		t.Errorf("expected the decrypted OAuth client, got %+v", clients[0])
+	}
+}
@@ -0,0 +1,97 @@
+package tyk_vcs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/TykTechnologies/tyk-sync/tyk-importer"
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+// specPath turns a file name from the spec into a path for the repo file system, spec
+// files written on Windows may use backslashes
+func specPath(name string) string {
+	name = strings.Replace(name, "\\", "/", -1)
+	return strings.TrimPrefix(path.Clean(name), "./")
+}
+
+// normaliseText drops a UTF-8 byte order mark and turns CRLF line endings into LF, as
+// written by editors and git checkouts on Windows
+func normaliseText(raw []byte) []byte {
+	raw = bytes.TrimPrefix(raw, []byte("\xef\xbb\xbf"))
+	return bytes.Replace(raw, []byte("\r\n"), []byte("\n"), -1)
+}
+
+// readObjectFile reads an API definition or policy file as JSON, files named .yaml or .yml
+// are converted from YAML and files encrypted with SOPS decrypted
+func readObjectFile(fs billy.Filesystem, name string) ([]byte, error) {
+	raw, err := readTextFile(fs, name)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, decrypted, err := openSOPS(name, raw)
+	if err != nil || decrypted {
+		return raw, err
+	}
+
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml":
+		conv, err := tyk_importer.YAMLToJSON(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", name, err)
+		}
+		return conv, nil
+	}
+
+	return raw, nil
+}
+
+// windowsReserved are names Windows doesn't allow as file names, with any extension
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SafeFileName makes an ID or name usable as a file name on every OS: anything but letters,
+// digits, '-', '_' and '.' is replaced with '_', and names Windows reserves or strips
+// (trailing dots and spaces) are avoided. A name that had to be changed ends with a hash of
+// the original, so that e.g. "a/b" and "a_b" don't share a file.
+func SafeFileName(name string) string {
+	safe := safeName(name)
+	if safe != name {
+		sum := sha256.Sum256([]byte(name))
+		safe += "-" + hex.EncodeToString(sum[:4])
+	}
+
+	return safe
+}
+
+// safeName is SafeFileName without the hash, for callers that tell clashing names apart
+// themselves
+func safeName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+
+	safe = strings.TrimRight(safe, ".")
+	if safe == "" {
+		safe = "_"
+	}
+
+	base := strings.ToUpper(strings.SplitN(safe, ".", 2)[0])
+	if windowsReserved[base] {
+		safe = "_" + safe
+	}
+
+	return safe
+}
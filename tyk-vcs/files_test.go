@@ -0,0 +1,85 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+func TestSafeFileName(t *testing.T) {
+	cases := map[string]string{
+		"5c0f8a3e2b8a": "5c0f8a3e2b8a",
+		"org_api":      "org_api",
+		"org/api":      "org_api-1e10e21e",
+		"org\\api":     "org_api-8838b64f",
+		"c:api":        "c_api-422f83d2",
+		"a b?*<>|\"":   "a_b______-c5030e30",
+		"café-ünïcode": "café-ünïcode",
+		"api.":         "api-03e8d63f",
+		"...":          "_-ab5df625",
+		"":             "_-e3b0c442",
+		"CON":          "_CON-a3dbc4b6",
+		"nul.json":     "_nul.json-01207fdc",
+		"com1":         "_com1-957a49c3",
+		"console":      "console",
+	}
+
+	for in, want := range cases {
+		if got := SafeFileName(in); got != want {
+			t.Errorf("SafeFileName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSpecPath(t *testing.T) {
+	cases := map[string]string{
+		"api.json":              "api.json",
+		"./api.json":            "api.json",
+		"apis\\users\\api.json": "apis/users/api.json",
+		".\\apis\\api.json":     "apis/api.json",
+		"apis//api.json":        "apis/api.json",
+	}
+
+	for in, want := range cases {
+		if got := specPath(in); got != want {
+			t.Errorf("specPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormaliseText(t *testing.T) {
+	got := string(normaliseText([]byte("\xef\xbb\xbf{\r\n  \"a\": 1\r\n}\r\n")))
+	if want := "{\n  \"a\": 1\n}\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestFetchWindowsSpec(t *testing.T) {
+	fs := memfs.New()
+	files := map[string]string{
+		".tyk.json":     "\xef\xbb\xbf{\r\n  \"type\": \"apidef\",\r\n  \"files\": [{\"file\": \"apis\\\\api.json\"}]\r\n}\r\n",
+		"apis/api.json": "{\r\n  \"api_id\": \"1\",\r\n  \"name\": \"Windows\"\r\n}\r\n",
+	}
+	for name, content := range files {
+		f, err := fs.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+		f.Close()
+	}
+
+	spec, err := fetchSpec(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := fetchAPIDefinitions(fs, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(defs) != 1 || defs[0].Name != "Windows" {
+		t.Fatalf("unexpected definitions: %+v", defs)
+	}
+}
@@ -0,0 +1,102 @@
+package tyk_vcs
+
+import (
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// ManagedMarker is the key set to the repo of the spec, see TykSourceSpec.Repo, in the
+// config_data of the APIs and the meta_data of the policies tyk-sync publishes. Nothing is
+// set for specs without a repo, gc only considers objects carrying the repo it runs for.
+const ManagedMarker = "tyk_sync_repo"
+
+func markManaged(repo string, def *objects.DBApiDefinition) {
+	if repo == "" || def.APIDefinition == nil {
+		return
+	}
+	if def.ConfigData == nil {
+		def.ConfigData = map[string]interface{}{}
+	}
+	def.ConfigData[ManagedMarker] = repo
+}
+
+// MarkManaged marks defs and pols as published by tyk-sync from repo, it does nothing if
+// repo is empty
+func MarkManaged(repo string, defs []objects.DBApiDefinition, pols []objects.Policy) {
+	if repo == "" {
+		return
+	}
+
+	for i := range defs {
+		markManaged(repo, &defs[i])
+	}
+
+	for i := range pols {
+		if pols[i].MetaData == nil {
+			pols[i].MetaData = map[string]interface{}{}
+		}
+		pols[i].MetaData[ManagedMarker] = repo
+	}
+}
+
+// managedBy is the repo an object was published from, empty if it carries no marker
+func managedBy(data map[string]interface{}) string {
+	repo, _ := data[ManagedMarker].(string)
+	return repo
+}
+
+// IsManagedAPI tells whether def was published by tyk-sync, from any repo
+func IsManagedAPI(def objects.DBApiDefinition) bool {
+	return def.APIDefinition != nil && managedBy(def.ConfigData) != ""
+}
+
+// Orphans are the managed objects of a target that are no longer in git
+type Orphans struct {
+	APIs     []objects.DBApiDefinition
+	Policies []objects.Policy
+}
+
+// Size is the number of orphaned objects
+func (o *Orphans) Size() int {
+	return len(o.APIs) + len(o.Policies)
+}
+
+// FindOrphans returns the objects of the target that carry the marker of repo and match
+// none of the git objects, APIs by API ID and policies by ID or database ID. Objects
+// without the marker, e.g. created by hand, or published from other repos are never orphans.
+func FindOrphans(repo string, apis []objects.DBApiDefinition, pols []objects.Policy, gitDefs []objects.DBApiDefinition, gitPols []objects.Policy) *Orphans {
+	inGit := map[string]bool{}
+	for _, d := range gitDefs {
+		if d.APIDefinition != nil && d.APIID != "" {
+			inGit[d.APIID] = true
+		}
+	}
+
+	polsInGit := map[string]bool{}
+	for _, p := range gitPols {
+		if p.ID != "" {
+			polsInGit[p.ID] = true
+		}
+		if p.MID != "" {
+			polsInGit[p.MID.Hex()] = true
+		}
+	}
+
+	o := &Orphans{}
+	for _, a := range apis {
+		if a.APIDefinition != nil && managedBy(a.ConfigData) == repo && !inGit[a.APIID] {
+			o.APIs = append(o.APIs, a)
+		}
+	}
+
+	for _, p := range pols {
+		if managedBy(p.MetaData) != repo {
+			continue
+		}
+		if (p.ID != "" && polsInGit[p.ID]) || (p.MID != "" && polsInGit[p.MID.Hex()]) {
+			continue
+		}
+		o.Policies = append(o.Policies, p)
+	}
+
+	return o
+}
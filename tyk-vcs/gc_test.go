@@ -0,0 +1,43 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestFindOrphans(t *testing.T) {
+	gitDefs := []objects.DBApiDefinition{{APIDefinition: &apidef.APIDefinition{APIID: "a"}}}
+	gitPols := []objects.Policy{{ID: "p"}, {MID: bson.ObjectIdHex("5e9d9544a1dcd60001d0ed30")}}
+
+	apis := []objects.DBApiDefinition{
+		{APIDefinition: &apidef.APIDefinition{APIID: "a"}},
+		{APIDefinition: &apidef.APIDefinition{APIID: "b"}},
+		{APIDefinition: &apidef.APIDefinition{APIID: "manual"}},
+	}
+	pols := []objects.Policy{
+		{ID: "p", MID: bson.ObjectIdHex("5e9d9544a1dcd60001d0ed31")},
+		{MID: bson.ObjectIdHex("5e9d9544a1dcd60001d0ed30")},
+		{ID: "q", MID: bson.ObjectIdHex("5e9d9544a1dcd60001d0ed32")},
+		{ID: "manual"},
+		{ID: "other", MID: bson.ObjectIdHex("5e9d9544a1dcd60001d0ed33")},
+	}
+	apis = append(apis, objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: "other"}})
+	MarkManaged("payments", apis[:2], pols[:3])
+	MarkManaged("billing", apis[3:], pols[4:])
+	MarkManaged("", apis[2:3], pols[3:4])
+
+	o := FindOrphans("payments", apis, pols, gitDefs, gitPols)
+	if len(o.APIs) != 1 || o.APIs[0].APIID != "b" {
+		t.Fatalf("unexpected orphaned APIs: %v", o.APIs)
+	}
+	if len(o.Policies) != 1 || o.Policies[0].ID != "q" || o.Size() != 2 {
+		t.Fatalf("unexpected orphaned policies: %v", o.Policies)
+	}
+
+	if !IsManagedAPI(apis[3]) || IsManagedAPI(apis[2]) {
+		t.Error("expected the APIs marked by any repo to be managed")
+	}
+}
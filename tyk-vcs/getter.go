@@ -2,19 +2,26 @@ package tyk_vcs
 
 import (
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-importer"
+	"github.com/TykTechnologies/tyk-sync/tyk-oas"
 	"github.com/TykTechnologies/tyk-sync/tyk-swagger"
 	"github.com/TykTechnologies/tyk/apidef"
-	"gopkg.in/mgo.v2/bson"
 	"gopkg.in/src-d/go-billy.v4"
 	"gopkg.in/src-d/go-billy.v4/memfs"
 	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-billy.v4/util"
 	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
 	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
 	"gopkg.in/src-d/go-git.v4/storage/memory"
 )
@@ -23,6 +30,10 @@ type Getter interface {
 	FetchRepo() error
 	FetchAPIDef(spec *TykSourceSpec) ([]objects.DBApiDefinition, error)
 	FetchPolicies(spec *TykSourceSpec) ([]objects.Policy, error)
+	FetchKeys(spec *TykSourceSpec) ([]objects.Key, error)
+	FetchOAuthClients(spec *TykSourceSpec) ([]objects.OAuthClient, error)
+	FetchCertificates(spec *TykSourceSpec) ([]Certificate, error)
+	FetchContractTests(spec *TykSourceSpec) ([]ContractTests, error)
 	FetchTykSpec() (*TykSourceSpec, error)
 }
 
@@ -37,8 +48,28 @@ type GitGetter struct {
 	repo      string
 	branch    string
 	key       []byte
+	opts      GitOptions
 	fs        billy.Filesystem
+	worktree  billy.Filesystem
 	r         *git.Repository
+	// commit is the commit checked out, if not the tip of the clone
+	commit    string
+}
+
+// GitOptions select what the git getter checks out of the repo
+type GitOptions struct {
+	// Tag is checked out instead of the branch
+	Tag string
+	// Commit is checked out instead of the tip of the branch, it must be part of its history
+	Commit string
+	// SubDir is the directory holding the spec file, only its files are checked out
+	SubDir string
+	// Submodules are cloned as well, this checks out the whole repo
+	Submodules bool
+	// FullHistory clones all commits of the branch, by default only its tip is cloned
+	FullHistory bool
+	// Cache keeps the clone for later getters of the same repo and branch, if set
+	Cache *CloneCache
 }
 
 type FSGetter struct {
@@ -48,11 +79,22 @@ type FSGetter struct {
 }
 
 func NewGGetter(repo, branch string, key []byte) (*GitGetter, error) {
+	return NewGGetterWithOptions(repo, branch, key, GitOptions{})
+}
+
+func NewGGetterWithOptions(repo, branch string, key []byte, opts GitOptions) (*GitGetter, error) {
+	if opts.Tag != "" && opts.Commit != "" {
+		return nil, errors.New("a tag and a commit can't be checked out at the same time")
+	}
+
+	fs := memfs.New()
 	gh := &GitGetter{
 		repo:      repo,
 		branch:    branch,
 		key:       key,
-		fs:        memfs.New(),
+		opts:      opts,
+		fs:        fs,
+		worktree:  fs,
 	}
 
 	return gh, nil
@@ -67,20 +109,56 @@ func NewFSGetter(filePath string) (*FSGetter, error) {
 }
 
 func (gg *GitGetter) FetchRepo() error {
-
-	cloneOptions := git.CloneOptions{
-		URL:           gg.repo,
-		ReferenceName: plumbing.ReferenceName(gg.branch),
-		SingleBranch:  true,
+	if gg.r != nil {
+		return nil
 	}
+
+	var auth transport.AuthMethod
 	if len(gg.key) != 0 {
 		publicKey, keyError := ssh.NewPublicKeys("git", gg.key, "")
 		if keyError != nil {
 			fmt.Println("Error getting key for git authentication:", keyError)
 		}
-		cloneOptions.Auth = publicKey
+		auth = publicKey
+	}
+
+	ref := plumbing.ReferenceName(gg.branch)
+	if gg.opts.Tag != "" {
+		ref = plumbing.NewTagReferenceName(gg.opts.Tag)
+	}
+
+	// A commit or the commits of a range may not be the tip, they need the history
+	depth := 1
+	if gg.opts.Commit != "" || gg.opts.FullHistory {
+		depth = 0
+	}
+
+	key := cacheKey(gg.repo, ref, depth)
+	if cached := gg.opts.Cache.get(key); cached != nil {
+		fresh, err := refreshClone(cached.r, ref, depth, auth)
+		if err != nil {
+			gg.opts.Cache.drop(key)
+			return err
+		}
+
+		if fresh {
+			gg.r = cached.r
+			gg.worktree = cached.worktree
+			return gg.checkoutRev(ref)
+		}
+		gg.opts.Cache.drop(key)
 	}
-	r, err := git.Clone(memory.NewStorage(), gg.fs, &cloneOptions)
+
+	cloneOptions := git.CloneOptions{
+		URL:           gg.repo,
+		ReferenceName: ref,
+		SingleBranch:  true,
+		Depth:         depth,
+		// Checked out below, a commit or a subdirectory only
+		NoCheckout:    gg.opts.Commit != "" || gg.opts.SubDir != "" || gg.opts.Submodules,
+		Auth:          auth,
+	}
+	r, err := git.Clone(memory.NewStorage(), gg.worktree, &cloneOptions)
 
 	if err != nil {
 		return err
@@ -88,32 +166,229 @@ func (gg *GitGetter) FetchRepo() error {
 
 	gg.r = r
 
+	if cloneOptions.NoCheckout {
+		if err := gg.checkoutRev(ref); err != nil {
+			return err
+		}
+	}
+
+	gg.opts.Cache.put(key, &cachedClone{r: r, worktree: gg.worktree})
 	return nil
 }
 
-func (gg *FSGetter) FetchRepo() error {
+// refreshClone brings a cached clone up to date with the remote, it returns false if the
+// clone is shallow and the remote moved on: fetching into shallow clones is not supported,
+// they are cloned again.
+func refreshClone(r *git.Repository, ref plumbing.ReferenceName, depth int, auth transport.AuthMethod) (bool, error) {
+	if depth == 0 {
+		err := r.Fetch(&git.FetchOptions{
+			RefSpecs: []config.RefSpec{config.RefSpec(fmt.Sprintf("+%v:%v", ref, ref))},
+			Auth:     auth,
+			Force:    true,
+		})
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			return false, err
+		}
+		return true, nil
+	}
+
+	local, err := r.Reference(ref, true)
+	if err != nil {
+		return false, nil
+	}
+
+	remote, err := r.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return false, err
+	}
+
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return false, err
+	}
+
+	for _, rr := range refs {
+		if rr.Name() == ref {
+			return rr.Hash() == local.Hash(), nil
+		}
+	}
+
+	return false, fmt.Errorf("couldn't find remote ref %q", ref)
+}
+
+// checkoutRev checks out the commit of the options, or the tip of ref
+func (gg *GitGetter) checkoutRev(ref plumbing.ReferenceName) error {
+	rev := gg.opts.Commit
+	if rev == "" {
+		rev = ref.String()
+	}
+
+	h, err := gg.r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		gg.r = nil
+		return fmt.Errorf("commit %v: %v", rev, err)
+	}
+
+	if err := gg.checkout(*h); err != nil {
+		gg.r = nil
+		return err
+	}
+
 	return nil
 }
 
-func fetchSpec(fs billy.Filesystem) (*TykSourceSpec, error) {
-	specFile, err := fs.Open(".tyk.json")
+// subDir is the subdirectory holding the spec file as a path in the repo, empty for the root
+func (gg *GitGetter) subDir() string {
+	if gg.opts.SubDir == "" {
+		return ""
+	}
+
+	dir := strings.Trim(specPath(gg.opts.SubDir), "/")
+	if dir == "." {
+		return ""
+	}
+
+	return dir
+}
+
+// checkout puts the files of a commit in the file system the objects are read from. With
+// a subdirectory and no submodules, only the files of the subdirectory are written.
+func (gg *GitGetter) checkout(h plumbing.Hash) error {
+	gg.commit = h.String()
+	dir := gg.subDir()
+	if dir != "" && !gg.opts.Submodules {
+		fs, err := checkoutDir(gg.r, h, dir)
+		if err != nil {
+			return err
+		}
+		gg.fs = fs
+		return nil
+	}
+
+	w, err := gg.r.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := w.Checkout(&git.CheckoutOptions{Hash: h, Force: true}); err != nil {
+		return err
+	}
+
+	if gg.opts.Submodules {
+		subs, err := w.Submodules()
+		if err != nil {
+			return err
+		}
+
+		updateOptions := &git.SubmoduleUpdateOptions{
+			Init:              true,
+			RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		}
+		if len(gg.key) != 0 {
+			publicKey, keyError := ssh.NewPublicKeys("git", gg.key, "")
+			if keyError != nil {
+				return keyError
+			}
+			updateOptions.Auth = publicKey
+		}
+		if err := subs.Update(updateOptions); err != nil {
+			return fmt.Errorf("submodules: %v", err)
+		}
+	}
+
+	if dir == "" {
+		gg.fs = gg.worktree
+		return nil
+	}
+
+	fs, err := gg.worktree.Chroot(dir)
+	if err != nil {
+		return err
+	}
+	gg.fs = fs
+
+	return nil
+}
+
+// checkoutDir writes the files of a directory of a commit to a new file system
+func checkoutDir(r *git.Repository, h plumbing.Hash, dir string) (billy.Filesystem, error) {
+	c, err := r.CommitObject(h)
 	if err != nil {
-		fmt.Println(".tyk.json")
 		return nil, err
 	}
 
-	rawSpec, err := ioutil.ReadAll(specFile)
+	root, err := c.Tree()
 	if err != nil {
 		return nil, err
 	}
 
-	ts := TykSourceSpec{}
-	err = json.Unmarshal(rawSpec, &ts)
+	tree, err := root.Tree(dir)
+	if err != nil {
+		return nil, fmt.Errorf("directory %v: %v", dir, err)
+	}
+
+	fs := memfs.New()
+	err = tree.Files().ForEach(func(f *object.File) error {
+		contents, err := f.Contents()
+		if err != nil {
+			return err
+		}
+
+		return util.WriteFile(fs, f.Name, []byte(contents), 0644)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &ts, nil
+	return fs, nil
+}
+
+func (gg *FSGetter) FetchRepo() error {
+	return nil
+}
+
+// NewSubGetter reads the spec and objects of a subdirectory of what g fetched, the
+// repo must have been fetched already
+func NewSubGetter(g Getter, dir string) (Getter, error) {
+	var fs billy.Filesystem
+	switch gg := g.(type) {
+	case *GitGetter:
+		if gg.r == nil {
+			return nil, errors.New("no repository in memory, fetch repo first")
+		}
+		fs = gg.fs
+	case *FSGetter:
+		fs = gg.fs
+	case *SourceGetter:
+		fs = gg.fs
+	default:
+		return nil, fmt.Errorf("subdirectories are not supported by %T", g)
+	}
+
+	sub, err := fs.Chroot(specPath(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	return &FSGetter{fs: sub}, nil
+}
+
+func fetchSpec(fs billy.Filesystem) (*TykSourceSpec, error) {
+	l := &specLoader{fs: fs, loaded: map[string]bool{}}
+	ts, err := l.loadSpec(SpecFile)
+	if err != nil {
+		return nil, err
+	}
+	ts.includes = l.order
+
+	if err := checkToolVersion(ts, Version().Version); err != nil {
+		return nil, err
+	}
+
+	if err := expandGlobs(fs, ts); err != nil {
+		return nil, err
+	}
+
+	return ts, nil
 }
 
 func (gg *GitGetter) FetchTykSpec() (*TykSourceSpec, error) {
@@ -144,25 +419,53 @@ func fetchAPIDefinitions(fs billy.Filesystem, spec *TykSourceSpec) ([]objects.DB
 		return fetchAPIDefinitionsDirect(fs, spec)
 	case TYPE_OAI:
 		return fetchAPIDefinitionsFromOAI(fs, spec)
+	case TYPE_POSTMAN:
+		return fetchAPIDefinitionsFromImport(fs, spec, convertPostman)
+	case TYPE_BLUEPRINT:
+		return fetchAPIDefinitionsFromImport(fs, spec, convertBlueprint)
+	case TYPE_WSDL:
+		return fetchAPIDefinitionsFromImport(fs, spec, convertWSDL)
+	case TYPE_ASYNCAPI:
+		return fetchAPIDefinitionsFromImport(fs, spec, convertAsyncAPI)
+	case TYPE_GRPC:
+		return fetchAPIDefinitionsFromGRPC(fs, spec)
+	case TYPE_OPERATOR:
+		defs, _, err := fetchOperatorResources(fs, spec)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Imported %v definitions\n", len(defs))
+		return defs, nil
 	default:
-		return nil, fmt.Errorf("Type must be '%v or '%v'", TYPE_APIDEF, TYPE_OAI)
+		return nil, fmt.Errorf("Type must be one of '%v', '%v', '%v', '%v', '%v', '%v', '%v' or '%v'", TYPE_APIDEF, TYPE_OAI, TYPE_POSTMAN, TYPE_BLUEPRINT, TYPE_WSDL, TYPE_OPERATOR, TYPE_GRPC, TYPE_ASYNCAPI)
 	}
 }
 
 func fetchAPIDefinitionsDirect(fs billy.Filesystem, spec *TykSourceSpec) ([]objects.DBApiDefinition, error) {
 	defNames := spec.Files
 	defs := make([]objects.DBApiDefinition, len(defNames))
+	defaults := newDirDefaults(fs)
 	for i, defInfo := range defNames {
-		defFile, err := fs.Open(defInfo.File)
+		rawDef, err := readObjectFile(fs, defInfo.File)
 		if err != nil {
 			return nil, err
 		}
 
-		rawDef, err := ioutil.ReadAll(defFile)
+		if tyk_oas.IsTykOAS(rawDef) {
+			if rawDef, err = classicFromOAS(defInfo.File, rawDef); err != nil {
+				return nil, err
+			}
+		}
+
+		rawDef, seed, err := readObjectIDs(defInfo.File, rawDef, "id")
 		if err != nil {
 			return nil, err
 		}
 
+		if rawDef, err = defaults.apply(defInfo.File, rawDef); err != nil {
+			return nil, err
+		}
+
 		ad := objects.DBApiDefinition{}
 		err = json.Unmarshal(rawDef, &ad)
 		if err != nil || (ad.APIDefinition == nil){
@@ -174,19 +477,24 @@ func fetchAPIDefinitionsDirect(fs billy.Filesystem, spec *TykSourceSpec) ([]obje
 			ad.APIDefinition = &def
 		}
 
-		if defInfo.APIID != "" {
-			ad.APIID = defInfo.APIID
+		// Keep the unknown fields around, the publisher decides if they are sent
+		if err := ad.KeepRaw(rawDef); err != nil {
+			return nil, err
 		}
 
-		if defInfo.DBID != "" {
-			ad.Id = bson.ObjectIdHex(defInfo.DBID)
+		if defInfo.APIID != "" {
+			ad.APIID = defInfo.APIID
 		}
 
 		if defInfo.ORGID != "" {
 			ad.OrgID = defInfo.ORGID
 		}
 
+		if err := applySpecID(&ad, defInfo, seed); err != nil {
+			return nil, err
+		}
 
+		ad.File = defInfo.File
 		defs[i] = ad
 	}
 
@@ -199,12 +507,7 @@ func fetchAPIDefinitionsFromOAI(fs billy.Filesystem, spec *TykSourceSpec) ([]obj
 	defs := make([]objects.DBApiDefinition, len(oaiNames))
 
 	for i, oaiInfo := range oaiNames {
-		oaiFile, err := fs.Open(oaiInfo.File)
-		if err != nil {
-			return nil, err
-		}
-
-		rawData, err := ioutil.ReadAll(oaiFile)
+		rawData, err := readTextFile(fs, oaiInfo.File)
 		if err != nil {
 			return nil, err
 		}
@@ -222,32 +525,183 @@ func fetchAPIDefinitionsFromOAI(fs billy.Filesystem, spec *TykSourceSpec) ([]obj
 			return nil, err
 		}
 
-		if oaiInfo.APIID != "" {
-			ad.APIID = oaiInfo.APIID
+		if err := applyImportOverrides(ad, oaiInfo); err != nil {
+			return nil, err
+		}
+		ad.File = oaiInfo.File
+		defs[i] = *ad
+	}
+
+	return defs, nil
+}
+
+// classicFromOAS converts the Tyk OAS definition of file into a classic one, which every
+// target supports, warning about the fields it loses
+func classicFromOAS(file string, raw []byte) ([]byte, error) {
+	def, lost, err := tyk_oas.ToClassic(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", file, err)
+	}
+
+	for _, c := range lost {
+		fmt.Printf("--> [WARNING] %v: %v has no classic equivalent and is not published\n", file, c.Path)
+	}
+
+	return json.Marshal(def)
+}
+
+// applyImportOverrides sets the values from the spec file on a definition generated by an importer
+func applyImportOverrides(ad *objects.DBApiDefinition, info APIInfo) error {
+	if info.APIID != "" {
+		ad.APIID = info.APIID
+	}
+
+	if err := applySpecID(ad, info, ""); err != nil {
+		return err
+	}
+
+	if info.OAS.OverrideListenPath != "" {
+		ad.Proxy.ListenPath = info.OAS.OverrideListenPath
+	}
+
+	if info.OAS.OverrideTarget != "" {
+		ad.Proxy.TargetURL = info.OAS.OverrideTarget
+	}
+
+	if info.OAS.StripListenPath {
+		ad.Proxy.StripListenPath = true
+	}
+
+	return nil
+}
+
+type importConverter func(raw []byte, info APIInfo) (*objects.DBApiDefinition, error)
+
+func convertPostman(raw []byte, info APIInfo) (*objects.DBApiDefinition, error) {
+	collection := tyk_importer.PostmanCollection{}
+	if err := json.Unmarshal(raw, &collection); err != nil {
+		return nil, err
+	}
+
+	return tyk_importer.CreateDefinitionFromPostman(&collection, info.ORGID, info.OAS.VersionName)
+}
+
+func convertBlueprint(raw []byte, info APIInfo) (*objects.DBApiDefinition, error) {
+	bp, err := tyk_importer.ParseBlueprint(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return tyk_importer.CreateDefinitionFromBlueprint(bp, info.ORGID, info.OAS.VersionName)
+}
+
+func convertWSDL(raw []byte, info APIInfo) (*objects.DBApiDefinition, error) {
+	w, err := tyk_importer.ParseWSDL(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return tyk_importer.CreateDefinitionFromWSDL(w, info.ORGID, info.OAS.VersionName)
+}
+
+func convertAsyncAPI(raw []byte, info APIInfo) (*objects.DBApiDefinition, error) {
+	doc, err := tyk_importer.ParseAsyncAPI(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return tyk_importer.CreateDefinitionFromAsyncAPI(doc, info.ORGID, info.OAS.VersionName)
+}
+
+func fetchAPIDefinitionsFromImport(fs billy.Filesystem, spec *TykSourceSpec, convert importConverter) ([]objects.DBApiDefinition, error) {
+	defs := make([]objects.DBApiDefinition, len(spec.Files))
+
+	for i, info := range spec.Files {
+		rawData, err := readTextFile(fs, info.File)
+		if err != nil {
+			return nil, err
+		}
+
+		ad, err := convert(rawData, info)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", info.File, err)
 		}
 
-		if oaiInfo.DBID != "" {
-			ad.Id = bson.ObjectIdHex(oaiInfo.DBID)
+		if err := applyImportOverrides(ad, info); err != nil {
+			return nil, err
 		}
+		ad.File = info.File
+		defs[i] = *ad
+	}
+
+	fmt.Printf("Imported %v definitions\n", len(defs))
+	return defs, nil
+}
+
+// fetchAPIDefinitionsFromGRPC builds a definition for every service of the descriptor sets
+// of the spec. The IDs and listen path set for a file only fit a file with one service.
+func fetchAPIDefinitionsFromGRPC(fs billy.Filesystem, spec *TykSourceSpec) ([]objects.DBApiDefinition, error) {
+	defs := []objects.DBApiDefinition{}
 
-		if oaiInfo.OAS.OverrideListenPath != "" {
-			ad.Proxy.ListenPath = oaiInfo.OAS.OverrideListenPath
+	for _, info := range spec.Files {
+		// Descriptor sets are binary, they are read as they are
+		rawData, err := readFile(fs, info.File)
+		if err != nil {
+			return nil, err
 		}
 
-		if oaiInfo.OAS.OverrideTarget != "" {
-			ad.Proxy.TargetURL = oaiInfo.OAS.OverrideTarget
+		services, err := tyk_importer.ParseDescriptorSet(rawData)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", info.File, err)
+		}
+		if len(services) > 1 && (info.APIID != "" || info.DBID != "" || info.OAS.OverrideListenPath != "") {
+			return nil, fmt.Errorf("%v: api_id, db_id and override_listen_path can't be set for a descriptor set of %v services", info.File, len(services))
 		}
 
-		if oaiInfo.OAS.StripListenPath {
-			ad.Proxy.StripListenPath = true
+		found, err := tyk_importer.CreateDefinitionsFromGRPC(services, info.ORGID, info.OAS.VersionName)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", info.File, err)
 		}
 
-		defs[i] = *ad
+		for i := range found {
+			if err := applyImportOverrides(&found[i], info); err != nil {
+				return nil, err
+			}
+			found[i].File = info.File
+		}
+		defs = append(defs, found...)
 	}
 
+	fmt.Printf("Imported %v definitions\n", len(defs))
 	return defs, nil
 }
 
+// fetchOperatorResources converts the Tyk Operator resources in the files of the spec, all
+// files are read together as policies may point to APIs of other files
+func fetchOperatorResources(fs billy.Filesystem, spec *TykSourceSpec) ([]objects.DBApiDefinition, []objects.Policy, error) {
+	resources := []tyk_importer.OperatorResource{}
+	for _, info := range spec.Files {
+		rawData, err := readTextFile(fs, info.File)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		found, err := tyk_importer.ParseOperatorResources(rawData)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%v: %v", info.File, err)
+		}
+
+		for _, r := range found {
+			if _, ok := r.Spec["org_id"]; !ok && info.ORGID != "" {
+				r.Spec["org_id"] = info.ORGID
+			}
+		}
+		resources = append(resources, found...)
+	}
+
+	return tyk_importer.CreateDefinitionsFromOperator(resources)
+}
+
 func (gg *FSGetter) FetchPolicies(spec *TykSourceSpec) ([]objects.Policy, error) {
 	return fetchPolicies(gg.fs, spec)
 }
@@ -262,14 +716,29 @@ func (gg *GitGetter) FetchPolicies(spec *TykSourceSpec) ([]objects.Policy, error
 func fetchPolicies(fs billy.Filesystem, spec *TykSourceSpec) ([]objects.Policy, error)  {
 	defNames := spec.Policies
 	defs := make([]objects.Policy, len(defNames))
+
+	if spec.Type == TYPE_OPERATOR {
+		_, pols, err := fetchOperatorResources(fs, spec)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pol := range pols {
+			if pol.OrgID == "" {
+				return nil, fmt.Errorf("SecurityPolicy %v must include an org ID, set org_id in the spec file", pol.Name)
+			}
+		}
+		defs = append(defs, pols...)
+	}
+
 	for i, defInfo := range defNames {
-		defFile, err := fs.Open(defInfo.File)
+		rawDef, err := readObjectFile(fs, defInfo.File)
 		if err != nil {
 			fmt.Println(defInfo.File)
 			return nil, err
 		}
 
-		rawDef, err := ioutil.ReadAll(defFile)
+		rawDef, seed, err := readObjectIDs(defInfo.File, rawDef, "_id")
 		if err != nil {
 			return nil, err
 		}
@@ -279,15 +748,25 @@ func fetchPolicies(fs billy.Filesystem, spec *TykSourceSpec) ([]objects.Policy,
 		if err != nil {
 			return nil, err
 		}
-
 		if defInfo.ID != "" {
 			pol.ID = defInfo.ID
 		}
 
+		// The id_seed of the spec entry wins over the file, an _id set in the file over both
+		if defInfo.IDSeed != "" {
+			seed = defInfo.IDSeed
+		}
+		pol.MID = objects.NormalID(pol.MID)
+		if pol.MID == "" && seed != "" {
+			pol.IDSeed = seed
+			pol.ApplySeed()
+		}
+
 		if pol.OrgID == "" {
 			return nil, errors.New("Policies must include an org ID")
 		}
 
+		pol.File = defInfo.File
 		defs[i] = pol
 	}
 
@@ -295,3 +774,142 @@ func fetchPolicies(fs billy.Filesystem, spec *TykSourceSpec) ([]objects.Policy,
 
 	return defs, nil
 }
+
+func (gg *FSGetter) FetchKeys(spec *TykSourceSpec) ([]objects.Key, error) {
+	return fetchKeys(gg.fs, spec)
+}
+
+func (gg *GitGetter) FetchKeys(spec *TykSourceSpec) ([]objects.Key, error) {
+	if gg.r == nil {
+		return nil, errors.New("No repository in memory, fetch repo first")
+	}
+	return fetchKeys(gg.fs, spec)
+}
+
+func fetchKeys(fs billy.Filesystem, spec *TykSourceSpec) ([]objects.Key, error) {
+	keys := make([]objects.Key, len(spec.Keys))
+	for i, info := range spec.Keys {
+		raw, err := readFile(fs, info.File)
+		if err != nil {
+			return nil, err
+		}
+		if raw, err = openEncrypted(info.File, raw); err != nil {
+			return nil, err
+		}
+		if raw, _, err = openSOPS(info.File, raw); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(raw, &keys[i]); err != nil {
+			return nil, fmt.Errorf("%v: %v", info.File, err)
+		}
+
+		if info.KeyID != "" {
+			keys[i].KeyID = info.KeyID
+		}
+	}
+
+	fmt.Printf("Fetched %v keys\n", len(keys))
+	return keys, nil
+}
+
+func (gg *FSGetter) FetchOAuthClients(spec *TykSourceSpec) ([]objects.OAuthClient, error) {
+	return fetchOAuthClients(gg.fs, spec)
+}
+
+func (gg *GitGetter) FetchOAuthClients(spec *TykSourceSpec) ([]objects.OAuthClient, error) {
+	if gg.r == nil {
+		return nil, errors.New("No repository in memory, fetch repo first")
+	}
+	return fetchOAuthClients(gg.fs, spec)
+}
+
+func fetchOAuthClients(fs billy.Filesystem, spec *TykSourceSpec) ([]objects.OAuthClient, error) {
+	clients := make([]objects.OAuthClient, len(spec.OAuthClients))
+	for i, info := range spec.OAuthClients {
+		raw, err := readFile(fs, info.File)
+		if err != nil {
+			return nil, err
+		}
+		if raw, err = openEncrypted(info.File, raw); err != nil {
+			return nil, err
+		}
+		if raw, _, err = openSOPS(info.File, raw); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(raw, &clients[i]); err != nil {
+			return nil, fmt.Errorf("%v: %v", info.File, err)
+		}
+
+		if info.ClientID != "" {
+			clients[i].ClientID = info.ClientID
+		}
+		if info.APIID != "" {
+			clients[i].APIID = info.APIID
+		}
+	}
+
+	fmt.Printf("Fetched %v OAuth clients\n", len(clients))
+	return clients, nil
+}
+
+func (gg *FSGetter) FetchCertificates(spec *TykSourceSpec) ([]Certificate, error) {
+	return fetchCertificates(gg.fs, spec)
+}
+
+func (gg *GitGetter) FetchCertificates(spec *TykSourceSpec) ([]Certificate, error) {
+	if gg.r == nil {
+		return nil, errors.New("No repository in memory, fetch repo first")
+	}
+	return fetchCertificates(gg.fs, spec)
+}
+
+func fetchCertificates(fs billy.Filesystem, spec *TykSourceSpec) ([]Certificate, error) {
+	certs := make([]Certificate, len(spec.Certificates))
+	for i, info := range spec.Certificates {
+		raw, err := readFile(fs, info.File)
+		if err != nil {
+			return nil, err
+		}
+
+		certs[i].ID = info.ID
+		if block, _ := pem.Decode(raw); block != nil {
+			certs[i].PEM = raw
+		}
+	}
+
+	fmt.Printf("Fetched %v certificates\n", len(certs))
+	return certs, nil
+}
+
+func (gg *FSGetter) FetchContractTests(spec *TykSourceSpec) ([]ContractTests, error) {
+	return fetchContractTests(gg.fs, spec)
+}
+
+func (gg *GitGetter) FetchContractTests(spec *TykSourceSpec) ([]ContractTests, error) {
+	if gg.r == nil {
+		return nil, errors.New("No repository in memory, fetch repo first")
+	}
+	return fetchContractTests(gg.fs, spec)
+}
+
+func readFile(fs billy.Filesystem, name string) ([]byte, error) {
+	f, err := fs.Open(specPath(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
+
+// readTextFile reads a JSON, YAML, XML or markdown file of the repo
+func readTextFile(fs billy.Filesystem, name string) ([]byte, error) {
+	raw, err := readFile(fs, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return normaliseText(raw), nil
+}
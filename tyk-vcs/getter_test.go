@@ -1,11 +1,31 @@
 package tyk_vcs
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
 )
 
 const REPO string = "https://github.com/lonelycode/integration-test.git"
 
+// memGetter is a getter of the files given, in memory
+func memGetter(t *testing.T, files map[string]string) *FSGetter {
+	fs := memfs.New()
+	for name, contents := range files {
+		if err := util.WriteFile(fs, name, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &FSGetter{fs: fs}
+}
+
 func TestNewGGetter(t *testing.T) {
 	_, e := NewGGetter(REPO, "refs/heads/master", []byte{})
 	if e != nil {
@@ -125,3 +145,127 @@ func TestGitGetter_FetchAPIDef_Swagger(t *testing.T) {
 		t.Fatalf("Target Was not properly set, expected: %v, got %v", ad.Proxy.ListenPath, ts.Files[0].OAS.OverrideListenPath)
 	}
 }
+
+func TestNewGGetterWithOptions(t *testing.T) {
+	if _, err := NewGGetterWithOptions(REPO, "refs/heads/master", nil, GitOptions{Tag: "v1", Commit: "abc"}); err == nil {
+		t.Fatal("expected an error for a tag and a commit")
+	}
+}
+
+func TestGitGetter_CheckoutSubDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "tyk-subdir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := os.Mkdir(filepath.Join(root, "apis"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "other.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Add("other.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	first := commitFiles(t, root, w, map[string]string{
+		".tyk.json": `{"type": "apidef", "files": [{"file": "a.json"}]}`,
+		"a.json":    `{"api_id": "a", "name": "A"}`,
+	})
+	commitFiles(t, root, w, map[string]string{
+		"a.json": `{"api_id": "a", "name": "A v2"}`,
+	})
+
+	gg := &GitGetter{r: r, opts: GitOptions{SubDir: "./apis/"}, worktree: memfs.New()}
+	if err := gg.checkout(plumbing.NewHash(first)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := gg.fs.Stat("other.json"); err == nil {
+		t.Fatal("files outside of the subdirectory must not be checked out")
+	}
+
+	spec, err := gg.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := gg.FetchAPIDef(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 1 || defs[0].Name != "A" {
+		t.Fatalf("expected the definition of the first commit, got %v", defs)
+	}
+
+	changes, err := gg.Changes(first, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changes.Changed["a.json"] || len(changes.Changed) != 1 {
+		t.Fatalf("unexpected changes: %v", changes.Changed)
+	}
+
+	defs, err = gg.FetchAPIDef(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defs[0].Name != "A v2" {
+		t.Fatalf("expected --to-commit to be checked out, got %v", defs[0].Name)
+	}
+}
+
+func TestFetchTykOASDefinitions(t *testing.T) {
+	g := memGetter(t, map[string]string{
+		".tyk.json":    `{"type": "apidef", "files": [{"file": "classic.json"}, {"file": "orders.yaml", "api_id": "orders"}]}`,
+		"classic.json": `{"api_definition": {"api_id": "users", "name": "Users", "proxy": {"listen_path": "/users/"}}}`,
+		"orders.yaml": `
+openapi: 3.0.3
+info:
+  title: Orders
+  version: 1.0.0
+paths: {}
+x-tyk-api-gateway:
+  info:
+    name: Orders
+    state:
+      active: true
+  upstream:
+    url: http://orders
+  server:
+    listenPath:
+      value: /orders/
+      strip: true
+`,
+	})
+
+	spec, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := g.FetchAPIDef(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(defs) != 2 || defs[0].APIID != "users" {
+		t.Fatalf("unexpected definitions: %v", defs)
+	}
+	orders := defs[1]
+	if orders.APIID != "orders" || orders.Name != "Orders" || orders.Proxy.ListenPath != "/orders/" ||
+		orders.Proxy.TargetURL != "http://orders" || !orders.UseKeylessAccess || !orders.Active {
+		t.Errorf("the Tyk OAS definition was not converted: %+v", orders.APIDefinition)
+	}
+	if _, ok := orders.Passthrough["x-tyk-api-gateway"]; ok {
+		t.Error("the Tyk OAS document should not be passed through to the target")
+	}
+}
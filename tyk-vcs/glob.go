@@ -0,0 +1,228 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+// IsGlob tells whether a file name of the spec is a pattern, e.g. apis/**/*.json
+func IsGlob(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// MatchGlob tells whether a file name matches a pattern of the spec. Patterns are matched
+// with path.Match per directory, and a ** directory matches any number of directories,
+// including none. As in shells, hidden files and directories only match a pattern that
+// starts with a dot, e.g. teams/*/.tyk.json.
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(specPath(pattern), "/"), strings.Split(specPath(name), "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+				if i < len(name) && hidden(name[i]) {
+					return false
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if hidden(name[0]) && !hidden(pattern[0]) {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+func hidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// walkFiles lists the files under dir, the .git directory is skipped
+func walkFiles(fs billy.Filesystem, dir string) ([]string, error) {
+	infos, err := fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, info := range infos {
+		if info.IsDir() && info.Name() == ".git" {
+			continue
+		}
+
+		name := path.Join(dir, info.Name())
+		if !info.IsDir() {
+			files = append(files, name)
+			continue
+		}
+
+		sub, err := walkFiles(fs, name)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sub...)
+	}
+
+	return files, nil
+}
+
+// globber expands the patterns of a spec, every file is listed once: files listed by name
+// are not added again by the patterns matching them
+type globber struct {
+	all    []string
+	listed map[string]bool
+}
+
+func newGlobber(fs billy.Filesystem, names []string) (*globber, error) {
+	all, err := walkFiles(fs, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(all)
+
+	g := &globber{all: all, listed: map[string]bool{}}
+	for _, n := range names {
+		if !IsGlob(n) {
+			g.listed[specPath(n)] = true
+		}
+	}
+
+	return g, nil
+}
+
+// expand returns the files matching pattern but none of the exclusions that no earlier entry
+// listed
+func (g *globber) expand(pattern string, exclude []string) ([]string, error) {
+	for _, p := range append([]string{pattern}, exclude...) {
+		if _, err := path.Match(specPath(p), ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %v: %v", p, err)
+		}
+	}
+
+	matches := []string{}
+	for _, f := range g.all {
+		// Defaults files are not definitions, see DefaultsFile
+		if g.listed[f] || path.Base(f) == DefaultsFile || !MatchGlob(pattern, f) || matchesAny(f, exclude) {
+			continue
+		}
+		g.listed[f] = true
+		matches = append(matches, f)
+	}
+
+	return matches, nil
+}
+
+// matchesAny tells whether name matches one of the patterns
+func matchesAny(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if MatchGlob(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandGlobs replaces the entries of the API and policy files given as patterns with one
+// entry per matching file, in the order of their names. The other settings of an entry,
+// e.g. its patches, apply to every file it matches, but IDs can't be given for a pattern.
+func expandGlobs(fs billy.Filesystem, spec *TykSourceSpec) error {
+	names := []string{}
+	for _, info := range spec.Files {
+		if IsGlob(info.File) {
+			if info.APIID != "" || info.DBID != "" || info.IDSeed != "" {
+				return fmt.Errorf("%v: api_id, db_id and id_seed can't be set for a pattern, it may match several files", info.File)
+			}
+			spec.patterns = append(spec.patterns, info.File)
+		}
+		names = append(names, info.File)
+	}
+	for _, info := range spec.Policies {
+		if IsGlob(info.File) {
+			if info.ID != "" || info.IDSeed != "" {
+				return fmt.Errorf("%v: id and id_seed can't be set for a pattern, it may match several files", info.File)
+			}
+			spec.patterns = append(spec.patterns, info.File)
+		}
+		names = append(names, info.File)
+	}
+
+	if len(spec.patterns) == 0 {
+		return nil
+	}
+
+	g, err := newGlobber(fs, names)
+	if err != nil {
+		return err
+	}
+
+	files := []APIInfo{}
+	for _, info := range spec.Files {
+		if !IsGlob(info.File) {
+			files = append(files, info)
+			continue
+		}
+
+		matches, err := g.expand(info.File, info.Exclude)
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			match := info
+			match.File, match.Exclude = m, nil
+			files = append(files, match)
+		}
+	}
+
+	policies := []PolicyInfo{}
+	for _, info := range spec.Policies {
+		if !IsGlob(info.File) {
+			policies = append(policies, info)
+			continue
+		}
+
+		matches, err := g.expand(info.File, info.Exclude)
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			match := info
+			match.File, match.Exclude = m, nil
+			policies = append(policies, match)
+		}
+	}
+
+	spec.Files, spec.Policies = files, policies
+	return nil
+}
+
+// ListsAPIFile tells whether an entry of the spec file, by name or by pattern, lists the API
+// definition file name
+func (ts *TykSourceSpec) ListsAPIFile(name string) bool {
+	for _, info := range ts.Files {
+		if specPath(info.File) == specPath(name) {
+			return true
+		}
+		if IsGlob(info.File) && MatchGlob(info.File, name) && !matchesAny(name, info.Exclude) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package tyk_vcs
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		match         bool
+	}{
+		{"apis/*.json", "apis/a.json", true},
+		{"apis/*.json", "apis/team/a.json", false},
+		{"apis/**/*.json", "apis/a.json", true},
+		{"apis/**/*.json", "apis/team/sub/a.json", true},
+		{"apis/**/*.json", "other/a.json", false},
+		{"**/*.yaml", "policies/p.yaml", true},
+		{"./apis/*.json", "apis/a.json", true},
+		{"apis\\*.json", "apis/a.json", true},
+		{"apis/**", "apis/team/a.json", true},
+		{"apis/a?.json", "apis/ab.json", true},
+		{"apis/[ab].json", "apis/c.json", false},
+		{"policies/*", "policies/.hidden.yaml", false},
+		{"**/*.json", ".git/config.json", false},
+		{"teams/*/.tyk.json", "teams/a/.tyk.json", true},
+	}
+
+	for _, tc := range cases {
+		if got := MatchGlob(tc.pattern, tc.name); got != tc.match {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.match)
+		}
+	}
+}
+
+func globFS(t *testing.T, spec string) *FSGetter {
+	return memGetter(t, map[string]string{
+		".tyk.json":             spec,
+		"apis/a.json":           `{"api_definition": {"api_id": "a", "name": "A"}}`,
+		"apis/team/b.json":      `{"api_definition": {"api_id": "b", "name": "B"}}`,
+		"apis/team/draft.json":  `{"api_definition": {"api_id": "draft", "name": "Draft"}}`,
+		"apis/notes.md":         "Not an API",
+		"policies/p1.yaml":      "id: p1\nname: P1\norg_id: org\nrate: 100\n",
+		"policies/p2.json":      `{"id": "p2", "name": "P2", "org_id": "org"}`,
+		"policies/.hidden.yaml": "not: listed",
+	})
+}
+
+func TestExpandGlobs(t *testing.T) {
+	g := globFS(t, `{
+		"type": "apidef",
+		"files": [
+			{"file": "apis/team/b.json", "api_id": "listed-b"},
+			{"file": "apis/**/*.json", "exclude": ["**/draft.json"], "org_id": "org"}
+		],
+		"policies": [{"file": "policies/*.yaml"}, {"file": "policies/*.json"}]
+	}`)
+
+	spec, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{}
+	for _, f := range spec.Files {
+		files = append(files, f.File)
+	}
+	if len(files) != 2 || files[0] != "apis/team/b.json" || files[1] != "apis/a.json" {
+		t.Fatalf("unexpected files: %v", files)
+	}
+	if spec.Files[1].ORGID != "org" || spec.Files[1].Exclude != nil {
+		t.Errorf("the settings of the pattern were not copied: %+v", spec.Files[1])
+	}
+
+	defs, err := g.FetchAPIDef(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defs[0].APIID != "listed-b" || defs[1].APIID != "a" {
+		t.Errorf("unexpected definitions: %v, %v", defs[0].APIID, defs[1].APIID)
+	}
+
+	pols, err := g.FetchPolicies(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pols) != 2 || pols[0].ID != "p1" || pols[0].Rate != 100 || pols[1].ID != "p2" {
+		t.Errorf("unexpected policies: %+v", pols)
+	}
+
+	if !spec.ListsAPIFile("apis/team/b.json") {
+		t.Error("expected the listed file to be found")
+	}
+}
+
+func TestExpandGlobsErrors(t *testing.T) {
+	for _, spec := range []string{
+		`{"type": "apidef", "files": [{"file": "apis/*.json", "api_id": "a"}]}`,
+		`{"type": "apidef", "policies": [{"file": "policies/*", "id": "p"}]}`,
+		`{"type": "apidef", "files": [{"file": "apis/[.json"}]}`,
+	} {
+		if _, err := globFS(t, spec).FetchTykSpec(); err == nil {
+			t.Errorf("expected an error for %v", spec)
+		}
+	}
+}
+
+func TestListsAPIFile(t *testing.T) {
+	spec := &TykSourceSpec{Files: []APIInfo{
+		{File: "./api-a.json"},
+		{File: "apis/**/*.json", Exclude: []string{"apis/drafts/*"}},
+	}}
+
+	cases := map[string]bool{
+		"api-a.json":         true,
+		"api-b.json":         false,
+		"apis/x/api-c.json":  true,
+		"apis/drafts/d.json": false,
+	}
+	for name, want := range cases {
+		if got := spec.ListsAPIFile(name); got != want {
+			t.Errorf("ListsAPIFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
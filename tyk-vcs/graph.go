@@ -0,0 +1,335 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// Kinds of the nodes of a dependency graph
+const (
+	NodeAPI         = "api"
+	NodePolicy      = "policy"
+	NodeCertificate = "certificate"
+	NodeWebhook     = "webhook"
+	NodeBundle      = "bundle"
+	NodePlugin      = "plugin"
+
+	GraphDOT     = "dot"
+	GraphMermaid = "mermaid"
+
+	// webhookHandler is the event handler of the gateway calling a webhook
+	webhookHandler = "eh_web_hook_handler"
+)
+
+// GraphNode is an object of a dependency graph. Missing nodes are referenced by the objects
+// of the repo without being part of it, e.g. an API a policy grants access to.
+type GraphNode struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	Label   string `json:"label"`
+	Missing bool   `json:"missing,omitempty"`
+}
+
+// GraphEdge is a reference from an object to another: policies grant APIs, APIs use
+// certificates, webhooks, plugin bundles and plugins, and the policies of their OIDC clients
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label,omitempty"`
+}
+
+// DependencyGraph holds the objects of a repo and their references, nodes and edges are
+// sorted so that the exported graphs are stable
+type DependencyGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+type graphBuilder struct {
+	nodes map[string]*GraphNode
+	edges map[GraphEdge]bool
+}
+
+func (b *graphBuilder) node(kind, id, label string, missing bool) string {
+	key := kind + ":" + id
+	if n, ok := b.nodes[key]; ok {
+		// A node referenced before it was defined is part of the repo after all
+		if !missing {
+			n.Missing = false
+			n.Label = label
+		}
+		return key
+	}
+
+	b.nodes[key] = &GraphNode{ID: key, Kind: kind, Label: label, Missing: missing}
+	return key
+}
+
+func (b *graphBuilder) edge(from, to, label string) {
+	b.edges[GraphEdge{From: from, To: to, Label: label}] = true
+}
+
+func middlewarePlugins(mw apidef.MiddlewareSection) []apidef.MiddlewareDefinition {
+	plugins := []apidef.MiddlewareDefinition{}
+	for _, list := range [][]apidef.MiddlewareDefinition{mw.Pre, mw.PostKeyAuth, mw.Post, mw.Response} {
+		plugins = append(plugins, list...)
+	}
+	if mw.AuthCheck.Name != "" {
+		plugins = append(plugins, mw.AuthCheck)
+	}
+	return plugins
+}
+
+// BuildDependencyGraph resolves the references between defs, pols and the certificates,
+// webhooks, plugin bundles and plugins the definitions use
+func BuildDependencyGraph(defs []objects.DBApiDefinition, pols []objects.Policy) *DependencyGraph {
+	b := &graphBuilder{nodes: map[string]*GraphNode{}, edges: map[GraphEdge]bool{}}
+
+	// Policies are referenced by their explicit ID, or their database ID
+	policyNodes := map[string]string{}
+	for _, p := range pols {
+		id := p.ID
+		if id == "" {
+			id = p.MID.Hex()
+		}
+		n := b.node(NodePolicy, id, p.Name, false)
+		policyNodes[id] = n
+		if mid := p.MID.Hex(); mid != "" {
+			policyNodes[mid] = n
+		}
+
+		for apiID, access := range p.AccessRights {
+			label := access.APIName
+			if label == "" {
+				label = apiID
+			}
+			b.edge(n, b.node(NodeAPI, apiID, label, true), "grants")
+		}
+	}
+
+	for _, d := range defs {
+		if d.APIDefinition == nil {
+			continue
+		}
+		api := b.node(NodeAPI, d.APIID, d.Name, false)
+
+		for _, id := range d.Certificates {
+			b.edge(api, b.node(NodeCertificate, id, id, false), "server certificate")
+		}
+		for _, id := range d.ClientCertificates {
+			b.edge(api, b.node(NodeCertificate, id, id, false), "client certificate")
+		}
+		for _, id := range d.UpstreamCertificates {
+			b.edge(api, b.node(NodeCertificate, id, id, false), "upstream certificate")
+		}
+		for _, ids := range d.PinnedPublicKeys {
+			for _, id := range strings.Split(ids, ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					b.edge(api, b.node(NodeCertificate, id, id, false), "pinned public key")
+				}
+			}
+		}
+
+		for event, handlers := range d.EventHandlers.Events {
+			for _, h := range handlers {
+				if h.Handler != webhookHandler {
+					continue
+				}
+				target, _ := h.HandlerMeta["target_path"].(string)
+				if target == "" {
+					continue
+				}
+				label := target
+				if name, _ := h.HandlerMeta["name"].(string); name != "" {
+					label = name
+				}
+				b.edge(api, b.node(NodeWebhook, target, label, false), string(event))
+			}
+		}
+
+		if d.CustomMiddlewareBundle != "" {
+			b.edge(api, b.node(NodeBundle, d.CustomMiddlewareBundle, d.CustomMiddlewareBundle, false), "bundle")
+		} else {
+			for _, mw := range middlewarePlugins(d.CustomMiddleware) {
+				id := mw.Name
+				if mw.Path != "" {
+					id = mw.Path + "#" + mw.Name
+				}
+				b.edge(api, b.node(NodePlugin, id, id, false), string(d.CustomMiddleware.Driver))
+			}
+		}
+
+		if d.UseOpenID {
+			for _, p := range d.OpenIDOptions.Providers {
+				for _, polID := range p.ClientIDs {
+					n, ok := policyNodes[polID]
+					if !ok {
+						n = b.node(NodePolicy, polID, polID, true)
+					}
+					b.edge(api, n, "OIDC client policy")
+				}
+			}
+		}
+	}
+
+	g := &DependencyGraph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	for _, n := range b.nodes {
+		g.Nodes = append(g.Nodes, *n)
+	}
+	for e := range b.edges {
+		g.Edges = append(g.Edges, e)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Label < b.Label
+	})
+
+	return g
+}
+
+// Around returns the part of the graph connected to the objects with the given IDs (API IDs,
+// policy IDs, certificate IDs...), what a change to them may affect and what they depend on
+func (g *DependencyGraph) Around(ids []string) *DependencyGraph {
+	neighbours := map[string][]string{}
+	for _, e := range g.Edges {
+		neighbours[e.From] = append(neighbours[e.From], e.To)
+		neighbours[e.To] = append(neighbours[e.To], e.From)
+	}
+
+	wanted := map[string]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	seen := map[string]bool{}
+	queue := []string{}
+	for _, n := range g.Nodes {
+		if wanted[n.ID] || wanted[strings.TrimPrefix(n.ID, n.Kind+":")] {
+			seen[n.ID] = true
+			queue = append(queue, n.ID)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range neighbours[id] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	sub := &DependencyGraph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	for _, n := range g.Nodes {
+		if seen[n.ID] {
+			sub.Nodes = append(sub.Nodes, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if seen[e.From] {
+			sub.Edges = append(sub.Edges, e)
+		}
+	}
+
+	return sub
+}
+
+var dotShapes = map[string]string{
+	NodeAPI:         "box",
+	NodePolicy:      "ellipse",
+	NodeCertificate: "note",
+	NodeWebhook:     "cds",
+	NodeBundle:      "folder",
+	NodePlugin:      "component",
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s) + `"`
+}
+
+// DOT renders the graph in the Graphviz format, e.g. for dot -Tsvg
+func (g *DependencyGraph) DOT() string {
+	out := &strings.Builder{}
+	out.WriteString("digraph tyk {\n\trankdir=LR;\n")
+	for _, n := range g.Nodes {
+		style := ""
+		if n.Missing {
+			style = `, style=dashed`
+		}
+		fmt.Fprintf(out, "\t%v [label=%v, shape=%v%v];\n", dotQuote(n.ID), dotQuote(n.Kind+": "+n.Label), dotShapes[n.Kind], style)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(out, "\t%v -> %v [label=%v];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(e.Label))
+	}
+	out.WriteString("}\n")
+
+	return out.String()
+}
+
+func mermaidText(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "|", "#124;", "\n", " ").Replace(s)
+}
+
+// Mermaid renders the graph as a Mermaid flowchart, which GitHub and GitLab render in
+// Markdown files
+func (g *DependencyGraph) Mermaid() string {
+	ids := map[string]string{}
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%v", i)
+	}
+
+	out := &strings.Builder{}
+	out.WriteString("flowchart LR\n")
+	for _, n := range g.Nodes {
+		label := mermaidText(n.Kind + ": " + n.Label)
+		switch n.Kind {
+		case NodePolicy:
+			fmt.Fprintf(out, "    %v([\"%v\"])\n", ids[n.ID], label)
+		case NodeCertificate, NodeBundle, NodePlugin:
+			fmt.Fprintf(out, "    %v[/\"%v\"/]\n", ids[n.ID], label)
+		case NodeWebhook:
+			fmt.Fprintf(out, "    %v{{\"%v\"}}\n", ids[n.ID], label)
+		default:
+			fmt.Fprintf(out, "    %v[\"%v\"]\n", ids[n.ID], label)
+		}
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(out, "    %v -->|\"%v\"| %v\n", ids[e.From], mermaidText(e.Label), ids[e.To])
+	}
+
+	missing := []string{}
+	for _, n := range g.Nodes {
+		if n.Missing {
+			missing = append(missing, ids[n.ID])
+		}
+	}
+	if len(missing) > 0 {
+		out.WriteString("    classDef missing stroke-dasharray: 5 5\n")
+		fmt.Fprintf(out, "    class %v missing\n", strings.Join(missing, ","))
+	}
+
+	return out.String()
+}
+
+// Render renders the graph in format, GraphDOT or GraphMermaid
+func (g *DependencyGraph) Render(format string) (string, error) {
+	switch format {
+	case GraphDOT:
+		return g.DOT(), nil
+	case GraphMermaid:
+		return g.Mermaid(), nil
+	}
+	return "", fmt.Errorf("unknown graph format %q, must be %v or %v", format, GraphDOT, GraphMermaid)
+}
@@ -0,0 +1,84 @@
+package tyk_vcs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	payments := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{
+		APIID:                  "a1",
+		Name:                   "Payments",
+		UpstreamCertificates:   map[string]string{"*": "cert1"},
+		CustomMiddlewareBundle: "payments-v2.zip",
+		EventHandlers: apidef.EventHandlerMetaConfig{Events: map[apidef.TykEvent][]apidef.EventHandlerTriggerConfig{
+			"QuotaExceeded": {{Handler: webhookHandler, HandlerMeta: map[string]interface{}{"target_path": "https://hooks/quota", "name": "Quota alerts"}}},
+		}},
+	}}
+	orders := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: "a2", Name: "Orders", UseOpenID: true}}
+	orders.OpenIDOptions.Providers = []apidef.OIDProviderConfig{{Issuer: "https://idp", ClientIDs: map[string]string{"client": "gold"}}}
+	orders.CustomMiddleware.Driver = apidef.OttoDriver
+	orders.CustomMiddleware.Pre = []apidef.MiddlewareDefinition{{Name: "checkOrder", Path: "middleware/order.js"}}
+
+	pols := []objects.Policy{
+		{ID: "gold", Name: "Gold", AccessRights: map[string]objects.AccessDefinition{"a1": {APIName: "Payments"}, "gone": {}}},
+		{ID: "silver", Name: "Silver", AccessRights: map[string]objects.AccessDefinition{"a3": {}}},
+	}
+
+	g := BuildDependencyGraph([]objects.DBApiDefinition{payments, orders}, pols)
+
+	nodes := map[string]GraphNode{}
+	for _, n := range g.Nodes {
+		nodes[n.ID] = n
+	}
+	for _, id := range []string{"api:a1", "api:a2", "policy:gold", "certificate:cert1", "webhook:https://hooks/quota", "bundle:payments-v2.zip", "plugin:middleware/order.js#checkOrder"} {
+		if n, ok := nodes[id]; !ok || n.Missing {
+			t.Errorf("expected %v in the graph, got %+v", id, n)
+		}
+	}
+	if !nodes["api:gone"].Missing || nodes["api:a1"].Label != "Payments" {
+		t.Errorf("expected the API missing from the repo flagged, got %+v", nodes)
+	}
+
+	edges := map[string]bool{}
+	for _, e := range g.Edges {
+		edges[e.From+" "+e.To+" "+e.Label] = true
+	}
+	for _, e := range []string{
+		"policy:gold api:a1 grants",
+		"api:a1 certificate:cert1 upstream certificate",
+		"api:a1 webhook:https://hooks/quota QuotaExceeded",
+		"api:a2 policy:gold OIDC client policy",
+		"api:a2 plugin:middleware/order.js#checkOrder otto",
+	} {
+		if !edges[e] {
+			t.Errorf("expected the edge %v, got %v", e, g.Edges)
+		}
+	}
+
+	// A change to the certificate affects Payments, Gold and through Gold, Orders
+	around := g.Around([]string{"cert1"})
+	if len(around.Nodes) != 8 {
+		t.Errorf("expected the Silver policy left out, got %+v", around.Nodes)
+	}
+	for _, n := range around.Nodes {
+		if n.ID == "policy:silver" || n.ID == "api:a3" {
+			t.Errorf("unexpected %v around cert1", n.ID)
+		}
+	}
+
+	dot := g.DOT()
+	if !strings.Contains(dot, `"policy:gold" -> "api:a1" [label="grants"];`) || !strings.Contains(dot, `"api:gone" [label="api: gone", shape=box, style=dashed];`) {
+		t.Errorf("unexpected dot graph:\n%v", dot)
+	}
+	mermaid := g.Mermaid()
+	if !strings.HasPrefix(mermaid, "flowchart LR\n") || !strings.Contains(mermaid, `-->|"grants"|`) || !strings.Contains(mermaid, "class ") {
+		t.Errorf("unexpected mermaid graph:\n%v", mermaid)
+	}
+	if _, err := g.Render("svg"); err == nil {
+		t.Error("expected an unknown format refused")
+	}
+}
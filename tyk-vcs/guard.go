@@ -0,0 +1,73 @@
+package tyk_vcs
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// DeleteGuard stops a sync that would delete more objects than expected, e.g. because the
+// path or branch pointed at the wrong (or an empty) set of definitions
+type DeleteGuard struct {
+	// MaxDeletes is the number of deletes allowed without confirmation, 0 disables the check
+	MaxDeletes int
+	// MaxDeletePercent is the share of the existing objects that may be deleted without
+	// confirmation, 0 disables the check
+	MaxDeletePercent float64
+	// Force skips the guard
+	Force bool
+	// Confirm is asked when a threshold is exceeded, with no Confirm the sync is refused
+	Confirm func(question string) bool
+}
+
+// Exceeded returns a description of the threshold the plan exceeds, or an empty string
+func (g *DeleteGuard) Exceeded(plan *objects.SyncPlan) string {
+	deletes := len(plan.Delete)
+	if deletes == 0 {
+		return ""
+	}
+
+	// Deactivating as many APIs takes them offline all the same
+	verb := "deleted"
+	if plan.Deactivate {
+		verb = "deactivated"
+	}
+
+	if g.MaxDeletes > 0 && deletes > g.MaxDeletes {
+		return fmt.Sprintf("%v %v would be %v, more than the allowed %v", deletes, plan.Kind, verb, g.MaxDeletes)
+	}
+
+	if g.MaxDeletePercent > 0 && plan.Existing > 0 {
+		percent := float64(deletes) * 100 / float64(plan.Existing)
+		if percent > g.MaxDeletePercent {
+			return fmt.Sprintf("%v of %v %v (%.0f%%) would be %v, more than the allowed %v%%",
+				deletes, plan.Existing, plan.Kind, percent, verb, g.MaxDeletePercent)
+		}
+	}
+
+	return ""
+}
+
+// Check can be used as the plan check of a client
+func (g *DeleteGuard) Check(plan *objects.SyncPlan) error {
+	if g.Force {
+		return nil
+	}
+
+	reason := g.Exceeded(plan)
+	if reason == "" {
+		return nil
+	}
+
+	if g.Confirm != nil {
+		for _, item := range plan.Delete {
+			fmt.Printf("--> Delete: %v (%v)\n", item.Name, item.ID)
+		}
+
+		if g.Confirm(reason + ", continue?") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("refusing to sync: %v, use --force-delete to continue anyway", reason)
+}
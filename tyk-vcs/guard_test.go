@@ -0,0 +1,41 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+func planWithDeletes(existing, deletes int) *objects.SyncPlan {
+	plan := &objects.SyncPlan{Kind: "APIs", Existing: existing}
+	for i := 0; i < deletes; i++ {
+		plan.Delete = append(plan.Delete, objects.SyncItem{ID: "api", Index: i})
+	}
+
+	return plan
+}
+
+func TestDeleteGuard(t *testing.T) {
+	tests := []struct {
+		name     string
+		guard    DeleteGuard
+		plan     *objects.SyncPlan
+		expected bool
+	}{
+		{"no deletes", DeleteGuard{MaxDeletes: 1, MaxDeletePercent: 10}, planWithDeletes(10, 0), true},
+		{"below count", DeleteGuard{MaxDeletes: 2}, planWithDeletes(10, 2), true},
+		{"above count", DeleteGuard{MaxDeletes: 2}, planWithDeletes(10, 3), false},
+		{"above percent", DeleteGuard{MaxDeletePercent: 20}, planWithDeletes(10, 3), false},
+		{"below percent", DeleteGuard{MaxDeletePercent: 50}, planWithDeletes(10, 3), true},
+		{"forced", DeleteGuard{MaxDeletes: 1, Force: true}, planWithDeletes(10, 5), true},
+		{"confirmed", DeleteGuard{MaxDeletes: 1, Confirm: func(string) bool { return true }}, planWithDeletes(10, 5), true},
+		{"declined", DeleteGuard{MaxDeletes: 1, Confirm: func(string) bool { return false }}, planWithDeletes(10, 5), false},
+	}
+
+	for _, tc := range tests {
+		err := tc.guard.Check(tc.plan)
+		if (err == nil) != tc.expected {
+			t.Fatalf("%v: expected allowed=%v, got error: %v", tc.name, tc.expected, err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// GuardrailsInfo are the limits the policies of a repo must keep, checked by analyze and
+// before sync, publish and update change anything, so a policy granting too much isn't
+// promoted by accident
+type GuardrailsInfo struct {
+	// MaxRate is the most requests per second a policy may allow, 0 for no maximum
+	MaxRate float64 `json:"max_rate,omitempty"`
+	// RequireQuota requires every policy to set a quota
+	RequireQuota bool `json:"require_quota,omitempty"`
+	// ProductionTags are the tags of production APIs, policies granting them must set both a
+	// rate limit and a quota
+	ProductionTags []string `json:"production_tags,omitempty"`
+}
+
+// GuardrailViolation is a policy breaking a rule of the guardrails
+type GuardrailViolation struct {
+	PolicyID string `json:"policy_id"`
+	Name     string `json:"name"`
+	// APIID is set when the violation is about the access right to one API
+	APIID string `json:"api_id,omitempty"`
+	// Rule is the guardrail broken: max_rate, require_quota or production_tags
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (v GuardrailViolation) String() string {
+	return fmt.Sprintf("Policy %v (%v) %v", v.Name, v.PolicyID, v.Message)
+}
+
+// policyLimit is the rate limit and quota a policy applies to an API, a rate or per of 0 or
+// less is no rate limit and a quota_max of 0 or less no quota, as the gateway reads them
+type policyLimit struct {
+	rate  float64
+	per   float64
+	quota int64
+}
+
+func (l policyLimit) unlimitedRate() bool {
+	return l.rate <= 0 || l.per <= 0
+}
+
+func (l policyLimit) unlimitedQuota() bool {
+	return l.quota <= 0
+}
+
+// accessLimit is the limit pol applies to the API of ar: the limit of the access right with
+// per API limits, those of the policy otherwise
+func accessLimit(pol *objects.Policy, ar objects.AccessDefinition) policyLimit {
+	if pol.Partitions.PerAPI && ar.Limit != nil {
+		return policyLimit{rate: ar.Limit.Rate, per: ar.Limit.Per, quota: ar.Limit.QuotaMax}
+	}
+	return policyLimit{rate: pol.Rate, per: pol.Per, quota: pol.QuotaMax}
+}
+
+// CheckGuardrails returns the violations of the guardrails g by pols. Limits are checked per
+// access right for policies with per API limits, once per policy otherwise. The production
+// APIs are those of defs carrying one of the production tags. g may be nil.
+func CheckGuardrails(g *GuardrailsInfo, pols []objects.Policy, defs []objects.DBApiDefinition) []GuardrailViolation {
+	violations := []GuardrailViolation{}
+	if g == nil {
+		return violations
+	}
+
+	production := map[string]bool{}
+	for _, d := range defs {
+		if d.APIDefinition == nil {
+			continue
+		}
+		for _, t := range d.Tags {
+			for _, pt := range g.ProductionTags {
+				if t == pt {
+					production[d.APIID] = true
+				}
+			}
+		}
+	}
+
+	for i := range pols {
+		pol := &pols[i]
+		id := pol.ID
+		if id == "" {
+			id = pol.MID.Hex()
+		}
+		add := func(apiID, rule, format string, args ...interface{}) {
+			violations = append(violations, GuardrailViolation{PolicyID: id, Name: pol.Name, APIID: apiID, Rule: rule, Message: fmt.Sprintf(format, args...)})
+		}
+		checkLimit := func(apiID string, l policyLimit) {
+			on := ""
+			if apiID != "" {
+				on = " on API " + apiID
+			}
+			if g.MaxRate > 0 {
+				if l.unlimitedRate() {
+					add(apiID, "max_rate", "has no rate limit%v, the max_rate is %v per second", on, g.MaxRate)
+				} else if l.rate/l.per > g.MaxRate {
+					add(apiID, "max_rate", "allows %v requests per %v seconds%v, more than the max_rate of %v per second", l.rate, l.per, on, g.MaxRate)
+				}
+			}
+			if g.RequireQuota && l.unlimitedQuota() {
+				add(apiID, "require_quota", "has no quota%v", on)
+			}
+		}
+
+		apiIDs := []string{}
+		for apiID := range pol.AccessRights {
+			apiIDs = append(apiIDs, apiID)
+		}
+		sort.Strings(apiIDs)
+
+		if !pol.Partitions.PerAPI || len(apiIDs) == 0 {
+			checkLimit("", policyLimit{rate: pol.Rate, per: pol.Per, quota: pol.QuotaMax})
+		}
+
+		for _, apiID := range apiIDs {
+			l := accessLimit(pol, pol.AccessRights[apiID])
+			if pol.Partitions.PerAPI {
+				checkLimit(apiID, l)
+			}
+			if !production[apiID] {
+				continue
+			}
+			if l.unlimitedRate() {
+				add(apiID, "production_tags", "grants the production API %v without a rate limit", apiID)
+			}
+			if l.unlimitedQuota() {
+				add(apiID, "production_tags", "grants the production API %v without a quota", apiID)
+			}
+		}
+	}
+
+	return violations
+}
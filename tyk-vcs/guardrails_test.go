@@ -0,0 +1,57 @@
+package tyk_vcs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+func TestCheckGuardrails(t *testing.T) {
+	payments := liveDef("payments", "/payments/", "", true)
+	payments.Tags = []string{"production"}
+	sandbox := liveDef("sandbox", "/sandbox/", "", true)
+	defs := []objects.DBApiDefinition{payments, sandbox}
+
+	ok := objects.Policy{ID: "ok", Name: "OK", Rate: 100, Per: 60, QuotaMax: 1000,
+		AccessRights: map[string]objects.AccessDefinition{"payments": {APIID: "payments"}}}
+	fast := objects.Policy{ID: "fast", Name: "Fast", Rate: 1000, Per: 1, QuotaMax: 1000,
+		AccessRights: map[string]objects.AccessDefinition{"sandbox": {APIID: "sandbox"}}}
+	open := objects.Policy{ID: "open", Name: "Open", Rate: -1, Per: -1, QuotaMax: -1,
+		AccessRights: map[string]objects.AccessDefinition{"payments": {APIID: "payments"}, "sandbox": {APIID: "sandbox"}}}
+	perAPI := objects.Policy{ID: "per-api", Name: "Per API", AccessRights: map[string]objects.AccessDefinition{
+		"payments": {APIID: "payments", Limit: &objects.APILimit{Rate: 10, Per: 1, QuotaMax: -1}},
+		"sandbox":  {APIID: "sandbox", Limit: &objects.APILimit{Rate: 50, Per: 1, QuotaMax: 100}},
+	}}
+	perAPI.Partitions.PerAPI = true
+
+	g := &GuardrailsInfo{MaxRate: 20, RequireQuota: true, ProductionTags: []string{"production"}}
+	got := []string{}
+	for _, v := range CheckGuardrails(g, []objects.Policy{ok, fast, open, perAPI}, defs) {
+		got = append(got, v.PolicyID+" "+v.APIID+" "+v.Rule)
+	}
+
+	expected := []string{
+		"fast  max_rate",
+		"open  max_rate",
+		"open  require_quota",
+		"open payments production_tags",
+		"open payments production_tags",
+		"per-api payments require_quota",
+		"per-api payments production_tags",
+		"per-api sandbox max_rate",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected violations:\n%v\nexpected:\n%v", got, expected)
+	}
+
+	// Only the production rule
+	g = &GuardrailsInfo{ProductionTags: []string{"production"}}
+	if v := CheckGuardrails(g, []objects.Policy{ok, fast}, defs); len(v) != 0 {
+		t.Fatalf("unexpected violations: %v", v)
+	}
+
+	if v := CheckGuardrails(nil, []objects.Policy{open}, defs); len(v) != 0 {
+		t.Fatalf("no guardrails should allow anything: %v", v)
+	}
+}
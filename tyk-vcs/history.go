@@ -0,0 +1,272 @@
+package tyk_vcs
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"gopkg.in/src-d/go-git.v4"
+)
+
+const (
+	HistoryOK     = "ok"
+	HistoryFailed = "failed"
+
+	// HistoryRun is the action of the entry a sync records when it finishes, publish and
+	// update record theirs with their own name
+	HistoryRun = "sync"
+)
+
+// CommitReporter is implemented by getters that know the commit the objects are read from
+type CommitReporter interface {
+	CheckedOutCommit() (string, error)
+}
+
+// CheckedOutCommit is the commit checked out by FetchRepo
+func (gg *GitGetter) CheckedOutCommit() (string, error) {
+	if gg.commit != "" {
+		return gg.commit, nil
+	}
+	if gg.r == nil {
+		return "", fmt.Errorf("no repository in memory, fetch repo first")
+	}
+
+	head, err := gg.r.Head()
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
+}
+
+// CheckedOutCommit is the commit checked out in the directory, if it is part of a git repo
+func (gg *FSGetter) CheckedOutCommit() (string, error) {
+	r, err := git.PlainOpenWithOptions(gg.fs.Root(), &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return "", err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
+}
+
+// HistoryEntry is a change applied to a target, or the outcome of a sync run
+type HistoryEntry struct {
+	Time   time.Time `json:"time"`
+	Target string    `json:"target"`
+	// Commit is the commit the objects were read from, if they were read from git
+	Commit string `json:"commit,omitempty"`
+	// Kind is APIs or policies, empty for runs
+	Kind string `json:"kind,omitempty"`
+	// Action is create, update or delete, for runs the command: sync, publish or update
+	Action string `json:"action"`
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	// Hash is the SHA-256 of the JSON of the object sent, for creates and updates
+	Hash   string `json:"hash,omitempty"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// History records publishes to a file, one JSON entry per line, entries are only ever
+// appended to it
+type History struct {
+	Path   string
+	Target string
+	Commit string
+
+	mu sync.Mutex
+}
+
+// Record appends an entry, its time, target and commit are set if empty
+func (h *History) Record(e HistoryEntry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if e.Target == "" {
+		e.Target = h.Target
+	}
+	if e.Commit == "" {
+		e.Commit = h.Commit
+	}
+
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f, err := os.OpenFile(h.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(raw, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// RecordRun records the outcome of a run of the command action, e.g. HistoryRun
+func (h *History) RecordRun(action string, err error) error {
+	e := HistoryEntry{Action: action, Result: HistoryOK}
+	if err != nil {
+		e.Result, e.Error = HistoryFailed, err.Error()
+	}
+	return h.Record(e)
+}
+
+func hashObject(v interface{}) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func (h *History) recordEvent(e *objects.HookEvent) {
+	entry := HistoryEntry{Kind: e.Kind, Action: e.Action, ID: e.ID, Result: HistoryOK}
+	switch {
+	case e.API != nil && e.API.APIDefinition != nil:
+		entry.ID, entry.Name = e.API.APIID, e.API.Name
+		if payload, err := e.API.DefinitionPayload(); err == nil {
+			entry.Hash = hashObject(payload)
+		}
+	case e.Policy != nil:
+		entry.Name = e.Policy.Name
+		if entry.ID == "" {
+			entry.ID = e.Policy.MID.Hex()
+		}
+		entry.Hash = hashObject(e.Policy)
+	}
+
+	if e.Err != nil {
+		entry.Result, entry.Error = HistoryFailed, e.Err.Error()
+	}
+
+	if err := h.Record(entry); err != nil {
+		fmt.Printf("--> [WARNING] Could not record the %v of %v in the history: %v\n", e.Action, entry.ID, err)
+	}
+}
+
+// Hooks return the hooks recording every change of a client, h may be nil
+func (h *History) Hooks() *objects.Hooks {
+	if h == nil {
+		return nil
+	}
+
+	return &objects.Hooks{
+		OnAfterCreate: h.recordEvent,
+		OnAfterUpdate: h.recordEvent,
+		OnAfterDelete: h.recordEvent,
+		OnError:       h.recordEvent,
+	}
+}
+
+// HistoryFilter selects history entries, empty fields match any entry
+type HistoryFilter struct {
+	Target string
+	Kind   string
+	ID     string
+	Commit string
+	Since  time.Time
+	// Failed only matches failed changes and runs
+	Failed bool
+}
+
+func (f HistoryFilter) matches(e HistoryEntry) bool {
+	return (f.Target == "" || e.Target == f.Target) &&
+		(f.Kind == "" || e.Kind == f.Kind) &&
+		(f.ID == "" || e.ID == f.ID) &&
+		(f.Commit == "" || e.Commit == f.Commit) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(!f.Failed || e.Result == HistoryFailed)
+}
+
+// ReadHistory returns the entries of a history file matching the filter, oldest first. A
+// missing file is an empty history.
+func ReadHistory(path string, filter HistoryFilter) ([]HistoryEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return []HistoryEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []HistoryEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		e := HistoryEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%v:%v: %v", path, line, err)
+		}
+		if filter.matches(e) {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// syncedCommits returns the commits of the syncs of target that succeeded, oldest first
+func syncedCommits(path, target string) ([]string, error) {
+	entries, err := ReadHistory(path, HistoryFilter{Target: target})
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []string{}
+	for _, e := range entries {
+		if e.Kind == "" && e.Action == HistoryRun && e.Result == HistoryOK && e.Commit != "" {
+			commits = append(commits, e.Commit)
+		}
+	}
+	return commits, nil
+}
+
+// LastSyncedCommit returns the commit of the last sync of target that succeeded, empty if
+// there is none yet
+func LastSyncedCommit(path, target string) (string, error) {
+	commits, err := syncedCommits(path, target)
+	if err != nil || len(commits) == 0 {
+		return "", err
+	}
+	return commits[len(commits)-1], nil
+}
+
+// PreviousSyncedCommit returns the commit target was synced to before the last successful
+// sync, the one to roll back to, empty if there is none
+func PreviousSyncedCommit(path, target string) (string, error) {
+	commits, err := syncedCommits(path, target)
+	if err != nil || len(commits) == 0 {
+		return "", err
+	}
+
+	last := commits[len(commits)-1]
+	for i := len(commits) - 2; i >= 0; i-- {
+		if commits[i] != last {
+			return commits[i], nil
+		}
+	}
+	return "", nil
+}
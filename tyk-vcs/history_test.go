@@ -0,0 +1,80 @@
+package tyk_vcs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history.jsonl")
+
+	if commit, err := LastSyncedCommit(path, "http://dash"); err != nil || commit != "" {
+		t.Fatalf("expected an empty history, got %q %v", commit, err)
+	}
+
+	h := &History{Path: path, Target: "http://dash", Commit: "c1"}
+	hooks := h.Hooks()
+	def := &objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: "a1", Name: "A1"}}
+
+	if err := hooks.Run(&objects.HookEvent{Kind: "APIs", Action: objects.HookUpdate, API: def, ID: "a1"}, func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	hooks.Run(&objects.HookEvent{Kind: "policies", Action: objects.HookDelete, ID: "p1"}, func() error { return errors.New("not found") })
+	h.RecordRun(HistoryRun, nil)
+
+	h.Commit = "c2"
+	h.RecordRun(HistoryRun, errors.New("dashboard down"))
+
+	entries, err := ReadHistory(path, HistoryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %v", entries)
+	}
+	if e := entries[0]; e.ID != "a1" || e.Name != "A1" || e.Hash == "" || e.Commit != "c1" || e.Result != HistoryOK {
+		t.Errorf("unexpected update entry %+v", e)
+	}
+	if e := entries[1]; e.Kind != "policies" || e.Result != HistoryFailed || e.Error != "not found" {
+		t.Errorf("unexpected delete entry %+v", e)
+	}
+
+	failed, _ := ReadHistory(path, HistoryFilter{Failed: true, Kind: "policies"})
+	if len(failed) != 1 || failed[0].ID != "p1" {
+		t.Errorf("expected the failed delete, got %v", failed)
+	}
+
+	// The last sync failed, resuming starts from the one before
+	if commit, _ := LastSyncedCommit(path, "http://dash"); commit != "c1" {
+		t.Errorf("expected c1 as the last synced commit, got %q", commit)
+	}
+	if commit, _ := LastSyncedCommit(path, "http://other"); commit != "" {
+		t.Errorf("expected no synced commit for another target, got %q", commit)
+	}
+
+	// Publishes don't count as syncs, rolling back skips the syncs of the last commit
+	if commit, _ := PreviousSyncedCommit(path, "http://dash"); commit != "" {
+		t.Errorf("expected nothing to roll back to, got %q", commit)
+	}
+	h.Commit = "c3"
+	h.RecordRun("publish", nil)
+	h.RecordRun(HistoryRun, nil)
+	h.RecordRun(HistoryRun, nil)
+	if commit, _ := LastSyncedCommit(path, "http://dash"); commit != "c3" {
+		t.Errorf("expected c3 as the last synced commit, got %q", commit)
+	}
+	if commit, _ := PreviousSyncedCommit(path, "http://dash"); commit != "c1" {
+		t.Errorf("expected to roll back to c1, got %q", commit)
+	}
+}
@@ -0,0 +1,255 @@
+package tyk_vcs
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+const (
+	IdPJWKS = "jwks"
+	IdPOIDC = "oidc"
+
+	// oidcDiscoveryPath is where an OpenID Connect issuer publishes its configuration
+	oidcDiscoveryPath = "/.well-known/openid-configuration"
+
+	// maxIdPResponse caps the size of the discovery documents and key sets read
+	maxIdPResponse = 1 << 20
+)
+
+// IdPUse is a JWKS URL or OpenID Connect issuer and the APIs that reference it
+type IdPUse struct {
+	URL  string   `json:"url"`
+	Kind string   `json:"kind"`
+	APIs []string `json:"apis"`
+	// KeyType is the kty the keys must have for the signing method of the APIs, any if empty
+	KeyType string `json:"key_type,omitempty"`
+	// Keys is the number of usable signing keys the key set holds
+	Keys  int    `json:"keys"`
+	Error string `json:"error,omitempty"`
+}
+
+// jwtKeyTypes maps the jwt_signing_method of a definition to the kty of the JWKS keys
+var jwtKeyTypes = map[string]string{
+	"rsa":   "RSA",
+	"ecdsa": "EC",
+	"hmac":  "oct",
+}
+
+// jwksURL returns the URL jwt_source points to, it is either the URL or the URL base64
+// encoded; sources holding a key or secret, or redacted, have none
+func jwksURL(source string) string {
+	source = strings.TrimSpace(source)
+	if source == "" || placeholderMatch.MatchString(source) {
+		return ""
+	}
+	if isHTTPURL(source) {
+		return source
+	}
+
+	if raw, err := base64.StdEncoding.DecodeString(source); err == nil && isHTTPURL(string(raw)) {
+		return strings.TrimSpace(string(raw))
+	}
+	return ""
+}
+
+func isHTTPURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// IdPReferences lists the JWKS URLs of the JWT APIs and the issuers of the OpenID Connect
+// APIs of defs, with the APIs using them, sorted by URL
+func IdPReferences(defs []objects.DBApiDefinition) []IdPUse {
+	uses := map[string]*IdPUse{}
+	add := func(kind, url, keyType, api string) {
+		key := kind + " " + url
+		u, ok := uses[key]
+		if !ok {
+			u = &IdPUse{URL: url, Kind: kind, KeyType: keyType}
+			uses[key] = u
+		}
+		// APIs expecting different key types are satisfied by any of the keys
+		if u.KeyType != keyType {
+			u.KeyType = ""
+		}
+		for _, a := range u.APIs {
+			if a == api {
+				return
+			}
+		}
+		u.APIs = append(u.APIs, api)
+	}
+
+	for _, d := range defs {
+		if d.APIDefinition == nil {
+			continue
+		}
+
+		api := fmt.Sprintf("%v (%v)", d.Name, d.APIID)
+		if d.EnableJWT {
+			if url := jwksURL(d.JWTSource); url != "" {
+				add(IdPJWKS, url, jwtKeyTypes[d.JWTSigningMethod], api)
+			}
+		}
+		if d.UseOpenID {
+			for _, p := range d.OpenIDOptions.Providers {
+				if isHTTPURL(p.Issuer) {
+					add(IdPOIDC, p.Issuer, "", api)
+				}
+			}
+		}
+	}
+
+	list := []IdPUse{}
+	for _, u := range uses {
+		list = append(list, *u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].URL != list[j].URL {
+			return list[i].URL < list[j].URL
+		}
+		return list[i].Kind < list[j].Kind
+	})
+
+	return list
+}
+
+// CheckIdentityProviders fetches the key sets of the JWKS URLs and the discovery documents,
+// and then key sets, of the issuers defs reference, and reports those that can't be read
+// or hold no usable signing key
+func CheckIdentityProviders(defs []objects.DBApiDefinition, client *http.Client) []IdPUse {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	uses := IdPReferences(defs)
+	for i := range uses {
+		u := &uses[i]
+
+		jwks := u.URL
+		if u.Kind == IdPOIDC {
+			var err error
+			if jwks, err = discoverJWKS(client, u.URL); err != nil {
+				u.Error = err.Error()
+				continue
+			}
+		}
+
+		keys, err := fetchJWKS(client, jwks)
+		if err != nil {
+			u.Error = err.Error()
+			continue
+		}
+
+		u.Keys = usableKeys(keys, u.KeyType)
+		switch {
+		case len(keys) == 0:
+			u.Error = fmt.Sprintf("the key set of %v holds no keys", jwks)
+		case u.Keys == 0 && u.KeyType != "":
+			u.Error = fmt.Sprintf("the key set of %v holds %v keys, none a usable %v signing key", jwks, len(keys), u.KeyType)
+		case u.Keys == 0:
+			u.Error = fmt.Sprintf("the key set of %v holds %v keys, none a usable signing key", jwks, len(keys))
+		}
+	}
+
+	return uses
+}
+
+func getIdPJSON(client *http.Client, url string, obj interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %v: %v", url, resp.Status)
+	}
+
+	raw, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxIdPResponse))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, obj); err != nil {
+		return fmt.Errorf("GET %v: not JSON: %v", url, err)
+	}
+
+	return nil
+}
+
+// discoverJWKS reads the jwks_uri of the issuer from its discovery document, which must
+// name the same issuer as tokens are matched to the provider by their iss claim
+func discoverJWKS(client *http.Client, issuer string) (string, error) {
+	discovery := struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}{}
+	if err := getIdPJSON(client, strings.TrimSuffix(issuer, "/")+oidcDiscoveryPath, &discovery); err != nil {
+		return "", err
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return "", fmt.Errorf("the discovery document of %v names the issuer %q, tokens it issues won't match the provider", issuer, discovery.Issuer)
+	}
+	if discovery.JWKSURI == "" {
+		return "", fmt.Errorf("the discovery document of %v has no jwks_uri", issuer)
+	}
+
+	return discovery.JWKSURI, nil
+}
+
+// jwk holds the fields of a JSON Web Key telling whether it can verify signatures
+type jwk struct {
+	Kty string   `json:"kty"`
+	Use string   `json:"use"`
+	N   string   `json:"n"`
+	E   string   `json:"e"`
+	X   string   `json:"x"`
+	Y   string   `json:"y"`
+	K   string   `json:"k"`
+	X5C []string `json:"x5c"`
+}
+
+func fetchJWKS(client *http.Client, url string) ([]jwk, error) {
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	if err := getIdPJSON(client, url, &set); err != nil {
+		return nil, err
+	}
+
+	return set.Keys, nil
+}
+
+// usableKeys counts the signing keys of the type kty (any if empty) that carry their key
+// material
+func usableKeys(keys []jwk, kty string) int {
+	n := 0
+	for _, k := range keys {
+		if (k.Use != "" && k.Use != "sig") || (kty != "" && k.Kty != kty) {
+			continue
+		}
+
+		usable := len(k.X5C) > 0
+		switch k.Kty {
+		case "RSA":
+			usable = usable || (k.N != "" && k.E != "")
+		case "EC":
+			usable = usable || (k.X != "" && k.Y != "")
+		case "oct":
+			usable = k.K != ""
+		}
+		if usable {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,83 @@
+package tyk_vcs
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestCheckIdentityProviders(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jwks":
+			w.Write([]byte(`{"keys": [{"kty": "RSA", "use": "sig", "n": "0vx7", "e": "AQAB"}, {"kty": "RSA", "use": "enc", "n": "x", "e": "AQAB"}]}`))
+		case "/empty":
+			w.Write([]byte(`{"keys": []}`))
+		case "/ec":
+			w.Write([]byte(`{"keys": [{"kty": "EC", "x": "f83O", "y": "x_FE"}]}`))
+		case "/issuer" + oidcDiscoveryPath:
+			w.Write([]byte(`{"issuer": "` + ts.URL + `/issuer", "jwks_uri": "` + ts.URL + `/jwks"}`))
+		case "/moved" + oidcDiscoveryPath:
+			w.Write([]byte(`{"issuer": "https://elsewhere", "jwks_uri": "` + ts.URL + `/jwks"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	jwtAPI := func(id, source, method string) objects.DBApiDefinition {
+		return objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: id, Name: id, EnableJWT: true, JWTSource: source, JWTSigningMethod: method}}
+	}
+	oidcAPI := func(id string, issuers ...string) objects.DBApiDefinition {
+		def := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: id, Name: id, UseOpenID: true}}
+		for _, iss := range issuers {
+			def.OpenIDOptions.Providers = append(def.OpenIDOptions.Providers, apidef.OIDProviderConfig{Issuer: iss})
+		}
+		return def
+	}
+
+	defs := []objects.DBApiDefinition{
+		jwtAPI("a1", ts.URL+"/jwks", "rsa"),
+		jwtAPI("a2", base64.StdEncoding.EncodeToString([]byte(ts.URL+"/jwks")), "rsa"),
+		jwtAPI("a3", ts.URL+"/empty", "rsa"),
+		jwtAPI("a4", ts.URL+"/ec", "rsa"),
+		jwtAPI("a5", ts.URL+"/missing", ""),
+		// Embedded keys and redacted sources are not fetched
+		jwtAPI("a6", base64.StdEncoding.EncodeToString([]byte("-----BEGIN PUBLIC KEY-----")), "rsa"),
+		jwtAPI("a7", "${TYK_SECRET_A7_JWT_SOURCE}", "rsa"),
+		oidcAPI("o1", ts.URL+"/issuer/", ts.URL+"/moved"),
+	}
+
+	found := map[string]IdPUse{}
+	for _, u := range CheckIdentityProviders(defs, nil) {
+		found[u.Kind+" "+strings.TrimPrefix(u.URL, ts.URL)] = u
+	}
+	if len(found) != 6 {
+		t.Fatalf("expected 6 identity providers, got %v", found)
+	}
+
+	if u := found["jwks /jwks"]; u.Error != "" || u.Keys != 1 || len(u.APIs) != 2 {
+		t.Errorf("expected one usable key shared by two APIs, got %+v", u)
+	}
+	if u := found["jwks /empty"]; !strings.Contains(u.Error, "no keys") {
+		t.Errorf("expected an empty key set reported, got %+v", u)
+	}
+	if u := found["jwks /ec"]; u.Keys != 0 || !strings.Contains(u.Error, "RSA") {
+		t.Errorf("expected keys of the wrong type reported, got %+v", u)
+	}
+	if u := found["jwks /missing"]; !strings.Contains(u.Error, "404") {
+		t.Errorf("expected an unreachable key set reported, got %+v", u)
+	}
+	if u := found["oidc /issuer/"]; u.Error != "" || u.Keys != 1 {
+		t.Errorf("expected the keys of the issuer discovered, got %+v", u)
+	}
+	if u := found["oidc /moved"]; !strings.Contains(u.Error, "elsewhere") {
+		t.Errorf("expected an issuer mismatch reported, got %+v", u)
+	}
+}
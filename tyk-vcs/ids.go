@@ -0,0 +1,96 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// idSeedKey is the field of API definition and policy files deriving their object ID from a
+// readable seed when they have no ID, see objects.SeedID. Targets don't know it, it is
+// removed before the definition is decoded.
+const idSeedKey = "id_seed"
+
+// readObjectIDs reads the object ID fields, idKey, of a definition or policy file, as well
+// as those of a dumped api_definition. A missing, null, empty or zero ID is removed, so it is
+// neither decoded nor passed through as a real one, and the id_seed fields are taken out. It
+// returns the raw file without them and the seed, empty if none is set.
+func readObjectIDs(file string, raw []byte, idKey string) ([]byte, string, error) {
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		// Not an object, decoding the file reports it
+		return raw, "", nil
+	}
+
+	objs := []map[string]interface{}{doc}
+	if inner, ok := doc["api_definition"].(map[string]interface{}); ok {
+		objs = append(objs, inner)
+	}
+
+	seed := ""
+	changed := false
+	for _, obj := range objs {
+		if v, ok := obj[idSeedKey]; ok {
+			s, isString := v.(string)
+			if !isString {
+				return nil, "", fmt.Errorf("%v: %v must be a string", file, idSeedKey)
+			}
+			if s != "" {
+				seed = s
+			}
+			delete(obj, idSeedKey)
+			changed = true
+		}
+
+		v, ok := obj[idKey]
+		if !ok {
+			continue
+		}
+		id, isString := v.(string)
+		if v != nil && !isString {
+			return nil, "", fmt.Errorf("%v: %v must be a string", file, idKey)
+		}
+		if id != "" && !bson.IsObjectIdHex(id) {
+			return nil, "", fmt.Errorf("%v: %v %q is not an object ID of 24 hex characters, leave it empty or set %v to derive one", file, idKey, id, idSeedKey)
+		}
+		if id == "" || objects.EmptyID(bson.ObjectIdHex(id)) {
+			delete(obj, idKey)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return raw, seed, nil
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, "", err
+	}
+	return out, seed, nil
+}
+
+// applySpecID sets the object ID the spec entry of ad gives it with db_id. Without one, an
+// API that has no ID is seeded with the id_seed of the entry, or else with seed, the id_seed
+// of its file. A seeded API without an API ID gets one from the seed too: the dashboard gives
+// a created API an ID of its own and only keeps the API ID it is updated with.
+func applySpecID(ad *objects.DBApiDefinition, info APIInfo, seed string) error {
+	if info.DBID == "" {
+		if info.IDSeed != "" {
+			seed = info.IDSeed
+		}
+		ad.Id = objects.NormalID(ad.Id)
+		if ad.Id == "" && seed != "" {
+			ad.IDSeed = seed
+			ad.ApplySeed()
+		}
+		return nil
+	}
+	if !bson.IsObjectIdHex(info.DBID) {
+		return fmt.Errorf("%v: db_id %q is not an object ID of 24 hex characters, set %v for a readable one", info.File, info.DBID, idSeedKey)
+	}
+	ad.Id = objects.NormalID(bson.ObjectIdHex(info.DBID))
+	return nil
+}
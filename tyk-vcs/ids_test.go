@@ -0,0 +1,172 @@
+package tyk_vcs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/dashboard"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/testserver"
+)
+
+func TestFetchObjectIDs(t *testing.T) {
+	g := memGetter(t, map[string]string{
+		".tyk.json": `{
+			"type": "apidef",
+			"files": [{"file": "zero.json"}, {"file": "seeded.json"}, {"file": "empty.json"}, {"file": "spec.json", "id_seed": "spec-api"},
+				{"file": "explicit.json", "id_seed": "ignored"}],
+			"policies": [{"file": "pol-seeded.json"}, {"file": "pol-zero.json"}, {"file": "pol-explicit.json", "id_seed": "ignored"},
+				{"file": "pol-seeded.json", "id_seed": "platinum"}]
+		}`,
+		"zero.json":         `{"api_definition": {"id": "000000000000000000000000", "api_id": "zero", "name": "Zero"}}`,
+		"seeded.json":       `{"api_definition": {"id": "", "id_seed": "payments-api", "api_id": "seeded", "name": "Seeded"}}`,
+		"empty.json":        `{"id": null, "api_id": "empty", "name": "Empty"}`,
+		"spec.json":         `{"api_definition": {"api_id": "spec", "name": "Spec", "org_id": "org"}}`,
+		"explicit.json":     `{"api_definition": {"id": "5e9d9544a1dcd60001d0ed20", "api_id": "explicit", "name": "Explicit"}}`,
+		"pol-seeded.json":   `{"id_seed": "gold", "name": "Gold", "org_id": "org"}`,
+		"pol-zero.json":     `{"_id": "000000000000000000000000", "id": "silver", "name": "Silver", "org_id": "org"}`,
+		"pol-explicit.json": `{"_id": "5e9d9544a1dcd60001d0ed21", "name": "Bronze", "org_id": "org"}`,
+	})
+
+	spec, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := g.FetchAPIDef(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if defs[0].Id != "" || defs[2].Id != "" {
+		t.Errorf("zero and empty IDs should be unset, got %q and %q", defs[0].Id.Hex(), defs[2].Id.Hex())
+	}
+	if _, ok := defs[0].Passthrough["id"]; ok {
+		t.Error("the zero ID should not be passed through to the target")
+	}
+	if defs[1].Id != objects.SeedID("", "payments-api") {
+		t.Errorf("expected the ID derived from id_seed, got %q", defs[1].Id.Hex())
+	}
+	if _, ok := defs[1].Passthrough["id_seed"]; ok {
+		t.Error("id_seed should not be passed through to the target")
+	}
+	if defs[3].Id != objects.SeedID("org", "spec-api") {
+		t.Errorf("expected the ID derived from the id_seed of the spec, got %q", defs[3].Id.Hex())
+	}
+
+	if defs[4].Id.Hex() != "5e9d9544a1dcd60001d0ed20" {
+		t.Errorf("the id_seed of the spec should not replace the ID of the file, got %q", defs[4].Id.Hex())
+	}
+
+	pols, err := g.FetchPolicies(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pols[0].MID != objects.SeedID("org", "gold") {
+		t.Errorf("expected the policy _id derived from id_seed, got %q", pols[0].MID.Hex())
+	}
+	if pols[1].MID != "" || pols[1].ID != "silver" {
+		t.Errorf("the zero _id should be unset, got %q", pols[1].MID.Hex())
+	}
+	if pols[2].MID.Hex() != "5e9d9544a1dcd60001d0ed21" {
+		t.Errorf("the id_seed of the spec should not replace the _id of the file, got %q", pols[2].MID.Hex())
+	}
+	if pols[3].MID != objects.SeedID("org", "platinum") {
+		t.Errorf("the id_seed of the spec should win over the one of the file, got %q", pols[3].MID.Hex())
+	}
+}
+
+func TestSeededIDsFollowTheOrg(t *testing.T) {
+	g := memGetter(t, map[string]string{
+		".tyk.json":  `{"type": "apidef", "files": [{"file": "api.json", "id_seed": "payments-api"}, {"file": "named.json", "id_seed": "orders-api"}]}`,
+		"api.json":   `{"api_definition": {"name": "Payments", "org_id": "org1"}}`,
+		"named.json": `{"api_definition": {"api_id": "orders", "name": "Orders", "org_id": "org1"}}`,
+	})
+	spec, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := g.FetchAPIDef(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// --org publishes the repo to another org, which gets IDs of its own
+	if err := (Pipeline{OrgTransformer("org2")}).Run(defs); err != nil {
+		t.Fatal(err)
+	}
+	seeded := objects.SeedID("org2", "payments-api")
+	if defs[0].Id != seeded || defs[0].APIID != seeded.Hex() {
+		t.Errorf("expected the IDs seeded in org2, got %q and %q", defs[0].Id.Hex(), defs[0].APIID)
+	}
+	if defs[1].Id != objects.SeedID("org2", "orders-api") || defs[1].APIID != "orders" {
+		t.Errorf("expected the ID seeded in org2 and the API ID kept, got %q and %q", defs[1].Id.Hex(), defs[1].APIID)
+	}
+}
+
+func TestFetchInvalidObjectID(t *testing.T) {
+	for name, files := range map[string]map[string]string{
+		"file": {
+			".tyk.json": `{"type": "apidef", "files": [{"file": "api.json"}]}`,
+			"api.json":  `{"api_definition": {"id": "payments", "api_id": "payments"}}`,
+		},
+		"spec": {
+			".tyk.json": `{"type": "apidef", "files": [{"file": "api.json", "db_id": "payments"}]}`,
+			"api.json":  `{"api_definition": {"api_id": "payments"}}`,
+		},
+	} {
+		g := memGetter(t, files)
+		spec, err := g.FetchTykSpec()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = g.FetchAPIDef(spec)
+		if err == nil || !strings.Contains(err.Error(), "id_seed") {
+			t.Errorf("%v: expected an error pointing to id_seed, got %v", name, err)
+		}
+	}
+}
+
+func TestSeededAPISyncsInPlace(t *testing.T) {
+	g := memGetter(t, map[string]string{
+		".tyk.json": `{"type": "apidef", "files": [{"file": "api.json", "id_seed": "payments-api"}]}`,
+		"api.json": `{"api_definition": {"name": "Payments", "org_id": "` + testserver.DefaultOrgID + `", "active": true,
+			"proxy": {"listen_path": "/payments/"}, "version_data": {"not_versioned": true, "versions": {"Default": {"name": "Default"}}}}}`,
+	})
+	spec, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := g.FetchAPIDef(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defs[0].APIID != objects.SeedID(testserver.DefaultOrgID, "payments-api").Hex() {
+		t.Fatalf("expected the API ID derived from id_seed, got %q", defs[0].APIID)
+	}
+
+	s := testserver.New()
+	defer s.Close()
+	c, err := dashboard.NewDashboardClient(s.URL, testserver.DefaultSecret, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Sync(defs); err != nil {
+		t.Fatal(err)
+	}
+
+	// The dashboard doesn't keep the seeded database ID, the second sync matches the API ID
+	var planned *objects.SyncPlan
+	c.SetPlanCheck(func(plan *objects.SyncPlan) error {
+		planned = plan
+		return nil
+	})
+	if err := c.Sync(defs); err != nil {
+		t.Fatal(err)
+	}
+	if len(planned.Update) != 1 || len(planned.Create) != 0 || len(planned.Delete) != 0 {
+		t.Errorf("expected the second sync to update the API, got %+v", planned)
+	}
+	if apis := s.APIs(); len(apis) != 1 || apis[0].APIID != defs[0].APIID {
+		t.Errorf("expected the API to keep the seeded API ID, got %v APIs", len(apis))
+	}
+}
@@ -0,0 +1,206 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+// SpecFile is the name of the spec file at the root of a repo or directory
+const SpecFile = ".tyk.json"
+
+// rebase makes the file names of the spec file in dir relative to the root of the repo
+func rebase(spec *TykSourceSpec, dir string) {
+	if dir == "." || dir == "" {
+		return
+	}
+
+	join := func(names []string) []string {
+		out := make([]string, len(names))
+		for i, n := range names {
+			out[i] = path.Join(dir, specPath(n))
+		}
+		return out
+	}
+
+	for i := range spec.Files {
+		spec.Files[i].File = path.Join(dir, specPath(spec.Files[i].File))
+		spec.Files[i].Exclude = join(spec.Files[i].Exclude)
+	}
+	for i := range spec.Policies {
+		spec.Policies[i].File = path.Join(dir, specPath(spec.Policies[i].File))
+		spec.Policies[i].Exclude = join(spec.Policies[i].Exclude)
+	}
+	for i := range spec.Keys {
+		spec.Keys[i].File = path.Join(dir, specPath(spec.Keys[i].File))
+	}
+	for i := range spec.OAuthClients {
+		spec.OAuthClients[i].File = path.Join(dir, specPath(spec.OAuthClients[i].File))
+	}
+	for i := range spec.Certificates {
+		spec.Certificates[i].File = path.Join(dir, specPath(spec.Certificates[i].File))
+	}
+	for i := range spec.ContractTests {
+		spec.ContractTests[i].File = path.Join(dir, specPath(spec.ContractTests[i].File))
+	}
+	for i := range spec.Tenants {
+		spec.Tenants[i].Path = path.Join(dir, specPath(spec.Tenants[i].Path))
+	}
+	for name, p := range spec.Profiles {
+		p.EncryptTo = join(p.EncryptTo)
+		spec.Profiles[name] = p
+	}
+}
+
+// mergeSpec merges the spec over into base: over sets the type if base has none, and
+// replaces the profiles and strip profiles of the same name, the lists of both are
+// concatenated, those of base first
+func mergeSpec(base, over *TykSourceSpec) error {
+	if over.Type != "" {
+		if base.Type != "" && base.Type != over.Type {
+			return fmt.Errorf("type '%v' conflicts with type '%v'", over.Type, base.Type)
+		}
+		base.Type = over.Type
+	}
+
+	base.Files = append(base.Files, over.Files...)
+	base.Policies = append(base.Policies, over.Policies...)
+	base.Keys = append(base.Keys, over.Keys...)
+	base.OAuthClients = append(base.OAuthClients, over.OAuthClients...)
+	base.Certificates = append(base.Certificates, over.Certificates...)
+	base.ContractTests = append(base.ContractTests, over.ContractTests...)
+	base.Products = append(base.Products, over.Products...)
+	base.Tenants = append(base.Tenants, over.Tenants...)
+	base.Windows = append(base.Windows, over.Windows...)
+	base.Ignore = append(base.Ignore, over.Ignore...)
+	base.DataPlanes = append(base.DataPlanes, over.DataPlanes...)
+
+	for name, p := range over.Profiles {
+		if base.Profiles == nil {
+			base.Profiles = map[string]TargetProfile{}
+		}
+		base.Profiles[name] = p
+	}
+	for name, fields := range over.StripProfiles {
+		if base.StripProfiles == nil {
+			base.StripProfiles = map[string][]string{}
+		}
+		base.StripProfiles[name] = fields
+	}
+
+	if base.Repo == "" {
+		base.Repo = over.Repo
+	} else if over.Repo != "" && over.Repo != base.Repo {
+		return fmt.Errorf("repo '%v' conflicts with repo '%v'", over.Repo, base.Repo)
+	}
+
+	base.Requires = mergeRequires(base.Requires, over.Requires)
+
+	if over.Guardrails != nil {
+		if base.Guardrails == nil {
+			base.Guardrails = &GuardrailsInfo{}
+		}
+		// The strictest of the rules apply
+		if over.Guardrails.MaxRate > 0 && (base.Guardrails.MaxRate == 0 || over.Guardrails.MaxRate < base.Guardrails.MaxRate) {
+			base.Guardrails.MaxRate = over.Guardrails.MaxRate
+		}
+		base.Guardrails.RequireQuota = base.Guardrails.RequireQuota || over.Guardrails.RequireQuota
+		base.Guardrails.ProductionTags = append(base.Guardrails.ProductionTags, over.Guardrails.ProductionTags...)
+	}
+
+	if over.Protect != nil {
+		if base.Protect == nil {
+			base.Protect = &ProtectInfo{}
+		}
+		base.Protect.APIs = append(base.Protect.APIs, over.Protect.APIs...)
+		base.Protect.Tags = append(base.Protect.Tags, over.Protect.Tags...)
+	}
+
+	return nil
+}
+
+// specLoader reads a spec file with the spec files it includes, every file may only be read
+// once, which rules out cycles
+type specLoader struct {
+	fs     billy.Filesystem
+	loaded map[string]bool
+	order  []string
+}
+
+// loadSpec reads the spec file name. The files it includes are merged in their order, and
+// the spec itself over them, see mergeSpec. The file names of every spec file are relative
+// to its directory, those of the merged spec to the root of the repo.
+func (l *specLoader) loadSpec(name string) (*TykSourceSpec, error) {
+	name = specPath(name)
+	if l.loaded[name] {
+		return nil, fmt.Errorf("spec file %v is included more than once", name)
+	}
+	l.loaded[name] = true
+	l.order = append(l.order, name)
+
+	raw, err := readTextFile(l.fs, name)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &TykSourceSpec{}
+	if err := json.Unmarshal(raw, spec); err != nil {
+		return nil, fmt.Errorf("%v: %v", name, err)
+	}
+
+	includes, err := l.includes(path.Dir(name), spec.Include)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", name, err)
+	}
+
+	merged := &TykSourceSpec{}
+	for _, inc := range includes {
+		sub, err := l.loadSpec(inc)
+		if err != nil {
+			return nil, err
+		}
+		if err := mergeSpec(merged, sub); err != nil {
+			return nil, fmt.Errorf("%v: including %v: %v", name, inc, err)
+		}
+	}
+
+	spec.Include = nil
+	rebase(spec, path.Dir(name))
+	if err := mergeSpec(merged, spec); err != nil {
+		return nil, fmt.Errorf("%v: %v", name, err)
+	}
+
+	return merged, nil
+}
+
+// includes resolves the include list of the spec file in dir, patterns are expanded to the
+// files they match, in the order of their names
+func (l *specLoader) includes(dir string, include []string) ([]string, error) {
+	files := []string{}
+	var all []string
+	for _, inc := range include {
+		name := path.Join(dir, specPath(inc))
+		if !IsGlob(inc) {
+			files = append(files, name)
+			continue
+		}
+
+		if all == nil {
+			var err error
+			if all, err = walkFiles(l.fs, ""); err != nil {
+				return nil, err
+			}
+			sort.Strings(all)
+		}
+		for _, f := range all {
+			if MatchGlob(name, f) && !l.loaded[f] {
+				files = append(files, f)
+			}
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,104 @@
+package tyk_vcs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIncludeSpecs(t *testing.T) {
+	g := memGetter(t, map[string]string{
+		".tyk.json": `{
+			"include": ["shared/org.json", "teams/*/.tyk.json"],
+			"files": [{"file": "root.json"}],
+			"profiles": {"prod": {"tags": ["edge"]}},
+			"protect": {"tags": ["manual"]},
+			"guardrails": {"max_rate": 50, "production_tags": ["prod"]}
+		}`,
+		"shared/org.json": `{
+			"type": "apidef",
+			"repo": "payments-apis",
+			"profiles": {"prod": {"tags": ["shared"]}, "staging": {"tags": ["stg"], "encrypt_to": ["keys/ops.asc"]}},
+			"protect": {"apis": ["portal"]},
+			"guardrails": {"max_rate": 20, "require_quota": true},
+			"ignore": ["active"]
+		}`,
+		"teams/a/.tyk.json":   `{"files": [{"file": "api.json"}, {"file": "apis/*.json"}], "policies": [{"file": "pol.json"}]}`,
+		"teams/a/apis/x.json": `{}`,
+		"teams/b/.tyk.json":   `{"include": ["../../shared/extra.json"], "files": [{"file": "./api.json"}]}`,
+		"shared/extra.json":   `{"ignore": ["tags[]"]}`,
+	})
+
+	spec, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Type != TYPE_APIDEF {
+		t.Errorf("expected the included type, got %q", spec.Type)
+	}
+
+	files := []string{}
+	for _, f := range spec.Files {
+		files = append(files, f.File)
+	}
+	if expected := []string{"teams/a/api.json", "teams/a/apis/x.json", "teams/b/api.json", "root.json"}; !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected files %v, got %v", expected, files)
+	}
+	if len(spec.Policies) != 1 || spec.Policies[0].File != "teams/a/pol.json" {
+		t.Errorf("unexpected policies: %v", spec.Policies)
+	}
+
+	if tags := spec.Profiles["prod"].Tags; !reflect.DeepEqual(tags, []string{"edge"}) {
+		t.Errorf("the profile of the including spec should win, got tags %v", tags)
+	}
+	if enc := spec.Profiles["staging"].EncryptTo; !reflect.DeepEqual(enc, []string{"shared/keys/ops.asc"}) {
+		t.Errorf("unexpected staging keys: %v", enc)
+	}
+	if !reflect.DeepEqual(spec.Protect, &ProtectInfo{APIs: []string{"portal"}, Tags: []string{"manual"}}) {
+		t.Errorf("unexpected protect: %+v", spec.Protect)
+	}
+	if !reflect.DeepEqual(spec.Guardrails, &GuardrailsInfo{MaxRate: 20, RequireQuota: true, ProductionTags: []string{"prod"}}) {
+		t.Errorf("the strictest guardrails should apply, got %+v", spec.Guardrails)
+	}
+	if !reflect.DeepEqual(spec.Ignore, []string{"active", "tags[]"}) {
+		t.Errorf("unexpected ignore rules: %v", spec.Ignore)
+	}
+	if spec.Repo != "payments-apis" {
+		t.Errorf("expected the included repo, got %q", spec.Repo)
+	}
+	if spec.Include != nil {
+		t.Errorf("the includes should be resolved, got %v", spec.Include)
+	}
+}
+
+func TestIncludeSpecsErrors(t *testing.T) {
+	cases := map[string]map[string]string{
+		"cycle": {
+			".tyk.json": `{"include": ["a.json"]}`,
+			"a.json":    `{"include": [".tyk.json"]}`,
+		},
+		"twice": {
+			".tyk.json": `{"include": ["a.json", "b.json"]}`,
+			"a.json":    `{"include": ["c.json"]}`,
+			"b.json":    `{"include": ["c.json"]}`,
+			"c.json":    `{}`,
+		},
+		"missing": {
+			".tyk.json": `{"include": ["a.json"]}`,
+		},
+		"type conflict": {
+			".tyk.json": `{"type": "oas", "include": ["a.json"]}`,
+			"a.json":    `{"type": "apidef"}`,
+		},
+		"repo conflict": {
+			".tyk.json": `{"repo": "payments", "include": ["a.json"]}`,
+			"a.json":    `{"repo": "billing"}`,
+		},
+	}
+
+	for name, files := range cases {
+		if _, err := memGetter(t, files).FetchTykSpec(); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IndexFile is written next to the spec file by a dump
+const IndexFile = ".tyk-index.json"
+
+const (
+	FileNamesByID   = "id"
+	FileNamesByName = "name"
+)
+
+// IndexEntry maps the ID of a dumped object to its file and human readable name
+type IndexEntry struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	File string `json:"file"`
+}
+
+// Index lists the files of a dump, the next dump into the same directory reads it to keep
+// the file of each object where it was, so renaming an API doesn't move its file in git
+type Index struct {
+	APIs     []IndexEntry `json:"apis,omitempty"`
+	Policies []IndexEntry `json:"policies,omitempty"`
+}
+
+// ReadIndex reads the index of a previous dump in dir, an empty index is returned if there
+// isn't one
+func ReadIndex(dir string) (*Index, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(dir, IndexFile))
+	if os.IsNotExist(err) {
+		return &Index{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ix := &Index{}
+	if err := json.Unmarshal(normaliseText(raw), ix); err != nil {
+		return nil, fmt.Errorf("%v: %v", IndexFile, err)
+	}
+
+	return ix, nil
+}
+
+// Write writes the index into dir
+func (ix *Index) Write(dir string) error {
+	j, err := json.MarshalIndent(ix, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, IndexFile), j, 0644)
+}
+
+// FileNamer picks the file names of a dump and records them in a new index. Objects found in
+// the previous index keep their file, new ones are named after their ID or, with
+// FileNamesByName, after their name.
+type FileNamer struct {
+	prev  map[string]string
+	used  map[string]bool
+	mode  string
+	index *Index
+}
+
+func NewFileNamer(prev *Index, mode string) (*FileNamer, error) {
+	if mode == "" {
+		mode = FileNamesByID
+	}
+	if mode != FileNamesByID && mode != FileNamesByName {
+		return nil, fmt.Errorf("unknown file naming %q, must be id or name", mode)
+	}
+
+	n := &FileNamer{
+		prev:  map[string]string{},
+		used:  map[string]bool{},
+		mode:  mode,
+		index: &Index{},
+	}
+
+	if prev != nil {
+		for _, e := range prev.APIs {
+			if err := checkDumpFile(e.File); err != nil {
+				return nil, fmt.Errorf("%v: %v", IndexFile, err)
+			}
+			n.prev["api/"+e.ID] = e.File
+		}
+		for _, e := range prev.Policies {
+			if err := checkDumpFile(e.File); err != nil {
+				return nil, fmt.Errorf("%v: %v", IndexFile, err)
+			}
+			n.prev["policy/"+e.ID] = e.File
+		}
+	}
+
+	return n, nil
+}
+
+// checkDumpFile refuses a file listed by an index or dump state that isn't a plain file name,
+// one edited to point at e.g. ../../.bashrc must not make a dump write or remove files outside
+// of its directory
+func checkDumpFile(fname string) error {
+	if fname == "" || fname == "." || fname == ".." || strings.ContainsAny(fname, "/\\:") {
+		return fmt.Errorf("%q is not a file name of the dump directory", fname)
+	}
+
+	return nil
+}
+
+// APIFile returns the file name for an API and adds it to the index
+func (n *FileNamer) APIFile(id, name string) string {
+	fname := n.pick("api", id, name)
+	n.index.APIs = append(n.index.APIs, IndexEntry{ID: id, Name: name, File: fname})
+	return fname
+}
+
+// PolicyFile returns the file name for a policy and adds it to the index
+func (n *FileNamer) PolicyFile(id, name string) string {
+	fname := n.pick("policy", id, name)
+	n.index.Policies = append(n.index.Policies, IndexEntry{ID: id, Name: name, File: fname})
+	return fname
+}
+
+// Index returns the index of the files named so far
+func (n *FileNamer) Index() *Index {
+	return n.index
+}
+
+func (n *FileNamer) pick(kind, id, name string) string {
+	if fname, ok := n.prev[kind+"/"+id]; ok && !n.used[strings.ToLower(fname)] {
+		n.used[strings.ToLower(fname)] = true
+		return fname
+	}
+
+	base := id
+	if n.mode == FileNamesByName && name != "" {
+		base = strings.ToLower(name)
+	}
+	base = kind + "-" + safeName(base)
+
+	// Names may clash, also once made safe or with files kept from the previous dump.
+	// Compared without case as the file systems of Windows and macOS are not case sensitive.
+	fname := base + ".json"
+	for i := 2; n.used[strings.ToLower(fname)] || n.takenByPrev(fname); i++ {
+		fname = fmt.Sprintf("%v-%v.json", base, i)
+	}
+
+	n.used[strings.ToLower(fname)] = true
+	return fname
+}
+
+// takenByPrev tells whether the previous dump used fname for another object, which may still
+// be dumped later
+func (n *FileNamer) takenByPrev(fname string) bool {
+	for _, f := range n.prev {
+		if strings.EqualFold(f, fname) {
+			return true
+		}
+	}
+
+	return false
+}
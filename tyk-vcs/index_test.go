@@ -0,0 +1,83 @@
+package tyk_vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestFileNamerKeepsFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tyk-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	first, err := NewFileNamer(nil, FileNamesByName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := first.APIFile("1", "Users API"); got != "api-users_api.json" {
+		t.Fatalf("got %v", got)
+	}
+	if got := first.APIFile("2", "users api"); got != "api-users_api-2.json" {
+		t.Fatalf("clashing name got %v", got)
+	}
+	if got := first.PolicyFile("5c0f", ""); got != "policy-5c0f.json" {
+		t.Fatalf("policy without name got %v", got)
+	}
+	if err := first.Index().Write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	prev, err := ReadIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// API 1 was renamed, a new API took its old name
+	second, err := NewFileNamer(prev, FileNamesByName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := second.APIFile("3", "Users API"); got != "api-users_api-3.json" {
+		t.Fatalf("new API got %v", got)
+	}
+	if got := second.APIFile("1", "Customers API"); got != "api-users_api.json" {
+		t.Fatalf("renamed API got %v", got)
+	}
+	if got := second.Index().APIs[1]; got.Name != "Customers API" {
+		t.Fatalf("index not updated: %+v", got)
+	}
+
+	// Switching to IDs only affects new objects
+	third, err := NewFileNamer(second.Index(), FileNamesByID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := third.APIFile("1", "Customers API"); got != "api-users_api.json" {
+		t.Fatalf("got %v", got)
+	}
+	if got := third.APIFile("4", "Orders"); got != "api-4.json" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestReadIndexMissing(t *testing.T) {
+	ix, err := ReadIndex(os.TempDir() + "/tyk-index-does-not-exist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ix.APIs) != 0 || len(ix.Policies) != 0 {
+		t.Fatalf("expected an empty index: %+v", ix)
+	}
+}
+
+func TestFileNamerRefusesPaths(t *testing.T) {
+	for _, fname := range []string{"../api-1.json", "apis/api-1.json", "..\\api-1.json", "/etc/passwd", "c:api.json", "..", ""} {
+		prev := &Index{APIs: []IndexEntry{{ID: "1", File: fname}}}
+		if _, err := NewFileNamer(prev, FileNamesByID); err == nil {
+			t.Errorf("expected %q to be refused", fname)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package tyk_vcs
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultLeaseTTL is how long a leader holds its lease without renewing it
+const DefaultLeaseTTL = 15 * time.Second
+
+// LeaseState is what a holder learns when taking or renewing a lease
+type LeaseState struct {
+	// Held is set if the holder has the lease
+	Held bool
+	// Requests counts the syncs requested by standby instances, see RequestSync
+	Requests int64
+}
+
+// LeaderLease is a lock with an expiry that instances of a daemon race for, so that one of
+// them, the leader, applies changes while the others stand by
+type LeaderLease interface {
+	// Acquire takes the lease for holder if it is free or expired, or renews it if holder
+	// has it, for ttl
+	Acquire(holder string, ttl time.Duration) (LeaseState, error)
+	// Release frees the lease if holder has it, so another instance takes over at once
+	Release(holder string) error
+	// RequestSync asks the leader to sync, for webhooks received by a standby instance
+	RequestSync() error
+}
+
+// LeaderElector keeps racing for a lease: it renews it while it leads and tries to take it
+// over while it stands by, both every third of the TTL
+type LeaderElector struct {
+	Lease LeaderLease
+	// Holder identifies this instance, the hostname by default (the pod name in Kubernetes)
+	Holder string
+	// TTL is DefaultLeaseTTL if zero
+	TTL time.Duration
+	// OnChange, if set, is told when this instance becomes the leader or stops being it
+	OnChange func(leader bool)
+	// OnError, if set, is told the errors reaching the lease, which lose the leadership
+	OnError func(err error)
+
+	mu       sync.Mutex
+	leader   bool
+	requests int64
+}
+
+func (e *LeaderElector) holder() string {
+	if e.Holder == "" {
+		e.Holder, _ = os.Hostname()
+	}
+	return e.Holder
+}
+
+func (e *LeaderElector) ttl() time.Duration {
+	if e.TTL <= 0 {
+		return DefaultLeaseTTL
+	}
+	return e.TTL
+}
+
+// IsLeader tells whether this instance held the lease when it last tried to take or renew it
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// elect tries to take or renew the lease once. It returns whether leadership changed, and
+// whether syncs were requested since the last call while leading.
+func (e *LeaderElector) elect() (changed, requested bool, err error) {
+	state, err := e.Lease.Acquire(e.holder(), e.ttl())
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// An instance that can't reach the lease must assume another one took it
+	held := err == nil && state.Held
+	changed = held != e.leader
+	requested = held && !changed && state.Requests != e.requests
+	e.leader = held
+	if err == nil {
+		e.requests = state.Requests
+	}
+
+	return changed, requested, err
+}
+
+// Run races for the lease until ctx is done, then releases it if it is held. sync is called
+// when this instance becomes the leader, and while it leads when standbys requested a sync.
+func (e *LeaderElector) Run(ctx context.Context, sync func()) {
+	ticker := time.NewTicker(e.ttl() / 3)
+	defer ticker.Stop()
+
+	for {
+		changed, requested, err := e.elect()
+		if err != nil && e.OnError != nil {
+			e.OnError(err)
+		}
+		if changed && e.OnChange != nil {
+			e.OnChange(e.IsLeader())
+		}
+		if (changed && e.IsLeader()) || requested {
+			sync()
+		}
+
+		select {
+		case <-ctx.Done():
+			if err := e.release(); err != nil && e.OnError != nil {
+				e.OnError(err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *LeaderElector) release() error {
+	e.mu.Lock()
+	leader := e.leader
+	e.leader = false
+	e.mu.Unlock()
+
+	if !leader {
+		return nil
+	}
+	return e.Lease.Release(e.holder())
+}
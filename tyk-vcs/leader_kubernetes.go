@@ -0,0 +1,286 @@
+package tyk_vcs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	// requestsAnnotation counts the syncs standbys requested on a Kubernetes lease
+	requestsAnnotation = "tyk-sync/sync-requests"
+	// microTime is the format of the times of a Lease
+	microTime = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// ErrLeaseConflict is returned when the lease changed while it was being written
+var ErrLeaseConflict = errors.New("the lease was changed by another instance")
+
+// KubernetesLease is a coordination.k8s.io/v1 Lease, the lock Kubernetes controllers elect
+// their leader with. The service account of the pods needs get, create and update on leases.
+type KubernetesLease struct {
+	Namespace string
+	Name      string
+	// APIServer is the URL of the API server
+	APIServer string
+	// Token is the bearer token of the service account
+	Token  string
+	Client *http.Client
+}
+
+// NewKubernetesLease returns the lease name in the namespace of the cluster the process runs
+// in, with the service account of its pod. An empty namespace is the namespace of the pod.
+func NewKubernetesLease(namespace, name string) (*KubernetesLease, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes cluster, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set")
+	}
+
+	token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in %v/ca.crt", serviceAccountDir)
+	}
+
+	if namespace == "" {
+		ns, err := ioutil.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, err
+		}
+		namespace = strings.TrimSpace(string(ns))
+	}
+
+	return &KubernetesLease{
+		Namespace: namespace,
+		Name:      name,
+		APIServer: "https://" + net.JoinHostPort(host, port),
+		Token:     strings.TrimSpace(string(token)),
+		Client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+	}, nil
+}
+
+type leaseMeta struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions,omitempty"`
+}
+
+type k8sLease struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Metadata   leaseMeta `json:"metadata"`
+	Spec       leaseSpec `json:"spec"`
+}
+
+func (l *k8sLease) requests() int64 {
+	n, _ := strconv.ParseInt(l.Metadata.Annotations[requestsAnnotation], 10, 64)
+	return n
+}
+
+// expired tells whether the holder of the lease failed to renew it in time
+func (l *k8sLease) expired(now time.Time) bool {
+	if l.Spec.HolderIdentity == "" {
+		return true
+	}
+
+	renewed, err := time.Parse(microTime, l.Spec.RenewTime)
+	if err != nil {
+		return true
+	}
+	return now.After(renewed.Add(time.Duration(l.Spec.LeaseDurationSeconds) * time.Second))
+}
+
+// leaseSeconds rounds a TTL up to whole seconds
+func leaseSeconds(ttl time.Duration) int {
+	return int((ttl + time.Second - 1) / time.Second)
+}
+
+func (k *KubernetesLease) url(name string) string {
+	u := fmt.Sprintf("%v/apis/coordination.k8s.io/v1/namespaces/%v/leases", strings.TrimSuffix(k.APIServer, "/"), k.Namespace)
+	if name != "" {
+		u += "/" + name
+	}
+	return u
+}
+
+// do sends a request to the API server, a nil lease is returned if it doesn't exist
+func (k *KubernetesLease) do(method, url string, body *k8sLease) (*k8sLease, error) {
+	var reqBody []byte
+	if body != nil {
+		var err error
+		if reqBody, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if k.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.Token)
+	}
+
+	client := k.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound && method == http.MethodGet:
+		return nil, nil
+	case resp.StatusCode == http.StatusConflict:
+		return nil, ErrLeaseConflict
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("%v %v returned %v: %s", method, url, resp.StatusCode, raw)
+	}
+
+	lease := &k8sLease{}
+	if err := json.Unmarshal(raw, lease); err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+func (k *KubernetesLease) get() (*k8sLease, error) {
+	return k.do(http.MethodGet, k.url(k.Name), nil)
+}
+
+func (k *KubernetesLease) update(lease *k8sLease) (*k8sLease, error) {
+	return k.do(http.MethodPut, k.url(k.Name), lease)
+}
+
+func (k *KubernetesLease) Acquire(holder string, ttl time.Duration) (LeaseState, error) {
+	// Writes conflict when standbys request syncs, the lease is read again then
+	for attempt := 0; ; attempt++ {
+		state, err := k.acquire(holder, ttl)
+		if err != ErrLeaseConflict || attempt == 4 {
+			return state, err
+		}
+	}
+}
+
+func (k *KubernetesLease) acquire(holder string, ttl time.Duration) (LeaseState, error) {
+	now := time.Now()
+	lease, err := k.get()
+	if err != nil {
+		return LeaseState{}, err
+	}
+
+	if lease == nil {
+		lease = &k8sLease{
+			APIVersion: "coordination.k8s.io/v1",
+			Kind:       "Lease",
+			Metadata:   leaseMeta{Name: k.Name, Namespace: k.Namespace},
+			Spec: leaseSpec{
+				HolderIdentity:       holder,
+				LeaseDurationSeconds: leaseSeconds(ttl),
+				AcquireTime:          now.Format(microTime),
+				RenewTime:            now.Format(microTime),
+			},
+		}
+
+		if _, err := k.do(http.MethodPost, k.url(""), lease); err != nil {
+			// A conflict if another instance created it first
+			return LeaseState{}, err
+		}
+		return LeaseState{Held: true}, nil
+	}
+
+	state := LeaseState{Requests: lease.requests()}
+	if lease.Spec.HolderIdentity != holder {
+		if !lease.expired(now) {
+			return state, nil
+		}
+		lease.Spec.HolderIdentity = holder
+		lease.Spec.AcquireTime = now.Format(microTime)
+		lease.Spec.LeaseTransitions++
+	}
+	lease.Spec.LeaseDurationSeconds = leaseSeconds(ttl)
+	lease.Spec.RenewTime = now.Format(microTime)
+
+	if _, err := k.update(lease); err != nil {
+		return state, err
+	}
+
+	state.Held = true
+	return state, nil
+}
+
+func (k *KubernetesLease) Release(holder string) error {
+	lease, err := k.get()
+	if err != nil || lease == nil || lease.Spec.HolderIdentity != holder {
+		return err
+	}
+
+	lease.Spec.HolderIdentity = ""
+	_, err = k.update(lease)
+	return err
+}
+
+func (k *KubernetesLease) RequestSync() error {
+	for attempt := 0; attempt < 5; attempt++ {
+		lease, err := k.get()
+		if err != nil {
+			return err
+		}
+		if lease == nil {
+			return errors.New("no leader holds the lease yet")
+		}
+
+		if lease.Metadata.Annotations == nil {
+			lease.Metadata.Annotations = map[string]string{}
+		}
+		lease.Metadata.Annotations[requestsAnnotation] = strconv.FormatInt(lease.requests()+1, 10)
+
+		if _, err := k.update(lease); err != ErrLeaseConflict {
+			return err
+		}
+	}
+
+	return ErrLeaseConflict
+}
@@ -0,0 +1,196 @@
+package tyk_vcs
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// acquireScript takes the lease KEYS[1] for ARGV[1] for ARGV[2] milliseconds if it is free
+// or held by ARGV[1], and returns whether it did and the syncs requested in KEYS[2]
+const acquireScript = `local h = redis.call("get", KEYS[1])
+local n = tonumber(redis.call("get", KEYS[2]) or "0")
+if h == false or h == ARGV[1] then
+  redis.call("set", KEYS[1], ARGV[1], "PX", ARGV[2])
+  return {1, n}
+end
+return {0, n}`
+
+// releaseScript deletes the lease KEYS[1] if ARGV[1] holds it
+const releaseScript = `if redis.call("get", KEYS[1]) == ARGV[1] then
+  return redis.call("del", KEYS[1])
+end
+return 0`
+
+// RedisLease is a lease stored in a Redis key, which expires unless the holder renews it.
+// Each call opens a connection, so the lease survives Redis restarts and failovers.
+type RedisLease struct {
+	// Addr is the host:port of Redis
+	Addr     string
+	Password string
+	DB       int
+	TLS      bool
+	// Key holds the holder of the lease, Key:sync-requests counts the syncs standbys requested
+	Key     string
+	Timeout time.Duration
+}
+
+// NewRedisLease returns the lease key of the Redis at rawURL, redis://[:password@]host[:port][/db]
+// or rediss:// for TLS
+func NewRedisLease(rawURL, key string) (*RedisLease, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("unknown Redis URL scheme %q, must be redis or rediss", u.Scheme)
+	}
+
+	l := &RedisLease{Addr: u.Host, TLS: u.Scheme == "rediss", Key: key, Timeout: 5 * time.Second}
+	if u.Port() == "" {
+		l.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		l.Password, _ = u.User.Password()
+	}
+
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if l.DB, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", db)
+		}
+	}
+
+	return l, nil
+}
+
+func writeCommand(w *bufio.Writer, args ...string) error {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(a), a)
+	}
+	return w.Flush()
+}
+
+// readReply reads a RESP reply: a string, an int64, nil or a []interface{} of them
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty Redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis: %v", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("unexpected Redis reply %q", line)
+}
+
+// do runs a command on a new connection, authenticated and on the database of the lease
+func (l *RedisLease) do(args ...string) (interface{}, error) {
+	dialer := &net.Dialer{Timeout: l.Timeout}
+	var conn net.Conn
+	var err error
+	if l.TLS {
+		host, _, _ := net.SplitHostPort(l.Addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", l.Addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", l.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if l.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(l.Timeout))
+	}
+
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+	commands := [][]string{}
+	if l.Password != "" {
+		commands = append(commands, []string{"AUTH", l.Password})
+	}
+	if l.DB != 0 {
+		commands = append(commands, []string{"SELECT", strconv.Itoa(l.DB)})
+	}
+	commands = append(commands, args)
+
+	var reply interface{}
+	for _, c := range commands {
+		if err := writeCommand(w, c...); err != nil {
+			return nil, err
+		}
+		if reply, err = readReply(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return reply, nil
+}
+
+func (l *RedisLease) requestsKey() string {
+	return l.Key + ":sync-requests"
+}
+
+func (l *RedisLease) Acquire(holder string, ttl time.Duration) (LeaseState, error) {
+	reply, err := l.do("EVAL", acquireScript, "2", l.Key, l.requestsKey(), holder, strconv.FormatInt(int64(ttl/time.Millisecond), 10))
+	if err != nil {
+		return LeaseState{}, err
+	}
+
+	items, ok := reply.([]interface{})
+	if !ok || len(items) != 2 {
+		return LeaseState{}, fmt.Errorf("unexpected reply %v to taking the lease", reply)
+	}
+	held, _ := items[0].(int64)
+	requests, _ := items[1].(int64)
+
+	return LeaseState{Held: held == 1, Requests: requests}, nil
+}
+
+func (l *RedisLease) Release(holder string) error {
+	_, err := l.do("EVAL", releaseScript, "1", l.Key, holder)
+	return err
+}
+
+func (l *RedisLease) RequestSync() error {
+	_, err := l.do("INCR", l.requestsKey())
+	return err
+}
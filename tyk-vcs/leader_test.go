@@ -0,0 +1,342 @@
+package tyk_vcs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memLease is a lease without expiry
+type memLease struct {
+	mu       sync.Mutex
+	holder   string
+	requests int64
+}
+
+func (m *memLease) Acquire(holder string, ttl time.Duration) (LeaseState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.holder == "" {
+		m.holder = holder
+	}
+	return LeaseState{Held: m.holder == holder, Requests: m.requests}, nil
+}
+
+func (m *memLease) Release(holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.holder == holder {
+		m.holder = ""
+	}
+	return nil
+}
+
+func (m *memLease) RequestSync() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests++
+	return nil
+}
+
+func TestLeaderElection(t *testing.T) {
+	lease := &memLease{}
+	var syncs int32
+	newDaemon := func(holder string) *Daemon {
+		return &Daemon{
+			Branch:  "refs/heads/master",
+			Elector: &LeaderElector{Lease: lease, Holder: holder},
+			Sync: func(ctx context.Context) error {
+				atomic.AddInt32(&syncs, 1)
+				return nil
+			},
+		}
+	}
+	a, b := newDaemon("a"), newDaemon("b")
+
+	if changed, _, _ := a.Elector.elect(); !changed || !a.Elector.IsLeader() {
+		t.Fatal("expected a elected")
+	}
+	if _, _, _ = b.Elector.elect(); b.Elector.IsLeader() {
+		t.Fatal("expected b to stand by")
+	}
+	if s := b.Status(); !s.Ready || s.Leader {
+		t.Errorf("expected a ready standby, got %+v", s)
+	}
+
+	b.Trigger()
+	if b.Status().Syncing || atomic.LoadInt32(&syncs) != 0 {
+		t.Error("expected the standby not to sync")
+	}
+
+	// A push to the standby is passed on to the leader on its next renewal
+	ts := httptest.NewServer(b.Handler())
+	defer ts.Close()
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/webhook", strings.NewReader(testPush))
+	req.Header.Set("X-GitHub-Event", "push")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || lease.requests != 1 {
+		t.Errorf("expected the push passed on, got %v and %v requests", resp.StatusCode, lease.requests)
+	}
+	if _, requested, _ := a.Elector.elect(); !requested {
+		t.Error("expected the leader told about the requested sync")
+	}
+	if _, requested, _ := a.Elector.elect(); requested {
+		t.Error("expected a request to be seen once")
+	}
+
+	// Once the leader releases the lease the standby takes over
+	if err := a.Elector.release(); err != nil || a.Elector.IsLeader() {
+		t.Fatalf("expected a to step down, got %v", err)
+	}
+	if changed, _, _ := b.Elector.elect(); !changed || !b.Elector.IsLeader() {
+		t.Error("expected b elected")
+	}
+}
+
+func TestLeaderLostCancelsSync(t *testing.T) {
+	lease := &memLease{}
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	d := &Daemon{
+		Elector: &LeaderElector{Lease: lease, Holder: "a", TTL: 30 * time.Millisecond},
+		Sync: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			stopped <- ctx.Err()
+			return ctx.Err()
+		},
+	}
+	d.Start()
+	defer d.Shutdown(context.Background())
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the leader to sync")
+	}
+
+	// Another replica takes the lease while the sync runs
+	lease.mu.Lock()
+	lease.holder = "b"
+	lease.mu.Unlock()
+
+	select {
+	case err := <-stopped:
+		if err != context.Canceled {
+			t.Errorf("expected the sync canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the sync canceled once the lease was lost")
+	}
+}
+
+// fakeLeases is an API server storing Leases, with the optimistic concurrency of Kubernetes
+type fakeLeases struct {
+	mu      sync.Mutex
+	lease   *k8sLease
+	version int
+}
+
+func (f *fakeLeases) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !strings.HasPrefix(r.URL.Path, "/apis/coordination.k8s.io/v1/namespaces/tyk/leases") || r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	in := &k8sLease{}
+	if r.Method != http.MethodGet {
+		json.NewDecoder(r.Body).Decode(in)
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if f.lease == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	case http.MethodPost:
+		if f.lease != nil {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.lease = in
+	case http.MethodPut:
+		if f.lease == nil || in.Metadata.ResourceVersion != f.lease.Metadata.ResourceVersion {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.lease = in
+	}
+
+	if r.Method != http.MethodGet {
+		f.version++
+		f.lease.Metadata.ResourceVersion = strconv.Itoa(f.version)
+	}
+	json.NewEncoder(w).Encode(f.lease)
+}
+
+func TestKubernetesLease(t *testing.T) {
+	leases := &fakeLeases{}
+	ts := httptest.NewServer(leases)
+	defer ts.Close()
+
+	l := &KubernetesLease{Namespace: "tyk", Name: "tyk-sync", APIServer: ts.URL, Token: "token"}
+
+	if state, err := l.Acquire("a", time.Minute); err != nil || !state.Held {
+		t.Fatalf("expected a to create the lease, got %+v %v", state, err)
+	}
+	if state, err := l.Acquire("b", time.Minute); err != nil || state.Held {
+		t.Fatalf("expected b to stand by, got %+v %v", state, err)
+	}
+
+	if err := l.RequestSync(); err != nil {
+		t.Fatal(err)
+	}
+	if state, err := l.Acquire("a", time.Minute); err != nil || !state.Held || state.Requests != 1 {
+		t.Errorf("expected a to renew and see the request, got %+v %v", state, err)
+	}
+
+	// An expired lease is taken over
+	leases.lease.Spec.RenewTime = time.Now().Add(-2 * time.Minute).Format(microTime)
+	if state, err := l.Acquire("b", time.Minute); err != nil || !state.Held {
+		t.Fatalf("expected b to take the expired lease, got %+v %v", state, err)
+	}
+	if leases.lease.Spec.LeaseTransitions != 1 || leases.lease.Spec.LeaseDurationSeconds != 60 {
+		t.Errorf("unexpected lease %+v", leases.lease.Spec)
+	}
+
+	if err := l.Release("a"); err != nil || leases.lease.Spec.HolderIdentity != "b" {
+		t.Errorf("expected a release by a to be ignored, got %v", err)
+	}
+	if err := l.Release("b"); err != nil || leases.lease.Spec.HolderIdentity != "" {
+		t.Errorf("expected b to release the lease, got %v", err)
+	}
+	if state, _ := l.Acquire("a", time.Minute); !state.Held {
+		t.Error("expected a released lease to be free")
+	}
+}
+
+// fakeRedis answers the commands of RedisLease, without expiry
+func fakeRedis(t *testing.T) (string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	keys := map[string]string{}
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+		for {
+			reply, err := readReply(r)
+			if err != nil {
+				return
+			}
+			args := []string{}
+			for _, a := range reply.([]interface{}) {
+				args = append(args, a.(string))
+			}
+
+			mu.Lock()
+			switch {
+			case args[0] == "AUTH" && args[1] == "s3cr3t":
+				w.WriteString("+OK\r\n")
+			case args[0] == "INCR":
+				n, _ := strconv.Atoi(keys[args[1]])
+				keys[args[1]] = strconv.Itoa(n + 1)
+				w.WriteString(":" + keys[args[1]] + "\r\n")
+			case args[0] == "EVAL" && args[1] == acquireScript:
+				held := 0
+				if h, ok := keys[args[3]]; !ok || h == args[5] {
+					keys[args[3]] = args[5]
+					held = 1
+				}
+				n, _ := strconv.Atoi(keys[args[4]])
+				w.WriteString("*2\r\n:" + strconv.Itoa(held) + "\r\n:" + strconv.Itoa(n) + "\r\n")
+			case args[0] == "EVAL" && args[1] == releaseScript:
+				if keys[args[3]] == args[4] {
+					delete(keys, args[3])
+				}
+				w.WriteString(":1\r\n")
+			default:
+				w.WriteString("-ERR unexpected command " + args[0] + "\r\n")
+			}
+			mu.Unlock()
+			w.Flush()
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	return ln.Addr().String(), func() { ln.Close() }
+}
+
+func TestRedisLease(t *testing.T) {
+	if _, err := NewRedisLease("http://localhost", "k"); err == nil {
+		t.Error("expected an error for a URL that isn't redis://")
+	}
+	l, err := NewRedisLease("rediss://:pw@redis.internal/2", "k")
+	if err != nil || l.Addr != "redis.internal:6379" || l.Password != "pw" || l.DB != 2 || !l.TLS {
+		t.Errorf("unexpected lease %+v %v", l, err)
+	}
+
+	addr, stop := fakeRedis(t)
+	defer stop()
+
+	l, err = NewRedisLease("redis://:s3cr3t@"+addr, "tyk-sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state, err := l.Acquire("a", time.Minute); err != nil || !state.Held {
+		t.Fatalf("expected a to take the lease, got %+v %v", state, err)
+	}
+	if state, err := l.Acquire("b", time.Minute); err != nil || state.Held {
+		t.Fatalf("expected b to stand by, got %+v %v", state, err)
+	}
+	if err := l.RequestSync(); err != nil {
+		t.Fatal(err)
+	}
+	if state, err := l.Acquire("a", time.Minute); err != nil || !state.Held || state.Requests != 1 {
+		t.Errorf("expected a to renew and see the request, got %+v %v", state, err)
+	}
+	if err := l.Release("a"); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := l.Acquire("b", time.Minute); !state.Held {
+		t.Error("expected b to take the released lease")
+	}
+
+	l.Password = "wrong"
+	if _, err := l.Acquire("b", time.Minute); err == nil {
+		t.Error("expected the Redis error returned")
+	}
+}
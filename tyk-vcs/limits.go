@@ -0,0 +1,79 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// limitWarningShare is the share of a limit from which the size of a target is warned about
+const limitWarningShare = 0.9
+
+// PlanLimits are the most APIs and policies the plan of a target allows, e.g. a Tyk Cloud
+// plan, 0 for no limit. The dashboard API doesn't report them, they are set per target profile.
+type PlanLimits struct {
+	MaxAPIs     int `json:"max_apis,omitempty"`
+	MaxPolicies int `json:"max_policies,omitempty"`
+}
+
+// PlannedSize is how many objects of a kind a target holds, and will after a sync
+type PlannedSize struct {
+	Kind     string
+	Existing int
+	After    int
+	Max      int
+	// Estimate is set when After is the most the target may hold, e.g. when the objects
+	// removed from the repo are kept or only some objects are synced
+	Estimate bool
+}
+
+// PlanSize returns the size of a target holding the existing objects once the repo objects are
+// synced, both given by their IDs. Existing objects the repo doesn't have are deleted by a
+// sync, unless keepRemoved is set, the size is then an estimate. Objects without an ID are new.
+func PlanSize(kind string, existing, repo []string, keepRemoved bool, max int) PlannedSize {
+	size := PlannedSize{Kind: kind, Existing: len(existing), After: len(repo), Max: max, Estimate: keepRemoved}
+	if !keepRemoved {
+		return size
+	}
+
+	inRepo := map[string]bool{}
+	for _, id := range repo {
+		if id != "" {
+			inRepo[id] = true
+		}
+	}
+	for _, id := range existing {
+		if id == "" || !inRepo[id] {
+			size.After++
+		}
+	}
+
+	return size
+}
+
+// CheckPlanLimits returns the warnings about the targets close to their limits, or that may
+// exceed them, and an error if a sync is sure to exceed one, so it fails before any change
+// rather than half way through
+func CheckPlanLimits(sizes []PlannedSize) ([]string, error) {
+	warnings := []string{}
+	exceeded := []string{}
+	for _, s := range sizes {
+		if s.Max <= 0 {
+			continue
+		}
+
+		switch {
+		case s.After > s.Max && !s.Estimate:
+			exceeded = append(exceeded, fmt.Sprintf("the sync would leave %v %v on the target, %v more than the %v of its plan", s.After, s.Kind, s.After-s.Max, s.Max))
+		case s.After > s.Max:
+			warnings = append(warnings, fmt.Sprintf("the target may hold up to %v %v after the sync, more than the %v of its plan", s.After, s.Kind, s.Max))
+		case float64(s.After) >= limitWarningShare*float64(s.Max):
+			warnings = append(warnings, fmt.Sprintf("the target will hold %v of the %v %v of its plan", s.After, s.Max, s.Kind))
+		}
+	}
+
+	if len(exceeded) > 0 {
+		return warnings, fmt.Errorf("%v, remove objects or raise the plan limits", strings.Join(exceeded, "; "))
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,39 @@
+package tyk_vcs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanSize(t *testing.T) {
+	existing := []string{"a1", "a2", "old", ""}
+	repo := []string{"a1", "a2", "new", ""}
+
+	if s := PlanSize("APIs", existing, repo, false, 10); s.After != 4 || s.Existing != 4 || s.Estimate {
+		t.Errorf("a full sync should leave the repo objects, got %+v", s)
+	}
+
+	// old and the existing API without an ID are kept
+	if s := PlanSize("APIs", existing, repo, true, 10); s.After != 6 || !s.Estimate {
+		t.Errorf("the removed objects should be counted when kept, got %+v", s)
+	}
+}
+
+func TestCheckPlanLimits(t *testing.T) {
+	warnings, err := CheckPlanLimits([]PlannedSize{
+		{Kind: "APIs", After: 12, Max: 10},
+		{Kind: "policies", After: 9, Max: 10},
+		{Kind: "keys", After: 100},
+	})
+	if err == nil || !strings.Contains(err.Error(), "leave 12 APIs on the target, 2 more than the 10 of its plan") {
+		t.Errorf("expected the APIs over the limit to fail, got %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "9 of the 10 policies") {
+		t.Errorf("expected a warning for the policies close to the limit, got %v", warnings)
+	}
+
+	warnings, err = CheckPlanLimits([]PlannedSize{{Kind: "APIs", After: 12, Max: 10, Estimate: true}})
+	if err != nil || len(warnings) != 1 || !strings.Contains(warnings[0], "may hold up to 12 APIs") {
+		t.Errorf("an estimate over the limit should only warn, got %v, %v", warnings, err)
+	}
+}
@@ -0,0 +1,88 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/levigross/grequests"
+)
+
+// LiveCheck is the outcome of checking that a gateway serves a published API
+type LiveCheck struct {
+	APIID      string `json:"api_id"`
+	Name       string `json:"name"`
+	ListenPath string `json:"listen_path"`
+	Live       bool   `json:"live"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// LivenessChecker checks that the APIs loaded on a gateway: the gateway must answer on
+// /hello, and a HEAD request to the listen path of each API must not be answered with a
+// 404, which the gateway returns for paths no API listens on. Errors and 5xx responses of
+// the upstream still mean the API routes.
+type LivenessChecker struct {
+	GatewayURL string
+	// Timeout is how long to wait for an API to load, gateways pick up changes with a delay
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+func (l *LivenessChecker) url(listenPath string) string {
+	return strings.TrimSuffix(l.GatewayURL, "/") + "/" + strings.TrimPrefix(listenPath, "/")
+}
+
+func (l *LivenessChecker) checkAPI(def objects.DBApiDefinition) LiveCheck {
+	c := LiveCheck{APIID: def.APIID, Name: def.Name, ListenPath: def.Proxy.ListenPath}
+
+	ro := &grequests.RequestOptions{
+		// APIs on a custom domain are only routed for that host
+		Host:           def.Domain,
+		RequestTimeout: 10 * time.Second,
+	}
+
+	deadline := time.Now().Add(l.Timeout)
+	for {
+		resp, err := grequests.Head(l.url(def.Proxy.ListenPath), ro)
+		if err != nil {
+			c.Error = err.Error()
+		} else {
+			c.StatusCode = resp.StatusCode
+			c.Live = resp.StatusCode != 404
+			c.Error = ""
+		}
+
+		if c.Live || time.Now().After(deadline) {
+			return c
+		}
+		time.Sleep(l.Interval)
+	}
+}
+
+// Check returns the liveness of each active API, the error is set when the gateway itself
+// can't be reached
+func (l *LivenessChecker) Check(defs []objects.DBApiDefinition) ([]LiveCheck, error) {
+	if l.Interval == 0 {
+		l.Interval = time.Second
+	}
+
+	resp, err := grequests.Get(l.url("/hello"), &grequests.RequestOptions{RequestTimeout: 10 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("gateway health check returned error: %v (code: %v)", resp.String(), resp.StatusCode)
+	}
+
+	checks := []LiveCheck{}
+	for _, def := range defs {
+		if !def.Active {
+			continue
+		}
+		checks = append(checks, l.checkAPI(def))
+	}
+
+	return checks, nil
+}
@@ -0,0 +1,63 @@
+package tyk_vcs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func liveDef(id, listenPath, domain string, active bool) objects.DBApiDefinition {
+	def := &apidef.APIDefinition{APIID: id, Name: id, Active: active, Domain: domain}
+	def.Proxy.ListenPath = listenPath
+	return objects.DBApiDefinition{APIDefinition: def}
+}
+
+func TestLivenessChecker(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/hello":
+			w.Write([]byte(`{"status":"pass"}`))
+		case strings.HasPrefix(r.URL.Path, "/users/"):
+			w.WriteHeader(http.StatusUnauthorized)
+		case strings.HasPrefix(r.URL.Path, "/broken/"):
+			w.WriteHeader(http.StatusBadGateway)
+		case strings.HasPrefix(r.URL.Path, "/orders/") && r.Host == "orders.example.com":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	checker := &LivenessChecker{GatewayURL: ts.URL, Timeout: 50 * time.Millisecond, Interval: 10 * time.Millisecond}
+	checks, err := checker.Check([]objects.DBApiDefinition{
+		liveDef("users", "/users/", "", true),
+		liveDef("broken", "/broken/", "", true),
+		liveDef("orders", "/orders/", "orders.example.com", true),
+		liveDef("missing", "/missing/", "", true),
+		liveDef("inactive", "/inactive/", "", false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]bool{"users": true, "broken": true, "orders": true, "missing": false}
+	if len(checks) != len(expected) {
+		t.Fatalf("expected %v checks, got %+v", len(expected), checks)
+	}
+	for _, c := range checks {
+		if c.Live != expected[c.APIID] {
+			t.Fatalf("%v: expected live=%v, got %+v", c.APIID, expected[c.APIID], c)
+		}
+	}
+
+	ts.Close()
+	if _, err := checker.Check(nil); err == nil {
+		t.Fatal("expected an error when the gateway is down")
+	}
+}
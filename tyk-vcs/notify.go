@@ -0,0 +1,84 @@
+package tyk_vcs
+
+import (
+	"fmt"
+
+	"github.com/levigross/grequests"
+)
+
+const (
+	NotifyAlways  = "always"
+	NotifySuccess = "success"
+	NotifyFailure = "failure"
+)
+
+// Notifier posts a sync report to webhooks once a sync has finished. URLs receive the report
+// as JSON, SlackURLs (Slack incoming webhooks) a message with its summary.
+type Notifier struct {
+	URLs      []string
+	SlackURLs []string
+	// On is NotifyAlways, NotifySuccess or NotifyFailure
+	On string
+	// Status, if set, is posted for every outcome
+	Status *CommitStatus
+	// Comment, if set, posts the Markdown report on the pull request for every outcome
+	Comment *PRComment
+}
+
+func (n *Notifier) wants(r *SyncReport) bool {
+	switch n.On {
+	case NotifySuccess:
+		return r.Success
+	case NotifyFailure:
+		return !r.Success
+	}
+
+	return true
+}
+
+func post(url string, body interface{}) error {
+	resp, err := grequests.Post(url, &grequests.RequestOptions{JSON: body})
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %v returned error: %v (code: %v)", url, resp.String(), resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Send notifies every configured webhook, a failing webhook doesn't stop the others
+func (n *Notifier) Send(r *SyncReport) []error {
+	errs := []error{}
+	if n.Status != nil {
+		if err := n.Status.Post(r.Success, r.Summary()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if n.Comment != nil {
+		if err := n.Comment.Post(r.Markdown()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if !n.wants(r) {
+		return errs
+	}
+
+	for _, url := range n.URLs {
+		if err := post(url, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, url := range n.SlackURLs {
+		if err := post(url, map[string]string{"text": r.Summary()}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,178 @@
+package tyk_vcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-patch"
+	"github.com/TykTechnologies/tyk/apidef"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ObjectPatch is an RFC 7396 JSON merge patch of a live API or policy, e.g. to flip active
+// or raise a rate limit in an emergency without the full definition at hand. Patch files
+// committed to git record who changed what and why.
+type ObjectPatch struct {
+	APIID    string `json:"api_id,omitempty"`
+	PolicyID string `json:"policy_id,omitempty"`
+	// Reason is printed when the patch is applied
+	Reason string          `json:"reason,omitempty"`
+	Patch  json.RawMessage `json:"patch"`
+}
+
+// identityFields are the fields a patch may not change, the object would no longer be the
+// one patched
+var identityFields = map[string][]string{
+	"api":    {"api_id", "id", "org_id"},
+	"policy": {"_id", "id", "org_id"},
+}
+
+// ReadObjectPatches reads a patch file, which holds a patch or a list of them
+func ReadObjectPatches(raw []byte) ([]ObjectPatch, error) {
+	patches := []ObjectPatch{}
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(raw, &patches); err != nil {
+			return nil, err
+		}
+	} else {
+		p := ObjectPatch{}
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, err
+		}
+		patches = append(patches, p)
+	}
+
+	for i, p := range patches {
+		if (p.APIID == "") == (p.PolicyID == "") {
+			return nil, fmt.Errorf("patch %v: set one of api_id or policy_id", i)
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal(p.Patch, &obj); err != nil || obj == nil {
+			return nil, fmt.Errorf("patch %v: the patch must be a JSON object", i)
+		}
+	}
+
+	return patches, nil
+}
+
+// Target describes the object patched, e.g. API a1
+func (p *ObjectPatch) Target() string {
+	if p.APIID != "" {
+		return "API " + p.APIID
+	}
+	return "policy " + p.PolicyID
+}
+
+// Fields lists the fields the patch sets or removes, as dotted paths, e.g. proxy.target_url
+func (p *ObjectPatch) Fields() []string {
+	var patch interface{}
+	json.Unmarshal(p.Patch, &patch)
+
+	fields := []string{}
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		obj, ok := v.(map[string]interface{})
+		if !ok || len(obj) == 0 {
+			fields = append(fields, prefix)
+			return
+		}
+		for k, child := range obj {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			walk(k, child)
+		}
+	}
+	walk("", patch)
+	sort.Strings(fields)
+
+	return fields
+}
+
+// merge patches a live object, refusing to change what identifies it
+func (p *ObjectPatch) merge(kind string, live map[string]interface{}) ([]byte, error) {
+	var patch interface{}
+	if err := json.Unmarshal(p.Patch, &patch); err != nil {
+		return nil, err
+	}
+
+	patched, _ := tyk_patch.MergePatch(live, patch).(map[string]interface{})
+	for _, f := range identityFields[kind] {
+		if !reflect.DeepEqual(live[f], patched[f]) {
+			return nil, fmt.Errorf("the patch of %v may not change its %v", p.Target(), f)
+		}
+	}
+
+	return json.Marshal(patched)
+}
+
+// Apply fetches the live object from the target of the publisher, patches it and, unless
+// dryRun, updates it. The patched object is returned.
+func (p *ObjectPatch) Apply(publisher Publisher, dryRun bool) (interface{}, error) {
+	fetcher, ok := publisher.(Fetcher)
+	if !ok {
+		return nil, fmt.Errorf("%v can't fetch the live objects to patch", publisher.Name())
+	}
+
+	if p.APIID != "" {
+		live, err := fetcher.FetchAPIRaw(&objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: p.APIID}})
+		if err != nil {
+			return nil, err
+		}
+		if live == nil {
+			return nil, fmt.Errorf("%v not found", p.Target())
+		}
+
+		raw, err := p.merge("api", live)
+		if err != nil {
+			return nil, err
+		}
+		def := objects.DBApiDefinition{}
+		if err := json.Unmarshal([]byte(`{"api_definition":`+string(raw)+`}`), &def); err != nil {
+			return nil, err
+		}
+		// The fields unknown to tyk-sync are sent back as they were
+		if err := def.KeepRaw(raw); err != nil {
+			return nil, err
+		}
+
+		if dryRun {
+			return &def, nil
+		}
+		return &def, publisher.Update(&def)
+	}
+
+	pol := &objects.Policy{ID: p.PolicyID}
+	if bson.IsObjectIdHex(p.PolicyID) {
+		pol.MID = bson.ObjectIdHex(p.PolicyID)
+	}
+	live, err := fetcher.FetchPolicyRaw(pol)
+	if err != nil {
+		return nil, err
+	}
+	if live == nil {
+		return nil, fmt.Errorf("%v not found", p.Target())
+	}
+
+	raw, err := p.merge("policy", live)
+	if err != nil {
+		return nil, err
+	}
+	patched := &objects.Policy{}
+	if err := json.Unmarshal(raw, patched); err != nil {
+		return nil, err
+	}
+	if patched.MID == "" && patched.ID == "" {
+		return nil, errors.New("the live policy has no ID")
+	}
+
+	if dryRun {
+		return patched, nil
+	}
+	return patched, publisher.UpdatePolicy(patched)
+}
@@ -0,0 +1,96 @@
+package tyk_vcs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// livePublisher serves live objects and records the updates
+type livePublisher struct {
+	recordingPublisher
+	apis     map[string]map[string]interface{}
+	pols     map[string]map[string]interface{}
+	updated  *objects.DBApiDefinition
+	polSaved *objects.Policy
+}
+
+func (l *livePublisher) FetchAPIRaw(def *objects.DBApiDefinition) (map[string]interface{}, error) {
+	return l.apis[def.APIID], nil
+}
+func (l *livePublisher) FetchPolicyRaw(pol *objects.Policy) (map[string]interface{}, error) {
+	return l.pols[pol.ID], nil
+}
+func (l *livePublisher) Update(def *objects.DBApiDefinition) error {
+	l.updated = def
+	return nil
+}
+func (l *livePublisher) UpdatePolicy(pol *objects.Policy) error {
+	l.polSaved = pol
+	return nil
+}
+
+func TestReadObjectPatches(t *testing.T) {
+	patches, err := ReadObjectPatches([]byte(`{"api_id": "a1", "reason": "INC-42", "patch": {"active": false, "proxy": {"target_url": "http://dr"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := patches[0].Fields(); !reflect.DeepEqual(fields, []string{"active", "proxy.target_url"}) {
+		t.Errorf("unexpected fields %v", fields)
+	}
+
+	for _, raw := range []string{
+		`{"patch": {"active": false}}`,
+		`{"api_id": "a1", "policy_id": "gold", "patch": {}}`,
+		`[{"api_id": "a1", "patch": [1]}]`,
+	} {
+		if _, err := ReadObjectPatches([]byte(raw)); err == nil {
+			t.Errorf("expected %v to be refused", raw)
+		}
+	}
+}
+
+func TestObjectPatch_Apply(t *testing.T) {
+	p := &livePublisher{
+		apis: map[string]map[string]interface{}{"a1": {
+			"api_id": "a1", "name": "Payments", "active": true,
+			"proxy": map[string]interface{}{"listen_path": "/pay/", "target_url": "http://up"},
+			// Unknown to the vendored apidef
+			"graphql": map[string]interface{}{"enabled": false},
+		}},
+		pols: map[string]map[string]interface{}{"gold": {"_id": "5e9d9544a1dcd60001d0ed20", "id": "gold", "rate": 100.0, "per": 60.0}},
+	}
+
+	patch := ObjectPatch{APIID: "a1", Patch: []byte(`{"active": false, "proxy": {"target_url": "http://dr"}}`)}
+	if _, err := patch.Apply(p, true); err != nil || p.updated != nil {
+		t.Fatalf("expected a dry run not to update, got %v %v", p.updated, err)
+	}
+	if _, err := patch.Apply(p, false); err != nil {
+		t.Fatal(err)
+	}
+	if p.updated.Active || p.updated.Proxy.TargetURL != "http://dr" || p.updated.Proxy.ListenPath != "/pay/" {
+		t.Errorf("expected the patched fields only to change, got %+v", p.updated.APIDefinition)
+	}
+	if _, ok := p.updated.Passthrough["graphql"]; !ok {
+		t.Error("expected the unknown fields of the live API to be kept")
+	}
+
+	pol := ObjectPatch{PolicyID: "gold", Patch: []byte(`{"rate": 1000}`)}
+	if _, err := pol.Apply(p, false); err != nil {
+		t.Fatal(err)
+	}
+	if p.polSaved.Rate != 1000 || p.polSaved.Per != 60 || p.polSaved.MID.Hex() != "5e9d9544a1dcd60001d0ed20" {
+		t.Errorf("unexpected patched policy %+v", p.polSaved)
+	}
+
+	renamed := ObjectPatch{APIID: "a1", Patch: []byte(`{"api_id": "a2"}`)}
+	if _, err := renamed.Apply(p, false); err == nil || !strings.Contains(err.Error(), "api_id") {
+		t.Errorf("expected the ID not to be patched, got %v", err)
+	}
+	missing := ObjectPatch{APIID: "a9", Patch: []byte(`{"active": true}`)}
+	if _, err := missing.Apply(p, false); err == nil {
+		t.Error("expected a missing API to fail")
+	}
+}
@@ -0,0 +1,59 @@
+package tyk_vcs
+
+import (
+	"sort"
+	"sync"
+)
+
+// FetchFailure is an object FetchParallel could not fetch, by its index
+type FetchFailure struct {
+	Index int
+	Err   error
+}
+
+// FetchParallel calls fetch for the indexes 0 to n-1 on up to workers goroutines, fetch
+// must only write to the results of its own index. progress, which may be nil, is called
+// after every fetch with the number of fetches done. A failed fetch doesn't stop the
+// others, the failures are returned in index order.
+func FetchParallel(n, workers int, fetch func(i int) error, progress func(done, total int)) []FetchFailure {
+	if workers < 1 {
+		workers = 1
+	}
+
+	indexes := make(chan int)
+	go func() {
+		for i := 0; i < n; i++ {
+			indexes <- i
+		}
+		close(indexes)
+	}()
+
+	var mu sync.Mutex
+	failures := []FetchFailure{}
+	done := 0
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				err := fetch(i)
+
+				mu.Lock()
+				if err != nil {
+					failures = append(failures, FetchFailure{Index: i, Err: err})
+				}
+				done++
+				if progress != nil {
+					progress(done, n)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+	return failures
+}
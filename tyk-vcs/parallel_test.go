@@ -0,0 +1,52 @@
+package tyk_vcs
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFetchParallel(t *testing.T) {
+	results := make([]int, 50)
+	var running, maxRunning int32
+	calls := 0
+
+	failures := FetchParallel(len(results), 4, func(i int) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+
+		if i%10 == 3 {
+			return errors.New("failed")
+		}
+		results[i] = i * 2
+		return nil
+	}, func(done, total int) {
+		calls++
+		if total != 50 || done != calls {
+			t.Errorf("unexpected progress %v/%v", done, total)
+		}
+	})
+
+	if calls != 50 {
+		t.Fatalf("expected progress for every fetch, got %v", calls)
+	}
+	if maxRunning > 4 {
+		t.Fatalf("expected at most 4 fetches at once, got %v", maxRunning)
+	}
+	if len(failures) != 5 || failures[0].Index != 3 || failures[4].Index != 43 {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+	if results[49] != 98 || results[3] != 0 {
+		t.Fatal("unexpected results")
+	}
+
+	if f := FetchParallel(0, 4, func(int) error { return errors.New("never called") }, nil); len(f) != 0 {
+		t.Fatal("expected no failures without objects")
+	}
+}
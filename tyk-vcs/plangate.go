@@ -0,0 +1,98 @@
+package tyk_vcs
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// errPlanned stops a sync once its plan is checked, during the planning pass of a PlanGate
+var errPlanned = errors.New("the sync was only planned")
+
+// PlanGate runs the plan checks of every kind of object a sync changes before any is
+// applied, so that e.g. the delete guard of the APIs refuses a sync before the policies are
+// synced. The sync is run a first time to plan and check every kind, stopping before each
+// is applied, then again to apply the plans as they were checked.
+type PlanGate struct {
+	check objects.PlanCheck
+
+	mu       sync.Mutex
+	planning bool
+	checked  map[string]*objects.SyncPlan
+}
+
+// NewPlanGate gates the plans with check, which may be nil
+func NewPlanGate(check objects.PlanCheck) *PlanGate {
+	return &PlanGate{check: check, checked: map[string]*objects.SyncPlan{}}
+}
+
+// Check is the plan check to give the client syncing the objects
+func (g *PlanGate) Check(plan *objects.SyncPlan) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.planning {
+		if g.check != nil {
+			if err := g.check(plan); err != nil {
+				return err
+			}
+		}
+
+		checked := *plan
+		g.checked[plan.Kind] = &checked
+		return errPlanned
+	}
+
+	checked, ok := g.checked[plan.Kind]
+	if !ok {
+		if g.check == nil {
+			return nil
+		}
+		return g.check(plan)
+	}
+
+	// Only what was checked is applied, objects that turned up since are left alone
+	plan.Create = keepChecked(plan.Create, checked.Create)
+	plan.Update = keepChecked(plan.Update, checked.Update)
+	plan.Delete = keepChecked(plan.Delete, checked.Delete)
+	return nil
+}
+
+// Plan runs sync to check the plans of every kind it syncs, without applying them
+func (g *PlanGate) Plan(sync func() error) error {
+	g.mu.Lock()
+	g.planning = true
+	g.checked = map[string]*objects.SyncPlan{}
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		g.planning = false
+		g.mu.Unlock()
+	}()
+
+	if err := sync(); err != nil && !errors.Is(err, errPlanned) {
+		return err
+	}
+	return nil
+}
+
+func keepChecked(items, checked []objects.SyncItem) []objects.SyncItem {
+	key := func(item objects.SyncItem) string {
+		return item.ID + "\x00" + item.Name
+	}
+
+	ok := map[string]bool{}
+	for _, item := range checked {
+		ok[key(item)] = true
+	}
+
+	out := []objects.SyncItem{}
+	for _, item := range items {
+		if ok[key(item)] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package tyk_vcs
+
+import (
+	"sort"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// PolicyUsage is how a policy of a target is used: by how many keys, and which APIs it
+// grants access to
+type PolicyUsage struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Keys int    `json:"keys"`
+	// APIs are the APIs of the access rights that exist on the target, as name (API ID)
+	APIs []string `json:"apis"`
+	// MissingAPIs are the API IDs of the access rights no API of the target has
+	MissingAPIs []string `json:"missing_apis,omitempty"`
+	Unused      bool     `json:"unused"`
+}
+
+// KeyPolicies returns the policies a key session applies, apply_policies and the legacy
+// apply_policy_id
+func KeyPolicies(k *objects.Key) []string {
+	ids := []string{}
+	if k.Session == nil {
+		return ids
+	}
+
+	if pols, ok := k.Session["apply_policies"].([]interface{}); ok {
+		for _, p := range pols {
+			if id, ok := p.(string); ok && id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if id, ok := k.Session["apply_policy_id"].(string); ok && id != "" {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// PolicyKeyCounter counts the keys applying each policy, keys are added one at a time so
+// that a whole org of them needn't be held in memory
+type PolicyKeyCounter map[string]int
+
+// Add counts the policies k applies, once each
+func (c PolicyKeyCounter) Add(k *objects.Key) {
+	seen := map[string]bool{}
+	for _, id := range KeyPolicies(k) {
+		if !seen[id] {
+			seen[id] = true
+			c[id]++
+		}
+	}
+}
+
+// ReportPolicyUsage cross-references pols with the APIs and the keys, counted by keys, of
+// their target. Keys may apply a policy by its explicit or its database ID. Policies no key
+// applies are unused. The report is sorted by name, then ID.
+func ReportPolicyUsage(pols []objects.Policy, apis []objects.DBApiDefinition, keys PolicyKeyCounter) []PolicyUsage {
+	names := map[string]string{}
+	for _, a := range apis {
+		if a.APIDefinition != nil {
+			names[a.APIID] = a.Name
+		}
+	}
+
+	report := []PolicyUsage{}
+	for _, p := range pols {
+		u := PolicyUsage{ID: p.ID, Name: p.Name, APIs: []string{}}
+		if u.ID == "" {
+			u.ID = p.MID.Hex()
+		}
+
+		u.Keys = keys[p.ID]
+		if mid := p.MID.Hex(); mid != "" && mid != p.ID {
+			u.Keys += keys[mid]
+		}
+		u.Unused = u.Keys == 0
+
+		for apiID := range p.AccessRights {
+			if name, ok := names[apiID]; ok {
+				u.APIs = append(u.APIs, name+" ("+apiID+")")
+			} else {
+				u.MissingAPIs = append(u.MissingAPIs, apiID)
+			}
+		}
+		sort.Strings(u.APIs)
+		sort.Strings(u.MissingAPIs)
+
+		report = append(report, u)
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Name != report[j].Name {
+			return report[i].Name < report[j].Name
+		}
+		return report[i].ID < report[j].ID
+	})
+
+	return report
+}
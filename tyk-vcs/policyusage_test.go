@@ -0,0 +1,43 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestReportPolicyUsage(t *testing.T) {
+	mid := bson.NewObjectId()
+	pols := []objects.Policy{
+		{ID: "gold", Name: "Gold", AccessRights: map[string]objects.AccessDefinition{"a1": {}, "gone": {}}},
+		{MID: mid, Name: "Bronze", AccessRights: map[string]objects.AccessDefinition{"a2": {}}},
+		{ID: "silver", Name: "Silver"},
+	}
+	apis := []objects.DBApiDefinition{
+		{APIDefinition: &apidef.APIDefinition{APIID: "a1", Name: "Payments"}},
+		{APIDefinition: &apidef.APIDefinition{APIID: "a2", Name: "Orders"}},
+	}
+
+	keys := PolicyKeyCounter{}
+	keys.Add(&objects.Key{Session: map[string]interface{}{"apply_policies": []interface{}{"gold", "gold"}}})
+	keys.Add(&objects.Key{Session: map[string]interface{}{"apply_policy_id": mid.Hex()}})
+	keys.Add(&objects.Key{Session: map[string]interface{}{"apply_policies": []interface{}{"gold", mid.Hex()}}})
+	keys.Add(&objects.Key{})
+
+	report := ReportPolicyUsage(pols, apis, keys)
+	if len(report) != 3 {
+		t.Fatalf("expected 3 policies, got %+v", report)
+	}
+
+	if u := report[0]; u.ID != mid.Hex() || u.Keys != 2 || u.Unused || len(u.APIs) != 1 || u.APIs[0] != "Orders (a2)" {
+		t.Errorf("unexpected usage of the policy applied by its database ID %+v", u)
+	}
+	if u := report[1]; u.ID != "gold" || u.Keys != 2 || len(u.MissingAPIs) != 1 || u.MissingAPIs[0] != "gone" {
+		t.Errorf("expected the missing API flagged, got %+v", u)
+	}
+	if u := report[2]; u.ID != "silver" || !u.Unused || len(u.APIs) != 0 {
+		t.Errorf("expected the policy without keys unused, got %+v", u)
+	}
+}
@@ -0,0 +1,232 @@
+package tyk_vcs
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/levigross/grequests"
+)
+
+// GitHub rejects longer comments
+const maxCommentLength = 65536
+
+// PRComment posts the summary of a run as a comment on the pull request (GitHub) or merge
+// request (GitLab) of the commit, so reviewers see what it changes on the target next to the
+// code. Every run of the same Context updates its comment instead of adding one.
+type PRComment struct {
+	Provider string
+	APIURL   string
+	// Repo is owner/name on GitHub and the project ID on GitLab
+	Repo string
+	// Number is the pull request number or merge request IID, 0 to look up the request the
+	// commit SHA was merged from, e.g. in a pipeline of the main branch
+	Number  int
+	SHA     string
+	Token   string
+	Context string
+}
+
+// PRCommentFromEnv reads the pull request, commit and repository from the variables GitHub
+// Actions and GitLab CI set, the token is read from GITHUB_TOKEN or GITLAB_TOKEN. Outside of a
+// pull request pipeline the request is looked up by the commit when posting.
+func PRCommentFromEnv(provider, context string, getenv func(string) string) (*PRComment, error) {
+	c := &PRComment{Provider: provider, Context: context}
+
+	number := ""
+	switch provider {
+	case StatusGitHub:
+		c.APIURL = getenv("GITHUB_API_URL")
+		if c.APIURL == "" {
+			c.APIURL = "https://api.github.com"
+		}
+		c.Repo = getenv("GITHUB_REPOSITORY")
+		c.SHA = getenv("GITHUB_SHA")
+		c.Token = getenv("GITHUB_TOKEN")
+		// refs/pull/<number>/merge in pull_request workflows
+		if ref := strings.Split(getenv("GITHUB_REF"), "/"); len(ref) == 4 && ref[1] == "pull" {
+			number = ref[2]
+		}
+	case StatusGitLab:
+		c.APIURL = getenv("CI_API_V4_URL")
+		c.Repo = getenv("CI_PROJECT_ID")
+		c.SHA = getenv("CI_COMMIT_SHA")
+		c.Token = getenv("GITLAB_TOKEN")
+		number = getenv("CI_MERGE_REQUEST_IID")
+	default:
+		return nil, fmt.Errorf("unknown pull request comment provider %q, must be github or gitlab", provider)
+	}
+
+	if number != "" {
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pull request number %q", number)
+		}
+		c.Number = n
+	}
+
+	if c.APIURL == "" || c.Repo == "" || (c.Number == 0 && c.SHA == "") || c.Token == "" {
+		return nil, errors.New("the pull request, repository or token to comment with is not set in the environment")
+	}
+
+	return c, nil
+}
+
+// request sends body to fullPath and decodes the response into result, either may be nil.
+// It returns the URL of the next page of a list, empty on the last one.
+func (c *PRComment) request(method, fullPath string, body, result interface{}) (string, error) {
+	ro := &grequests.RequestOptions{Headers: providerHeaders(c.Provider, c.Token)}
+	if body != nil {
+		ro.JSON = body
+	}
+
+	resp, err := grequests.Req(method, fullPath, ro)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("pull request comment returned error: %v (code: %v)", resp.String(), resp.StatusCode)
+	}
+
+	if result == nil {
+		return "", nil
+	}
+	return nextLink(resp.Header.Get("Link")), resp.JSON(result)
+}
+
+// nextLink is the rel="next" URL of a Link header, which both GitHub and GitLab page lists with
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		for _, p := range parts[1:] {
+			if strings.TrimSpace(p) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
+	}
+
+	return ""
+}
+
+// truncateComment shortens body to max bytes on a line boundary, closing the <details>
+// blocks left open by the cut
+func truncateComment(body string, max int) string {
+	if len(body) <= max {
+		return body
+	}
+
+	const note = "\n_(summary truncated)_\n"
+	cut := body[:max-len(note)]
+	for {
+		if i := strings.LastIndex(cut, "\n"); i > 0 {
+			cut = cut[:i+1]
+		} else {
+			for len(cut) > 0 && !utf8.RuneStart(body[len(cut)]) {
+				cut = cut[:len(cut)-1]
+			}
+		}
+
+		closers := ""
+		if open := strings.Count(cut, "<details>") - strings.Count(cut, "</details>"); open > 0 {
+			closers = strings.Repeat("\n</details>\n", open)
+		}
+
+		out := cut + closers + note
+		if len(out) <= max {
+			return out
+		}
+		cut = cut[:len(cut)-(len(out)-max)]
+	}
+}
+
+// project is the API path of the repository
+func (c *PRComment) project() string {
+	if c.Provider == StatusGitLab {
+		return fmt.Sprintf("%v/projects/%v", strings.TrimSuffix(c.APIURL, "/"), url.PathEscape(c.Repo))
+	}
+
+	return fmt.Sprintf("%v/repos/%v", strings.TrimSuffix(c.APIURL, "/"), c.Repo)
+}
+
+// number returns the pull request to comment on, looked up by the commit if not known
+func (c *PRComment) number() (int, error) {
+	if c.Number != 0 {
+		return c.Number, nil
+	}
+
+	requests := []struct {
+		Number int `json:"number"`
+		IID    int `json:"iid"`
+	}{}
+	lookup := fmt.Sprintf("%v/commits/%v/pulls", c.project(), c.SHA)
+	if c.Provider == StatusGitLab {
+		lookup = fmt.Sprintf("%v/repository/commits/%v/merge_requests", c.project(), c.SHA)
+	}
+	if _, err := c.request("GET", lookup, nil, &requests); err != nil {
+		return 0, err
+	}
+
+	if len(requests) == 0 {
+		return 0, fmt.Errorf("no pull request found for commit %v", c.SHA)
+	}
+	if c.Provider == StatusGitLab {
+		return requests[0].IID, nil
+	}
+	return requests[0].Number, nil
+}
+
+// marker is hidden in the comment to find it again
+func (c *PRComment) marker() string {
+	return fmt.Sprintf("<!-- tyk-sync: %v -->", c.Context)
+}
+
+// Post comments body, Markdown, on the pull request, or replaces the comment of an earlier run
+func (c *PRComment) Post(body string) error {
+	if c.Provider != StatusGitHub && c.Provider != StatusGitLab {
+		return fmt.Errorf("unknown pull request comment provider %q", c.Provider)
+	}
+
+	n, err := c.number()
+	if err != nil {
+		return err
+	}
+
+	body = truncateComment(c.marker()+"\n"+body, maxCommentLength)
+
+	comments := fmt.Sprintf("%v/issues/%v/comments", c.project(), n)
+	if c.Provider == StatusGitLab {
+		comments = fmt.Sprintf("%v/merge_requests/%v/notes", c.project(), n)
+	}
+
+	payload := map[string]string{"body": body}
+	for page := comments + "?per_page=100"; page != ""; {
+		existing := []struct {
+			ID   int    `json:"id"`
+			Body string `json:"body"`
+		}{}
+		page, err = c.request("GET", page, nil, &existing)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range existing {
+			if !strings.Contains(e.Body, c.marker()) {
+				continue
+			}
+
+			if c.Provider == StatusGitLab {
+				_, err = c.request("PUT", fmt.Sprintf("%v/%v", comments, e.ID), payload, nil)
+			} else {
+				_, err = c.request("PATCH", fmt.Sprintf("%v/issues/comments/%v", c.project(), e.ID), payload, nil)
+			}
+			return err
+		}
+	}
+
+	_, err = c.request("POST", comments, payload, nil)
+	return err
+}
@@ -0,0 +1,159 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// fakeCommentAPI serves the comments of one pull request, on the GitHub or GitLab paths
+func fakeCommentAPI(comments map[int]string) (*httptest.Server, *[]string) {
+	calls := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		body := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/pulls"), strings.HasSuffix(r.URL.Path, "/merge_requests"):
+			fmt.Fprint(w, `[{"number": 7, "iid": 7}]`)
+		case r.Method == "GET":
+			list := []map[string]interface{}{}
+			for id, b := range comments {
+				list = append(list, map[string]interface{}{"id": id, "body": b})
+			}
+			json.NewEncoder(w).Encode(list)
+		case r.Method == "POST":
+			comments[len(comments)+1] = body["body"]
+			w.WriteHeader(http.StatusCreated)
+		default:
+			parts := strings.Split(r.URL.Path, "/")
+			var id int
+			fmt.Sscan(parts[len(parts)-1], &id)
+			comments[id] = body["body"]
+		}
+	}))
+
+	return ts, &calls
+}
+
+func TestPRCommentGitHub(t *testing.T) {
+	comments := map[int]string{1: "LGTM"}
+	ts, calls := fakeCommentAPI(comments)
+	defer ts.Close()
+
+	env := map[string]string{
+		"GITHUB_API_URL":    ts.URL,
+		"GITHUB_REPOSITORY": "org/repo",
+		"GITHUB_REF":        "refs/pull/7/merge",
+		"GITHUB_TOKEN":      "token",
+	}
+	c, err := PRCommentFromEnv(StatusGitHub, "sync http://dash", func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Number != 7 {
+		t.Fatalf("expected the pull request number from GITHUB_REF, got %v", c.Number)
+	}
+
+	if err := c.Post("first run"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Post("second run"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(comments) != 2 || !strings.Contains(comments[2], "second run") || comments[1] != "LGTM" {
+		t.Errorf("expected the comment of the first run to be updated, got %v", comments)
+	}
+	expected := []string{
+		"GET /repos/org/repo/issues/7/comments", "POST /repos/org/repo/issues/7/comments",
+		"GET /repos/org/repo/issues/7/comments", "PATCH /repos/org/repo/issues/comments/2",
+	}
+	if strings.Join(*calls, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("unexpected calls %v", *calls)
+	}
+
+	delete(env, "GITHUB_REF")
+	if _, err := PRCommentFromEnv(StatusGitHub, "sync", func(k string) string { return env[k] }); err == nil {
+		t.Error("expected an error without a pull request or commit")
+	}
+}
+
+func TestPRCommentGitLabByCommit(t *testing.T) {
+	comments := map[int]string{}
+	ts, calls := fakeCommentAPI(comments)
+	defer ts.Close()
+
+	env := map[string]string{
+		"CI_API_V4_URL": ts.URL,
+		"CI_PROJECT_ID": "42",
+		"CI_COMMIT_SHA": "abc123",
+		"GITLAB_TOKEN":  "token",
+	}
+	c, err := PRCommentFromEnv(StatusGitLab, "verify http://dash", func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Post("drift"); err != nil {
+		t.Fatal(err)
+	}
+
+	if (*calls)[0] != "GET /projects/42/repository/commits/abc123/merge_requests" || (*calls)[2] != "POST /projects/42/merge_requests/7/notes" {
+		t.Errorf("unexpected calls %v", *calls)
+	}
+	if !strings.Contains(comments[1], "<!-- tyk-sync: verify http://dash -->") {
+		t.Errorf("expected the marker in the comment, got %q", comments[1])
+	}
+}
+
+func TestPRCommentPaging(t *testing.T) {
+	calls := []string{}
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+		if r.Method != "GET" {
+			return
+		}
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%v/repos/org/repo/issues/7/comments?per_page=100&page=2>; rel="next", <%v/last>; rel="last"`, ts.URL, ts.URL))
+			fmt.Fprint(w, `[{"id": 1, "body": "LGTM"}]`)
+			return
+		}
+		fmt.Fprint(w, `[{"id": 2, "body": "<!-- tyk-sync: sync -->\nfirst run"}]`)
+	}))
+	defer ts.Close()
+
+	c := &PRComment{Provider: StatusGitHub, APIURL: ts.URL, Repo: "org/repo", Number: 7, Token: "token", Context: "sync"}
+	if err := c.Post("second run"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"GET /repos/org/repo/issues/7/comments?per_page=100", "GET /repos/org/repo/issues/7/comments?per_page=100&page=2",
+		"PATCH /repos/org/repo/issues/comments/2",
+	}
+	if strings.Join(calls, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected the comment on the second page to be updated, got %v", calls)
+	}
+}
+
+func TestTruncateComment(t *testing.T) {
+	body := "### Sync\n\n<details><summary>APIs</summary>\n\n" + strings.Repeat("- created **Ünïcode** (`a1`)\n", 100)
+	out := truncateComment(body, 200)
+	if len(out) > 200 || !utf8.ValidString(out) {
+		t.Fatalf("expected at most 200 bytes of valid UTF-8, got %v: %q", len(out), out)
+	}
+	if !strings.Contains(out, "(`a1`)\n\n</details>\n") || !strings.HasSuffix(out, "_(summary truncated)_\n") {
+		t.Errorf("expected the cut on a line with the details closed, got %q", out)
+	}
+
+	if truncateComment("short", 200) != "short" {
+		t.Error("expected a short comment to be kept")
+	}
+}
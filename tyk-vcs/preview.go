@@ -0,0 +1,161 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/files"
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+const (
+	previewPolicies = "policies"
+	previewConf     = "tyk.conf"
+	previewCompose  = "docker-compose.yml"
+	// previewRoot is where the gateway image keeps its files
+	previewRoot = "/opt/tyk-gateway"
+)
+
+// composeService matches the names docker compose allows for services
+var composeService = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// PreviewOptions configure the local CE gateway a preview is rendered for
+type PreviewOptions struct {
+	// Secret is the secret of the gateway API, e.g. to create keys
+	Secret string
+	// Port is the port the gateway listens on
+	Port int
+	// Redis is the host:port of the redis the gateway uses
+	Redis string
+	// Image is the gateway image of the docker compose file, e.g. tykio/tyk-gateway:v2.9.4
+	Image string
+	// Compose also writes a docker compose file running the gateway and a redis
+	Compose bool
+}
+
+// WritePreview renders defs and pols into dir for a local CE gateway: the files of a gateway
+// target (see files.Client) with the active APIs, a tyk.conf loading them and, with Compose,
+// a docker-compose.yml mounting them. The previous preview is synced, so APIs deleted from
+// the repo go away. It returns the files written, relative to dir.
+func WritePreview(dir string, defs []objects.DBApiDefinition, pols []objects.Policy, opts PreviewOptions) ([]string, error) {
+	host, port, err := net.SplitHostPort(opts.Redis)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis address %q: %v", opts.Redis, err)
+	}
+	redisPort, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis port %q", port)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	c, err := files.NewClient(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// The gateway doesn't serve inactive APIs
+	active := []objects.DBApiDefinition{}
+	for _, def := range defs {
+		if def.APIDefinition != nil && def.Active {
+			active = append(active, def)
+		}
+	}
+	if err := c.Sync(active); err != nil {
+		return nil, err
+	}
+	if err := c.SyncPolicies(pols); err != nil {
+		return nil, err
+	}
+
+	written := []string{}
+	for _, def := range active {
+		written = append(written, files.APIFile(def.APIID))
+	}
+	written = append(written, filepath.FromSlash(files.PoliciesFile))
+
+	write := func(name string, raw []byte) error {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), raw, 0644); err != nil {
+			return fmt.Errorf("Error writing file: %v", err)
+		}
+		written = append(written, name)
+		return nil
+	}
+
+	conf := map[string]interface{}{
+		"listen_port":        opts.Port,
+		"secret":             opts.Secret,
+		"template_path":      previewRoot + "/templates",
+		"middleware_path":    previewRoot + "/middleware",
+		"use_db_app_configs": false,
+		"app_path":           previewRoot + "/" + files.AppsDir,
+		"storage": map[string]interface{}{
+			"type": "redis",
+			"host": host,
+			"port": redisPort,
+		},
+		"policies": map[string]interface{}{
+			"policy_source":      "file",
+			"policy_record_name": previewRoot + "/" + files.PoliciesFile,
+		},
+		"enable_analytics": false,
+		"enable_jsvm":      true,
+		"hash_keys":        true,
+	}
+	raw, err := json.MarshalIndent(conf, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := write(previewConf, raw); err != nil {
+		return nil, err
+	}
+
+	if opts.Compose {
+		// An IP address is a redis running elsewhere, as are hosts that aren't valid service
+		// names: the compose file doesn't run one
+		redisService := host
+		if net.ParseIP(host) != nil || !composeService.MatchString(host) {
+			redisService = ""
+		}
+		if err := write(previewCompose, []byte(previewComposeFile(opts, redisService))); err != nil {
+			return nil, err
+		}
+	}
+
+	return written, nil
+}
+
+// previewComposeFile runs the gateway with the files of the preview mounted, and a redis
+// named after the host the gateway is configured with unless redisHost is empty
+func previewComposeFile(opts PreviewOptions, redisHost string) string {
+	lines := []string{
+		`version: "3"`,
+		`services:`,
+		`  tyk-gateway:`,
+		fmt.Sprintf(`    image: %v`, opts.Image),
+		`    ports:`,
+		fmt.Sprintf(`      - "%v:%v"`, opts.Port, opts.Port),
+		`    volumes:`,
+		fmt.Sprintf(`      - ./%v:%v/%v`, previewConf, previewRoot, previewConf),
+		fmt.Sprintf(`      - ./%v:%v/%v`, files.AppsDir, previewRoot, files.AppsDir),
+		fmt.Sprintf(`      - ./%v:%v/%v`, previewPolicies, previewRoot, previewPolicies),
+	}
+	if redisHost != "" {
+		lines = append(lines,
+			`    depends_on:`,
+			fmt.Sprintf(`      - %v`, redisHost),
+			fmt.Sprintf(`  %v:`, redisHost),
+			`    image: redis:5`,
+		)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,109 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestWritePreview(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Left by a previous preview
+	if err := os.MkdirAll(filepath.Join(dir, "apps"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "apps", "deleted.json"), []byte(`{"api_id": "deleted"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defs := []objects.DBApiDefinition{
+		liveDef("users", "/users/", "", true),
+		liveDef("inactive", "/inactive/", "", false),
+	}
+	mid := bson.NewObjectId()
+	pols := []objects.Policy{{ID: "gold", Name: "Gold", Rate: 100}, {MID: mid, Name: "Silver"}}
+
+	opts := PreviewOptions{Secret: "s3cret", Port: 8181, Redis: "redis:6379", Image: "tykio/tyk-gateway:v2.9.4", Compose: true}
+	written, err := WritePreview(dir, defs, pols, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{filepath.Join("apps", "users.json"), filepath.Join("policies", "policies.json"), "tyk.conf", "docker-compose.yml"}
+	if !reflect.DeepEqual(written, expected) {
+		t.Errorf("expected %v written, got %v", expected, written)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "apps", "deleted.json")); !os.IsNotExist(err) {
+		t.Error("expected the definitions of the previous preview to be removed")
+	}
+
+	api := map[string]interface{}{}
+	readJSON(t, filepath.Join(dir, "apps", "users.json"), &api)
+	if api["api_id"] != "users" {
+		t.Errorf("unexpected definition %v", api)
+	}
+
+	policies := map[string]map[string]interface{}{}
+	readJSON(t, filepath.Join(dir, "policies", "policies.json"), &policies)
+	if len(policies) != 2 || policies["gold"]["rate"] != 100.0 || policies[mid.Hex()]["id"] != mid.Hex() {
+		t.Errorf("unexpected policies %v", policies)
+	}
+	if _, ok := policies["gold"]["_id"]; ok {
+		t.Error("expected the database IDs to be dropped")
+	}
+
+	conf := map[string]interface{}{}
+	readJSON(t, filepath.Join(dir, "tyk.conf"), &conf)
+	storage := conf["storage"].(map[string]interface{})
+	if conf["secret"] != "s3cret" || conf["listen_port"] != 8181.0 || storage["host"] != "redis" || storage["port"] != 6379.0 {
+		t.Errorf("unexpected gateway config %v", conf)
+	}
+
+	compose, err := ioutil.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"image: tykio/tyk-gateway:v2.9.4", `- "8181:8181"`, "- ./apps:/opt/tyk-gateway/apps", "  redis:"} {
+		if !strings.Contains(string(compose), s) {
+			t.Errorf("expected the compose file to contain %q, got:\n%s", s, compose)
+		}
+	}
+
+	// A redis given by its IP runs elsewhere, it is no service of the compose file
+	opts.Redis = "10.0.0.5:6379"
+	if _, err := WritePreview(dir, defs, pols, opts); err != nil {
+		t.Fatal(err)
+	}
+	if compose, err = ioutil.ReadFile(filepath.Join(dir, "docker-compose.yml")); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(compose), "10.0.0.5") || strings.Contains(string(compose), "depends_on") {
+		t.Errorf("expected no redis service for an IP address, got:\n%s", compose)
+	}
+
+	opts.Redis = "redis"
+	if _, err := WritePreview(dir, defs, pols, opts); err == nil {
+		t.Error("expected a redis address without a port to fail")
+	}
+}
+
+func readJSON(t *testing.T, path string, v interface{}) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,208 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// Unit is a product resolved against the fetched definitions and policies
+type Unit struct {
+	Name     string
+	APIs     []objects.DBApiDefinition
+	Policies []objects.Policy
+}
+
+// GroupProducts resolves the products of the spec, it returns the units and the
+// definitions and policies that are not part of any product
+func GroupProducts(products []ProductInfo, defs []objects.DBApiDefinition, pols []objects.Policy) ([]Unit, []objects.DBApiDefinition, []objects.Policy, error) {
+	usedAPIs := map[string]bool{}
+	usedPols := map[string]bool{}
+	units := []Unit{}
+
+	for _, p := range products {
+		u := Unit{Name: p.Name}
+		missing := []string{}
+
+		for _, id := range p.APIs {
+			if usedAPIs[id] {
+				return nil, nil, nil, fmt.Errorf("product %v: API %v is part of more than one product", p.Name, id)
+			}
+			usedAPIs[id] = true
+
+			found := false
+			for _, d := range defs {
+				if d.APIID == id {
+					u.APIs = append(u.APIs, d)
+					found = true
+				}
+			}
+			if !found {
+				missing = append(missing, "API "+id)
+			}
+		}
+
+		for _, id := range p.Policies {
+			if usedPols[id] {
+				return nil, nil, nil, fmt.Errorf("product %v: policy %v is part of more than one product", p.Name, id)
+			}
+			usedPols[id] = true
+
+			found := false
+			for _, pol := range pols {
+				if pol.ID == id {
+					u.Policies = append(u.Policies, pol)
+					found = true
+				}
+			}
+			if !found {
+				missing = append(missing, "policy "+id)
+			}
+		}
+
+		// Products left out entirely by --apis or --policies are skipped, but a unit
+		// can't be published in parts
+		if len(u.APIs) == 0 && len(u.Policies) == 0 {
+			continue
+		}
+		if len(missing) > 0 {
+			return nil, nil, nil, fmt.Errorf("product %v: %v not found or not selected", p.Name, strings.Join(missing, ", "))
+		}
+
+		units = append(units, u)
+	}
+
+	restDefs := []objects.DBApiDefinition{}
+	for _, d := range defs {
+		if !usedAPIs[d.APIID] {
+			restDefs = append(restDefs, d)
+		}
+	}
+
+	restPols := []objects.Policy{}
+	for _, pol := range pols {
+		if pol.ID == "" || !usedPols[pol.ID] {
+			restPols = append(restPols, pol)
+		}
+	}
+
+	return units, restDefs, restPols, nil
+}
+
+// fromRaw decodes an object fetched back from a target with a Fetcher
+func fromRaw(raw map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// transaction records how to undo each change made to the target
+type transaction struct {
+	undo []func() error
+}
+
+func (t *transaction) rollback() error {
+	failed := []string{}
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		if err := t.undo[i](); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+// PublishUnit creates (action CREATE) or updates (action UPDATE) the policies and then the
+// APIs of a unit. If any of them fails, the changes already made are undone: created objects
+// are deleted and updated ones are set back to the version fetched before the update.
+func PublishUnit(p Publisher, u Unit, action PublishAction) error {
+	deleter, canDelete := p.(Deleter)
+	fetcher, canFetch := p.(Fetcher)
+	if action == CREATE && !canDelete {
+		return errors.New("the publisher can not delete objects, so a product can not be rolled back")
+	}
+	if action == UPDATE && !canFetch {
+		return errors.New("the publisher can not fetch objects, so a product can not be rolled back")
+	}
+
+	t := &transaction{}
+	err := func() error {
+		for i := range u.Policies {
+			pol := u.Policies[i]
+			if action == CREATE {
+				id, err := p.CreatePolicy(&pol)
+				if err != nil {
+					return fmt.Errorf("policy %v: %v", pol.Name, err)
+				}
+				t.undo = append(t.undo, func() error { return deleter.DeletePolicy(id) })
+				continue
+			}
+
+			raw, err := fetcher.FetchPolicyRaw(&pol)
+			if err != nil {
+				return fmt.Errorf("policy %v: %v", pol.Name, err)
+			}
+
+			prev := objects.Policy{}
+			if err := fromRaw(raw, &prev); err != nil {
+				return err
+			}
+
+			if err := p.UpdatePolicy(&pol); err != nil {
+				return fmt.Errorf("policy %v: %v", pol.Name, err)
+			}
+			t.undo = append(t.undo, func() error { return p.UpdatePolicy(&prev) })
+		}
+
+		for i := range u.APIs {
+			def := u.APIs[i]
+			if action == CREATE {
+				id, err := p.Create(&def)
+				if err != nil {
+					return fmt.Errorf("API %v: %v", def.Name, err)
+				}
+				t.undo = append(t.undo, func() error { return deleter.DeleteAPI(id) })
+				continue
+			}
+
+			raw, err := fetcher.FetchAPIRaw(&def)
+			if err != nil {
+				return fmt.Errorf("API %v: %v", def.Name, err)
+			}
+
+			prev := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{}}
+			if err := fromRaw(raw, prev.APIDefinition); err != nil {
+				return err
+			}
+			prev.Passthrough = raw
+
+			if err := p.Update(&def); err != nil {
+				return fmt.Errorf("API %v: %v", def.Name, err)
+			}
+			t.undo = append(t.undo, func() error { return p.Update(&prev) })
+		}
+
+		return nil
+	}()
+
+	if err == nil {
+		return nil
+	}
+
+	if rbErr := t.rollback(); rbErr != nil {
+		return fmt.Errorf("%v, rolling back failed: %v", err, rbErr)
+	}
+
+	return fmt.Errorf("%v, rolled back", err)
+}
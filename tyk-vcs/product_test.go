@@ -0,0 +1,90 @@
+package tyk_vcs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// recordingPublisher fails to create the APIs named in fail and records every call
+type recordingPublisher struct {
+	fail  map[string]bool
+	calls []string
+}
+
+func (r *recordingPublisher) Name() string { return "recording" }
+func (r *recordingPublisher) Create(def *objects.DBApiDefinition) (string, error) {
+	if r.fail[def.Name] {
+		return "", errors.New("failed")
+	}
+	r.calls = append(r.calls, "create api "+def.APIID)
+	return def.APIID, nil
+}
+func (r *recordingPublisher) Update(def *objects.DBApiDefinition) error { return nil }
+func (r *recordingPublisher) Sync(defs []objects.DBApiDefinition) error { return nil }
+func (r *recordingPublisher) UpdatePolicy(pol *objects.Policy) error    { return nil }
+func (r *recordingPublisher) SyncPolicies(pols []objects.Policy) error  { return nil }
+func (r *recordingPublisher) Reload() error                             { return nil }
+func (r *recordingPublisher) CreatePolicy(pol *objects.Policy) (string, error) {
+	r.calls = append(r.calls, "create policy "+pol.ID)
+	return pol.ID, nil
+}
+func (r *recordingPublisher) DeleteAPI(id string) error {
+	r.calls = append(r.calls, "delete api "+id)
+	return nil
+}
+func (r *recordingPublisher) DeletePolicy(id string) error {
+	r.calls = append(r.calls, "delete policy "+id)
+	return nil
+}
+
+func testAPI(id string) objects.DBApiDefinition {
+	return objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: id, Name: id}}
+}
+
+func TestGroupProducts(t *testing.T) {
+	defs := []objects.DBApiDefinition{testAPI("a1"), testAPI("a2")}
+	pols := []objects.Policy{{ID: "p1"}, {ID: "p2"}}
+
+	units, restDefs, restPols, err := GroupProducts([]ProductInfo{{Name: "payments", APIs: []string{"a1"}, Policies: []string{"p1"}}}, defs, pols)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(units) != 1 || len(units[0].APIs) != 1 || len(units[0].Policies) != 1 {
+		t.Fatalf("unexpected units: %+v", units)
+	}
+	if len(restDefs) != 1 || restDefs[0].APIID != "a2" || len(restPols) != 1 || restPols[0].ID != "p2" {
+		t.Errorf("unexpected remaining objects: %v %v", restDefs, restPols)
+	}
+
+	if _, _, _, err := GroupProducts([]ProductInfo{{Name: "payments", APIs: []string{"a1"}, Policies: []string{"p3"}}}, defs, pols); err == nil {
+		t.Error("expected an error for a partly selected product")
+	}
+}
+
+func TestPublishUnitRollsBack(t *testing.T) {
+	p := &recordingPublisher{fail: map[string]bool{"a2": true}}
+	u := Unit{
+		Name:     "payments",
+		APIs:     []objects.DBApiDefinition{testAPI("a1"), testAPI("a2")},
+		Policies: []objects.Policy{{ID: "p1"}},
+	}
+
+	if err := PublishUnit(p, u, CREATE); err == nil {
+		t.Fatal("expected the unit to fail")
+	}
+
+	expected := []string{"create policy p1", "create api a1", "delete api a1", "delete policy p1"}
+	if len(p.calls) != len(expected) {
+		t.Fatalf("expected calls %v, got %v", expected, p.calls)
+	}
+	for i := range expected {
+		if p.calls[i] != expected[i] {
+			t.Errorf("expected calls %v, got %v", expected, p.calls)
+			break
+		}
+	}
+}
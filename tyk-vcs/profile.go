@@ -0,0 +1,164 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-patch"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// Profile returns the named target profile, an empty name returns an empty profile
+func (ts *TykSourceSpec) Profile(name string) (*TargetProfile, error) {
+	if name == "" {
+		return &TargetProfile{}, nil
+	}
+
+	p, ok := ts.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %v is not defined in the spec file", name)
+	}
+
+	return &p, nil
+}
+
+func mergeStrings(existing, extra []string) []string {
+	seen := map[string]bool{}
+	for _, s := range existing {
+		seen[s] = true
+	}
+
+	for _, s := range extra {
+		if !seen[s] {
+			existing = append(existing, s)
+			seen[s] = true
+		}
+	}
+
+	return existing
+}
+
+// removeMatching returns the strings not matching any of patterns, see path.Match
+func removeMatching(existing, patterns []string) []string {
+	if len(patterns) == 0 {
+		return existing
+	}
+
+	kept := []string{}
+	for _, s := range existing {
+		remove := false
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, s); ok || p == s {
+				remove = true
+				break
+			}
+		}
+
+		if !remove {
+			kept = append(kept, s)
+		}
+	}
+
+	return kept
+}
+
+// mapHost maps a host, with or without a port, with the domain map m
+func mapHost(m map[string]string, host string) string {
+	if to, ok := m[host]; ok {
+		return to
+	}
+
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if to, ok := m[h]; ok {
+			return net.JoinHostPort(to, port)
+		}
+	}
+
+	return host
+}
+
+// mapOrigin maps the host of a CORS origin, like https://api.dev.example.com
+func mapOrigin(m map[string]string, origin string) string {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return mapHost(m, origin)
+	}
+
+	u.Host = mapHost(m, u.Host)
+	return u.String()
+}
+
+// Apply merges the profile defaults into a definition
+func (tp *TargetProfile) Apply(def *objects.DBApiDefinition) {
+	if def.APIDefinition == nil {
+		return
+	}
+
+	def.Tags = mergeStrings(removeMatching(def.Tags, tp.RemoveTags), tp.Tags)
+	def.TagHeaders = mergeStrings(def.TagHeaders, tp.TagHeaders)
+
+	if tp.EnableDetailedRecording != nil {
+		def.EnableDetailedRecording = *tp.EnableDetailedRecording
+	}
+
+	if tp.DoNotTrack != nil {
+		def.DoNotTrack = *tp.DoNotTrack
+	}
+
+	if tp.ExpireAnalyticsAfter != 0 {
+		def.ExpireAnalyticsAfter = tp.ExpireAnalyticsAfter
+	}
+
+	if len(tp.DomainMap) > 0 {
+		def.Domain = mapHost(tp.DomainMap, def.Domain)
+		for i, origin := range def.CORS.AllowedOrigins {
+			def.CORS.AllowedOrigins[i] = mapOrigin(tp.DomainMap, origin)
+		}
+	}
+
+	if tp.TagsDisabled != nil {
+		def.SetRaw("tags_disabled", *tp.TagsDisabled)
+	}
+}
+
+// ApplyPatches applies the per-object patches declared for the profile, each object gets
+// those of the file entry it was read from
+func (ts *TykSourceSpec) ApplyPatches(profile string, defs []objects.DBApiDefinition, pols []objects.Policy) error {
+	if profile == "" {
+		return nil
+	}
+
+	for i := range defs {
+		info, ok := ts.apiInfo(&defs[i])
+		ops := info.Patches[profile]
+		if !ok || len(ops) == 0 {
+			continue
+		}
+
+		patched := apidef.APIDefinition{}
+		if err := tyk_patch.ApplyTo(defs[i].APIDefinition, &patched, ops); err != nil {
+			return fmt.Errorf("%v: %v", info.File, err)
+		}
+		defs[i].APIDefinition = &patched
+	}
+
+	for i := range pols {
+		info, ok := ts.policyInfo(&pols[i])
+		ops := info.Patches[profile]
+		if !ok || len(ops) == 0 {
+			continue
+		}
+
+		patched := objects.Policy{}
+		if err := tyk_patch.ApplyTo(pols[i], &patched, ops); err != nil {
+			return fmt.Errorf("%v: %v", info.File, err)
+		}
+		patched.File = pols[i].File
+		pols[i] = patched
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-patch"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestProfile(t *testing.T) {
+	spec := &TykSourceSpec{}
+	if err := json.Unmarshal([]byte(`{"profiles": {"prod": {"tags": ["prod"], "do_not_track": true}}}`), spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if p, err := spec.Profile(""); err != nil || !reflect.DeepEqual(p, &TargetProfile{}) {
+		t.Errorf("expected an empty profile without a name, got %+v %v", p, err)
+	}
+	if p, err := spec.Profile("prod"); err != nil || !reflect.DeepEqual(p.Tags, []string{"prod"}) || p.DoNotTrack == nil || !*p.DoNotTrack {
+		t.Errorf("expected the prod profile, got %+v %v", p, err)
+	}
+	if _, err := spec.Profile("staging"); err == nil {
+		t.Error("expected an unknown profile to fail")
+	}
+
+	// Applying a profile doesn't change the profile of the spec
+	p, _ := spec.Profile("prod")
+	p.Tags = append(p.Tags, "changed")
+	if p, _ := spec.Profile("prod"); len(p.Tags) != 1 {
+		t.Errorf("expected the spec profile unchanged, got %v", p.Tags)
+	}
+}
+
+func TestApplyAnalyticsDefaults(t *testing.T) {
+	on, off := true, false
+	tp := &TargetProfile{
+		Tags:                    []string{"prod", "edge"},
+		TagHeaders:              []string{"X-Team"},
+		EnableDetailedRecording: &on,
+		DoNotTrack:              &off,
+		ExpireAnalyticsAfter:    3600,
+	}
+
+	def := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{
+		Tags:                 []string{"edge", "payments"},
+		TagHeaders:           []string{"X-Team"},
+		DoNotTrack:           true,
+		ExpireAnalyticsAfter: 60,
+	}}
+	tp.Apply(&def)
+
+	if want := []string{"edge", "payments", "prod"}; !reflect.DeepEqual(def.Tags, want) {
+		t.Errorf("expected tags %v, got %v", want, def.Tags)
+	}
+	if want := []string{"X-Team"}; !reflect.DeepEqual(def.TagHeaders, want) {
+		t.Errorf("expected tag headers %v, got %v", want, def.TagHeaders)
+	}
+	if !def.EnableDetailedRecording || def.DoNotTrack || def.ExpireAnalyticsAfter != 3600 {
+		t.Errorf("expected the analytics settings of the profile, got %+v", def.APIDefinition)
+	}
+
+	// Settings the profile leaves unset keep the values of the definition
+	def = objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{DoNotTrack: true, ExpireAnalyticsAfter: 60}}
+	(&TargetProfile{}).Apply(&def)
+	if !def.DoNotTrack || def.ExpireAnalyticsAfter != 60 || def.EnableDetailedRecording {
+		t.Errorf("expected the definition unchanged, got %+v", def.APIDefinition)
+	}
+
+	// Objects without an API definition are left alone
+	tp.Apply(&objects.DBApiDefinition{})
+}
+
+func TestApplySegmentTags(t *testing.T) {
+	disabled := false
+	tp := &TargetProfile{Tags: []string{"edge-eu", "prod"}, RemoveTags: []string{"edge-*"}, TagsDisabled: &disabled}
+
+	def := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{Tags: []string{"edge-us", "payments", "prod"}}}
+	tp.Apply(&def)
+
+	if want := []string{"payments", "prod", "edge-eu"}; !reflect.DeepEqual(def.Tags, want) {
+		t.Errorf("expected tags %v, got %v", want, def.Tags)
+	}
+
+	payload, err := def.DefinitionPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := payload.(map[string]interface{})["tags_disabled"]; !ok || v != false {
+		t.Errorf("expected tags_disabled passed through, got %v", v)
+	}
+
+	def.DropRaw()
+	payload, err = def.DefinitionPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := payload.(map[string]interface{}); ok {
+		t.Error("expected tags_disabled dropped without passthrough")
+	}
+}
+
+func TestApplyDomainMap(t *testing.T) {
+	tp := &TargetProfile{DomainMap: map[string]string{"api.dev.example.com": "api.example.com"}}
+
+	def := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{Domain: "api.dev.example.com:8443"}}
+	def.CORS.AllowedOrigins = []string{"https://api.dev.example.com", "http://api.dev.example.com:8080", "*", "https://other.example.com"}
+	tp.Apply(&def)
+
+	if def.Domain != "api.example.com:8443" {
+		t.Errorf("expected the domain mapped, got %v", def.Domain)
+	}
+	want := []string{"https://api.example.com", "http://api.example.com:8080", "*", "https://other.example.com"}
+	if !reflect.DeepEqual(def.CORS.AllowedOrigins, want) {
+		t.Errorf("expected origins %v, got %v", want, def.CORS.AllowedOrigins)
+	}
+}
+
+func TestApplyPatches(t *testing.T) {
+	listenPath := func(path string) map[string][]tyk_patch.Operation {
+		return map[string][]tyk_patch.Operation{"prod": {{Op: "replace", Path: "/proxy/listen_path", Value: path}}}
+	}
+	spec := &TykSourceSpec{
+		Files: []APIInfo{
+			{File: "./a.json", Patches: listenPath("/a/")},
+			{File: "b.json", Patches: listenPath("/b/")},
+			{File: "grpc.pb", APIID: "g1", Patches: listenPath("/g/")},
+		},
+		Policies: []PolicyInfo{
+			{File: "p1.json"},
+			{File: "p2.json", Patches: map[string][]tyk_patch.Operation{"prod": {{Op: "replace", Path: "/rate", Value: 10}}}},
+		},
+	}
+
+	// Not in the order of the spec, and with definitions not read from a listed file
+	defs := []objects.DBApiDefinition{
+		{APIDefinition: &apidef.APIDefinition{APIID: "b"}, File: "b.json"},
+		{APIDefinition: &apidef.APIDefinition{APIID: "g1"}},
+		{APIDefinition: &apidef.APIDefinition{APIID: "other"}},
+		{APIDefinition: &apidef.APIDefinition{APIID: "a"}, File: "a.json"},
+	}
+	pols := []objects.Policy{{ID: "p2", File: "p2.json"}}
+
+	if err := spec.ApplyPatches("prod", defs, pols); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{"/b/", "/g/", "", "/a/"} {
+		if defs[i].Proxy.ListenPath != want {
+			t.Errorf("%v: expected listen path %q, got %q", defs[i].APIID, want, defs[i].Proxy.ListenPath)
+		}
+	}
+	if pols[0].Rate != 10 || pols[0].File != "p2.json" {
+		t.Errorf("expected the patch of p2.json applied, got %+v", pols[0])
+	}
+}
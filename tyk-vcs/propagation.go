@@ -0,0 +1,99 @@
+package tyk_vcs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// PropagationWaiter waits for the gateways of a dashboard to load the definitions a sync or
+// publish wrote, by polling the checksum of the definitions each node reports
+type PropagationWaiter struct {
+	Fetch    func() ([]objects.GatewayNode, error)
+	Timeout  time.Duration
+	Interval time.Duration
+
+	before map[string]string
+}
+
+// Start records the checksums of the nodes before the changes are made
+func (w *PropagationWaiter) Start() error {
+	nodes, err := w.Fetch()
+	if err != nil {
+		return err
+	}
+
+	if len(nodes) == 0 {
+		return errors.New("no gateway is registered with the dashboard")
+	}
+
+	w.before = map[string]string{}
+	for _, n := range nodes {
+		if n.Checksum == "" {
+			return fmt.Errorf("gateway %v does not report the checksum of its definitions", n.Hostname)
+		}
+		w.before[n.NodeID] = n.Checksum
+	}
+
+	return nil
+}
+
+// pending returns the nodes that haven't loaded the new definitions: all nodes must report
+// the same checksum and, if the run changed anything, the nodes seen by Start a new one
+func (w *PropagationWaiter) pending(nodes []objects.GatewayNode, changed bool) []string {
+	counts := map[string]int{}
+	for _, n := range nodes {
+		counts[n.Checksum]++
+	}
+
+	// The checksum most nodes agree on is taken as the new one
+	target := ""
+	for sum, count := range counts {
+		if count > counts[target] || (count == counts[target] && sum > target) {
+			target = sum
+		}
+	}
+
+	waiting := []string{}
+	for _, n := range nodes {
+		prev, seen := w.before[n.NodeID]
+		if n.Checksum == "" || n.Checksum != target || (changed && seen && n.Checksum == prev) {
+			waiting = append(waiting, n.Hostname)
+		}
+	}
+
+	return waiting
+}
+
+// Wait polls the nodes until all of them loaded the new definitions or the timeout passes,
+// changed tells whether the run made any changes
+func (w *PropagationWaiter) Wait(changed bool) error {
+	if w.Interval == 0 {
+		w.Interval = 2 * time.Second
+	}
+
+	deadline := time.Now().Add(w.Timeout)
+	for {
+		nodes, err := w.Fetch()
+		if err != nil {
+			return err
+		}
+
+		waiting := w.pending(nodes, changed)
+		if len(nodes) > 0 && len(waiting) == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			if len(nodes) == 0 {
+				return errors.New("no gateway is registered with the dashboard")
+			}
+			return fmt.Errorf("%v of %v gateways did not load the new definitions in %v: %v",
+				len(waiting), len(nodes), w.Timeout, strings.Join(waiting, ", "))
+		}
+		time.Sleep(w.Interval)
+	}
+}
@@ -0,0 +1,70 @@
+package tyk_vcs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+func nodes(sums ...string) []objects.GatewayNode {
+	out := []objects.GatewayNode{}
+	for i, sum := range sums {
+		id := string(rune('a' + i))
+		out = append(out, objects.GatewayNode{NodeID: id, Hostname: "gw-" + id, Checksum: sum})
+	}
+
+	return out
+}
+
+func TestPropagationWaiter(t *testing.T) {
+	polls := [][]objects.GatewayNode{
+		nodes("old", "old", "old"),
+		nodes("new", "old", "old"),
+		nodes("new", "new", "old"),
+		nodes("new", "new", "new"),
+	}
+
+	i := 0
+	w := &PropagationWaiter{
+		Fetch: func() ([]objects.GatewayNode, error) {
+			n := polls[i]
+			if i < len(polls)-1 {
+				i++
+			}
+			return n, nil
+		},
+		Timeout:  time.Second,
+		Interval: time.Millisecond,
+	}
+
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Wait(true); err != nil {
+		t.Fatal(err)
+	}
+	if i != len(polls)-1 {
+		t.Fatalf("returned before all nodes loaded the changes, after %v polls", i)
+	}
+
+	// Without changes the nodes only have to agree
+	w.before = map[string]string{"a": "new", "b": "new", "c": "new"}
+	if got := w.pending(nodes("new", "new", "new"), false); len(got) != 0 {
+		t.Fatalf("unexpected pending nodes: %v", got)
+	}
+	if got := w.pending(nodes("new", "new", "new"), true); len(got) != 3 {
+		t.Fatalf("expected all nodes to be pending: %v", got)
+	}
+
+	w.Timeout = 10 * time.Millisecond
+	w.Fetch = func() ([]objects.GatewayNode, error) { return nodes("new", "other"), nil }
+	if err := w.Wait(false); err == nil {
+		t.Fatal("expected a timeout when the nodes disagree")
+	}
+
+	w.Fetch = func() ([]objects.GatewayNode, error) { return nodes("new", ""), nil }
+	if err := w.Start(); err == nil {
+		t.Fatal("expected an error for nodes without a checksum")
+	}
+}
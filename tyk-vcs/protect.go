@@ -0,0 +1,58 @@
+package tyk_vcs
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// ProtectInfo lists APIs on the target that sync must never update or delete, by API ID or
+// by a tag set on the target, e.g. the portal API or APIs that are managed by hand
+type ProtectInfo struct {
+	APIs []string `json:"apis,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+func (p *ProtectInfo) protects(item objects.SyncItem) bool {
+	for _, id := range p.APIs {
+		if item.ID == id {
+			return true
+		}
+	}
+
+	for _, tag := range p.Tags {
+		for _, t := range item.Tags {
+			if t == tag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p *ProtectInfo) keep(action string, items []objects.SyncItem) []objects.SyncItem {
+	kept := []objects.SyncItem{}
+	for _, item := range items {
+		if p.protects(item) {
+			fmt.Printf("--> Protected, not going to %v: %v (%v)\n", action, item.Name, item.ID)
+			continue
+		}
+		kept = append(kept, item)
+	}
+
+	return kept
+}
+
+// Check can be used as the plan check of a client, it removes the protected APIs from the
+// updates and deletes of the plan. p may be nil.
+func (p *ProtectInfo) Check(plan *objects.SyncPlan) error {
+	if p == nil || plan.Kind != "APIs" {
+		return nil
+	}
+
+	plan.Update = p.keep("update", plan.Update)
+	plan.Delete = p.keep("delete", plan.Delete)
+
+	return nil
+}
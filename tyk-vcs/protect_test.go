@@ -0,0 +1,49 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+func TestProtectCheck(t *testing.T) {
+	protect := &ProtectInfo{APIs: []string{"portal"}, Tags: []string{"manual"}}
+
+	plan := &objects.SyncPlan{
+		Kind:   "APIs",
+		Create: []objects.SyncItem{{ID: "portal", Index: 0}},
+		Update: []objects.SyncItem{
+			{ID: "portal", Index: 0},
+			{ID: "users", Index: 1},
+		},
+		Delete: []objects.SyncItem{
+			{ID: "legacy", Tags: []string{"internal", "manual"}, Index: 0},
+			{ID: "old", Tags: []string{"internal"}, Index: 1},
+		},
+	}
+
+	if err := protect.Check(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Create) != 1 {
+		t.Fatalf("creates should not be protected: %+v", plan.Create)
+	}
+	if len(plan.Update) != 1 || plan.Update[0].ID != "users" {
+		t.Fatalf("unexpected updates: %+v", plan.Update)
+	}
+	if len(plan.Delete) != 1 || plan.Delete[0].ID != "old" {
+		t.Fatalf("unexpected deletes: %+v", plan.Delete)
+	}
+
+	// Policies are not protected, and no protection is fine
+	pols := &objects.SyncPlan{Kind: "policies", Delete: []objects.SyncItem{{ID: "portal"}}}
+	if err := protect.Check(pols); err != nil || len(pols.Delete) != 1 {
+		t.Fatalf("policies should be left alone: %+v %v", pols.Delete, err)
+	}
+
+	var none *ProtectInfo
+	if err := none.Check(plan); err != nil || len(plan.Delete) != 1 {
+		t.Fatalf("nil protection changed the plan: %v", err)
+	}
+}
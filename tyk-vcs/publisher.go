@@ -14,3 +14,56 @@ type Publisher interface {
 	SyncPolicies([]objects.Policy) error
 	Reload() error
 }
+
+// CertificatePublisher is implemented by publishers that can upload certificates
+type CertificatePublisher interface {
+	CreateCertificate(cert []byte) (string, error)
+}
+
+// KeyPublisher is implemented by publishers that can create keys
+type KeyPublisher interface {
+	CreateKey(key *objects.Key) error
+}
+
+// OAuthClientPublisher is implemented by publishers that can register OAuth clients
+type OAuthClientPublisher interface {
+	FetchOAuthClients(apiID string) ([]objects.OAuthClient, error)
+	CreateOAuthClient(client *objects.OAuthClient) error
+}
+
+// Fetcher is implemented by publishers that can read back what they published, as raw
+// JSON so that fields unknown to the vendored apidef are retained
+type Fetcher interface {
+	FetchAPIRaw(apiDef *objects.DBApiDefinition) (map[string]interface{}, error)
+	FetchPolicyRaw(pol *objects.Policy) (map[string]interface{}, error)
+}
+
+// BatchFetcher is implemented by publishers that can read back many objects with one call
+// per kind rather than one per object, see Fetcher. The results are in the order of the
+// objects given, nil for those the target doesn't have.
+type BatchFetcher interface {
+	FetchAPIsRaw(defs []objects.DBApiDefinition) ([]map[string]interface{}, error)
+	FetchPoliciesRaw(pols []objects.Policy) ([]map[string]interface{}, error)
+}
+
+// VersionReporter is implemented by publishers that can tell the version of their target
+type VersionReporter interface {
+	TargetVersion() (string, error)
+}
+
+// Deleter is implemented by publishers that can remove what they created, by the ID
+// Create or CreatePolicy returned
+type Deleter interface {
+	DeleteAPI(id string) error
+	DeletePolicy(id string) error
+}
+
+// APILister is implemented by publishers that can list the APIs of their target
+type APILister interface {
+	FetchAPIs() ([]objects.DBApiDefinition, error)
+}
+
+// PolicyLister is implemented by publishers that can list the policies of their target
+type PolicyLister interface {
+	FetchPolicies() ([]objects.Policy, error)
+}
@@ -0,0 +1,203 @@
+package tyk_vcs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-patch"
+)
+
+// DefaultRedactions are the JSON pointers (relative to the API definition, * matches any key)
+// of fields that carry secrets
+var DefaultRedactions = []string{
+	"/jwt_source",
+	"/request_signing/secret",
+	"/auth/signature/secret",
+	"/auth_configs/*/signature/secret",
+	"/upstream_certificates/*",
+	"/pinned_public_keys/*",
+	"/version_data/versions/*/global_headers/Authorization",
+	"/version_data/versions/*/extended_paths/transform_headers/*/add_headers/Authorization",
+}
+
+var (
+	placeholderName  = regexp.MustCompile(`[^A-Z0-9]+`)
+	placeholderMatch = regexp.MustCompile(`\$\{(TYK_SECRET_[A-Z0-9_]+)\}`)
+)
+
+// Redactor replaces secrets in API definitions with ${TYK_SECRET_...} placeholders, which
+// are resolved from the environment again when the definitions are published
+type Redactor struct {
+	Paths []string
+}
+
+// placeholder names the secret at the pointer tokens of an API. Case and separators are lost
+// in the readable part, so it ends with a hash of the API ID and the full pointer, which
+// keeps e.g. the upstream certificates of a.example.com and a-example.com apart.
+func placeholder(apiID string, tokens []string) string {
+	pointer := ""
+	for _, t := range tokens {
+		pointer += "/" + strings.Replace(strings.Replace(t, "~", "~0", -1), "/", "~1", -1)
+	}
+	sum := sha256.Sum256([]byte(apiID + pointer))
+
+	name := strings.ToUpper(strings.Join(append([]string{"TYK_SECRET", apiID}, tokens...), "_"))
+	name = strings.Trim(placeholderName.ReplaceAllString(name, "_"), "_")
+	return name + "_" + strings.ToUpper(hex.EncodeToString(sum[:4]))
+}
+
+// redact walks the pointer tokens, * matches every key or index, and replaces the
+// non-empty strings it finds. seen holds the concrete tokens walked so far.
+func redact(node interface{}, tokens []string, seen []string, apiID string, found map[string]bool) interface{} {
+	if len(tokens) == 0 {
+		s, ok := node.(string)
+		if !ok || s == "" || placeholderMatch.MatchString(s) {
+			return node
+		}
+
+		name := placeholder(apiID, seen)
+		found[name] = true
+		return "${" + name + "}"
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			if tokens[0] == "*" || tokens[0] == k {
+				n[k] = redact(v, tokens[1:], append(seen, k), apiID, found)
+			}
+		}
+	case []interface{}:
+		for i, v := range n {
+			k := fmt.Sprintf("%v", i)
+			if tokens[0] == "*" || tokens[0] == k {
+				n[i] = redact(v, tokens[1:], append(seen, k), apiID, found)
+			}
+		}
+	}
+
+	return node
+}
+
+// RedactAPI replaces the secrets in the definition and returns the placeholder names used.
+// The passthrough fields, sent to targets newer than the vendored apidef, are redacted too.
+func (r *Redactor) RedactAPI(def *objects.DBApiDefinition) ([]string, error) {
+	raw, err := json.Marshal(def.APIDefinition)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	for _, p := range r.Paths {
+		tokens, err := tyk_patch.ParsePointer(p)
+		if err != nil {
+			return nil, err
+		}
+		doc = redact(doc, tokens, []string{}, def.APIID, found)
+		if def.Passthrough != nil {
+			redact(def.Passthrough, tokens, []string{}, def.APIID, found)
+		}
+	}
+
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	if raw, err = json.Marshal(doc); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(raw, def.APIDefinition); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// resolvePlaceholders replaces the placeholders in raw JSON with the values returned by
+// lookup, adding the names without one to missing
+func resolvePlaceholders(raw []byte, lookup func(string) (string, bool), missing map[string]bool) []byte {
+	return placeholderMatch.ReplaceAllFunc(raw, func(m []byte) []byte {
+		name := string(placeholderMatch.FindSubmatch(m)[1])
+		val, ok := lookup(name)
+		if !ok {
+			missing[name] = true
+			return m
+		}
+
+		// The placeholder sits inside a JSON string, so the value has to be escaped
+		escaped, _ := json.Marshal(val)
+		return escaped[1 : len(escaped)-1]
+	})
+}
+
+// ResolveSecrets replaces ${TYK_SECRET_...} placeholders in the definitions, their passthrough
+// fields included, with the values returned by lookup (usually os.LookupEnv), it fails if any
+// of them has no value
+func ResolveSecrets(defs []objects.DBApiDefinition, lookup func(string) (string, bool)) error {
+	missing := map[string]bool{}
+
+	for i := range defs {
+		raw, err := json.Marshal(defs[i].APIDefinition)
+		if err != nil {
+			return err
+		}
+
+		if placeholderMatch.Match(raw) {
+			if err := json.Unmarshal(resolvePlaceholders(raw, lookup, missing), defs[i].APIDefinition); err != nil {
+				return err
+			}
+		}
+
+		if defs[i].Passthrough == nil {
+			continue
+		}
+		if raw, err = json.Marshal(defs[i].Passthrough); err != nil {
+			return err
+		}
+		if !placeholderMatch.Match(raw) {
+			continue
+		}
+
+		resolved := map[string]interface{}{}
+		if err := json.Unmarshal(resolvePlaceholders(raw, lookup, missing), &resolved); err != nil {
+			return err
+		}
+		// Replaced in place, the definitions may be copies sharing the map
+		for k := range defs[i].Passthrough {
+			delete(defs[i].Passthrough, k)
+		}
+		for k, v := range resolved {
+			defs[i].Passthrough[k] = v
+		}
+	}
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return fmt.Errorf("secrets referenced by the definitions are not set: %v", strings.Join(names, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestRedactAndResolve(t *testing.T) {
+	def := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: "abc123"}}
+	def.RequestSigning.Secret = "s3cr\"et"
+	def.UpstreamCertificates = map[string]string{"*.example.com": "cert-id"}
+	def.VersionData.Versions = map[string]apidef.VersionInfo{
+		"Default": {Name: "Default", GlobalHeaders: map[string]string{"Authorization": "Basic dXNlcjpwYXNz", "X-Other": "keep"}},
+	}
+
+	r := &Redactor{Paths: DefaultRedactions}
+	names, err := r.RedactAPI(&def)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 3 {
+		t.Fatalf("Expected 3 redacted fields, got: %v", names)
+	}
+
+	signing := placeholder("abc123", []string{"request_signing", "secret"})
+	if !strings.HasPrefix(signing, "TYK_SECRET_ABC123_REQUEST_SIGNING_SECRET_") || def.RequestSigning.Secret != "${"+signing+"}" {
+		t.Fatalf("Signing secret not redacted: %v", def.RequestSigning.Secret)
+	}
+
+	if def.VersionData.Versions["Default"].GlobalHeaders["X-Other"] != "keep" {
+		t.Fatal("Unrelated header was changed")
+	}
+
+	env := map[string]string{
+		signing: "s3cr\"et",
+		placeholder("abc123", []string{"upstream_certificates", "*.example.com"}): "cert-id",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	defs := []objects.DBApiDefinition{def}
+	if err := ResolveSecrets(defs, lookup); err == nil {
+		t.Fatal("Expected an error for the unset Authorization header secret")
+	}
+
+	env[placeholder("abc123", []string{"version_data", "versions", "Default", "global_headers", "Authorization"})] = "Basic dXNlcjpwYXNz"
+	if err := ResolveSecrets(defs, lookup); err != nil {
+		t.Fatal(err)
+	}
+
+	if defs[0].RequestSigning.Secret != "s3cr\"et" || defs[0].UpstreamCertificates["*.example.com"] != "cert-id" {
+		t.Fatalf("Secrets were not restored: %+v", defs[0].RequestSigning)
+	}
+}
+
+func TestRedactPlaceholdersDontCollide(t *testing.T) {
+	def := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: "abc123"}}
+	def.UpstreamCertificates = map[string]string{"a.example.com": "cert-1", "a-example.com": "cert-2", "A.EXAMPLE.COM": "cert-3"}
+
+	names, err := (&Redactor{Paths: DefaultRedactions}).RedactAPI(&def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 3 {
+		t.Fatalf("expected a placeholder per certificate, got %v", names)
+	}
+
+	env := map[string]string{}
+	for host, ref := range def.UpstreamCertificates {
+		env[strings.Trim(ref, "${}")] = host
+	}
+	if err := ResolveSecrets([]objects.DBApiDefinition{def}, func(name string) (string, bool) { v, ok := env[name]; return v, ok }); err != nil {
+		t.Fatal(err)
+	}
+	for host, v := range def.UpstreamCertificates {
+		if v != host {
+			t.Errorf("expected %v resolved to its own secret, got %v", host, v)
+		}
+	}
+}
+
+func TestRedactPassthroughSecrets(t *testing.T) {
+	def := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: "abc123"}}
+	def.SetRaw("upstream_auth", map[string]interface{}{
+		"basic_auth": map[string]interface{}{"username": "user", "password": "hunter2"},
+	})
+
+	names, err := (&Redactor{Paths: []string{"/upstream_auth/basic_auth/password"}}).RedactAPI(&def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 {
+		t.Fatalf("expected the passthrough password redacted, got %v", names)
+	}
+
+	dumped, err := json.Marshal(&def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(dumped), "hunter2") || !strings.Contains(string(dumped), "${"+names[0]+"}") {
+		t.Fatalf("password left in the dumped definition: %s", dumped)
+	}
+
+	// The published definitions are copies, they share the passthrough map
+	if err := ResolveSecrets([]objects.DBApiDefinition{def}, func(name string) (string, bool) { return "hunter2", name == names[0] }); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := json.Marshal(&def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(payload), `"password":"hunter2"`) || !strings.Contains(string(payload), `"username":"user"`) {
+		t.Fatalf("password not resolved in the payload: %s", payload)
+	}
+}
@@ -0,0 +1,417 @@
+package tyk_vcs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+const (
+	// DefaultReleasesURL lists the releases of tyk-sync
+	DefaultReleasesURL = "https://api.github.com/repos/TykTechnologies/tyk-sync/releases"
+
+	// ChecksumsAsset lists the SHA-256 of the archives of a release, ChecksumsAsset plus
+	// SignatureSuffix is its detached OpenPGP signature
+	ChecksumsAsset  = "checksums.txt"
+	SignatureSuffix = ".sig"
+
+	ChannelStable     = "stable"
+	ChannelPrerelease = "prerelease"
+
+	// maxReleaseAsset caps the size of the release assets downloaded
+	maxReleaseAsset = 200 << 20
+)
+
+// Release is a release as listed by the GitHub API
+type Release struct {
+	Tag        string         `json:"tag_name"`
+	Draft      bool           `json:"draft"`
+	Prerelease bool           `json:"prerelease"`
+	Assets     []ReleaseAsset `json:"assets"`
+}
+
+type ReleaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Version is the tag of the release without its leading v
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+func (r *Release) asset(name string) (*ReleaseAsset, error) {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("release %v has no %v", r.Tag, name)
+}
+
+// Updater finds, downloads and verifies the releases of the binary. A release is only
+// trusted if its checksums are signed by one of the Keys and its archive matches them.
+type Updater struct {
+	// ReleasesURL defaults to DefaultReleasesURL
+	ReleasesURL string
+	// Channel is stable, the default, for releases only, or prerelease to also get the
+	// release candidates
+	Channel string
+	Keys    openpgp.EntityList
+	// Binary is the name of the executable in the archives, tyk-sync by default
+	Binary string
+	// GOOS and GOARCH are the platform of the binary downloaded, the running one by default
+	GOOS   string
+	GOARCH string
+	Client *http.Client
+}
+
+func (u *Updater) client() *http.Client {
+	if u.Client != nil {
+		return u.Client
+	}
+	return http.DefaultClient
+}
+
+func (u *Updater) get(url string) ([]byte, error) {
+	resp, err := u.client().Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %v: %v", url, resp.Status)
+	}
+
+	raw, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxReleaseAsset+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > maxReleaseAsset {
+		return nil, fmt.Errorf("GET %v: larger than %v bytes", url, maxReleaseAsset)
+	}
+
+	return raw, nil
+}
+
+// Latest returns the newest release of the channel, the releases are listed newest first
+func (u *Updater) Latest() (*Release, error) {
+	switch u.Channel {
+	case "", ChannelStable, ChannelPrerelease:
+	default:
+		return nil, fmt.Errorf("unknown release channel %q, must be %v or %v", u.Channel, ChannelStable, ChannelPrerelease)
+	}
+
+	releasesURL := u.ReleasesURL
+	if releasesURL == "" {
+		releasesURL = DefaultReleasesURL
+	}
+
+	raw, err := u.get(releasesURL)
+	if err != nil {
+		return nil, err
+	}
+
+	releases := []Release{}
+	if err := json.Unmarshal(raw, &releases); err != nil {
+		return nil, fmt.Errorf("couldn't read the releases: %v", err)
+	}
+
+	for i := range releases {
+		r := &releases[i]
+		if r.Draft || (r.Prerelease && u.Channel != ChannelPrerelease) {
+			continue
+		}
+		return r, nil
+	}
+
+	return nil, fmt.Errorf("no release in the %v channel", u.channel())
+}
+
+func (u *Updater) channel() string {
+	if u.Channel == "" {
+		return ChannelStable
+	}
+	return u.Channel
+}
+
+func (u *Updater) binary() string {
+	name := u.Binary
+	if name == "" {
+		name = "tyk-sync"
+	}
+	if u.platform() == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+func (u *Updater) platform() string {
+	if u.GOOS != "" {
+		return u.GOOS
+	}
+	return runtime.GOOS
+}
+
+func (u *Updater) arch() string {
+	if u.GOARCH != "" {
+		return u.GOARCH
+	}
+	return runtime.GOARCH
+}
+
+// ArchiveName is the name goreleaser gives the archive of the platform, the OS and arch
+// replaced as in .goreleaser.yml
+func (u *Updater) ArchiveName(r *Release) string {
+	goos, arch := u.platform(), u.arch()
+	switch goos {
+	case "linux":
+		goos = "Linux"
+	}
+	switch arch {
+	case "386":
+		arch = "i386"
+	case "amd64":
+		arch = "x86_64"
+	case "arm":
+		// Only ARMv7 is released
+		arch = "armv7"
+	}
+
+	ext := ".tar.gz"
+	if u.platform() == "windows" {
+		ext = ".zip"
+	}
+
+	name := u.Binary
+	if name == "" {
+		name = "tyk-sync"
+	}
+	return fmt.Sprintf("%v_%v_%v_%v%v", name, r.Version(), goos, arch, ext)
+}
+
+// verifiedChecksums downloads the checksums of the release and checks their signature
+func (u *Updater) verifiedChecksums(r *Release) (map[string]string, error) {
+	if len(u.Keys) == 0 {
+		return nil, errors.New("no release signing keys to verify the release with")
+	}
+
+	sums, err := r.asset(ChecksumsAsset)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := r.asset(ChecksumsAsset + SignatureSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("%v, refusing an unsigned release", err)
+	}
+
+	rawSums, err := u.get(sums.URL)
+	if err != nil {
+		return nil, err
+	}
+	rawSig, err := u.get(sig.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	// goreleaser signs with gpg --detach-sig, binary unless --armor is set
+	_, err = openpgp.CheckDetachedSignature(u.Keys, bytes.NewReader(rawSums), bytes.NewReader(rawSig))
+	if err != nil {
+		_, err = openpgp.CheckArmoredDetachedSignature(u.Keys, bytes.NewReader(rawSums), bytes.NewReader(rawSig))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bad signature of the %v checksums: %v", r.Tag, err)
+	}
+
+	checksums := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(rawSums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+
+	return checksums, scanner.Err()
+}
+
+// Download returns the binary of the release for the platform, once its archive is verified
+func (u *Updater) Download(r *Release) ([]byte, error) {
+	checksums, err := u.verifiedChecksums(r)
+	if err != nil {
+		return nil, err
+	}
+
+	name := u.ArchiveName(r)
+	want, ok := checksums[name]
+	if !ok {
+		return nil, fmt.Errorf("the %v checksums don't list %v", r.Tag, name)
+	}
+	archive, err := r.asset(name)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := u.get(archive.URL)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(raw)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("%v has SHA-256 %v, the signed checksums say %v", name, got, want)
+	}
+
+	if strings.HasSuffix(name, ".zip") {
+		return u.fromZip(raw)
+	}
+	return u.fromTarGz(raw)
+}
+
+func (u *Updater) fromTarGz(raw []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag == tar.TypeReg && path.Base(h.Name) == u.binary() {
+			return ioutil.ReadAll(io.LimitReader(tr, maxReleaseAsset))
+		}
+	}
+
+	return nil, fmt.Errorf("no %v in the archive", u.binary())
+}
+
+func (u *Updater) fromZip(raw []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || path.Base(f.Name) != u.binary() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return ioutil.ReadAll(io.LimitReader(rc, maxReleaseAsset))
+	}
+
+	return nil, fmt.Errorf("no %v in the archive", u.binary())
+}
+
+// ReplaceExecutable swaps the file at exe for bin, keeping its mode. The new binary is
+// written next to it and renamed over it, so exe is never left half written.
+func ReplaceExecutable(exe string, bin []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(exe), "."+filepath.Base(exe)+".new-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bin); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	// Windows doesn't replace a running executable, it can be moved away though
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(tmp.Name(), exe)
+}
+
+// NewerVersion tells whether version a, e.g. 1.4.0 or v1.5.0-rc1, is newer than b. A
+// release is newer than its release candidates.
+func NewerVersion(a, b string) bool {
+	return compareVersions(a, b) > 0
+}
+
+func compareVersions(a, b string) int {
+	a, b = strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v")
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	case aPre > bPre:
+		return 1
+	}
+	return -1
+}
+
+func splitVersion(v string) (string, string) {
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		return v[:i], v[i+1:]
+	}
+	return v, ""
+}
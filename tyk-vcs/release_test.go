@@ -0,0 +1,177 @@
+package tyk_vcs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+func testArchive(t *testing.T, name string, bin []byte) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range []struct {
+		name string
+		body []byte
+	}{{"README.md", []byte("readme")}, {name, bin}} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0755, Size: int64(len(f.body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(f.body)
+	}
+	tw.Close()
+	gz.Close()
+
+	return buf.Bytes()
+}
+
+func TestUpdater(t *testing.T) {
+	signer, err := openpgp.NewEntity("release", "", "release@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := openpgp.NewEntity("other", "", "other@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archiveName := "tyk-sync_1.5.0_Linux_x86_64.tar.gz"
+	assets := map[string][]byte{archiveName: testArchive(t, "tyk-sync", []byte("new binary"))}
+	sum := sha256.Sum256(assets[archiveName])
+	assets[ChecksumsAsset] = []byte(hex.EncodeToString(sum[:]) + "  " + archiveName + "\n")
+	sign := func(by *openpgp.Entity) {
+		sig := &bytes.Buffer{}
+		if err := openpgp.DetachSign(sig, by, bytes.NewReader(assets[ChecksumsAsset]), nil); err != nil {
+			t.Fatal(err)
+		}
+		assets[ChecksumsAsset+SignatureSuffix] = sig.Bytes()
+	}
+	sign(signer)
+
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/releases" {
+			releases := []Release{
+				{Tag: "v1.6.0-rc1", Prerelease: true},
+				{Tag: "v1.5.0"},
+				{Tag: "v1.4.0"},
+			}
+			for name := range assets {
+				releases[1].Assets = append(releases[1].Assets, ReleaseAsset{Name: name, URL: ts.URL + "/download/" + name})
+			}
+			json.NewEncoder(w).Encode(releases)
+			return
+		}
+		raw, ok := assets[strings.TrimPrefix(r.URL.Path, "/download/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(raw)
+	}))
+	defer ts.Close()
+
+	u := &Updater{ReleasesURL: ts.URL + "/releases", Keys: openpgp.EntityList{signer}, GOOS: "linux", GOARCH: "amd64"}
+
+	if _, err := (&Updater{ReleasesURL: u.ReleasesURL, Channel: "nightly"}).Latest(); err == nil {
+		t.Error("expected an unknown channel refused")
+	}
+	if r, err := (&Updater{ReleasesURL: u.ReleasesURL, Channel: ChannelPrerelease}).Latest(); err != nil || r.Tag != "v1.6.0-rc1" {
+		t.Errorf("expected the release candidate on the prerelease channel, got %+v %v", r, err)
+	}
+
+	latest, err := u.Latest()
+	if err != nil || latest.Version() != "1.5.0" {
+		t.Fatalf("expected 1.5.0 on the stable channel, got %+v %v", latest, err)
+	}
+	if name := u.ArchiveName(latest); name != archiveName {
+		t.Errorf("unexpected archive name %v", name)
+	}
+
+	bin, err := u.Download(latest)
+	if err != nil || string(bin) != "new binary" {
+		t.Fatalf("expected the binary, got %q %v", bin, err)
+	}
+
+	// Releases not signed by the release key, or not matching their checksums, are refused
+	sign(other)
+	if _, err := u.Download(latest); err == nil || !strings.Contains(err.Error(), "bad signature") {
+		t.Errorf("expected a bad signature, got %v", err)
+	}
+	sign(signer)
+	assets[archiveName] = testArchive(t, "tyk-sync", []byte("tampered"))
+	if _, err := u.Download(latest); err == nil || !strings.Contains(err.Error(), "SHA-256") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+	if _, err := (&Updater{ReleasesURL: u.ReleasesURL}).Download(latest); err == nil {
+		t.Error("expected a release without keys to verify it refused")
+	}
+
+	dir, err := ioutil.TempDir("", "self-update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	exe := filepath.Join(dir, "tyk-sync")
+	if err := ioutil.WriteFile(exe, []byte("old binary"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReplaceExecutable(exe, bin); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := ioutil.ReadFile(exe)
+	info, _ := os.Stat(exe)
+	files, _ := ioutil.ReadDir(dir)
+	if string(raw) != "new binary" || info.Mode().Perm() != 0750 || len(files) != 1 {
+		t.Errorf("expected the binary replaced in place, got %q %v and %v files", raw, info.Mode(), len(files))
+	}
+}
+
+func TestNewerVersion(t *testing.T) {
+	for _, c := range []struct {
+		a, b  string
+		newer bool
+	}{
+		{"1.5.0", "1.4.9", true},
+		{"v1.10.0", "1.9.0", true},
+		{"1.5.0", "1.5.0-rc2", true},
+		{"1.5.0-rc2", "1.5.0-rc1", true},
+		{"1.5.0-rc1", "1.5.0", false},
+		{"1.5.0", "1.5.0", false},
+		{"1.4.0", "1.5", false},
+	} {
+		if got := NewerVersion(c.a, c.b); got != c.newer {
+			t.Errorf("NewerVersion(%v, %v) = %v", c.a, c.b, got)
+		}
+	}
+}
+
+func TestUpdater_ArchiveName(t *testing.T) {
+	r := &Release{Tag: "v1.5.0"}
+	for platform, expected := range map[string]string{
+		"linux/arm":     "tyk-sync_1.5.0_Linux_armv7.tar.gz",
+		"linux/arm64":   "tyk-sync_1.5.0_Linux_arm64.tar.gz",
+		"darwin/amd64":  "tyk-sync_1.5.0_darwin_x86_64.tar.gz",
+		"darwin/arm64":  "tyk-sync_1.5.0_darwin_arm64.tar.gz",
+		"windows/386":   "tyk-sync_1.5.0_windows_i386.zip",
+		"windows/arm":   "tyk-sync_1.5.0_windows_armv7.zip",
+		"windows/arm64": "tyk-sync_1.5.0_windows_arm64.zip",
+	} {
+		parts := strings.Split(platform, "/")
+		if name := (&Updater{GOOS: parts[0], GOARCH: parts[1]}).ArchiveName(r); name != expected {
+			t.Errorf("%v: expected %v, got %v", platform, expected, name)
+		}
+	}
+}
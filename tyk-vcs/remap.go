@@ -0,0 +1,103 @@
+package tyk_vcs
+
+import (
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// IDMap maps the IDs policies and APIs have in the source environment to the ones they
+// have on the target, e.g. when the target doesn't keep explicit policy IDs
+type IDMap struct {
+	APIs     map[string]string
+	Policies map[string]string
+}
+
+func NewIDMap() *IDMap {
+	return &IDMap{APIs: map[string]string{}, Policies: map[string]string{}}
+}
+
+// AddPolicy records that the policy known as source is target on the target, empty or
+// unchanged IDs are ignored
+func (m *IDMap) AddPolicy(source, target string) {
+	if source != "" && target != "" && source != target {
+		m.Policies[source] = target
+	}
+}
+
+// AddAPI records that the API with the source API ID has the target one on the target
+func (m *IDMap) AddAPI(source, target string) {
+	if source != "" && target != "" && source != target {
+		m.APIs[source] = target
+	}
+}
+
+// PolicyRef is the ID keys use to apply a policy as stored on a target: its explicit ID,
+// or its database ID if the target doesn't keep explicit IDs
+func PolicyRef(raw map[string]interface{}) string {
+	if id, ok := raw["id"].(string); ok && id != "" {
+		return id
+	}
+
+	id, _ := raw["_id"].(string)
+	return id
+}
+
+// RemapKey rewrites the policies (apply_policies, apply_policy_id) and the API IDs of the
+// access rights of a key session to the IDs of the target, it returns the number of
+// references changed
+func (m *IDMap) RemapKey(k *objects.Key) int {
+	changed := 0
+	s := k.Session
+	if s == nil {
+		return 0
+	}
+
+	if pols, ok := s["apply_policies"].([]interface{}); ok {
+		for i, p := range pols {
+			if id, ok := p.(string); ok && m.Policies[id] != "" {
+				pols[i] = m.Policies[id]
+				changed++
+			}
+		}
+	}
+
+	if id, ok := s["apply_policy_id"].(string); ok && m.Policies[id] != "" {
+		s["apply_policy_id"] = m.Policies[id]
+		changed++
+	}
+
+	if rights, ok := s["access_rights"].(map[string]interface{}); ok {
+		remapped := make(map[string]interface{}, len(rights))
+		for apiID, access := range rights {
+			target, found := m.APIs[apiID]
+			if !found {
+				remapped[apiID] = access
+				continue
+			}
+
+			if a, ok := access.(map[string]interface{}); ok {
+				a["api_id"] = target
+			}
+			remapped[target] = access
+			changed++
+		}
+		s["access_rights"] = remapped
+	}
+
+	return changed
+}
+
+// RemapOAuthClient rewrites the API and the policy of an OAuth client to the IDs of the
+// target, it returns the number of references changed
+func (m *IDMap) RemapOAuthClient(c *objects.OAuthClient) int {
+	changed := 0
+	if id := m.APIs[c.APIID]; id != "" {
+		c.APIID = id
+		changed++
+	}
+	if id := m.Policies[c.PolicyID]; id != "" {
+		c.PolicyID = id
+		changed++
+	}
+
+	return changed
+}
@@ -0,0 +1,71 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+func TestIDMap_RemapKey(t *testing.T) {
+	m := NewIDMap()
+	m.AddPolicy("p1", "5e9d9544a1dcd60001d0ed30")
+	m.AddPolicy("p2", "p2")
+	m.AddAPI("a1", "b1")
+
+	k := &objects.Key{
+		KeyID: "k",
+		Session: map[string]interface{}{
+			"apply_policies":  []interface{}{"p1", "p2", "p3"},
+			"apply_policy_id": "p1",
+			"access_rights": map[string]interface{}{
+				"a1": map[string]interface{}{"api_id": "a1", "api_name": "A"},
+				"a2": map[string]interface{}{"api_id": "a2"},
+			},
+		},
+	}
+
+	if n := m.RemapKey(k); n != 3 {
+		t.Fatalf("expected 3 references to change, got %v", n)
+	}
+
+	pols := k.Session["apply_policies"].([]interface{})
+	if pols[0] != "5e9d9544a1dcd60001d0ed30" || pols[1] != "p2" || pols[2] != "p3" {
+		t.Fatalf("unexpected policies: %v", pols)
+	}
+	if k.Session["apply_policy_id"] != "5e9d9544a1dcd60001d0ed30" {
+		t.Fatalf("unexpected policy: %v", k.Session["apply_policy_id"])
+	}
+
+	rights := k.Session["access_rights"].(map[string]interface{})
+	if _, ok := rights["a1"]; ok {
+		t.Fatal("the source API ID must be replaced")
+	}
+	if rights["b1"].(map[string]interface{})["api_id"] != "b1" || rights["a2"] == nil {
+		t.Fatalf("unexpected access rights: %v", rights)
+	}
+}
+
+func TestIDMap_RemapOAuthClient(t *testing.T) {
+	m := NewIDMap()
+	m.AddPolicy("p1", "5e9d9544a1dcd60001d0ed30")
+	m.AddAPI("a1", "b1")
+
+	c := &objects.OAuthClient{ClientID: "c", APIID: "a1", PolicyID: "p1"}
+	if n := m.RemapOAuthClient(c); n != 2 || c.APIID != "b1" || c.PolicyID != "5e9d9544a1dcd60001d0ed30" {
+		t.Fatalf("expected the API and policy remapped, got %v %+v", n, c)
+	}
+
+	c = &objects.OAuthClient{ClientID: "c", APIID: "a2"}
+	if n := m.RemapOAuthClient(c); n != 0 || c.APIID != "a2" {
+		t.Fatalf("expected an unmapped client unchanged, got %v %+v", n, c)
+	}
+}
+
+func TestPolicyRef(t *testing.T) {
+	if ref := PolicyRef(map[string]interface{}{"id": "p1", "_id": "5e9d"}); ref != "p1" {
+		t.Fatalf("expected the explicit ID, got %v", ref)
+	}
+	if ref := PolicyRef(map[string]interface{}{"id": "", "_id": "5e9d"}); ref != "5e9d" {
+		t.Fatalf("expected the database ID, got %v", ref)
+	}
+}
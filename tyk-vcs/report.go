@@ -0,0 +1,287 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-diff"
+)
+
+// SyncReport summarises a sync, publish or update run, it is what notifications are sent with
+type SyncReport struct {
+	// Action is the command run, sync, publish or update
+	Action     string             `json:"action"`
+	Target     string             `json:"target"`
+	Success    bool               `json:"success"`
+	Error      string             `json:"error,omitempty"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+	Plans      []objects.SyncPlan `json:"plans"`
+	// Applied is the number of changes the target took, the plans are what was to be done
+	Applied int `json:"applied"`
+	// Tenants are the reports of each org, when the spec fans out to several
+	Tenants []*SyncReport `json:"tenants,omitempty"`
+	// Live is the liveness of the synced APIs, when checked
+	Live []LiveCheck `json:"live,omitempty"`
+	// Contracts are the results of the contract tests of the synced APIs, when run
+	Contracts []ContractResult `json:"contracts,omitempty"`
+}
+
+func NewSyncReport(target string) *SyncReport {
+	return &SyncReport{
+		Action:    "sync",
+		Target:    target,
+		StartedAt: time.Now(),
+		Plans:     []objects.SyncPlan{},
+	}
+}
+
+// Record wraps a plan check (which may be nil) so that every plan it lets through is
+// added to the report
+func (r *SyncReport) Record(check objects.PlanCheck) objects.PlanCheck {
+	return func(plan *objects.SyncPlan) error {
+		if check != nil {
+			if err := check(plan); err != nil {
+				return err
+			}
+		}
+
+		r.Plans = append(r.Plans, *plan)
+		return nil
+	}
+}
+
+// Track wraps a progress (which may be nil) so that every change the clients apply is
+// counted in Applied
+func (r *SyncReport) Track(p objects.Progress) objects.Progress {
+	done := map[string]int{}
+	return objects.ProgressFunc(func(e objects.ProgressEvent) {
+		// A new sync of the kind starts counting from zero again
+		if e.Done < done[e.Kind] {
+			done[e.Kind] = 0
+		}
+		r.Applied += e.Done - done[e.Kind]
+		done[e.Kind] = e.Done
+
+		if p != nil {
+			p.Progress(e)
+		}
+	})
+}
+
+// Finish sets the outcome of the run
+func (r *SyncReport) Finish(err error) {
+	r.FinishedAt = time.Now()
+	r.Success = err == nil
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Changed tells whether the run created, updated or deleted anything, in any tenant
+func (r *SyncReport) Changed() bool {
+	for _, p := range r.Plans {
+		if len(p.Create)+len(p.Update)+len(p.Delete) > 0 {
+			return true
+		}
+	}
+
+	for _, t := range r.Tenants {
+		if t.Changed() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sent tells whether any change was applied to the target, in any tenant. Unlike Changed
+// it is false when the run failed, or stopped, before applying its plans.
+func (r *SyncReport) Sent() bool {
+	if r.Applied > 0 {
+		return true
+	}
+
+	for _, t := range r.Tenants {
+		if t.Sent() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Summary is a one line description of the run, e.g. for chat messages
+func (r *SyncReport) Summary() string {
+	changes := []string{}
+	for _, p := range r.Plans {
+		changes = append(changes, fmt.Sprintf("%v: %v created, %v updated, %v deleted", p.Kind, len(p.Create), len(p.Update), len(p.Delete)))
+	}
+
+	if !r.Success {
+		return fmt.Sprintf("%v failed: %v", r.title(), r.Error)
+	}
+
+	if len(r.Tenants) > 0 {
+		return fmt.Sprintf("%v succeeded for %v tenants", r.title(), len(r.Tenants))
+	}
+
+	notLive := 0
+	for _, c := range r.Live {
+		if !c.Live {
+			notLive++
+		}
+	}
+	if notLive > 0 {
+		changes = append(changes, fmt.Sprintf("%v of %v APIs not live", notLive, len(r.Live)))
+	}
+
+	if len(changes) == 0 {
+		return fmt.Sprintf("%v succeeded", r.title())
+	}
+
+	return fmt.Sprintf("%v succeeded (%v)", r.title(), strings.Join(changes, "; "))
+}
+
+// title names the run, e.g. "Publish to http://dash"
+func (r *SyncReport) title() string {
+	action := "Sync"
+	if r.Action != "" {
+		action = strings.ToUpper(r.Action[:1]) + r.Action[1:]
+	}
+	return action + " to " + r.Target
+}
+
+func markdownItem(action string, item objects.SyncItem) string {
+	if item.ID == "" {
+		return fmt.Sprintf("- %v **%v**\n", action, item.Name)
+	}
+	return fmt.Sprintf("- %v **%v** (`%v`)\n", action, item.Name, item.ID)
+}
+
+// writePlans writes the plans of r as a table of counts, followed by the objects of each.
+// The plans of a failed run are what was to be done, only Applied of them were.
+func (r *SyncReport) writePlans(out *strings.Builder) {
+	if !r.Changed() {
+		out.WriteString("\nNothing to create, update or delete.\n")
+		return
+	}
+
+	created, updated, deleted, deactivated := "created", "updated", "deleted", "deactivated"
+	if r.Success {
+		out.WriteString("\n| Objects | Created | Updated | Deleted |\n| --- | --- | --- | --- |\n")
+	} else {
+		planned := 0
+		for _, p := range r.Plans {
+			planned += len(p.Create) + len(p.Update) + len(p.Delete)
+		}
+		fmt.Fprintf(out, "\n%v of the %v planned changes were applied before the sync failed.\n", r.Applied, planned)
+		out.WriteString("\n| Objects | To create | To update | To delete |\n| --- | --- | --- | --- |\n")
+		created, updated, deleted, deactivated = "to create", "to update", "to delete", "to deactivate"
+	}
+	for _, p := range r.Plans {
+		fmt.Fprintf(out, "| %v | %v | %v | %v |\n", p.Kind, len(p.Create), len(p.Update), len(p.Delete))
+	}
+
+	for _, p := range r.Plans {
+		if len(p.Create)+len(p.Update)+len(p.Delete) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(out, "\n<details><summary>%v</summary>\n\n", p.Kind)
+		for _, item := range p.Create {
+			out.WriteString(markdownItem(created, item))
+		}
+		for _, item := range p.Update {
+			out.WriteString(markdownItem(updated, item))
+		}
+		for _, item := range p.Delete {
+			if p.Deactivate {
+				out.WriteString(markdownItem(deactivated, item))
+			} else {
+				out.WriteString(markdownItem(deleted, item))
+			}
+		}
+		out.WriteString("\n</details>\n")
+	}
+}
+
+// Markdown describes the run and lists the objects it changed on the target, e.g. for pull
+// request comments
+func (r *SyncReport) Markdown() string {
+	out := &strings.Builder{}
+	if r.Success {
+		fmt.Fprintf(out, "### %v succeeded\n", r.title())
+	} else {
+		fmt.Fprintf(out, "### %v failed\n\n```\n%v\n```\n", r.title(), r.Error)
+	}
+
+	// Publish and update have no plans, they create or update every object of the repo
+	if len(r.Tenants) == 0 && (r.Action == "sync" || len(r.Plans) > 0) {
+		r.writePlans(out)
+	}
+	for _, t := range r.Tenants {
+		fmt.Fprintf(out, "\n#### %v\n", t.Target)
+		if !t.Success {
+			fmt.Fprintf(out, "\nFailed: %v\n", t.Error)
+		}
+		t.writePlans(out)
+	}
+
+	notLive := []string{}
+	for _, c := range r.Live {
+		if !c.Live {
+			notLive = append(notLive, fmt.Sprintf("`%v`", c.ListenPath))
+		}
+	}
+	if len(notLive) > 0 {
+		fmt.Fprintf(out, "\n%v of %v APIs not live: %v\n", len(notLive), len(r.Live), strings.Join(notLive, ", "))
+	}
+
+	failed := 0
+	for _, c := range r.Contracts {
+		if !c.Passed {
+			failed++
+		}
+	}
+	if len(r.Contracts) > 0 {
+		fmt.Fprintf(out, "\n%v of %v contract tests failed\n", failed, len(r.Contracts))
+	}
+
+	return out.String()
+}
+
+// DiffMarkdown describes the differences verify found between the repo and the target, e.g.
+// for pull request comments
+func DiffMarkdown(target string, diffs []tyk_diff.ObjectDiff) string {
+	out := &strings.Builder{}
+	if len(diffs) == 0 {
+		fmt.Fprintf(out, "### %v matches the repo\n\nAll objects are stored as published.\n", target)
+		return out.String()
+	}
+
+	fmt.Fprintf(out, "### %v differs from the repo for %v objects\n", target, len(diffs))
+	for _, d := range diffs {
+		name := fmt.Sprintf("%v **%v**", d.Kind, d.Name)
+		if d.ID != "" {
+			name += fmt.Sprintf(" (`%v`)", d.ID)
+		}
+
+		switch {
+		case d.Error != "":
+			fmt.Fprintf(out, "\n- %v could not be fetched: %v\n", name, d.Error)
+		case len(d.Changes) > 0:
+			fmt.Fprintf(out, "\n<details><summary>%v: %v fields</summary>\n\n", name, len(d.Changes))
+			for _, c := range d.Changes {
+				fmt.Fprintf(out, "- `%v`\n", c)
+			}
+			out.WriteString("\n</details>\n")
+		default:
+			fmt.Fprintf(out, "\n- %v: %v patch operations\n", name, len(d.Patch))
+		}
+	}
+
+	return out.String()
+}
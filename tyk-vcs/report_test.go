@@ -0,0 +1,150 @@
+package tyk_vcs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-diff"
+)
+
+func TestSyncReportRecord(t *testing.T) {
+	r := NewSyncReport("http://dash")
+
+	reject := r.Record(func(plan *objects.SyncPlan) error {
+		if plan.Kind == "policies" {
+			return errors.New("rejected")
+		}
+		return nil
+	})
+
+	if err := reject(&objects.SyncPlan{Kind: "APIs", Create: []objects.SyncItem{{ID: "a1"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reject(&objects.SyncPlan{Kind: "policies"}); err == nil {
+		t.Fatal("expected the plan check error")
+	}
+
+	if len(r.Plans) != 1 {
+		t.Fatalf("expected only the accepted plan to be recorded, got %v", r.Plans)
+	}
+
+	r.Finish(nil)
+	if expected := "Sync to http://dash succeeded (APIs: 1 created, 0 updated, 0 deleted)"; r.Summary() != expected {
+		t.Errorf("expected %q, got %q", expected, r.Summary())
+	}
+
+	r.Finish(errors.New("boom"))
+	if r.Success || r.Summary() != "Sync to http://dash failed: boom" {
+		t.Errorf("unexpected summary for a failed sync: %q", r.Summary())
+	}
+}
+
+func TestSyncReportMarkdown(t *testing.T) {
+	r := NewSyncReport("http://dash")
+	r.Plans = append(r.Plans,
+		objects.SyncPlan{Kind: "APIs", Create: []objects.SyncItem{{ID: "a1", Name: "Users"}}, Delete: []objects.SyncItem{{ID: "a2", Name: "Legacy"}}, Deactivate: true},
+		objects.SyncPlan{Kind: "policies"},
+	)
+	r.Finish(nil)
+
+	md := r.Markdown()
+	for _, expected := range []string{"### Sync to http://dash succeeded", "| APIs | 1 | 0 | 1 |", "| policies | 0 | 0 | 0 |", "- created **Users** (`a1`)", "- deactivated **Legacy** (`a2`)"} {
+		if !strings.Contains(md, expected) {
+			t.Errorf("expected %q in:\n%v", expected, md)
+		}
+	}
+	if strings.Contains(md, "<summary>policies</summary>") {
+		t.Error("plans without changes should not be listed")
+	}
+
+	// A failed sync lists what it planned, not what it did
+	r.Applied = 1
+	r.Finish(errors.New("boom"))
+	md = r.Markdown()
+	for _, expected := range []string{"1 of the 2 planned changes were applied", "| APIs | 1 | 0 | 1 |", "- to create **Users** (`a1`)", "- to deactivate **Legacy** (`a2`)"} {
+		if !strings.Contains(md, expected) {
+			t.Errorf("expected %q in:\n%v", expected, md)
+		}
+	}
+	if strings.Contains(md, "- created") {
+		t.Errorf("a failed sync should not list objects as created:\n%v", md)
+	}
+
+	r = NewSyncReport("http://dash")
+	r.Finish(errors.New("boom"))
+	if md := r.Markdown(); !strings.Contains(md, "failed") || !strings.Contains(md, "boom") || !strings.Contains(md, "Nothing to create") {
+		t.Errorf("unexpected Markdown for a failed sync:\n%v", md)
+	}
+}
+
+func TestPublishReportMarkdown(t *testing.T) {
+	r := NewSyncReport("http://dash")
+	r.Action = "publish"
+	r.Contracts = []ContractResult{{Name: "needs a key", APIID: "a1", Passed: true}, {Name: "with a key", APIID: "a1"}}
+	r.Finish(errors.New("1 of 2 contract tests failed"))
+
+	md := r.Markdown()
+	for _, expected := range []string{"### Publish to http://dash failed", "1 of 2 contract tests failed"} {
+		if !strings.Contains(md, expected) {
+			t.Errorf("expected %q in:\n%v", expected, md)
+		}
+	}
+	if strings.Contains(md, "Nothing to create") {
+		t.Errorf("a publish has no plans to list:\n%v", md)
+	}
+	if r.Summary() != "Publish to http://dash failed: 1 of 2 contract tests failed" {
+		t.Errorf("unexpected summary %q", r.Summary())
+	}
+}
+
+func TestDiffMarkdown(t *testing.T) {
+	md := DiffMarkdown("http://dash", []tyk_diff.ObjectDiff{
+		{Kind: "API", Name: "Users", ID: "a1", Changes: []tyk_diff.Change{{Path: "/active", Kind: tyk_diff.Changed, Expected: true, Actual: false}}},
+		{Kind: "Policy", Name: "Gold", Error: "not found"},
+	})
+
+	for _, expected := range []string{"differs from the repo for 2 objects", "API **Users** (`a1`): 1 fields", "/active changed", "Policy **Gold** could not be fetched: not found"} {
+		if !strings.Contains(md, expected) {
+			t.Errorf("expected %q in:\n%v", expected, md)
+		}
+	}
+
+	if md := DiffMarkdown("http://dash", nil); !strings.Contains(md, "matches the repo") {
+		t.Errorf("unexpected Markdown without differences:\n%v", md)
+	}
+}
+
+func TestSyncReportTrack(t *testing.T) {
+	r := NewSyncReport("http://dash")
+	forwarded := 0
+	p := r.Track(objects.ProgressFunc(func(e objects.ProgressEvent) { forwarded++ }))
+
+	// A plan that fails before its changes are applied sends nothing
+	r.Plans = append(r.Plans, objects.SyncPlan{Kind: "APIs", Update: []objects.SyncItem{{ID: "a1"}}})
+	tr := objects.TrackProgress(p, "APIs", 2)
+	tr.Phase(objects.PhaseUpdate, 2)
+	if !r.Changed() || r.Sent() {
+		t.Fatalf("expected planned changes only, got %v applied", r.Applied)
+	}
+
+	tr.Done("a1")
+	tr.Done("")
+	objects.TrackProgress(p, "policies", 1).Done("p1")
+	// A second sync of APIs counts from zero again
+	objects.TrackProgress(p, "APIs", 1).Done("a2")
+
+	if r.Applied != 4 || !r.Sent() || forwarded != 5 {
+		t.Errorf("expected 4 changes applied and 5 events forwarded, got %v and %v", r.Applied, forwarded)
+	}
+
+	tenant := NewSyncReport("http://dash")
+	tenant.Tenants = []*SyncReport{r}
+	if !tenant.Sent() {
+		t.Error("expected the changes of a tenant to be sent")
+	}
+
+	// Nothing is forwarded, or panics, without a progress
+	NewSyncReport("http://dash").Track(nil).Progress(objects.ProgressEvent{Kind: "APIs", Done: 1})
+}
@@ -0,0 +1,115 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RequiresInfo are the versions a repo may be applied with, as constraints such as ">=1.4"
+// or ">=5.0, <6": comma separated comparisons, all of which must hold, with one of the
+// operators >=, >, <=, <, = and !=, a version alone meaning =
+type RequiresInfo struct {
+	// Tool constrains the version of tyk-sync
+	Tool string `json:"tool,omitempty"`
+	// Dashboard and Gateway constrain the version of the target published to
+	Dashboard string `json:"dashboard,omitempty"`
+	Gateway   string `json:"gateway,omitempty"`
+}
+
+var constraintOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// SatisfiesVersion reports if version meets constraint, an empty constraint is met by any
+// version
+func SatisfiesVersion(version, constraint string) (bool, error) {
+	for _, clause := range strings.Split(constraint, ",") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+
+		op := "="
+		for _, o := range constraintOperators {
+			if strings.HasPrefix(clause, o) {
+				op = o
+				break
+			}
+		}
+		want := strings.TrimSpace(strings.TrimPrefix(clause, op))
+		if want == "" {
+			return false, fmt.Errorf("invalid version constraint %q", constraint)
+		}
+
+		c := compareVersions(version, want)
+		ok := false
+		switch op {
+		case ">=":
+			ok = c >= 0
+		case "<=":
+			ok = c <= 0
+		case "!=":
+			ok = c != 0
+		case ">":
+			ok = c > 0
+		case "<":
+			ok = c < 0
+		case "=":
+			ok = c == 0
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// CheckTarget fails if the version of the dashboard, or gateway, published to doesn't meet
+// the constraint of the spec, so that definitions in a format it doesn't know aren't
+// stored half understood
+func (r *RequiresInfo) CheckTarget(version string, gateway bool) error {
+	if r == nil {
+		return nil
+	}
+
+	what, constraint := "dashboard", r.Dashboard
+	if gateway {
+		what, constraint = "gateway", r.Gateway
+	}
+	if constraint == "" {
+		return nil
+	}
+	if version == "" {
+		return fmt.Errorf("the spec requires %v %v, but the version of the target is unknown", what, constraint)
+	}
+
+	ok, err := SatisfiesVersion(version, constraint)
+	if err != nil {
+		return fmt.Errorf("requires.%v: %v", what, err)
+	}
+	if !ok {
+		return fmt.Errorf("the spec requires %v %v, the target is %v", what, constraint, version)
+	}
+	return nil
+}
+
+// mergeRequires adds the constraints of over to those of base, the versions must meet both
+func mergeRequires(base, over *RequiresInfo) *RequiresInfo {
+	if over == nil {
+		return base
+	}
+	if base == nil {
+		base = &RequiresInfo{}
+	}
+
+	join := func(a, b string) string {
+		if a == "" || b == "" {
+			return a + b
+		}
+		return a + ", " + b
+	}
+	base.Tool = join(base.Tool, over.Tool)
+	base.Dashboard = join(base.Dashboard, over.Dashboard)
+	base.Gateway = join(base.Gateway, over.Gateway)
+
+	return base
+}
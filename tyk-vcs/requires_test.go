@@ -0,0 +1,68 @@
+package tyk_vcs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSatisfiesVersion(t *testing.T) {
+	for _, c := range []struct {
+		version, constraint string
+		expected            bool
+	}{
+		{"1.4.0", ">=1.4", true},
+		{"1.3.9", ">=1.4", false},
+		{"v5.0.2", ">=5.0, <6", true},
+		{"6.0.0", ">=5.0, <6", false},
+		{"1.5.0-rc1", ">=1.5.0", false},
+		{"1.5.0", "!=1.5.0", false},
+		{"1.5.1", "1.5.1", true},
+		{"2.0.0", "", true},
+	} {
+		ok, err := SatisfiesVersion(c.version, c.constraint)
+		if err != nil || ok != c.expected {
+			t.Errorf("%v %q: expected %v, got %v %v", c.version, c.constraint, c.expected, ok, err)
+		}
+	}
+
+	if _, err := SatisfiesVersion("1.0.0", ">="); err == nil {
+		t.Error("expected a constraint without a version to be invalid")
+	}
+}
+
+func TestRequiresInfo_CheckTarget(t *testing.T) {
+	r := &RequiresInfo{Dashboard: ">=5.0"}
+
+	if err := r.CheckTarget("v4.3.1", false); err == nil || !strings.Contains(err.Error(), "requires dashboard >=5.0") {
+		t.Errorf("expected an older dashboard to be refused, got %v", err)
+	}
+	if err := r.CheckTarget("", false); err == nil {
+		t.Error("expected a dashboard of unknown version to be refused")
+	}
+	if err := r.CheckTarget("v5.1.0", false); err != nil {
+		t.Error(err)
+	}
+	if err := r.CheckTarget("v2.9.0", true); err != nil {
+		t.Errorf("expected gateways not to be constrained, got %v", err)
+	}
+}
+
+func TestRequiresTool(t *testing.T) {
+	defer func(v string) { version = v }(version)
+
+	g := memGetter(t, map[string]string{
+		".tyk.json":       `{"type": "apidef", "include": ["teams/.tyk.json"], "requires": {"tool": ">=1.4", "dashboard": ">=5.0"}}`,
+		"teams/.tyk.json": `{"requires": {"tool": "<2"}}`,
+	})
+
+	for v, ok := range map[string]bool{"1.3.0": false, "1.4.0": true, "2.0.0": false, DevVersion: true} {
+		version = v
+		spec, err := g.FetchTykSpec()
+		if ok != (err == nil) {
+			t.Errorf("%v: expected it to read the spec %v, got %v", v, ok, err)
+		}
+		if err == nil && (spec.Requires.Tool != "<2, >=1.4" || spec.Requires.Dashboard != ">=5.0") {
+			t.Errorf("expected the constraints of every file, got %+v", spec.Requires)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package tyk_vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// envAssignment matches the variable assignments of .env files
+var envAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// UpdateSecretFile stores a rotated secret in the file CI reads it from. If the file is a .env
+// file (NAME=value or export NAME=value lines), the value of name is replaced, or appended if
+// the file doesn't assign it, and the other lines are kept. Otherwise the file is made to hold
+// the secret alone. The file is
+// replaced at once and is only readable by its owner, the old secret never lingers in a half
+// written file.
+func UpdateSecretFile(path, name, secret string) error {
+	contents := secret + "\n"
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		lines := strings.Split(string(raw), "\n")
+		found, isEnv := false, false
+		for i, l := range lines {
+			trimmed := strings.TrimSpace(l)
+			prefix := ""
+			if strings.HasPrefix(trimmed, "export ") {
+				prefix = "export "
+				trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "export "))
+			}
+			if envAssignment.MatchString(trimmed) || strings.HasPrefix(trimmed, "#") {
+				isEnv = true
+			}
+			if strings.HasPrefix(trimmed, name+"=") {
+				lines[i] = prefix + name + "=" + secret
+				found = true
+			}
+		}
+
+		switch {
+		case found:
+			contents = strings.Join(lines, "\n")
+		case isEnv:
+			env := strings.TrimRight(string(raw), "\n")
+			contents = env + "\n" + name + "=" + secret + "\n"
+		}
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(contents); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// TempFile already creates the file readable by its owner only
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,58 @@
+package tyk_vcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateSecretFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secretfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name     string
+		existing string
+		expected string
+	}{
+		{"missing", "", "new\n"},
+		{"raw key", "old\n", "new\n"},
+		{"env file", "TYKGIT_DB_URL=http://db\nTYKGIT_DB_SECRET=old\n", "TYKGIT_DB_URL=http://db\nTYKGIT_DB_SECRET=new\n"},
+		{"exported", "export TYKGIT_DB_SECRET=old", "export TYKGIT_DB_SECRET=new"},
+		{"other variable", "OTHER_SECRET=old\n", "OTHER_SECRET=old\nTYKGIT_DB_SECRET=new\n"},
+		{"comments only", "# CI secrets", "# CI secrets\nTYKGIT_DB_SECRET=new\n"},
+	}
+
+	for _, tc := range cases {
+		p := filepath.Join(dir, tc.name)
+		if tc.existing != "" {
+			if err := ioutil.WriteFile(p, []byte(tc.existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := UpdateSecretFile(p, "TYKGIT_DB_SECRET", "new"); err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+
+		raw, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(raw) != tc.expected {
+			t.Errorf("%v: expected %q, got %q", tc.name, tc.expected, raw)
+		}
+
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%v: expected mode 0600, got %v", tc.name, info.Mode().Perm())
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package tyk_vcs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// SmokeTestCopy is a copy of def that can be published next to the live API: it has no
+// database ID, its API ID, name and slug get a smoke-test suffix and its listen path is
+// moved under prefix, e.g. /smoke-test
+func SmokeTestCopy(def objects.DBApiDefinition, prefix string) objects.DBApiDefinition {
+	if def.APIDefinition == nil {
+		return def
+	}
+
+	d := *def.APIDefinition
+	d.Id = ""
+	if d.APIID != "" {
+		d.APIID += "-smoke-test"
+	}
+	if d.Slug != "" {
+		d.Slug += "-smoke-test"
+	}
+	d.Name += " (smoke test)"
+
+	// The raw definition is shared with the live API, the copy must not be created with its ID
+	if def.Passthrough != nil {
+		raw := make(map[string]interface{}, len(def.Passthrough))
+		for k, v := range def.Passthrough {
+			raw[k] = v
+		}
+		delete(raw, "id")
+		def.Passthrough = raw
+	}
+
+	listenPath := path.Join("/", prefix, d.Proxy.ListenPath)
+	if strings.HasSuffix(d.Proxy.ListenPath, "/") {
+		listenPath += "/"
+	}
+	d.Proxy.ListenPath = listenPath
+
+	def.APIDefinition = &d
+	return def
+}
+
+// SmokeTest runs a shell command against a published API, it is passed the API in the
+// TYK_SYNC_API_ID, TYK_SYNC_API_NAME and TYK_SYNC_LISTEN_PATH environment variables
+type SmokeTest struct {
+	Command string
+	Timeout time.Duration
+}
+
+// Run runs the command for def, the command fails the test with a non zero exit code or
+// by running longer than the timeout
+func (s *SmokeTest) Run(def objects.DBApiDefinition) error {
+	ctx := context.Background()
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	c := exec.CommandContext(ctx, "sh", "-c", s.Command)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(),
+		"TYK_SYNC_API_ID="+def.APIID,
+		"TYK_SYNC_API_NAME="+def.Name,
+		"TYK_SYNC_LISTEN_PATH="+def.Proxy.ListenPath,
+	)
+
+	err := c.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("smoke test timed out after %v", s.Timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("smoke test failed: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package tyk_vcs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestSmokeTestCopy(t *testing.T) {
+	live := &apidef.APIDefinition{Id: "5e9d9544a1dcd60001d0ed30", APIID: "a", Name: "A", Slug: "a"}
+	live.Proxy.ListenPath = "/payments/"
+	raw := map[string]interface{}{"id": "5e9d9544a1dcd60001d0ed30", "api_id": "a", "graphql": map[string]interface{}{}}
+
+	smoke := SmokeTestCopy(objects.DBApiDefinition{APIDefinition: live, Passthrough: raw}, "/smoke-test")
+	if smoke.APIID != "a-smoke-test" || smoke.Slug != "a-smoke-test" || smoke.Id != "" || smoke.Proxy.ListenPath != "/smoke-test/payments/" {
+		t.Fatalf("unexpected copy: %+v", smoke.APIDefinition)
+	}
+	if _, ok := smoke.Passthrough["id"]; ok || smoke.Passthrough["graphql"] == nil {
+		t.Fatalf("unexpected raw copy: %v", smoke.Passthrough)
+	}
+	if live.APIID != "a" || live.Proxy.ListenPath != "/payments/" || raw["id"] == nil {
+		t.Fatal("the live definition must not change")
+	}
+}
+
+func TestSmokeTest_Run(t *testing.T) {
+	def := &apidef.APIDefinition{APIID: "a-smoke-test"}
+	def.Proxy.ListenPath = "/smoke-test/payments/"
+
+	pass := &SmokeTest{Command: `test "$TYK_SYNC_LISTEN_PATH" = /smoke-test/payments/ && test "$TYK_SYNC_API_ID" = a-smoke-test`}
+	if err := pass.Run(objects.DBApiDefinition{APIDefinition: def}); err != nil {
+		t.Fatal(err)
+	}
+
+	fail := &SmokeTest{Command: "exit 1"}
+	if err := fail.Run(objects.DBApiDefinition{APIDefinition: def}); err == nil {
+		t.Fatal("expected the test to fail")
+	}
+
+	slow := &SmokeTest{Command: "exec sleep 5", Timeout: 50 * time.Millisecond}
+	if err := slow.Run(objects.DBApiDefinition{APIDefinition: def}); err == nil {
+		t.Fatal("expected the test to time out")
+	}
+}
@@ -0,0 +1,570 @@
+package tyk_vcs
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"gopkg.in/yaml.v2"
+)
+
+// SOPSMetadataKey is the top level key SOPS keeps the data key and MAC of a file under
+const SOPSMetadataKey = "sops"
+
+// sopsYAMLMetadata matches the sops key of the YAML files SOPS encrypted
+var sopsYAMLMetadata = regexp.MustCompile(`(?m)^sops:`)
+
+// sopsValue matches the values SOPS encrypts, ENC[AES256_GCM,data:...,iv:...,tag:...,type:str]
+var sopsValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:([^,]*),iv:([^,]+),tag:([^,]+),type:([a-z]+)\]$`)
+
+// SOPSKeys are the master keys the data keys of SOPS encrypted files are decrypted with: age
+// identities, and AWS credentials for the KMS keys
+type SOPSKeys struct {
+	Age []age.Identity
+
+	AWSAccessKey    string
+	AWSSecretKey    string
+	AWSSessionToken string
+	// KMSEndpoint replaces https://kms.<region>.amazonaws.com, e.g. for LocalStack
+	KMSEndpoint string
+	Client      *http.Client
+}
+
+var (
+	sopsKeysMu sync.Mutex
+	sopsKeys   *SOPSKeys
+)
+
+// SetSOPSKeys sets the keys SOPS encrypted files are decrypted with, instead of those of the
+// environment, see SOPSKeysFromEnv
+func SetSOPSKeys(k *SOPSKeys) {
+	sopsKeysMu.Lock()
+	defer sopsKeysMu.Unlock()
+	sopsKeys = k
+}
+
+// SOPSKeysFromEnv reads the keys from the variables sops itself uses: the age identities of
+// SOPS_AGE_KEY, or of the file SOPS_AGE_KEY_FILE, sops/age/keys.txt of the user config
+// directory by default, and the standard AWS credentials
+func SOPSKeysFromEnv() (*SOPSKeys, error) {
+	k := &SOPSKeys{
+		AWSAccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		AWSSecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		AWSSessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		KMSEndpoint:     os.Getenv("AWS_ENDPOINT_URL"),
+	}
+
+	ageKeys := os.Getenv("SOPS_AGE_KEY")
+	if ageKeys == "" {
+		file := os.Getenv("SOPS_AGE_KEY_FILE")
+		if file == "" {
+			if dir, err := os.UserConfigDir(); err == nil {
+				file = filepath.Join(dir, "sops", "age", "keys.txt")
+			}
+		}
+		raw, err := ioutil.ReadFile(file)
+		if err != nil && (!os.IsNotExist(err) || os.Getenv("SOPS_AGE_KEY_FILE") != "") {
+			return nil, err
+		}
+		ageKeys = string(raw)
+	}
+
+	ids, err := ParseAgeIdentities(ageKeys)
+	if err != nil {
+		return nil, err
+	}
+	k.Age = ids
+
+	return k, nil
+}
+
+func currentSOPSKeys() (*SOPSKeys, error) {
+	sopsKeysMu.Lock()
+	k := sopsKeys
+	sopsKeysMu.Unlock()
+	if k != nil {
+		return k, nil
+	}
+	return SOPSKeysFromEnv()
+}
+
+// sopsNode is a value of a SOPS file, its maps kept in the order of the file as the MAC is
+// of the values in that order
+type sopsNode struct {
+	// kind is map, list, string, number, bool or null
+	kind   string
+	keys   []string
+	values []*sopsNode
+	text   string
+}
+
+func (n *sopsNode) get(key string) *sopsNode {
+	for i, k := range n.keys {
+		if k == key {
+			return n.values[i]
+		}
+	}
+	return nil
+}
+
+func readJSONNode(dec *json.Decoder) (*sopsNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		n := &sopsNode{kind: "list"}
+		if t == '{' {
+			n.kind = "map"
+		}
+		for dec.More() {
+			if n.kind == "map" {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, key.(string))
+			}
+			v, err := readJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, v)
+		}
+		// The closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case string:
+		return &sopsNode{kind: "string", text: t}, nil
+	case json.Number:
+		return &sopsNode{kind: "number", text: t.String()}, nil
+	case bool:
+		return &sopsNode{kind: "bool", text: strconv.FormatBool(t)}, nil
+	}
+	return &sopsNode{kind: "null"}, nil
+}
+
+func yamlNode(v interface{}) *sopsNode {
+	switch t := v.(type) {
+	case yaml.MapSlice:
+		n := &sopsNode{kind: "map"}
+		for _, item := range t {
+			n.keys = append(n.keys, fmt.Sprint(item.Key))
+			n.values = append(n.values, yamlNode(item.Value))
+		}
+		return n
+	case []interface{}:
+		n := &sopsNode{kind: "list"}
+		for _, item := range t {
+			n.values = append(n.values, yamlNode(item))
+		}
+		return n
+	case string:
+		return &sopsNode{kind: "string", text: t}
+	case int:
+		return &sopsNode{kind: "number", text: strconv.Itoa(t)}
+	case float64:
+		return &sopsNode{kind: "number", text: strconv.FormatFloat(t, 'f', -1, 64)}
+	case bool:
+		return &sopsNode{kind: "bool", text: strconv.FormatBool(t)}
+	case nil:
+		return &sopsNode{kind: "null"}
+	}
+	return &sopsNode{kind: "string", text: fmt.Sprint(v)}
+}
+
+func parseSOPSNode(raw []byte, isYAML bool) (*sopsNode, error) {
+	if isYAML {
+		doc := yaml.MapSlice{}
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		return yamlNode(doc), nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	n, err := readJSONNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	if n.kind != "map" {
+		return nil, errors.New("not a JSON object")
+	}
+	return n, nil
+}
+
+// writeJSON encodes the node as JSON, the maps in their order
+func (n *sopsNode) writeJSON(out *bytes.Buffer) {
+	switch n.kind {
+	case "map", "list":
+		open, close := byte('['), byte(']')
+		if n.kind == "map" {
+			open, close = '{', '}'
+		}
+		out.WriteByte(open)
+		for i, v := range n.values {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if n.kind == "map" {
+				key, _ := json.Marshal(n.keys[i])
+				out.Write(key)
+				out.WriteByte(':')
+			}
+			v.writeJSON(out)
+		}
+		out.WriteByte(close)
+	case "string":
+		s, _ := json.Marshal(n.text)
+		out.Write(s)
+	case "number", "bool":
+		out.WriteString(n.text)
+	default:
+		out.WriteString("null")
+	}
+}
+
+// macText is how SOPS writes the value of a leaf into the MAC of the file
+func (n *sopsNode) macText() string {
+	switch n.kind {
+	case "bool":
+		// As Go's strings.Title of the formatted bool
+		if n.text == "true" {
+			return "True"
+		}
+		return "False"
+	case "number":
+		if i, err := strconv.ParseInt(n.text, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10)
+		}
+		if f, err := strconv.ParseFloat(n.text, 64); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	}
+	return n.text
+}
+
+// IsSOPS reports if raw is a JSON or YAML file encrypted with SOPS, that is with the sops
+// metadata at its top level
+func IsSOPS(raw []byte, isYAML bool) bool {
+	if isYAML {
+		return sopsYAMLMetadata.Match(raw)
+	}
+	if !bytes.Contains(raw, []byte(`"`+SOPSMetadataKey+`"`)) {
+		return false
+	}
+	probe := map[string]json.RawMessage{}
+	if json.Unmarshal(raw, &probe) != nil {
+		return false
+	}
+	_, ok := probe[SOPSMetadataKey]
+	return ok
+}
+
+type sopsMetadata struct {
+	KMS []struct {
+		ARN     string            `json:"arn"`
+		Context map[string]string `json:"context"`
+		Enc     string            `json:"enc"`
+	} `json:"kms"`
+	Age []struct {
+		Recipient string `json:"recipient"`
+		Enc       string `json:"enc"`
+	} `json:"age"`
+	// The master keys that aren't supported, only listed in the error if none is usable
+	PGP              []json.RawMessage `json:"pgp"`
+	GCPKMS           []json.RawMessage `json:"gcp_kms"`
+	AzureKV          []json.RawMessage `json:"azure_kv"`
+	HCVault          []json.RawMessage `json:"hc_vault"`
+	KeyGroups        []json.RawMessage `json:"key_groups"`
+	LastModified     string            `json:"lastmodified"`
+	MAC              string            `json:"mac"`
+	MACOnlyEncrypted bool              `json:"mac_only_encrypted"`
+	// Values under keys with the suffix are left in clear text, even if they look encrypted
+	UnencryptedSuffix string `json:"unencrypted_suffix"`
+}
+
+// dataKey decrypts the data key of the file with the first master key that can
+func (k *SOPSKeys) dataKey(meta *sopsMetadata) ([]byte, error) {
+	if len(meta.KeyGroups) > 0 {
+		return nil, errors.New("key groups aren't supported, decrypt the file with sops")
+	}
+
+	failures := []string{}
+	for _, e := range meta.Age {
+		if len(k.Age) == 0 {
+			failures = append(failures, "age "+e.Recipient+": no identity, set SOPS_AGE_KEY_FILE")
+			continue
+		}
+		key, err := DecryptAge([]byte(e.Enc), k.Age)
+		if err == nil {
+			return key, nil
+		}
+		failures = append(failures, fmt.Sprintf("age %v: %v", e.Recipient, err))
+	}
+
+	for _, e := range meta.KMS {
+		key, err := k.decryptKMS(e.ARN, e.Enc, e.Context)
+		if err == nil {
+			return key, nil
+		}
+		failures = append(failures, fmt.Sprintf("kms %v: %v", e.ARN, err))
+	}
+
+	unsupported := [][]json.RawMessage{meta.PGP, meta.GCPKMS, meta.AzureKV, meta.HCVault}
+	for i, kind := range []string{"pgp", "gcp_kms", "azure_kv", "hc_vault"} {
+		if len(unsupported[i]) > 0 {
+			failures = append(failures, kind+" keys aren't supported")
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil, errors.New("the file has no master keys")
+	}
+	return nil, fmt.Errorf("couldn't decrypt the data key: %v", strings.Join(failures, "; "))
+}
+
+// decryptKMS decrypts a data key with the Decrypt action of AWS KMS
+func (k *SOPSKeys) decryptKMS(arn, enc string, context map[string]string) ([]byte, error) {
+	if k.AWSAccessKey == "" {
+		return nil, errors.New("no AWS credentials, set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	// arn:aws:kms:<region>:<account>:key/<id>
+	parts := strings.Split(arn, ":")
+	if len(parts) < 6 {
+		return nil, errors.New("invalid KMS key ARN")
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted data key: %v", err)
+	}
+
+	config := aws.NewConfig().
+		WithRegion(parts[3]).
+		WithCredentials(credentials.NewStaticCredentials(k.AWSAccessKey, k.AWSSecretKey, k.AWSSessionToken))
+	if k.KMSEndpoint != "" {
+		config = config.WithEndpoint(k.KMSEndpoint)
+	}
+	if k.Client != nil {
+		config = config.WithHTTPClient(k.Client)
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := kms.New(sess).Decrypt(&kms.DecryptInput{
+		CiphertextBlob:    blob,
+		KeyId:             aws.String(arn),
+		EncryptionContext: aws.StringMap(context),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
+
+// decryptSOPSValue decrypts an ENC[...] value, additional data is the path of its key
+func decryptSOPSValue(value string, key []byte, additional string) (string, string, error) {
+	m := sopsValue.FindStringSubmatch(value)
+	if m == nil {
+		return "", "", errors.New("invalid encrypted value")
+	}
+
+	parts := [][]byte{}
+	for _, p := range m[1:4] {
+		b, err := base64.StdEncoding.DecodeString(p)
+		if err != nil {
+			return "", "", errors.New("invalid encrypted value")
+		}
+		parts = append(parts, b)
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", "", err
+	}
+	aead, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", "", err
+	}
+	plain, err := aead.Open(nil, iv, append(data, tag...), []byte(additional))
+	if err != nil {
+		return "", "", errors.New("the value doesn't decrypt, wrong key or tampered with")
+	}
+
+	return string(plain), m[4], nil
+}
+
+// decryptNode decrypts the values of a node in place, writing them into the MAC in order
+func decryptNode(n *sopsNode, path []string, key []byte, mac io.Writer, meta *sopsMetadata) error {
+	switch n.kind {
+	case "map":
+		for i, k := range n.keys {
+			if err := decryptNode(n.values[i], append(path, k), key, mac, meta); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "list":
+		// The items of a list have the path of the list
+		for _, v := range n.values {
+			if err := decryptNode(v, path, key, mac, meta); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	encrypted := n.kind == "string" && sopsValue.MatchString(n.text)
+	for _, p := range path {
+		if meta.UnencryptedSuffix != "" && strings.HasSuffix(p, meta.UnencryptedSuffix) {
+			encrypted = false
+		}
+	}
+	if !encrypted {
+		if !meta.MACOnlyEncrypted {
+			io.WriteString(mac, n.macText())
+		}
+		return nil
+	}
+
+	plain, typ, err := decryptSOPSValue(n.text, key, strings.Join(path, ":")+":")
+	if err != nil {
+		return fmt.Errorf("%v: %v", strings.Join(path, "."), err)
+	}
+	io.WriteString(mac, plain)
+
+	switch typ {
+	case "int", "float":
+		n.kind = "number"
+		if _, err := strconv.ParseFloat(plain, 64); err != nil {
+			return fmt.Errorf("%v: invalid number %q", strings.Join(path, "."), plain)
+		}
+	case "bool":
+		b, err := strconv.ParseBool(plain)
+		if err != nil {
+			return fmt.Errorf("%v: invalid bool %q", strings.Join(path, "."), plain)
+		}
+		n.kind = "bool"
+		plain = strconv.FormatBool(b)
+	default:
+		n.kind = "string"
+	}
+	n.text = plain
+
+	return nil
+}
+
+// DecryptSOPS decrypts a JSON or YAML file encrypted with SOPS, the file is returned as JSON
+// without its sops metadata. The MAC of the file is checked, so values can't be removed,
+// reordered or swapped in from another file unnoticed.
+func (k *SOPSKeys) DecryptSOPS(raw []byte, isYAML bool) ([]byte, error) {
+	tree, meta, err := parseSOPS(raw, isYAML)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := k.dataKey(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptSOPS(tree, meta, key)
+}
+
+// parseSOPS reads the values and the sops metadata of a file
+func parseSOPS(raw []byte, isYAML bool) (*sopsNode, *sopsMetadata, error) {
+	tree, err := parseSOPSNode(raw, isYAML)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metaNode := tree.get(SOPSMetadataKey)
+	if metaNode == nil {
+		return nil, nil, errors.New("no sops metadata")
+	}
+	metaJSON := &bytes.Buffer{}
+	metaNode.writeJSON(metaJSON)
+	meta := &sopsMetadata{}
+	if err := json.Unmarshal(metaJSON.Bytes(), meta); err != nil {
+		return nil, nil, fmt.Errorf("invalid sops metadata: %v", err)
+	}
+
+	return tree, meta, nil
+}
+
+// decryptSOPS decrypts the values of a file with its data key and checks its MAC
+func decryptSOPS(tree *sopsNode, meta *sopsMetadata, key []byte) ([]byte, error) {
+	doc := &sopsNode{kind: "map"}
+	for i, name := range tree.keys {
+		if name != SOPSMetadataKey {
+			doc.keys = append(doc.keys, name)
+			doc.values = append(doc.values, tree.values[i])
+		}
+	}
+
+	hash := sha512.New()
+	if err := decryptNode(doc, nil, key, hash, meta); err != nil {
+		return nil, err
+	}
+
+	expected, _, err := decryptSOPSValue(meta.MAC, key, meta.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("sops MAC: %v", err)
+	}
+	if !strings.EqualFold(expected, hex.EncodeToString(hash.Sum(nil))) {
+		return nil, errors.New("the sops MAC doesn't match the values, the file was tampered with")
+	}
+
+	out := &bytes.Buffer{}
+	doc.writeJSON(out)
+	return out.Bytes(), nil
+}
+
+// openSOPS decrypts the file name if it is encrypted with SOPS, other files are returned
+// as they are
+func openSOPS(name string, raw []byte) ([]byte, bool, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	isYAML := ext == ".yaml" || ext == ".yml"
+	if !IsSOPS(raw, isYAML) {
+		return raw, false, nil
+	}
+
+	k, err := currentSOPSKeys()
+	if err != nil {
+		return nil, false, fmt.Errorf("%v: %v", name, err)
+	}
+	plain, err := k.DecryptSOPS(raw, isYAML)
+	if err != nil {
+		return nil, false, fmt.Errorf("%v: %v", name, err)
+	}
+	return plain, true, nil
+}
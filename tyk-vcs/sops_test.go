@@ -0,0 +1,224 @@
+package tyk_vcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/openpgp"
+	pgparmor "golang.org/x/crypto/openpgp/armor"
+)
+
+// The files of testdata/sops were written by the sops and age CLIs. example.json is the
+// example of the sops repo, its data key encrypted to the PGP key of the sops functional
+// tests (pgp.asc). example.yaml and payments.enc.json were encrypted by sops 3.7.3 with
+// --age to the identity of age.txt, made by age-keygen, as was hello.age by age -a.
+
+// pgpDataKey decrypts the data key of a sops file with the PGP key of the sops functional
+// tests, which tyk-sync doesn't support but the example files of sops are encrypted to
+func pgpDataKey(t *testing.T, meta *sopsMetadata) []byte {
+	f, err := ioutil.ReadFile("testdata/sops/pgp.asc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, raw := range meta.PGP {
+		key := struct {
+			Enc string `json:"enc"`
+		}{}
+		if err := json.Unmarshal(raw, &key); err != nil {
+			t.Fatal(err)
+		}
+		block, err := pgparmor.Decode(strings.NewReader(key.Enc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		md, err := openpgp.ReadMessage(block.Body, ring, nil, nil)
+		if err != nil {
+			continue
+		}
+		dataKey, err := ioutil.ReadAll(md.UnverifiedBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dataKey
+	}
+
+	t.Fatal("no PGP key of the file decrypts")
+	return nil
+}
+
+func TestDecryptSOPS_PGPFixture(t *testing.T) {
+	raw, err := ioutil.ReadFile("testdata/sops/example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSOPS(raw, false) {
+		t.Fatal("expected the sops metadata to be detected")
+	}
+
+	tree, meta, err := parseSOPS(raw, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := decryptSOPS(tree, meta, pgpDataKey(t, meta))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(plain), `{"firstName":"John","lastName":"Smith","age":25.4,"address":{"city":"New York"`) {
+		t.Errorf("expected the decrypted file without its metadata, got %s", plain)
+	}
+
+	// Keys that aren't supported are listed when no other key decrypts the file
+	if _, err := (&SOPSKeys{}).DecryptSOPS(raw, false); err == nil || !strings.Contains(err.Error(), "pgp keys aren't supported") {
+		t.Errorf("expected the PGP keys to be reported, got %v", err)
+	}
+}
+
+func testAgeIdentities(t *testing.T) []age.Identity {
+	raw, err := ioutil.ReadFile("testdata/sops/age.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := ParseAgeIdentities(string(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestDecryptSOPS_Age(t *testing.T) {
+	raw, err := ioutil.ReadFile("testdata/sops/example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSOPS(raw, true) {
+		t.Fatal("expected the sops metadata to be detected")
+	}
+
+	plain, err := (&SOPSKeys{Age: testAgeIdentities(t)}).DecryptSOPS(raw, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"name":"Payments","upstream":{"url":"http://payments.internal","secret":"t0ps3cr3t"},"retries":3,"ratio":0.25,` +
+		`"enabled":true,"tags":["edge","public"],"notes_unencrypted":["left in clear text","by the _unencrypted suffix"]}`
+	if string(plain) != expected {
+		t.Errorf("expected the decrypted file without its metadata, got %s", plain)
+	}
+
+	// Values left in clear text are covered by the MAC too
+	tampered := bytes.Replace(raw, []byte("left in clear text"), []byte("changed unnoticed"), 1)
+	if _, err := (&SOPSKeys{Age: testAgeIdentities(t)}).DecryptSOPS(tampered, true); err == nil || !strings.Contains(err.Error(), "MAC") {
+		t.Errorf("expected the changed value to fail the MAC, got %v", err)
+	}
+
+	other, _ := age.GenerateX25519Identity()
+	if _, err := (&SOPSKeys{Age: []age.Identity{other}}).DecryptSOPS(raw, true); err == nil || !strings.Contains(err.Error(), "no age identity") {
+		t.Errorf("expected another identity not to decrypt the data key, got %v", err)
+	}
+}
+
+func TestDecryptAge(t *testing.T) {
+	raw, err := ioutil.ReadFile("testdata/sops/hello.age")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := DecryptAge(raw, testAgeIdentities(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plain) != "tyk-sync\n" {
+		t.Errorf("unexpected plain text %q", plain)
+	}
+
+	if _, err := ParseAgeIdentities("AGE-SECRET-KEY-1INVALID"); err == nil {
+		t.Error("expected an invalid identity to fail")
+	}
+}
+
+// sopsAgeKeys are the age master keys of a file sops wrote as JSON
+var sopsAgeKeys = regexp.MustCompile(`(?s)"age": \[.*?\]`)
+
+func TestDecryptSOPS_KMS(t *testing.T) {
+	raw, err := ioutil.ReadFile("testdata/sops/payments.enc.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, meta, err := parseSOPS(raw, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKey, err := DecryptAge([]byte(meta.Age[0].Enc), testAgeIdentities(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const arn = "arn:aws:kms:eu-west-1:123456789012:key/1234abcd"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.Header.Get("X-Amz-Target") != "TrentService.Decrypt" || req["KeyId"] != arn || req["CiphertextBlob"] != "YmxvYg==" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/kms/aws4_request") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": dataKey})
+	}))
+	defer ts.Close()
+
+	// The master keys aren't covered by the MAC, so the data key can be swapped to a KMS key
+	kmsFile := sopsAgeKeys.ReplaceAll(raw, []byte(fmt.Sprintf(`"kms": [{"arn": %q, "enc": "YmxvYg=="}]`, arn)))
+	kmsFile = bytes.Replace(kmsFile, []byte(`"kms": null,`), nil, 1)
+
+	keys := &SOPSKeys{AWSAccessKey: "key", AWSSecretKey: "secret", KMSEndpoint: ts.URL}
+	plain, err := keys.DecryptSOPS(kmsFile, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(plain), `"target_url":"http://payments.internal"`) {
+		t.Errorf("expected the decrypted file, got %s", plain)
+	}
+
+	if _, err := (&SOPSKeys{}).DecryptSOPS(kmsFile, false); err == nil || !strings.Contains(err.Error(), "no AWS credentials") {
+		t.Errorf("expected KMS keys to need credentials, got %v", err)
+	}
+}
+
+func TestFetchSOPSDefinitions(t *testing.T) {
+	raw, err := ioutil.ReadFile("testdata/sops/payments.enc.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetSOPSKeys(&SOPSKeys{Age: testAgeIdentities(t)})
+	defer SetSOPSKeys(nil)
+
+	g := memGetter(t, map[string]string{
+		".tyk.json":         `{"type": "apidef", "files": [{"file": "payments.enc.json"}]}`,
+		"payments.enc.json": string(raw),
+	})
+
+	spec, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := g.FetchAPIDef(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defs[0].Proxy.TargetURL != "http://payments.internal" || defs[0].Auth.AuthHeaderName != "X-Secret-Header" {
+		t.Errorf("expected the decrypted definition, got %+v", defs[0].APIDefinition)
+	}
+}
@@ -0,0 +1,345 @@
+package tyk_vcs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+)
+
+// Source is where the spec file and the files it lists are read from, e.g. a bucket or an
+// artifact repository. Sources are read whole, by a SourceGetter, before the spec is loaded.
+type Source interface {
+	// List returns the paths of the files of the source, relative to its root and separated
+	// by slashes
+	List() ([]string, error)
+	// Read returns the contents of a file List returned
+	Read(name string) ([]byte, error)
+}
+
+// SourceFactory opens the source of a URL of the scheme it is registered for
+type SourceFactory func(u *url.URL) (Source, error)
+
+var (
+	sourcesMu sync.Mutex
+	sources   = map[string]SourceFactory{
+		"file":  newFileSource,
+		"s3":    newS3Source,
+		"http":  newHTTPSource,
+		"https": newHTTPSource,
+	}
+)
+
+// RegisterSource makes the sources of the URLs of scheme readable by the commands taking a
+// repo, e.g. artifactory://repo/apis. Programs embedding tyk-sync register theirs from an
+// init function, a scheme registered again replaces the source before.
+func RegisterSource(scheme string, f SourceFactory) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	sources[strings.ToLower(scheme)] = f
+}
+
+// archiveSuffixes are the archives HTTP sources download, other HTTP URLs are git repos
+var archiveSuffixes = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+func isArchive(name string) bool {
+	for _, s := range archiveSuffixes {
+		if strings.HasSuffix(strings.ToLower(name), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSource opens the source of rawurl if its scheme is registered, ok is false for the
+// URLs of git repos, which include the HTTP URLs that aren't archives
+func NewSource(rawurl string) (src Source, ok bool, err error) {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Scheme == "" {
+		// scp-like git URLs, e.g. git@github.com:org/repo.git
+		return nil, false, nil
+	}
+
+	sourcesMu.Lock()
+	f, ok := sources[strings.ToLower(u.Scheme)]
+	sourcesMu.Unlock()
+	if !ok || ((u.Scheme == "http" || u.Scheme == "https") && !isArchive(u.Path)) {
+		return nil, false, nil
+	}
+
+	src, err = f(u)
+	return src, true, err
+}
+
+// SourceGetter reads the spec and objects of a source, which FetchRepo copies to memory
+type SourceGetter struct {
+	src Source
+	fs  billy.Filesystem
+}
+
+func NewSourceGetter(src Source) *SourceGetter {
+	return &SourceGetter{src: src, fs: memfs.New()}
+}
+
+func (sg *SourceGetter) FetchRepo() error {
+	names, err := sg.src.List()
+	if err != nil {
+		return err
+	}
+
+	fs := memfs.New()
+	for _, name := range names {
+		data, err := sg.src.Read(name)
+		if err != nil {
+			return fmt.Errorf("%v: %v", name, err)
+		}
+		if err := util.WriteFile(fs, specPath(name), data, 0644); err != nil {
+			return err
+		}
+	}
+	sg.fs = fs
+	fmt.Printf("Fetched %v files\n", len(names))
+
+	return nil
+}
+
+func (sg *SourceGetter) FetchTykSpec() (*TykSourceSpec, error) {
+	return fetchSpec(sg.fs)
+}
+
+func (sg *SourceGetter) FetchAPIDef(spec *TykSourceSpec) ([]objects.DBApiDefinition, error) {
+	return fetchAPIDefinitions(sg.fs, spec)
+}
+
+func (sg *SourceGetter) FetchPolicies(spec *TykSourceSpec) ([]objects.Policy, error) {
+	return fetchPolicies(sg.fs, spec)
+}
+
+func (sg *SourceGetter) FetchKeys(spec *TykSourceSpec) ([]objects.Key, error) {
+	return fetchKeys(sg.fs, spec)
+}
+
+func (sg *SourceGetter) FetchOAuthClients(spec *TykSourceSpec) ([]objects.OAuthClient, error) {
+	return fetchOAuthClients(sg.fs, spec)
+}
+
+func (sg *SourceGetter) FetchCertificates(spec *TykSourceSpec) ([]Certificate, error) {
+	return fetchCertificates(sg.fs, spec)
+}
+
+func (sg *SourceGetter) FetchContractTests(spec *TykSourceSpec) ([]ContractTests, error) {
+	return fetchContractTests(sg.fs, spec)
+}
+
+// FSSource reads the files of a directory
+type FSSource struct {
+	fs billy.Filesystem
+}
+
+func NewFSSource(dir string) *FSSource {
+	return &FSSource{fs: osfs.New(dir)}
+}
+
+func newFileSource(u *url.URL) (Source, error) {
+	return NewFSSource(path.Join(u.Host, u.Path)), nil
+}
+
+func (s *FSSource) List() ([]string, error) {
+	return walkFiles(s.fs, "")
+}
+
+func (s *FSSource) Read(name string) ([]byte, error) {
+	return readFile(s.fs, name)
+}
+
+// HTTPSource downloads a tar, gzipped tar or zip archive of the files. The user info of the
+// URL is sent as basic auth, or TYKGIT_SOURCE_TOKEN as a bearer token. An archive holding a
+// single directory with the spec file in it, as those of Github releases, is read from it.
+type HTTPSource struct {
+	URL    string
+	Header http.Header
+	Client *http.Client
+
+	files map[string][]byte
+}
+
+func newHTTPSource(u *url.URL) (Source, error) {
+	s := &HTTPSource{Header: http.Header{}}
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		s.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(u.User.Username()+":"+pass)))
+	} else if token := os.Getenv("TYKGIT_SOURCE_TOKEN"); token != "" {
+		s.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	copied := *u
+	copied.User = nil
+	s.URL = copied.String()
+
+	return s, nil
+}
+
+func (s *HTTPSource) download() error {
+	req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range s.Header {
+		req.Header[k] = v
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v returned %v", s.URL, resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	u, _ := url.Parse(s.URL)
+	if strings.HasSuffix(strings.ToLower(u.Path), ".zip") {
+		s.files, err = readZip(data)
+	} else {
+		s.files, err = readTar(data, !strings.HasSuffix(strings.ToLower(u.Path), ".tar"))
+	}
+	if err != nil {
+		return fmt.Errorf("%v: %v", s.URL, err)
+	}
+	s.files = archiveRoot(s.files)
+
+	return nil
+}
+
+func readTar(data []byte, gzipped bool) (map[string][]byte, error) {
+	var r io.Reader = bytes.NewReader(data)
+	if gzipped {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		contents, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[strings.TrimPrefix(path.Clean("/"+h.Name), "/")] = contents
+	}
+}
+
+func readZip(data []byte) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		contents, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[strings.TrimPrefix(path.Clean("/"+f.Name), "/")] = contents
+	}
+
+	return files, nil
+}
+
+// archiveRoot moves the files of an archive holding a single directory with the spec file up
+// to the root
+func archiveRoot(files map[string][]byte) map[string][]byte {
+	if _, ok := files[SpecFile]; ok {
+		return files
+	}
+
+	top := ""
+	for name := range files {
+		dir := strings.SplitN(name, "/", 2)[0]
+		if dir == name || (top != "" && dir != top) {
+			return files
+		}
+		top = dir
+	}
+	if _, ok := files[top+"/"+SpecFile]; !ok {
+		return files
+	}
+
+	moved := map[string][]byte{}
+	for name, contents := range files {
+		moved[strings.TrimPrefix(name, top+"/")] = contents
+	}
+	return moved
+}
+
+func (s *HTTPSource) List() ([]string, error) {
+	if s.files == nil {
+		if err := s.download(); err != nil {
+			return nil, err
+		}
+	}
+
+	names := []string{}
+	for name := range s.files {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (s *HTTPSource) Read(name string) ([]byte, error) {
+	contents, ok := s.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%v is not in %v", name, s.URL)
+	}
+	return contents, nil
+}
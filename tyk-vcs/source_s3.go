@@ -0,0 +1,196 @@
+package tyk_vcs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// S3Source reads the objects under a prefix of an S3 bucket, or of an S3 compatible store
+// such as MinIO with Endpoint set. The requests are signed with AWS Signature Version 4
+// unless no access key is set, for public buckets.
+type S3Source struct {
+	Bucket string
+	Prefix string
+	Region string
+	// Endpoint is the URL of an S3 compatible store, the bucket is then part of the path of
+	// the requests rather than of their host
+	Endpoint     string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Client       *http.Client
+}
+
+// newS3Source opens s3://bucket/prefix, the region and endpoint may be set with the region
+// and endpoint query parameters, the credentials and region are read from the standard AWS
+// environment variables
+func newS3Source(u *url.URL) (Source, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("%q has no bucket", u.String())
+	}
+
+	s := &S3Source{
+		Bucket:       u.Host,
+		Prefix:       strings.TrimPrefix(u.Path, "/"),
+		Region:       u.Query().Get("region"),
+		Endpoint:     u.Query().Get("endpoint"),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	for _, env := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if s.Region == "" {
+			s.Region = os.Getenv(env)
+		}
+	}
+	if s.Region == "" {
+		s.Region = "us-east-1"
+	}
+	if s.Endpoint == "" {
+		s.Endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if s.Prefix != "" && !strings.HasSuffix(s.Prefix, "/") {
+		s.Prefix += "/"
+	}
+
+	return s, nil
+}
+
+// sign adds the headers of Signature Version 4 to a request without a body, requests of
+// public buckets aren't signed
+func (s *S3Source) sign(req *http.Request, now time.Time) error {
+	if s.AccessKey == "" {
+		return nil
+	}
+
+	signer := v4.NewSigner(credentials.NewStaticCredentials(s.AccessKey, s.SecretKey, s.SessionToken), func(v *v4.Signer) {
+		// The path of objectURL is escaped already
+		v.DisableURIPathEscaping = true
+	})
+	_, err := signer.Sign(req, nil, "s3", s.Region, now)
+	return err
+}
+
+// awsEscape encodes s as the canonical requests of Signature Version 4 expect: every byte
+// but the unreserved characters, and the slashes of paths, percent-encoded in upper case
+func awsEscape(s string, path bool) string {
+	out := &strings.Builder{}
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			out.WriteByte(b)
+		case b == '/' && path:
+			out.WriteByte(b)
+		default:
+			fmt.Fprintf(out, "%%%02X", b)
+		}
+	}
+	return out.String()
+}
+
+// objectURL is the URL of a key of the bucket, or of the bucket itself for an empty key
+func (s *S3Source) objectURL(key string, query url.Values) (*url.URL, error) {
+	var u *url.URL
+	if s.Endpoint != "" {
+		base, err := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+		if err != nil {
+			return nil, err
+		}
+		u = base
+		u.Path += "/" + s.Bucket + "/" + key
+	} else {
+		u = &url.URL{Scheme: "https", Host: fmt.Sprintf("%v.s3.%v.amazonaws.com", s.Bucket, s.Region), Path: "/" + key}
+	}
+	// The path is sent as it is signed
+	u.RawPath = awsEscape(u.Path, true)
+	u.RawQuery = strings.Replace(query.Encode(), "+", "%20", -1)
+
+	return u, nil
+}
+
+func (s *S3Source) get(key string, query url.Values) ([]byte, error) {
+	u, err := s.objectURL(key, query)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sign(req, time.Now()); err != nil {
+		return nil, err
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("S3 returned error: %v (code: %v)", strings.TrimSpace(string(body)), resp.StatusCode)
+	}
+
+	return body, nil
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *S3Source) List() ([]string, error) {
+	names := []string{}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		body, err := s.get("", query)
+		if err != nil {
+			return nil, err
+		}
+		list := s3ListResult{}
+		if err := xml.Unmarshal(body, &list); err != nil {
+			return nil, err
+		}
+
+		for _, c := range list.Contents {
+			// Folders made in the console are empty objects named with a trailing slash
+			if name := strings.TrimPrefix(c.Key, s.Prefix); name != "" && !strings.HasSuffix(name, "/") {
+				names = append(names, name)
+			}
+		}
+		if !list.IsTruncated || list.NextContinuationToken == "" {
+			return names, nil
+		}
+		token = list.NextContinuationToken
+	}
+}
+
+func (s *S3Source) Read(name string) ([]byte, error) {
+	return s.get(s.Prefix+name, nil)
+}
@@ -1,5 +1,10 @@
 package tyk_vcs
 
+import (
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-patch"
+)
+
 type PublishAction string
 type SpecType string
 
@@ -8,10 +13,21 @@ const (
 	UPDATE PublishAction = "update"
 	ERROR  PublishAction = "error"
 
-	TYPE_APIDEF SpecType = "apidef"
-	TYPE_OAI    SpecType = "oas"
+	TYPE_APIDEF    SpecType = "apidef"
+	TYPE_OAI       SpecType = "oas"
+	TYPE_POSTMAN   SpecType = "postman"
+	TYPE_BLUEPRINT SpecType = "blueprint"
+	TYPE_WSDL      SpecType = "wsdl"
+	TYPE_OPERATOR  SpecType = "operator"
+	// TYPE_GRPC files are compiled protobuf descriptor sets (protoc -o), every service becomes
+	// a definition
+	TYPE_GRPC SpecType = "grpc"
+	// TYPE_ASYNCAPI files are AsyncAPI documents of websocket servers
+	TYPE_ASYNCAPI SpecType = "asyncapi"
 )
 
+// APIInfo lists an API definition file, File may be a pattern matching several files, e.g.
+// apis/**/*.json, see MatchGlob
 type APIInfo struct {
 	File  string `json:"file,omitempty"`
 	APIID string `json:"api_id,omitempty"`
@@ -23,15 +39,175 @@ type APIInfo struct {
 		VersionName        string `json:"version_name,omitempty"`
 		StripListenPath    bool   `json:"strip_listen_path,omitempty"`
 	} `json:"oas,omitempty"`
+	// Patches are JSON Patch (RFC 6902) operations applied to the definition, keyed by target profile
+	Patches map[string][]tyk_patch.Operation `json:"patches,omitempty"`
+	// Display are the dashboard presentation fields of the API
+	Display *DisplayInfo `json:"display,omitempty"`
+	// Exclude are patterns of the files the File pattern doesn't list
+	Exclude []string `json:"exclude,omitempty"`
+	// Sunset marks the API as deprecated, to be deactivated on its date
+	Sunset *SunsetInfo `json:"sunset,omitempty"`
+	// IDSeed derives the object ID of the API when db_id isn't set, see objects.SeedID
+	IDSeed string `json:"id_seed,omitempty"`
 }
 
 type PolicyInfo struct {
+	File    string                           `json:"file,omitempty"`
+	ID      string                           `json:"id,omitempty"`
+	Patches map[string][]tyk_patch.Operation `json:"patches,omitempty"`
+	Exclude []string                         `json:"exclude,omitempty"`
+	// IDSeed derives the _id of the policy, see objects.SeedID
+	IDSeed string `json:"id_seed,omitempty"`
+}
+
+// KeyInfo points to a gateway session dumped with `dump --gateway --keys`
+type KeyInfo struct {
+	File  string `json:"file,omitempty"`
+	KeyID string `json:"key_id,omitempty"`
+}
+
+// OAuthClientInfo points to an OAuth client dumped with `dump --oauth-clients`
+type OAuthClientInfo struct {
+	File     string `json:"file,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	APIID    string `json:"api_id,omitempty"`
+}
+
+// CertificateInfo points to a certificate, the file is either a PEM file or the
+// certificate meta data written by a gateway dump
+type CertificateInfo struct {
 	File string `json:"file,omitempty"`
 	ID   string `json:"id,omitempty"`
 }
 
+// Certificate is a certificate listed in the spec, PEM is empty when the file only
+// holds the meta data of a gateway dump
+type Certificate struct {
+	ID  string
+	PEM []byte
+}
+
 type TykSourceSpec struct {
-	Type     SpecType     `json:"type,omitempty"`
-	Files    []APIInfo    `json:"files,omitempty"`
-	Policies []PolicyInfo `json:"policies,omitempty"`
+	// Include are spec files, or patterns of them such as teams/*/tyk.json, merged into the
+	// spec, see mergeSpec
+	Include      []string          `json:"include,omitempty"`
+	Type         SpecType          `json:"type,omitempty"`
+	Files        []APIInfo         `json:"files,omitempty"`
+	Policies     []PolicyInfo      `json:"policies,omitempty"`
+	Keys         []KeyInfo         `json:"keys,omitempty"`
+	OAuthClients []OAuthClientInfo `json:"oauth_clients,omitempty"`
+	Certificates []CertificateInfo `json:"certificates,omitempty"`
+	// ContractTests are the files of the request and response cases run after publishing
+	ContractTests []ContractTestInfo       `json:"contract_tests,omitempty"`
+	Profiles      map[string]TargetProfile `json:"profiles,omitempty"`
+	Products      []ProductInfo            `json:"products,omitempty"`
+	Tenants       []TenantInfo             `json:"tenants,omitempty"`
+	Protect       *ProtectInfo             `json:"protect,omitempty"`
+	// Windows are the deployment windows changes may be applied in, any time if empty
+	Windows []WindowInfo `json:"windows,omitempty"`
+	// StripProfiles are named sets of fields to strip from definitions, see TargetProfile.Strip
+	StripProfiles map[string][]string `json:"strip_profiles,omitempty"`
+	// Ignore are the rules, see tyk_diff.ParseRule, of the fields verify leaves out of the
+	// comparison as they legitimately differ per environment, e.g. active or tags[]
+	Ignore []string `json:"ignore,omitempty"`
+	// Requires constrains the versions of tyk-sync and of the targets, see RequiresInfo
+	Requires *RequiresInfo `json:"requires,omitempty"`
+	// DataPlanes are the MDCB data plane groups publishes are staggered by, in order
+	DataPlanes []DataPlane `json:"data_planes,omitempty"`
+	// Guardrails are the limits the policies must keep to be published
+	Guardrails *GuardrailsInfo `json:"guardrails,omitempty"`
+	// Repo identifies the repo on the targets: the objects published from it are marked
+	// with it, see gc. Nothing is marked if it is empty.
+	Repo string `json:"repo,omitempty"`
+
+	// patterns are the patterns API and policy files were listed by, before expandGlobs
+	// replaced them with the files they match
+	patterns []string
+	// includes are the spec files it was read from, itself included
+	includes []string
+}
+
+// TenantInfo maps a subdirectory, which holds its own spec file, to a dashboard org. The
+// secret of a dashboard user of that org is read from the SecretEnv environment variable.
+type TenantInfo struct {
+	Path      string `json:"path"`
+	OrgID     string `json:"org_id"`
+	SecretEnv string `json:"secret_env,omitempty"`
+}
+
+// ProductInfo groups APIs (by API ID) with the policies (by ID) they need, a product is
+// published as one unit
+type ProductInfo struct {
+	Name     string   `json:"name"`
+	APIs     []string `json:"apis,omitempty"`
+	Policies []string `json:"policies,omitempty"`
+}
+
+// TargetProfile holds conventions for one target environment, they are applied to
+// every definition published while the profile is selected (--profile)
+type TargetProfile struct {
+	// Tags are merged into the tags of every definition, use these for gateway segment tags
+	Tags []string `json:"tags,omitempty"`
+	// RemoveTags are removed from the tags of every definition before Tags are merged, they
+	// may be patterns like edge-*, e.g. to move APIs from one segment to another
+	RemoveTags []string `json:"remove_tags,omitempty"`
+	// TagsDisabled sets tags_disabled, which gateways newer than the vendored apidef know, it
+	// is only sent with passthrough
+	TagsDisabled            *bool    `json:"tags_disabled,omitempty"`
+	TagHeaders              []string `json:"tag_headers,omitempty"`
+	EnableDetailedRecording *bool    `json:"enable_detailed_recording,omitempty"`
+	DoNotTrack              *bool    `json:"do_not_track,omitempty"`
+	ExpireAnalyticsAfter    int64    `json:"expire_analytics_after,omitempty"`
+	// DomainMap replaces the domains of the definitions, in the domain field and the CORS
+	// allowed origins, e.g. api.dev.example.com with api.example.com
+	DomainMap map[string]string `json:"domain_map,omitempty"`
+	// Strip names the strip profiles whose fields are removed from the published definitions,
+	// e.g. cloud-safe
+	Strip []string `json:"strip,omitempty"`
+	// EncryptTo are ASCII armored OpenPGP public key files, relative to the spec file, that
+	// dumps with the profile encrypt the files of keys to
+	EncryptTo []string `json:"encrypt_to,omitempty"`
+	// ListParams are query parameters sent with the dashboard list calls, e.g. for a proxy in
+	// front of the dashboard
+	ListParams map[string]string `json:"list_params,omitempty"`
+	// PageSize fetches the dashboard lists page by page rather than at once, for proxies that
+	// limit response sizes, it is the page_size the dashboard is configured with
+	PageSize int `json:"page_size,omitempty"`
+	// Limits are the most objects the plan of the target allows, checked before a sync
+	Limits *PlanLimits `json:"limits,omitempty"`
+}
+
+// apiInfo returns the file entry of def: the one of the file it was read from, or for a
+// definition not read from a listed file, the one setting its API ID
+func (ts *TykSourceSpec) apiInfo(def *objects.DBApiDefinition) (APIInfo, bool) {
+	if def.APIDefinition == nil {
+		return APIInfo{}, false
+	}
+	for _, info := range ts.Files {
+		if def.File != "" && specPath(info.File) == specPath(def.File) {
+			return info, true
+		}
+	}
+	for _, info := range ts.Files {
+		if def.File == "" && info.APIID != "" && info.APIID == def.APIID {
+			return info, true
+		}
+	}
+	return APIInfo{}, false
+}
+
+// policyInfo returns the file entry of pol: the one of the file it was read from, or for a
+// policy not read from a listed file, the one setting its ID
+func (ts *TykSourceSpec) policyInfo(pol *objects.Policy) (PolicyInfo, bool) {
+	for _, info := range ts.Policies {
+		if pol.File != "" && specPath(info.File) == specPath(pol.File) {
+			return info, true
+		}
+	}
+	for _, info := range ts.Policies {
+		if pol.File == "" && info.ID != "" && info.ID == pol.ID {
+			return info, true
+		}
+	}
+	return PolicyInfo{}, false
 }
@@ -0,0 +1,132 @@
+package tyk_vcs
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// DataPlane is a group of MDCB data plane gateways, those segmented to load the APIs tagged
+// with Tag, e.g. an edge region
+type DataPlane struct {
+	Tag string `json:"tag"`
+	// GatewayURL is a gateway of the group, the APIs rolled out to the group are checked live
+	// on it before the next group gets them (optional)
+	GatewayURL string `json:"gateway_url,omitempty"`
+}
+
+// Stagger rolls definitions out to the data plane groups of an MDCB control plane one group
+// after the other, in the order of Groups, so a change doesn't reload every edge region at
+// once
+type Stagger struct {
+	Groups []DataPlane
+}
+
+func NewStagger(groups []DataPlane) (*Stagger, error) {
+	if len(groups) == 0 {
+		return nil, errors.New("the spec lists no data_planes to stagger the publish by")
+	}
+
+	seen := map[string]bool{}
+	for _, g := range groups {
+		if g.Tag == "" {
+			return nil, errors.New("data plane groups need a tag")
+		}
+		if seen[g.Tag] {
+			return nil, fmt.Errorf("data plane %v is listed twice", g.Tag)
+		}
+		seen[g.Tag] = true
+	}
+
+	return &Stagger{Groups: groups}, nil
+}
+
+// group returns the index of the group of tag, -1 if it isn't a data plane tag
+func (s *Stagger) group(tag string) int {
+	for i, g := range s.Groups {
+		if g.Tag == tag {
+			return i
+		}
+	}
+	return -1
+}
+
+// grouped tells if def is tagged for any data plane group
+func (s *Stagger) grouped(def objects.DBApiDefinition) bool {
+	for _, t := range def.Tags {
+		if s.group(t) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Stage returns copies of the definitions rolled out with the group at index n: those tagged
+// with its tag, and with the first group those tagged for no group. Their group tags are
+// limited to the groups up to n, so the later groups don't load new APIs yet. deployed are
+// the tags the APIs already have on the target by API ID, these are kept until the last
+// group, so no group stops serving an API before the roll out completes.
+func (s *Stagger) Stage(defs []objects.DBApiDefinition, deployed map[string][]string, n int) []objects.DBApiDefinition {
+	last := n == len(s.Groups)-1
+	staged := []objects.DBApiDefinition{}
+	for _, d := range defs {
+		if d.APIDefinition == nil {
+			continue
+		}
+
+		in := n == 0 && !s.grouped(d)
+		tags := []string{}
+		for _, t := range d.Tags {
+			g := s.group(t)
+			if g == n {
+				in = true
+			}
+			if g <= n {
+				tags = append(tags, t)
+			}
+		}
+		for _, t := range deployed[d.APIID] {
+			if s.group(t) < 0 || hasTag(tags, t) {
+				continue
+			}
+			if last {
+				// Removed from the groups git no longer tags it for
+				in = true
+				continue
+			}
+			tags = append(tags, t)
+		}
+		if !in {
+			continue
+		}
+
+		def := *d.APIDefinition
+		def.Tags = tags
+		d.APIDefinition = &def
+		staged = append(staged, d)
+	}
+
+	return staged
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Ungrouped returns the names of the definitions tagged for no data plane group, which the
+// segmented data planes don't load
+func (s *Stagger) Ungrouped(defs []objects.DBApiDefinition) []string {
+	names := []string{}
+	for _, d := range defs {
+		if d.APIDefinition != nil && !s.grouped(d) {
+			names = append(names, d.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,66 @@
+package tyk_vcs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func staggerDef(id string, tags ...string) objects.DBApiDefinition {
+	return objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: id, Name: id, Tags: tags}}
+}
+
+func TestStagger(t *testing.T) {
+	s, err := NewStagger([]DataPlane{{Tag: "eu"}, {Tag: "us"}, {Tag: "ap"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defs := []objects.DBApiDefinition{
+		staggerDef("global", "eu", "us", "ap", "public"),
+		staggerDef("us-only", "us"),
+		staggerDef("control", "internal"),
+		staggerDef("moved", "ap"),
+	}
+	// global is already served by us and ap, moved by us only
+	deployed := map[string][]string{
+		"global": {"us", "ap", "public"},
+		"moved":  {"us"},
+	}
+
+	expected := []map[string][]string{
+		{"global": {"eu", "public", "us", "ap"}, "control": {"internal"}},
+		{"global": {"eu", "us", "public", "ap"}, "us-only": {"us"}},
+		{"global": {"eu", "us", "ap", "public"}, "moved": {"ap"}},
+	}
+	for n := range s.Groups {
+		got := map[string][]string{}
+		for _, d := range s.Stage(defs, deployed, n) {
+			got[d.APIID] = d.Tags
+		}
+		if !reflect.DeepEqual(got, expected[n]) {
+			t.Errorf("group %v: expected %v, got %v", n, expected[n], got)
+		}
+	}
+
+	// New APIs only get the tags of the groups rolled out so far
+	if staged := s.Stage(defs[:1], nil, 0); !reflect.DeepEqual(staged[0].Tags, []string{"eu", "public"}) {
+		t.Errorf("expected a new API to be limited to the first group, got %v", staged[0].Tags)
+	}
+
+	if len(defs[0].Tags) != 4 {
+		t.Errorf("expected the definitions to keep their tags, got %v", defs[0].Tags)
+	}
+	if names := s.Ungrouped(defs); !reflect.DeepEqual(names, []string{"control"}) {
+		t.Errorf("unexpected ungrouped APIs %v", names)
+	}
+
+	if _, err := NewStagger(nil); err == nil {
+		t.Error("expected a spec without data planes to fail")
+	}
+	if _, err := NewStagger([]DataPlane{{Tag: "eu"}, {Tag: "eu"}}); err == nil {
+		t.Error("expected a group listed twice to fail")
+	}
+}
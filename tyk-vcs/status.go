@@ -0,0 +1,127 @@
+package tyk_vcs
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/levigross/grequests"
+)
+
+const (
+	StatusGitHub = "github"
+	StatusGitLab = "gitlab"
+
+	// GitHub rejects longer descriptions
+	maxStatusDescription = 140
+)
+
+// CommitStatus posts the outcome of a run as a commit status (GitHub) or pipeline job
+// status (GitLab), so branch protection can require a successful sync
+type CommitStatus struct {
+	Provider string
+	APIURL   string
+	// Repo is owner/name on GitHub and the project ID on GitLab
+	Repo    string
+	SHA     string
+	Token   string
+	Context string
+}
+
+// CommitStatusFromEnv reads the commit and repository from the variables GitHub Actions
+// and GitLab CI set, the token is read from GITHUB_TOKEN or GITLAB_TOKEN
+func CommitStatusFromEnv(provider, context string, getenv func(string) string) (*CommitStatus, error) {
+	s := &CommitStatus{Provider: provider, Context: context}
+
+	switch provider {
+	case StatusGitHub:
+		s.APIURL = getenv("GITHUB_API_URL")
+		if s.APIURL == "" {
+			s.APIURL = "https://api.github.com"
+		}
+		s.Repo = getenv("GITHUB_REPOSITORY")
+		s.SHA = getenv("GITHUB_SHA")
+		s.Token = getenv("GITHUB_TOKEN")
+	case StatusGitLab:
+		s.APIURL = getenv("CI_API_V4_URL")
+		s.Repo = getenv("CI_PROJECT_ID")
+		s.SHA = getenv("CI_COMMIT_SHA")
+		s.Token = getenv("GITLAB_TOKEN")
+	default:
+		return nil, fmt.Errorf("unknown commit status provider %q, must be github or gitlab", provider)
+	}
+
+	if s.APIURL == "" || s.Repo == "" || s.SHA == "" || s.Token == "" {
+		return nil, errors.New("the commit, repository or token to post the commit status with is not set in the environment")
+	}
+
+	return s, nil
+}
+
+// providerHeaders authenticates the requests to the API of provider with token
+func providerHeaders(provider, token string) map[string]string {
+	if provider == StatusGitLab {
+		return map[string]string{"PRIVATE-TOKEN": token}
+	}
+
+	return map[string]string{
+		"Authorization": "token " + token,
+		"Accept":        "application/vnd.github.v3+json",
+	}
+}
+
+// Post sets the status of the commit
+func (s *CommitStatus) Post(success bool, description string) error {
+	if len(description) > maxStatusDescription {
+		description = description[:maxStatusDescription-3] + "..."
+	}
+
+	var fullPath string
+	var ro *grequests.RequestOptions
+	state := "success"
+
+	switch s.Provider {
+	case StatusGitHub:
+		if !success {
+			state = "failure"
+		}
+
+		fullPath = fmt.Sprintf("%v/repos/%v/statuses/%v", strings.TrimSuffix(s.APIURL, "/"), s.Repo, s.SHA)
+		ro = &grequests.RequestOptions{
+			JSON: map[string]string{
+				"state":       state,
+				"context":     s.Context,
+				"description": description,
+			},
+			Headers: providerHeaders(s.Provider, s.Token),
+		}
+	case StatusGitLab:
+		if !success {
+			state = "failed"
+		}
+
+		fullPath = fmt.Sprintf("%v/projects/%v/statuses/%v", strings.TrimSuffix(s.APIURL, "/"), url.PathEscape(s.Repo), s.SHA)
+		ro = &grequests.RequestOptions{
+			JSON: map[string]string{
+				"state":       state,
+				"name":        s.Context,
+				"description": description,
+			},
+			Headers: providerHeaders(s.Provider, s.Token),
+		}
+	default:
+		return fmt.Errorf("unknown commit status provider %q", s.Provider)
+	}
+
+	resp, err := grequests.Post(fullPath, ro)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("commit status returned error: %v (code: %v)", resp.String(), resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCommitStatusGitHub(t *testing.T) {
+	var path string
+	body := map[string]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	env := map[string]string{
+		"GITHUB_API_URL":    ts.URL,
+		"GITHUB_REPOSITORY": "org/repo",
+		"GITHUB_SHA":        "abc123",
+		"GITHUB_TOKEN":      "token",
+	}
+	s, err := CommitStatusFromEnv(StatusGitHub, "tyk-sync", func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Post(false, strings.Repeat("x", 200)); err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/repos/org/repo/statuses/abc123" {
+		t.Errorf("unexpected path %v", path)
+	}
+	if body["state"] != "failure" || body["context"] != "tyk-sync" || len(body["description"]) != maxStatusDescription {
+		t.Errorf("unexpected status %v", body)
+	}
+
+	delete(env, "GITHUB_TOKEN")
+	if _, err := CommitStatusFromEnv(StatusGitHub, "tyk-sync", func(k string) string { return env[k] }); err == nil {
+		t.Error("expected an error without a token")
+	}
+}
@@ -0,0 +1,43 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StripProfiles are the built in strip profiles, named sets of API definition fields (dotted
+// JSON paths) that particular targets reject, a spec can add its own in strip_profiles
+var StripProfiles = map[string][]string{
+	// The features Tyk Cloud doesn't support, see dashboard.CheckCloudRestrictions. The
+	// protocol is kept, a TCP or TLS API without it would be published as an HTTP one.
+	"cloud-safe": {"listen_port", "enable_proxy_protocol"},
+	// The database ID the dashboard keeps, a gateway has no use for it
+	"gateway-only": {"id"},
+}
+
+// StripFields resolves the strip profiles selected by the target profile to the fields to
+// remove from the definitions, profiles of the spec take precedence over the built in ones
+func (ts *TykSourceSpec) StripFields(tp *TargetProfile) ([]string, error) {
+	fields := []string{}
+	for _, name := range tp.Strip {
+		profile, ok := ts.StripProfiles[name]
+		if !ok {
+			profile, ok = StripProfiles[name]
+		}
+		if !ok {
+			known := []string{}
+			for n := range StripProfiles {
+				known = append(known, n)
+			}
+			for n := range ts.StripProfiles {
+				known = append(known, n)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown strip profile %v, expected one of %v", name, known)
+		}
+
+		fields = mergeStrings(fields, profile)
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,27 @@
+package tyk_vcs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStripFields(t *testing.T) {
+	spec := &TykSourceSpec{StripProfiles: map[string][]string{
+		"legacy":       {"graphql", "proxy.preserve_host_header"},
+		"gateway-only": {"id", "domain"},
+	}}
+
+	fields, err := spec.StripFields(&TargetProfile{Strip: []string{"cloud-safe", "legacy", "gateway-only"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"listen_port", "enable_proxy_protocol", "graphql", "proxy.preserve_host_header", "id", "domain"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("expected %v, got %v", expected, fields)
+	}
+
+	if _, err := spec.StripFields(&TargetProfile{Strip: []string{"nope"}}); err == nil {
+		t.Fatal("expected an error for an unknown strip profile")
+	}
+}
@@ -0,0 +1,159 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// DeprecatedTag is added to the tags of the APIs being sunset, e.g. to find them in the
+// dashboard
+const DeprecatedTag = "deprecated"
+
+// sunsetMethods are the methods the deprecation headers are added to the responses of
+var sunsetMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// SunsetInfo marks an API as deprecated, to be retired on Date. Until then it is published
+// with the Deprecation and Sunset (RFC 8594) response headers and the deprecated tag, from
+// Date on it is published inactive, so the next sync takes it offline.
+type SunsetInfo struct {
+	// Date is the day the API is retired, e.g. 2021-06-30, or a time in RFC 3339 format
+	Date string `json:"date"`
+	// Link is the page documenting the deprecation or the replacement of the API, sent in a
+	// Link header
+	Link string `json:"link,omitempty"`
+}
+
+// Time parses the sunset date, days start at midnight UTC
+func (si *SunsetInfo) Time() (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", si.Date); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, si.Date)
+	if err != nil {
+		return t, fmt.Errorf("invalid sunset date %q, use YYYY-MM-DD", si.Date)
+	}
+	return t, nil
+}
+
+// Retired tells if the sunset date has passed at now
+func (si *SunsetInfo) Retired(now time.Time) (bool, error) {
+	t, err := si.Time()
+	if err != nil {
+		return false, err
+	}
+	return !now.Before(t), nil
+}
+
+// Apply deactivates def if the sunset date has passed at now, and otherwise tags it and adds
+// the deprecation headers to the responses of its versions. The headers are added with the
+// extended paths, versions without them are switched to extended paths if they don't list
+// paths of their own, which the extended paths would replace, and fail otherwise.
+func (si *SunsetInfo) Apply(def *objects.DBApiDefinition, now time.Time) error {
+	if si == nil || def.APIDefinition == nil {
+		return nil
+	}
+
+	t, err := si.Time()
+	if err != nil {
+		return err
+	}
+	if !now.Before(t) {
+		def.Active = false
+		return nil
+	}
+
+	def.Tags = mergeStrings(def.Tags, []string{DeprecatedTag})
+
+	headers := map[string]string{
+		"Deprecation": "true",
+		"Sunset":      t.UTC().Format(http.TimeFormat),
+	}
+	if si.Link != "" {
+		headers["Link"] = fmt.Sprintf("<%v>; rel=\"sunset\"", si.Link)
+	}
+
+	injected := false
+	for name, v := range def.VersionData.Versions {
+		if !v.UseExtendedPaths {
+			if len(v.Paths.Ignored)+len(v.Paths.WhiteList)+len(v.Paths.BlackList) > 0 {
+				return fmt.Errorf("version %v lists its paths without extended paths, the deprecation headers can't be added, convert it to extended paths", name)
+			}
+			v.UseExtendedPaths = true
+		}
+		v.ExtendedPaths.TransformResponseHeader = injectHeaders(v.ExtendedPaths.TransformResponseHeader, headers)
+		def.VersionData.Versions[name] = v
+		injected = true
+	}
+
+	// The gateway only transforms response headers with the header injector
+	if injected {
+		for _, p := range def.ResponseProcessors {
+			if p.Name == "header_injector" {
+				return nil
+			}
+		}
+		def.ResponseProcessors = append(def.ResponseProcessors, apidef.ResponseProcessor{Name: "header_injector"})
+	}
+
+	return nil
+}
+
+// injectHeaders adds headers to the responses of every path, for all methods, merging them
+// into the entries already set for the root path
+func injectHeaders(metas []apidef.HeaderInjectionMeta, headers map[string]string) []apidef.HeaderInjectionMeta {
+	for _, method := range sunsetMethods {
+		found := false
+		for i := range metas {
+			if metas[i].Path != "/" || metas[i].Method != method {
+				continue
+			}
+			if metas[i].AddHeaders == nil {
+				metas[i].AddHeaders = map[string]string{}
+			}
+			for k, v := range headers {
+				metas[i].AddHeaders[k] = v
+			}
+			found = true
+		}
+
+		if !found {
+			add := map[string]string{}
+			for k, v := range headers {
+				add[k] = v
+			}
+			metas = append(metas, apidef.HeaderInjectionMeta{Path: "/", Method: method, AddHeaders: add})
+		}
+	}
+
+	return metas
+}
+
+// SunsetStatus describes the sunset of the file entries of the spec at now, e.g. for the
+// commands to print before publishing
+func (ts *TykSourceSpec) SunsetStatus(now time.Time) ([]string, error) {
+	status := []string{}
+	for _, info := range ts.Files {
+		if info.Sunset == nil {
+			continue
+		}
+
+		retired, err := info.Sunset.Retired(now)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", info.File, err)
+		}
+		if retired {
+			status = append(status, fmt.Sprintf("%v was sunset on %v, it is deactivated", info.File, info.Sunset.Date))
+		} else {
+			status = append(status, fmt.Sprintf("%v is deprecated, it will be deactivated on %v", info.File, info.Sunset.Date))
+		}
+	}
+
+	return status, nil
+}
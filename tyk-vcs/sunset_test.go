@@ -0,0 +1,94 @@
+package tyk_vcs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func sunsetAPI() *objects.DBApiDefinition {
+	def := &apidef.APIDefinition{APIID: "a1", Active: true, Tags: []string{"edge"}}
+	def.VersionData.Versions = map[string]apidef.VersionInfo{
+		"v1":     {Name: "v1", UseExtendedPaths: true},
+		"legacy": {Name: "legacy"},
+	}
+	return &objects.DBApiDefinition{APIDefinition: def}
+}
+
+func TestSunsetInfo_Apply(t *testing.T) {
+	si := &SunsetInfo{Date: "2021-06-30", Link: "https://developer.example.com/payments-v2"}
+	before := time.Date(2021, 6, 29, 23, 0, 0, 0, time.UTC)
+
+	def := sunsetAPI()
+	if err := si.Apply(def, before); err != nil {
+		t.Fatal(err)
+	}
+	if !def.Active || len(def.Tags) != 2 || def.Tags[1] != DeprecatedTag {
+		t.Errorf("expected the API active and tagged until the sunset, got %v %v", def.Active, def.Tags)
+	}
+
+	metas := def.VersionData.Versions["v1"].ExtendedPaths.TransformResponseHeader
+	if len(metas) != len(sunsetMethods) {
+		t.Fatalf("expected the headers on every method, got %+v", metas)
+	}
+	headers := metas[0].AddHeaders
+	if headers["Deprecation"] != "true" || headers["Sunset"] != "Wed, 30 Jun 2021 00:00:00 GMT" ||
+		headers["Link"] != `<https://developer.example.com/payments-v2>; rel="sunset"` {
+		t.Errorf("unexpected deprecation headers %v", headers)
+	}
+	if legacy := def.VersionData.Versions["legacy"]; !legacy.UseExtendedPaths || len(legacy.ExtendedPaths.TransformResponseHeader) != len(sunsetMethods) {
+		t.Error("expected a version without paths to get the headers with extended paths")
+	}
+	if len(def.ResponseProcessors) != 1 || def.ResponseProcessors[0].Name != "header_injector" {
+		t.Errorf("expected the header injector, got %v", def.ResponseProcessors)
+	}
+
+	// Applied again, e.g. to a dumped definition, nothing is added twice
+	if err := si.Apply(def, before); err != nil {
+		t.Fatal(err)
+	}
+	if len(def.VersionData.Versions["v1"].ExtendedPaths.TransformResponseHeader) != len(sunsetMethods) ||
+		len(def.ResponseProcessors) != 1 || len(def.Tags) != 2 {
+		t.Error("expected the sunset to be applied once")
+	}
+
+	retired := sunsetAPI()
+	if err := si.Apply(retired, time.Date(2021, 6, 30, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if retired.Active {
+		t.Error("expected the API to be deactivated on its sunset date")
+	}
+
+	if err := (&SunsetInfo{Date: "30/06/2021"}).Apply(sunsetAPI(), before); err == nil {
+		t.Error("expected an invalid date to fail")
+	}
+
+	// The extended paths would replace the paths listed the old way
+	listed := sunsetAPI()
+	legacy := listed.VersionData.Versions["legacy"]
+	legacy.Paths.WhiteList = []string{"/payments"}
+	listed.VersionData.Versions["legacy"] = legacy
+	if err := si.Apply(listed, before); err == nil {
+		t.Error("expected a version listing paths without extended paths to fail")
+	}
+}
+
+func TestSunsetStatus(t *testing.T) {
+	ts := &TykSourceSpec{Files: []APIInfo{
+		{File: "payments-v1.json", Sunset: &SunsetInfo{Date: "2021-06-30"}},
+		{File: "payments-v2.json"},
+		{File: "orders-v1.json", Sunset: &SunsetInfo{Date: "2021-03-01T12:00:00Z"}},
+	}}
+
+	status, err := ts.SunsetStatus(time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 2 || status[0] != "payments-v1.json is deprecated, it will be deactivated on 2021-06-30" ||
+		status[1] != "orders-v1.json was sunset on 2021-03-01T12:00:00Z, it is deactivated" {
+		t.Errorf("unexpected status %v", status)
+	}
+}
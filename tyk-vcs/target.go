@@ -0,0 +1,122 @@
+package tyk_vcs
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// Target is a consumer of the objects of a repo: the dashboard or gateway a sync publishes
+// to, or a secondary consumer told the same objects in the same run, e.g. a service catalog
+// or an internal registry
+type Target interface {
+	Name() string
+	// Push hands the target the APIs and policies of the repo, which replace those it had
+	Push(defs []objects.DBApiDefinition, pols []objects.Policy) error
+}
+
+// TargetFactory opens the target of a URL of the scheme it is registered for
+type TargetFactory func(u *url.URL) (Target, error)
+
+var (
+	targetsMu sync.Mutex
+	targets   = map[string]TargetFactory{}
+)
+
+// RegisterTarget makes the targets of the URLs of scheme usable with sync --push-to.
+// Schemes may name the protocol to reach the target with after a +, e.g. catalog+https://,
+// the factory is then given the URL with that protocol as its scheme. Programs embedding
+// tyk-sync register theirs from an init function. Only a PublisherTarget is synced with the
+// plan checks of the sync, other targets are handed the objects to push as they are.
+func RegisterTarget(scheme string, f TargetFactory) {
+	targetsMu.Lock()
+	defer targetsMu.Unlock()
+	targets[strings.ToLower(scheme)] = f
+}
+
+// TargetSchemes lists the registered schemes
+func TargetSchemes() []string {
+	targetsMu.Lock()
+	defer targetsMu.Unlock()
+
+	schemes := []string{}
+	for s := range targets {
+		schemes = append(schemes, s)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// NewTarget opens the target of rawurl, whose scheme must be registered
+func NewTarget(rawurl string) (Target, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if i := strings.Index(scheme, "+"); i >= 0 {
+		scheme, u.Scheme = scheme[:i], scheme[i+1:]
+	}
+
+	targetsMu.Lock()
+	f, ok := targets[scheme]
+	targetsMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no target is registered for %q, registered: %v", scheme, strings.Join(TargetSchemes(), ", "))
+	}
+
+	return f(u)
+}
+
+// PublisherTarget pushes to a dashboard or gateway by syncing its publisher. Gateways have
+// no policies and are reloaded once synced.
+type PublisherTarget struct {
+	Publisher Publisher
+	Gateway   bool
+	// Gate, if set, is the plan check of the publisher: the policies and the APIs are both
+	// planned and checked before either is synced
+	Gate *PlanGate
+}
+
+func (t *PublisherTarget) Name() string {
+	return t.Publisher.Name()
+}
+
+func (t *PublisherTarget) Push(defs []objects.DBApiDefinition, pols []objects.Policy) error {
+	if t.Gate != nil && len(pols) > 0 && !t.Gateway {
+		fmt.Println("Planning Policies and APIs...")
+		err := t.Gate.Plan(func() error {
+			if err := t.Publisher.SyncPolicies(pols); err != nil && !errors.Is(err, errPlanned) {
+				return err
+			}
+			return t.Publisher.Sync(defs)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(pols) > 0 && !t.Gateway {
+		fmt.Println("Processing Policies...")
+		if err := t.Publisher.SyncPolicies(pols); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Processing APIs...")
+	if err := t.Publisher.Sync(defs); err != nil {
+		return err
+	}
+
+	if t.Gateway {
+		return t.Publisher.Reload()
+	}
+
+	return nil
+}
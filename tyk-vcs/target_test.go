@@ -0,0 +1,136 @@
+package tyk_vcs
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// syncRecorder records the syncs and reloads of a publisher
+type syncRecorder struct {
+	recordingPublisher
+}
+
+func (r *syncRecorder) Sync(defs []objects.DBApiDefinition) error {
+	r.calls = append(r.calls, "sync apis")
+	return nil
+}
+func (r *syncRecorder) SyncPolicies(pols []objects.Policy) error {
+	r.calls = append(r.calls, "sync policies")
+	return nil
+}
+func (r *syncRecorder) Reload() error {
+	r.calls = append(r.calls, "reload")
+	return nil
+}
+
+func TestPublisherTarget(t *testing.T) {
+	pols := []objects.Policy{{ID: "gold"}}
+
+	dashboard := &syncRecorder{}
+	if err := (&PublisherTarget{Publisher: dashboard}).Push(nil, pols); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"sync policies", "sync apis"}; !reflect.DeepEqual(dashboard.calls, expected) {
+		t.Errorf("expected %v, got %v", expected, dashboard.calls)
+	}
+
+	gateway := &syncRecorder{}
+	if err := (&PublisherTarget{Publisher: gateway, Gateway: true}).Push(nil, pols); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"sync apis", "reload"}; !reflect.DeepEqual(gateway.calls, expected) {
+		t.Errorf("expected %v, got %v", expected, gateway.calls)
+	}
+}
+
+// plannedPublisher plans the deletes of its syncs with check, like the clients, and records
+// what it applies
+type plannedPublisher struct {
+	recordingPublisher
+	check   objects.PlanCheck
+	deletes map[string][]objects.SyncItem
+}
+
+func (p *plannedPublisher) sync(kind string) error {
+	plan := &objects.SyncPlan{Kind: kind, Delete: p.deletes[kind]}
+	if err := p.check(plan); err != nil {
+		return err
+	}
+	for _, item := range plan.Delete {
+		p.calls = append(p.calls, "delete "+kind+" "+item.ID)
+	}
+	return nil
+}
+
+func (p *plannedPublisher) Sync(defs []objects.DBApiDefinition) error { return p.sync("APIs") }
+func (p *plannedPublisher) SyncPolicies(pols []objects.Policy) error  { return p.sync("policies") }
+
+func TestPublisherTarget_Gate(t *testing.T) {
+	guard := &DeleteGuard{MaxDeletes: 1}
+	p := &plannedPublisher{deletes: map[string][]objects.SyncItem{
+		"policies": {{ID: "gold"}},
+		"APIs":     {{ID: "a1"}, {ID: "a2"}},
+	}}
+	gate := NewPlanGate(guard.Check)
+	p.check = gate.Check
+
+	// The APIs exceed the guard, the policies are not deleted either
+	target := &PublisherTarget{Publisher: p, Gate: gate}
+	if err := target.Push(nil, []objects.Policy{{ID: "silver"}}); err == nil || !strings.Contains(err.Error(), "2 APIs") {
+		t.Fatalf("expected the APIs to be refused, got %v", err)
+	}
+	if len(p.calls) != 0 {
+		t.Errorf("expected nothing to be applied, got %v", p.calls)
+	}
+
+	// Only what was checked is applied
+	p.deletes["APIs"] = p.deletes["APIs"][:1]
+	planned := 0
+	gate = NewPlanGate(func(plan *objects.SyncPlan) error {
+		planned++
+		if plan.Kind == "APIs" {
+			plan.Delete = nil
+		}
+		return nil
+	})
+	p.check = gate.Check
+	target.Gate = gate
+	if err := target.Push(nil, []objects.Policy{{ID: "silver"}}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"delete policies gold"}; !reflect.DeepEqual(p.calls, expected) || planned != 2 {
+		t.Errorf("expected each plan to be checked once and applied as checked, got %v after %v checks", p.calls, planned)
+	}
+}
+
+type catalogTarget struct {
+	url string
+}
+
+func (c *catalogTarget) Name() string { return "catalog " + c.url }
+func (c *catalogTarget) Push(defs []objects.DBApiDefinition, pols []objects.Policy) error {
+	return nil
+}
+
+func TestNewTarget(t *testing.T) {
+	RegisterTarget("catalog", func(u *url.URL) (Target, error) {
+		return &catalogTarget{url: u.String()}, nil
+	})
+	defer delete(targets, "catalog")
+
+	target, err := NewTarget("catalog+https://catalog.internal/apis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Name() != "catalog https://catalog.internal/apis" {
+		t.Errorf("expected the URL of the protocol after the +, got %v", target.Name())
+	}
+
+	if _, err := NewTarget("registry://apis"); err == nil || !strings.Contains(err.Error(), "catalog") {
+		t.Errorf("expected the unknown scheme to fail listing the registered ones, got %v", err)
+	}
+}
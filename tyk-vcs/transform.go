@@ -0,0 +1,138 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-patch"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// Transformer changes a definition between loading it from the repo and publishing it, e.g.
+// to fill in defaults of the target or values of the environment
+type Transformer interface {
+	Transform(def *objects.DBApiDefinition) error
+}
+
+// TransformerFunc is a function used as a Transformer
+type TransformerFunc func(def *objects.DBApiDefinition) error
+
+func (f TransformerFunc) Transform(def *objects.DBApiDefinition) error {
+	return f(def)
+}
+
+// Pipeline runs transformers in sequence, each of them on every definition before the next
+// one starts
+type Pipeline []Transformer
+
+// Run transforms defs in place. The definitions a transformer fails on are reported
+// together, the later transformers don't run.
+func (p Pipeline) Run(defs []objects.DBApiDefinition) error {
+	for _, t := range p {
+		failed := []string{}
+		for i := range defs {
+			if defs[i].APIDefinition == nil {
+				continue
+			}
+			if err := t.Transform(&defs[i]); err != nil {
+				failed = append(failed, fmt.Sprintf("%v (%v): %v", defs[i].Name, defs[i].APIID, err))
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("transforming the definitions: %v", strings.Join(failed, "; "))
+		}
+	}
+
+	return nil
+}
+
+var (
+	transformersMu sync.Mutex
+	transformers   = []Transformer{}
+)
+
+// RegisterTransformer adds t to the transformers run after the built-in ones on every
+// definition tyk-sync loads, in the order they are registered. Programs embedding tyk-sync
+// register theirs from an init function.
+func RegisterTransformer(t Transformer) {
+	transformersMu.Lock()
+	defer transformersMu.Unlock()
+	transformers = append(transformers, t)
+}
+
+// RegisteredTransformers returns the transformers added with RegisterTransformer
+func RegisteredTransformers() []Transformer {
+	transformersMu.Lock()
+	defer transformersMu.Unlock()
+	return append([]Transformer{}, transformers...)
+}
+
+// ProfileTransformer merges the defaults of a target profile, tags among them, into the
+// definitions and sets the fields to strip from them
+func ProfileTransformer(tp *TargetProfile, strip []string) Transformer {
+	return TransformerFunc(func(def *objects.DBApiDefinition) error {
+		tp.Apply(def)
+		def.Strip = strip
+		return nil
+	})
+}
+
+// FileTransformer applies the display fields, the sunset and the patches of the profile declared
+// by the file entries of the spec, each definition gets those of the entry of the file it was
+// read from. Definitions not read from a listed file get those of the entry setting their API
+// ID, others are left as they are.
+func (ts *TykSourceSpec) FileTransformer(profile string) Transformer {
+	return TransformerFunc(func(def *objects.DBApiDefinition) error {
+		info, ok := ts.apiInfo(def)
+		if !ok {
+			return nil
+		}
+
+		info.Display.Apply(def)
+		if err := info.Sunset.Apply(def, time.Now()); err != nil {
+			return fmt.Errorf("%v: %v", info.File, err)
+		}
+
+		ops := info.Patches[profile]
+		if profile == "" || len(ops) == 0 {
+			return nil
+		}
+		patched := apidef.APIDefinition{}
+		if err := tyk_patch.ApplyTo(def.APIDefinition, &patched, ops); err != nil {
+			return fmt.Errorf("%v: %v", info.File, err)
+		}
+		def.APIDefinition = &patched
+		return nil
+	})
+}
+
+// OrgTransformer sets the org of the definitions to orgID, if set, and derives the IDs of the
+// seeded ones in it
+func OrgTransformer(orgID string) Transformer {
+	return TransformerFunc(func(def *objects.DBApiDefinition) error {
+		if orgID != "" {
+			def.OrgID = orgID
+			def.ApplySeed()
+		}
+		return nil
+	})
+}
+
+// ManagedTransformer marks the definitions as published by tyk-sync from repo, see MarkManaged
+func ManagedTransformer(repo string) Transformer {
+	return TransformerFunc(func(def *objects.DBApiDefinition) error {
+		markManaged(repo, def)
+		return nil
+	})
+}
+
+// SecretTransformer replaces the ${TYK_SECRET_...} placeholders of the definitions with the
+// values returned by lookup, see ResolveSecrets
+func SecretTransformer(lookup func(string) (string, bool)) Transformer {
+	return TransformerFunc(func(def *objects.DBApiDefinition) error {
+		return ResolveSecrets([]objects.DBApiDefinition{*def}, lookup)
+	})
+}
@@ -0,0 +1,96 @@
+package tyk_vcs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk-sync/tyk-patch"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestPipeline(t *testing.T) {
+	defs := []objects.DBApiDefinition{
+		{APIDefinition: &apidef.APIDefinition{APIID: "a1", Name: "Payments"}, File: "payments.json"},
+		{APIDefinition: &apidef.APIDefinition{APIID: "a2", Name: "Orders"}, File: "orders.json"},
+	}
+	defs[0].Proxy.TargetURL = "http://${TYK_SECRET_UPSTREAM}"
+	// The entries aren't in the order of the definitions
+	spec := &TykSourceSpec{Files: []APIInfo{
+		{File: "orders.json"},
+		{File: "payments.json", Display: &DisplayInfo{Categories: []string{"billing"}},
+			Patches: map[string][]tyk_patch.Operation{"prod": {{Op: "replace", Path: "/proxy/listen_path", Value: "/pay/"}}}},
+	}}
+
+	order := []string{}
+	custom := TransformerFunc(func(def *objects.DBApiDefinition) error {
+		// The user transformers see what the built-in ones made of the definitions
+		order = append(order, def.APIID+" "+def.OrgID+" "+def.Proxy.TargetURL)
+		return nil
+	})
+
+	lookup := func(name string) (string, bool) { return "upstream", name == "TYK_SECRET_UPSTREAM" }
+	pipeline := Pipeline{
+		ProfileTransformer(&TargetProfile{Tags: []string{"edge"}}, []string{"proxy.preserve_host_header"}),
+		spec.FileTransformer("prod"),
+		OrgTransformer("org1"),
+		ManagedTransformer("payments"),
+		SecretTransformer(lookup),
+		custom,
+	}
+	if err := pipeline.Run(defs); err != nil {
+		t.Fatal(err)
+	}
+
+	payments := defs[0]
+	if payments.Name != "Payments #billing" || payments.Proxy.ListenPath != "/pay/" || payments.OrgID != "org1" {
+		t.Errorf("expected the display, patches and org applied, got %v %v %v", payments.Name, payments.Proxy.ListenPath, payments.OrgID)
+	}
+	if len(payments.Tags) != 1 || payments.Tags[0] != "edge" || len(payments.Strip) != 1 {
+		t.Errorf("expected the profile defaults applied, got %v %v", payments.Tags, payments.Strip)
+	}
+	if payments.ConfigData[ManagedMarker] != "payments" {
+		t.Errorf("expected the definition marked managed, got %v", payments.ConfigData)
+	}
+	if strings.Join(order, ",") != "a1 org1 http://upstream,a2 org1 " {
+		t.Errorf("expected the custom transformer to run last, got %v", order)
+	}
+}
+
+func TestPipeline_Errors(t *testing.T) {
+	defs := []objects.DBApiDefinition{
+		{APIDefinition: &apidef.APIDefinition{APIID: "a1", Name: "Payments"}},
+		{APIDefinition: &apidef.APIDefinition{APIID: "a2", Name: "Orders"}},
+	}
+
+	ran := false
+	pipeline := Pipeline{
+		TransformerFunc(func(def *objects.DBApiDefinition) error { return errors.New("no " + def.APIID) }),
+		TransformerFunc(func(def *objects.DBApiDefinition) error { ran = true; return nil }),
+	}
+
+	err := pipeline.Run(defs)
+	if err == nil || !strings.Contains(err.Error(), "Payments (a1): no a1") || !strings.Contains(err.Error(), "Orders (a2): no a2") {
+		t.Errorf("expected the failures of both definitions, got %v", err)
+	}
+	if ran {
+		t.Error("expected the pipeline to stop at the failing transformer")
+	}
+}
+
+func TestRegisterTransformer(t *testing.T) {
+	before := len(RegisteredTransformers())
+	RegisterTransformer(OrgTransformer("org1"))
+	defer func() { transformers = transformers[:before] }()
+
+	registered := RegisteredTransformers()
+	if len(registered) != before+1 {
+		t.Fatalf("expected the transformer registered, got %v", registered)
+	}
+
+	def := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{}}
+	if err := (Pipeline(registered)).Run([]objects.DBApiDefinition{def}); err != nil || def.OrgID != "org1" {
+		t.Errorf("expected the registered transformer to run, got %v %v", def.OrgID, err)
+	}
+}
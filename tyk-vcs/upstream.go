@@ -0,0 +1,161 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+const (
+	// UpstreamCheckSkip set to true in the config_data of an API skips the upstream checks
+	// of the API, e.g. for upstreams only the gateways can reach
+	UpstreamCheckSkip = "tyk_sync_skip_upstream_check"
+
+	// UpstreamTCP resolves the upstreams and connects to them, UpstreamHEAD also sends them a
+	// HEAD request, any response means the upstream is reachable
+	UpstreamTCP  = "tcp"
+	UpstreamHEAD = "head"
+)
+
+// UpstreamCheck is the outcome of checking the runner reaches an upstream of an API
+type UpstreamCheck struct {
+	APIID      string `json:"api_id"`
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	Reachable  bool   `json:"reachable"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// UpstreamChecker checks the target_url, or load balancing targets, of API definitions
+// resolve and accept connections from where tyk-sync runs
+type UpstreamChecker struct {
+	// Mode is UpstreamTCP, the default, or UpstreamHEAD
+	Mode    string
+	Timeout time.Duration
+	Workers int
+}
+
+// SkipsUpstreamCheck tells whether the config_data of def opts it out of upstream checks
+func SkipsUpstreamCheck(def objects.DBApiDefinition) bool {
+	skip, _ := def.ConfigData[UpstreamCheckSkip].(bool)
+	return skip
+}
+
+// upstreams lists the URLs def proxies to. APIs using service discovery, looping to other
+// APIs with tyk:// or whose URL is still a placeholder have none that can be checked.
+func upstreams(def objects.DBApiDefinition) []string {
+	if def.Proxy.ServiceDiscovery.UseDiscoveryService {
+		return nil
+	}
+
+	targets := []string{def.Proxy.TargetURL}
+	if def.Proxy.EnableLoadBalancing && len(def.Proxy.Targets) > 0 {
+		targets = def.Proxy.Targets
+	}
+
+	urls := []string{}
+	for _, t := range targets {
+		t = strings.TrimSpace(t)
+		if t == "" || strings.HasPrefix(t, "tyk://") || strings.Contains(t, "${") {
+			continue
+		}
+		urls = append(urls, t)
+	}
+
+	return urls
+}
+
+// upstreamAddress is the host:port an upstream URL connects to
+func upstreamAddress(u *url.URL) (string, error) {
+	if u.Host == "" {
+		return "", fmt.Errorf("%q has no host", u.String())
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+
+	switch u.Scheme {
+	case "http", "ws":
+		return net.JoinHostPort(u.Hostname(), "80"), nil
+	case "https", "wss":
+		return net.JoinHostPort(u.Hostname(), "443"), nil
+	}
+	return "", fmt.Errorf("%q has no port", u.String())
+}
+
+func (c *UpstreamChecker) checkURL(check *UpstreamCheck) {
+	u, err := url.Parse(check.URL)
+	if err != nil {
+		check.Error = err.Error()
+		return
+	}
+
+	addr, err := upstreamAddress(u)
+	if err != nil {
+		check.Error = err.Error()
+		return
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, c.Timeout)
+	if err != nil {
+		check.Error = err.Error()
+		return
+	}
+	conn.Close()
+
+	if c.Mode != UpstreamHEAD || (u.Scheme != "http" && u.Scheme != "https") {
+		check.Reachable = true
+		return
+	}
+
+	client := &http.Client{
+		Timeout: c.Timeout,
+		// A redirect is an answer of the upstream, it needn't be followed
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Head(check.URL)
+	if err != nil {
+		check.Error = err.Error()
+		return
+	}
+	resp.Body.Close()
+
+	check.StatusCode = resp.StatusCode
+	check.Reachable = true
+}
+
+// Check returns the result of checking each upstream of each active API of defs, skipping
+// the APIs that opt out with UpstreamCheckSkip
+func (c *UpstreamChecker) Check(defs []objects.DBApiDefinition) ([]UpstreamCheck, error) {
+	switch c.Mode {
+	case "", UpstreamTCP, UpstreamHEAD:
+	default:
+		return nil, fmt.Errorf("unknown upstream check %q, must be %v or %v", c.Mode, UpstreamTCP, UpstreamHEAD)
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5 * time.Second
+	}
+
+	checks := []UpstreamCheck{}
+	for _, def := range defs {
+		if def.APIDefinition == nil || !def.Active || SkipsUpstreamCheck(def) {
+			continue
+		}
+		for _, u := range upstreams(def) {
+			checks = append(checks, UpstreamCheck{APIID: def.APIID, Name: def.Name, URL: u})
+		}
+	}
+
+	FetchParallel(len(checks), c.Workers, func(i int) error {
+		c.checkURL(&checks[i])
+		return nil
+	}, nil)
+
+	return checks, nil
+}
@@ -0,0 +1,80 @@
+package tyk_vcs
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestUpstreamChecker(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("expected a HEAD request, got %v", r.Method)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	// A port nothing listens on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + l.Addr().String()
+	l.Close()
+
+	api := func(id string, targets ...string) objects.DBApiDefinition {
+		def := objects.DBApiDefinition{APIDefinition: &apidef.APIDefinition{APIID: id, Name: id, Active: true}}
+		def.Proxy.TargetURL = targets[0]
+		if len(targets) > 1 {
+			def.Proxy.EnableLoadBalancing = true
+			def.Proxy.Targets = targets
+		}
+		return def
+	}
+
+	skipped := api("skipped", closed)
+	skipped.ConfigData = map[string]interface{}{UpstreamCheckSkip: true}
+	inactive := api("inactive", closed)
+	inactive.Active = false
+	discovered := api("discovered", closed)
+	discovered.Proxy.ServiceDiscovery.UseDiscoveryService = true
+
+	defs := []objects.DBApiDefinition{
+		api("up", ts.URL+"/v1"),
+		api("balanced", ts.URL, closed),
+		api("looping", "tyk://other-api"),
+		api("placeholder", "${TYK_SECRET_UPSTREAM}"),
+		api("noport", "tcp://upstream"),
+		skipped, inactive, discovered,
+	}
+
+	checks, err := (&UpstreamChecker{Mode: UpstreamHEAD, Timeout: time.Second}).Check(defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 4 {
+		t.Fatalf("expected 4 upstreams checked, got %+v", checks)
+	}
+	if c := checks[0]; !c.Reachable || c.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the upstream reachable whatever it answers, got %+v", c)
+	}
+	if c := checks[1]; c.APIID != "balanced" || !c.Reachable {
+		t.Errorf("expected the first target reachable, got %+v", c)
+	}
+	if c := checks[2]; c.APIID != "balanced" || c.Reachable || c.Error == "" {
+		t.Errorf("expected the second target unreachable, got %+v", c)
+	}
+	if c := checks[3]; c.APIID != "noport" || c.Reachable {
+		t.Errorf("expected an upstream without a port refused, got %+v", c)
+	}
+
+	if _, err := (&UpstreamChecker{Mode: "ping"}).Check(defs); err == nil {
+		t.Error("expected an unknown check refused")
+	}
+}
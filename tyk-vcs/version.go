@@ -0,0 +1,98 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// DevVersion is the version of the builds that aren't of a release
+const DevVersion = "dev"
+
+// The release builds set these with -ldflags, see .goreleaser.yml, e.g.
+// -X github.com/TykTechnologies/tyk-sync/tyk-vcs.version=1.2.0
+var (
+	version = DevVersion
+	commit  = ""
+	date    = ""
+)
+
+// BuildInfo is what a binary, or a program embedding tyk-sync, was built from
+type BuildInfo struct {
+	Version string `json:"version"`
+	// Commit is the git commit of the release, Date the time of that commit
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// Version is the build of tyk-sync running. Builds without -ldflags that were installed
+// from a tagged module, with go get or go install, report the version of the module.
+func Version() BuildInfo {
+	b := BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if b.Version == DevVersion {
+		if info, ok := debug.ReadBuildInfo(); ok && isRelease(info.Main.Version) {
+			b.Version = strings.TrimPrefix(info.Main.Version, "v")
+		}
+	}
+
+	return b
+}
+
+// pseudoVersion matches the timestamp and commit of the versions Go gives untagged commits
+var pseudoVersion = regexp.MustCompile(`\d{14}-[0-9a-f]{12}`)
+
+// isRelease reports if a module version is a tag, rather than a local build, (devel), or
+// a pseudo-version of an untagged commit
+func isRelease(v string) bool {
+	return strings.HasPrefix(v, "v") && !strings.Contains(v, "+dirty") && !pseudoVersion.MatchString(v)
+}
+
+// String is the one line summary of the build put in bug reports, e.g.
+// 1.2.0 (commit 3f2a1c9, 2021-03-04T10:00:00Z) go1.15.8 linux/arm64
+func (b BuildInfo) String() string {
+	details := []string{}
+	if b.Commit != "" {
+		c := b.Commit
+		if len(c) > 7 {
+			c = c[:7]
+		}
+		details = append(details, "commit "+c)
+	}
+	if b.Date != "" {
+		details = append(details, b.Date)
+	}
+
+	s := b.Version
+	if len(details) > 0 {
+		s += " (" + strings.Join(details, ", ") + ")"
+	}
+	return s + " " + b.GoVersion + " " + b.Platform
+}
+
+// checkToolVersion fails if the spec needs another tyk-sync than running, its requires.tool,
+// so that a repo using features of a later release isn't half applied by an older one.
+// Development builds have no version to compare and are let through.
+func checkToolVersion(ts *TykSourceSpec, running string) error {
+	if running == DevVersion || ts.Requires == nil || ts.Requires.Tool == "" {
+		return nil
+	}
+	ok, err := SatisfiesVersion(running, ts.Requires.Tool)
+	if err != nil {
+		return fmt.Errorf("requires.tool: %v", err)
+	}
+	if !ok {
+		return fmt.Errorf("the spec requires tyk-sync %v, this is %v: please upgrade, e.g. with self-update", ts.Requires.Tool, running)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package tyk_vcs
+
+import "testing"
+
+func TestBuildInfo_String(t *testing.T) {
+	b := BuildInfo{Version: "1.2.0", Commit: "3f2a1c9d0e", Date: "2021-03-04T10:00:00Z", GoVersion: "go1.15.8", Platform: "windows/amd64"}
+	if s := b.String(); s != "1.2.0 (commit 3f2a1c9, 2021-03-04T10:00:00Z) go1.15.8 windows/amd64" {
+		t.Errorf("unexpected summary %v", s)
+	}
+
+	b = BuildInfo{Version: DevVersion, GoVersion: "go1.15.8", Platform: "linux/arm"}
+	if s := b.String(); s != "dev go1.15.8 linux/arm" {
+		t.Errorf("unexpected summary %v", s)
+	}
+}
+
+func TestIsRelease(t *testing.T) {
+	for v, expected := range map[string]bool{
+		"v1.4.0":     true,
+		"v1.5.0-rc1": true,
+		"(devel)":    false,
+		"":           false,
+		"v0.0.0-20261014182729-791cbb026b68+dirty": false,
+		"v1.4.1-0.20210304100000-3f2a1c9d0e7b":     false,
+	} {
+		if isRelease(v) != expected {
+			t.Errorf("%q: expected a release %v", v, expected)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package tyk_vcs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	WebhookGitHub          = "github"
+	WebhookGitLab          = "gitlab"
+	WebhookGitea           = "gitea"
+	WebhookBitbucketServer = "bitbucket-server"
+	WebhookAzureDevOps     = "azure-devops"
+
+	zeroCommit = "0000000000000000000000000000000000000000"
+)
+
+// ErrNotPush is returned for webhook events that are not pushes, e.g. pings or pull
+// requests, they can be acknowledged and ignored
+var ErrNotPush = errors.New("not a push event")
+
+// ErrUnsignedWebhook is returned for webhooks that don't carry a valid signature or token
+var ErrUnsignedWebhook = errors.New("the webhook is not signed with the webhook secret")
+
+// VerifyWebhook checks that a webhook was sent by a git server knowing secret: the HMAC-SHA256
+// of the body in X-Hub-Signature-256 (GitHub, Gitea), X-Gitea-Signature or X-Hub-Signature
+// (Bitbucket Server), the secret itself in X-Gitlab-Token, or as the password of the basic
+// auth of Azure DevOps. Values are compared in constant time.
+func VerifyWebhook(header http.Header, body []byte, secret string) error {
+	if secret == "" {
+		return nil
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	sum := mac.Sum(nil)
+
+	// Gitea sends the bare hex digest
+	signatures := []string{
+		header.Get("X-Hub-Signature-256"),
+		header.Get("X-Hub-Signature"),
+		"sha256=" + header.Get("X-Gitea-Signature"),
+	}
+	for _, sig := range signatures {
+		if !strings.HasPrefix(sig, "sha256=") {
+			continue
+		}
+		got, err := hex.DecodeString(strings.TrimPrefix(sig, "sha256="))
+		if err == nil && hmac.Equal(got, sum) {
+			return nil
+		}
+	}
+
+	if token := header.Get("X-Gitlab-Token"); token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1 {
+		return nil
+	}
+
+	r := http.Request{Header: header}
+	if _, pass, ok := r.BasicAuth(); ok && subtle.ConstantTimeCompare([]byte(pass), []byte(secret)) == 1 {
+		return nil
+	}
+
+	return ErrUnsignedWebhook
+}
+
+// PushEvent is a push to one ref of a repo, as sent in the webhook of a git server
+type PushEvent struct {
+	Provider string
+	// URLs are the clone URLs of the repo the payload lists, HTTP and SSH
+	URLs []string
+	// Ref is the full name of the pushed ref, e.g. refs/heads/master
+	Ref    string
+	Before string
+	After  string
+	// Deleted is set when the push removed the ref
+	Deleted bool
+}
+
+// normaliseRepoURL makes clone URLs of the same repo comparable: no scheme, user, .git
+// suffix or trailing slash, and the scp-like SSH syntax turned into a path
+func normaliseRepoURL(u string) string {
+	u = strings.ToLower(strings.TrimSpace(u))
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	} else if i := strings.Index(u, ":"); i >= 0 {
+		u = u[:i] + "/" + u[i+1:]
+	}
+	if i := strings.Index(u, "@"); i >= 0 && i < strings.Index(u+"/", "/") {
+		u = u[i+1:]
+	}
+
+	u = strings.TrimSuffix(strings.TrimSuffix(u, "/"), ".git")
+	return u
+}
+
+// Matches tells whether the event is a push to branch (a full ref name) of repo, which
+// may be given by any of its clone URLs
+func (e PushEvent) Matches(repo, branch string) bool {
+	if e.Ref != branch {
+		return false
+	}
+
+	want := normaliseRepoURL(repo)
+	for _, u := range e.URLs {
+		if normaliseRepoURL(u) == want {
+			return true
+		}
+	}
+
+	return false
+}
+
+func nonEmpty(values ...string) []string {
+	out := []string{}
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// gitHubPush is the push payload of GitHub, Gitea uses the same fields
+type gitHubPush struct {
+	Ref        string `json:"ref"`
+	Before     string `json:"before"`
+	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
+	Repository struct {
+		CloneURL string `json:"clone_url"`
+		SSHURL   string `json:"ssh_url"`
+		HTMLURL  string `json:"html_url"`
+	} `json:"repository"`
+}
+
+func parseGitHubPush(provider string, body []byte) ([]PushEvent, error) {
+	p := gitHubPush{}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+
+	return []PushEvent{{
+		Provider: provider,
+		URLs:     nonEmpty(p.Repository.CloneURL, p.Repository.SSHURL, p.Repository.HTMLURL),
+		Ref:      p.Ref,
+		Before:   p.Before,
+		After:    p.After,
+		Deleted:  p.Deleted || p.After == zeroCommit,
+	}}, nil
+}
+
+func parseGitLabPush(body []byte) ([]PushEvent, error) {
+	var p struct {
+		Ref     string `json:"ref"`
+		Before  string `json:"before"`
+		After   string `json:"after"`
+		Project struct {
+			HTTPURL string `json:"git_http_url"`
+			SSHURL  string `json:"git_ssh_url"`
+			WebURL  string `json:"web_url"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+
+	return []PushEvent{{
+		Provider: WebhookGitLab,
+		URLs:     nonEmpty(p.Project.HTTPURL, p.Project.SSHURL, p.Project.WebURL),
+		Ref:      p.Ref,
+		Before:   p.Before,
+		After:    p.After,
+		Deleted:  p.After == zeroCommit,
+	}}, nil
+}
+
+func parseBitbucketServerPush(body []byte) ([]PushEvent, error) {
+	var p struct {
+		Repository struct {
+			Links struct {
+				Clone []struct {
+					Href string `json:"href"`
+				} `json:"clone"`
+			} `json:"links"`
+		} `json:"repository"`
+		Changes []struct {
+			Ref struct {
+				ID string `json:"id"`
+			} `json:"ref"`
+			FromHash string `json:"fromHash"`
+			ToHash   string `json:"toHash"`
+			Type     string `json:"type"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+
+	urls := []string{}
+	for _, c := range p.Repository.Links.Clone {
+		urls = append(urls, c.Href)
+	}
+
+	events := []PushEvent{}
+	for _, c := range p.Changes {
+		events = append(events, PushEvent{
+			Provider: WebhookBitbucketServer,
+			URLs:     urls,
+			Ref:      c.Ref.ID,
+			Before:   c.FromHash,
+			After:    c.ToHash,
+			Deleted:  c.Type == "DELETE",
+		})
+	}
+
+	return events, nil
+}
+
+func parseAzureDevOpsPush(body []byte) ([]PushEvent, error) {
+	var p struct {
+		EventType string `json:"eventType"`
+		Resource  struct {
+			RefUpdates []struct {
+				Name        string `json:"name"`
+				OldObjectID string `json:"oldObjectId"`
+				NewObjectID string `json:"newObjectId"`
+			} `json:"refUpdates"`
+			Repository struct {
+				RemoteURL string `json:"remoteUrl"`
+				SSHURL    string `json:"sshUrl"`
+			} `json:"repository"`
+		} `json:"resource"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+
+	if p.EventType != "git.push" {
+		return nil, ErrNotPush
+	}
+
+	events := []PushEvent{}
+	for _, u := range p.Resource.RefUpdates {
+		events = append(events, PushEvent{
+			Provider: WebhookAzureDevOps,
+			URLs:     nonEmpty(p.Resource.Repository.RemoteURL, p.Resource.Repository.SSHURL),
+			Ref:      u.Name,
+			Before:   u.OldObjectID,
+			After:    u.NewObjectID,
+			Deleted:  u.NewObjectID == zeroCommit,
+		})
+	}
+
+	return events, nil
+}
+
+// ParsePushEvents reads the push webhook of GitHub, GitLab, Gitea, Bitbucket Server or
+// Azure DevOps, the sender is told by the event header it sets or, for Azure DevOps, the
+// payload. A push may update several refs.
+func ParsePushEvents(header http.Header, body []byte) ([]PushEvent, error) {
+	switch {
+	// Gitea also sends the GitHub header, so it is checked first
+	case header.Get("X-Gitea-Event") != "":
+		if header.Get("X-Gitea-Event") != "push" {
+			return nil, ErrNotPush
+		}
+		return parseGitHubPush(WebhookGitea, body)
+	case header.Get("X-GitHub-Event") != "":
+		if header.Get("X-GitHub-Event") != "push" {
+			return nil, ErrNotPush
+		}
+		return parseGitHubPush(WebhookGitHub, body)
+	case header.Get("X-Gitlab-Event") != "":
+		if header.Get("X-Gitlab-Event") != "Push Hook" {
+			return nil, ErrNotPush
+		}
+		return parseGitLabPush(body)
+	case header.Get("X-Event-Key") != "":
+		if header.Get("X-Event-Key") != "repo:refs_changed" {
+			return nil, ErrNotPush
+		}
+		return parseBitbucketServerPush(body)
+	case strings.Contains(string(body), `"eventType"`):
+		return parseAzureDevOpsPush(body)
+	default:
+		return nil, fmt.Errorf("unknown webhook sender, expected a push from %v, %v, %v, %v or %v",
+			WebhookGitHub, WebhookGitLab, WebhookGitea, WebhookBitbucketServer, WebhookAzureDevOps)
+	}
+}
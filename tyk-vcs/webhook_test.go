@@ -0,0 +1,163 @@
+package tyk_vcs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"testing"
+)
+
+func TestParsePushEvents(t *testing.T) {
+	cases := []struct {
+		name   string
+		header string
+		value  string
+		body   string
+		want   PushEvent
+	}{
+		{
+			name:   "github",
+			header: "X-GitHub-Event",
+			value:  "push",
+			body:   `{"ref": "refs/heads/master", "before": "a1", "after": "b2", "repository": {"clone_url": "https://github.com/acme/apis.git", "ssh_url": "git@github.com:acme/apis.git"}}`,
+			want:   PushEvent{Provider: WebhookGitHub, Ref: "refs/heads/master", Before: "a1", After: "b2"},
+		},
+		{
+			name:   "gitlab",
+			header: "X-Gitlab-Event",
+			value:  "Push Hook",
+			body:   `{"object_kind": "push", "ref": "refs/heads/master", "before": "a1", "after": "b2", "project": {"git_http_url": "https://gitlab.com/acme/apis.git"}}`,
+			want:   PushEvent{Provider: WebhookGitLab, Ref: "refs/heads/master", Before: "a1", After: "b2"},
+		},
+		{
+			name:   "gitea",
+			header: "X-Gitea-Event",
+			value:  "push",
+			body:   `{"ref": "refs/heads/master", "before": "a1", "after": "0000000000000000000000000000000000000000", "repository": {"clone_url": "https://git.acme.com/acme/apis.git"}}`,
+			want:   PushEvent{Provider: WebhookGitea, Ref: "refs/heads/master", Before: "a1", After: zeroCommit, Deleted: true},
+		},
+		{
+			name:   "bitbucket server",
+			header: "X-Event-Key",
+			value:  "repo:refs_changed",
+			body:   `{"eventKey": "repo:refs_changed", "repository": {"slug": "apis", "links": {"clone": [{"href": "ssh://git@bitbucket.acme.com:7999/acme/apis.git", "name": "ssh"}]}}, "changes": [{"ref": {"id": "refs/heads/master", "displayId": "master", "type": "BRANCH"}, "fromHash": "a1", "toHash": "b2", "type": "UPDATE"}]}`,
+			want:   PushEvent{Provider: WebhookBitbucketServer, Ref: "refs/heads/master", Before: "a1", After: "b2"},
+		},
+		{
+			name: "azure devops",
+			body: `{"eventType": "git.push", "resource": {"refUpdates": [{"name": "refs/heads/master", "oldObjectId": "a1", "newObjectId": "b2"}], "repository": {"remoteUrl": "https://dev.azure.com/acme/apis/_git/apis"}}}`,
+			want: PushEvent{Provider: WebhookAzureDevOps, Ref: "refs/heads/master", Before: "a1", After: "b2"},
+		},
+	}
+
+	for _, c := range cases {
+		header := http.Header{}
+		if c.header != "" {
+			header.Set(c.header, c.value)
+		}
+
+		events, err := ParsePushEvents(header, []byte(c.body))
+		if err != nil {
+			t.Fatalf("%v: %v", c.name, err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("%v: expected one event, got %v", c.name, events)
+		}
+
+		e := events[0]
+		if e.Provider != c.want.Provider || e.Ref != c.want.Ref || e.Before != c.want.Before || e.After != c.want.After || e.Deleted != c.want.Deleted {
+			t.Fatalf("%v: got %+v, expected %+v", c.name, e, c.want)
+		}
+		if len(e.URLs) == 0 {
+			t.Fatalf("%v: no clone URLs", c.name)
+		}
+	}
+}
+
+func TestParsePushEvents_NotPush(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-GitHub-Event", "ping")
+	if _, err := ParsePushEvents(header, []byte(`{}`)); err != ErrNotPush {
+		t.Fatalf("expected ErrNotPush, got %v", err)
+	}
+
+	if _, err := ParsePushEvents(http.Header{}, []byte(`{"eventType": "git.pullrequest.created"}`)); err != ErrNotPush {
+		t.Fatalf("expected ErrNotPush, got %v", err)
+	}
+
+	if _, err := ParsePushEvents(http.Header{}, []byte(`{}`)); err == nil {
+		t.Fatal("expected an error for an unknown sender")
+	}
+}
+
+func TestPushEvent_Matches(t *testing.T) {
+	e := PushEvent{
+		Ref:  "refs/heads/master",
+		URLs: []string{"https://github.com/acme/apis.git", "git@github.com:acme/apis.git"},
+	}
+
+	for _, repo := range []string{"https://github.com/acme/apis", "git@github.com:Acme/apis.git", "https://token@github.com/acme/apis.git/"} {
+		if !e.Matches(repo, "refs/heads/master") {
+			t.Fatalf("expected %v to match", repo)
+		}
+	}
+
+	if e.Matches("https://github.com/acme/other.git", "refs/heads/master") {
+		t.Fatal("another repo must not match")
+	}
+	if e.Matches("https://github.com/acme/apis.git", "refs/heads/dev") {
+		t.Fatal("another branch must not match")
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	body := []byte(`{"ref": "refs/heads/master"}`)
+	sig := webhookHMAC(body, "other")
+
+	signed := func(name, value string) http.Header {
+		h := http.Header{}
+		h.Set(name, value)
+		return h
+	}
+	basic := http.Header{}
+	basic.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("azure:s3cret")))
+
+	valid := map[string]http.Header{
+		"github":           signed("X-Hub-Signature-256", "sha256="+webhookHMAC(body, "s3cret")),
+		"gitea":            signed("X-Gitea-Signature", webhookHMAC(body, "s3cret")),
+		"bitbucket-server": signed("X-Hub-Signature", "sha256="+webhookHMAC(body, "s3cret")),
+		"gitlab":           signed("X-Gitlab-Token", "s3cret"),
+		"azure-devops":     basic,
+	}
+	for name, h := range valid {
+		if err := VerifyWebhook(h, body, "s3cret"); err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+	}
+
+	invalid := map[string]http.Header{
+		"unsigned":    {},
+		"wrong hmac":  signed("X-Hub-Signature-256", "sha256="+sig),
+		"wrong token": signed("X-Gitlab-Token", "secret"),
+		"other body":  signed("X-Hub-Signature-256", "sha256="+webhookHMAC([]byte(`{}`), "s3cret")),
+		"sha1":        signed("X-Hub-Signature", "sha1="+webhookHMAC(body, "s3cret")),
+		"empty gitea": signed("X-Gitea-Signature", ""),
+	}
+	for name, h := range invalid {
+		if err := VerifyWebhook(h, body, "s3cret"); err != ErrUnsignedWebhook {
+			t.Errorf("%v: expected ErrUnsignedWebhook, got %v", name, err)
+		}
+	}
+
+	if err := VerifyWebhook(http.Header{}, body, ""); err != nil {
+		t.Errorf("expected no check without a secret, got %v", err)
+	}
+}
+
+func webhookHMAC(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
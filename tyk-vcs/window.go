@@ -0,0 +1,329 @@
+package tyk_vcs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+// WindowInfo is a deployment window, either recurring: opening whenever the cron expression
+// (minute hour day-of-month month day-of-week) matches and lasting Duration, or a one off
+// window of a maintenance calendar from Start to End (RFC 3339)
+type WindowInfo struct {
+	Name     string `json:"name,omitempty"`
+	Cron     string `json:"cron,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	// Timezone is the location the cron expression is read in, defaults to UTC
+	Timezone string `json:"timezone,omitempty"`
+	Start    string `json:"start,omitempty"`
+	End      string `json:"end,omitempty"`
+}
+
+// maxWindowLookahead bounds the search for the next opening of a recurring window
+const maxWindowLookahead = 366 * 24 * time.Hour
+
+type cronField map[int]bool
+
+type cronSchedule struct {
+	minute, hour, dom, month, dow cronField
+	// anyDom and anyDow tell whether the day fields are *, cron matches either day field
+	// when both are restricted
+	anyDom, anyDow bool
+}
+
+var (
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+func parseCronValue(v string, min int, names []string) (int, error) {
+	for i, n := range names {
+		if strings.ToLower(v) == n {
+			return i + min, nil
+		}
+	}
+
+	return strconv.Atoi(v)
+}
+
+func parseCronField(field string, min, max int, names []string) (cronField, error) {
+	values := cronField{}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s < 1 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			step = s
+			part = part[:i]
+		}
+
+		from, to := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			v, err := parseCronValue(bounds[0], min, names)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			from, to = v, v
+			if len(bounds) == 2 {
+				if to, err = parseCronValue(bounds[1], min, names); err != nil {
+					return nil, fmt.Errorf("invalid value %q", part)
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("%q is out of range %v-%v", part, min, max)
+		}
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+
+	return values, nil
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, minute hour day-of-month month day-of-week", expr)
+	}
+
+	s := &cronSchedule{anyDom: fields[2] == "*", anyDow: fields[4] == "*"}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	// 7 is Sunday too
+	if s.dow, err = parseCronField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, err
+	}
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+
+	return s, nil
+}
+
+func (s *cronSchedule) matches(t time.Time) bool {
+	if !s.minute[t.Minute()] || !s.hour[t.Hour()] || !s.month[int(t.Month())] {
+		return false
+	}
+
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	switch {
+	case s.anyDom && s.anyDow:
+		return true
+	case s.anyDom:
+		return dow
+	case s.anyDow:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// window is a parsed WindowInfo
+type window struct {
+	name       string
+	schedule   *cronSchedule
+	duration   time.Duration
+	location   *time.Location
+	start, end time.Time
+}
+
+func (w WindowInfo) label() string {
+	if w.Name != "" {
+		return w.Name
+	}
+	if w.Cron != "" {
+		return fmt.Sprintf("%v for %v", w.Cron, w.Duration)
+	}
+
+	return fmt.Sprintf("%v to %v", w.Start, w.End)
+}
+
+func (w WindowInfo) parse() (*window, error) {
+	parsed := &window{name: w.label(), location: time.UTC}
+
+	if w.Cron == "" {
+		if w.Start == "" || w.End == "" {
+			return nil, errors.New("a window needs a cron expression and a duration, or a start and an end")
+		}
+
+		var err error
+		if parsed.start, err = time.Parse(time.RFC3339, w.Start); err != nil {
+			return nil, fmt.Errorf("invalid start: %v", err)
+		}
+		if parsed.end, err = time.Parse(time.RFC3339, w.End); err != nil {
+			return nil, fmt.Errorf("invalid end: %v", err)
+		}
+		if !parsed.end.After(parsed.start) {
+			return nil, errors.New("the end of a window must be after its start")
+		}
+		return parsed, nil
+	}
+
+	var err error
+	if parsed.schedule, err = parseCron(w.Cron); err != nil {
+		return nil, err
+	}
+	if parsed.duration, err = time.ParseDuration(w.Duration); err != nil || parsed.duration <= 0 {
+		return nil, fmt.Errorf("invalid duration %q, a cron window needs a duration, e.g. 2h", w.Duration)
+	}
+	if w.Timezone != "" {
+		if parsed.location, err = time.LoadLocation(w.Timezone); err != nil {
+			return nil, err
+		}
+	}
+
+	return parsed, nil
+}
+
+// open tells whether the window is open at now
+func (w *window) open(now time.Time) bool {
+	if w.schedule == nil {
+		return !now.Before(w.start) && now.Before(w.end)
+	}
+
+	t := now.In(w.location).Truncate(time.Minute)
+	for opened := t; now.Sub(opened) < w.duration; opened = opened.Add(-time.Minute) {
+		if w.schedule.matches(opened) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// next is the time the window opens after now, zero if it never does (within a year for
+// recurring windows)
+func (w *window) next(now time.Time) time.Time {
+	if w.schedule == nil {
+		if now.Before(w.start) {
+			return w.start
+		}
+		return time.Time{}
+	}
+
+	t := now.In(w.location).Truncate(time.Minute).Add(time.Minute)
+	for limit := now.Add(maxWindowLookahead); t.Before(limit); t = t.Add(time.Minute) {
+		if w.schedule.matches(t) {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// WindowGate refuses to apply changes outside of the deployment windows of a spec: the
+// changes are still planned, and listed, but dropped. Without windows there is no gate.
+type WindowGate struct {
+	// Open is the window open when the gate was created, empty if none is
+	Open string
+	// Next is when the next window opens, zero if none does
+	Next time.Time
+	// Override applies the changes even though no window is open
+	Override bool
+	// Refused is the number of changes that were not applied
+	Refused int
+}
+
+// NewWindowGate checks the windows at now, the gate is nil if windows is empty
+func NewWindowGate(windows []WindowInfo, now time.Time, override bool) (*WindowGate, error) {
+	if len(windows) == 0 {
+		return nil, nil
+	}
+
+	g := &WindowGate{Override: override}
+	for _, info := range windows {
+		w, err := info.parse()
+		if err != nil {
+			return nil, fmt.Errorf("window %v: %v", info.label(), err)
+		}
+
+		if w.open(now) {
+			g.Open = w.name
+			return g, nil
+		}
+		if next := w.next(now); !next.IsZero() && (g.Next.IsZero() || next.Before(g.Next)) {
+			g.Next = next
+		}
+	}
+
+	return g, nil
+}
+
+// Closed tells whether changes are refused: no window is open and there is no override.
+// g may be nil.
+func (g *WindowGate) Closed() bool {
+	return g != nil && g.Open == "" && !g.Override
+}
+
+// Status describes the state of the gate, for the output of a command
+func (g *WindowGate) Status() string {
+	switch {
+	case g.Open != "":
+		return fmt.Sprintf("Deployment window %v is open", g.Open)
+	case g.Override:
+		return "No deployment window is open, applying the changes anyway (--override-window)"
+	case g.Next.IsZero():
+		return "No deployment window is open, only planning the changes"
+	default:
+		return fmt.Sprintf("No deployment window is open, only planning the changes, the next window opens at %v", g.Next.Format(time.RFC3339))
+	}
+}
+
+// Keep drops, and lists, the items of action when the gate is closed
+func (g *WindowGate) Keep(action string, items []objects.SyncItem) []objects.SyncItem {
+	if !g.Closed() {
+		return items
+	}
+
+	for _, item := range items {
+		fmt.Printf("--> Outside of the deployment windows, not going to %v: %v (%v)\n", action, item.Name, item.ID)
+	}
+	g.Refused += len(items)
+
+	return []objects.SyncItem{}
+}
+
+// Check can be used as the plan check of a client, it empties the plan when the gate is
+// closed. g may be nil.
+func (g *WindowGate) Check(plan *objects.SyncPlan) error {
+	if !g.Closed() {
+		return nil
+	}
+
+	plan.Create = g.Keep("create", plan.Create)
+	plan.Update = g.Keep("update", plan.Update)
+	plan.Delete = g.Keep("delete", plan.Delete)
+
+	return nil
+}
+
+// Err is the error a command ends with when it refused changes. g may be nil.
+func (g *WindowGate) Err() error {
+	if g == nil || g.Refused == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%v changes were not applied outside of the deployment windows, use --override-window to apply them", g.Refused)
+}
@@ -0,0 +1,98 @@
+package tyk_vcs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/tyk-sync/clients/objects"
+)
+
+func TestNewWindowGate(t *testing.T) {
+	weeknights := []WindowInfo{{Name: "weeknights", Cron: "0 22 * * mon-fri", Duration: "2h"}}
+
+	cases := []struct {
+		name    string
+		windows []WindowInfo
+		now     string
+		open    bool
+		next    string
+	}{
+		{name: "in a recurring window", windows: weeknights, now: "2026-10-14T23:30:00Z", open: true},
+		{name: "before a recurring window", windows: weeknights, now: "2026-10-14T12:00:00Z", next: "2026-10-14T22:00:00Z"},
+		// No window on weekends
+		{name: "weekend", windows: weeknights, now: "2026-10-17T23:59:00Z", next: "2026-10-19T22:00:00Z"},
+		{name: "at the end of a window", windows: weeknights, now: "2026-10-15T00:00:00Z", next: "2026-10-15T22:00:00Z"},
+		{name: "timezone", windows: []WindowInfo{{Cron: "0 9 * * *", Duration: "1h", Timezone: "America/New_York"}}, now: "2026-10-14T13:30:00Z", open: true},
+		{name: "steps and lists", windows: []WindowInfo{{Cron: "*/15 8,20 1 * *", Duration: "5m"}}, now: "2026-11-01T20:47:00Z", open: true},
+		{
+			name:    "calendar",
+			windows: []WindowInfo{{Start: "2026-12-01T20:00:00Z", End: "2026-12-01T23:00:00Z"}},
+			now:     "2026-10-14T12:00:00Z",
+			next:    "2026-12-01T20:00:00Z",
+		},
+		{name: "past calendar window", windows: []WindowInfo{{Start: "2026-01-01T20:00:00Z", End: "2026-01-01T23:00:00Z"}}, now: "2026-10-14T12:00:00Z"},
+	}
+
+	for _, c := range cases {
+		now, _ := time.Parse(time.RFC3339, c.now)
+		g, err := NewWindowGate(c.windows, now, false)
+		if err != nil {
+			t.Fatalf("%v: %v", c.name, err)
+		}
+
+		if g.Closed() == c.open {
+			t.Fatalf("%v: expected open=%v, got %+v", c.name, c.open, g)
+		}
+		if c.next != "" && g.Next.UTC().Format(time.RFC3339) != c.next {
+			t.Fatalf("%v: expected the next window at %v, got %v", c.name, c.next, g.Next)
+		}
+		if c.next == "" && !c.open && !g.Next.IsZero() {
+			t.Fatalf("%v: expected no next window, got %v", c.name, g.Next)
+		}
+	}
+}
+
+func TestNewWindowGate_Invalid(t *testing.T) {
+	for _, w := range []WindowInfo{
+		{Cron: "0 22 * *", Duration: "1h"},
+		{Cron: "0 25 * * *", Duration: "1h"},
+		{Cron: "0 22 * * *"},
+		{Cron: "0 22 * * *", Duration: "1h", Timezone: "Nowhere/Else"},
+		{Start: "2026-12-01T20:00:00Z"},
+		{Start: "2026-12-01T20:00:00Z", End: "2026-12-01T19:00:00Z"},
+	} {
+		if _, err := NewWindowGate([]WindowInfo{w}, time.Now(), false); err == nil {
+			t.Fatalf("expected an error for %+v", w)
+		}
+	}
+
+	if g, err := NewWindowGate(nil, time.Now(), false); g != nil || err != nil {
+		t.Fatal("expected no gate without windows")
+	}
+}
+
+func TestWindowGate_Check(t *testing.T) {
+	plan := func() *objects.SyncPlan {
+		return &objects.SyncPlan{
+			Kind:   "APIs",
+			Create: []objects.SyncItem{{ID: "a"}},
+			Update: []objects.SyncItem{{ID: "b"}},
+			Delete: []objects.SyncItem{{ID: "c"}},
+		}
+	}
+
+	closed := &WindowGate{}
+	p := plan()
+	closed.Check(p)
+	if len(p.Create)+len(p.Update)+len(p.Delete) != 0 || closed.Refused != 3 || closed.Err() == nil {
+		t.Fatalf("expected the plan to be refused: %+v", p)
+	}
+
+	for _, g := range []*WindowGate{nil, {Open: "weeknights"}, {Override: true}} {
+		p := plan()
+		g.Check(p)
+		if len(p.Create)+len(p.Update)+len(p.Delete) != 3 || g.Err() != nil {
+			t.Fatalf("expected the plan to be applied with %+v", g)
+		}
+	}
+}